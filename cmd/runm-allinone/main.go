@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"google.golang.org/grpc"
+
+	"github.com/runmachine-io/runmachine/pkg/allinone"
+	"github.com/runmachine-io/runmachine/pkg/allinone/config"
+
+	"github.com/runmachine-io/runmachine/pkg/health"
+	"github.com/runmachine-io/runmachine/pkg/logging"
+	"github.com/runmachine-io/runmachine/pkg/metrics"
+	"github.com/runmachine-io/runmachine/pkg/tracing"
+	"github.com/runmachine-io/runmachine/pkg/util"
+)
+
+func main() {
+	logcfg := logging.ConfigFromOpts()
+	logcfg.Service = "runm-allinone"
+	log := logging.New(logcfg)
+	log.HandleLevelSignals()
+
+	defer log.WithSection("runm-allinone")()
+
+	cfg := config.ConfigFromOpts()
+
+	tracer, err := tracing.Init(log, "runm-allinone", tracing.ConfigFromOpts())
+	if err != nil {
+		log.ERR("failed to set up tracing: %v", err)
+		os.Exit(1)
+	}
+	defer tracer.Close()
+
+	addr := fmt.Sprintf("%s:%d", cfg.BindHost, cfg.BindPort)
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.ERR("failed to listen: %v", err)
+		os.Exit(1)
+	}
+	log.L2("listening on TCP %s", addr)
+
+	srv, err := allinone.New(cfg, log)
+	if err != nil {
+		log.ERR("failed to create runm-allinone server: %v", err)
+		os.Exit(1)
+	}
+	defer srv.Close()
+
+	if cfg.MetricsAddress != "" {
+		metrics.Serve(log, cfg.MetricsAddress)
+	}
+
+	// Set up the gRPC server serving all runmachine services on our port
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(util.ChainUnaryServer(
+			tracing.UnaryServerInterceptor(),
+			logging.UnaryServerInterceptor(),
+			metrics.UnaryServerInterceptor("runm-allinone"),
+		)),
+		grpc.StreamInterceptor(util.ChainStreamServer(
+			tracing.StreamServerInterceptor(),
+			logging.StreamServerInterceptor(),
+			metrics.StreamServerInterceptor("runm-allinone"),
+		)),
+	}
+
+	// Report dependency state via the standard grpc.health.v1 Health service
+	hc := health.New(
+		log, srv.HealthCheck,
+		"runm.RunmMetadata", "runm.RunmResource", "runm.RunmControl",
+		"runm.RunmAPI",
+	)
+
+	s := grpc.NewServer(opts...)
+
+	// Handle SIGTERM and SIGINT signals by stopping the gRPC server, which
+	// causes Serve() to return and the deferred cleanup to run
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		sig := <-sigs
+		log.L1("received %s.", sig)
+		hc.Stop()
+		s.GracefulStop()
+	}()
+
+	hc.Register(s)
+	// NOTE(jaypipes): runm-api, runm-control and runm-resource check the
+	// health of the services they depend on by calling this same gRPC
+	// server, so the first check must not block serving
+	go hc.Start()
+	srv.Register(s)
+	s.Serve(lis)
+}
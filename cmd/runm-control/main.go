@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/runmachine-io/runmachine/pkg/control/server"
+	"github.com/runmachine-io/runmachine/pkg/control/server/config"
+	pb "github.com/runmachine-io/runmachine/proto"
+
+	"github.com/runmachine-io/runmachine/pkg/health"
+	"github.com/runmachine-io/runmachine/pkg/logging"
+	"github.com/runmachine-io/runmachine/pkg/metrics"
+	"github.com/runmachine-io/runmachine/pkg/registry"
+	"github.com/runmachine-io/runmachine/pkg/tracing"
+	"github.com/runmachine-io/runmachine/pkg/util"
+)
+
+func main() {
+	logcfg := logging.ConfigFromOpts()
+	logcfg.Service = "runm-control"
+	log := logging.New(logcfg)
+	log.HandleLevelSignals()
+
+	defer log.WithSection("runm-control")()
+
+	cfg := config.ConfigFromOpts()
+
+	tracer, err := tracing.Init(log, "runm-control", tracing.ConfigFromOpts())
+	if err != nil {
+		log.ERR("failed to set up tracing: %v", err)
+		os.Exit(1)
+	}
+	defer tracer.Close()
+
+	reg, err := registry.Connect(log)
+	if err != nil {
+		log.ERR("failed to connect to service registry: %v", err)
+		os.Exit(1)
+	}
+
+	cs, err := server.New(cfg, log, reg)
+	if err != nil {
+		log.ERR("failed to create runm-control server: %v", err)
+		os.Exit(1)
+	}
+	defer cs.Close()
+
+	addr := fmt.Sprintf("%s:%d", cfg.BindHost, cfg.BindPort)
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.ERR("failed to listen: %v", err)
+		os.Exit(1)
+	}
+	log.L2("listening on TCP %s", addr)
+
+	if cfg.MetricsAddress != "" {
+		metrics.Serve(log, cfg.MetricsAddress)
+	}
+
+	// Set up the gRPC server listening on incoming TCP connections on our port
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(util.ChainUnaryServer(
+			tracing.UnaryServerInterceptor(),
+			logging.UnaryServerInterceptor(),
+			metrics.UnaryServerInterceptor("runm-control"),
+		)),
+		grpc.StreamInterceptor(util.ChainStreamServer(
+			tracing.StreamServerInterceptor(),
+			logging.StreamServerInterceptor(),
+			metrics.StreamServerInterceptor("runm-control"),
+		)),
+	}
+	if cfg.UseTLS {
+		creds, err := credentials.NewServerTLSFromFile(
+			cfg.CertPath,
+			cfg.KeyPath,
+		)
+		if err != nil {
+			log.ERR("failed to generate credentials: %v", err)
+			os.Exit(1)
+		}
+		opts = append(opts, grpc.Creds(creds))
+		log.L2("using credentials file %v", cfg.KeyPath)
+	}
+
+	// Report dependency state via the standard grpc.health.v1 Health service
+	hc := health.New(log, cs.HealthCheck, "runm.RunmControl")
+
+	// Handle SIGTERM signals and close our Service instance, which should take
+	// care of notifying the service registry about our endpoint going away
+	sigs := make(chan os.Signal, 1)
+	done := make(chan bool, 1)
+	signal.Notify(sigs, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		log.L1("received %s.", sig)
+		hc.Stop()
+		cs.Close()
+		tracer.Close()
+		done <- true
+	}()
+
+	s := grpc.NewServer(opts...)
+	hc.Register(s)
+	hc.Start()
+	pb.RegisterRunmControlServer(s, cs)
+	s.Serve(lis)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/runmachine-io/runmachine/pkg/gateway/server"
+	"github.com/runmachine-io/runmachine/pkg/gateway/server/config"
+
+	"github.com/runmachine-io/runmachine/pkg/logging"
+	"github.com/runmachine-io/runmachine/pkg/registry"
+	"github.com/runmachine-io/runmachine/pkg/tracing"
+)
+
+func main() {
+	logcfg := logging.ConfigFromOpts()
+	logcfg.Service = "runm-gateway"
+	log := logging.New(logcfg)
+	log.HandleLevelSignals()
+
+	defer log.WithSection("runm-gateway")()
+
+	cfg := config.ConfigFromOpts()
+
+	tracer, err := tracing.Init(log, "runm-gateway", tracing.ConfigFromOpts())
+	if err != nil {
+		log.ERR("failed to set up tracing: %v", err)
+		os.Exit(1)
+	}
+	defer tracer.Close()
+
+	reg, err := registry.Connect(log)
+	if err != nil {
+		log.ERR("failed to connect to service registry: %v", err)
+		os.Exit(1)
+	}
+
+	gw, err := server.New(cfg, log, reg)
+	if err != nil {
+		log.ERR("failed to create runm-gateway server: %v", err)
+		os.Exit(1)
+	}
+	defer gw.Close()
+
+	addr := fmt.Sprintf("%s:%d", cfg.BindHost, cfg.BindPort)
+	hs := &http.Server{
+		Addr:    addr,
+		Handler: tracing.HTTPHandler(gw),
+	}
+
+	// Handle SIGTERM signals and gracefully shut down the HTTP server,
+	// allowing in-flight requests to complete
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		log.L1("received %s.", sig)
+		hs.Shutdown(context.Background())
+	}()
+
+	log.L2("listening on HTTP %s", addr)
+	if cfg.UseTLS {
+		log.L2("using credentials file %v", cfg.KeyPath)
+		err = hs.ListenAndServeTLS(cfg.CertPath, cfg.KeyPath)
+	} else {
+		err = hs.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		log.ERR("failed to serve: %v", err)
+		os.Exit(1)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os/signal"
 	"syscall"
 
+	flag "github.com/ogier/pflag"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 
@@ -14,17 +15,42 @@ import (
 	"github.com/runmachine-io/runmachine/pkg/metadata/server/config"
 	pb "github.com/runmachine-io/runmachine/proto"
 
+	"github.com/runmachine-io/runmachine/pkg/health"
 	"github.com/runmachine-io/runmachine/pkg/logging"
+	"github.com/runmachine-io/runmachine/pkg/metrics"
+	"github.com/runmachine-io/runmachine/pkg/registry"
+	"github.com/runmachine-io/runmachine/pkg/tracing"
+	"github.com/runmachine-io/runmachine/pkg/util"
 )
 
 func main() {
-	log := logging.New(logging.ConfigFromOpts())
+	logcfg := logging.ConfigFromOpts()
+	logcfg.Service = "runm-metadata"
+	log := logging.New(logcfg)
+	log.HandleLevelSignals()
 
 	defer log.WithSection("runm-metadata")()
 
 	cfg := config.ConfigFromOpts()
 
-	md, err := server.New(cfg, log)
+	if flag.Arg(0) == "migrate" {
+		os.Exit(runMigrate(log, cfg, flag.Args()[1:]))
+	}
+
+	tracer, err := tracing.Init(log, "runm-metadata", tracing.ConfigFromOpts())
+	if err != nil {
+		log.ERR("failed to set up tracing: %v", err)
+		os.Exit(1)
+	}
+	defer tracer.Close()
+
+	reg, err := registry.Connect(log)
+	if err != nil {
+		log.ERR("failed to connect to service registry: %v", err)
+		os.Exit(1)
+	}
+
+	md, err := server.New(cfg, log, reg)
 	if err != nil {
 		log.ERR("failed to create runm-metadata server: %v", err)
 		os.Exit(1)
@@ -39,8 +65,23 @@ func main() {
 	}
 	log.L2("listening on TCP %s", addr)
 
+	if cfg.MetricsAddress != "" {
+		metrics.Serve(log, cfg.MetricsAddress)
+	}
+
 	// Set up the gRPC server listening on incoming TCP connections on our port
-	var opts []grpc.ServerOption
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(util.ChainUnaryServer(
+			tracing.UnaryServerInterceptor(),
+			logging.UnaryServerInterceptor(),
+			metrics.UnaryServerInterceptor("runm-metadata"),
+		)),
+		grpc.StreamInterceptor(util.ChainStreamServer(
+			tracing.StreamServerInterceptor(),
+			logging.StreamServerInterceptor(),
+			metrics.StreamServerInterceptor("runm-metadata"),
+		)),
+	}
 	if cfg.UseTLS {
 		creds, err := credentials.NewServerTLSFromFile(
 			cfg.CertPath,
@@ -50,10 +91,13 @@ func main() {
 			log.ERR("failed to generate credentials: %v", err)
 			os.Exit(1)
 		}
-		opts = []grpc.ServerOption{grpc.Creds(creds)}
+		opts = append(opts, grpc.Creds(creds))
 		log.L2("using credentials file %v", cfg.KeyPath)
 	}
 
+	// Report dependency state via the standard grpc.health.v1 Health service
+	hc := health.New(log, md.HealthCheck, "runm.RunmMetadata")
+
 	// Handle SIGTERM signals and close our Service instance, which should take
 	// care of notifying the service registry about our endpoint going away
 	sigs := make(chan os.Signal, 1)
@@ -62,11 +106,15 @@ func main() {
 	go func() {
 		sig := <-sigs
 		log.L1("received %s.", sig)
+		hc.Stop()
 		md.Close()
+		tracer.Close()
 		done <- true
 	}()
 
 	s := grpc.NewServer(opts...)
+	hc.Register(s)
+	hc.Start()
 	pb.RegisterRunmMetadataServer(s, md)
 	s.Serve(lis)
 }
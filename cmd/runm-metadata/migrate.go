@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/runmachine-io/runmachine/pkg/logging"
+	"github.com/runmachine-io/runmachine/pkg/metadata/server/config"
+	"github.com/runmachine-io/runmachine/pkg/metadata/server/storage"
+)
+
+const migrateUsage = `usage: runm-metadata migrate status
+       runm-metadata migrate dry-run
+       runm-metadata migrate up
+
+status   Show the current and latest versions of the etcd keyspace layout.
+dry-run  Show the changes each pending keyspace migration would make without
+         making them.
+up       Run all pending keyspace migrations.
+`
+
+// runMigrate runs the "migrate" subcommand with the supplied arguments and
+// returns the process exit code
+func runMigrate(
+	log *logging.Logs,
+	cfg *config.Config,
+	args []string,
+) int {
+	if len(args) != 1 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 1
+	}
+
+	s, err := storage.Connect(log, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		return 1
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	switch args[0] {
+	case "status":
+		err = migrateStatus(ctx, s)
+	case "dry-run":
+		var reports []*storage.KeyspaceMigrationReport
+		reports, err = s.MigrateKeyspace(ctx, true)
+		printReports(reports)
+	case "up":
+		var reports []*storage.KeyspaceMigrationReport
+		reports, err = s.MigrateKeyspace(ctx, false)
+		fmt.Printf("ran %d migration(s)\n", len(reports))
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+func migrateStatus(ctx context.Context, s *storage.Store) error {
+	version, err := s.KeyspaceVersion(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("current version: %d\n", version)
+	fmt.Printf("latest version:  %d\n", s.LatestKeyspaceVersion())
+	return nil
+}
+
+func printReports(reports []*storage.KeyspaceMigrationReport) {
+	if len(reports) == 0 {
+		fmt.Println("no pending migrations")
+		return
+	}
+	headers := []string{
+		"Version",
+		"Description",
+		"Keys Scanned",
+		"Puts",
+		"Deletes",
+		"Sample Keys",
+	}
+	rows := make([][]string, len(reports))
+	for x, rep := range reports {
+		rows[x] = []string{
+			strconv.Itoa(rep.Version),
+			rep.Description,
+			strconv.Itoa(rep.Scanned),
+			strconv.Itoa(rep.Puts),
+			strconv.Itoa(rep.Deletes),
+			strings.Join(rep.Keys, "\n"),
+		}
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(headers)
+	table.AppendBulk(rows)
+	table.Render()
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/runmachine-io/runmachine/pkg/logging"
+	"github.com/runmachine-io/runmachine/pkg/resource/server/config"
+	"github.com/runmachine-io/runmachine/pkg/resource/server/storage"
+)
+
+const migrateUsage = `usage: runm-resource migrate status
+       runm-resource migrate up [VERSION]
+       runm-resource migrate down [VERSION]
+
+status  Show each migration of the resource database schema and whether it
+        has been applied.
+up      Apply all migrations up to and including VERSION (default: latest).
+down    Revert all migrations newer than VERSION (default: the version
+        before the most recently applied migration). Use 0 to revert all
+        migrations.
+`
+
+// runMigrate runs the "migrate" subcommand with the supplied arguments and
+// returns the process exit code
+func runMigrate(
+	log *logging.Logs,
+	cfg *config.Config,
+	args []string,
+) int {
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 1
+	}
+	target := -1
+	if len(args) == 2 {
+		v, err := strconv.Atoi(args[1])
+		if err != nil || v < 0 {
+			fmt.Fprintf(os.Stderr, "invalid VERSION %q\n", args[1])
+			return 1
+		}
+		target = v
+	}
+
+	m, err := storage.NewMigrator(log, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		return 1
+	}
+	defer m.Close()
+
+	ctx := context.Background()
+	switch args[0] {
+	case "status":
+		err = migrateStatus(ctx, m)
+	case "up":
+		if target < 0 {
+			target = 0
+		}
+		var n int
+		n, err = m.Up(ctx, target)
+		if err == nil {
+			fmt.Printf("applied %d migration(s)\n", n)
+		}
+	case "down":
+		if target < 0 {
+			target, err = previousVersion(ctx, m)
+			if err != nil {
+				break
+			}
+		}
+		var n int
+		n, err = m.Down(ctx, target)
+		if err == nil {
+			fmt.Printf("reverted %d migration(s)\n", n)
+		}
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+func migrateStatus(ctx context.Context, m *storage.Migrator) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	headers := []string{
+		"Version",
+		"Description",
+		"Status",
+		"Applied At",
+	}
+	rows := make([][]string, len(statuses))
+	for x, st := range statuses {
+		status := "pending"
+		appliedAt := ""
+		if st.Applied {
+			status = "applied"
+			if st.Modified {
+				status = "modified"
+			}
+			if !st.AppliedAt.IsZero() {
+				appliedAt = st.AppliedAt.Format(time.RFC3339)
+			}
+		}
+		rows[x] = []string{
+			strconv.Itoa(st.Version),
+			st.Description,
+			status,
+			appliedAt,
+		}
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(headers)
+	table.AppendBulk(rows)
+	table.Render()
+	return nil
+}
+
+// previousVersion returns the version before the most recently applied
+// migration
+func previousVersion(ctx context.Context, m *storage.Migrator) (int, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for x := len(statuses) - 1; x >= 0; x-- {
+		if statuses[x].Applied {
+			return statuses[x].Version - 1, nil
+		}
+	}
+	return 0, nil
+}
@@ -49,6 +49,8 @@ var (
 	cliObjectDocPath string
 	// CLI-provided set of --filter options
 	cliFilters = []string{}
+	// CLI-provided --selector option
+	cliSelector string
 )
 
 func exitIfConnectErr(err error) {
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	cliDefinitionGlobal     bool
+	cliDefinitionPartition  string
+	cliDefinitionObjectType string
+	cliDefinitionSubtype    string
+)
+
+var definitionCommand = &cobra.Command{
+	Use:   "definition",
+	Short: "Manipulate object definitions",
+}
+
+func init() {
+	definitionCommand.AddCommand(definitionGetCommand)
+	definitionCommand.AddCommand(definitionSetCommand)
+}
+
+// definitionPartition returns the partition identifier to use for an object
+// definition based on the --global and --partition CLI options, defaulting to
+// the user's session partition
+func definitionPartition(sessionPartition string) string {
+	if cliDefinitionGlobal {
+		return ""
+	}
+	if cliDefinitionPartition == "" {
+		return sessionPartition
+	}
+	return cliDefinitionPartition
+}
+
+// exitIfNoDefinitionType writes an error and exits if the --type CLI option was
+// not supplied
+func exitIfNoDefinitionType(cmd *cobra.Command) {
+	if cliDefinitionObjectType == "" {
+		fmt.Fprintf(
+			os.Stderr,
+			"Error: please specify the object type with the --type CLI "+
+				"option\n",
+		)
+		cmd.Help()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	usageDefinitionGet = `Show the definition for objects of a type
+
+The --type CLI option is required and specifies the type of object to show the
+definition for:
+
+  runm definition get --type runm.image
+
+Specifying the --global CLI option will show the global default definition for
+the object type:
+
+  runm definition get --type runm.image --global
+
+To show the definition for objects in a specific partition, if an admin has
+overridden the definition for objects of the type in that partition, use the
+--partition CLI option:
+
+  runm definition get --type runm.image --partition part0
+
+The --subtype CLI option can be used to return a definition that has been set
+for a specific subtype of object. For runm.provider objects, the subtype is
+the provider type:
+
+  runm definition get --type runm.provider --subtype runm.compute
+
+NOTE: Specifying neither --global nor --partition CLI options will return the
+exact definition that will be used to validate input data for objects of the
+type (and subtype, if supplied) in the user's session partition. This is the
+definition overridden for the session partition, if any, or otherwise the
+global definition.
+`
+)
+
+var definitionGetCommand = &cobra.Command{
+	Use:   "get",
+	Short: "Show information for an object definition",
+	Run:   definitionGet,
+	Long:  usageDefinitionGet,
+}
+
+func setupDefinitionGetFlags() {
+	definitionGetCommand.Flags().StringVarP(
+		&cliDefinitionObjectType,
+		"type", "t",
+		"",
+		"Object type code, e.g. runm.image.",
+	)
+	definitionGetCommand.Flags().BoolVarP(
+		&cliDefinitionGlobal,
+		"global", "g",
+		false,
+		"Show the global default definition for the object type.",
+	)
+	definitionGetCommand.Flags().StringVarP(
+		&cliDefinitionPartition,
+		"partition", "",
+		"",
+		"Optional partition identifier.",
+	)
+	definitionGetCommand.Flags().StringVarP(
+		&cliDefinitionSubtype,
+		"subtype", "s",
+		"",
+		"Optional object subtype, e.g. a provider type.",
+	)
+}
+
+func init() {
+	setupDefinitionGetFlags()
+}
+
+func definitionGet(cmd *cobra.Command, args []string) {
+	exitIfNoDefinitionType(cmd)
+
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+
+	session := getSession()
+
+	obj, err := client.DefinitionGet(
+		context.Background(),
+		&pb.DefinitionGetRequest{
+			Session:    session,
+			ObjectType: cliDefinitionObjectType,
+			Partition:  definitionPartition(session.Partition),
+			Subtype:    cliDefinitionSubtype,
+			Resolve:    !cliDefinitionGlobal && cliDefinitionPartition == "",
+		},
+	)
+	exitIfError(err)
+	printObjectDefinition(obj)
+}
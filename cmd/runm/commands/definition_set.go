@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	usageDefinitionSet = `Set the definition for objects of a type
+
+The --type CLI option is required and specifies the type of object to set the
+definition for:
+
+  runm definition set --type runm.image -f image-definition.yaml
+
+Specifying the --global CLI option will set the global default definition for
+the object type:
+
+  runm definition set --type runm.image --global
+
+To override the definition for objects of the type in a specific partition, use
+the --partition CLI option:
+
+  runm definition set --type runm.image --partition part0
+
+The --subtype CLI option can be used to override the definition for a specific
+subtype of object. For runm.provider objects, the subtype is the provider
+type:
+
+  runm definition set --type runm.provider --subtype runm.compute
+
+NOTE: Specifying neither --global or --partition CLI options will set the
+definition for objects of the type in the user's session partition.
+`
+)
+
+var definitionSetCommand = &cobra.Command{
+	Use:   "set",
+	Short: "Define the schema for objects of a type",
+	Run:   definitionSet,
+	Long:  usageDefinitionSet,
+}
+
+func setupDefinitionSetFlags() {
+	definitionSetCommand.Flags().StringVarP(
+		&cliDefinitionObjectType,
+		"type", "t",
+		"",
+		"Object type code, e.g. runm.image.",
+	)
+	definitionSetCommand.Flags().BoolVarP(
+		&cliDefinitionGlobal,
+		"global", "g",
+		false,
+		"Set the global default definition for the object type.",
+	)
+	definitionSetCommand.Flags().StringVarP(
+		&cliDefinitionPartition,
+		"partition", "",
+		"",
+		"Identifier of partition to set an override definition for.",
+	)
+	definitionSetCommand.Flags().StringVarP(
+		&cliDefinitionSubtype,
+		"subtype", "s",
+		"",
+		"Optional object subtype, e.g. a provider type.",
+	)
+	definitionSetCommand.Flags().StringVarP(
+		&cliObjectDocPath,
+		"file", "f",
+		"",
+		"optional filepath to YAML document to send.",
+	)
+}
+
+func init() {
+	setupDefinitionSetFlags()
+}
+
+func definitionSet(cmd *cobra.Command, args []string) {
+	exitIfNoDefinitionType(cmd)
+
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	session := getSession()
+
+	req := &pb.DefinitionSetRequest{
+		Session:    session,
+		Format:     pb.PayloadFormat_YAML,
+		Payload:    readInputDocumentOrExit(),
+		ObjectType: cliDefinitionObjectType,
+		Partition:  definitionPartition(session.Partition),
+		Subtype:    cliDefinitionSubtype,
+	}
+
+	resp, err := client.DefinitionSet(context.Background(), req)
+	exitIfError(err)
+	obj := resp.ObjectDefinition
+	if !quiet {
+		fmt.Printf("ok\n")
+		if verbose {
+			printObjectDefinition(obj)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/runmachine-io/runmachine/pkg/api/types"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	// The number of bytes of an image's bits sent in each message by runm
+	// image upload
+	imageUploadChunkSize = 64 * 1024
+)
+
+var imageCommand = &cobra.Command{
+	Use:   "image",
+	Short: "Manipulate images",
+}
+
+func init() {
+	imageCommand.AddCommand(imageListCommand)
+	imageCommand.AddCommand(imageShowCommand)
+	imageCommand.AddCommand(imageCreateCommand)
+	imageCommand.AddCommand(imageUploadCommand)
+	imageCommand.AddCommand(imageDownloadCommand)
+	imageCommand.AddCommand(imageDeleteCommand)
+}
+
+func printImage(obj *pb.Image) {
+	fmt.Printf("Partition:    %s\n", obj.Partition)
+	fmt.Printf("Project:      %s\n", obj.Project)
+	fmt.Printf("UUID:         %s\n", obj.Uuid)
+	fmt.Printf("Name:         %s\n", obj.Name)
+	fmt.Printf("Status:       %s\n", types.ImageStatusString(obj.Status))
+	fmt.Printf(
+		"Visibility:   %s\n", types.ImageVisibilityString(obj.Visibility),
+	)
+	if len(obj.Members) > 0 {
+		fmt.Printf("Members:      %s\n", strings.Join(obj.Members, ","))
+	}
+	if obj.Architecture != "" {
+		fmt.Printf("Architecture: %s\n", obj.Architecture)
+	}
+	if obj.Os != "" {
+		fmt.Printf("OS:           %s\n", obj.Os)
+	}
+	fmt.Printf("Format:       %s\n", obj.Format)
+	if obj.Status == pb.ImageStatus_IMAGE_ACTIVE {
+		fmt.Printf("Size:         %d\n", obj.Size)
+	}
+	if obj.Checksum != "" {
+		fmt.Printf("Checksum:     %s\n", obj.Checksum)
+	}
+	if len(obj.Properties) > 0 {
+		fmt.Printf("Properties:\n")
+		for _, prop := range obj.Properties {
+			fmt.Printf("   %s=%s\n", prop.Key, prop.Value)
+		}
+	}
+	if obj.Tags != nil {
+		tags := strings.Join(obj.Tags, ",")
+		fmt.Printf("Tags:         %s\n", tags)
+	}
+}
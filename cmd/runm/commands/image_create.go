@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	usageImageCreate = `Register a new image
+
+The image is described by a YAML document. For example:
+
+  name: ubuntu-18.04
+  architecture: x86_64
+  os: linux
+  format: qcow2
+  visibility: public
+  properties:
+    distro: ubuntu
+
+The image's bits are uploaded afterwards with runm image upload. If the
+document has a checksum (a hex-encoded SHA-256 digest) or size (in bytes), the
+uploaded bits must match them.
+`
+)
+
+var imageCreateCommand = &cobra.Command{
+	Use:   "create",
+	Short: "Register an image",
+	Run:   imageCreate,
+	Long:  usageImageCreate,
+}
+
+func setupImageCreateFlags() {
+	imageCreateCommand.Flags().StringVarP(
+		&cliObjectDocPath,
+		"file", "f",
+		"",
+		"optional filepath to YAML document to send.",
+	)
+}
+
+func init() {
+	setupImageCreateFlags()
+}
+
+func imageCreate(cmd *cobra.Command, args []string) {
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	req := &pb.CreateRequest{
+		Session: getSession(),
+		Format:  pb.PayloadFormat_YAML,
+		Payload: readInputDocumentOrExit(),
+	}
+
+	resp, err := client.ImageCreate(context.Background(), req)
+	exitIfError(err)
+	obj := resp.Image
+	if !quiet {
+		if verbose {
+			printImage(obj)
+		} else {
+			fmt.Printf("%s\n", obj.Uuid)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+var imageDeleteCommand = &cobra.Command{
+	Use:   "delete <search> [<search> ...]",
+	Short: "Delete images and their bits",
+	Run:   imageDelete,
+}
+
+func imageDelete(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(
+			os.Stderr,
+			"Error: please specify the UUIDs or names of the images to "+
+				"delete\n",
+		)
+		cmd.Help()
+		os.Exit(1)
+	}
+
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	req := &pb.ImageDeleteRequest{
+		Session: getSession(),
+		Search:  args,
+	}
+
+	resp, err := client.ImageDelete(context.Background(), req)
+	exitIfError(err)
+	if !quiet {
+		if verbose {
+			fmt.Fprintf(os.Stdout, "deleted %d image(s)\n", resp.NumDeleted)
+		} else {
+			fmt.Fprintf(os.Stdout, "ok\n")
+		}
+	}
+}
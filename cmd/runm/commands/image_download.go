@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+var (
+	// Path of the file runm image download writes the image's bits to
+	cliImageOutPath string
+)
+
+var imageDownloadCommand = &cobra.Command{
+	Use:   "download <search>",
+	Short: "Download the bits of an image",
+	Run:   imageDownload,
+}
+
+func setupImageDownloadFlags() {
+	imageDownloadCommand.Flags().StringVarP(
+		&cliImageOutPath,
+		"output", "o",
+		"",
+		"optional filepath to write the image to. Defaults to STDOUT.",
+	)
+}
+
+func init() {
+	setupImageDownloadFlags()
+}
+
+func imageDownload(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintf(
+			os.Stderr,
+			"Error: please provide a single argument: either specify a UUID "+
+				"or a name for the image to download\n",
+		)
+		cmd.Help()
+		os.Exit(1)
+	}
+
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	req := &pb.ImageGetRequest{
+		Session: getSession(),
+		Search:  args[0],
+	}
+	stream, err := client.ImageDownload(context.Background(), req)
+	exitIfConnectErr(err)
+
+	// NOTE(jaypipes): We read the first chunk before creating the output
+	// file so that a failed download doesn't leave an empty file behind
+	chunk, err := stream.Recv()
+	if err != io.EOF {
+		exitIfError(err)
+	}
+	out := os.Stdout
+	if cliImageOutPath != "" {
+		f, err := os.Create(cliImageOutPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+	for chunk != nil {
+		if _, err := out.Write(chunk.Data); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		chunk, err = stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		exitIfError(err)
+	}
+}
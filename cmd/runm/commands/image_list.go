@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"io"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	"github.com/runmachine-io/runmachine/pkg/api/types"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+var imageListCommand = &cobra.Command{
+	Use:   "list",
+	Short: "List information about images",
+	Run:   imageList,
+}
+
+func setupImageListFlags() {
+	imageListCommand.Flags().StringVarP(
+		&cliSelector,
+		"selector", "s",
+		"",
+		usageObjectSelectorOption,
+	)
+}
+
+func init() {
+	setupImageListFlags()
+}
+
+func imageList(cmd *cobra.Command, args []string) {
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	req := &pb.ImageListRequest{
+		Session:  getSession(),
+		Selector: cliSelector,
+	}
+	stream, err := client.ImageList(context.Background(), req)
+	exitIfConnectErr(err)
+
+	msgs := make([]*pb.Image, 0)
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		exitIfError(err)
+		msgs = append(msgs, msg)
+	}
+	if len(msgs) == 0 {
+		exitNoRecords()
+	}
+	headers := []string{
+		"Project",
+		"UUID",
+		"Name",
+		"Status",
+		"Visibility",
+		"Format",
+	}
+	rows := make([][]string, len(msgs))
+	for x, obj := range msgs {
+		rows[x] = []string{
+			obj.Project,
+			obj.Uuid,
+			obj.Name,
+			types.ImageStatusString(obj.Status),
+			types.ImageVisibilityString(obj.Visibility),
+			obj.Format,
+		}
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(headers)
+	table.AppendBulk(rows)
+	table.Render()
+}
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	usageImageShow = `Show information for a single image
+
+Specify a single CLI argument with the UUID or name of the image you wish to
+show:
+
+  runm image show 4f4f54c9bfb44cce9a02d4daf6f79ea3
+
+or
+
+  runm image show ubuntu-18.04
+
+NOTE: when using the second form, with the image's name instead of UUID, the
+user's session partition is used to find the image. An image owned by the
+user's session project is shown in preference to a public or shared image of
+the same name owned by another project.
+`
+)
+
+var imageShowCommand = &cobra.Command{
+	Use:     "show <search>",
+	Aliases: []string{"get"},
+	Short:   "Show information for a single image",
+	Run:     imageShow,
+	Long:    usageImageShow,
+}
+
+func imageShow(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintf(
+			os.Stderr,
+			"Error: please provide a single argument: either specify a UUID "+
+				"or a name for the image to show\n",
+		)
+		cmd.Help()
+		os.Exit(1)
+	}
+
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	obj, err := client.ImageGet(
+		context.Background(),
+		&pb.ImageGetRequest{
+			Session: getSession(),
+			Search:  args[0],
+		},
+	)
+	exitIfError(err)
+	printImage(obj)
+}
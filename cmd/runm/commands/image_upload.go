@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	usageImageUpload = `Upload the bits of a registered image
+
+Specify the UUID or name of the image and the path of the file to upload:
+
+  runm image upload ubuntu-18.04 ./bionic-server-cloudimg-amd64.img
+
+If no file is given, the image's bits are read from STDIN. The bits of an
+image may only be uploaded once.
+`
+)
+
+var imageUploadCommand = &cobra.Command{
+	Use:   "upload <search> [<file>]",
+	Short: "Upload the bits of an image",
+	Run:   imageUpload,
+	Long:  usageImageUpload,
+}
+
+func imageUpload(cmd *cobra.Command, args []string) {
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Fprintf(
+			os.Stderr,
+			"Error: please specify the UUID or name of the image and "+
+				"optionally the file to upload\n",
+		)
+		cmd.Help()
+		os.Exit(1)
+	}
+	in := os.Stdin
+	if len(args) == 2 {
+		f, err := os.Open(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	stream, err := client.ImageUpload(context.Background())
+	exitIfConnectErr(err)
+
+	// The first message identifies the image and every message carries the
+	// next chunk of the image's bits
+	req := &pb.ImageUploadRequest{
+		Session: getSession(),
+		Search:  args[0],
+	}
+	buf := make([]byte, imageUploadChunkSize)
+	for {
+		n, err := io.ReadFull(in, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		req.Data = buf[:n]
+		if sendErr := stream.Send(req); sendErr != nil {
+			// The server has ended the stream. The reason is returned from
+			// CloseAndRecv() below.
+			break
+		}
+		if err != nil {
+			break
+		}
+		req = &pb.ImageUploadRequest{}
+	}
+	obj, err := stream.CloseAndRecv()
+	exitIfError(err)
+	if !quiet {
+		if verbose {
+			printImage(obj)
+		} else {
+			fmt.Printf("%s\n", obj.Checksum)
+		}
+	}
+}
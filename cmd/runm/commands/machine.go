@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/runmachine-io/runmachine/pkg/api/types"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+var machineCommand = &cobra.Command{
+	Use:   "machine",
+	Short: "Manipulate machines",
+}
+
+func init() {
+	machineCommand.AddCommand(machineListCommand)
+	machineCommand.AddCommand(machineShowCommand)
+	machineCommand.AddCommand(machineCreateCommand)
+	machineCommand.AddCommand(machineStartCommand)
+	machineCommand.AddCommand(machineStopCommand)
+	machineCommand.AddCommand(machineDeleteCommand)
+}
+
+func printMachine(obj *pb.Machine) {
+	fmt.Printf("Partition:     %s\n", obj.Partition)
+	fmt.Printf("Project:       %s\n", obj.Project)
+	fmt.Printf("UUID:          %s\n", obj.Uuid)
+	fmt.Printf("Name:          %s\n", obj.Name)
+	fmt.Printf("Image:         %s\n", obj.Image)
+	if obj.Provider != "" {
+		fmt.Printf("Provider:      %s\n", obj.Provider)
+	}
+	if len(obj.Resources) > 0 {
+		codes := make([]string, 0, len(obj.Resources))
+		for code := range obj.Resources {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		fmt.Printf("Resources:\n")
+		for _, code := range codes {
+			fmt.Printf("   %s=%d\n", code, obj.Resources[code])
+		}
+	}
+	fmt.Printf(
+		"Desired state: %s\n", types.MachineStateString(obj.DesiredState),
+	)
+	fmt.Printf("State:         %s\n", types.MachineStateString(obj.State))
+	if obj.StateReason != "" {
+		fmt.Printf("State reason:  %s\n", obj.StateReason)
+	}
+	if obj.StateTime != 0 {
+		fmt.Printf(
+			"State since:   %s\n",
+			time.Unix(obj.StateTime, 0).UTC().Format(time.RFC3339),
+		)
+	}
+	if len(obj.Properties) > 0 {
+		fmt.Printf("Properties:\n")
+		for _, prop := range obj.Properties {
+			fmt.Printf("   %s=%s\n", prop.Key, prop.Value)
+		}
+	}
+	if obj.Tags != nil {
+		tags := strings.Join(obj.Tags, ",")
+		fmt.Printf("Tags:          %s\n", tags)
+	}
+}
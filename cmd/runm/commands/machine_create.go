@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	usageMachineCreate = `Create a new machine
+
+The machine is described by a YAML document. For example:
+
+  name: db0
+  image: ubuntu-18.04
+  provider: compute0
+  resources:
+    runm.cpu.dedicated: 4
+    runm.memory: 8589934592
+  properties:
+    role: database
+
+The image must have had its bits uploaded. The resources are claimed from the
+provider when the machine is created, and creating the machine fails if the
+provider does not have enough capacity. The machine is started once it is
+built unless the document has "desired_state: stopped".
+`
+)
+
+var machineCreateCommand = &cobra.Command{
+	Use:   "create",
+	Short: "Create a machine",
+	Run:   machineCreate,
+	Long:  usageMachineCreate,
+}
+
+func setupMachineCreateFlags() {
+	machineCreateCommand.Flags().StringVarP(
+		&cliObjectDocPath,
+		"file", "f",
+		"",
+		"optional filepath to YAML document to send.",
+	)
+}
+
+func init() {
+	setupMachineCreateFlags()
+}
+
+func machineCreate(cmd *cobra.Command, args []string) {
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	req := &pb.CreateRequest{
+		Session: getSession(),
+		Format:  pb.PayloadFormat_YAML,
+		Payload: readInputDocumentOrExit(),
+	}
+
+	resp, err := client.MachineCreate(context.Background(), req)
+	exitIfError(err)
+	obj := resp.Machine
+	if !quiet {
+		if verbose {
+			printMachine(obj)
+		} else {
+			fmt.Printf("%s\n", obj.Uuid)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+var machineDeleteCommand = &cobra.Command{
+	Use:   "delete <search> [<search> ...]",
+	Short: "Delete machines and release their resources",
+	Run:   machineDelete,
+}
+
+func machineDelete(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(
+			os.Stderr,
+			"Error: please specify the UUIDs or names of the machines to "+
+				"delete\n",
+		)
+		cmd.Help()
+		os.Exit(1)
+	}
+
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	req := &pb.MachineDeleteRequest{
+		Session: getSession(),
+		Search:  args,
+	}
+
+	resp, err := client.MachineDelete(context.Background(), req)
+	exitIfError(err)
+	if !quiet {
+		if verbose {
+			fmt.Fprintf(os.Stdout, "deleted %d machine(s)\n", resp.NumDeleted)
+		} else {
+			fmt.Fprintf(os.Stdout, "ok\n")
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"io"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	"github.com/runmachine-io/runmachine/pkg/api/types"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+var machineListCommand = &cobra.Command{
+	Use:   "list",
+	Short: "List information about machines",
+	Run:   machineList,
+}
+
+func setupMachineListFlags() {
+	machineListCommand.Flags().StringVarP(
+		&cliSelector,
+		"selector", "s",
+		"",
+		usageObjectSelectorOption,
+	)
+}
+
+func init() {
+	setupMachineListFlags()
+}
+
+func machineList(cmd *cobra.Command, args []string) {
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	req := &pb.MachineListRequest{
+		Session:  getSession(),
+		Selector: cliSelector,
+	}
+	stream, err := client.MachineList(context.Background(), req)
+	exitIfConnectErr(err)
+
+	msgs := make([]*pb.Machine, 0)
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		exitIfError(err)
+		msgs = append(msgs, msg)
+	}
+	if len(msgs) == 0 {
+		exitNoRecords()
+	}
+	headers := []string{
+		"UUID",
+		"Name",
+		"Desired State",
+		"State",
+		"Provider",
+	}
+	rows := make([][]string, len(msgs))
+	for x, obj := range msgs {
+		rows[x] = []string{
+			obj.Uuid,
+			obj.Name,
+			types.MachineStateString(obj.DesiredState),
+			types.MachineStateString(obj.State),
+			obj.Provider,
+		}
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(headers)
+	table.AppendBulk(rows)
+	table.Render()
+}
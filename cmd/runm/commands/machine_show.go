@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	usageMachineShow = `Show information for a single machine
+
+Specify a single CLI argument with the UUID or name of the machine you wish to
+show:
+
+  runm machine show 8b4a3ad5c8e44bb8b6cba8d2b1a1ad53
+
+or
+
+  runm machine show db0
+
+NOTE: when using the second form, with the machine's name instead of UUID,
+the user's session partition and project are used to find the machine.
+`
+)
+
+var machineShowCommand = &cobra.Command{
+	Use:     "show <search>",
+	Aliases: []string{"get"},
+	Short:   "Show information for a single machine",
+	Run:     machineShow,
+	Long:    usageMachineShow,
+}
+
+func machineShow(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintf(
+			os.Stderr,
+			"Error: please provide a single argument: either specify a UUID "+
+				"or a name for the machine to show\n",
+		)
+		cmd.Help()
+		os.Exit(1)
+	}
+
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	obj, err := client.MachineGet(
+		context.Background(),
+		&pb.MachineGetRequest{
+			Session: getSession(),
+			Search:  args[0],
+		},
+	)
+	exitIfError(err)
+	printMachine(obj)
+}
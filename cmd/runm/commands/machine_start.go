@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+var machineStartCommand = &cobra.Command{
+	Use:   "start <search>",
+	Short: "Set the desired state of a machine to running",
+	Run:   machineStart,
+}
+
+var machineStopCommand = &cobra.Command{
+	Use:   "stop <search>",
+	Short: "Set the desired state of a machine to stopped",
+	Run:   machineStop,
+}
+
+func machineStart(cmd *cobra.Command, args []string) {
+	machineSetDesiredState(cmd, args, "start")
+}
+
+func machineStop(cmd *cobra.Command, args []string) {
+	machineSetDesiredState(cmd, args, "stop")
+}
+
+// machineSetDesiredState calls the API's machine_start or machine_stop RPC,
+// depending on the supplied action, for the machine named in the CLI
+// arguments
+func machineSetDesiredState(cmd *cobra.Command, args []string, action string) {
+	if len(args) != 1 {
+		fmt.Fprintf(
+			os.Stderr,
+			"Error: please provide a single argument: either specify a UUID "+
+				"or a name for the machine to %s\n",
+			action,
+		)
+		cmd.Help()
+		os.Exit(1)
+	}
+
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	req := &pb.MachineGetRequest{
+		Session: getSession(),
+		Search:  args[0],
+	}
+	var obj *pb.Machine
+	var err error
+	if action == "start" {
+		obj, err = client.MachineStart(context.Background(), req)
+	} else {
+		obj, err = client.MachineStop(context.Background(), req)
+	}
+	exitIfError(err)
+	if !quiet {
+		if verbose {
+			printMachine(obj)
+		} else {
+			fmt.Fprintf(os.Stdout, "ok\n")
+		}
+	}
+}
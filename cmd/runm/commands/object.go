@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+
+	"github.com/runmachine-io/runmachine/pkg/api/types"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	usageObjectTypeOption = `code of the type of object to operate on.
+
+Required for the get, list and delete commands. For the create and update
+commands, the object type is read from the "object_type" field of the YAML
+document if --type is not supplied.
+`
+
+	usageObjectSelectorOption = `optional selector expression to apply.
+
+--selector <selector expression>
+
+The selector is made up of predicates combined with AND (or &&), OR (or ||)
+and NOT (or !), with parentheses for grouping. Predicates separated only by
+whitespace are AND'd together.
+
+Each predicate is a $field$op$value expression where $field is one of
+partition, uuid, name or tag, or a property key (which may be prefixed with
+"prop:"). Type predicates may not be used. $op may be =, !=, in (...) or not
+in (...) and, for properties, >, >=, <, <= or ~ (regular expression match). A property key on its own matches
+objects having that property. An unquoted $value ending in an asterisk (*)
+matches by prefix.
+
+Examples:
+
+Find all objects with names beginning with "ubuntu" that are tagged "lts":
+
+--selector "name=ubuntu* tag=lts"
+
+Find all objects with an "os.distro" property of either "fedora" or "centos":
+
+--selector "os.distro in (fedora,centos)"
+`
+)
+
+var (
+	// CLI-provided --type option
+	cliObjectType string
+)
+
+var objectCommand = &cobra.Command{
+	Use:   "object",
+	Short: "Manipulate generic object information",
+}
+
+func init() {
+	objectCommand.PersistentFlags().StringVarP(
+		&cliObjectType,
+		"type", "t",
+		"",
+		usageObjectTypeOption,
+	)
+
+	objectCommand.AddCommand(objectListCommand)
+	objectCommand.AddCommand(objectGetCommand)
+	objectCommand.AddCommand(objectCreateCommand)
+	objectCommand.AddCommand(objectUpdateCommand)
+	objectCommand.AddCommand(objectDeleteCommand)
+}
+
+// objectTypeOrExit returns the object type supplied with the --type CLI
+// option, exiting with an error if it wasn't supplied
+func objectTypeOrExit(cmd *cobra.Command) string {
+	if cliObjectType == "" {
+		fmt.Fprintf(
+			os.Stderr,
+			"Error: please specify the type of object with --type\n",
+		)
+		cmd.Help()
+		os.Exit(1)
+	}
+	return cliObjectType
+}
+
+// readObjectDocumentOrExit reads the YAML document describing an object and
+// returns it with its object type set to the object type supplied with the
+// --type CLI option, if any
+func readObjectDocumentOrExit() []byte {
+	b := readInputDocumentOrExit()
+	if cliObjectType == "" {
+		return b
+	}
+	var obj types.Object
+	if err := yaml.Unmarshal(b, &obj); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	if obj.ObjectType != "" && obj.ObjectType != cliObjectType {
+		fmt.Fprintf(
+			os.Stderr,
+			"Error: document has object type %s but --type is %s\n",
+			obj.ObjectType, cliObjectType,
+		)
+		os.Exit(1)
+	}
+	obj.ObjectType = cliObjectType
+	b, err := yaml.Marshal(&obj)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	return b
+}
+
+func printObject(obj *pb.Object) {
+	fmt.Printf("Partition:   %s\n", obj.Partition)
+	fmt.Printf("Object Type: %s\n", obj.ObjectType)
+	if obj.Project != "" {
+		fmt.Printf("Project:     %s\n", obj.Project)
+	}
+	fmt.Printf("UUID:        %s\n", obj.Uuid)
+	fmt.Printf("Name:        %s\n", obj.Name)
+	if obj.Properties != nil {
+		fmt.Printf("Properties:\n")
+		for _, prop := range obj.Properties {
+			fmt.Printf("   %s=%s\n", prop.Key, prop.Value)
+		}
+	}
+	if obj.Tags != nil {
+		tags := strings.Join(obj.Tags, ",")
+		fmt.Printf("Tags:        %s\n", tags)
+	}
+}
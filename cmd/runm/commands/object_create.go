@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+var objectCreateCommand = &cobra.Command{
+	Use:   "create",
+	Short: "Create an object",
+	Run:   objectCreate,
+}
+
+func setupObjectCreateFlags() {
+	objectCreateCommand.Flags().StringVarP(
+		&cliObjectDocPath,
+		"file", "f",
+		"",
+		"optional filepath to YAML document to send.",
+	)
+}
+
+func init() {
+	setupObjectCreateFlags()
+}
+
+func objectCreate(cmd *cobra.Command, args []string) {
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	req := &pb.CreateRequest{
+		Session: getSession(),
+		Format:  pb.PayloadFormat_YAML,
+		Payload: readObjectDocumentOrExit(),
+	}
+
+	resp, err := client.ObjectCreate(context.Background(), req)
+	exitIfError(err)
+	obj := resp.Object
+	if !quiet {
+		if verbose {
+			printObject(obj)
+		} else {
+			fmt.Printf("%s\n", obj.Uuid)
+		}
+	}
+}
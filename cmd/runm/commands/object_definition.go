@@ -2,6 +2,10 @@ package commands
 
 import (
 	"fmt"
+	"os"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
 
 	pb "github.com/runmachine-io/runmachine/proto"
 )
@@ -57,3 +61,38 @@ func printObjectDefinition(obj *pb.ObjectDefinition) {
 		}
 	}
 }
+
+// printObjectDefinitionViolations lists the objects that do not satisfy an
+// object definition and exits with a non-zero status if there are any
+func printObjectDefinitionViolations(
+	violations []*pb.ObjectDefinitionViolation,
+) {
+	if len(violations) == 0 {
+		if !quiet {
+			fmt.Printf("ok: all existing objects satisfy the definition\n")
+		}
+		return
+	}
+	headers := []string{
+		"UUID",
+		"Name",
+		"Partition",
+		"Type",
+		"Errors",
+	}
+	rows := make([][]string, len(violations))
+	for x, v := range violations {
+		rows[x] = []string{
+			v.ObjectUuid,
+			v.ObjectName,
+			v.Partition,
+			v.Subtype,
+			strings.Join(v.Errors, "\n"),
+		}
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(headers)
+	table.AppendBulk(rows)
+	table.Render()
+	os.Exit(1)
+}
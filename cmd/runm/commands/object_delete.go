@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	objectDeleteUsage = `runm object delete may be called in two ways:
+
+The first way is to specify object identifiers (object name or UUID) as
+arguments. For example, to delete a runm.provider_group object with the UUID
+873109dc73e343e19e7bcc51a3ef4db5, you would call:
+
+  runm object delete --type runm.provider_group 873109dc73e343e19e7bcc51a3ef4db5
+
+Multiple identifiers may be passed to delete multiple objects. For example,
+to delete runm.provider_group objects with the names "rack1" and "rack2", you
+would call:
+
+  runm object delete --type runm.provider_group rack1 rack2
+
+The second way is to specify a "--selector <expression>" CLI option. All
+objects of the type matching the selector will be deleted. For example, to
+delete all runm.provider_group objects tagged "decommissioned", you would
+call:
+
+  runm object delete --type runm.provider_group --selector "tag=decommissioned"
+`
+)
+
+var objectDeleteCommand = &cobra.Command{
+	Use:   "delete [<id> ...]",
+	Short: "Delete objects of a type",
+	Run:   objectDelete,
+	Long:  objectDeleteUsage,
+}
+
+func setupObjectDeleteFlags() {
+	objectDeleteCommand.Flags().StringVarP(
+		&cliSelector,
+		"selector", "s",
+		"",
+		usageObjectSelectorOption,
+	)
+}
+
+func init() {
+	setupObjectDeleteFlags()
+}
+
+func objectDelete(cmd *cobra.Command, args []string) {
+	objType := objectTypeOrExit(cmd)
+	if len(args) == 0 && cliSelector == "" {
+		fmt.Fprintf(
+			os.Stderr,
+			"Error: please specify either one or more UUIDs or names of "+
+				"objects to delete or a --selector\n",
+		)
+		cmd.Help()
+		os.Exit(1)
+	}
+
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	req := &pb.ObjectDeleteRequest{
+		Session:    getSession(),
+		ObjectType: objType,
+		Search:     args,
+		Selector:   cliSelector,
+	}
+
+	resp, err := client.ObjectDelete(context.Background(), req)
+	exitIfError(err)
+	if !quiet {
+		if verbose {
+			fmt.Fprintf(os.Stdout, "deleted %d object(s)\n", resp.NumDeleted)
+		} else {
+			fmt.Fprintf(os.Stdout, "ok\n")
+		}
+	}
+}
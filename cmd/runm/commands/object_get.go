@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	usageObjectGet = `Show information for a single object
+
+Specify the type of the object with --type and a single CLI argument with the
+UUID or name of the object you wish to show:
+
+  runm object get --type runm.image 4f4f54c9bfb44cce9a02d4daf6f79ea3
+
+or
+
+  runm object get --type runm.image ubuntu-18.04
+
+NOTE: when using the second form, with the object's name instead of UUID, the
+user's session partition (and for project-scoped object types, the user's
+session project) is used to find the object.
+`
+)
+
+var objectGetCommand = &cobra.Command{
+	Use:   "get <search>",
+	Short: "Show information for a single object",
+	Run:   objectGet,
+	Long:  usageObjectGet,
+}
+
+func objectGet(cmd *cobra.Command, args []string) {
+	objType := objectTypeOrExit(cmd)
+	if len(args) != 1 {
+		fmt.Fprintf(
+			os.Stderr,
+			"Error: please provide a single argument: either specify a UUID "+
+				"or a name for the object to show\n",
+		)
+		cmd.Help()
+		os.Exit(1)
+	}
+
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	obj, err := client.ObjectGet(
+		context.Background(),
+		&pb.ObjectGetRequest{
+			Session:    getSession(),
+			ObjectType: objType,
+			Search:     args[0],
+		},
+	)
+	exitIfError(err)
+	printObject(obj)
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"io"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+var objectListCommand = &cobra.Command{
+	Use:   "list",
+	Short: "List information about objects of a type",
+	Run:   objectList,
+}
+
+func setupObjectListFlags() {
+	objectListCommand.Flags().StringVarP(
+		&cliSelector,
+		"selector", "s",
+		"",
+		usageObjectSelectorOption,
+	)
+}
+
+func init() {
+	setupObjectListFlags()
+}
+
+func objectList(cmd *cobra.Command, args []string) {
+	objType := objectTypeOrExit(cmd)
+
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	req := &pb.ObjectListRequest{
+		Session:    getSession(),
+		ObjectType: objType,
+		Selector:   cliSelector,
+	}
+	stream, err := client.ObjectList(context.Background(), req)
+	exitIfConnectErr(err)
+
+	msgs := make([]*pb.Object, 0)
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		exitIfError(err)
+		msgs = append(msgs, msg)
+	}
+	if len(msgs) == 0 {
+		exitNoRecords()
+	}
+	headers := []string{
+		"Partition",
+		"Project",
+		"UUID",
+		"Name",
+	}
+	rows := make([][]string, len(msgs))
+	for x, obj := range msgs {
+		rows[x] = []string{
+			obj.Partition,
+			obj.Project,
+			obj.Uuid,
+			obj.Name,
+		}
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(headers)
+	table.AppendBulk(rows)
+	table.Render()
+}
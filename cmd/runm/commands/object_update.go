@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	usageObjectUpdate = `Replace the properties and tags of an existing object
+
+The YAML document identifies the object to update with its "uuid" or "name"
+field. The object's properties and tags are replaced with the "properties" and
+"tags" in the YAML document. An object's partition, project, type and name may
+not be changed.
+`
+)
+
+var objectUpdateCommand = &cobra.Command{
+	Use:   "update",
+	Short: "Update an object",
+	Run:   objectUpdate,
+	Long:  usageObjectUpdate,
+}
+
+func setupObjectUpdateFlags() {
+	objectUpdateCommand.Flags().StringVarP(
+		&cliObjectDocPath,
+		"file", "f",
+		"",
+		"optional filepath to YAML document to send.",
+	)
+}
+
+func init() {
+	setupObjectUpdateFlags()
+}
+
+func objectUpdate(cmd *cobra.Command, args []string) {
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	req := &pb.UpdateRequest{
+		Session: getSession(),
+		Format:  pb.PayloadFormat_YAML,
+		Payload: readObjectDocumentOrExit(),
+	}
+
+	resp, err := client.ObjectUpdate(context.Background(), req)
+	exitIfError(err)
+	obj := resp.Object
+	if !quiet {
+		if verbose {
+			printObject(obj)
+		} else {
+			fmt.Printf("%s\n", obj.Uuid)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -10,6 +11,10 @@ import (
 )
 
 const (
+	// Prefix of a filter field expression that always filters on a property,
+	// even if the property key is the same as one of the other fields
+	propertyFieldPrefix = "prop:"
+
 	usageProviderFilterOption = `optional filter to apply.
 
 --filter <filter expression>
@@ -34,6 +39,21 @@ having a property with key $field regardless of the property's value.
 The $value should be an identifier or name for the $field. You can use an
 asterisk (*) to indicate a prefix match.
 
+To compare a property's value with something other than equality, use a
+prop:$key$op$value expression. $op may be any of the following:
+
+- =: the property value equals $value. A comma-separated list of values
+  matches a property value equal to any of them, and a $value ending in an
+  asterisk (*) matches property values beginning with $value
+- >, >=, <, <=: the property value is greater than, greater than or equal
+  to, less than, or less than or equal to $value. Properties defined as
+  integers or numbers are compared numerically; all other properties are
+  compared lexically
+- ~: the property value matches the regular expression $value
+
+A prop:$key expression without an $op filters on providers having a property
+with key $key, even if $key is one of the fields listed above.
+
 Examples:
 
 Find all runm.compute providers starting with "db":
@@ -65,6 +85,46 @@ string "AMD":
 
 --filter 'cpu.model="Intel Core i7"' \
 --filter "cpu.model=AMD*"
+
+Find all providers in row 3 or higher of a site in the "us-east" or "us-west"
+regions:
+
+--filter "prop:location.row>=3 prop:location.region=us-east,us-west"
+
+Find all providers with an "os" property value beginning with "linux":
+
+--filter "prop:os~^linux"
+`
+)
+
+const (
+	usageProviderSelectorOption = `optional selector expression to apply.
+
+--selector <selector expression>
+
+A selector is a more expressive alternative to --filter and may not be used
+along with it. The selector is made up of predicates combined with AND (or
+&&), OR (or ||) and NOT (or !), with parentheses for grouping. Predicates
+separated only by whitespace are AND'd together.
+
+Each predicate is a $field$op$value expression where $field is one of
+partition, type, uuid, name or tag, or a property key (which may be prefixed
+with "prop:"). $op may be =, !=, in (...) or not in (...) and, for properties,
+>, >=, <, <= or ~ (regular expression match). A property key on its own
+matches providers having that property. An unquoted $value ending in an
+asterisk (*) matches by prefix. Quote values containing whitespace or any of
+the characters ()=!<>~,&|
+
+Examples:
+
+Find all runm.compute providers in partitions part0 or part1 that have a GPU
+and are not in maintenance:
+
+--selector "type=runm.compute partition in (part0,part1) tag=gpu !tag=maintenance"
+
+Find all providers in row 3 or higher that run Linux or have no os property:
+
+--selector "prop:location.row>=3 and (prop:os~^linux or not prop:os)"
 `
 )
 
@@ -92,7 +152,29 @@ func buildProviderFilters() []*pb.ProviderFilter {
 		filter := &pb.ProviderFilter{}
 		reqPropItems := make([]*pb.Property, 0)
 		reqPropKeys := make([]string, 0)
+		reqPropExprs := make([]*pb.PropertyExpression, 0)
 		for _, fieldExpr := range fieldExprs {
+			if strings.HasPrefix(fieldExpr, propertyFieldPrefix) {
+				// The user supplied something like --filter
+				// "prop:location.row>=3" which is always a filter on the
+				// property with key "location.row"
+				expr, err := parsePropertyExpression(
+					strings.TrimPrefix(fieldExpr, propertyFieldPrefix),
+				)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+					os.Exit(1)
+				}
+				if expr == nil {
+					reqPropKeys = append(
+						reqPropKeys,
+						strings.TrimPrefix(fieldExpr, propertyFieldPrefix),
+					)
+				} else {
+					reqPropExprs = append(reqPropExprs, expr)
+				}
+				continue
+			}
 			kvs := strings.SplitN(fieldExpr, "=", 2)
 			field := kvs[0]
 			if len(kvs) == 1 {
@@ -127,16 +209,29 @@ func buildProviderFilters() []*pb.ProviderFilter {
 				}
 			default:
 				// All other $fields are property key filters...
+				if usePrefix {
+					reqPropExprs = append(
+						reqPropExprs,
+						&pb.PropertyExpression{
+							Key:      field,
+							Operator: pb.PropertyOperator_PROPERTY_PREFIX,
+							Values:   []string{value},
+						},
+					)
+					continue
+				}
 				reqPropItems = append(
 					reqPropItems,
 					&pb.Property{Key: field, Value: value},
 				)
 			}
 		}
-		if len(reqPropItems) > 0 || len(reqPropKeys) > 0 {
+		if len(reqPropItems) > 0 || len(reqPropKeys) > 0 ||
+			len(reqPropExprs) > 0 {
 			filter.PropertyFilter = &pb.PropertyFilter{
-				RequireItems: reqPropItems,
-				RequireKeys:  reqPropKeys,
+				RequireItems:       reqPropItems,
+				RequireKeys:        reqPropKeys,
+				RequireExpressions: reqPropExprs,
 			}
 		}
 		filters = append(filters, filter)
@@ -144,12 +239,74 @@ func buildProviderFilters() []*pb.ProviderFilter {
 	return filters
 }
 
+// propertyExpressionOperators are the operators that may follow the property
+// key in a prop:$key$op$value filter expression. Longer operators must come
+// before the operators they begin with.
+var propertyExpressionOperators = []struct {
+	token    string
+	operator pb.PropertyOperator
+}{
+	{">=", pb.PropertyOperator_PROPERTY_GREATER_THAN_EQUAL},
+	{"<=", pb.PropertyOperator_PROPERTY_LESS_THAN_EQUAL},
+	{">", pb.PropertyOperator_PROPERTY_GREATER_THAN},
+	{"<", pb.PropertyOperator_PROPERTY_LESS_THAN},
+	{"~", pb.PropertyOperator_PROPERTY_REGEX},
+	{"=", pb.PropertyOperator_PROPERTY_EQUAL},
+}
+
+// parsePropertyExpression parses the $key$op$value part of a prop: filter
+// expression into a PropertyExpression. Returns nil if the supplied string is
+// only a property key.
+//
+// An = operator with a comma-separated list of values is turned into an IN
+// expression and an = operator with a value ending in an asterisk is turned
+// into a PREFIX expression.
+func parsePropertyExpression(expr string) (*pb.PropertyExpression, error) {
+	opStart := strings.IndexAny(expr, "<>~=")
+	if opStart == -1 {
+		return nil, nil
+	}
+	key := expr[:opStart]
+	if key == "" {
+		return nil, fmt.Errorf("missing property key in filter %q", expr)
+	}
+	rest := expr[opStart:]
+	for _, op := range propertyExpressionOperators {
+		if !strings.HasPrefix(rest, op.token) {
+			continue
+		}
+		value := strings.TrimPrefix(rest, op.token)
+		if value == "" {
+			return nil, fmt.Errorf("missing value in filter %q", expr)
+		}
+		res := &pb.PropertyExpression{
+			Key:      key,
+			Operator: op.operator,
+			Values:   []string{value},
+		}
+		if op.operator == pb.PropertyOperator_PROPERTY_EQUAL {
+			if strings.Contains(value, ",") {
+				res.Operator = pb.PropertyOperator_PROPERTY_IN
+				res.Values = strings.Split(value, ",")
+			} else if strings.HasSuffix(value, "*") {
+				res.Operator = pb.PropertyOperator_PROPERTY_PREFIX
+				res.Values = []string{strings.TrimRight(value, "*")}
+			}
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("unknown operator in filter %q", expr)
+}
+
 func printProvider(obj *pb.Provider) {
 	fmt.Printf("Partition:     %s\n", obj.Partition.Uuid)
 	fmt.Printf("Provider Type: %s\n", obj.ProviderType.Code)
 	fmt.Printf("UUID:          %s\n", obj.Uuid)
 	fmt.Printf("Name:          %s\n", obj.Name)
 	fmt.Printf("Generation:    %d\n", obj.Generation)
+	if obj.Origin != "" {
+		fmt.Printf("Origin:        %s\n", obj.Origin)
+	}
 	if obj.Parent != nil {
 		if obj.Parent.Uuid != "" {
 			fmt.Printf("Parent:        %s\n", obj.Parent.Uuid)
@@ -1,7 +1,12 @@
 package commands
 
 import (
+	"io"
+
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	pb "github.com/runmachine-io/runmachine/proto"
 )
 
 var (
@@ -18,4 +23,70 @@ var providerDefinitionCommand = &cobra.Command{
 func init() {
 	providerDefinitionCommand.AddCommand(providerDefinitionGetCommand)
 	providerDefinitionCommand.AddCommand(providerDefinitionSetCommand)
+	providerDefinitionCommand.AddCommand(providerDefinitionHistoryCommand)
+	providerDefinitionCommand.AddCommand(providerDefinitionDiffCommand)
+	providerDefinitionCommand.AddCommand(providerDefinitionRollbackCommand)
+}
+
+// setupProviderDefinitionKeyFlags adds the CLI options that select which
+// provider definition a command operates on
+func setupProviderDefinitionKeyFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(
+		&cliProviderDefinitionGlobal,
+		"global", "g",
+		false,
+		"Use the global default definition for providers.",
+	)
+	cmd.Flags().StringVarP(
+		&cliProviderDefinitionPartition,
+		"partition", "",
+		"",
+		"Identifier of partition with an override provider definition.",
+	)
+	cmd.Flags().StringVarP(
+		&cliProviderDefinitionType,
+		"type", "t",
+		"",
+		"Optional provider type.",
+	)
+}
+
+// providerDefinitionPartition returns the partition identifier to use for a
+// provider definition based on the --global and --partition CLI options,
+// defaulting to the user's session partition
+func providerDefinitionPartition(sessionPartition string) string {
+	if cliProviderDefinitionGlobal {
+		return ""
+	}
+	if cliProviderDefinitionPartition == "" {
+		return sessionPartition
+	}
+	return cliProviderDefinitionPartition
+}
+
+// providerDefinitionHistory returns all revisions of the provider definition
+// selected by the CLI options, oldest first
+func providerDefinitionHistory(
+	client pb.RunmAPIClient,
+	session *pb.Session,
+) []*pb.ObjectDefinition {
+	req := &pb.DefinitionHistoryRequest{
+		Session:    session,
+		ObjectType: "runm.provider",
+		Partition:  providerDefinitionPartition(session.Partition),
+		Subtype:    cliProviderDefinitionType,
+	}
+	stream, err := client.DefinitionHistory(context.Background(), req)
+	exitIfConnectErr(err)
+
+	defs := make([]*pb.ObjectDefinition, 0)
+	for {
+		def, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		exitIfError(err)
+		defs = append(defs, def)
+	}
+	return defs
 }
@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/runmachine-io/runmachine/pkg/api/types"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	usageProviderDefinitionDiff = `Show changes between revisions of a provider definition
+
+With no --from or --to CLI options, shows what the latest revision of the
+provider definition changed from the revision before it:
+
+  runm provider definition diff --global
+
+Use the --to CLI option to show what a particular revision changed:
+
+  runm provider definition diff --global --to 3
+
+Use the --from CLI option to compare two revisions that are not next to each
+other. If --to is not also supplied, the revision is compared to the latest
+revision:
+
+  runm provider definition diff --global --from 1
+
+The --global, --partition and --type CLI options select the provider
+definition in the same way as for the runm provider definition set command.
+`
+)
+
+var (
+	cliProviderDefinitionDiffFrom uint64
+	cliProviderDefinitionDiffTo   uint64
+)
+
+var providerDefinitionDiffCommand = &cobra.Command{
+	Use:   "diff",
+	Short: "Show changes between revisions of a provider definition",
+	Run:   providerDefinitionDiff,
+	Long:  usageProviderDefinitionDiff,
+}
+
+func setupProviderDefinitionDiffFlags() {
+	setupProviderDefinitionKeyFlags(providerDefinitionDiffCommand)
+	providerDefinitionDiffCommand.Flags().Uint64VarP(
+		&cliProviderDefinitionDiffFrom,
+		"from", "",
+		0,
+		"Revision to compare from.",
+	)
+	providerDefinitionDiffCommand.Flags().Uint64VarP(
+		&cliProviderDefinitionDiffTo,
+		"to", "",
+		0,
+		"Revision to compare to. Defaults to the latest revision.",
+	)
+}
+
+func init() {
+	setupProviderDefinitionDiffFlags()
+}
+
+// findRevision returns the object definition with the supplied revision from a
+// list of object definition revisions, or exits if there isn't one
+func findRevision(
+	defs []*pb.ObjectDefinition,
+	revision uint64,
+) *pb.ObjectDefinition {
+	for _, def := range defs {
+		if def.Revision == revision {
+			return def
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Error: revision %d not found\n", revision)
+	os.Exit(1)
+	return nil
+}
+
+func providerDefinitionDiff(cmd *cobra.Command, args []string) {
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	session := getSession()
+
+	defs := providerDefinitionHistory(client, session)
+	if len(defs) == 0 {
+		exitNoRecords()
+	}
+
+	to := defs[len(defs)-1]
+	if cliProviderDefinitionDiffTo != 0 {
+		to = findRevision(defs, cliProviderDefinitionDiffTo)
+	}
+	if cliProviderDefinitionDiffFrom == 0 {
+		// Each revision stores what it changed from the revision before it
+		fmt.Print(to.Diff)
+		return
+	}
+	from := findRevision(defs, cliProviderDefinitionDiffFrom)
+	fmt.Print(types.ObjectDefinitionDiff(
+		from,
+		to,
+		fmt.Sprintf("revision %d", from.Revision),
+		fmt.Sprintf("revision %d", to.Revision),
+	))
+}
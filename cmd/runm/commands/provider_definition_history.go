@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	usageProviderDefinitionHistory = `Show the revisions of a provider definition
+
+Every time a provider definition is set or rolled back, a new revision of the
+provider definition is saved. This command lists those revisions, oldest
+first, along with the user that created each revision and when.
+
+The --global, --partition and --type CLI options select the provider
+definition in the same way as for the runm provider definition set command:
+
+  runm provider definition history --global --type runm.compute
+
+NOTE: Specifying neither --global or --partition CLI options will show the
+revisions of the definition for providers in the user's session partition.
+`
+)
+
+var providerDefinitionHistoryCommand = &cobra.Command{
+	Use:   "history",
+	Short: "Show the revisions of a provider definition",
+	Run:   providerDefinitionHistoryList,
+	Long:  usageProviderDefinitionHistory,
+}
+
+func init() {
+	setupProviderDefinitionKeyFlags(providerDefinitionHistoryCommand)
+}
+
+func providerDefinitionHistoryList(cmd *cobra.Command, args []string) {
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	session := getSession()
+
+	defs := providerDefinitionHistory(client, session)
+	if len(defs) == 0 {
+		exitNoRecords()
+	}
+	headers := []string{
+		"Revision",
+		"Author",
+		"Created",
+		"Rollback Of",
+	}
+	rows := make([][]string, len(defs))
+	for x, def := range defs {
+		created := ""
+		if def.CreatedAt != 0 {
+			created = time.Unix(def.CreatedAt, 0).UTC().Format(time.RFC3339)
+		}
+		rollbackOf := ""
+		if def.RollbackOf != 0 {
+			rollbackOf = strconv.FormatUint(def.RollbackOf, 10)
+		}
+		rows[x] = []string{
+			strconv.FormatUint(def.Revision, 10),
+			def.Author,
+			created,
+			rollbackOf,
+		}
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(headers)
+	table.AppendBulk(rows)
+	table.Render()
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	usageProviderDefinitionRollback = `Roll back a provider definition to an earlier revision
+
+The schema and property permissions of the supplied revision are saved as a new
+revision of the provider definition. Use the runm provider definition history
+command to find the revision to roll back to:
+
+  runm provider definition rollback --global --type runm.compute 2
+
+The --global, --partition and --type CLI options select the provider
+definition in the same way as for the runm provider definition set command.
+`
+)
+
+var providerDefinitionRollbackCommand = &cobra.Command{
+	Use:   "rollback <revision>",
+	Short: "Roll back a provider definition to an earlier revision",
+	Args:  cobra.ExactArgs(1),
+	Run:   providerDefinitionRollback,
+	Long:  usageProviderDefinitionRollback,
+}
+
+func init() {
+	setupProviderDefinitionKeyFlags(providerDefinitionRollbackCommand)
+}
+
+func providerDefinitionRollback(cmd *cobra.Command, args []string) {
+	revision, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil || revision == 0 {
+		fmt.Fprintf(os.Stderr, "Error: invalid revision %q\n", args[0])
+		os.Exit(1)
+	}
+
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	session := getSession()
+
+	req := &pb.DefinitionRollbackRequest{
+		Session:    session,
+		ObjectType: "runm.provider",
+		Partition:  providerDefinitionPartition(session.Partition),
+		Subtype:    cliProviderDefinitionType,
+		Revision:   revision,
+	}
+	resp, err := client.DefinitionRollback(context.Background(), req)
+	exitIfError(err)
+	obj := resp.ObjectDefinition
+	if !quiet {
+		fmt.Printf("ok (revision %d)\n", obj.Revision)
+		if verbose {
+			fmt.Print(obj.Diff)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package commands
 
 import (
 	"fmt"
-
 	"github.com/spf13/cobra"
 	"golang.org/x/net/context"
 
@@ -43,9 +42,31 @@ The --type CLI option may be used together with either the --global or
 --partition CLI option. If neither the --global nor --partition CLI options are
 supplied and the --type CLI option is used, then the provider definition for
 the user's session partition and the supplied provider type is set.
+
+Changing a provider definition can leave existing providers that do not
+satisfy the new definition. To see which existing providers would not satisfy
+a new provider definition without saving it, use the --dry-run CLI option:
+
+  runm provider definition set --type "runm.compute" --dry-run -f def.yaml
+
+The providers that do not satisfy the definition are listed along with the
+reasons why, and the command exits with a non-zero status if there are any.
+
+To save the provider definition only if all existing providers satisfy it, use
+the --enforce CLI option:
+
+  runm provider definition set --type "runm.compute" --enforce -f def.yaml
+
+The providers are checked before the definition is saved, so a provider created
+or updated while the definition is being set is not checked against it.
 `
 )
 
+var (
+	cliProviderDefinitionDryRun  bool
+	cliProviderDefinitionEnforce bool
+)
+
 var providerDefinitionSetCommand = &cobra.Command{
 	Use:   "set",
 	Short: "Define the schema for providers",
@@ -78,6 +99,19 @@ func setupProviderDefinitionSetFlags() {
 		"",
 		"optional filepath to YAML document to send.",
 	)
+	providerDefinitionSetCommand.Flags().BoolVarP(
+		&cliProviderDefinitionDryRun,
+		"dry-run", "",
+		false,
+		"Show existing providers that do not satisfy the definition "+
+			"instead of saving it.",
+	)
+	providerDefinitionSetCommand.Flags().BoolVarP(
+		&cliProviderDefinitionEnforce,
+		"enforce", "",
+		false,
+		"Only save the definition if all existing providers satisfy it.",
+	)
 }
 
 func init() {
@@ -108,10 +142,16 @@ func providerDefinitionSet(cmd *cobra.Command, args []string) {
 		Payload:      readInputDocumentOrExit(),
 		Partition:    argPartition,
 		ProviderType: cliProviderDefinitionType,
+		DryRun:       cliProviderDefinitionDryRun,
+		Enforce:      cliProviderDefinitionEnforce,
 	}
 
 	resp, err := client.ProviderDefinitionSet(context.Background(), req)
 	exitIfError(err)
+	if cliProviderDefinitionDryRun {
+		printObjectDefinitionViolations(resp.Violations)
+		return
+	}
 	obj := resp.ObjectDefinition
 	if !quiet {
 		fmt.Printf("ok\n")
@@ -31,6 +31,12 @@ providers matching the filter expression will be deleted. For example, if you
 wanted to delete all providers in a partition called "part42", you would call:
 
   runm provider delete --filter "partition=part42"
+
+A "--selector <expression>" CLI option may be used instead of "--filter". For
+example, to delete all providers in partition "part42" that are not tagged
+"keep", you would call:
+
+  runm provider delete --selector "partition=part42 and not tag=keep"
 `
 )
 
@@ -48,6 +54,12 @@ func setupProviderDeleteFlags() {
 		nil,
 		usageProviderFilterOption,
 	)
+	providerDeleteCommand.Flags().StringVarP(
+		&cliSelector,
+		"selector", "s",
+		"",
+		usageProviderSelectorOption,
+	)
 }
 
 func init() {
@@ -68,6 +80,7 @@ func providerDelete(cmd *cobra.Command, args []string) {
 	// expressions *or* each CLI argument passed as a separate lookup
 	if len(args) == 0 {
 		req.Any = buildProviderFilters()
+		req.Selector = cliSelector
 	} else {
 		// We treat each argument as a Name-or-UUID filter
 		filters := make([]*pb.ProviderFilter, len(args))
@@ -24,6 +24,12 @@ func setupProviderListFlags() {
 		nil,
 		usageProviderFilterOption,
 	)
+	providerListCommand.Flags().StringVarP(
+		&cliSelector,
+		"selector", "s",
+		"",
+		usageProviderSelectorOption,
+	)
 }
 
 func init() {
@@ -36,8 +42,9 @@ func providerList(cmd *cobra.Command, args []string) {
 
 	client := pb.NewRunmAPIClient(conn)
 	req := &pb.ProviderListRequest{
-		Session: getSession(),
-		Any:     buildProviderFilters(),
+		Session:  getSession(),
+		Any:      buildProviderFilters(),
+		Selector: cliSelector,
 	}
 	stream, err := client.ProviderList(context.Background(), req)
 	exitIfConnectErr(err)
@@ -54,12 +61,24 @@ func providerList(cmd *cobra.Command, args []string) {
 	if len(msgs) == 0 {
 		exitNoRecords()
 	}
+	// Only show the origin column when at least one provider was returned
+	// from a peered runmachine deployment
+	showOrigin := false
+	for _, obj := range msgs {
+		if obj.Origin != "" {
+			showOrigin = true
+			break
+		}
+	}
 	headers := []string{
 		"Partition",
 		"Provider Type",
 		"UUID",
 		"Name",
 	}
+	if showOrigin {
+		headers = append(headers, "Origin")
+	}
 	rows := make([][]string, len(msgs))
 	for x, obj := range msgs {
 		rows[x] = []string{
@@ -68,6 +87,9 @@ func providerList(cmd *cobra.Command, args []string) {
 			obj.Uuid,
 			obj.Name,
 		}
+		if showOrigin {
+			rows[x] = append(rows[x], obj.Origin)
+		}
 	}
 	table := tablewriter.NewWriter(os.Stdout)
 	table.SetHeader(headers)
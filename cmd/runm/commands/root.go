@@ -108,10 +108,15 @@ func addConnectFlags() {
 func init() {
 	addConnectFlags()
 
+	RootCommand.AddCommand(definitionCommand)
 	RootCommand.AddCommand(helpEnvCommand)
+	RootCommand.AddCommand(imageCommand)
+	RootCommand.AddCommand(machineCommand)
+	RootCommand.AddCommand(objectCommand)
 	RootCommand.AddCommand(partitionCommand)
 	RootCommand.AddCommand(providerCommand)
 	RootCommand.AddCommand(providerTypeCommand)
+	RootCommand.AddCommand(statusCommand)
 	RootCommand.SilenceUsage = true
 
 	clientLog = log.New(ioutil.Discard, "", 0)
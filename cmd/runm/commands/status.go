@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+var statusCommand = &cobra.Command{
+	Use:   "status",
+	Short: "Show the health of runmachine service endpoints",
+	Run:   showStatus,
+	Long: `Show the health of each runm-api, runm-metadata and runm-resource
+service endpoint registered in the service registry.
+
+Exits with a non-zero return code if any endpoint is not SERVING.`,
+}
+
+func showStatus(cmd *cobra.Command, args []string) {
+	conn := connect()
+	defer conn.Close()
+
+	client := pb.NewRunmAPIClient(conn)
+	req := &pb.StatusRequest{
+		Session: getSession(),
+	}
+	resp, err := client.Status(context.Background(), req)
+	exitIfError(err)
+
+	if len(resp.Endpoints) == 0 {
+		exitNoRecords()
+	}
+	healthy := true
+	headers := []string{
+		"Service",
+		"Address",
+		"Status",
+		"Error",
+	}
+	rows := make([][]string, len(resp.Endpoints))
+	for x, ep := range resp.Endpoints {
+		if ep.Status != "SERVING" {
+			healthy = false
+		}
+		rows[x] = []string{
+			ep.Service,
+			ep.Address,
+			ep.Status,
+			ep.Error,
+		}
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(headers)
+	table.AppendBulk(rows)
+	table.Render()
+	if !healthy {
+		os.Exit(1)
+	}
+}
@@ -6,8 +6,13 @@ import "github.com/golang/protobuf/proto"
 // parse a protobuf message. Purposefully made to function like database/sql
 // package's Rows interface.  Useful for test mocking and abstracting a storage
 // layer.
+//
+// Next returns true if there is a record to Scan. Scan reads the record into
+// the supplied message and moves on to the next record. When Next returns
+// false, Err returns the error, if any, that ended the iteration.
 type Cursor interface {
 	Next() bool
 	Close() error
 	Scan(msg proto.Message) error
+	Err() error
 }
@@ -0,0 +1,247 @@
+// Package allinone runs the runm-metadata, runm-resource, runm-control and
+// runm-api services in a single process for development and testing. The
+// metadata service and the task queue of runm-control are backed by an
+// embedded etcd server, runm-resource by a SQLite database file unless
+// another database is configured, and the services find each other using an
+// in-process service registry instead of gsr, so no outside services need to
+// be running.
+package allinone
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/coreos/etcd/embed"
+	"google.golang.org/grpc"
+
+	"github.com/runmachine-io/runmachine/pkg/allinone/config"
+	apiserver "github.com/runmachine-io/runmachine/pkg/api/server"
+	apiconfig "github.com/runmachine-io/runmachine/pkg/api/server/config"
+	"github.com/runmachine-io/runmachine/pkg/blobstore"
+	ctlserver "github.com/runmachine-io/runmachine/pkg/control/server"
+	ctlconfig "github.com/runmachine-io/runmachine/pkg/control/server/config"
+	"github.com/runmachine-io/runmachine/pkg/embedetcd"
+	"github.com/runmachine-io/runmachine/pkg/logging"
+	metaserver "github.com/runmachine-io/runmachine/pkg/metadata/server"
+	metaconfig "github.com/runmachine-io/runmachine/pkg/metadata/server/config"
+	"github.com/runmachine-io/runmachine/pkg/registry"
+	resserver "github.com/runmachine-io/runmachine/pkg/resource/server"
+	resconfig "github.com/runmachine-io/runmachine/pkg/resource/server/config"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	apiServiceName      = "runmachine-api"
+	metadataServiceName = "runmachine-metadata"
+	resourceServiceName = "runmachine-resource"
+	controlServiceName  = "runmachine-control"
+	etcdName            = "runm-allinone"
+	etcdKeyPrefix       = "runm-metadata/"
+	controlKeyPrefix    = "runm-control/"
+	etcdStartTimeout    = 60 * time.Second
+	connectTimeout      = 30 * time.Second
+	lbPolicy            = "round_robin"
+	taskRetention       = 24 * time.Hour
+	taskLease           = time.Minute
+)
+
+// Server runs the runm-metadata, runm-resource, runm-control and runm-api
+// services. All four services are served by the same gRPC server.
+type Server struct {
+	log      *logging.Logs
+	cfg      *config.Config
+	etcd     *embed.Etcd
+	registry *registry.Local
+	metadata *metaserver.Server
+	resource *resserver.Server
+	control  *ctlserver.Server
+	api      *apiserver.Server
+}
+
+// Close stops all services and the embedded etcd server
+func (s *Server) Close() {
+	if s.api != nil {
+		s.api.Close()
+	}
+	if s.control != nil {
+		s.control.Close()
+	}
+	if s.resource != nil {
+		s.resource.Close()
+	}
+	if s.metadata != nil {
+		s.metadata.Close()
+	}
+	if s.etcd != nil {
+		s.etcd.Close()
+	}
+}
+
+// Register registers the RunmMetadata, RunmResource, RunmControl and RunmAPI
+// services with the supplied gRPC server
+func (s *Server) Register(gs *grpc.Server) {
+	pb.RegisterRunmMetadataServer(gs, s.metadata)
+	pb.RegisterRunmResourceServer(gs, s.resource)
+	pb.RegisterRunmControlServer(gs, s.control)
+	pb.RegisterRunmAPIServer(gs, s.api)
+}
+
+// HealthCheck returns nil if the dependencies of all services are healthy
+func (s *Server) HealthCheck(ctx context.Context) error {
+	if err := s.metadata.HealthCheck(ctx); err != nil {
+		return err
+	}
+	if err := s.resource.HealthCheck(ctx); err != nil {
+		return err
+	}
+	if err := s.control.HealthCheck(ctx); err != nil {
+		return err
+	}
+	return s.api.HealthCheck(ctx)
+}
+
+// New starts the embedded etcd server and returns a Server running all
+// runmachine services. The services are registered in the in-process service
+// registry at the configured bind address but are not served until the Server
+// is registered with a gRPC server listening at that address.
+func New(
+	cfg *config.Config,
+	log *logging.Logs,
+) (*Server, error) {
+	s := &Server{
+		log:      log,
+		cfg:      cfg,
+		registry: registry.NewLocal(),
+	}
+
+	// The embedded etcd server and the SQLite database of runm-resource both
+	// keep their files in the data directory
+	if err := os.MkdirAll(cfg.DataDir, 0700); err != nil {
+		return nil, fmt.Errorf(
+			"failed to create data directory %s: %v", cfg.DataDir, err,
+		)
+	}
+
+	clientURL, err := s.startEtcd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start embedded etcd: %v", err)
+	}
+
+	s.metadata, err = metaserver.New(s.metadataConfig(clientURL), log, s.registry)
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to create runm-metadata server: %v", err)
+	}
+	s.resource, err = resserver.New(s.resourceConfig(), log, s.registry)
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to create runm-resource server: %v", err)
+	}
+	s.control, err = ctlserver.New(s.controlConfig(clientURL), log, s.registry)
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to create runm-control server: %v", err)
+	}
+	s.api, err = apiserver.New(s.apiConfig(), log, s.registry)
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to create runm-api server: %v", err)
+	}
+	return s, nil
+}
+
+// startEtcd starts a single-member etcd cluster listening on the loopback
+// interface and returns the URL clients should use to connect to it
+func (s *Server) startEtcd() (*url.URL, error) {
+	clientURL := &url.URL{
+		Scheme: "http",
+		Host:   fmt.Sprintf("127.0.0.1:%d", s.cfg.EtcdClientPort),
+	}
+	peerURL := &url.URL{
+		Scheme: "http",
+		Host:   fmt.Sprintf("127.0.0.1:%d", s.cfg.EtcdPeerPort),
+	}
+
+	dir := filepath.Join(s.cfg.DataDir, "etcd")
+	s.log.L2("starting embedded etcd server with data dir %s.", dir)
+	e, err := embedetcd.Start(
+		etcdName, dir, *clientURL, *peerURL, etcdStartTimeout,
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.etcd = e
+	s.log.L2("embedded etcd server listening on %s.", clientURL)
+	return clientURL, nil
+}
+
+func (s *Server) metadataConfig(etcdURL *url.URL) *metaconfig.Config {
+	return &metaconfig.Config{
+		BindHost:                  s.cfg.BindHost,
+		BindPort:                  s.cfg.BindPort,
+		ServiceName:               metadataServiceName,
+		EtcdEndpoints:             []string{etcdURL.String()},
+		EtcdKeyPrefix:             etcdKeyPrefix,
+		EtcdConnectTimeoutSeconds: connectTimeout,
+		EtcdRequestTimeoutSeconds: time.Second,
+		EtcdDialTimeoutSeconds:    time.Second,
+		BootstrapToken:            s.cfg.BootstrapToken,
+	}
+}
+
+func (s *Server) resourceConfig() *resconfig.Config {
+	return &resconfig.Config{
+		BindHost:                     s.cfg.BindHost,
+		BindPort:                     s.cfg.BindPort,
+		ServiceName:                  resourceServiceName,
+		MetadataServiceName:          metadataServiceName,
+		StorageConnectTimeoutSeconds: connectTimeout,
+		StorageDSN:                   s.storageDSN(),
+		LBPolicy:                     lbPolicy,
+	}
+}
+
+// storageDSN returns the configured DSN of the runm-resource database or, if
+// none is configured, the DSN of a SQLite database file in the data directory
+func (s *Server) storageDSN() string {
+	if s.cfg.StorageDSN != "" {
+		return s.cfg.StorageDSN
+	}
+	return "sqlite://" + filepath.Join(s.cfg.DataDir, "resource.db")
+}
+
+func (s *Server) controlConfig(etcdURL *url.URL) *ctlconfig.Config {
+	return &ctlconfig.Config{
+		BindHost:                  s.cfg.BindHost,
+		BindPort:                  s.cfg.BindPort,
+		ServiceName:               controlServiceName,
+		MetadataServiceName:       metadataServiceName,
+		ResourceServiceName:       resourceServiceName,
+		EtcdEndpoints:             []string{etcdURL.String()},
+		EtcdKeyPrefix:             controlKeyPrefix,
+		EtcdConnectTimeoutSeconds: connectTimeout,
+		EtcdRequestTimeoutSeconds: time.Second,
+		EtcdDialTimeoutSeconds:    time.Second,
+		LBPolicy:                  lbPolicy,
+		TaskRetentionSeconds:      taskRetention,
+		TaskLeaseSeconds:          taskLease,
+	}
+}
+
+func (s *Server) apiConfig() *apiconfig.Config {
+	return &apiconfig.Config{
+		BindHost:            s.cfg.BindHost,
+		BindPort:            s.cfg.BindPort,
+		ServiceName:         apiServiceName,
+		MetadataServiceName: metadataServiceName,
+		ResourceServiceName: resourceServiceName,
+		Peers:               map[string]string{},
+		LBPolicy:            lbPolicy,
+		ImageStoreDriver:    blobstore.DriverLocal,
+		ImageStorePath:      filepath.Join(s.cfg.DataDir, "images"),
+	}
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	flag "github.com/ogier/pflag"
+
+	"github.com/jaypipes/envutil"
+	"github.com/runmachine-io/runmachine/pkg/util"
+)
+
+const (
+	defaultBindPort       = 10000
+	defaultEtcdClientPort = 22379
+	defaultEtcdPeerPort   = 22380
+	defaultStorageDSN     = ""
+	defaultBootstrapToken = ""
+	defaultMetricsAddress = ""
+)
+
+var (
+	defaultBindHost = util.BindHost()
+	defaultDataDir  = filepath.Join(os.TempDir(), "runm-allinone")
+)
+
+type Config struct {
+	BindHost string
+	BindPort int
+	// Directory under which the embedded etcd and SQLite databases and uploaded
+	// images are kept
+	DataDir string
+	// Port the embedded etcd server listens on for client connections. The
+	// embedded etcd server only listens on the loopback interface.
+	EtcdClientPort int
+	// Port the embedded etcd server listens on for peer connections
+	EtcdPeerPort int
+	// DSN of the database used by runm-resource. If empty, runm-resource uses
+	// a SQLite database file in DataDir.
+	StorageDSN string
+	// The value of a one-time-use token that can be used to bootstrap a
+	// runmachine deployment with a new partition by an unauthenticated user
+	BootstrapToken string
+	// Address (host:port) of the HTTP listener serving Prometheus metrics at
+	// /metrics. Metrics are not served if empty.
+	MetricsAddress string
+}
+
+func ConfigFromOpts() *Config {
+	optHost := flag.String(
+		"bind-address",
+		envutil.WithDefault(
+			"RUNM_ALLINONE_BIND_HOST", defaultBindHost,
+		),
+		"The host address the server will listen on",
+	)
+	optPort := flag.Int(
+		"bind-port",
+		envutil.WithDefaultInt(
+			"RUNM_ALLINONE_BIND_PORT", defaultBindPort,
+		),
+		"The port the server will listen on",
+	)
+	optDataDir := flag.String(
+		"data-dir",
+		envutil.WithDefault(
+			"RUNM_ALLINONE_DATA_DIR", defaultDataDir,
+		),
+		"Directory under which the embedded etcd and SQLite databases and "+
+			"uploaded images are kept",
+	)
+	optEtcdClientPort := flag.Int(
+		"etcd-client-port",
+		envutil.WithDefaultInt(
+			"RUNM_ALLINONE_ETCD_CLIENT_PORT", defaultEtcdClientPort,
+		),
+		"The loopback port the embedded etcd server listens on for clients",
+	)
+	optEtcdPeerPort := flag.Int(
+		"etcd-peer-port",
+		envutil.WithDefaultInt(
+			"RUNM_ALLINONE_ETCD_PEER_PORT", defaultEtcdPeerPort,
+		),
+		"The loopback port the embedded etcd server listens on for peers",
+	)
+	optStorageDSN := flag.String(
+		"storage-dsn",
+		envutil.WithDefault(
+			"RUNM_ALLINONE_STORAGE_DSN", defaultStorageDSN,
+		),
+		"DSN of the database used by runm-resource. Defaults to a SQLite "+
+			"database file in the data directory.",
+	)
+	optBootstrapToken := flag.String(
+		"bootstrap-token",
+		envutil.WithDefault(
+			"RUNM_ALLINONE_BOOTSTRAP_TOKEN", defaultBootstrapToken,
+		),
+		"One-time-use token that can be used to bootstrap a new partition",
+	)
+	optMetricsAddress := flag.String(
+		"metrics-address",
+		envutil.WithDefault(
+			"RUNM_ALLINONE_METRICS_ADDRESS", defaultMetricsAddress,
+		),
+		"Address (host:port) to serve Prometheus metrics on. "+
+			"Metrics are not served if empty.",
+	)
+
+	flag.Parse()
+
+	return &Config{
+		BindHost:       *optHost,
+		BindPort:       *optPort,
+		DataDir:        *optDataDir,
+		EtcdClientPort: *optEtcdClientPort,
+		EtcdPeerPort:   *optEtcdPeerPort,
+		StorageDSN:     *optStorageDSN,
+		BootstrapToken: *optBootstrapToken,
+		MetricsAddress: *optMetricsAddress,
+	}
+}
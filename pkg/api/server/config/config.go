@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	flag "github.com/ogier/pflag"
 
@@ -20,6 +21,11 @@ const (
 	defaultServiceName         = "runmachine-api"
 	defaultMetadataServiceName = "runmachine-metadata"
 	defaultResourceServiceName = "runmachine-resource"
+	defaultPeers               = ""
+	defaultLBPolicy            = "round_robin"
+	defaultMetricsAddress      = ""
+	defaultImageStoreDriver    = "local"
+	defaultImageStorePath      = "/var/lib/runmachine/images"
 )
 
 var (
@@ -37,6 +43,19 @@ type Config struct {
 	ServiceName         string
 	MetadataServiceName string
 	ResourceServiceName string
+	// Map, keyed by partition name or UUID, of the address of the runm-api
+	// endpoint of the peered runmachine deployment that owns the partition
+	Peers map[string]string
+	// Policy used to balance RPCs across the endpoints of the metadata and
+	// resource services
+	LBPolicy string
+	// Address (host:port) of the HTTP listener serving Prometheus metrics at
+	// /metrics. Metrics are not served if empty.
+	MetricsAddress string
+	// Name of the blob store driver used to keep the bits of images
+	ImageStoreDriver string
+	// Directory the local image store driver keeps the bits of images in
+	ImageStorePath string
 }
 
 func ConfigFromOpts() *Config {
@@ -96,9 +115,56 @@ func ConfigFromOpts() *Config {
 		),
 		"Name to use when querying the service registry for the resource service",
 	)
+	optPeers := flag.String(
+		"peers",
+		envutil.WithDefault(
+			"RUNM_API_PEERS", defaultPeers,
+		),
+		"Comma-delimited list of $partition=$address pairs. Each pair "+
+			"indicates that the partition (name or UUID) is owned by a "+
+			"peered runmachine deployment whose runm-api endpoint is "+
+			"listening at $address",
+	)
+	optLBPolicy := flag.String(
+		"lb-policy",
+		envutil.WithDefault(
+			"RUNM_API_LB_POLICY", defaultLBPolicy,
+		),
+		"Policy (round_robin or least_loaded) used to balance RPCs "+
+			"across metadata and resource service endpoints",
+	)
+	optMetricsAddress := flag.String(
+		"metrics-address",
+		envutil.WithDefault(
+			"RUNM_API_METRICS_ADDRESS", defaultMetricsAddress,
+		),
+		"Address (host:port) to serve Prometheus metrics on. Metrics are "+
+			"not served if empty",
+	)
+	optImageStoreDriver := flag.String(
+		"image-store-driver",
+		envutil.WithDefault(
+			"RUNM_API_IMAGE_STORE_DRIVER", defaultImageStoreDriver,
+		),
+		"Blob store driver used to keep the bits of images. Only local is "+
+			"supported",
+	)
+	optImageStorePath := flag.String(
+		"image-store-path",
+		envutil.WithDefault(
+			"RUNM_API_IMAGE_STORE_PATH", defaultImageStorePath,
+		),
+		"Directory the local image store driver keeps the bits of images in",
+	)
 
 	flag.Parse()
 
+	peers, err := parsePeers(*optPeers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error parsing peers option: %s\n", err)
+		os.Exit(1)
+	}
+
 	return &Config{
 		UseTLS:              *optUseTLS,
 		CertPath:            *optCertPath,
@@ -108,7 +174,43 @@ func ConfigFromOpts() *Config {
 		ServiceName:         *optServiceName,
 		MetadataServiceName: *optMetadataServiceName,
 		ResourceServiceName: *optResourceServiceName,
+		Peers:               peers,
+		LBPolicy:            *optLBPolicy,
+		MetricsAddress:      *optMetricsAddress,
+		ImageStoreDriver:    *optImageStoreDriver,
+		ImageStorePath:      *optImageStorePath,
+	}
+}
+
+// parsePeers takes a comma-delimited string of $partition=$address pairs and
+// returns a map of peer runm-api endpoint addresses keyed by partition
+func parsePeers(opt string) (map[string]string, error) {
+	peers := make(map[string]string, 0)
+	if strings.TrimSpace(opt) == "" {
+		return peers, nil
+	}
+	for _, pair := range strings.Split(opt, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		eqPos := strings.Index(pair, "=")
+		if eqPos < 1 || eqPos == len(pair)-1 {
+			return nil, fmt.Errorf(
+				"invalid peer %s. expected $partition=$address", pair,
+			)
+		}
+		part := pair[:eqPos]
+		addr := pair[eqPos+1:]
+		if existing, exists := peers[part]; exists && existing != addr {
+			return nil, fmt.Errorf(
+				"partition %s is owned by multiple peers (%s and %s)",
+				part, existing, addr,
+			)
+		}
+		peers[part] = addr
 	}
+	return peers, nil
 }
 
 // Returns the TLS configuration struct to use with etcd client.
@@ -1,8 +1,13 @@
 package server
 
 import (
+	"fmt"
+	"strings"
+
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+
+	pb "github.com/runmachine-io/runmachine/proto"
 )
 
 var (
@@ -98,6 +103,35 @@ var (
 		codes.FailedPrecondition,
 		"at least one provider filter is required.",
 	)
+	ErrSelectorWithFilters = status.Errorf(
+		codes.InvalidArgument,
+		"a selector may not be supplied along with filters.",
+	)
+	ErrSelectorWithSearch = status.Errorf(
+		codes.InvalidArgument,
+		"a selector may not be supplied along with UUIDs or names.",
+	)
+	ErrImageNotOwned = status.Errorf(
+		codes.PermissionDenied,
+		"only the project that owns an image may change it.",
+	)
+	ErrImageAlreadyUploaded = status.Errorf(
+		codes.FailedPrecondition,
+		"the image's bits have already been uploaded.",
+	)
+	ErrImageNotUploaded = status.Errorf(
+		codes.FailedPrecondition,
+		"the image's bits have not been uploaded.",
+	)
+	ErrImageNotActive = status.Errorf(
+		codes.FailedPrecondition,
+		"machines may only be booted from images whose bits have been "+
+			"uploaded.",
+	)
+	ErrAtLeastOneObjectRequired = status.Errorf(
+		codes.FailedPrecondition,
+		"at least one object UUID or name, or a selector, is required.",
+	)
 	ErrObjectDeleteFailed = status.Errorf(
 		codes.FailedPrecondition,
 		"failed to delete object (check response errors collection).",
@@ -116,6 +150,13 @@ var (
 	)
 )
 
+func errSelectorInvalid(err error) error {
+	return status.Errorf(
+		codes.InvalidArgument,
+		"invalid selector: %s", err,
+	)
+}
+
 func errProviderTypeNotFound(providerType string) error {
 	return status.Errorf(
 		codes.FailedPrecondition,
@@ -123,6 +164,23 @@ func errProviderTypeNotFound(providerType string) error {
 	)
 }
 
+func errObjectDefinitionViolated(
+	objectType string,
+	violations []*pb.ObjectDefinitionViolation,
+) error {
+	msg := fmt.Sprintf(
+		"Definition not saved: %d existing %s objects do not satisfy it:",
+		len(violations), objectType,
+	)
+	for _, v := range violations {
+		msg += fmt.Sprintf(
+			"\n- %s (%s): %s",
+			v.ObjectName, v.ObjectUuid, strings.Join(v.Errors, "; "),
+		)
+	}
+	return status.Error(codes.FailedPrecondition, msg)
+}
+
 func errPartitionNotFound(partition string) error {
 	return status.Errorf(
 		codes.FailedPrecondition,
@@ -136,3 +194,65 @@ func errObjectTypeNotFound(objectType string) error {
 		"Object type %s not found", objectType,
 	)
 }
+
+func errObjectTypeReserved(objectType string, command string) error {
+	return status.Errorf(
+		codes.InvalidArgument,
+		"Objects of type %s may only be changed with the %s commands",
+		objectType, command,
+	)
+}
+
+func errObjectTypeNotProjectScoped(objectType string) error {
+	return status.Errorf(
+		codes.InvalidArgument,
+		"Object type %s is partition-scoped. Objects of this type may not "+
+			"be assigned a project.",
+		objectType,
+	)
+}
+
+func errImageChecksumMismatch(expected string, got string) error {
+	return status.Errorf(
+		codes.InvalidArgument,
+		"Uploaded image has checksum %s but the image was registered with "+
+			"checksum %s",
+		got, expected,
+	)
+}
+
+func errImageSizeMismatch(expected uint64, got uint64) error {
+	return status.Errorf(
+		codes.InvalidArgument,
+		"Uploaded image is %d bytes but the image was registered with a "+
+			"size of %d bytes",
+		got, expected,
+	)
+}
+
+func errMachineProviderPartitionMismatch(
+	provider string,
+	partition string,
+) error {
+	return status.Errorf(
+		codes.FailedPrecondition,
+		"Provider %s is not in the machine's partition %s",
+		provider, partition,
+	)
+}
+
+func errMachineStateTransitionInvalid(from string, to string) error {
+	return status.Errorf(
+		codes.FailedPrecondition,
+		"A machine may not transition from state %s to state %s",
+		from, to,
+	)
+}
+
+func errObjectProjectMismatch(project string, sessProject string) error {
+	return status.Errorf(
+		codes.PermissionDenied,
+		"Object project %s does not match session project %s",
+		project, sessProject,
+	)
+}
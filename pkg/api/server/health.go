@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/runmachine-io/runmachine/pkg/health"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	// How long to wait for each endpoint to respond to a health check when
+	// gathering status
+	statusEndpointTimeout = 3 * time.Second
+)
+
+// HealthCheck returns nil if the API service is able to reach healthy
+// metadata and resource services
+func (s *Server) HealthCheck(ctx context.Context) error {
+	if err := health.CheckConn(ctx, s.metasc.Conn()); err != nil {
+		return fmt.Errorf("metadata service unhealthy: %s", err)
+	}
+	if err := health.CheckConn(ctx, s.ressc.Conn()); err != nil {
+		return fmt.Errorf("resource service unhealthy: %s", err)
+	}
+	return nil
+}
+
+// Status returns the health of each endpoint of the API, metadata and
+// resource services registered in the gsr service registry
+func (s *Server) Status(
+	ctx context.Context,
+	req *pb.StatusRequest,
+) (*pb.StatusResponse, error) {
+	services := []string{
+		s.cfg.ServiceName,
+		s.cfg.MetadataServiceName,
+		s.cfg.ResourceServiceName,
+	}
+	res := make([]*pb.ServiceEndpointStatus, 0)
+	for _, svc := range services {
+		for _, ep := range s.registry.Endpoints(svc) {
+			epStatus := &pb.ServiceEndpointStatus{
+				Service: svc,
+				Address: ep.Address,
+			}
+			epCtx, cancel := context.WithTimeout(ctx, statusEndpointTimeout)
+			st, err := health.CheckAddress(epCtx, ep.Address)
+			cancel()
+			epStatus.Status = st.String()
+			if err != nil {
+				epStatus.Error = err.Error()
+			}
+			res = append(res, epStatus)
+		}
+	}
+	return &pb.StatusResponse{
+		Endpoints: res,
+	}, nil
+}
@@ -0,0 +1,603 @@
+package server
+
+import (
+	"context"
+	"io"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/runmachine-io/runmachine/pkg/api/types"
+	"github.com/runmachine-io/runmachine/pkg/errors"
+	"github.com/runmachine-io/runmachine/pkg/util"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	// The number of bytes of an image's bits sent in each ImageChunk message
+	// by ImageDownload
+	_IMAGE_CHUNK_SIZE = 64 * 1024
+	// The default disk format of an image's bits
+	defaultImageFormat = "raw"
+)
+
+// imageObjectProperties returns the properties of the runm.image object for
+// the supplied image. The image's attributes are stored in the properties
+// with the keys in types.ImagePropertyKeys, along with the image's own
+// properties.
+func imageObjectProperties(img *pb.Image) ([]*pb.Property, error) {
+	members := make([]interface{}, len(img.Members))
+	for x, m := range img.Members {
+		members[x] = m
+	}
+	attrs := map[string]interface{}{
+		"format":     img.Format,
+		"visibility": types.ImageVisibilityString(img.Visibility),
+		"status":     types.ImageStatusString(img.Status),
+	}
+	if img.Architecture != "" {
+		attrs["architecture"] = img.Architecture
+	}
+	if img.Os != "" {
+		attrs["os"] = img.Os
+	}
+	if img.Checksum != "" {
+		attrs["checksum"] = img.Checksum
+	}
+	if img.Size != 0 {
+		attrs["size"] = int64(img.Size)
+	}
+	if len(members) > 0 {
+		attrs["members"] = members
+	}
+	props, err := objectPropertiesFromMap(attrs)
+	if err != nil {
+		return nil, err
+	}
+	props = append(props, img.Properties...)
+	types.SortProperties(props)
+	return props, nil
+}
+
+// imageFromObject returns the image described by the supplied runm.image
+// object
+func imageFromObject(obj *pb.Object) *pb.Image {
+	img := &pb.Image{
+		Partition:  obj.Partition,
+		Project:    obj.Project,
+		Uuid:       obj.Uuid,
+		Name:       obj.Name,
+		Properties: make([]*pb.Property, 0),
+		Tags:       obj.Tags,
+	}
+	for _, prop := range obj.Properties {
+		switch prop.Key {
+		case "architecture":
+			img.Architecture = prop.Value
+		case "os":
+			img.Os = prop.Value
+		case "format":
+			img.Format = prop.Value
+		case "checksum":
+			img.Checksum = prop.Value
+		case "size":
+			if size, ok := types.PropertyInterface(prop).(int64); ok {
+				img.Size = uint64(size)
+			}
+		case "visibility":
+			// NOTE(jaypipes): An invalid visibility is treated as private
+			img.Visibility, _ = types.ImageVisibilityFromString(prop.Value)
+		case "members":
+			values, _ := types.PropertyInterface(prop).([]interface{})
+			for _, v := range values {
+				if m, ok := v.(string); ok {
+					img.Members = append(img.Members, m)
+				}
+			}
+		case "status":
+			img.Status, _ = types.ImageStatusFromString(prop.Value)
+		default:
+			img.Properties = append(img.Properties, prop)
+		}
+	}
+	return img
+}
+
+// imageVisibleTo returns true if the supplied image may be seen and used by
+// the session's project. Images owned by other projects are only visible in
+// their own partition.
+func imageVisibleTo(img *pb.Image, sess *pb.Session) bool {
+	if img.Project == sess.Project {
+		return true
+	}
+	if img.Partition != sess.Partition {
+		return false
+	}
+	switch img.Visibility {
+	case pb.ImageVisibility_IMAGE_PUBLIC:
+		return true
+	case pb.ImageVisibility_IMAGE_SHARED:
+		for _, m := range img.Members {
+			if m == sess.Project {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// imageStream reads the runm.image objects returned by the metadata service
+// one at a time and skips the images not visible to the session's project
+type imageStream struct {
+	sess *pb.Session
+	objs pb.RunmMetadata_ObjectFindClient
+}
+
+// Recv returns the next image visible to the session's project, or io.EOF if
+// there are no more images
+func (st *imageStream) Recv() (*pb.Image, error) {
+	if st.objs == nil {
+		return nil, io.EOF
+	}
+	for {
+		obj, err := st.objs.Recv()
+		if err != nil {
+			return nil, err
+		}
+		img := imageFromObject(obj)
+		if imageVisibleTo(img, st.sess) {
+			return img, nil
+		}
+	}
+}
+
+// imagesGetMatching returns a stream of the images visible to the session's
+// project that match any of the supplied runm.image object filters
+func (s *Server) imagesGetMatching(
+	ctx context.Context,
+	sess *pb.Session,
+	any []*pb.ObjectFilter,
+) (*imageStream, error) {
+	st := &imageStream{sess: sess}
+	if len(any) == 0 {
+		return st, nil
+	}
+	// Images owned by other projects may be visible to the session's
+	// project, so we look for images in every project and then filter out
+	// the ones the session's project may not see
+	// TODO(jaypipes): Use the by-property index to only read the images that
+	// are owned by the session's project or are public or shared
+	for _, f := range any {
+		f.AnyProject = true
+	}
+	objs, err := s.objectsGetMatching(ctx, sess, any)
+	if err != nil {
+		return nil, err
+	}
+	st.objs = objs
+	return st, nil
+}
+
+// imageGet returns the image visible to the session's project with the
+// supplied UUID or name. When looking up an image by name, an image owned by
+// the session's project is preferred over images owned by other projects. If
+// no such image could be found, returns (nil, ErrNotFound)
+func (s *Server) imageGet(
+	ctx context.Context,
+	sess *pb.Session,
+	search string,
+) (*pb.Image, error) {
+	filter := &pb.ObjectFilter{
+		ObjectTypeFilter: &pb.ObjectTypeFilter{
+			CodeFilter: &pb.CodeFilter{
+				Code: "runm.image",
+			},
+		},
+	}
+	if util.IsUuidLike(search) {
+		filter.UuidFilter = &pb.UuidFilter{
+			Uuid: search,
+		}
+	} else {
+		filter.PartitionFilter = &pb.UuidsFilter{
+			Uuids: []string{sess.Partition},
+		}
+		filter.NameFilter = &pb.NameFilter{
+			Name: search,
+		}
+	}
+	st, err := s.imagesGetMatching(ctx, sess, []*pb.ObjectFilter{filter})
+	if err != nil {
+		return nil, err
+	}
+	imgs := make([]*pb.Image, 0)
+	for {
+		img, err := st.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if img.Project == sess.Project {
+			return img, nil
+		}
+		imgs = append(imgs, img)
+	}
+	switch len(imgs) {
+	case 0:
+		return nil, ErrNotFound
+	case 1:
+		return imgs[0], nil
+	}
+	return nil, ErrMultipleRecordsFound
+}
+
+// imageGetOwned returns the image owned by the session's project with the
+// supplied UUID or name. Only the owning project may change an image.
+func (s *Server) imageGetOwned(
+	ctx context.Context,
+	sess *pb.Session,
+	search string,
+) (*pb.Image, error) {
+	img, err := s.imageGet(ctx, sess, search)
+	if err != nil {
+		return nil, err
+	}
+	// TODO(jaypipes): AUTHZ check if user can change images owned by other
+	// projects (i.e. an admin)
+	if img.Project != sess.Project {
+		return nil, ErrImageNotOwned
+	}
+	return img, nil
+}
+
+// imageUpdate saves the attributes, properties and tags of the supplied image
+// to its runm.image object and returns the updated image
+func (s *Server) imageUpdate(
+	ctx context.Context,
+	sess *pb.Session,
+	img *pb.Image,
+) (*pb.Image, error) {
+	props, err := imageObjectProperties(img)
+	if err != nil {
+		return nil, err
+	}
+	obj := &pb.Object{
+		Uuid:       img.Uuid,
+		Properties: props,
+		Tags:       img.Tags,
+	}
+	changed, err := s.objectUpdate(ctx, sess, obj, img.Format)
+	if err != nil {
+		return nil, err
+	}
+	return imageFromObject(changed), nil
+}
+
+// ImageGet looks up an image by UUID or name and returns an Image protobuf
+// message
+func (s *Server) ImageGet(
+	ctx context.Context,
+	req *pb.ImageGetRequest,
+) (*pb.Image, error) {
+	if req.Search == "" {
+		return nil, ErrSearchRequired
+	}
+	return s.imageGet(ctx, req.Session, req.Search)
+}
+
+// ImageList streams zero or more Image messages back to the client for the
+// images visible to the session's project that match an optional selector
+func (s *Server) ImageList(
+	req *pb.ImageListRequest,
+	stream pb.RunmAPI_ImageListServer,
+) error {
+	ctx := stream.Context()
+	any, err := s.objectFilters(ctx, req.Session, "runm.image", req.Selector)
+	if err != nil {
+		return err
+	}
+	st, err := s.imagesGetMatching(ctx, req.Session, any)
+	if err != nil {
+		return err
+	}
+	for {
+		img, err := st.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = stream.Send(img); err != nil {
+			return err
+		}
+	}
+}
+
+// validateImageCreateRequest unmarshals the supplied request payload into an
+// Image message, ensuring the image's partition exists and its project is
+// the session's project
+func (s *Server) validateImageCreateRequest(
+	ctx context.Context,
+	req *pb.CreateRequest,
+) (*pb.Image, error) {
+	var input types.Image
+	if err := yaml.Unmarshal(req.Payload, &input); err != nil {
+		return nil, err
+	}
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	sess := req.Session
+	partUuid := sess.Partition
+	if input.Partition != "" {
+		// Check that the supplied partition exists, and if the user supplied a
+		// partition name, translate it to a partition UUID
+		part, err := s.partitionGet(ctx, sess, input.Partition)
+		if err != nil {
+			return nil, err
+		}
+		partUuid = part.Uuid
+	}
+
+	objType, err := s.objectTypeGetByCode(ctx, sess, "runm.image")
+	if err != nil {
+		return nil, err
+	}
+	project, err := objectProject(objType, input.Project, sess)
+	if err != nil {
+		return nil, err
+	}
+
+	props, err := objectPropertiesFromMap(input.Properties)
+	if err != nil {
+		return nil, err
+	}
+	format := input.Format
+	if format == "" {
+		format = defaultImageFormat
+	}
+	// NOTE(jaypipes): Validate() has already checked the visibility string
+	vis, _ := types.ImageVisibilityFromString(input.Visibility)
+	return &pb.Image{
+		Partition:    partUuid,
+		Project:      project,
+		Uuid:         input.Uuid,
+		Name:         input.Name,
+		Architecture: input.Architecture,
+		Os:           input.Os,
+		Format:       format,
+		Checksum:     input.Checksum,
+		Size:         input.Size,
+		Visibility:   vis,
+		Members:      input.Members,
+		Status:       pb.ImageStatus_IMAGE_QUEUED,
+		Properties:   props,
+		Tags:         input.Tags,
+	}, nil
+}
+
+// ImageCreate registers a new image. The image's bits are uploaded with
+// ImageUpload after the image is created.
+func (s *Server) ImageCreate(
+	ctx context.Context,
+	req *pb.CreateRequest,
+) (*pb.ImageCreateResponse, error) {
+	// TODO(jaypipes): AUTHZ check if user can write images
+
+	img, err := s.validateImageCreateRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	props, err := imageObjectProperties(img)
+	if err != nil {
+		return nil, err
+	}
+	obj := &pb.Object{
+		Partition:  img.Partition,
+		ObjectType: "runm.image",
+		Project:    img.Project,
+		Uuid:       img.Uuid,
+		Name:       img.Name,
+		Properties: props,
+		Tags:       img.Tags,
+	}
+	// The image's format is the subtype used to look up the object definition
+	// that the image's properties are validated against
+	if err = s.objectCreate(ctx, req.Session, obj, img.Format); err != nil {
+		return nil, err
+	}
+	s.log.ForContext(ctx).L1(
+		"created new image with UUID %s in partition %s and project %s "+
+			"with name %s",
+		obj.Uuid, obj.Partition, obj.Project, obj.Name,
+	)
+
+	return &pb.ImageCreateResponse{
+		Image: imageFromObject(obj),
+	}, nil
+}
+
+// imageUploadReader reads the bits of an image from the messages of an
+// ImageUpload request stream
+type imageUploadReader struct {
+	stream pb.RunmAPI_ImageUploadServer
+	buf    []byte
+}
+
+func (r *imageUploadReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		msg, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		r.buf = msg.Data
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// ImageUpload receives the bits of a registered image and stores them in the
+// image store. If the image was registered with a checksum or size, the
+// uploaded bits must match them.
+func (s *Server) ImageUpload(stream pb.RunmAPI_ImageUploadServer) error {
+	ctx := stream.Context()
+	first, err := stream.Recv()
+	if err != nil {
+		if err == io.EOF {
+			return ErrSearchRequired
+		}
+		return err
+	}
+	if first.Search == "" {
+		return ErrSearchRequired
+	}
+	sess := first.Session
+	img, err := s.imageGetOwned(ctx, sess, first.Search)
+	if err != nil {
+		return err
+	}
+	if img.Status != pb.ImageStatus_IMAGE_QUEUED {
+		return ErrImageAlreadyUploaded
+	}
+
+	r := &imageUploadReader{stream: stream, buf: first.Data}
+	info, err := s.images.Put(ctx, img.Uuid, r)
+	if err != nil {
+		if err == errors.ErrDuplicate {
+			return ErrImageAlreadyUploaded
+		}
+		s.log.ForContext(ctx).ERR(
+			"failed storing bits of image %s: %s", img.Uuid, err,
+		)
+		return ErrUnknown
+	}
+
+	if img.Checksum != "" && img.Checksum != info.Checksum {
+		err = errImageChecksumMismatch(img.Checksum, info.Checksum)
+	} else if img.Size != 0 && img.Size != info.Size {
+		err = errImageSizeMismatch(img.Size, info.Size)
+	}
+	var updated *pb.Image
+	if err == nil {
+		img.Checksum = info.Checksum
+		img.Size = info.Size
+		img.Status = pb.ImageStatus_IMAGE_ACTIVE
+		updated, err = s.imageUpdate(ctx, sess, img)
+	}
+	if err != nil {
+		// Don't leave bits behind for an image that is still queued
+		if delErr := s.images.Delete(ctx, img.Uuid); delErr != nil {
+			s.log.ForContext(ctx).ERR(
+				"failed removing bits of image %s after failed upload: %s",
+				img.Uuid, delErr,
+			)
+		}
+		return err
+	}
+	s.log.ForContext(ctx).L1(
+		"uploaded %d bytes of image with UUID %s", updated.Size, updated.Uuid,
+	)
+	return stream.SendAndClose(updated)
+}
+
+// ImageDownload streams the bits of an image back to the client
+func (s *Server) ImageDownload(
+	req *pb.ImageGetRequest,
+	stream pb.RunmAPI_ImageDownloadServer,
+) error {
+	ctx := stream.Context()
+	if req.Search == "" {
+		return ErrSearchRequired
+	}
+	img, err := s.imageGet(ctx, req.Session, req.Search)
+	if err != nil {
+		return err
+	}
+	if img.Status != pb.ImageStatus_IMAGE_ACTIVE {
+		return ErrImageNotUploaded
+	}
+	r, err := s.images.Get(ctx, img.Uuid)
+	if err != nil {
+		s.log.ForContext(ctx).ERR(
+			"failed reading bits of active image %s: %s", img.Uuid, err,
+		)
+		return ErrUnknown
+	}
+	defer r.Close()
+
+	buf := make([]byte, _IMAGE_CHUNK_SIZE)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			chunk := &pb.ImageChunk{Data: buf[:n]}
+			if sendErr := stream.Send(chunk); sendErr != nil {
+				return sendErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			s.log.ForContext(ctx).ERR(
+				"failed reading bits of image %s: %s", img.Uuid, err,
+			)
+			return ErrUnknown
+		}
+	}
+}
+
+// ImageDelete removes one or more images owned by the session's project along
+// with their bits. The bits are removed first so that a failure never leaves
+// bits behind that no image refers to. If removing the images from the
+// metadata service then fails, the images remain active without bits and
+// deleting them again completes the removal.
+func (s *Server) ImageDelete(
+	ctx context.Context,
+	req *pb.ImageDeleteRequest,
+) (*pb.DeleteResponse, error) {
+	// TODO(jaypipes): AUTHZ check if user can write images
+
+	if len(req.Search) == 0 {
+		return nil, ErrSearchRequired
+	}
+	sess := req.Session
+	imgs := make([]*pb.Image, len(req.Search))
+	uuids := make([]string, len(req.Search))
+	for x, search := range req.Search {
+		img, err := s.imageGetOwned(ctx, sess, search)
+		if err != nil {
+			return nil, err
+		}
+		imgs[x] = img
+		uuids[x] = img.Uuid
+	}
+
+	for _, img := range imgs {
+		if img.Status != pb.ImageStatus_IMAGE_ACTIVE {
+			continue
+		}
+		err := s.images.Delete(ctx, img.Uuid)
+		if err != nil && err != errors.ErrNotFound {
+			s.log.ForContext(ctx).ERR(
+				"failed removing bits of image %s: %s", img.Uuid, err,
+			)
+			return nil, ErrUnknown
+		}
+	}
+	if err := s.objectDelete(ctx, sess, uuids); err != nil {
+		return nil, err
+	}
+	s.log.ForContext(ctx).L1(
+		"deleted %d images in project %s", len(uuids), sess.Project,
+	)
+
+	// TODO(jaypipes): Send an event notification
+
+	return &pb.DeleteResponse{
+		NumDeleted: uint64(len(uuids)),
+	}, nil
+}
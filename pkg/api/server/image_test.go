@@ -0,0 +1,174 @@
+package server
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/runmachine-io/runmachine/pkg/api/types"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+func TestImageObjectProperties(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	distro, err := types.NewProperty("distro", "ubuntu")
+	require.Nil(err)
+	img := &pb.Image{
+		Partition:    "part0",
+		Project:      "proj0",
+		Uuid:         "4f4f54c9bfb44cce9a02d4daf6f79ea3",
+		Name:         "ubuntu-18.04",
+		Architecture: "x86_64",
+		Os:           "linux",
+		Format:       "qcow2",
+		Checksum:     "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		Size:         1 << 32,
+		Visibility:   pb.ImageVisibility_IMAGE_SHARED,
+		Members:      []string{"proj1", "proj2"},
+		Status:       pb.ImageStatus_IMAGE_ACTIVE,
+		Properties:   []*pb.Property{distro},
+		Tags:         []string{"lts"},
+	}
+	props, err := imageObjectProperties(img)
+	require.Nil(err)
+
+	keys := make([]string, len(props))
+	for x, prop := range props {
+		keys[x] = prop.Key
+	}
+	assert.Equal(
+		[]string{
+			"architecture", "checksum", "distro", "format", "members", "os",
+			"size", "status", "visibility",
+		},
+		keys,
+	)
+
+	obj := &pb.Object{
+		Partition:  img.Partition,
+		ObjectType: "runm.image",
+		Project:    img.Project,
+		Uuid:       img.Uuid,
+		Name:       img.Name,
+		Properties: props,
+		Tags:       img.Tags,
+	}
+	assert.Equal(img, imageFromObject(obj))
+}
+
+func TestImageVisibleTo(t *testing.T) {
+	assert := assert.New(t)
+
+	sess := &pb.Session{
+		User:      "alice",
+		Project:   "proj1",
+		Partition: "part0",
+	}
+	tests := []struct {
+		img    *pb.Image
+		expect bool
+	}{
+		{
+			img:    &pb.Image{Partition: "part1", Project: "proj1"},
+			expect: true,
+		},
+		{
+			img:    &pb.Image{Project: "proj0"},
+			expect: false,
+		},
+		{
+			img: &pb.Image{
+				Partition:  "part0",
+				Project:    "proj0",
+				Visibility: pb.ImageVisibility_IMAGE_PUBLIC,
+			},
+			expect: true,
+		},
+		{
+			img: &pb.Image{
+				Partition:  "part1",
+				Project:    "proj0",
+				Visibility: pb.ImageVisibility_IMAGE_PUBLIC,
+			},
+			expect: false,
+		},
+		{
+			img: &pb.Image{
+				Partition:  "part0",
+				Project:    "proj0",
+				Visibility: pb.ImageVisibility_IMAGE_SHARED,
+				Members:    []string{"proj2", "proj1"},
+			},
+			expect: true,
+		},
+		{
+			img: &pb.Image{
+				Project:    "proj0",
+				Visibility: pb.ImageVisibility_IMAGE_SHARED,
+				Members:    []string{"proj2"},
+			},
+			expect: false,
+		},
+	}
+	for _, test := range tests {
+		assert.Equal(test.expect, imageVisibleTo(test.img, sess))
+	}
+}
+
+// fakeObjectFindClient returns a fixed set of objects from an ObjectFind
+// stream
+type fakeObjectFindClient struct {
+	grpc.ClientStream
+	objs []*pb.Object
+}
+
+func (f *fakeObjectFindClient) Recv() (*pb.Object, error) {
+	if len(f.objs) == 0 {
+		return nil, io.EOF
+	}
+	obj := f.objs[0]
+	f.objs = f.objs[1:]
+	return obj, nil
+}
+
+func TestImageStream(t *testing.T) {
+	assert := assert.New(t)
+
+	sess := &pb.Session{Project: "proj1", Partition: "part0"}
+	st := &imageStream{
+		sess: sess,
+		objs: &fakeObjectFindClient{
+			objs: []*pb.Object{
+				&pb.Object{Partition: "part0", Project: "proj0", Name: "private"},
+				&pb.Object{Partition: "part0", Project: "proj1", Name: "own"},
+				&pb.Object{
+					Partition: "part0",
+					Project:   "proj0",
+					Name:      "public",
+					Properties: []*pb.Property{
+						&pb.Property{Key: "visibility", Value: "public"},
+					},
+				},
+			},
+		},
+	}
+	names := make([]string, 0)
+	for {
+		img, err := st.Recv()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(err)
+		names = append(names, img.Name)
+	}
+	assert.Equal([]string{"own", "public"}, names)
+
+	// A stream for no filters has no images
+	_, err := (&imageStream{sess: sess}).Recv()
+	assert.Equal(io.EOF, err)
+}
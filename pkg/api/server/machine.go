@@ -0,0 +1,498 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/runmachine-io/runmachine/pkg/api/types"
+	"github.com/runmachine-io/runmachine/pkg/util"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// machineObjectProperties returns the properties of the runm.machine object
+// for the supplied machine. The machine's attributes are stored in the
+// properties with the keys in types.MachinePropertyKeys, along with the
+// machine's own properties.
+func machineObjectProperties(m *pb.Machine) ([]*pb.Property, error) {
+	attrs := map[string]interface{}{
+		"image":         m.Image,
+		"desired_state": types.MachineStateString(m.DesiredState),
+		"state":         types.MachineStateString(m.State),
+		"state_time":    m.StateTime,
+	}
+	if m.Provider != "" {
+		attrs["provider"] = m.Provider
+	}
+	if len(m.Resources) > 0 {
+		resources := make(map[string]interface{}, len(m.Resources))
+		for code, amount := range m.Resources {
+			resources[code] = int64(amount)
+		}
+		attrs["resources"] = resources
+	}
+	if m.StateReason != "" {
+		attrs["state_reason"] = m.StateReason
+	}
+	props, err := objectPropertiesFromMap(attrs)
+	if err != nil {
+		return nil, err
+	}
+	props = append(props, m.Properties...)
+	types.SortProperties(props)
+	return props, nil
+}
+
+// machineFromObject returns the machine described by the supplied
+// runm.machine object
+func machineFromObject(obj *pb.Object) *pb.Machine {
+	m := &pb.Machine{
+		Partition:  obj.Partition,
+		Project:    obj.Project,
+		Uuid:       obj.Uuid,
+		Name:       obj.Name,
+		Properties: make([]*pb.Property, 0),
+		Tags:       obj.Tags,
+		Generation: obj.Generation,
+	}
+	for _, prop := range obj.Properties {
+		switch prop.Key {
+		case "image":
+			m.Image = prop.Value
+		case "provider":
+			m.Provider = prop.Value
+		case "resources":
+			values, _ := types.PropertyInterface(prop).(map[string]interface{})
+			if len(values) > 0 {
+				m.Resources = make(map[string]uint64, len(values))
+			}
+			for code, v := range values {
+				if amount, ok := v.(int64); ok {
+					m.Resources[code] = uint64(amount)
+				}
+			}
+		case "desired_state":
+			m.DesiredState, _ = types.MachineStateFromString(prop.Value)
+		case "state":
+			// NOTE(jaypipes): An invalid state is treated as pending
+			m.State, _ = types.MachineStateFromString(prop.Value)
+		case "state_reason":
+			m.StateReason = prop.Value
+		case "state_time":
+			m.StateTime, _ = types.PropertyInterface(prop).(int64)
+		default:
+			m.Properties = append(m.Properties, prop)
+		}
+	}
+	return m
+}
+
+// machineGet returns the machine in the session's project with the supplied
+// UUID or name. If no such machine could be found, returns (nil, ErrNotFound)
+func (s *Server) machineGet(
+	ctx context.Context,
+	sess *pb.Session,
+	search string,
+) (*pb.Machine, error) {
+	obj, err := s.objectGet(ctx, sess, "runm.machine", "", search)
+	if err != nil {
+		return nil, err
+	}
+	// TODO(jaypipes): AUTHZ check if user can see machines owned by other
+	// projects (i.e. an admin)
+	if obj.Project != sess.Project {
+		return nil, ErrNotFound
+	}
+	return machineFromObject(obj), nil
+}
+
+// machineUpdate saves the attributes, properties and tags of the supplied
+// machine to its runm.machine object and returns the updated machine. The
+// update fails if the machine's object has been changed since the machine was
+// read.
+func (s *Server) machineUpdate(
+	ctx context.Context,
+	sess *pb.Session,
+	m *pb.Machine,
+) (*pb.Machine, error) {
+	props, err := machineObjectProperties(m)
+	if err != nil {
+		return nil, err
+	}
+	obj := &pb.Object{
+		Uuid:       m.Uuid,
+		Properties: props,
+		Tags:       m.Tags,
+		Generation: m.Generation,
+	}
+	changed, err := s.objectUpdate(ctx, sess, obj, "")
+	if err != nil {
+		return nil, err
+	}
+	return machineFromObject(changed), nil
+}
+
+// MachineGet looks up a machine by UUID or name and returns a Machine
+// protobuf message
+func (s *Server) MachineGet(
+	ctx context.Context,
+	req *pb.MachineGetRequest,
+) (*pb.Machine, error) {
+	if req.Search == "" {
+		return nil, ErrSearchRequired
+	}
+	return s.machineGet(ctx, req.Session, req.Search)
+}
+
+// MachineList streams zero or more Machine messages back to the client for
+// the machines in the session's project that match an optional selector
+func (s *Server) MachineList(
+	req *pb.MachineListRequest,
+	stream pb.RunmAPI_MachineListServer,
+) error {
+	ctx := stream.Context()
+	any, err := s.objectFilters(ctx, req.Session, "runm.machine", req.Selector)
+	if err != nil {
+		return err
+	}
+	if len(any) == 0 {
+		return nil
+	}
+	objs, err := s.objectsGetMatching(ctx, req.Session, any)
+	if err != nil {
+		return err
+	}
+	for {
+		obj, err := objs.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err = stream.Send(machineFromObject(obj)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateMachineCreateRequest unmarshals the supplied request payload into a
+// Machine message, ensuring the machine's partition exists, its project is
+// the session's project, its image is active and its provider is in the
+// machine's partition
+func (s *Server) validateMachineCreateRequest(
+	ctx context.Context,
+	req *pb.CreateRequest,
+) (*pb.Machine, error) {
+	var input types.Machine
+	if err := yaml.Unmarshal(req.Payload, &input); err != nil {
+		return nil, err
+	}
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	sess := req.Session
+	partUuid := sess.Partition
+	if input.Partition != "" {
+		// Check that the supplied partition exists, and if the user supplied a
+		// partition name, translate it to a partition UUID
+		part, err := s.partitionGet(ctx, sess, input.Partition)
+		if err != nil {
+			return nil, err
+		}
+		partUuid = part.Uuid
+	}
+
+	objType, err := s.objectTypeGetByCode(ctx, sess, "runm.machine")
+	if err != nil {
+		return nil, err
+	}
+	project, err := objectProject(objType, input.Project, sess)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := s.imageGet(ctx, sess, input.Image)
+	if err != nil {
+		return nil, err
+	}
+	if img.Status != pb.ImageStatus_IMAGE_ACTIVE {
+		return nil, ErrImageNotActive
+	}
+
+	provUuid := ""
+	if input.Provider != "" {
+		prov, err := s.objectGet(
+			ctx, sess, "runm.provider", partUuid, input.Provider,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if prov.Partition != partUuid {
+			return nil, errMachineProviderPartitionMismatch(
+				input.Provider, partUuid,
+			)
+		}
+		provUuid = prov.Uuid
+	}
+
+	props, err := objectPropertiesFromMap(input.Properties)
+	if err != nil {
+		return nil, err
+	}
+	desired := pb.MachineState_MACHINE_RUNNING
+	if input.DesiredState != "" {
+		// NOTE(jaypipes): Validate() has already checked the desired state
+		desired, _ = types.MachineStateFromString(input.DesiredState)
+	}
+	// The machine's UUID is needed to create its consumer in the resource
+	// service before its object is created in the metadata service
+	uuid := util.NewNormalizedUuid()
+	if input.Uuid != "" {
+		if !util.IsUuidLike(input.Uuid) {
+			return nil, fmt.Errorf("uuid %s is not a valid UUID", input.Uuid)
+		}
+		uuid = util.NormalizeUuid(input.Uuid)
+	}
+	return &pb.Machine{
+		Partition:    partUuid,
+		Project:      project,
+		Uuid:         uuid,
+		Name:         input.Name,
+		Image:        img.Uuid,
+		Provider:     provUuid,
+		Resources:    input.Resources,
+		DesiredState: desired,
+		State:        pb.MachineState_MACHINE_PENDING,
+		StateTime:    time.Now().Unix(),
+		Properties:   props,
+		Tags:         input.Tags,
+	}, nil
+}
+
+// MachineCreate creates a new machine. A consumer having the machine's UUID
+// is created in the resource service and the machine's resources are claimed
+// from its provider before the machine's runm.machine object is saved.
+func (s *Server) MachineCreate(
+	ctx context.Context,
+	req *pb.CreateRequest,
+) (*pb.MachineCreateResponse, error) {
+	// TODO(jaypipes): AUTHZ check if user can write machines
+
+	m, err := s.validateMachineCreateRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	sess := req.Session
+
+	s.log.ForContext(ctx).L3(
+		"creating new machine in partition %s with name %s...",
+		m.Partition, m.Name,
+	)
+	err = s.consumerCreate(ctx, sess, "runm.machine", m.Uuid, m.Project)
+	if err != nil {
+		return nil, err
+	}
+	// releaseConsumer deletes the machine's consumer, and with it any claimed
+	// resources, when a later step of creating the machine fails
+	// TODO(jaypipes): Use Taskflow-oriented library to undo the steps
+	releaseConsumer := func() {
+		err := s.consumerDeleteByUuids(ctx, sess, []string{m.Uuid})
+		if err != nil {
+			s.log.ForContext(ctx).ERR(
+				"failed removing consumer for machine %s after failed "+
+					"create: %s",
+				m.Uuid, err,
+			)
+		}
+	}
+	if len(m.Resources) > 0 {
+		err = s.claimCreate(ctx, sess, m.Uuid, m.Provider, m.Resources)
+		if err != nil {
+			releaseConsumer()
+			return nil, err
+		}
+	}
+
+	props, err := machineObjectProperties(m)
+	if err != nil {
+		releaseConsumer()
+		return nil, err
+	}
+	obj := &pb.Object{
+		Partition:  m.Partition,
+		ObjectType: "runm.machine",
+		Project:    m.Project,
+		Uuid:       m.Uuid,
+		Name:       m.Name,
+		Properties: props,
+		Tags:       m.Tags,
+	}
+	if err = s.objectCreate(ctx, sess, obj, ""); err != nil {
+		releaseConsumer()
+		return nil, err
+	}
+	s.log.ForContext(ctx).L1(
+		"created new machine with UUID %s in partition %s and project %s "+
+			"with name %s",
+		obj.Uuid, obj.Partition, obj.Project, obj.Name,
+	)
+
+	// TODO(jaypipes): Send an event notification so that the machine gets
+	// built on its provider
+
+	return &pb.MachineCreateResponse{
+		Machine: machineFromObject(obj),
+	}, nil
+}
+
+// MachineDelete removes one or more machines in the session's project and
+// releases the resources claimed for them
+func (s *Server) MachineDelete(
+	ctx context.Context,
+	req *pb.MachineDeleteRequest,
+) (*pb.DeleteResponse, error) {
+	// TODO(jaypipes): AUTHZ check if user can write machines
+
+	if len(req.Search) == 0 {
+		return nil, ErrSearchRequired
+	}
+	sess := req.Session
+	uuids := make([]string, len(req.Search))
+	for x, search := range req.Search {
+		m, err := s.machineGet(ctx, sess, search)
+		if err != nil {
+			return nil, err
+		}
+		uuids[x] = m.Uuid
+	}
+
+	// Release the machines' resources in the resource service first.
+	// Deleting consumers that no longer exist is not an error, so if either
+	// step below fails, retrying the delete completes it.
+	if err := s.consumerDeleteByUuids(ctx, sess, uuids); err != nil {
+		return nil, err
+	}
+
+	// And now delete the machine objects from the metadata service
+	if err := s.objectDelete(ctx, sess, uuids); err != nil {
+		// TODO(jaypipes): Use Taskflow-oriented library to retry deleting
+		// the machines whose resources were released above
+		s.log.ForContext(ctx).ERR(
+			"released resources of machines (%s) but failed to delete "+
+				"them: %s",
+			uuids, err,
+		)
+		return nil, err
+	}
+	s.log.ForContext(ctx).L1(
+		"deleted %d machines in project %s", len(uuids), sess.Project,
+	)
+
+	// TODO(jaypipes): Send an event notification
+
+	return &pb.DeleteResponse{
+		NumDeleted: uint64(len(uuids)),
+	}, nil
+}
+
+// machineSetDesiredState sets the desired state of the machine with the
+// supplied UUID or name and returns the updated machine
+func (s *Server) machineSetDesiredState(
+	ctx context.Context,
+	sess *pb.Session,
+	search string,
+	desired pb.MachineState,
+) (*pb.Machine, error) {
+	// TODO(jaypipes): AUTHZ check if user can write machines
+
+	if search == "" {
+		return nil, ErrSearchRequired
+	}
+	m, err := s.machineGet(ctx, sess, search)
+	if err != nil {
+		return nil, err
+	}
+	if m.DesiredState == desired {
+		return m, nil
+	}
+	m.DesiredState = desired
+	updated, err := s.machineUpdate(ctx, sess, m)
+	if err != nil {
+		return nil, err
+	}
+	s.log.ForContext(ctx).L1(
+		"set desired state of machine with UUID %s to %s",
+		updated.Uuid, types.MachineStateString(desired),
+	)
+
+	// TODO(jaypipes): Send an event notification
+
+	return updated, nil
+}
+
+// MachineStart sets the desired state of a machine to RUNNING
+func (s *Server) MachineStart(
+	ctx context.Context,
+	req *pb.MachineGetRequest,
+) (*pb.Machine, error) {
+	return s.machineSetDesiredState(
+		ctx, req.Session, req.Search, pb.MachineState_MACHINE_RUNNING,
+	)
+}
+
+// MachineStop sets the desired state of a machine to STOPPED
+func (s *Server) MachineStop(
+	ctx context.Context,
+	req *pb.MachineGetRequest,
+) (*pb.Machine, error) {
+	return s.machineSetDesiredState(
+		ctx, req.Session, req.Search, pb.MachineState_MACHINE_STOPPED,
+	)
+}
+
+// MachineStateSet records a transition of the observed state of a machine.
+// Returns an error if the machine may not transition from its current
+// observed state to the supplied state.
+func (s *Server) MachineStateSet(
+	ctx context.Context,
+	req *pb.MachineStateSetRequest,
+) (*pb.Machine, error) {
+	// TODO(jaypipes): AUTHZ check if the caller manages machines on the
+	// machine's provider
+
+	if req.Search == "" {
+		return nil, ErrSearchRequired
+	}
+	sess := req.Session
+	m, err := s.machineGet(ctx, sess, req.Search)
+	if err != nil {
+		return nil, err
+	}
+	if !types.MachineStateTransitionValid(m.State, req.State) {
+		return nil, errMachineStateTransitionInvalid(
+			types.MachineStateString(m.State),
+			types.MachineStateString(req.State),
+		)
+	}
+	from := m.State
+	m.State = req.State
+	m.StateReason = req.Reason
+	m.StateTime = time.Now().Unix()
+	updated, err := s.machineUpdate(ctx, sess, m)
+	if err != nil {
+		return nil, err
+	}
+	s.log.ForContext(ctx).L1(
+		"machine with UUID %s transitioned from state %s to %s",
+		updated.Uuid,
+		types.MachineStateString(from),
+		types.MachineStateString(updated.State),
+	)
+	return updated, nil
+}
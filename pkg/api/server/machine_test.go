@@ -0,0 +1,62 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/runmachine-io/runmachine/pkg/api/types"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+func TestMachineObjectProperties(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	role, err := types.NewProperty("role", "database")
+	require.Nil(err)
+	m := &pb.Machine{
+		Partition: "part0",
+		Project:   "proj0",
+		Uuid:      "8b4a3ad5c8e44bb8b6cba8d2b1a1ad53",
+		Name:      "db0",
+		Image:     "4f4f54c9bfb44cce9a02d4daf6f79ea3",
+		Provider:  "0dc1bb6c3f2c4c8e9e2f6bd0d8a1f5c2",
+		Resources: map[string]uint64{
+			"runm.cpu.dedicated": 4,
+			"runm.memory":        8 << 30,
+		},
+		DesiredState: pb.MachineState_MACHINE_RUNNING,
+		State:        pb.MachineState_MACHINE_ERROR,
+		StateReason:  "failed to write image to disk",
+		StateTime:    1539820800,
+		Properties:   []*pb.Property{role},
+		Tags:         []string{"prod"},
+	}
+	props, err := machineObjectProperties(m)
+	require.Nil(err)
+
+	keys := make([]string, len(props))
+	for x, prop := range props {
+		keys[x] = prop.Key
+	}
+	assert.Equal(
+		[]string{
+			"desired_state", "image", "provider", "resources", "role",
+			"state", "state_reason", "state_time",
+		},
+		keys,
+	)
+
+	obj := &pb.Object{
+		Partition:  m.Partition,
+		ObjectType: "runm.machine",
+		Project:    m.Project,
+		Uuid:       m.Uuid,
+		Name:       m.Name,
+		Properties: props,
+		Tags:       m.Tags,
+	}
+	assert.Equal(m, machineFromObject(obj))
+}
@@ -2,65 +2,23 @@ package server
 
 import (
 	"context"
-	"fmt"
 	"io"
 
 	"github.com/runmachine-io/runmachine/pkg/util"
 	pb "github.com/runmachine-io/runmachine/proto"
-	"google.golang.org/grpc"
 )
 
-// TODO(jaypipes): Add retry behaviour
-func (s *Server) metaConnect(addr string) (*grpc.ClientConn, error) {
-	var opts []grpc.DialOption
-	// TODO(jaypipes): Don't hardcode this to WithInsecure
-	opts = append(opts, grpc.WithInsecure())
-	conn, err := grpc.Dial(addr, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return conn, nil
-}
-
-// metaClient returns a metadata service client. We look up the metadata
-// service endpoint using the gsr service registry, connect to that endpoint,
-// and if successful, return a constructed gRPC client to the metadata service
-// at that endpoint.
+// metaClient returns a metadata service client. RPCs made with the client are
+// balanced across the metadata service endpoints registered in the gsr
+// service registry.
 func (s *Server) metaClient() (pb.RunmMetadataClient, error) {
-	if s.metaclient != nil {
-		return s.metaclient, nil
-	}
-	// TODO(jaypipes): Move this code into a generic ServiceRegistry
-	// struct/interface and allow for randomizing the pick of an endpoint from
-	// multiple endpoints of the same service.
-	var conn *grpc.ClientConn
-	var addr string
-	var err error
-	for _, ep := range s.registry.Endpoints(s.cfg.MetadataServiceName) {
-		addr = ep.Address
-		s.log.L3("connecting to metadata service at %s...", addr)
-		if conn, err = s.metaConnect(addr); err != nil {
-			s.log.ERR(
-				"failed to connect to metadata service endpoint at %s: %s",
-				addr, err,
-			)
-		} else {
-			break
-		}
-	}
-	if conn == nil {
-		msg := "unable to connect to any metadata service endpoint."
-		s.log.ERR(msg)
-		return nil, fmt.Errorf(msg)
-	}
-	s.metaclient = pb.NewRunmMetadataClient(conn)
-	s.log.L2("connected to metadata service at %s", addr)
 	return s.metaclient, nil
 }
 
 // partitionsGetMatching takes an API PartitionFilter and returns a list of
 // Partition messages matching the filter
 func (s *Server) partitionsGetMatchingFilter(
+	ctx context.Context,
 	sess *pb.Session,
 	filter *pb.SearchFilter,
 ) ([]*pb.Partition, error) {
@@ -75,15 +33,34 @@ func (s *Server) partitionsGetMatchingFilter(
 			UsePrefix: filter.UsePrefix,
 		}
 	}
+	return s.partitionsFind(ctx, sess, []*pb.PartitionFindFilter{mfil})
+}
+
+// partitionsGetAll returns a list of Partition messages for all partitions
+func (s *Server) partitionsGetAll(
+	ctx context.Context,
+	sess *pb.Session,
+) ([]*pb.Partition, error) {
+	return s.partitionsFind(ctx, sess, nil)
+}
+
+// partitionsFind returns a list of Partition messages matching any of the
+// supplied metadata service partition filters, or all partitions if there are
+// no filters
+func (s *Server) partitionsFind(
+	ctx context.Context,
+	sess *pb.Session,
+	any []*pb.PartitionFindFilter,
+) ([]*pb.Partition, error) {
 	mc, err := s.metaClient()
 	if err != nil {
 		return nil, err
 	}
 	req := &pb.PartitionFindRequest{
 		Session: sess,
-		Any:     []*pb.PartitionFindFilter{mfil},
+		Any:     any,
 	}
-	stream, err := mc.PartitionFind(context.Background(), req)
+	stream, err := mc.PartitionFind(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -105,18 +82,20 @@ func (s *Server) partitionsGetMatchingFilter(
 // partitionGet returns a partition record matching the supplied UUID or name
 // If no such partition could be found, returns (nil, ErrNotFound)
 func (s *Server) partitionGet(
+	ctx context.Context,
 	sess *pb.Session,
 	search string,
 ) (*pb.Partition, error) {
 	if util.IsUuidLike(search) {
-		return s.partitionGetByUuid(sess, search)
+		return s.partitionGetByUuid(ctx, sess, search)
 	}
-	return s.partitionGetByName(sess, search)
+	return s.partitionGetByName(ctx, sess, search)
 }
 
 // partitionGetByUuid returns a partition record matching the supplied UUID
 // key. If no such partition could be found, returns (nil, ErrNotFound)
 func (s *Server) partitionGetByUuid(
+	ctx context.Context,
 	sess *pb.Session,
 	uuid string,
 ) (*pb.Partition, error) {
@@ -128,7 +107,7 @@ func (s *Server) partitionGetByUuid(
 	if err != nil {
 		return nil, err
 	}
-	rec, err := mc.PartitionGetByUuid(context.Background(), req)
+	rec, err := mc.PartitionGetByUuid(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -143,6 +122,7 @@ func (s *Server) partitionGetByUuid(
 // partitionGetByName returns a partition record matching the supplied name.
 // If no such partition could be found, returns (nil, ErrNotFound)
 func (s *Server) partitionGetByName(
+	ctx context.Context,
 	sess *pb.Session,
 	name string,
 ) (*pb.Partition, error) {
@@ -154,7 +134,7 @@ func (s *Server) partitionGetByName(
 	if err != nil {
 		return nil, err
 	}
-	rec, err := mc.PartitionGetByName(context.Background(), req)
+	rec, err := mc.PartitionGetByName(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -170,6 +150,7 @@ func (s *Server) partitionGetByName(
 // pb.Partition message representing the newly-created partition in the
 // metadata service.
 func (s *Server) partitionCreate(
+	ctx context.Context,
 	sess *pb.Session,
 	part *pb.Partition,
 ) (*pb.Partition, error) {
@@ -181,7 +162,7 @@ func (s *Server) partitionCreate(
 	if err != nil {
 		return nil, err
 	}
-	resp, err := mc.PartitionCreate(context.Background(), req)
+	resp, err := mc.PartitionCreate(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -191,6 +172,7 @@ func (s *Server) partitionCreate(
 // uuidFromName returns a UUID matching the supplied object type and name. If
 // no such object could be found, returns ("", ErrNotFound)
 func (s *Server) uuidFromName(
+	ctx context.Context,
 	sess *pb.Session,
 	objType string,
 	name string,
@@ -204,7 +186,7 @@ func (s *Server) uuidFromName(
 	if err != nil {
 		return "", err
 	}
-	rec, err := mc.ObjectGetByName(context.Background(), req)
+	rec, err := mc.ObjectGetByName(ctx, req)
 	if err != nil {
 		return "", err
 	}
@@ -214,6 +196,7 @@ func (s *Server) uuidFromName(
 // objectFromUuid returns an Object message matching the supplied object UUID.
 // If no such object could be found, returns ("", ErrNotFound)
 func (s *Server) objectFromUuid(
+	ctx context.Context,
 	sess *pb.Session,
 	uuid string,
 ) (*pb.Object, error) {
@@ -225,7 +208,7 @@ func (s *Server) objectFromUuid(
 	if err != nil {
 		return nil, err
 	}
-	rec, err := mc.ObjectGetByUuid(context.Background(), req)
+	rec, err := mc.ObjectGetByUuid(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -235,10 +218,11 @@ func (s *Server) objectFromUuid(
 // nameFromUuid returns a name matching the supplied object UUID. If no such
 // object could be found, returns ("", ErrNotFound)
 func (s *Server) nameFromUuid(
+	ctx context.Context,
 	sess *pb.Session,
 	uuid string,
 ) (string, error) {
-	obj, err := s.objectFromUuid(sess, uuid)
+	obj, err := s.objectFromUuid(ctx, sess, uuid)
 	if err != nil {
 		return "", err
 	}
@@ -248,6 +232,7 @@ func (s *Server) nameFromUuid(
 // providerTypeGetByCode returns a provider type record matching the supplied
 // code. If no such provider type could be found, returns (nil, ErrNotFound)
 func (s *Server) providerTypeGetByCode(
+	ctx context.Context,
 	sess *pb.Session,
 	code string,
 ) (*pb.ProviderType, error) {
@@ -259,7 +244,7 @@ func (s *Server) providerTypeGetByCode(
 	if err != nil {
 		return nil, err
 	}
-	rec, err := mc.ProviderTypeGetByCode(context.Background(), req)
+	rec, err := mc.ProviderTypeGetByCode(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -269,34 +254,112 @@ func (s *Server) providerTypeGetByCode(
 	}, nil
 }
 
+// objectTypeGetByCode returns an object type record matching the supplied
+// code. If no such object type could be found, returns (nil, ErrNotFound)
+func (s *Server) objectTypeGetByCode(
+	ctx context.Context,
+	sess *pb.Session,
+	code string,
+) (*pb.ObjectType, error) {
+	req := &pb.ObjectTypeGetByCodeRequest{
+		Session: sess,
+		Code:    code,
+	}
+	mc, err := s.metaClient()
+	if err != nil {
+		return nil, err
+	}
+	return mc.ObjectTypeGetByCode(ctx, req)
+}
+
+// objectGetByName returns an Object message matching the supplied object type
+// and name in the supplied partition and the session's project. If partUuid
+// is empty, the session's partition is used. If no such object could be
+// found, returns (nil, ErrNotFound)
+func (s *Server) objectGetByName(
+	ctx context.Context,
+	sess *pb.Session,
+	objType string,
+	partUuid string,
+	name string,
+) (*pb.Object, error) {
+	req := &pb.ObjectGetByNameRequest{
+		Session:        sess,
+		PartitionUuid:  partUuid,
+		ObjectTypeCode: objType,
+		Name:           name,
+	}
+	mc, err := s.metaClient()
+	if err != nil {
+		return nil, err
+	}
+	return mc.ObjectGetByName(ctx, req)
+}
+
 // objectCreate creates a supplied object in the metadata service. The supplied
 // pointer to an Object is updated with fields from the newly-created object in
-// the metadata service, including any auto-created UUIDs
+// the metadata service, including any auto-created UUIDs and any properties
+// set to default values by the object's definition. The supplied subtype
+// of the object is used to look up the object definition that the metadata
+// service validates the object's properties against.
 func (s *Server) objectCreate(
+	ctx context.Context,
 	sess *pb.Session,
 	obj *pb.Object,
+	subtype string,
 ) error {
 	req := &pb.ObjectCreateRequest{
 		Session: sess,
 		Object:  obj,
+		Subtype: subtype,
 	}
 	mc, err := s.metaClient()
 	if err != nil {
 		return err
 	}
-	resp, err := mc.ObjectCreate(context.Background(), req)
+	resp, err := mc.ObjectCreate(ctx, req)
 	if err != nil {
 		return err
 	}
 	// Make sure that our object's UUID is set to the (possibly auto-created)
 	// UUID returned by the metadata service
 	obj.Uuid = resp.Object.Uuid
+	obj.Properties = resp.Object.Properties
+	obj.Generation = resp.Object.Generation
 	return nil
 }
 
+// objectUpdate replaces the properties and tags of the object with the
+// supplied object's UUID in the metadata service and returns the updated
+// object. The supplied subtype of the object is used to look up the object
+// definition that the metadata service validates the object's properties
+// against.
+func (s *Server) objectUpdate(
+	ctx context.Context,
+	sess *pb.Session,
+	obj *pb.Object,
+	subtype string,
+) (*pb.Object, error) {
+	req := &pb.ObjectUpdateRequest{
+		Session: sess,
+		Object:  obj,
+		Subtype: subtype,
+	}
+	mc, err := s.metaClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := mc.ObjectUpdate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Object, nil
+}
+
 // objectDelete deletes any object with one of the supplied UUIDs from the
 // metadata service
 func (s *Server) objectDelete(
+	ctx context.Context,
 	sess *pb.Session,
 	uuids []string,
 ) error {
@@ -308,7 +371,7 @@ func (s *Server) objectDelete(
 	if err != nil {
 		return err
 	}
-	_, err = mc.ObjectDeleteByUuids(context.Background(), req)
+	_, err = mc.ObjectDeleteByUuids(ctx, req)
 	if err != nil {
 		return err
 	}
@@ -316,11 +379,12 @@ func (s *Server) objectDelete(
 }
 
 // objectsGetMatching takes a slice of pointers to object filters and returns
-// matching pb.Object messages
+// a stream of matching pb.Object messages, ordered by UUID
 func (s *Server) objectsGetMatching(
+	ctx context.Context,
 	sess *pb.Session,
 	any []*pb.ObjectFilter,
-) ([]*pb.Object, error) {
+) (pb.RunmMetadata_ObjectFindClient, error) {
 	mc, err := s.metaClient()
 	if err != nil {
 		return nil, err
@@ -329,21 +393,5 @@ func (s *Server) objectsGetMatching(
 		Session: sess,
 		Any:     any,
 	}
-	stream, err := mc.ObjectFind(context.Background(), req)
-	if err != nil {
-		return nil, err
-	}
-
-	msgs := make([]*pb.Object, 0)
-	for {
-		msg, err := stream.Recv()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
-		msgs = append(msgs, msg)
-	}
-	return msgs, nil
+	return mc.ObjectFind(ctx, req)
 }
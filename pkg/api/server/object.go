@@ -0,0 +1,450 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/ghodss/yaml"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/runmachine-io/runmachine/pkg/api/types"
+	"github.com/runmachine-io/runmachine/pkg/selector"
+	"github.com/runmachine-io/runmachine/pkg/util"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// reservedObjectTypes maps the codes of object types that may not be written
+// with the generic object API calls to the CLI command group that manages
+// objects of that type. Objects of these types have records in other
+// runmachine services that must be kept in step with the object.
+var reservedObjectTypes = map[string]string{
+	"runm.provider": "runm provider",
+	"runm.image":    "runm image",
+	"runm.machine":  "runm machine",
+}
+
+// checkObjectTypeWritable returns an error if objects of the supplied object
+// type may not be created, updated or deleted with the generic object API
+// calls
+func checkObjectTypeWritable(objType string) error {
+	if command, reserved := reservedObjectTypes[objType]; reserved {
+		return errObjectTypeReserved(objType, command)
+	}
+	return nil
+}
+
+// objectProject returns the project an object of the supplied object type
+// should be assigned, given the project the user supplied (which may be
+// empty). Objects of PROJECT-scoped types default to the session's project
+// and may not be assigned to a project other than the session's project.
+// Objects of PARTITION-scoped types may not be assigned a project at all.
+func objectProject(
+	objType *pb.ObjectType,
+	project string,
+	sess *pb.Session,
+) (string, error) {
+	if objType.Scope != pb.ObjectTypeScope_PROJECT {
+		if project != "" {
+			return "", errObjectTypeNotProjectScoped(objType.Code)
+		}
+		return "", nil
+	}
+	if project == "" {
+		return sess.Project, nil
+	}
+	// TODO(jaypipes): AUTHZ check if user can create objects in projects
+	// other than their session's project (i.e. an admin)
+	if project != sess.Project {
+		return "", errObjectProjectMismatch(project, sess.Project)
+	}
+	return project, nil
+}
+
+// objectPropertiesFromMap returns a sorted slice of Property messages from
+// the supplied map of property key to value
+func objectPropertiesFromMap(
+	in map[string]interface{},
+) ([]*pb.Property, error) {
+	props := make([]*pb.Property, 0, len(in))
+	for key, val := range in {
+		prop, err := types.NewProperty(key, val)
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, prop)
+	}
+	types.SortProperties(props)
+	return props, nil
+}
+
+// objectGet returns the object of the supplied object type with the supplied
+// UUID or name. Objects are looked up by name in the supplied partition UUID
+// or name, or if empty, the session's partition. If no such object could be
+// found, returns (nil, ErrNotFound)
+func (s *Server) objectGet(
+	ctx context.Context,
+	sess *pb.Session,
+	objType string,
+	partition string,
+	search string,
+) (*pb.Object, error) {
+	if util.IsUuidLike(search) {
+		obj, err := s.objectFromUuid(ctx, sess, search)
+		if err != nil {
+			return nil, err
+		}
+		// NOTE(jaypipes): We return ErrNotFound instead of some type mismatch
+		// error so that we don't leak the existence of other types of objects
+		if obj.ObjectType != objType {
+			return nil, ErrNotFound
+		}
+		return obj, nil
+	}
+	partUuid := ""
+	if partition != "" {
+		part, err := s.partitionGet(ctx, sess, partition)
+		if err != nil {
+			return nil, err
+		}
+		partUuid = part.Uuid
+	}
+	return s.objectGetByName(ctx, sess, objType, partUuid, search)
+}
+
+// objectFilters returns the metadata service object filters matching objects
+// of the supplied object type and, if the supplied selector string is not
+// empty, the selector. Partition names in the selector are translated to
+// partition UUIDs. If the selector can never match any object, returns an
+// empty slice.
+func (s *Server) objectFilters(
+	ctx context.Context,
+	sess *pb.Session,
+	objType string,
+	sel string,
+) ([]*pb.ObjectFilter, error) {
+	typeFilter := &pb.ObjectTypeFilter{
+		CodeFilter: &pb.CodeFilter{
+			Code: objType,
+		},
+	}
+	if sel == "" {
+		return []*pb.ObjectFilter{
+			{ObjectTypeFilter: typeFilter},
+		}, nil
+	}
+	filters, err := selector.ObjectFilters(sel)
+	if err != nil {
+		return nil, errSelectorInvalid(err)
+	}
+	res := make([]*pb.ObjectFilter, 0, len(filters))
+	for _, f := range filters {
+		if f.ObjectTypeFilter != nil {
+			return nil, errSelectorInvalid(
+				fmt.Errorf("type may not be used in an object selector"),
+			)
+		}
+		f.ObjectTypeFilter = typeFilter
+		if f.PartitionFilter != nil {
+			search := f.PartitionFilter.Uuids[0]
+			part, err := s.partitionGet(ctx, sess, search)
+			if err != nil {
+				se, ok := status.FromError(err)
+				if ok && se.Code() == codes.NotFound {
+					// This filter will never match any objects since the
+					// partition doesn't exist
+					continue
+				}
+				return nil, err
+			}
+			f.PartitionFilter.Uuids = []string{part.Uuid}
+		}
+		res = append(res, f)
+	}
+	return res, nil
+}
+
+// ObjectGet looks up an object of a specific object type by UUID or name and
+// returns an Object protobuf message.
+func (s *Server) ObjectGet(
+	ctx context.Context,
+	req *pb.ObjectGetRequest,
+) (*pb.Object, error) {
+	if req.ObjectType == "" {
+		return nil, ErrObjectTypeRequired
+	}
+	if req.Search == "" {
+		return nil, ErrSearchRequired
+	}
+	return s.objectGet(
+		ctx, req.Session, req.ObjectType, req.Partition, req.Search,
+	)
+}
+
+// ObjectList streams zero or more Object messages of a specific object type
+// back to the client that match an optional selector
+func (s *Server) ObjectList(
+	req *pb.ObjectListRequest,
+	stream pb.RunmAPI_ObjectListServer,
+) error {
+	ctx := stream.Context()
+	if req.ObjectType == "" {
+		return ErrObjectTypeRequired
+	}
+	any, err := s.objectFilters(ctx, req.Session, req.ObjectType, req.Selector)
+	if err != nil {
+		return err
+	}
+	if len(any) == 0 {
+		s.log.ForContext(ctx).L3(
+			"ObjectList: returning nil since all filters evaluated to " +
+				"impossible conditions",
+		)
+		return nil
+	}
+	objs, err := s.objectsGetMatching(ctx, req.Session, any)
+	if err != nil {
+		return err
+	}
+	for {
+		obj, err := objs.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err = stream.Send(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateObjectCreateRequest unmarshals the supplied request payload into an
+// Object message, ensuring the object's type may be written with the generic
+// object API calls, its partition exists and its project is valid for the
+// object type's scope
+func (s *Server) validateObjectCreateRequest(
+	ctx context.Context,
+	req *pb.CreateRequest,
+) (*pb.Object, error) {
+	var input types.Object
+	if err := yaml.Unmarshal(req.Payload, &input); err != nil {
+		return nil, err
+	}
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	if input.Name == "" {
+		return nil, fmt.Errorf("name required")
+	}
+	if err := checkObjectTypeWritable(input.ObjectType); err != nil {
+		return nil, err
+	}
+
+	sess := req.Session
+	partUuid := sess.Partition
+	if input.Partition != "" {
+		// Check that the supplied partition exists, and if the user supplied a
+		// partition name, translate it to a partition UUID
+		part, err := s.partitionGet(ctx, sess, input.Partition)
+		if err != nil {
+			return nil, err
+		}
+		partUuid = part.Uuid
+	}
+
+	objType, err := s.objectTypeGetByCode(ctx, sess, input.ObjectType)
+	if err != nil {
+		if se, ok := status.FromError(err); ok && se.Code() == codes.NotFound {
+			return nil, errObjectTypeNotFound(input.ObjectType)
+		}
+		return nil, err
+	}
+	project, err := objectProject(objType, input.Project, sess)
+	if err != nil {
+		return nil, err
+	}
+
+	props, err := objectPropertiesFromMap(input.Properties)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Object{
+		Partition:  partUuid,
+		ObjectType: objType.Code,
+		Project:    project,
+		Uuid:       input.Uuid,
+		Name:       input.Name,
+		Properties: props,
+		Tags:       input.Tags,
+	}, nil
+}
+
+// ObjectCreate creates a new object of any object type that isn't managed by
+// its own API calls (like runm.provider)
+func (s *Server) ObjectCreate(
+	ctx context.Context,
+	req *pb.CreateRequest,
+) (*pb.ObjectCreateResponse, error) {
+	// TODO(jaypipes): AUTHZ check if user can write objects
+
+	obj, err := s.validateObjectCreateRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.ForContext(ctx).L3(
+		"creating new object of type %s in partition %s with name %s...",
+		obj.ObjectType, obj.Partition, obj.Name,
+	)
+	if err = s.objectCreate(ctx, req.Session, obj, ""); err != nil {
+		return nil, err
+	}
+	s.log.ForContext(ctx).L1(
+		"created new object with UUID %s of type %s in partition %s with "+
+			"name %s",
+		obj.Uuid, obj.ObjectType, obj.Partition, obj.Name,
+	)
+
+	return &pb.ObjectCreateResponse{
+		Object: obj,
+	}, nil
+}
+
+// ObjectUpdate replaces the properties and tags of an existing object,
+// identified by the UUID or name in the supplied request payload, with those
+// in the request payload
+func (s *Server) ObjectUpdate(
+	ctx context.Context,
+	req *pb.UpdateRequest,
+) (*pb.ObjectUpdateResponse, error) {
+	// TODO(jaypipes): AUTHZ check if user can write objects
+
+	var input types.Object
+	if err := yaml.Unmarshal(req.Payload, &input); err != nil {
+		return nil, err
+	}
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	if err := checkObjectTypeWritable(input.ObjectType); err != nil {
+		return nil, err
+	}
+
+	search := input.Uuid
+	if search == "" {
+		search = input.Name
+	}
+	obj, err := s.objectGet(
+		ctx, req.Session, input.ObjectType, input.Partition, search,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if input.Project != "" && input.Project != obj.Project {
+		return nil, fmt.Errorf("an object's project may not be changed")
+	}
+
+	props, err := objectPropertiesFromMap(input.Properties)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := s.objectUpdate(
+		ctx,
+		req.Session,
+		&pb.Object{
+			Uuid:       obj.Uuid,
+			Properties: props,
+			Tags:       input.Tags,
+		},
+		"",
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.log.ForContext(ctx).L1(
+		"updated object with UUID %s of type %s",
+		changed.Uuid, changed.ObjectType,
+	)
+
+	return &pb.ObjectUpdateResponse{
+		Object: changed,
+	}, nil
+}
+
+// ObjectDelete removes one or more objects of a specific object type, given
+// either their UUIDs or names or a selector matching the objects
+func (s *Server) ObjectDelete(
+	ctx context.Context,
+	req *pb.ObjectDeleteRequest,
+) (*pb.DeleteResponse, error) {
+	// TODO(jaypipes): AUTHZ check if user can write objects
+
+	if req.ObjectType == "" {
+		return nil, ErrObjectTypeRequired
+	}
+	if err := checkObjectTypeWritable(req.ObjectType); err != nil {
+		return nil, err
+	}
+	if req.Selector != "" && len(req.Search) > 0 {
+		return nil, ErrSelectorWithSearch
+	}
+	if req.Selector == "" && len(req.Search) == 0 {
+		return nil, ErrAtLeastOneObjectRequired
+	}
+
+	sess := req.Session
+	uuids := make([]string, 0, len(req.Search))
+	if req.Selector == "" {
+		for _, search := range req.Search {
+			// NOTE(jaypipes): Looking up the object first ensures that the
+			// object is owned by the session's partition and project, since
+			// the metadata service doesn't check ownership on delete
+			obj, err := s.objectGet(ctx, sess, req.ObjectType, "", search)
+			if err != nil {
+				return nil, err
+			}
+			uuids = append(uuids, obj.Uuid)
+		}
+	} else {
+		any, err := s.objectFilters(ctx, sess, req.ObjectType, req.Selector)
+		if err != nil {
+			return nil, err
+		}
+		if len(any) > 0 {
+			objs, err := s.objectsGetMatching(ctx, sess, any)
+			if err != nil {
+				return nil, err
+			}
+			for {
+				obj, err := objs.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return nil, err
+				}
+				uuids = append(uuids, obj.Uuid)
+			}
+		}
+	}
+	if len(uuids) == 0 {
+		return nil, ErrNoMatchingRecords
+	}
+
+	if err := s.objectDelete(ctx, sess, uuids); err != nil {
+		return nil, err
+	}
+	s.log.ForContext(ctx).L1(
+		"deleted %d objects of type %s", len(uuids), req.ObjectType,
+	)
+
+	// TODO(jaypipes): Send an event notification
+
+	return &pb.DeleteResponse{
+		NumDeleted: uint64(len(uuids)),
+	}, nil
+}
@@ -0,0 +1,352 @@
+package server
+
+import (
+	"context"
+	"io"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/runmachine-io/runmachine/pkg/api/types"
+	"github.com/runmachine-io/runmachine/pkg/errors"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// DefinitionGet looks up the object definition for an object type, optionally
+// overridden for a partition (by UUID or name) and object subtype.
+func (s *Server) DefinitionGet(
+	ctx context.Context,
+	req *pb.DefinitionGetRequest,
+) (*pb.ObjectDefinition, error) {
+	if req.ObjectType == "" {
+		return nil, ErrObjectTypeRequired
+	}
+	partUuid := ""
+	if req.Partition != "" {
+		// Translate any supplied partition identifier into a UUID
+		part, err := s.partitionGet(ctx, req.Session, req.Partition)
+		if err != nil {
+			return nil, err
+		}
+		partUuid = part.Uuid
+	}
+	return s.objectDefinitionGet(
+		ctx, req.Session, req.ObjectType, partUuid, req.Subtype, req.Resolve,
+	)
+}
+
+// validateDefinitionSetRequest ensures that the data the user sent in the
+// request payload can be unmarshal'd properly into YAML and that the data is
+// valid. If the user supplied a partition name, the request's Partition field
+// is set to the partition's UUID.
+func (s *Server) validateDefinitionSetRequest(
+	ctx context.Context,
+	req *pb.DefinitionSetRequest,
+) (*pb.ObjectDefinition, error) {
+	if req.ObjectType == "" {
+		return nil, ErrObjectTypeRequired
+	}
+	var input types.ObjectDefinition
+	if err := yaml.Unmarshal(req.Payload, &input); err != nil {
+		return nil, err
+	}
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	display := req.ObjectType + " GLOBAL"
+	if req.Partition != "" {
+		// Check that any supplied partition exists, and if the user supplied a
+		// partition name, translate it to a partition UUID
+		part, err := s.partitionGet(ctx, req.Session, req.Partition)
+		if err != nil {
+			if err == errors.ErrNotFound {
+				return nil, errPartitionNotFound(req.Partition)
+			}
+			s.log.ForContext(ctx).ERR(
+				"failed checking object definition's partition: %s", err,
+			)
+			return nil, ErrUnknown
+		}
+		display = req.ObjectType + " partition: '" + part.Uuid + "'"
+		req.Partition = part.Uuid
+	}
+	if req.Subtype != "" {
+		display += " subtype: '" + req.Subtype + "'"
+	}
+
+	propPerms := make([]*pb.PropertyPermissions, 0)
+
+	// Ensure that we've got some default access permissions for any properties
+	// that have been defined on the object definition
+	for propKey, propDef := range input.PropertyDefinitions {
+		if len(propDef.Permissions) == 0 {
+			s.log.ForContext(ctx).L3(
+				"setting default permissions on object definition "+
+					"for %s for property key '%s' to READ/WRITE "+
+					"for project '%s' and READ any",
+				display, propKey, req.Session.Project,
+			)
+			propPerms = append(propPerms,
+				&pb.PropertyPermissions{
+					Key: propKey,
+					Permissions: []*pb.PropertyPermission{
+						&pb.PropertyPermission{
+							Project: req.Session.Project,
+							Permission: types.PERMISSION_READ |
+								types.PERMISSION_WRITE,
+						},
+						&pb.PropertyPermission{
+							Permission: types.PERMISSION_READ,
+						},
+					},
+				},
+			)
+		} else {
+			// Make sure that the project that created the object definition
+			// can read and write the properties defined on it...
+			foundProj := false
+			for _, perm := range propDef.Permissions {
+				if perm.Project != "" && perm.Project == req.Session.Project {
+					permCode := perm.PermissionUint32()
+					if (permCode & types.PERMISSION_WRITE) == 0 {
+						s.log.ForContext(ctx).L1(
+							"added missing WRITE permission for "+
+								"object definition for %s "+
+								"for property key '%s' in project '%s'",
+							display, propKey, perm.Project,
+						)
+						permCode |= types.PERMISSION_WRITE
+					}
+					foundProj = true
+					propPerms = append(propPerms,
+						&pb.PropertyPermissions{
+							Key: propKey,
+							Permissions: []*pb.PropertyPermission{
+								&pb.PropertyPermission{
+									Project:    perm.Project,
+									Role:       perm.Role,
+									Permission: permCode,
+								},
+							},
+						},
+					)
+					break
+				}
+			}
+			if !foundProj {
+				s.log.ForContext(ctx).L1(
+					"added missing WRITE permission for object definition "+
+						"for %s for property key '%s' in project '%s'",
+					display, propKey, req.Session.Project,
+				)
+				propPerms = append(propPerms,
+					&pb.PropertyPermissions{
+						Key: propKey,
+						Permissions: []*pb.PropertyPermission{
+							&pb.PropertyPermission{
+								Project: req.Session.Project,
+								Permission: types.PERMISSION_READ |
+									types.PERMISSION_WRITE,
+							},
+						},
+					},
+				)
+			}
+		}
+	}
+	return &pb.ObjectDefinition{
+		Schema:              input.JSONSchemaString(req.ObjectType),
+		PropertyPermissions: propPerms,
+	}, nil
+}
+
+// DefinitionSet creates or updates the schema and property permissions for
+// objects of a particular object type, optionally overriding them for a
+// partition and object subtype
+func (s *Server) DefinitionSet(
+	ctx context.Context,
+	req *pb.DefinitionSetRequest,
+) (*pb.ObjectDefinitionSetResponse, error) {
+	// TODO(jaypipes): AUTHZ check if user can write definitions
+
+	odef, err := s.validateDefinitionSetRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	odef, err = s.objectDefinitionSet(
+		ctx, req.Session, odef, req.ObjectType, req.Partition, req.Subtype,
+	)
+	if err != nil {
+		s.log.ForContext(ctx).ERR(
+			"failed setting object definition for %s objects "+
+				"in partition '%s': %s",
+			req.ObjectType, req.Partition, err,
+		)
+		return nil, err
+	}
+
+	return &pb.ObjectDefinitionSetResponse{
+		ObjectDefinition: odef,
+	}, nil
+}
+
+// definitionPartitionUuid returns the UUID of the partition with the supplied
+// UUID or name, or empty string if no partition was supplied
+func (s *Server) definitionPartitionUuid(
+	ctx context.Context,
+	sess *pb.Session,
+	partition string,
+) (string, error) {
+	if partition == "" {
+		return "", nil
+	}
+	part, err := s.partitionGet(ctx, sess, partition)
+	if err != nil {
+		if err == errors.ErrNotFound {
+			return "", errPartitionNotFound(partition)
+		}
+		return "", err
+	}
+	return part.Uuid, nil
+}
+
+// DefinitionHistory streams every revision of the object definition for an
+// object type, optionally overridden for a partition (by UUID or name) and
+// object subtype, oldest first
+func (s *Server) DefinitionHistory(
+	req *pb.DefinitionHistoryRequest,
+	stream pb.RunmAPI_DefinitionHistoryServer,
+) error {
+	ctx := stream.Context()
+	if req.ObjectType == "" {
+		return ErrObjectTypeRequired
+	}
+	partUuid, err := s.definitionPartitionUuid(ctx, req.Session, req.Partition)
+	if err != nil {
+		return err
+	}
+	metareq := &pb.ObjectDefinitionHistoryRequest{
+		Session:       req.Session,
+		ObjectType:    req.ObjectType,
+		PartitionUuid: partUuid,
+		Subtype:       req.Subtype,
+	}
+	mc, err := s.metaClient()
+	if err != nil {
+		return err
+	}
+	metastream, err := mc.ObjectDefinitionHistory(ctx, metareq)
+	if err != nil {
+		return err
+	}
+	for {
+		def, err := metastream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err = stream.Send(def); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DefinitionRollback sets the object definition for an object type, optionally
+// overridden for a partition (by UUID or name) and object subtype, back to an
+// earlier revision
+func (s *Server) DefinitionRollback(
+	ctx context.Context,
+	req *pb.DefinitionRollbackRequest,
+) (*pb.ObjectDefinitionSetResponse, error) {
+	// TODO(jaypipes): AUTHZ check if user can write definitions
+
+	if req.ObjectType == "" {
+		return nil, ErrObjectTypeRequired
+	}
+	partUuid, err := s.definitionPartitionUuid(ctx, req.Session, req.Partition)
+	if err != nil {
+		return nil, err
+	}
+	metareq := &pb.ObjectDefinitionRollbackRequest{
+		Session:       req.Session,
+		ObjectType:    req.ObjectType,
+		PartitionUuid: partUuid,
+		Subtype:       req.Subtype,
+		Revision:      req.Revision,
+	}
+	mc, err := s.metaClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := mc.ObjectDefinitionRollback(ctx, metareq)
+	if err != nil {
+		s.log.ForContext(ctx).ERR(
+			"failed rolling back object definition for %s objects "+
+				"in partition '%s' to revision %d: %s",
+			req.ObjectType, req.Partition, req.Revision, err,
+		)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// objectDefinitionGet returns the object definition for the supplied object
+// type that has been set for the supplied partition and object subtype. If
+// resolve is true and no such object definition has been set, returns the
+// object definition that would be used to validate objects in the partition
+// and of the subtype.
+//
+// If no such object definition could be found, returns (nil, ErrNotFound)
+func (s *Server) objectDefinitionGet(
+	ctx context.Context,
+	sess *pb.Session,
+	objType string,
+	partUuid string,
+	subtype string,
+	resolve bool,
+) (*pb.ObjectDefinition, error) {
+	req := &pb.ObjectDefinitionGetRequest{
+		Session:       sess,
+		ObjectType:    objType,
+		PartitionUuid: partUuid,
+		Subtype:       subtype,
+		Resolve:       resolve,
+	}
+	mc, err := s.metaClient()
+	if err != nil {
+		return nil, err
+	}
+	return mc.ObjectDefinitionGet(ctx, req)
+}
+
+// objectDefinitionSet takes an object definition and saves it in the metadata
+// service, returning the saved object definition
+func (s *Server) objectDefinitionSet(
+	ctx context.Context,
+	sess *pb.Session,
+	def *pb.ObjectDefinition,
+	objType string,
+	partUuid string,
+	subtype string,
+) (*pb.ObjectDefinition, error) {
+	req := &pb.ObjectDefinitionSetRequest{
+		Session:          sess,
+		ObjectType:       objType,
+		PartitionUuid:    partUuid,
+		Subtype:          subtype,
+		ObjectDefinition: def,
+	}
+	mc, err := s.metaClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := mc.ObjectDefinitionSet(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.ObjectDefinition, nil
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+func TestObjectProject(t *testing.T) {
+	assert := assert.New(t)
+
+	sess := &pb.Session{
+		User:      "admin",
+		Project:   "proj0",
+		Partition: "part0",
+	}
+	projType := &pb.ObjectType{
+		Code:  "runm.image",
+		Scope: pb.ObjectTypeScope_PROJECT,
+	}
+	partType := &pb.ObjectType{
+		Code:  "runm.provider_group",
+		Scope: pb.ObjectTypeScope_PARTITION,
+	}
+
+	// Objects of a project-scoped type default to the session's project
+	project, err := objectProject(projType, "", sess)
+	assert.Nil(err)
+	assert.Equal("proj0", project)
+
+	project, err = objectProject(projType, "proj0", sess)
+	assert.Nil(err)
+	assert.Equal("proj0", project)
+
+	// ... and may not be created in some other project
+	_, err = objectProject(projType, "proj1", sess)
+	assert.NotNil(err)
+
+	// Objects of a partition-scoped type never have a project
+	project, err = objectProject(partType, "", sess)
+	assert.Nil(err)
+	assert.Equal("", project)
+
+	_, err = objectProject(partType, "proj0", sess)
+	assert.NotNil(err)
+}
+
+func TestCheckObjectTypeWritable(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(checkObjectTypeWritable("runm.provider_group"))
+	assert.NotNil(checkObjectTypeWritable("runm.provider"))
+	assert.NotNil(checkObjectTypeWritable("runm.image"))
+	assert.NotNil(checkObjectTypeWritable("runm.machine"))
+}
@@ -21,7 +21,7 @@ func (s *Server) PartitionGet(
 	if req.Filter == nil || req.Filter.PrimaryFilter == nil || req.Filter.PrimaryFilter.Search == "" {
 		return nil, ErrSearchRequired
 	}
-	return s.partitionGet(req.Session, req.Filter.PrimaryFilter.Search)
+	return s.partitionGet(ctx, req.Session, req.Filter.PrimaryFilter.Search)
 }
 
 // PartitionList streams zero or more Partition objects back to the client that
@@ -30,6 +30,7 @@ func (s *Server) PartitionList(
 	req *pb.PartitionListRequest,
 	stream pb.RunmAPI_PartitionListServer,
 ) error {
+	ctx := stream.Context()
 	metareq := &pb.PartitionFindRequest{
 		Session: req.Session,
 		// TODO(jaypipes): Any:     buildPartitionFilters(),
@@ -38,7 +39,7 @@ func (s *Server) PartitionList(
 	if err != nil {
 		return err
 	}
-	metastream, err := mc.PartitionFind(context.Background(), metareq)
+	metastream, err := mc.PartitionFind(ctx, metareq)
 	if err != nil {
 		return err
 	}
@@ -71,6 +72,7 @@ func (s *Server) PartitionList(
 // request payload can be unmarshal'd properly into YAML and contains all
 // relevant fields
 func (s *Server) validatePartitionCreateRequest(
+	ctx context.Context,
 	req *pb.CreateRequest,
 ) (*types.Partition, error) {
 	var p types.Partition
@@ -89,7 +91,7 @@ func (s *Server) PartitionCreate(
 ) (*pb.PartitionCreateResponse, error) {
 	// TODO(jaypipes): AUTHZ check if user can write a partition
 
-	input, err := s.validatePartitionCreateRequest(req)
+	input, err := s.validatePartitionCreateRequest(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -99,21 +101,21 @@ func (s *Server) PartitionCreate(
 		Uuid: input.Uuid,
 		Name: input.Name,
 	}
-	created, err := s.partitionCreate(req.Session, partObj)
+	created, err := s.partitionCreate(ctx, req.Session, partObj)
 	if err != nil {
 		if s, ok := status.FromError(err); ok {
 			if s.Code() == codes.AlreadyExists {
 				return nil, ErrDuplicate
 			}
 		}
-		s.log.ERR(
+		s.log.ForContext(ctx).ERR(
 			"failed creating partition in metadata service: %s",
 			err,
 		)
 		return nil, ErrUnknown
 	}
 
-	s.log.L1(
+	s.log.ForContext(ctx).L1(
 		"created new partition with UUID %s and name %s",
 		created.Uuid,
 		created.Name,
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"context"
+	"io"
+
+	"google.golang.org/grpc"
+
+	"github.com/runmachine-io/runmachine/pkg/tracing"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// peer is a peered runmachine deployment that owns one or more partitions.
+// Reads of objects in those partitions are forwarded to the runm-api endpoint
+// of the peer instead of being answered by the local metadata and resource
+// services.
+type peer struct {
+	address string
+	client  pb.RunmAPIClient
+}
+
+// TODO(jaypipes): Add retry behaviour
+func (s *Server) peerConnect(addr string) (*grpc.ClientConn, error) {
+	var opts []grpc.DialOption
+	// TODO(jaypipes): Don't hardcode this to WithInsecure
+	opts = append(opts, grpc.WithInsecure())
+	opts = append(opts, grpc.WithUnaryInterceptor(tracing.UnaryClientInterceptor()))
+	opts = append(opts, grpc.WithStreamInterceptor(tracing.StreamClientInterceptor()))
+	conn, err := grpc.Dial(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// setupPeers constructs a RunmAPI client for each distinct peer address in
+// the configuration. gRPC dials lazily, so peers that are down at startup do
+// not prevent the local runm-api service from starting.
+func (s *Server) setupPeers() error {
+	s.peers = make(map[string]*peer, len(s.cfg.Peers))
+	for _, addr := range s.cfg.Peers {
+		if _, exists := s.peers[addr]; exists {
+			continue
+		}
+		conn, err := s.peerConnect(addr)
+		if err != nil {
+			return err
+		}
+		s.peers[addr] = &peer{
+			address: addr,
+			client:  pb.NewRunmAPIClient(conn),
+		}
+	}
+	for part, addr := range s.cfg.Peers {
+		s.log.L2("partition %s is owned by peer at %s", part, addr)
+	}
+	return nil
+}
+
+// peerFromPartitionFilter returns the peer that owns the partition named in
+// the supplied partition filter, or nil if the partition is not owned by a
+// peer. Prefix searches are never forwarded since we cannot know whether the
+// prefix would match partitions owned by the local deployment.
+func (s *Server) peerFromPartitionFilter(
+	filter *pb.SearchFilter,
+) *peer {
+	if filter == nil || filter.UsePrefix || filter.Search == "" {
+		return nil
+	}
+	addr, exists := s.cfg.Peers[filter.Search]
+	if !exists {
+		return nil
+	}
+	return s.peers[addr]
+}
+
+// peerSession returns a copy of the supplied session that targets the
+// supplied partition. The partition in the user's session refers to a
+// partition in the local deployment, which the peer knows nothing about.
+func peerSession(
+	sess *pb.Session,
+	partition string,
+) *pb.Session {
+	res := &pb.Session{Partition: partition}
+	if sess != nil {
+		res.User = sess.User
+		res.Project = sess.Project
+	}
+	return res
+}
+
+// peerProviderGet forwards a ProviderGet request to the peer owning the
+// partition in the request's filter and returns the peer's provider annotated
+// with the peer's address as its origin.
+func (s *Server) peerProviderGet(
+	ctx context.Context,
+	p *peer,
+	req *pb.ProviderGetRequest,
+) (*pb.Provider, error) {
+	preq := &pb.ProviderGetRequest{
+		Session: peerSession(req.Session, req.Filter.PartitionFilter.Search),
+		Filter:  req.Filter,
+	}
+	prov, err := p.client.ProviderGet(ctx, preq)
+	if err != nil {
+		s.log.ForContext(ctx).ERR(
+			"failed getting provider from peer at %s: %s",
+			p.address, err,
+		)
+		return nil, err
+	}
+	if prov.Origin == "" {
+		prov.Origin = p.address
+	}
+	return prov, nil
+}
+
+// peerProviderList forwards a set of provider filters to the peer owning the
+// partitions in those filters and sends each provider returned by the peer,
+// annotated with the peer's address as its origin, to the supplied stream. A
+// peer may own more than one partition, so the filters are grouped by
+// partition and one request is sent to the peer for each partition.
+func (s *Server) peerProviderList(
+	ctx context.Context,
+	p *peer,
+	sess *pb.Session,
+	any []*pb.ProviderFilter,
+	stream pb.RunmAPI_ProviderListServer,
+) error {
+	parts := make([]string, 0)
+	byPart := make(map[string][]*pb.ProviderFilter, 0)
+	for _, f := range any {
+		part := ""
+		if f.PartitionFilter != nil {
+			part = f.PartitionFilter.Search
+		}
+		if _, exists := byPart[part]; !exists {
+			parts = append(parts, part)
+		}
+		byPart[part] = append(byPart[part], f)
+	}
+	for _, part := range parts {
+		preq := &pb.ProviderListRequest{
+			Session: peerSession(sess, part),
+			Any:     byPart[part],
+		}
+		if err := s.peerProviderListPartition(ctx, p, preq, stream); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// peerProviderListPartition sends a ProviderList request for a single
+// partition to the supplied peer and sends each provider returned by the peer
+// to the supplied stream
+func (s *Server) peerProviderListPartition(
+	ctx context.Context,
+	p *peer,
+	preq *pb.ProviderListRequest,
+	stream pb.RunmAPI_ProviderListServer,
+) error {
+	pstream, err := p.client.ProviderList(ctx, preq)
+	if err != nil {
+		s.log.ForContext(ctx).ERR(
+			"failed listing providers from peer at %s: %s",
+			p.address, err,
+		)
+		return err
+	}
+	for {
+		prov, err := pstream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			s.log.ForContext(ctx).ERR(
+				"failed listing providers from peer at %s: %s",
+				p.address, err,
+			)
+			return err
+		}
+		if prov.Origin == "" {
+			prov.Origin = p.address
+		}
+		if err = stream.Send(prov); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+
+	"github.com/runmachine-io/runmachine/pkg/api/server/config"
+	"github.com/runmachine-io/runmachine/pkg/logging"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// fakePeer stands in for the runm-api service of a peered runmachine
+// deployment and returns a fixed set of providers
+type fakePeer struct {
+	pb.RunmAPIServer
+	provs    []*pb.Provider
+	sessions []*pb.Session
+}
+
+func (f *fakePeer) ProviderGet(
+	ctx context.Context,
+	req *pb.ProviderGetRequest,
+) (*pb.Provider, error) {
+	f.sessions = append(f.sessions, req.Session)
+	for _, p := range f.provs {
+		if p.Name == req.Filter.PrimaryFilter.Search {
+			return p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (f *fakePeer) ProviderList(
+	req *pb.ProviderListRequest,
+	stream pb.RunmAPI_ProviderListServer,
+) error {
+	f.sessions = append(f.sessions, req.Session)
+	for _, p := range f.provs {
+		if p.Partition.Uuid != req.Session.Partition {
+			continue
+		}
+		if err := stream.Send(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectStream is a RunmAPI_ProviderListServer that records the providers
+// sent to it
+type collectStream struct {
+	grpc.ServerStream
+	provs []*pb.Provider
+}
+
+func (c *collectStream) Context() context.Context {
+	return context.Background()
+}
+
+func (c *collectStream) Send(p *pb.Provider) error {
+	c.provs = append(c.provs, p)
+	return nil
+}
+
+func startFakePeer(t *testing.T, fp *fakePeer) (string, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	srv := grpc.NewServer()
+	pb.RegisterRunmAPIServer(srv, fp)
+	go srv.Serve(lis)
+	return lis.Addr().String(), srv.Stop
+}
+
+func newPeeredServer(t *testing.T, peers map[string]string) *Server {
+	s := &Server{
+		log: logging.New(&logging.Config{}),
+		cfg: &config.Config{Peers: peers},
+	}
+	if err := s.setupPeers(); err != nil {
+		t.Fatalf("failed to set up peers: %s", err)
+	}
+	return s
+}
+
+func TestPeerProviderForwarding(t *testing.T) {
+	fp := &fakePeer{
+		provs: []*pb.Provider{
+			&pb.Provider{
+				Partition: &pb.Partition{Uuid: "west"},
+				Uuid:      "a5e0b7b5d4c345ef9e93d0e4a1b3f6d1",
+				Name:      "west1-row1-rack1-node1",
+			},
+		},
+	}
+	addr, stop := startFakePeer(t, fp)
+	defer stop()
+
+	s := newPeeredServer(t, map[string]string{"west": addr})
+	sess := &pb.Session{User: "alice", Project: "proj0", Partition: "east"}
+
+	stream := &collectStream{}
+	err := s.ProviderList(
+		&pb.ProviderListRequest{
+			Session: sess,
+			Any: []*pb.ProviderFilter{
+				&pb.ProviderFilter{
+					PartitionFilter: &pb.SearchFilter{Search: "west"},
+				},
+			},
+		},
+		stream,
+	)
+	assert.Nil(t, err)
+	assert.Len(t, stream.provs, 1)
+	assert.Equal(t, "west1-row1-rack1-node1", stream.provs[0].Name)
+	assert.Equal(t, addr, stream.provs[0].Origin)
+
+	p, err := s.ProviderGet(
+		context.Background(),
+		&pb.ProviderGetRequest{
+			Session: sess,
+			Filter: &pb.ProviderFilter{
+				PrimaryFilter:   &pb.SearchFilter{Search: "west1-row1-rack1-node1"},
+				PartitionFilter: &pb.SearchFilter{Search: "west"},
+			},
+		},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, addr, p.Origin)
+
+	// The session forwarded to the peer must target the peer's partition
+	// and not the partition the user targeted in the local deployment
+	for _, fsess := range fp.sessions {
+		assert.Equal(t, "west", fsess.Partition)
+		assert.Equal(t, "alice", fsess.User)
+	}
+}
+
+func TestPeerProviderForwardingMultiplePartitions(t *testing.T) {
+	fp := &fakePeer{
+		provs: []*pb.Provider{
+			&pb.Provider{
+				Partition: &pb.Partition{Uuid: "west"},
+				Uuid:      "a5e0b7b5d4c345ef9e93d0e4a1b3f6d1",
+				Name:      "west1-row1-rack1-node1",
+			},
+			&pb.Provider{
+				Partition: &pb.Partition{Uuid: "north"},
+				Uuid:      "c2d8f1e3b6a44b0f8e5d7c9a1b2e3f40",
+				Name:      "north1-row1-rack1-node1",
+			},
+		},
+	}
+	addr, stop := startFakePeer(t, fp)
+	defer stop()
+
+	// The same peer owns both partitions
+	s := newPeeredServer(t, map[string]string{"west": addr, "north": addr})
+	sess := &pb.Session{User: "alice", Project: "proj0", Partition: "east"}
+
+	stream := &collectStream{}
+	err := s.ProviderList(
+		&pb.ProviderListRequest{
+			Session: sess,
+			Any: []*pb.ProviderFilter{
+				&pb.ProviderFilter{
+					PartitionFilter: &pb.SearchFilter{Search: "west"},
+				},
+				&pb.ProviderFilter{
+					PartitionFilter: &pb.SearchFilter{Search: "north"},
+				},
+			},
+		},
+		stream,
+	)
+	assert.Nil(t, err)
+	assert.Len(t, stream.provs, 2)
+	assert.Equal(t, "west1-row1-rack1-node1", stream.provs[0].Name)
+	assert.Equal(t, "north1-row1-rack1-node1", stream.provs[1].Name)
+
+	// One request is forwarded for each partition, each targeting that
+	// partition in the peer
+	assert.Len(t, fp.sessions, 2)
+	assert.Equal(t, "west", fp.sessions[0].Partition)
+	assert.Equal(t, "north", fp.sessions[1].Partition)
+}
+
+func TestPeerFromPartitionFilter(t *testing.T) {
+	s := newPeeredServer(t, map[string]string{"west": "127.0.0.1:1"})
+
+	tests := []struct {
+		filter *pb.SearchFilter
+		peered bool
+	}{
+		{nil, false},
+		{&pb.SearchFilter{Search: "east"}, false},
+		{&pb.SearchFilter{Search: "west"}, true},
+		// Prefix searches may match local partitions and are never
+		// forwarded
+		{&pb.SearchFilter{Search: "west", UsePrefix: true}, false},
+	}
+	for _, test := range tests {
+		p := s.peerFromPartitionFilter(test.filter)
+		assert.Equal(t, test.peered, p != nil, "filter: %v", test.filter)
+	}
+}
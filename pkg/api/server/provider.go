@@ -11,8 +11,11 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/runmachine-io/runmachine/pkg/abstract"
 	"github.com/runmachine-io/runmachine/pkg/api/types"
+	"github.com/runmachine-io/runmachine/pkg/cursor"
 	"github.com/runmachine-io/runmachine/pkg/errors"
+	"github.com/runmachine-io/runmachine/pkg/selector"
 	"github.com/runmachine-io/runmachine/pkg/util"
 	pb "github.com/runmachine-io/runmachine/proto"
 )
@@ -23,32 +26,43 @@ func (s *Server) ProviderDelete(
 	ctx context.Context,
 	req *pb.ProviderDeleteRequest,
 ) (*pb.DeleteResponse, error) {
-	if len(req.Any) == 0 {
+	any, err := providerFilters(req.Any, req.Selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(any) == 0 {
 		return nil, ErrAtLeastOneProviderFilterRequired
 	}
 
-	provs, err := s.providersGetMatching(req.Session, req.Any)
+	cur, err := s.providersGetMatching(ctx, req.Session, any)
 	if err != nil {
 		return nil, err
 	}
-	if len(provs) == 0 {
-		return nil, ErrNoMatchingRecords
+	defer cur.Close()
+	uuids := make([]string, 0)
+	for cur.Next() {
+		prov := &pb.Provider{}
+		if err = cur.Scan(prov); err != nil {
+			return nil, err
+		}
+		uuids = append(uuids, prov.Uuid)
 	}
-
-	uuids := make([]string, len(provs))
-	for x, prov := range provs {
-		uuids[x] = prov.Uuid
+	if err = cur.Err(); err != nil {
+		return nil, err
+	}
+	if len(uuids) == 0 {
+		return nil, ErrNoMatchingRecords
 	}
 
 	// TODO(jaypipes): Archive the provider information?
 
 	// Delete the provider from the resource service
-	if err = s.providerDeleteByUuids(req.Session, uuids); err != nil {
+	if err = s.providerDeleteByUuids(ctx, req.Session, uuids); err != nil {
 		return nil, err
 	}
 
 	// And now delete the provider object from the metadata service
-	if err = s.objectDelete(req.Session, uuids); err != nil {
+	if err = s.objectDelete(ctx, req.Session, uuids); err != nil {
 		// TODO(jaypipes): Use Taskflow-oriented library to undo the delete
 		// that happened above in the resource service.
 		return nil, err
@@ -57,13 +71,14 @@ func (s *Server) ProviderDelete(
 	// TODO(jaypipes): Send an event notification
 
 	return &pb.DeleteResponse{
-		NumDeleted: uint64(len(provs)),
+		NumDeleted: uint64(len(uuids)),
 	}, nil
 }
 
 // providerDeleteByUuids deletes the provider records from the resource service
 // having any of the supplied UUIDs
 func (s *Server) providerDeleteByUuids(
+	ctx context.Context,
 	sess *pb.Session,
 	uuids []string,
 ) error {
@@ -72,9 +87,9 @@ func (s *Server) providerDeleteByUuids(
 		Uuids:   uuids,
 	}
 	rc, err := s.resClient()
-	_, err = rc.ProviderDeleteByUuids(context.Background(), req)
+	_, err = rc.ProviderDeleteByUuids(ctx, req)
 	if err != nil {
-		s.log.ERR(
+		s.log.ForContext(ctx).ERR(
 			"failed deleting providers with UUIDs (%s) in resource service: %s",
 			uuids, err,
 		)
@@ -96,16 +111,19 @@ func (s *Server) ProviderGet(
 	if !isValidSingleProviderFilter(req.Filter) {
 		return nil, ErrSearchRequired
 	}
+	if p := s.peerFromPartitionFilter(req.Filter.PartitionFilter); p != nil {
+		return s.peerProviderGet(ctx, p, req)
+	}
 	var err error
 	search := req.Filter.PrimaryFilter.Search
 	if !util.IsUuidLike(search) {
 		// Look up the provider's UUID in the metadata service by name
-		search, err = s.uuidFromName(req.Session, "runm.provider", search)
+		search, err = s.uuidFromName(ctx, req.Session, "runm.provider", search)
 		if err != nil {
 			return nil, err
 		}
 	}
-	p, err := s.providerGetByUuid(req.Session, search)
+	p, err := s.providerGetByUuid(ctx, req.Session, search)
 	if err != nil {
 		return nil, err
 	}
@@ -115,16 +133,17 @@ func (s *Server) ProviderGet(
 // providerGetByUuid returns a provider matching the supplied UUID key. If no
 // such provider could be found, returns (nil, ErrNotFound)
 func (s *Server) providerGetByUuid(
+	ctx context.Context,
 	sess *pb.Session,
 	uuid string,
 ) (*pb.Provider, error) {
 	// Grab the object from the metadata service
-	obj, err := s.objectFromUuid(sess, uuid)
+	obj, err := s.objectFromUuid(ctx, sess, uuid)
 	if err != nil {
 		if err == errors.ErrNotFound {
 			return nil, ErrUnknown
 		} else {
-			s.log.ERR("failed getting object with UUID %s: %s", uuid, err)
+			s.log.ForContext(ctx).ERR("failed getting object with UUID %s: %s", uuid, err)
 			return nil, ErrUnknown
 		}
 	}
@@ -137,11 +156,11 @@ func (s *Server) providerGetByUuid(
 	if err != nil {
 		return nil, err
 	}
-	p, err := rc.ProviderGetByUuid(context.Background(), req)
+	p, err := rc.ProviderGetByUuid(ctx, req)
 	if err != nil {
 		if se, ok := status.FromError(err); ok {
 			if se.Code() == codes.NotFound {
-				s.log.ERR(
+				s.log.ForContext(ctx).ERR(
 					"DATA CORRUPTION! failed getting provider with "+
 						"UUID %s: object with UUID %s exists in metadata "+
 						"service but ProviderGetByUuid returned no provider",
@@ -150,7 +169,7 @@ func (s *Server) providerGetByUuid(
 				return nil, ErrNotFound
 			}
 		}
-		s.log.ERR(
+		s.log.ForContext(ctx).ERR(
 			"failed to retrieve provider with UUID %s: %s",
 			uuid, err,
 		)
@@ -174,31 +193,88 @@ func providerMergeObject(
 	p.Tags = obj.Tags
 }
 
+// providerFilters returns the supplied provider filters or, if a selector
+// string is supplied, the provider filters the selector compiles into
+func providerFilters(
+	any []*pb.ProviderFilter,
+	sel string,
+) ([]*pb.ProviderFilter, error) {
+	if sel == "" {
+		return any, nil
+	}
+	if len(any) > 0 {
+		return nil, ErrSelectorWithFilters
+	}
+	res, err := selector.ProviderFilters(sel)
+	if err != nil {
+		return nil, errSelectorInvalid(err)
+	}
+	return res, nil
+}
+
 // ProviderList streams zero or more Provider objects back to the client that
-// match a set of optional filters
+// match a set of optional filters. Filters on partitions owned by a peered
+// runmachine deployment are forwarded to that peer and the peer's providers
+// are merged into the returned stream.
 func (s *Server) ProviderList(
 	req *pb.ProviderListRequest,
 	stream pb.RunmAPI_ProviderListServer,
 ) error {
-	provs, err := s.providersGetMatching(req.Session, req.Any)
+	ctx := stream.Context()
+	any, err := providerFilters(req.Any, req.Selector)
 	if err != nil {
 		return err
 	}
-	for _, prov := range provs {
-		if err = stream.Send(prov); err != nil {
+	local := make([]*pb.ProviderFilter, 0, len(any))
+	peers := make([]*peer, 0)
+	remote := make(map[*peer][]*pb.ProviderFilter, 0)
+	for _, f := range any {
+		p := s.peerFromPartitionFilter(f.PartitionFilter)
+		if p == nil {
+			local = append(local, f)
+			continue
+		}
+		if _, exists := remote[p]; !exists {
+			peers = append(peers, p)
+		}
+		remote[p] = append(remote[p], f)
+	}
+	if len(any) == 0 || len(local) > 0 {
+		cur, err := s.providersGetMatching(ctx, req.Session, local)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+		for cur.Next() {
+			prov := &pb.Provider{}
+			if err = cur.Scan(prov); err != nil {
+				return err
+			}
+			if err = stream.Send(prov); err != nil {
+				return err
+			}
+		}
+		if err = cur.Err(); err != nil {
+			return err
+		}
+	}
+	for _, p := range peers {
+		err := s.peerProviderList(ctx, p, req.Session, remote[p], stream)
+		if err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-// providersGetMatching returns a slice of pointers to API Provider messages
-// matching any of a set of API ProviderFilter messages.
+// providersGetMatching returns a cursor over API Provider messages matching
+// any of a set of API ProviderFilter messages, ordered by UUID. The caller is
+// responsible for closing the returned cursor.
 func (s *Server) providersGetMatching(
+	ctx context.Context,
 	sess *pb.Session,
 	any []*pb.ProviderFilter,
-) ([]*pb.Provider, error) {
-	res := make([]*pb.Provider, 0)
+) (abstract.Cursor, error) {
 	mfils := make([]*pb.ObjectFilter, 0)
 	// If the user specified one or more UUIDs or names in the incoming API
 	// provider filters, the metadata service will have already handled the
@@ -246,7 +322,7 @@ func (s *Server) providersGetMatching(
 				// name-or-UUID filter and then we pass those partition UUIDs
 				// in the object filter.
 				partObjs, err := s.partitionsGetMatchingFilter(
-					sess, filter.PartitionFilter,
+					ctx, sess, filter.PartitionFilter,
 				)
 				if err != nil {
 					return nil, err
@@ -272,6 +348,10 @@ func (s *Server) providersGetMatching(
 				mfil.PropertyFilter = filter.PropertyFilter
 				primaryFiltered = true
 			}
+			if filter.TagFilter != nil {
+				mfil.TagFilter = filter.TagFilter
+				primaryFiltered = true
+			}
 			mfils = append(mfils, mfil)
 		}
 	} else {
@@ -289,35 +369,55 @@ func (s *Server) providersGetMatching(
 
 	if len(any) > 0 && len(any) == invalidConds {
 		// No point going further, since all filters will return 0 results
-		s.log.L3(
+		s.log.ForContext(ctx).L3(
 			"ProviderList: returning nil since all filters evaluated to " +
 				"impossible conditions",
 		)
-		return res, nil
+		return cursor.Empty(), nil
 	}
 
+	// Both of the streams we join below are opened with this context so
+	// that closing the returned cursor stops them
+	ctx, cancel := context.WithCancel(ctx)
+
 	// Grab the basic object information from the metadata service first
-	objs, err := s.objectsGetMatching(sess, mfils)
+	objs, err := s.objectsGetMatching(ctx, sess, mfils)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
-	if len(objs) == 0 {
-		return res, nil
-	}
-
-	objMap := make(map[string]*pb.Object, len(objs))
-	for _, obj := range objs {
-		objMap[obj.Uuid] = obj
+	// If the user supplied filters that the metadata service has already
+	// resolved to a (hopefully small) set of objects, we pass those objects'
+	// UUIDs to the resource service so that it only returns the providers we
+	// will join against. We only read up to _PROVIDER_UUID_FILTER_MAX objects
+	// to do so, though, in order to keep memory use bounded.
+	buffered := make([]*pb.Object, 0)
+	objsDone := false
+	if primaryFiltered {
+		for len(buffered) < _PROVIDER_UUID_FILTER_MAX {
+			obj, err := objs.Recv()
+			if err == io.EOF {
+				objsDone = true
+				break
+			}
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			buffered = append(buffered, obj)
+		}
+		if objsDone && len(buffered) == 0 {
+			cancel()
+			return cursor.Empty(), nil
+		}
 	}
 
 	var uuids []string
-	if primaryFiltered {
-		uuids = make([]string, len(objMap))
-		x := 0
-		for uuid, _ := range objMap {
-			uuids[x] = uuid
-			x += 1
+	if objsDone {
+		uuids = make([]string, len(buffered))
+		for x, obj := range buffered {
+			uuids[x] = obj.Uuid
 		}
 	}
 
@@ -326,6 +426,9 @@ func (s *Server) providersGetMatching(
 	// filters to the resource service's ProviderList API call if there were
 	// filters passed to the API service's ProviderList API call.
 	rfils := make([]*pb.ProviderFindFilter, 0)
+	// A filter without any conditions for the resource service matches every
+	// provider, and so then does the whole set of filters
+	unfiltered := false
 	if len(any) > 0 {
 		for x, f := range any {
 			rfil := &pb.ProviderFindFilter{}
@@ -343,59 +446,58 @@ func (s *Server) providersGetMatching(
 					Codes: []string{f.ProviderTypeFilter.Search},
 				}
 			}
-			if primaryFiltered {
+			if uuids != nil {
 				rfil.UuidFilter = &pb.UuidsFilter{
 					Uuids: uuids,
 				}
 			}
+			if rfil.PartitionFilter == nil && rfil.ProviderTypeFilter == nil &&
+				rfil.UuidFilter == nil {
+				unfiltered = true
+			}
 			rfils = append(rfils, rfil)
 		}
 	}
+	if unfiltered {
+		rfils = nil
+	}
 
 	// OK, now we grab the provider-specific information from the resource
-	// service and mash the generic object information into the returned API
-	// Provider structs
+	// service. The returned cursor mashes the generic object information into
+	// the returned API Provider structs
 	rc, err := s.resClient()
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 	req := &pb.ProviderFindRequest{
 		Session: sess,
 		Any:     rfils,
 	}
-	stream, err := rc.ProviderFind(context.Background(), req)
+	provs, err := rc.ProviderFind(ctx, req)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
-
-	for {
-		p, err := stream.Recv()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
-		obj, exists := objMap[p.Uuid]
-		if !exists {
-			s.log.ERR(
-				"DATA CORRUPTION! provider with UUID %s returned from "+
-					"resource service but no matching object exists in "+
-					"metadata service!",
-				p.Uuid,
-			)
-			continue
-		}
-		providerMergeObject(p, obj)
-		res = append(res, p)
-	}
-	return res, nil
+	return &providerCursor{
+		log:      s.log.ForContext(ctx),
+		cancel:   cancel,
+		objs:     objs,
+		buffered: buffered,
+		objsDone: objsDone,
+		provs:    provs,
+		// When the resource service was not given the UUIDs of the matched
+		// objects, it will return providers that the user's primary
+		// filters excluded
+		strict: !primaryFiltered || objsDone,
+	}, nil
 }
 
 // validateProviderCreateRequest ensures that the data the user sent in the
 // request payload can be unmarshal'd properly into YAML, contains all relevant
 // fields and meets things like property meta validation checks.
 func (s *Server) validateProviderCreateRequest(
+	ctx context.Context,
 	req *pb.CreateRequest,
 ) (*pb.Provider, error) {
 	var input types.Provider
@@ -410,7 +512,7 @@ func (s *Server) validateProviderCreateRequest(
 	if input.Partition != "" {
 		// Check that the supplied partition exists, and if the user supplied a
 		// partition name, translate it to a partition UUID
-		part, err := s.partitionGet(req.Session, input.Partition)
+		part, err := s.partitionGet(ctx, req.Session, input.Partition)
 		if err != nil {
 			return nil, err
 		}
@@ -419,19 +521,35 @@ func (s *Server) validateProviderCreateRequest(
 
 	// Check that the supplied provider type exists
 	ptCode := input.ProviderType
-	if _, err := s.providerTypeGetByCode(req.Session, ptCode); err != nil {
+	if _, err := s.providerTypeGetByCode(ctx, req.Session, ptCode); err != nil {
 		return nil, err
 	}
 
 	// Grab the provider definition for this partition and use it to validate
 	// the supplied provider attributes and properties
-	inputJson, err := json.Marshal(&input)
+	odef, err := s.objectDefinitionGet(
+		ctx, req.Session, "runm.provider", partUuid, ptCode, true,
+	)
 	if err != nil {
 		return nil, err
 	}
-	odef, err := s.providerDefinitionGetMostExplicit(
-		req.Session, partUuid, ptCode,
-	)
+
+	// Set any properties the user didn't supply that have a default value in
+	// the provider definition
+	defaults, err := types.PropertyDefaults(odef.Schema)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range defaults {
+		if input.Properties == nil {
+			input.Properties = make(map[string]interface{}, len(defaults))
+		}
+		if _, found := input.Properties[key]; !found {
+			input.Properties[key] = value
+		}
+	}
+
+	inputJson, err := json.Marshal(&input)
 	if err != nil {
 		return nil, err
 	}
@@ -449,15 +567,15 @@ func (s *Server) validateProviderCreateRequest(
 		return nil, fmt.Errorf(msg)
 	}
 
-	props := make([]*pb.Property, 0)
-	if input.Properties != nil {
-		for key, val := range input.Properties {
-			props = append(props, &pb.Property{
-				Key:   key,
-				Value: propertyValueString(val),
-			})
+	props := make([]*pb.Property, 0, len(input.Properties))
+	for key, val := range input.Properties {
+		prop, err := types.NewProperty(key, val)
+		if err != nil {
+			return nil, err
 		}
+		props = append(props, prop)
 	}
+	types.SortProperties(props)
 
 	return &pb.Provider{
 		Partition: &pb.Partition{
@@ -473,35 +591,18 @@ func (s *Server) validateProviderCreateRequest(
 	}, nil
 }
 
-func propertyValueString(v interface{}) string {
-	switch vt := v.(type) {
-	case string:
-		return v.(string)
-	case int64:
-		return fmt.Sprintf("%d", v.(int64))
-	case float64:
-		// JSON unmarshaling apparently returns all numbers (including
-		// integers) as float64. So, I'm not entirely sure how to preserve
-		// actual floats (JSON number type)
-		return fmt.Sprintf("%d", int(v.(float64)))
-	default:
-		fmt.Printf("found unknown type for value: %s", vt)
-		return ""
-	}
-}
-
 func (s *Server) ProviderCreate(
 	ctx context.Context,
 	req *pb.CreateRequest,
 ) (*pb.ProviderCreateResponse, error) {
 	// TODO(jaypipes): AUTHZ check if user can write objects
 
-	p, err := s.validateProviderCreateRequest(req)
+	p, err := s.validateProviderCreateRequest(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	s.log.L3(
+	s.log.ForContext(ctx).L3(
 		"creating new provider in partition %s with name %s...",
 		p.Partition, p.Name,
 	)
@@ -515,16 +616,10 @@ func (s *Server) ProviderCreate(
 		Tags:       p.Tags,
 	}
 	if len(p.Properties) > 0 {
-		props := make([]*pb.Property, len(p.Properties))
-		for x, prop := range p.Properties {
-			props[x] = &pb.Property{
-				Key:   prop.Key,
-				Value: prop.Value,
-			}
-		}
-		obj.Properties = props
+		obj.Properties = p.Properties
 	}
-	if err := s.objectCreate(req.Session, obj); err != nil {
+	err = s.objectCreate(ctx, req.Session, obj, p.ProviderType.Code)
+	if err != nil {
 		return nil, err
 	}
 
@@ -532,10 +627,10 @@ func (s *Server) ProviderCreate(
 	p.Uuid = obj.Uuid
 
 	// Next save the provider record in the resource service
-	if err := s.providerCreate(req.Session, p); err != nil {
+	if err := s.providerCreate(ctx, req.Session, p); err != nil {
 		return nil, err
 	}
-	s.log.L1(
+	s.log.ForContext(ctx).L1(
 		"created new provider with UUID %s in partition %s with name %s",
 		p.Uuid, p.Partition, p.Name,
 	)
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/runmachine-io/runmachine/pkg/logging"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	// Maximum number of provider UUIDs that we will read from the metadata
+	// service's object stream and pass along to the resource service's
+	// ProviderFind call as a UUID filter. When more objects than this match
+	// the user's primary filters, we instead join against the unfiltered
+	// stream of providers from the resource service.
+	_PROVIDER_UUID_FILTER_MAX = 500
+)
+
+// providerCursor implements the abstract.Cursor interface for API Provider
+// messages by joining a stream of Object messages from the metadata service
+// with a stream of Provider messages from the resource service. Both streams
+// are ordered by UUID, so only the messages currently being joined are held in
+// memory.
+// NOTE(jaypipes): It is NOT safe to share these objects between threads.
+type providerCursor struct {
+	log *logging.Logs
+	// Cancels the context both streams were opened with
+	cancel context.CancelFunc
+	objs   pb.RunmMetadata_ObjectFindClient
+	// Objects read from objs before the join began
+	buffered []*pb.Object
+	// The object currently being joined, if already read
+	obj      *pb.Object
+	objsDone bool
+	provs    pb.RunmResource_ProviderFindClient
+	// When true, every provider returned from the resource service is
+	// expected to have a matching object in the metadata service
+	strict bool
+	cur    *pb.Provider
+	done   bool
+	err    error
+}
+
+// peekObject returns the object currently being joined, or nil if there are no
+// more objects
+func (c *providerCursor) peekObject() (*pb.Object, error) {
+	if c.obj != nil {
+		return c.obj, nil
+	}
+	// Objects read before the join began are joined before any remaining
+	// objects in the stream, even when the stream is already exhausted
+	if len(c.buffered) > 0 {
+		c.obj = c.buffered[0]
+		c.buffered = c.buffered[1:]
+		return c.obj, nil
+	}
+	if c.objsDone {
+		return nil, nil
+	}
+	obj, err := c.objs.Recv()
+	if err == io.EOF {
+		c.objsDone = true
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.obj = obj
+	return obj, nil
+}
+
+// fetch returns the next provider that has a matching object, or nil when
+// there are no more providers
+func (c *providerCursor) fetch() (*pb.Provider, error) {
+	for {
+		obj, err := c.peekObject()
+		if err != nil {
+			return nil, err
+		}
+		if obj == nil && !c.strict {
+			// Any remaining providers cannot match any object
+			return nil, nil
+		}
+		p, err := c.provs.Recv()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for obj != nil && obj.Uuid < p.Uuid {
+			// No provider exists for this object. Skip it.
+			c.obj = nil
+			if obj, err = c.peekObject(); err != nil {
+				return nil, err
+			}
+		}
+		if obj == nil || obj.Uuid != p.Uuid {
+			if c.strict {
+				c.log.ERR(
+					"DATA CORRUPTION! provider with UUID %s returned from "+
+						"resource service but no matching object exists in "+
+						"metadata service!",
+					p.Uuid,
+				)
+			}
+			continue
+		}
+		providerMergeObject(p, obj)
+		c.obj = nil
+		return p, nil
+	}
+}
+
+// peek returns the next provider to Scan without moving past it, or nil if
+// there are no more providers
+func (c *providerCursor) peek() *pb.Provider {
+	if c.cur == nil && !c.done {
+		c.cur, c.err = c.fetch()
+		if c.cur == nil || c.err != nil {
+			c.cur = nil
+			c.done = true
+			c.cancel()
+		}
+	}
+	return c.cur
+}
+
+func (c *providerCursor) Next() bool {
+	return c.peek() != nil
+}
+
+func (c *providerCursor) Scan(msg proto.Message) error {
+	p := c.peek()
+	if p == nil {
+		return fmt.Errorf("attempted to read past end of provider cursor.")
+	}
+	proto.Merge(msg, p)
+	c.cur = nil
+	return nil
+}
+
+func (c *providerCursor) Close() error {
+	c.cur = nil
+	c.done = true
+	c.cancel()
+	return nil
+}
+
+func (c *providerCursor) Err() error {
+	return c.err
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/runmachine-io/runmachine/pkg/logging"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// fakeObjectStream returns a fixed set of objects from an ObjectFind call
+type fakeObjectStream struct {
+	pb.RunmMetadata_ObjectFindClient
+	objs []*pb.Object
+}
+
+func (f *fakeObjectStream) Recv() (*pb.Object, error) {
+	if len(f.objs) == 0 {
+		return nil, io.EOF
+	}
+	obj := f.objs[0]
+	f.objs = f.objs[1:]
+	return obj, nil
+}
+
+// fakeProviderStream returns a fixed set of providers from a ProviderFind call
+type fakeProviderStream struct {
+	pb.RunmResource_ProviderFindClient
+	provs []*pb.Provider
+}
+
+func (f *fakeProviderStream) Recv() (*pb.Provider, error) {
+	if len(f.provs) == 0 {
+		return nil, io.EOF
+	}
+	p := f.provs[0]
+	f.provs = f.provs[1:]
+	return p, nil
+}
+
+func TestProviderCursorJoin(t *testing.T) {
+	assert := assert.New(t)
+
+	objs := func(uuids ...string) *fakeObjectStream {
+		res := &fakeObjectStream{}
+		for _, uuid := range uuids {
+			res.objs = append(res.objs, &pb.Object{Uuid: uuid, Name: "n" + uuid})
+		}
+		return res
+	}
+	provs := func(uuids ...string) *fakeProviderStream {
+		res := &fakeProviderStream{}
+		for _, uuid := range uuids {
+			res.provs = append(res.provs, &pb.Provider{Uuid: uuid})
+		}
+		return res
+	}
+
+	tests := []struct {
+		name     string
+		objs     *fakeObjectStream
+		buffered []*pb.Object
+		objsDone bool
+		provs    *fakeProviderStream
+		strict   bool
+		expect   []string
+	}{
+		{
+			name: "buffered and streamed objects",
+			objs: objs("b", "c", "e", "f"),
+			// Objects already read from the object stream are joined first
+			buffered: []*pb.Object{{Uuid: "a", Name: "na"}},
+			provs:    provs("a", "c", "d", "f", "g"),
+			expect:   []string{"na", "nc", "nf"},
+		},
+		{
+			// All matching objects fit in the buffer, so the object stream
+			// is already exhausted and the resource service only returns
+			// providers with the buffered objects' UUIDs
+			name: "all objects buffered",
+			objs: objs(),
+			buffered: []*pb.Object{
+				{Uuid: "a", Name: "na"},
+				{Uuid: "c", Name: "nc"},
+			},
+			objsDone: true,
+			provs:    provs("a", "c"),
+			strict:   true,
+			expect:   []string{"na", "nc"},
+		},
+	}
+	for _, test := range tests {
+		_, cancel := context.WithCancel(context.TODO())
+		cur := &providerCursor{
+			log:      logging.New(&logging.Config{}),
+			cancel:   cancel,
+			objs:     test.objs,
+			buffered: test.buffered,
+			objsDone: test.objsDone,
+			provs:    test.provs,
+			strict:   test.strict,
+		}
+
+		names := make([]string, 0)
+		for cur.Next() {
+			p := &pb.Provider{}
+			assert.Nil(cur.Scan(p), test.name)
+			names = append(names, p.Name)
+		}
+		assert.Nil(cur.Err(), test.name)
+		assert.Equal(test.expect, names, test.name)
+		assert.NotNil(cur.Scan(&pb.Provider{}), test.name)
+		cur.Close()
+	}
+}
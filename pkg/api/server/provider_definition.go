@@ -2,494 +2,240 @@ package server
 
 import (
 	"context"
-	"fmt"
 
-	"github.com/ghodss/yaml"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/runmachine-io/runmachine/pkg/api/types"
-	"github.com/runmachine-io/runmachine/pkg/errors"
 	pb "github.com/runmachine-io/runmachine/proto"
 )
 
 // ProviderDefinitionGet looks up a provider definition by partition UUID or
-// name and returns a ProviderDefinition protobuf message.
+// name and provider type and returns an ObjectDefinition protobuf message.
 func (s *Server) ProviderDefinitionGet(
 	ctx context.Context,
 	req *pb.ProviderDefinitionGetRequest,
 ) (*pb.ObjectDefinition, error) {
-	partUuid := ""
-	if req.Partition != "" {
-		// Translate any supplied partition identifier into a UUID
-		part, err := s.partitionGet(req.Session, req.Partition)
-		if err != nil {
-			return nil, err
-		}
-		partUuid = part.Uuid
-	}
-	ptCode := ""
-	if req.ProviderType != "" {
-		_, err := s.providerTypeGetByCode(req.Session, req.ProviderType)
-		if err != nil {
-			return nil, err
-		}
-		ptCode = req.ProviderType
-	}
-
-	var err error
-	var odef *pb.ObjectDefinition
-	if partUuid != "" {
-		if ptCode != "" {
-			odef, err = s.providerDefinitionGetByPartitionAndType(
-				req.Session, partUuid, ptCode,
-			)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			odef, err = s.providerDefinitionGetByPartition(
-				req.Session, partUuid,
-			)
-			if err != nil {
-				return nil, err
-			}
-		}
-	} else {
-		if ptCode != "" {
-			odef, err = s.providerDefinitionGetByType(
-				req.Session, ptCode,
-			)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			odef, err = s.providerDefinitionGetGlobalDefault(req.Session)
-			if err != nil {
-				return nil, err
-			}
-		}
-	}
-
-	// copy metadata property permissions to API property permissions
-	// TODO(jaypipes): This will not be necessary when Issue #111 is done and
-	// we have a single protobuffer namespace
-	apiPropPerms := make(
-		[]*pb.PropertyPermissions,
-		len(odef.PropertyPermissions),
-	)
-	for x, metaPropPerms := range odef.PropertyPermissions {
-		apiPropKeyPerms := make(
-			[]*pb.PropertyPermission, len(metaPropPerms.Permissions),
-		)
-		for y, metaPropKeyPerm := range metaPropPerms.Permissions {
-			apiPropKeyPerms[y] = &pb.PropertyPermission{
-				Project:    metaPropKeyPerm.Project,
-				Role:       metaPropKeyPerm.Role,
-				Permission: metaPropKeyPerm.Permission,
-			}
-		}
-		apiPropPerms[x] = &pb.PropertyPermissions{
-			Key:         metaPropPerms.Key,
-			Permissions: apiPropKeyPerms,
-		}
-	}
-	return &pb.ObjectDefinition{
-		Schema:              odef.Schema,
-		PropertyPermissions: apiPropPerms,
-	}, nil
-}
-
-// validateProviderDefinitionSetRequest ensures that the data the user sent in
-// the request payload can be unmarshal'd properly into YAML and that the data
-// is valid
-func (s *Server) validateProviderDefinitionSetRequest(
-	req *pb.ProviderDefinitionSetRequest,
-) (*pb.ObjectDefinition, error) {
-	var input types.ProviderDefinition
-	if err := yaml.Unmarshal(req.Payload, &input); err != nil {
-		return nil, err
-	}
-	if err := input.Validate(); err != nil {
-		return nil, err
-	}
-
-	partDisplay := "GLOBAL"
-	if req.Partition != "" {
-		// Check that any supplied partition exists, and if the user supplied a
-		// partition name, translate it to a partition UUID
-		part, err := s.partitionGet(req.Session, req.Partition)
-		if err != nil {
-			if err == errors.ErrNotFound {
-				return nil, errPartitionNotFound(req.Partition)
-			}
-			s.log.ERR("failed checking provider definition's partition: %s", err)
-			return nil, ErrUnknown
-		}
-		partDisplay = "partition: '" + part.Uuid + "'"
-		req.Partition = part.Uuid
-	}
-
-	if req.ProviderType != "" {
-		_, err := s.providerTypeGetByCode(req.Session, req.ProviderType)
-		if err != nil {
-			if err == errors.ErrNotFound {
-				return nil, errProviderTypeNotFound(req.ProviderType)
-			}
-			s.log.ERR(
-				"failed checking provider definition's provider type: %s",
-				err,
-			)
-			return nil, ErrUnknown
-		}
-		partDisplay += " provider type: '" + req.ProviderType + "'"
-	}
-
-	propPerms := make([]*pb.PropertyPermissions, 0)
-
-	// Ensure that we've got some default access permissions for any properties
-	// that have been defined on the provider definition
-	for propKey, propDef := range input.PropertyDefinitions {
-		if len(propDef.Permissions) == 0 {
-			s.log.L3(
-				"setting default permissions on provider definition "+
-					"in %s for property key '%s' to READ/WRITE "+
-					"for project '%s' and READ any",
-				partDisplay, propKey, req.Session.Project,
-			)
-			propPerms = append(propPerms,
-				&pb.PropertyPermissions{
-					Key: propKey,
-					Permissions: []*pb.PropertyPermission{
-						&pb.PropertyPermission{
-							Project: req.Session.Project,
-							Permission: types.PERMISSION_READ |
-								types.PERMISSION_WRITE,
-						},
-						&pb.PropertyPermission{
-							Permission: types.PERMISSION_READ,
-						},
-					},
-				},
-			)
-		} else {
-			// Make sure that the project that created the provider definition
-			// can read and write the properties defined on it...
-			foundProj := false
-			for _, perm := range propDef.Permissions {
-				if perm.Project != "" && perm.Project == req.Session.Project {
-					permCode := perm.PermissionUint32()
-					if (permCode & types.PERMISSION_WRITE) == 0 {
-						s.log.L1(
-							"added missing WRITE permission for "+
-								"provider definition in %s "+
-								"for property key '%s' in project '%s'",
-							partDisplay, propKey, perm.Project,
-						)
-						permCode |= types.PERMISSION_WRITE
-					}
-					foundProj = true
-					propPerms = append(propPerms,
-						&pb.PropertyPermissions{
-							Key: propKey,
-							Permissions: []*pb.PropertyPermission{
-								&pb.PropertyPermission{
-									Project:    perm.Project,
-									Role:       perm.Role,
-									Permission: permCode,
-								},
-							},
-						},
-					)
-					break
-				}
-			}
-			if !foundProj {
-				s.log.L1(
-					"added missing WRITE permission for provider definition "+
-						"in %s for property key '%s' in project '%s'",
-					partDisplay, propKey, req.Session.Project,
-				)
-				propPerms = append(propPerms,
-					&pb.PropertyPermissions{
-						Key: propKey,
-						Permissions: []*pb.PropertyPermission{
-							&pb.PropertyPermission{
-								Project: req.Session.Project,
-								Permission: types.PERMISSION_READ |
-									types.PERMISSION_WRITE,
-							},
-						},
-					},
-				)
-			}
-		}
-	}
-	return &pb.ObjectDefinition{
-		Schema:              input.JSONSchemaString(),
-		PropertyPermissions: propPerms,
-	}, nil
+	return s.DefinitionGet(ctx, &pb.DefinitionGetRequest{
+		Session:    req.Session,
+		ObjectType: "runm.provider",
+		Partition:  req.Partition,
+		Subtype:    req.ProviderType,
+	})
 }
 
 // ProviderDefinitionSet creates or updates the schema and property permissions
-// for providers in a particular partition
+// for providers in a particular partition and of a particular provider type.
+//
+// If the request's DryRun field is true, the provider definition is not saved
+// and the existing providers that would not satisfy it are returned instead.
+// If the request's Enforce field is true, the provider definition is only
+// saved if all existing providers it would govern satisfy it.
+//
+// NOTE(jaypipes): Enforcing a provider definition is not atomic. Providers
+// are checked before the provider definition is saved, and providers created
+// or updated in between are validated against the provider definition that
+// was in effect when they were written.
 func (s *Server) ProviderDefinitionSet(
 	ctx context.Context,
 	req *pb.ProviderDefinitionSetRequest,
 ) (*pb.ObjectDefinitionSetResponse, error) {
-	// TODO(jaypipes): AUTHZ check if user can write definitions
-
-	odef, err := s.validateProviderDefinitionSetRequest(req)
-	if err != nil {
-		return nil, err
-	}
-
-	// copy API property permissions to metadata property permissions
-	metaPropPerms := make(
-		[]*pb.PropertyPermissions,
-		len(odef.PropertyPermissions),
-	)
-	for x, apiPropPerms := range odef.PropertyPermissions {
-		metaPropKeyPerms := make(
-			[]*pb.PropertyPermission, len(apiPropPerms.Permissions),
-		)
-		for y, apiPropKeyPerm := range apiPropPerms.Permissions {
-			metaPropKeyPerms[y] = &pb.PropertyPermission{
-				Project:    apiPropKeyPerm.Project,
-				Role:       apiPropKeyPerm.Role,
-				Permission: apiPropKeyPerm.Permission,
-			}
-		}
-		metaPropPerms[x] = &pb.PropertyPermissions{
-			Key:         apiPropPerms.Key,
-			Permissions: metaPropKeyPerms,
-		}
-	}
-
-	metadef := &pb.ObjectDefinition{
-		Schema:              odef.Schema,
-		PropertyPermissions: metaPropPerms,
+	dreq := &pb.DefinitionSetRequest{
+		Session:    req.Session,
+		Format:     req.Format,
+		Payload:    req.Payload,
+		ObjectType: "runm.provider",
+		Partition:  req.Partition,
+		Subtype:    req.ProviderType,
 	}
-	_, err = s.providerDefinitionSet(
-		req.Session, metadef, req.Partition, req.ProviderType,
-	)
-	if err != nil {
-		s.log.ERR(
-			"failed setting object definition for runm.provider objects "+
-				"in partition '%s'",
-			req.Partition,
-		)
-		return nil, err
+	if !req.DryRun && !req.Enforce {
+		return s.DefinitionSet(ctx, dreq)
 	}
 
-	return &pb.ObjectDefinitionSetResponse{
-		ObjectDefinition: odef,
-	}, nil
-}
+	// TODO(jaypipes): AUTHZ check if user can write definitions
 
-// providerDefinitionGetGlobalDefault returns the global default object
-// definition for providers.
-//
-// If no such object definition could be found, returns (nil, ErrNotFound)
-func (s *Server) providerDefinitionGetGlobalDefault(
-	sess *pb.Session,
-) (*pb.ObjectDefinition, error) {
-	req := &pb.ProviderDefinitionGetGlobalDefaultRequest{
-		Session: sess,
-	}
-	mc, err := s.metaClient()
-	if err != nil {
-		return nil, err
-	}
-	def, err := mc.ProviderDefinitionGetGlobalDefault(
-		context.Background(), req,
-	)
+	odef, err := s.validateDefinitionSetRequest(ctx, dreq)
 	if err != nil {
 		return nil, err
 	}
-	return def, nil
-}
+	// NOTE(jaypipes): validateDefinitionSetRequest translates any partition
+	// name in the request into the partition's UUID
+	partUuid := dreq.Partition
 
-// providerDefinitionGetByPartition returns the object definition for providers
-// that has been set as the override for a supplied partition.
-//
-// If no such object definition could be found, returns (nil, ErrNotFound)
-func (s *Server) providerDefinitionGetByPartition(
-	sess *pb.Session,
-	partUuid string,
-) (*pb.ObjectDefinition, error) {
-	req := &pb.ProviderDefinitionGetByPartitionRequest{
-		Session:       sess,
-		PartitionUuid: partUuid,
-	}
-	mc, err := s.metaClient()
-	if err != nil {
-		return nil, err
-	}
-	def, err := mc.ProviderDefinitionGetByPartition(
-		context.Background(), req,
+	violations, err := s.providerDefinitionViolations(
+		ctx, req.Session, odef.Schema, partUuid, req.ProviderType,
 	)
 	if err != nil {
 		return nil, err
 	}
-	return def, nil
-}
-
-// providerDefinitionGetByType returns the object definition for providers that
-// has been overridden for the supplied provider type.
-//
-// If no such object definition could be found, returns (nil, ErrNotFound)
-func (s *Server) providerDefinitionGetByType(
-	sess *pb.Session,
-	provTypeCode string,
-) (*pb.ObjectDefinition, error) {
-	req := &pb.ProviderDefinitionGetByTypeRequest{
-		Session:          sess,
-		ProviderTypeCode: provTypeCode,
+	if req.DryRun {
+		return &pb.ObjectDefinitionSetResponse{
+			ObjectDefinition: odef,
+			Violations:       violations,
+		}, nil
 	}
-	mc, err := s.metaClient()
-	if err != nil {
-		return nil, err
+	if len(violations) > 0 {
+		return nil, errObjectDefinitionViolated("runm.provider", violations)
 	}
-	def, err := mc.ProviderDefinitionGetByType(
-		context.Background(), req,
-	)
-	if err != nil {
-		return nil, err
-	}
-	return def, nil
-}
 
-// providerDefinitionGetByPartitionAndType returns the object definition for
-// providers that has been overridden for the supplied partition and provider
-// type.
-//
-// If no such object definition could be found, returns (nil, ErrNotFound)
-func (s *Server) providerDefinitionGetByPartitionAndType(
-	sess *pb.Session,
-	partUuid string,
-	provTypeCode string,
-) (*pb.ObjectDefinition, error) {
-	req := &pb.ProviderDefinitionGetByPartitionAndTypeRequest{
-		Session:          sess,
-		PartitionUuid:    partUuid,
-		ProviderTypeCode: provTypeCode,
-	}
-	mc, err := s.metaClient()
-	if err != nil {
-		return nil, err
-	}
-	def, err := mc.ProviderDefinitionGetByPartitionAndType(
-		context.Background(), req,
+	odef, err = s.objectDefinitionSet(
+		ctx, req.Session, odef, "runm.provider", partUuid, req.ProviderType,
 	)
 	if err != nil {
+		s.log.ForContext(ctx).ERR(
+			"failed setting provider definition in partition '%s': %s",
+			partUuid, err,
+		)
 		return nil, err
 	}
-	return def, nil
+	return &pb.ObjectDefinitionSetResponse{
+		ObjectDefinition: odef,
+	}, nil
 }
 
-// providerDefinitionGetMostExplicit returns the object definition that would
-// be applied for the supplied partition and provider type.
-//
-// If a provider definition override has been set for the partition and
-// provider type, that object definition will be returned, otherwise...
-//
-// If a provider definition override has been set for the partition but not the
-// provider type, that object definition will be returned, otherwise...
-//
-// If a provider definition override has been set for the provider type but not
-// the partition, that object definition will be returned, otherwise...
-//
-// If no overrides for partition or provider type have been set, this will return the global default provider definition.
-func (s *Server) providerDefinitionGetMostExplicit(
+// providerDefinitionViolations returns the existing providers that a provider
+// definition set for the supplied partition UUID and provider type would
+// govern and whose properties do not satisfy the supplied schema document
+func (s *Server) providerDefinitionViolations(
+	ctx context.Context,
 	sess *pb.Session,
+	schema string,
 	partUuid string,
-	provTypeCode string,
-) (*pb.ObjectDefinition, error) {
+	ptCode string,
+) ([]*pb.ObjectDefinitionViolation, error) {
+	// A global provider definition governs providers in every partition, not
+	// only the session's partition, so we need a filter for each partition
+	partUuids := []string{partUuid}
 	if partUuid == "" {
-		return nil, fmt.Errorf("partUuid parameter must not be empty")
+		parts, err := s.partitionsGetAll(ctx, sess)
+		if err != nil {
+			return nil, err
+		}
+		if len(parts) == 0 {
+			return []*pb.ObjectDefinitionViolation{}, nil
+		}
+		partUuids = make([]string, len(parts))
+		for x, part := range parts {
+			partUuids[x] = part.Uuid
+		}
 	}
-	if provTypeCode == "" {
-		return nil, fmt.Errorf("provTypeCode parameter must not be empty")
+	any := make([]*pb.ProviderFilter, len(partUuids))
+	for x, part := range partUuids {
+		filter := &pb.ProviderFilter{
+			PartitionFilter: &pb.SearchFilter{Search: part},
+		}
+		if ptCode != "" {
+			filter.ProviderTypeFilter = &pb.SearchFilter{Search: ptCode}
+		}
+		any[x] = filter
 	}
-	mc, err := s.metaClient()
+	cur, err := s.providersGetMatching(ctx, sess, any)
 	if err != nil {
 		return nil, err
 	}
+	defer cur.Close()
 
-	// OK, first look to see if there's an override for the partition +
-	// provider type
-	pptreq := &pb.ProviderDefinitionGetByPartitionAndTypeRequest{
-		Session:          sess,
-		PartitionUuid:    partUuid,
-		ProviderTypeCode: provTypeCode,
-	}
-	def, err := mc.ProviderDefinitionGetByPartitionAndType(
-		context.Background(), pptreq,
-	)
-	if err != nil {
-		if err != errors.ErrNotFound {
-			return nil, err
+	exists := func(part string, sub string) (bool, error) {
+		_, err := s.objectDefinitionGet(
+			ctx, sess, "runm.provider", part, sub, false,
+		)
+		if err == nil {
+			return true, nil
+		}
+		if se, ok := status.FromError(err); ok && se.Code() == codes.NotFound {
+			return false, nil
 		}
-	} else {
-		return def, nil
+		return false, err
 	}
 
-	// We fell through here if there was no partition + provider type override.
-	// Next check to see if there's a partition (with no provider type)
-	// override.
-	preq := &pb.ProviderDefinitionGetByPartitionRequest{
-		Session:       sess,
-		PartitionUuid: partUuid,
-	}
-	def, err = mc.ProviderDefinitionGetByPartition(context.Background(), preq)
-	if err != nil {
-		if err != errors.ErrNotFound {
+	// Providers in the same partition and of the same provider type are
+	// governed by the same provider definition, so we only need to work out
+	// whether the new provider definition governs them once
+	governs := make(map[string]bool, 0)
+	violations := make([]*pb.ObjectDefinitionViolation, 0)
+	for cur.Next() {
+		p := &pb.Provider{}
+		if err = cur.Scan(p); err != nil {
 			return nil, err
 		}
-	} else {
-		return def, nil
-	}
-
-	// We fell through here if there was no partition + provider type override
-	// and no partition-only override. Next check to see if there's a provider
-	// type (no partition) override.
-	ptreq := &pb.ProviderDefinitionGetByTypeRequest{
-		Session:          sess,
-		ProviderTypeCode: provTypeCode,
-	}
-	def, err = mc.ProviderDefinitionGetByType(context.Background(), ptreq)
-	if err != nil {
-		if err != errors.ErrNotFound {
+		provPart := p.Partition.GetUuid()
+		provType := p.ProviderType.GetCode()
+		key := provPart + ":" + provType
+		governed, found := governs[key]
+		if !found {
+			governed, err = definitionGoverns(
+				partUuid, ptCode, provPart, provType, exists,
+			)
+			if err != nil {
+				return nil, err
+			}
+			governs[key] = governed
+		}
+		if !governed {
+			continue
+		}
+		msgs, err := types.PropertiesErrors(schema, p.Properties)
+		if err != nil {
 			return nil, err
 		}
-	} else {
-		return def, nil
+		if len(msgs) > 0 {
+			violations = append(violations, &pb.ObjectDefinitionViolation{
+				ObjectUuid: p.Uuid,
+				ObjectName: p.Name,
+				Partition:  provPart,
+				Subtype:    provType,
+				Errors:     msgs,
+			})
+		}
 	}
-
-	// Nothing found... fall back on the global default provider definition
-	return s.providerDefinitionGetGlobalDefault(sess)
+	if err = cur.Err(); err != nil {
+		return nil, err
+	}
+	return violations, nil
 }
 
-// providerDefinitionSet takes an object definition and saves it in the metadata
-// service, returning the saved object definition
-func (s *Server) providerDefinitionSet(
-	sess *pb.Session,
-	def *pb.ObjectDefinition,
+// definitionGoverns returns whether an object definition set for the supplied
+// partition UUID and subtype would be used to validate objects in the object
+// partition and of the object subtype. Empty partition UUID or subtype
+// arguments indicate the global or default object definition. The exists
+// function returns whether an object definition has been set for a partition
+// UUID and subtype.
+//
+// Objects are validated using the most explicit object definition that exists
+// (see the runm-metadata ObjectDefinitionGetMostExplicit storage method), so
+// the object definition does not govern objects that a more explicit object
+// definition has been set for.
+func definitionGoverns(
 	partUuid string,
-	provTypeCode string,
-) (*pb.ObjectDefinition, error) {
-	req := &pb.ProviderObjectDefinitionSetRequest{
-		Session:          sess,
-		ObjectDefinition: def,
-		PartitionUuid:    partUuid,
-		ProviderTypeCode: provTypeCode,
-	}
-	mc, err := s.metaClient()
-	if err != nil {
-		return nil, err
-	}
-	resp, err := mc.ProviderDefinitionSet(context.Background(), req)
-	if err != nil {
-		return nil, err
+	subtype string,
+	objPartUuid string,
+	objSubtype string,
+	exists func(partUuid string, subtype string) (bool, error),
+) (bool, error) {
+	if (partUuid != "" && partUuid != objPartUuid) ||
+		(subtype != "" && subtype != objSubtype) {
+		return false, nil
+	}
+	tries := [][]string{
+		{objPartUuid, objSubtype},
+		{objPartUuid, ""},
+		{"", objSubtype},
+		{"", ""},
+	}
+	for x, try := range tries {
+		if try[0] == partUuid && try[1] == subtype {
+			return true, nil
+		}
+		if x > 0 && try[0] == tries[x-1][0] && try[1] == tries[x-1][1] {
+			continue
+		}
+		found, err := exists(try[0], try[1])
+		if err != nil {
+			return false, err
+		}
+		if found {
+			return false, nil
+		}
 	}
-	return resp.ObjectDefinition, nil
+	return false, nil
 }
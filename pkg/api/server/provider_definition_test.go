@@ -0,0 +1,55 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefinitionGoverns(t *testing.T) {
+	assert := assert.New(t)
+
+	// Object definitions that have been set, as "partition:subtype"
+	set := map[string]bool{
+		":":                true,
+		"part0:":           true,
+		":runm.compute":    true,
+		"part1:runm.block": true,
+	}
+	exists := func(partUuid string, subtype string) (bool, error) {
+		return set[partUuid+":"+subtype], nil
+	}
+
+	tests := []struct {
+		part     string
+		subtype  string
+		objPart  string
+		objType  string
+		expected bool
+	}{
+		// The global default is overridden by the part0 default...
+		{"", "", "part0", "runm.block", false},
+		// ... but not in part2
+		{"", "", "part2", "runm.block", true},
+		// ... and it is overridden by the runm.compute override everywhere
+		{"", "", "part2", "runm.compute", false},
+		// The part0 default is more explicit than the runm.compute override
+		{"", "runm.compute", "part0", "runm.compute", false},
+		{"", "runm.compute", "part2", "runm.compute", true},
+		{"part0", "", "part0", "runm.compute", true},
+		// Overrides never govern objects in other partitions or subtypes
+		{"part0", "", "part1", "runm.compute", false},
+		{"", "runm.compute", "part2", "runm.block", false},
+		// A partition and subtype override is always the most explicit, even
+		// if it hasn't been set yet
+		{"part0", "runm.compute", "part0", "runm.compute", true},
+		{"part1", "runm.block", "part1", "runm.block", true},
+	}
+	for _, test := range tests {
+		governed, err := definitionGoverns(
+			test.part, test.subtype, test.objPart, test.objType, exists,
+		)
+		assert.Nil(err)
+		assert.Equal(test.expected, governed, "%+v", test)
+	}
+}
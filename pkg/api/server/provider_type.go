@@ -16,7 +16,7 @@ func (s *Server) ProviderTypeGet(
 	if req.Filter == nil || req.Filter.Search == "" {
 		return nil, ErrSearchRequired
 	}
-	return s.providerTypeGetByCode(req.Session, req.Filter.Search)
+	return s.providerTypeGetByCode(ctx, req.Session, req.Filter.Search)
 }
 
 // ProviderTypeList streams zero or more ProviderType objects back to the
@@ -25,6 +25,7 @@ func (s *Server) ProviderTypeList(
 	req *pb.ProviderTypeListRequest,
 	stream pb.RunmAPI_ProviderTypeListServer,
 ) error {
+	ctx := stream.Context()
 	metareq := &pb.ProviderTypeFindRequest{
 		Session: req.Session,
 		// TODO(jaypipes): Any:     buildProviderTypeFilters(),
@@ -33,7 +34,7 @@ func (s *Server) ProviderTypeList(
 	if err != nil {
 		return err
 	}
-	metastream, err := mc.ProviderTypeFind(context.Background(), metareq)
+	metastream, err := mc.ProviderTypeFind(ctx, metareq)
 	if err != nil {
 		return err
 	}
@@ -2,9 +2,7 @@ package server
 
 import (
 	"context"
-	"fmt"
 
-	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
@@ -12,51 +10,10 @@ import (
 	pb "github.com/runmachine-io/runmachine/proto"
 )
 
-// TODO(jaypipes): Add retry behaviour
-func (s *Server) resConnect(addr string) (*grpc.ClientConn, error) {
-	var opts []grpc.DialOption
-	// TODO(jaypipes): Don't hardcode this to WithInsecure
-	opts = append(opts, grpc.WithInsecure())
-	conn, err := grpc.Dial(addr, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return conn, nil
-}
-
-// resClient returns a resource service client. We look up the resource service
-// endpoint using the gsr service registry, connect to that endpoint, and if
-// successful, return a constructed gRPC client to the resource service at that
-// endpoint.
+// resClient returns a resource service client. RPCs made with the client are
+// balanced across the resource service endpoints registered in the gsr
+// service registry.
 func (s *Server) resClient() (pb.RunmResourceClient, error) {
-	if s.resclient != nil {
-		return s.resclient, nil
-	}
-	// TODO(jaypipes): Move this code into a generic ServiceRegistry
-	// struct/interface and allow for randomizing the pick of an endpoint from
-	// multiple endpoints of the same service.
-	var conn *grpc.ClientConn
-	var addr string
-	var err error
-	for _, ep := range s.registry.Endpoints(s.cfg.ResourceServiceName) {
-		addr = ep.Address
-		s.log.L3("connecting to resource service at %s...", addr)
-		if conn, err = s.resConnect(addr); err != nil {
-			s.log.ERR(
-				"failed to connect to resource service endpoint at %s: %s",
-				addr, err,
-			)
-		} else {
-			break
-		}
-	}
-	if conn == nil {
-		msg := "unable to connect to any resource service endpoint."
-		s.log.ERR(msg)
-		return nil, fmt.Errorf(msg)
-	}
-	s.resclient = pb.NewRunmResourceClient(conn)
-	s.log.L2("connected to resource service at %s", addr)
 	return s.resclient, nil
 }
 
@@ -65,6 +22,7 @@ func (s *Server) resClient() (pb.RunmResourceClient, error) {
 // object may have fields updated on it when creation is successful (such as
 // the provider's generation)
 func (s *Server) providerCreate(
+	ctx context.Context,
 	sess *pb.Session,
 	prov *pb.Provider,
 ) error {
@@ -82,14 +40,14 @@ func (s *Server) providerCreate(
 		Provider: p,
 	}
 	rc, err := s.resClient()
-	resp, err := rc.ProviderCreate(context.Background(), req)
+	resp, err := rc.ProviderCreate(ctx, req)
 	if err != nil {
 		if s, ok := status.FromError(err); ok {
 			if s.Code() == codes.AlreadyExists {
 				return errors.ErrDuplicate
 			}
 		}
-		s.log.ERR(
+		s.log.ForContext(ctx).ERR(
 			"failed saving provider with name '%s' in resource service: %s",
 			prov.Name, err,
 		)
@@ -98,3 +56,117 @@ func (s *Server) providerCreate(
 	prov.Generation = resp.Provider.Generation
 	return nil
 }
+
+// consumerCreate creates a consumer of the supplied consumer type in the
+// resource service with the supplied UUID, owned by the session's user and
+// the supplied project
+func (s *Server) consumerCreate(
+	ctx context.Context,
+	sess *pb.Session,
+	consumerType string,
+	uuid string,
+	project string,
+) error {
+	req := &pb.ConsumerCreateRequest{
+		Session: sess,
+		Consumer: &pb.Consumer{
+			Type: &pb.ConsumerType{
+				Code: consumerType,
+			},
+			Uuid:    uuid,
+			Project: project,
+			User:    sess.User,
+		},
+	}
+	rc, err := s.resClient()
+	if err != nil {
+		return err
+	}
+	if _, err = rc.ConsumerCreate(ctx, req); err != nil {
+		if se, ok := status.FromError(err); ok {
+			if se.Code() == codes.AlreadyExists {
+				return ErrDuplicate
+			}
+		}
+		s.log.ForContext(ctx).ERR(
+			"failed creating consumer with UUID %s in resource service: %s",
+			uuid, err,
+		)
+		return ErrUnknown
+	}
+	return nil
+}
+
+// consumerDeleteByUuids deletes the consumers having any of the supplied
+// UUIDs from the resource service, releasing the resources allocated to them
+func (s *Server) consumerDeleteByUuids(
+	ctx context.Context,
+	sess *pb.Session,
+	uuids []string,
+) error {
+	req := &pb.ConsumerDeleteByUuidsRequest{
+		Session: sess,
+		Uuids:   uuids,
+	}
+	rc, err := s.resClient()
+	if err != nil {
+		return err
+	}
+	if _, err = rc.ConsumerDeleteByUuids(ctx, req); err != nil {
+		s.log.ForContext(ctx).ERR(
+			"failed deleting consumers with UUIDs (%s) in resource service: %s",
+			uuids, err,
+		)
+		return err
+	}
+	return nil
+}
+
+// claimCreate allocates the supplied amounts of resources, keyed by resource
+// type code, on the supplied provider to the consumer with the supplied UUID
+// in the resource service
+func (s *Server) claimCreate(
+	ctx context.Context,
+	sess *pb.Session,
+	consumerUuid string,
+	providerUuid string,
+	resources map[string]uint64,
+) error {
+	items := make([]*pb.AllocationItem, 0, len(resources))
+	for code, amount := range resources {
+		items = append(items, &pb.AllocationItem{
+			Provider: &pb.Provider{
+				Uuid: providerUuid,
+			},
+			ResourceType: &pb.ResourceType{
+				Code: code,
+			},
+			Used: amount,
+		})
+	}
+	req := &pb.ClaimCreateRequest{
+		Session: sess,
+		Claim: &pb.Claim{
+			Allocation: &pb.Allocation{
+				Consumer: &pb.Consumer{
+					Uuid: consumerUuid,
+				},
+				Items: items,
+			},
+		},
+	}
+	rc, err := s.resClient()
+	if err != nil {
+		return err
+	}
+	if _, err = rc.ClaimCreate(ctx, req); err != nil {
+		// NOTE(jaypipes): Errors about capacity and generation conflicts are
+		// returned to the user as-is so they know whether to retry
+		s.log.ForContext(ctx).L2(
+			"failed claiming resources for consumer %s: %s",
+			consumerUuid, err,
+		)
+		return err
+	}
+	return nil
+}
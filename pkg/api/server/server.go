@@ -6,7 +6,10 @@ import (
 	"github.com/jaypipes/gsr"
 
 	"github.com/runmachine-io/runmachine/pkg/api/server/config"
+	"github.com/runmachine-io/runmachine/pkg/blobstore"
 	"github.com/runmachine-io/runmachine/pkg/logging"
+	"github.com/runmachine-io/runmachine/pkg/registry"
+	"github.com/runmachine-io/runmachine/pkg/svcclient"
 	pb "github.com/runmachine-io/runmachine/proto"
 )
 
@@ -17,15 +20,21 @@ var (
 type Server struct {
 	log        *logging.Logs
 	cfg        *config.Config
-	registry   *gsr.Registry
+	registry   registry.Registry
+	metasc     *svcclient.Client
 	metaclient pb.RunmMetadataClient
+	ressc      *svcclient.Client
 	resclient  pb.RunmResourceClient
+	// Map, keyed by address, of peered runmachine deployments
+	peers map[string]*peer
+	// Blob store keeping the bits of images
+	images blobstore.Store
 }
 
 func (s *Server) Close() {
 	addr := fmt.Sprintf("%s:%d", s.cfg.BindHost, s.cfg.BindPort)
 	s.log.L3(
-		"unregistering %s:%s endpoint in gsr...",
+		"unregistering %s:%s endpoint in service registry...",
 		s.cfg.ServiceName,
 		addr,
 	)
@@ -37,18 +46,19 @@ func (s *Server) Close() {
 	if err != nil {
 		s.log.ERR("failed to unregister: %s\n", err)
 	}
+	s.metasc.Close()
+	s.ressc.Close()
 }
 
 func New(
 	cfg *config.Config,
 	log *logging.Logs,
+	reg registry.Registry,
 ) (*Server, error) {
-	log.L3("connecting to gsr service registry.")
-	registry, err := gsr.New()
+	images, err := blobstore.New(cfg.ImageStoreDriver, cfg.ImageStorePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create gsr.Registry object: %v", err)
+		return nil, fmt.Errorf("failed to create image store: %v", err)
 	}
-	log.L2("connected to gsr service registry.")
 
 	// Register this runm-api service endpoint with the service registry
 	addr := fmt.Sprintf("%s:%d", cfg.BindHost, cfg.BindPort)
@@ -56,18 +66,42 @@ func New(
 		Service: &gsr.Service{Name: cfg.ServiceName},
 		Address: addr,
 	}
-	if err = registry.Register(&ep); err != nil {
-		return nil, fmt.Errorf("failed to register %v with gsr: %v", ep, err)
+	if err := reg.Register(&ep); err != nil {
+		return nil, fmt.Errorf("failed to register %v with service registry: %v", ep, err)
 	}
 	log.L2(
-		"registered %s service endpoint running at %s with gsr.",
+		"registered %s service endpoint running at %s with service registry.",
 		cfg.ServiceName,
 		addr,
 	)
 
-	return &Server{
-		log:      log,
-		cfg:      cfg,
-		registry: registry,
-	}, nil
+	metasc, err := svcclient.New(
+		log, reg, cfg.MetadataServiceName,
+		svcclient.DefaultOptions(cfg.LBPolicy),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metadata service client: %v", err)
+	}
+	ressc, err := svcclient.New(
+		log, reg, cfg.ResourceServiceName,
+		svcclient.DefaultOptions(cfg.LBPolicy),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource service client: %v", err)
+	}
+
+	s := &Server{
+		log:        log,
+		cfg:        cfg,
+		registry:   reg,
+		metasc:     metasc,
+		metaclient: pb.NewRunmMetadataClient(metasc.Conn()),
+		ressc:      ressc,
+		resclient:  pb.NewRunmResourceClient(ressc.Conn()),
+		images:     images,
+	}
+	if err = s.setupPeers(); err != nil {
+		return nil, fmt.Errorf("failed to set up peers: %v", err)
+	}
+	return s, nil
 }
@@ -0,0 +1,152 @@
+package types
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+var (
+	// the set of valid disk formats that may appear in the image's "format"
+	// field
+	ValidImageFormats = []string{
+		"raw",
+		"qcow2",
+		"iso",
+		"vmdk",
+		"vhd",
+	}
+	// The keys of the properties of runm.image objects that hold the
+	// attributes of the image. Users may not set these properties directly.
+	ImagePropertyKeys = []string{
+		"architecture",
+		"os",
+		"format",
+		"checksum",
+		"size",
+		"visibility",
+		"members",
+		"status",
+	}
+)
+
+// Image is a bootable bunch of bits. The image's bits are uploaded after the
+// image is created.
+type Image struct {
+	// Identifier of the partition the image belongs to. Defaults to the
+	// user's session partition.
+	Partition string `json:"partition,omitempty"`
+	// Optional identifier of the project that owns the image. Defaults to the
+	// user's session project.
+	Project string `json:"project,omitempty"`
+	// The UUID of the image. Expected to be blank when a user is creating a
+	// new image.
+	Uuid string `json:"uuid,omitempty"`
+	// Human-readable name for the image. Uniqueness is guaranteed in the
+	// scope of the partition and project the image belongs to.
+	Name string `json:"name"`
+	// The CPU architecture the image can be booted on, e.g. x86_64
+	Architecture string `json:"architecture,omitempty"`
+	// The operating system installed in the image, e.g. linux
+	Os string `json:"os,omitempty"`
+	// The disk format of the image's bits. Defaults to raw.
+	Format string `json:"format,omitempty"`
+	// Optional hex-encoded SHA-256 checksum the uploaded bits must have
+	Checksum string `json:"checksum,omitempty"`
+	// Optional size in bytes the uploaded bits must have
+	Size uint64 `json:"size,omitempty"`
+	// One of private, shared or public. Defaults to private.
+	Visibility string `json:"visibility,omitempty"`
+	// The projects, other than the owning project, that may use a shared
+	// image
+	Members []string `json:"members,omitempty"`
+	// Map of key/value properties associated with this image. Properties can
+	// have a structure and be validated against a schema.
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	// Array of string tags. Tags are unstructured and unvalidated and any user
+	// belonging to the owning project can add or remove any tag.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Validate returns an error if the image is invalid, nil otherwise
+func (i *Image) Validate() error {
+	if i.Name == "" {
+		return fmt.Errorf("name required")
+	}
+	if i.Format != "" {
+		found := false
+		for _, f := range ValidImageFormats {
+			if i.Format == f {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf(
+				"invalid format %s. valid choices: %s",
+				i.Format, ValidImageFormats,
+			)
+		}
+	}
+	if i.Checksum != "" {
+		if b, err := hex.DecodeString(i.Checksum); err != nil || len(b) != 32 {
+			return fmt.Errorf("checksum must be a hex-encoded SHA-256 digest")
+		}
+	}
+	vis, err := ImageVisibilityFromString(i.Visibility)
+	if err != nil {
+		return err
+	}
+	if len(i.Members) > 0 && vis != pb.ImageVisibility_IMAGE_SHARED {
+		return fmt.Errorf("members may only be set on shared images")
+	}
+	for _, key := range ImagePropertyKeys {
+		if _, found := i.Properties[key]; found {
+			return fmt.Errorf(
+				"property %s is reserved. set the image's %s field instead",
+				key, key,
+			)
+		}
+	}
+	return nil
+}
+
+// ImageVisibilityFromString returns the image visibility for the supplied
+// string, which is one of private, shared or public. An empty string is
+// private.
+func ImageVisibilityFromString(s string) (pb.ImageVisibility, error) {
+	if s == "" {
+		return pb.ImageVisibility_IMAGE_PRIVATE, nil
+	}
+	v, found := pb.ImageVisibility_value["IMAGE_"+strings.ToUpper(s)]
+	if !found {
+		return 0, fmt.Errorf(
+			"invalid visibility %s. valid choices: private, shared, public", s,
+		)
+	}
+	return pb.ImageVisibility(v), nil
+}
+
+// ImageVisibilityString returns the string for the supplied image
+// visibility, which is one of private, shared or public
+func ImageVisibilityString(v pb.ImageVisibility) string {
+	return strings.ToLower(strings.TrimPrefix(v.String(), "IMAGE_"))
+}
+
+// ImageStatusFromString returns the image status for the supplied string,
+// which is one of queued or active
+func ImageStatusFromString(s string) (pb.ImageStatus, error) {
+	v, found := pb.ImageStatus_value["IMAGE_"+strings.ToUpper(s)]
+	if !found {
+		return 0, fmt.Errorf("invalid image status %s", s)
+	}
+	return pb.ImageStatus(v), nil
+}
+
+// ImageStatusString returns the string for the supplied image status, which
+// is one of queued or active
+func ImageStatusString(s pb.ImageStatus) string {
+	return strings.ToLower(strings.TrimPrefix(s.String(), "IMAGE_"))
+}
@@ -0,0 +1,66 @@
+package types_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/runmachine-io/runmachine/pkg/api/types"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+func TestImageValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	checksum := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	valid := []*types.Image{
+		{Name: "ubuntu"},
+		{
+			Name:         "ubuntu",
+			Architecture: "x86_64",
+			Os:           "linux",
+			Format:       "qcow2",
+			Checksum:     checksum,
+			Visibility:   "shared",
+			Members:      []string{"proj1"},
+			Properties:   map[string]interface{}{"os_distro": "ubuntu"},
+		},
+	}
+	for _, img := range valid {
+		assert.Nil(img.Validate(), img.Name)
+	}
+
+	invalid := []*types.Image{
+		{},
+		{Name: "ubuntu", Format: "floppy"},
+		{Name: "ubuntu", Checksum: "abc"},
+		{Name: "ubuntu", Visibility: "everyone"},
+		{Name: "ubuntu", Members: []string{"proj1"}},
+		{
+			Name:       "ubuntu",
+			Properties: map[string]interface{}{"status": "active"},
+		},
+	}
+	for _, img := range invalid {
+		assert.NotNil(img.Validate())
+	}
+}
+
+func TestImageVisibility(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, v := range []pb.ImageVisibility{
+		pb.ImageVisibility_IMAGE_PRIVATE,
+		pb.ImageVisibility_IMAGE_SHARED,
+		pb.ImageVisibility_IMAGE_PUBLIC,
+	} {
+		got, err := types.ImageVisibilityFromString(types.ImageVisibilityString(v))
+		assert.Nil(err)
+		assert.Equal(v, got)
+	}
+	assert.Equal("public", types.ImageVisibilityString(pb.ImageVisibility_IMAGE_PUBLIC))
+
+	got, err := types.ImageVisibilityFromString("")
+	assert.Nil(err)
+	assert.Equal(pb.ImageVisibility_IMAGE_PRIVATE, got)
+}
@@ -0,0 +1,159 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+var (
+	// The keys of the properties of runm.machine objects that hold the
+	// attributes of the machine. Users may not set these properties directly.
+	MachinePropertyKeys = []string{
+		"image",
+		"provider",
+		"resources",
+		"desired_state",
+		"state",
+		"state_reason",
+		"state_time",
+	}
+	// Map, keyed by observed machine state, of the observed states a machine
+	// may transition to from that state
+	machineStateTransitions = map[pb.MachineState][]pb.MachineState{
+		pb.MachineState_MACHINE_PENDING: {
+			pb.MachineState_MACHINE_BUILDING,
+			pb.MachineState_MACHINE_ERROR,
+		},
+		pb.MachineState_MACHINE_BUILDING: {
+			pb.MachineState_MACHINE_RUNNING,
+			pb.MachineState_MACHINE_STOPPED,
+			pb.MachineState_MACHINE_ERROR,
+		},
+		pb.MachineState_MACHINE_RUNNING: {
+			pb.MachineState_MACHINE_STOPPED,
+			pb.MachineState_MACHINE_ERROR,
+		},
+		pb.MachineState_MACHINE_STOPPED: {
+			pb.MachineState_MACHINE_RUNNING,
+			pb.MachineState_MACHINE_ERROR,
+		},
+		// A machine in error may be rebuilt or may recover on its own
+		pb.MachineState_MACHINE_ERROR: {
+			pb.MachineState_MACHINE_BUILDING,
+			pb.MachineState_MACHINE_RUNNING,
+			pb.MachineState_MACHINE_STOPPED,
+		},
+	}
+)
+
+// Machine consumes compute resources from a provider and is booted from an
+// image
+type Machine struct {
+	// Identifier of the partition the machine belongs to. Defaults to the
+	// user's session partition.
+	Partition string `json:"partition,omitempty"`
+	// Optional identifier of the project that owns the machine. Defaults to
+	// the user's session project.
+	Project string `json:"project,omitempty"`
+	// The UUID of the machine. Expected to be blank when a user is creating a
+	// new machine.
+	Uuid string `json:"uuid,omitempty"`
+	// Human-readable name for the machine. Uniqueness is guaranteed in the
+	// scope of the partition and project the machine belongs to.
+	Name string `json:"name"`
+	// UUID or name of the image the machine is booted from
+	Image string `json:"image"`
+	// UUID or name of the provider the machine's resources are claimed from.
+	// Required if the machine consumes any resources.
+	Provider string `json:"provider,omitempty"`
+	// Map, keyed by resource type code, of the amount of each resource the
+	// machine consumes from its provider
+	Resources map[string]uint64 `json:"resources,omitempty"`
+	// Either running or stopped. Defaults to running.
+	DesiredState string `json:"desired_state,omitempty"`
+	// Map of key/value properties associated with this machine. Properties
+	// can have a structure and be validated against a schema.
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	// Array of string tags. Tags are unstructured and unvalidated and any user
+	// belonging to the owning project can add or remove any tag.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Validate returns an error if the machine is invalid, nil otherwise
+func (m *Machine) Validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("name required")
+	}
+	if m.Image == "" {
+		return fmt.Errorf("image required")
+	}
+	if len(m.Resources) > 0 && m.Provider == "" {
+		return fmt.Errorf("provider required when resources are requested")
+	}
+	for code, amount := range m.Resources {
+		if code == "" {
+			return fmt.Errorf("resource type code required")
+		}
+		if amount == 0 {
+			return fmt.Errorf("amount of resource %s must be positive", code)
+		}
+	}
+	if m.DesiredState != "" {
+		state, err := MachineStateFromString(m.DesiredState)
+		if err != nil {
+			return err
+		}
+		if !MachineDesiredStateValid(state) {
+			return fmt.Errorf(
+				"invalid desired state %s. valid choices: running, stopped",
+				m.DesiredState,
+			)
+		}
+	}
+	for _, key := range MachinePropertyKeys {
+		if _, found := m.Properties[key]; found {
+			return fmt.Errorf("property %s is reserved", key)
+		}
+	}
+	return nil
+}
+
+// MachineStateFromString returns the machine state for the supplied string,
+// which is one of pending, building, running, stopped or error
+func MachineStateFromString(s string) (pb.MachineState, error) {
+	v, found := pb.MachineState_value["MACHINE_"+strings.ToUpper(s)]
+	if !found {
+		return 0, fmt.Errorf(
+			"invalid machine state %s. valid choices: pending, building, "+
+				"running, stopped, error",
+			s,
+		)
+	}
+	return pb.MachineState(v), nil
+}
+
+// MachineStateString returns the string for the supplied machine state, which
+// is one of pending, building, running, stopped or error
+func MachineStateString(s pb.MachineState) string {
+	return strings.ToLower(strings.TrimPrefix(s.String(), "MACHINE_"))
+}
+
+// MachineDesiredStateValid returns true if a user may ask for a machine to be
+// in the supplied state
+func MachineDesiredStateValid(s pb.MachineState) bool {
+	return s == pb.MachineState_MACHINE_RUNNING ||
+		s == pb.MachineState_MACHINE_STOPPED
+}
+
+// MachineStateTransitionValid returns true if a machine may be observed to
+// transition from one state to another
+func MachineStateTransitionValid(from pb.MachineState, to pb.MachineState) bool {
+	for _, s := range machineStateTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package types_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/runmachine-io/runmachine/pkg/api/types"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+func TestMachineValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	valid := []*types.Machine{
+		{Name: "db0", Image: "ubuntu-18.04"},
+		{
+			Name:         "db0",
+			Image:        "ubuntu-18.04",
+			Provider:     "compute0",
+			Resources:    map[string]uint64{"runm.cpu.dedicated": 4},
+			DesiredState: "stopped",
+			Properties:   map[string]interface{}{"role": "database"},
+		},
+	}
+	for _, m := range valid {
+		assert.Nil(m.Validate(), m.Name)
+	}
+
+	invalid := []*types.Machine{
+		{Image: "ubuntu-18.04"},
+		{Name: "db0"},
+		{
+			Name:      "db0",
+			Image:     "ubuntu-18.04",
+			Resources: map[string]uint64{"runm.cpu.dedicated": 4},
+		},
+		{
+			Name:      "db0",
+			Image:     "ubuntu-18.04",
+			Provider:  "compute0",
+			Resources: map[string]uint64{"runm.cpu.dedicated": 0},
+		},
+		{Name: "db0", Image: "ubuntu-18.04", DesiredState: "building"},
+		{Name: "db0", Image: "ubuntu-18.04", DesiredState: "sleeping"},
+		{
+			Name:       "db0",
+			Image:      "ubuntu-18.04",
+			Properties: map[string]interface{}{"state": "running"},
+		},
+	}
+	for _, m := range invalid {
+		assert.NotNil(m.Validate())
+	}
+}
+
+func TestMachineStateTransitionValid(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		from   pb.MachineState
+		to     pb.MachineState
+		expect bool
+	}{
+		{
+			from:   pb.MachineState_MACHINE_PENDING,
+			to:     pb.MachineState_MACHINE_BUILDING,
+			expect: true,
+		},
+		{
+			from:   pb.MachineState_MACHINE_PENDING,
+			to:     pb.MachineState_MACHINE_RUNNING,
+			expect: false,
+		},
+		{
+			from:   pb.MachineState_MACHINE_BUILDING,
+			to:     pb.MachineState_MACHINE_RUNNING,
+			expect: true,
+		},
+		{
+			from:   pb.MachineState_MACHINE_RUNNING,
+			to:     pb.MachineState_MACHINE_STOPPED,
+			expect: true,
+		},
+		{
+			from:   pb.MachineState_MACHINE_RUNNING,
+			to:     pb.MachineState_MACHINE_RUNNING,
+			expect: false,
+		},
+		{
+			from:   pb.MachineState_MACHINE_STOPPED,
+			to:     pb.MachineState_MACHINE_PENDING,
+			expect: false,
+		},
+		{
+			from:   pb.MachineState_MACHINE_ERROR,
+			to:     pb.MachineState_MACHINE_BUILDING,
+			expect: true,
+		},
+	}
+	for _, test := range tests {
+		assert.Equal(
+			test.expect,
+			types.MachineStateTransitionValid(test.from, test.to),
+			"%s -> %s", test.from, test.to,
+		)
+	}
+
+	state, err := types.MachineStateFromString("running")
+	assert.Nil(err)
+	assert.Equal(pb.MachineState_MACHINE_RUNNING, state)
+	assert.Equal("error", types.MachineStateString(pb.MachineState_MACHINE_ERROR))
+}
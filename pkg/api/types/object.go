@@ -1,5 +1,7 @@
 package types
 
+import "fmt"
+
 // I would much prefer to be able to decorate our primary Protobuffer models
 // (in proto/defs) with tags that indicate YAML input validation, however the
 // developers of Google Protobuffers are not interested in supporting this
@@ -11,26 +13,39 @@ package types
 // :see: https://github.com/golang/protobuf/issues/52
 
 type Object struct {
-	// Identifier of the partition the object belongs to
-	Partition string `yaml:"partition"`
+	// Identifier of the partition the object belongs to. Defaults to the
+	// user's session partition.
+	Partition string `json:"partition,omitempty"`
 	// Code for the type of object this is
-	ObjectType string `yaml:"object_type"`
-	// Optional identifier of the project the object belongs to. Only required
-	// if the object's type is project-scoped
-	Project string `yaml:"project"`
+	ObjectType string `json:"object_type"`
+	// Optional identifier of the project the object belongs to. Only used if
+	// the object's type is project-scoped, in which case it defaults to the
+	// user's session project.
+	Project string `json:"project,omitempty"`
 	// The UUID of the object. Expected to be blank when a user is creating a
 	// new object.
-	Uuid string `yaml:"uuid"`
+	Uuid string `json:"uuid,omitempty"`
 	// Human-readable name for the object. Uniqueness is guaranteed in the
 	// scope of the type of object. If the type is project-scoped, then
 	// uniqueness is guaranteed within the scope of the partition, object type
 	// and project. If partition-scoped, uniqueness is guaranteed within the
 	// scope of the partition and object type.
-	Name string `yaml:"name"`
+	Name string `json:"name,omitempty"`
 	// Map of key/value properties associated with this object. Properties can
 	// have a structure and be validated against a schema.
-	Properties map[string]string `yaml:"properties"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
 	// Array of string tags. Tags are unstructured and unvalidated and any user
 	// belonging to the owning project can add or remove any tag.
-	Tags []string `yaml:"tags"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Validate returns an error if the object is invalid, nil otherwise
+func (o *Object) Validate() error {
+	if o.ObjectType == "" {
+		return fmt.Errorf("object_type required")
+	}
+	if o.Name == "" && o.Uuid == "" {
+		return fmt.Errorf("name or uuid required")
+	}
+	return nil
 }
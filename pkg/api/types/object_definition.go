@@ -0,0 +1,192 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// objectSchema describes the fields, other than properties and tags, that may
+// be set on objects of a particular object type
+type objectSchema struct {
+	Description string
+	// Fields having string values that may be set on the object
+	Fields []string
+	// Fields that must be set on the object
+	RequiredFields []string
+}
+
+var (
+	// The schema templates for the well-known runm object types, keyed by
+	// object type code. Objects of any other object type use
+	// defaultObjectSchema
+	objectSchemas = map[string]*objectSchema{
+		"runm.provider": &objectSchema{
+			Description:    "A provider of resources",
+			Fields:         []string{"partition", "provider_type", "name", "uuid", "parent"},
+			RequiredFields: []string{"partition", "provider_type"},
+		},
+		"runm.provider_group": &objectSchema{
+			Description:    "A group of providers",
+			Fields:         []string{"partition", "name", "uuid"},
+			RequiredFields: []string{"partition", "name"},
+		},
+		"runm.image": &objectSchema{
+			Description:    "A bootable bunch of bits",
+			Fields:         []string{"partition", "project", "name", "uuid"},
+			RequiredFields: []string{"partition", "project", "name"},
+		},
+		"runm.machine": &objectSchema{
+			Description:    "A machine consuming resources from providers",
+			Fields:         []string{"partition", "project", "name", "uuid"},
+			RequiredFields: []string{"partition", "project", "name"},
+		},
+	}
+	defaultObjectSchema = &objectSchema{
+		Description:    "An object",
+		Fields:         []string{"partition", "project", "name", "uuid"},
+		RequiredFields: []string{"partition", "name"},
+	}
+	objectSchemaTemplateContents = `{
+  "$id": "https://runmachine.io/{{ .ObjectType }}.schema.json",
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "description": {{ quote .Description }},
+  "type": "object",
+  "properties": {
+{{- range .Fields }}
+    {{ quote . }}: {
+      "type": "string"
+    },
+{{- end }}
+    "tags": {
+      "type": "array",
+      "items": {
+        "type": "string"
+      }
+    },
+    "properties": {
+      "type": "object",
+      {{ if len .PropertySchemas -}}
+      "properties": {
+{{- range $index, $propSchema := .PropertySchemas }}{{ if $index }},{{ end }}
+        {{ quote $propSchema.Key }}: {{ property_schema $propSchema.PropertySchema }}
+{{- end }}
+      },{{ end }}
+      "patternProperties": {
+        "^[a-zA-Z0-9]*$": {}
+      },
+      "required": [{{ quote_join .RequiredProperties ", " }}],
+      "additionalProperties": false
+    }
+  },
+  "required": [{{ quote_join .RequiredFields ", " }}],
+  "additionalProperties": false
+}
+`
+	objectSchemaTemplate *template.Template
+	templateFuncMap      = template.FuncMap{
+		"join":  strings.Join,
+		"quote": strconv.Quote,
+		"quote_join": func(elems []string, delim string) string {
+			quoted := make([]string, len(elems))
+			for x, elem := range elems {
+				quoted[x] = strconv.Quote(elem)
+			}
+			return strings.Join(quoted, delim)
+		},
+		"property_schema": func(schema *PropertySchema) string {
+			b, err := json.MarshalIndent(
+				schema.JSONSchema(), "        ", "  ",
+			)
+			if err != nil {
+				return fmt.Sprintf("TEMPLATE ERROR: %s", err)
+			}
+			return string(b)
+		},
+	}
+)
+
+func init() {
+	objectSchemaTemplate = template.Must(
+		template.New(
+			"object-schema",
+		).Funcs(
+			templateFuncMap,
+		).Parse(
+			objectSchemaTemplateContents,
+		),
+	)
+}
+
+// ObjectDefinition is used by runmachine system administrators to constrain
+// the properties that may be set on objects of a particular object type
+type ObjectDefinition struct {
+	// Named properties may have their values constrained by a property
+	// definition. The map key is the key of the property to apply the
+	// property definition to
+	PropertyDefinitions map[string]*PropertyDefinition `json:"property_definitions"`
+}
+
+// Validate returns an error if the definition is invalid, nil otherwise
+func (def *ObjectDefinition) Validate() error {
+	for _, pd := range def.PropertyDefinitions {
+		if err := pd.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type templateVars struct {
+	*objectSchema
+	ObjectType         string
+	RequiredProperties []string
+	PropertySchemas    []*propertySchemaWithKey
+}
+
+// JSONSchemaString returns a valid JSONSchema DRAFT-07 document describing the
+// fields and properties that may be set for the objects of the supplied object
+// type described by the object definition
+func (def *ObjectDefinition) JSONSchemaString(objType string) string {
+	schema, found := objectSchemas[objType]
+	if !found {
+		schema = defaultObjectSchema
+	}
+	vars := &templateVars{
+		objectSchema:       schema,
+		ObjectType:         objType,
+		RequiredProperties: make([]string, 0),
+		PropertySchemas:    make([]*propertySchemaWithKey, 0),
+	}
+	// Sort the property keys so that the same definition always produces the
+	// same schema document
+	keys := make([]string, 0, len(def.PropertyDefinitions))
+	for key := range def.PropertyDefinitions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		prop := def.PropertyDefinitions[key]
+		if prop.Required {
+			vars.RequiredProperties = append(vars.RequiredProperties, key)
+		}
+		ps := &propertySchemaWithKey{
+			PropertySchema: prop.Schema,
+			Key:            key,
+		}
+		vars.PropertySchemas = append(vars.PropertySchemas, ps)
+	}
+	var b bytes.Buffer
+	if err := objectSchemaTemplate.Execute(&b, vars); err != nil {
+		return fmt.Sprintf("TEMPLATE ERROR: %s", err)
+	}
+	return b.String()
+}
+
+func DefaultObjectDefinition() *ObjectDefinition {
+	return &ObjectDefinition{}
+}
@@ -0,0 +1,85 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// permissionString returns a human-readable representation of a property
+// permission code
+func permissionString(perm uint32) string {
+	switch perm {
+	case PERMISSION_NONE:
+		return "NONE"
+	case PERMISSION_READ:
+		return "READ"
+	case PERMISSION_WRITE:
+		return "WRITE"
+	case PERMISSION_READ | PERMISSION_WRITE:
+		return "READ/WRITE"
+	}
+	return fmt.Sprintf("%d", perm)
+}
+
+// objectDefinitionLines returns the lines of text that are compared when
+// diffing two object definitions: the schema document followed by one line
+// for each property permission
+func objectDefinitionLines(def *pb.ObjectDefinition) []string {
+	if def == nil {
+		return []string{}
+	}
+	var b strings.Builder
+	b.WriteString(def.Schema)
+	if !strings.HasSuffix(def.Schema, "\n") {
+		b.WriteString("\n")
+	}
+	if len(def.PropertyPermissions) > 0 {
+		b.WriteString("property_permissions:\n")
+	}
+	for _, pp := range def.PropertyPermissions {
+		for _, perm := range pp.Permissions {
+			project := perm.Project
+			if project == "" {
+				project = "*"
+			}
+			role := perm.Role
+			if role == "" {
+				role = "*"
+			}
+			fmt.Fprintf(
+				&b, "  %s: project=%s role=%s permission=%s\n",
+				pp.Key, project, role, permissionString(perm.Permission),
+			)
+		}
+	}
+	// NOTE(jaypipes): SplitLines adds a newline to the last line, so we trim
+	// the final newline to avoid every definition ending in a blank line
+	return difflib.SplitLines(strings.TrimSuffix(b.String(), "\n"))
+}
+
+// ObjectDefinitionDiff returns a unified diff of the schema and property
+// permissions of two object definitions. Either object definition may be nil,
+// in which case the diff shows all of the other object definition being added
+// or removed. Returns an empty string if the object definitions are the same.
+func ObjectDefinitionDiff(
+	from *pb.ObjectDefinition,
+	to *pb.ObjectDefinition,
+	fromName string,
+	toName string,
+) string {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        objectDefinitionLines(from),
+		B:        objectDefinitionLines(to),
+		FromFile: fromName,
+		ToFile:   toName,
+		Context:  3,
+	})
+	if err != nil {
+		return fmt.Sprintf("DIFF ERROR: %s", err)
+	}
+	return diff
+}
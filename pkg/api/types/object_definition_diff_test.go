@@ -0,0 +1,48 @@
+package types_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/runmachine-io/runmachine/pkg/api/types"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+func TestObjectDefinitionDiff(t *testing.T) {
+	assert := assert.New(t)
+
+	a := &pb.ObjectDefinition{
+		Schema: "{\n  \"type\": \"object\"\n}\n",
+	}
+	b := &pb.ObjectDefinition{
+		Schema: "{\n  \"type\": \"object\",\n  \"required\": [\"site\"]\n}\n",
+		PropertyPermissions: []*pb.PropertyPermissions{
+			{
+				Key: "site",
+				Permissions: []*pb.PropertyPermission{
+					{
+						Project:    "admin",
+						Permission: types.PERMISSION_READ | types.PERMISSION_WRITE,
+					},
+				},
+			},
+		},
+	}
+
+	assert.Equal("", types.ObjectDefinitionDiff(a, a, "a", "a"))
+
+	diff := types.ObjectDefinitionDiff(a, b, "revision 1", "revision 2")
+	assert.Contains(diff, "--- revision 1\n+++ revision 2\n")
+	assert.Contains(diff, "-  \"type\": \"object\"\n")
+	assert.Contains(diff, "+  \"type\": \"object\",\n")
+	assert.Contains(diff, "+  \"required\": [\"site\"]\n")
+	assert.Contains(
+		diff, "+  site: project=admin role=* permission=READ/WRITE\n",
+	)
+	assert.NotContains(diff, "\n \n")
+
+	// Diffing against no object definition shows every line being added
+	diff = types.ObjectDefinitionDiff(nil, a, "", "revision 1")
+	assert.Contains(diff, "+{\n+  \"type\": \"object\"\n+}\n")
+}
@@ -0,0 +1,74 @@
+package types_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/runmachine-io/runmachine/pkg/api/types"
+)
+
+func TestObjectDefinitionJSONSchemaString(t *testing.T) {
+	assert := assert.New(t)
+
+	def := types.ObjectDefinition{}
+	require.Nil(t, yaml.Unmarshal([]byte(`
+property_definitions:
+  site:
+    required: true
+    schema:
+      type: string
+  row:
+    schema:
+      type: integer
+`), &def))
+	require.Nil(t, def.Validate())
+
+	tests := []struct {
+		objType  string
+		fields   []string
+		required []interface{}
+	}{
+		{
+			objType:  "runm.provider",
+			fields:   []string{"partition", "provider_type", "parent"},
+			required: []interface{}{"partition", "provider_type"},
+		},
+		{
+			objType:  "runm.image",
+			fields:   []string{"partition", "project", "name"},
+			required: []interface{}{"partition", "project", "name"},
+		},
+		{
+			objType:  "runm.machine",
+			fields:   []string{"partition", "project", "name"},
+			required: []interface{}{"partition", "project", "name"},
+		},
+		{
+			objType:  "example.widget",
+			fields:   []string{"partition", "project", "name"},
+			required: []interface{}{"partition", "name"},
+		},
+	}
+	for _, test := range tests {
+		var schema map[string]interface{}
+		doc := def.JSONSchemaString(test.objType)
+		require.Nil(t, json.Unmarshal([]byte(doc), &schema), doc)
+
+		assert.Equal(
+			"https://runmachine.io/"+test.objType+".schema.json",
+			schema["$id"],
+		)
+		assert.Equal(test.required, schema["required"], test.objType)
+		fields := schema["properties"].(map[string]interface{})
+		for _, field := range test.fields {
+			assert.Contains(fields, field, test.objType)
+		}
+		props := fields["properties"].(map[string]interface{})
+		assert.Equal([]interface{}{"site"}, props["required"], test.objType)
+		assert.Contains(props["properties"], "row", test.objType)
+	}
+}
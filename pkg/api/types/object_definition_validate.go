@@ -0,0 +1,176 @@
+package types
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/xeipuuv/gojsonschema"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// PropertiesErrors returns the reasons the supplied properties are not valid
+// according to the supplied object definition schema document, or an empty
+// slice if the properties are valid
+func PropertiesErrors(
+	schemaDoc string,
+	props []*pb.Property,
+) ([]string, error) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(schemaDoc), &schema); err != nil {
+		return nil, err
+	}
+	propSchema := propertiesSchema(schema)
+	if propSchema == nil {
+		return []string{}, nil
+	}
+
+	// NOTE(jaypipes): Properties set before property values were typed only
+	// have a string value, so we convert each of those values to the type of
+	// value the property schema expects before validating it.
+	doc := make(map[string]interface{}, len(props))
+	for _, prop := range props {
+		if prop.TypedValue != nil {
+			doc[prop.Key] = PropertyValueInterface(prop.TypedValue)
+			continue
+		}
+		doc[prop.Key] = propertyValue(prop.Value, propSchema, prop.Key)
+	}
+	result, err := gojsonschema.Validate(
+		gojsonschema.NewGoLoader(propSchema),
+		gojsonschema.NewGoLoader(doc),
+	)
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]string, len(result.Errors()))
+	for x, resErr := range result.Errors() {
+		msgs[x] = resErr.String()
+	}
+	return msgs, nil
+}
+
+// PropertyDefaults returns the default values, keyed by property key, that
+// the supplied object definition schema document sets for properties that are
+// not set when an object is created
+func PropertyDefaults(schemaDoc string) (map[string]interface{}, error) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(schemaDoc), &schema); err != nil {
+		return nil, err
+	}
+	res := make(map[string]interface{}, 0)
+	propSchema := propertiesSchema(schema)
+	if propSchema == nil {
+		return res, nil
+	}
+	props, _ := propSchema["properties"].(map[string]interface{})
+	for key, keySchema := range props {
+		ks, ok := keySchema.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if def, found := ks["default"]; found {
+			res[key] = def
+		}
+	}
+	return res, nil
+}
+
+// NumericProperties returns the keys of the properties that the supplied
+// object definition schema document only allows integer or number values for
+func NumericProperties(schemaDoc string) (map[string]bool, error) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(schemaDoc), &schema); err != nil {
+		return nil, err
+	}
+	res := make(map[string]bool, 0)
+	propSchema := propertiesSchema(schema)
+	if propSchema == nil {
+		return res, nil
+	}
+	props, _ := propSchema["properties"].(map[string]interface{})
+	for key, keySchema := range props {
+		ks, _ := keySchema.(map[string]interface{})
+		valueTypes := schemaTypes(ks)
+		numeric := len(valueTypes) > 0
+		for _, valueType := range valueTypes {
+			if valueType != "integer" && valueType != "number" {
+				numeric = false
+			}
+		}
+		if numeric {
+			res[key] = true
+		}
+	}
+	return res, nil
+}
+
+// schemaTypes returns the types of value the supplied JSONSchema allows. The
+// JSONSchema "type" keyword may be either a single type or a list of types.
+func schemaTypes(schema map[string]interface{}) []interface{} {
+	switch t := schema["type"].(type) {
+	case string:
+		return []interface{}{t}
+	case []interface{}:
+		return t
+	}
+	return nil
+}
+
+// propertiesSchema returns the schema for an object's properties from an
+// object definition's schema document, or nil if the schema document does not
+// constrain the object's properties
+func propertiesSchema(
+	schema map[string]interface{},
+) map[string]interface{} {
+	fields, ok := schema["properties"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	props, ok := fields["properties"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return props
+}
+
+// propertyValue returns the supplied string property value converted to the
+// first type of value the property schema allows that the string can be
+// parsed as
+func propertyValue(
+	value string,
+	propSchema map[string]interface{},
+	key string,
+) interface{} {
+	props, _ := propSchema["properties"].(map[string]interface{})
+	keySchema, _ := props[key].(map[string]interface{})
+	for _, valueType := range schemaTypes(keySchema) {
+		switch valueType {
+		case "integer":
+			if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return i
+			}
+		case "number":
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				return f
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(value); err == nil {
+				return b
+			}
+		case "array":
+			var a []interface{}
+			if err := json.Unmarshal([]byte(value), &a); err == nil {
+				return a
+			}
+		case "object":
+			var o map[string]interface{}
+			if err := json.Unmarshal([]byte(value), &o); err == nil {
+				return o
+			}
+		case "string":
+			return value
+		}
+	}
+	return value
+}
@@ -0,0 +1,205 @@
+package types_test
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/runmachine-io/runmachine/pkg/api/types"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+func TestPropertiesErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	def := types.ObjectDefinition{}
+	require.Nil(t, yaml.Unmarshal([]byte(`
+property_definitions:
+  location.site:
+    required: true
+    schema:
+      type: string
+  location.row:
+    schema:
+      type: integer
+  net.fast:
+    schema:
+      type: boolean
+`), &def))
+	schema := def.JSONSchemaString("runm.image")
+
+	tests := []struct {
+		props []*pb.Property
+		valid bool
+	}{
+		{
+			props: []*pb.Property{
+				{Key: "location.site", Value: "dc1"},
+				{Key: "location.row", Value: "3"},
+				{Key: "net.fast", Value: "true"},
+			},
+			valid: true,
+		},
+		// A property without a definition may be set if its key matches the
+		// default pattern
+		{
+			props: []*pb.Property{
+				{Key: "location.site", Value: "dc1"},
+				{Key: "arch", Value: "x86_64"},
+			},
+			valid: true,
+		},
+		// Required property missing
+		{
+			props: []*pb.Property{
+				{Key: "location.row", Value: "3"},
+			},
+		},
+		// Value is not an integer
+		{
+			props: []*pb.Property{
+				{Key: "location.site", Value: "dc1"},
+				{Key: "location.row", Value: "three"},
+			},
+		},
+		// Value is not a boolean
+		{
+			props: []*pb.Property{
+				{Key: "location.site", Value: "dc1"},
+				{Key: "net.fast", Value: "very"},
+			},
+		},
+	}
+	for x, test := range tests {
+		msgs, err := types.PropertiesErrors(schema, test.props)
+		require.Nil(t, err)
+		assert.Equal(test.valid, len(msgs) == 0, "test %d: %v", x, msgs)
+	}
+
+	// The default object definition does not require any properties
+	msgs, err := types.PropertiesErrors(
+		types.DefaultObjectDefinition().JSONSchemaString("runm.machine"),
+		nil,
+	)
+	assert.Nil(err)
+	assert.Empty(msgs)
+}
+
+func TestPropertiesErrorsTyped(t *testing.T) {
+	assert := assert.New(t)
+
+	def := types.ObjectDefinition{}
+	require.Nil(t, yaml.Unmarshal([]byte(`
+property_definitions:
+  macs:
+    schema:
+      type: array
+      items:
+        type: string
+        pattern: "^([0-9a-f]{2}:){5}[0-9a-f]{2}$"
+      min_items: 1
+  clock:
+    schema:
+      type: number
+      exclusive_minimum: 0
+      default: 2.4
+  fast:
+    schema:
+      type: boolean
+  location:
+    schema:
+      type: object
+      properties:
+        row:
+          type: integer
+      required:
+        - row
+`), &def))
+	require.Nil(t, def.Validate())
+	schema := def.JSONSchemaString("runm.provider")
+
+	prop := func(key string, doc string) *pb.Property {
+		var value interface{}
+		require.Nil(t, yaml.Unmarshal([]byte(doc), &value))
+		p, err := types.NewProperty(key, value)
+		require.Nil(t, err)
+		return p
+	}
+
+	tests := []struct {
+		props []*pb.Property
+		valid bool
+	}{
+		{
+			props: []*pb.Property{
+				prop("macs", `["52:54:00:12:34:56"]`),
+				prop("clock", `2.45`),
+				prop("fast", `true`),
+				prop("location", `{"row": 3}`),
+			},
+			valid: true,
+		},
+		// Properties set before property values were typed are converted
+		{
+			props: []*pb.Property{
+				{Key: "macs", Value: `["52:54:00:12:34:56"]`},
+				{Key: "clock", Value: "2.45"},
+				{Key: "location", Value: `{"row": 3}`},
+			},
+			valid: true,
+		},
+		// Array item does not match the pattern
+		{
+			props: []*pb.Property{prop("macs", `["eth0"]`)},
+		},
+		// Empty array
+		{
+			props: []*pb.Property{prop("macs", `[]`)},
+		},
+		// Exclusive minimum
+		{
+			props: []*pb.Property{prop("clock", `0`)},
+		},
+		// Nested object missing a required field
+		{
+			props: []*pb.Property{prop("location", `{"rack": 3}`)},
+		},
+		// Value is not a boolean
+		{
+			props: []*pb.Property{prop("fast", `"very"`)},
+		},
+	}
+	for x, test := range tests {
+		msgs, err := types.PropertiesErrors(schema, test.props)
+		require.Nil(t, err)
+		assert.Equal(test.valid, len(msgs) == 0, "test %d: %v", x, msgs)
+	}
+
+	defaults, err := types.PropertyDefaults(schema)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"clock": 2.4}, defaults)
+}
+
+func TestNumericProperties(t *testing.T) {
+	assert := assert.New(t)
+
+	def := types.ObjectDefinition{}
+	require.Nil(t, yaml.Unmarshal([]byte(`
+property_definitions:
+  location.site:
+    schema:
+      type: string
+  location.row:
+    schema:
+      type: integer
+  cpu.speed:
+    schema:
+      type: number
+`), &def))
+
+	numeric, err := types.NumericProperties(def.JSONSchemaString("runm.image"))
+	require.Nil(t, err)
+	assert.Equal(map[string]bool{"location.row": true, "cpu.speed": true}, numeric)
+}
@@ -0,0 +1,168 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// NewProperty returns a Property protobuffer message for the supplied key and
+// value, where the value has been decoded from a YAML or JSON document
+func NewProperty(key string, value interface{}) (*pb.Property, error) {
+	typed, err := NewPropertyValue(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for property %s: %s", key, err)
+	}
+	return &pb.Property{
+		Key:        key,
+		Value:      PropertyValueString(value),
+		TypedValue: typed,
+	}, nil
+}
+
+// NewPropertyValue returns a PropertyValue protobuffer message for the
+// supplied value, where the value has been decoded from a YAML or JSON
+// document.
+// NOTE(jaypipes): JSON decoding returns all numbers as float64, so numbers
+// without a fractional part are stored as integers.
+func NewPropertyValue(value interface{}) (*pb.PropertyValue, error) {
+	switch v := value.(type) {
+	case string:
+		return &pb.PropertyValue{
+			Kind: &pb.PropertyValue_StringValue{StringValue: v},
+		}, nil
+	case bool:
+		return &pb.PropertyValue{
+			Kind: &pb.PropertyValue_BooleanValue{BooleanValue: v},
+		}, nil
+	case int:
+		return &pb.PropertyValue{
+			Kind: &pb.PropertyValue_IntegerValue{IntegerValue: int64(v)},
+		}, nil
+	case int64:
+		return &pb.PropertyValue{
+			Kind: &pb.PropertyValue_IntegerValue{IntegerValue: v},
+		}, nil
+	case float64:
+		if isWholeNumber(v) {
+			return &pb.PropertyValue{
+				Kind: &pb.PropertyValue_IntegerValue{IntegerValue: int64(v)},
+			}, nil
+		}
+		return &pb.PropertyValue{
+			Kind: &pb.PropertyValue_NumberValue{NumberValue: v},
+		}, nil
+	case []interface{}:
+		values := make([]*pb.PropertyValue, len(v))
+		for x, item := range v {
+			pv, err := NewPropertyValue(item)
+			if err != nil {
+				return nil, err
+			}
+			values[x] = pv
+		}
+		return &pb.PropertyValue{
+			Kind: &pb.PropertyValue_ArrayValue{
+				ArrayValue: &pb.PropertyValueArray{Values: values},
+			},
+		}, nil
+	case map[string]interface{}:
+		values := make(map[string]*pb.PropertyValue, len(v))
+		for k, item := range v {
+			pv, err := NewPropertyValue(item)
+			if err != nil {
+				return nil, err
+			}
+			values[k] = pv
+		}
+		return &pb.PropertyValue{
+			Kind: &pb.PropertyValue_ObjectValue{
+				ObjectValue: &pb.PropertyValueObject{Values: values},
+			},
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported type of value %T", value)
+}
+
+// isWholeNumber returns true if the supplied float has no fractional part and
+// can be represented exactly as an int64
+func isWholeNumber(f float64) bool {
+	return f == math.Trunc(f) && math.Abs(f) < (1<<53)
+}
+
+// PropertyValueInterface returns the Go value of the supplied PropertyValue
+// protobuffer message, using the same types as JSON decoding except that
+// integers are returned as int64
+func PropertyValueInterface(pv *pb.PropertyValue) interface{} {
+	switch v := pv.GetKind().(type) {
+	case *pb.PropertyValue_StringValue:
+		return v.StringValue
+	case *pb.PropertyValue_IntegerValue:
+		return v.IntegerValue
+	case *pb.PropertyValue_NumberValue:
+		return v.NumberValue
+	case *pb.PropertyValue_BooleanValue:
+		return v.BooleanValue
+	case *pb.PropertyValue_ArrayValue:
+		res := make([]interface{}, len(v.ArrayValue.GetValues()))
+		for x, item := range v.ArrayValue.GetValues() {
+			res[x] = PropertyValueInterface(item)
+		}
+		return res
+	case *pb.PropertyValue_ObjectValue:
+		res := make(map[string]interface{}, len(v.ObjectValue.GetValues()))
+		for k, item := range v.ObjectValue.GetValues() {
+			res[k] = PropertyValueInterface(item)
+		}
+		return res
+	}
+	return nil
+}
+
+// PropertyInterface returns the Go value of the supplied property. Properties
+// without a typed value have a string value.
+func PropertyInterface(prop *pb.Property) interface{} {
+	if prop.TypedValue == nil {
+		return prop.Value
+	}
+	return PropertyValueInterface(prop.TypedValue)
+}
+
+// PropertyValueString returns the string representation of a property value
+// that is stored in the Property protobuffer message's Value field
+func PropertyValueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		if isWholeNumber(v) {
+			return strconv.FormatInt(int64(v), 10)
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}, map[string]interface{}:
+		// NOTE(jaypipes): json.Marshal sorts map keys, so the same object
+		// always has the same string representation
+		b, err := json.Marshal(v)
+		if err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// SortProperties sorts the supplied properties by key
+func SortProperties(props []*pb.Property) {
+	sort.Slice(props, func(i, j int) bool {
+		return props[i].Key < props[j].Key
+	})
+}
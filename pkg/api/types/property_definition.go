@@ -3,6 +3,8 @@ package types
 import (
 	"encoding/json"
 	"fmt"
+
+	"github.com/xeipuuv/gojsonschema"
 )
 
 const (
@@ -21,6 +23,8 @@ var (
 		"integer",
 		"number",
 		"boolean",
+		"array",
+		"object",
 	}
 	// the set of valid format strings that may be specified in property schema
 	// document's "format" field
@@ -40,33 +44,6 @@ var (
 		"iri-reference",
 		"uri-template",
 	}
-	propertySchemaTemplateContents = `{{ define "property-schema" }}
-        {{ quote .Key}}: {
-          "type": [{{ quote_join .Types ", " }}]
-{{- if .MultipleOf }}
-          , "multipleOf": {{ deref_uint .MultipleOf }}
-{{- end }}
-{{- if .Minimum }}
-          , "minimum": {{ deref_int .Minimum }}
-{{- end }}
-{{- if .Maximum }}
-          , "maximum": {{ deref_int .Maximum }}
-{{- end }}
-{{- if .MinLength }}
-          , "minLength": {{ deref_uint .MinLength }}
-{{- end }}
-{{- if .MaxLength }}
-          , "maxLength": {{ deref_uint .MaxLength }}
-{{- end }}
-{{- if .Pattern }}
-          , "pattern": {{ .Pattern }}
-{{- end }}
-{{- if .Format }}
-          , "format": {{ .Formet }}
-{{- end }}
-        }
-{{- end -}}
-`
 )
 
 type PropertyDefinition struct {
@@ -162,21 +139,31 @@ func (a *StringArray) UnmarshalJSON(b []byte) error {
 }
 
 type PropertySchema struct {
-	// May only be a JSON scalar type (string, integer, number, etc)
+	// A JSON type (string, integer, number, boolean, array or object), or a
+	// list of JSON types, the property's value must have
 	Types StringArray `json:"type"`
 	// Property value must be one of these enumerated list of values. If this
 	// exists in the schema document and no types are specified, type is
 	// assumed to be string
-	Enum []string `json:"enum,omitempty"`
+	Enum []interface{} `json:"enum,omitempty"`
+	// The value the property is set to when an object is created without the
+	// property. The default value must itself satisfy the schema.
+	Default interface{} `json:"default,omitempty"`
 	// Indicates the property's value must be a multiple of this number. The
 	// property's type must be either "number" or "integer"
 	MultipleOf *uint `json:"multiple_of,omitempty"`
 	// Indicates the property's numeric value must be greater than or equal to
 	// this number The property's type must be either "number" or "integer"
-	Minimum *int `json:"minimum,omitempty"`
+	Minimum *float64 `json:"minimum,omitempty"`
 	// Indicates the property's numeric value must be less than or equal to
 	// this number The property's type must be either "number" or "integer"
-	Maximum *int `json:"maximum,omitempty"`
+	Maximum *float64 `json:"maximum,omitempty"`
+	// Indicates the property's numeric value must be greater than this
+	// number. The property's type must be either "number" or "integer"
+	ExclusiveMinimum *float64 `json:"exclusive_minimum,omitempty"`
+	// Indicates the property's numeric value must be less than this number.
+	// The property's type must be either "number" or "integer"
+	ExclusiveMaximum *float64 `json:"exclusive_maximum,omitempty"`
 	// Indicates the property's value must be a string and that string must
 	// have a length greater than or equal to this number
 	MinLength *uint `json:"min_length,omitempty"`
@@ -204,6 +191,23 @@ type PropertySchema struct {
 	// * "iri-reference"
 	// * "uri-template"
 	Format string `json:"format"`
+	// Indicates the property's value must be an array and each item in the
+	// array must satisfy this schema
+	Items *PropertySchema `json:"items,omitempty"`
+	// Indicates the property's value must be an array with at least this
+	// many items
+	MinItems *uint `json:"min_items,omitempty"`
+	// Indicates the property's value must be an array with at most this many
+	// items
+	MaxItems *uint `json:"max_items,omitempty"`
+	// Indicates the property's value must be an array with no duplicate items
+	UniqueItems bool `json:"unique_items,omitempty"`
+	// Indicates the property's value must be an object and the fields of the
+	// object with these keys must satisfy the corresponding schema
+	Properties map[string]*PropertySchema `json:"properties,omitempty"`
+	// Indicates the property's value must be an object with fields having
+	// these keys
+	Required []string `json:"required,omitempty"`
 }
 
 type propertySchemaWithKey struct {
@@ -237,6 +241,16 @@ func (schema *PropertySchema) Validate() error {
 			}
 		}
 	}
+	if schema.Items != nil {
+		if err := schema.Items.Validate(); err != nil {
+			return fmt.Errorf("invalid items schema: %s", err)
+		}
+	}
+	for key, field := range schema.Properties {
+		if err := field.Validate(); err != nil {
+			return fmt.Errorf("invalid schema for field '%s': %s", key, err)
+		}
+	}
 	if schema.Format != "" {
 		found := false
 		for _, f := range validFormats {
@@ -253,5 +267,90 @@ func (schema *PropertySchema) Validate() error {
 			)
 		}
 	}
+	if schema.Default != nil {
+		result, err := gojsonschema.Validate(
+			gojsonschema.NewGoLoader(schema.JSONSchema()),
+			gojsonschema.NewGoLoader(schema.Default),
+		)
+		if err != nil {
+			return err
+		}
+		if !result.Valid() {
+			return fmt.Errorf(
+				"default value %v does not satisfy the schema: %s",
+				schema.Default, result.Errors()[0],
+			)
+		}
+	}
 	return nil
 }
+
+// JSONSchema returns the JSONSchema DRAFT-07 document, as a map, that property
+// values must satisfy
+func (schema *PropertySchema) JSONSchema() map[string]interface{} {
+	doc := make(map[string]interface{}, 0)
+	if schema == nil {
+		return doc
+	}
+	if len(schema.Types) > 0 {
+		doc["type"] = []string(schema.Types)
+	} else if len(schema.Enum) > 0 {
+		doc["type"] = []string{"string"}
+	}
+	if len(schema.Enum) > 0 {
+		doc["enum"] = schema.Enum
+	}
+	if schema.Default != nil {
+		doc["default"] = schema.Default
+	}
+	if schema.MultipleOf != nil {
+		doc["multipleOf"] = *schema.MultipleOf
+	}
+	if schema.Minimum != nil {
+		doc["minimum"] = *schema.Minimum
+	}
+	if schema.Maximum != nil {
+		doc["maximum"] = *schema.Maximum
+	}
+	if schema.ExclusiveMinimum != nil {
+		doc["exclusiveMinimum"] = *schema.ExclusiveMinimum
+	}
+	if schema.ExclusiveMaximum != nil {
+		doc["exclusiveMaximum"] = *schema.ExclusiveMaximum
+	}
+	if schema.MinLength != nil {
+		doc["minLength"] = *schema.MinLength
+	}
+	if schema.MaxLength != nil {
+		doc["maxLength"] = *schema.MaxLength
+	}
+	if schema.Pattern != "" {
+		doc["pattern"] = schema.Pattern
+	}
+	if schema.Format != "" {
+		doc["format"] = schema.Format
+	}
+	if schema.Items != nil {
+		doc["items"] = schema.Items.JSONSchema()
+	}
+	if schema.MinItems != nil {
+		doc["minItems"] = *schema.MinItems
+	}
+	if schema.MaxItems != nil {
+		doc["maxItems"] = *schema.MaxItems
+	}
+	if schema.UniqueItems {
+		doc["uniqueItems"] = true
+	}
+	if len(schema.Properties) > 0 {
+		fields := make(map[string]interface{}, len(schema.Properties))
+		for key, field := range schema.Properties {
+			fields[key] = field.JSONSchema()
+		}
+		doc["properties"] = fields
+	}
+	if len(schema.Required) > 0 {
+		doc["required"] = schema.Required
+	}
+	return doc
+}
@@ -10,9 +10,10 @@ import (
 )
 
 var (
-	_zero    = 0
-	_one     = 1
-	_two     = 2
+	_zero    = 0.0
+	_one     = 1.0
+	_two     = 2.0
+	_half    = 0.5
 	_zero_us = uint(0)
 	_one_us  = uint(1)
 	_two_us  = uint(2)
@@ -198,6 +199,56 @@ min_length: 0
 				MinLength: &_zero_us,
 			},
 		},
+		// Check fractional exclusive bounds (number-based)
+		{
+			doc: `
+exclusive_minimum: 0.5
+exclusive_maximum: 2
+`,
+			expect: types.PropertySchema{
+				ExclusiveMinimum: &_half,
+				ExclusiveMaximum: &_two,
+			},
+		},
+		// Check arrays of strings with a default
+		{
+			doc: `
+type: array
+items:
+  type: string
+min_items: 1
+default:
+  - eth0
+`,
+			expect: types.PropertySchema{
+				Types: []string{"array"},
+				Items: &types.PropertySchema{
+					Types: []string{"string"},
+				},
+				MinItems: &_one_us,
+				Default:  []interface{}{"eth0"},
+			},
+		},
+		// Check nested objects
+		{
+			doc: `
+type: object
+properties:
+  row:
+    type: integer
+required:
+  - row
+`,
+			expect: types.PropertySchema{
+				Types: []string{"object"},
+				Properties: map[string]*types.PropertySchema{
+					"row": &types.PropertySchema{
+						Types: []string{"integer"},
+					},
+				},
+				Required: []string{"row"},
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -224,7 +275,35 @@ func TestPropertySchemaValidate(t *testing.T) {
 		// Bad type
 		{
 			doc: &types.PropertySchema{
-				Types: []string{"array", "string"},
+				Types: []string{"tuple", "string"},
+			},
+			expectErr: true,
+		},
+		// Bad type in array items
+		{
+			doc: &types.PropertySchema{
+				Types: []string{"array"},
+				Items: &types.PropertySchema{
+					Types: []string{"tuple"},
+				},
+			},
+			expectErr: true,
+		},
+		// Good default
+		{
+			doc: &types.PropertySchema{
+				Types:   []string{"number"},
+				Minimum: &_zero,
+				Default: 1.5,
+			},
+			expectErr: false,
+		},
+		// Default that does not satisfy the schema
+		{
+			doc: &types.PropertySchema{
+				Types:   []string{"number"},
+				Minimum: &_two,
+				Default: 1.5,
 			},
 			expectErr: true,
 		},
@@ -256,3 +335,35 @@ func TestPropertySchemaValidate(t *testing.T) {
 		}
 	}
 }
+
+func TestPropertySchemaJSONSchema(t *testing.T) {
+	assert := assert.New(t)
+
+	schema := types.PropertySchema{}
+	assert.Nil(yaml.Unmarshal([]byte(`
+type: array
+items:
+  type: string
+  enum:
+    - eth0
+    - eth1
+  pattern: "^eth[0-9]+$"
+  format: hostname
+unique_items: true
+`), &schema))
+
+	assert.Equal(map[string]interface{}{
+		"type": []string{"array"},
+		"items": map[string]interface{}{
+			"type":    []string{"string"},
+			"enum":    []interface{}{"eth0", "eth1"},
+			"pattern": "^eth[0-9]+$",
+			"format":  "hostname",
+		},
+		"uniqueItems": true,
+	}, schema.JSONSchema())
+
+	// Enumerated values are assumed to be strings if no type is specified
+	schema = types.PropertySchema{Enum: []interface{}{"a"}}
+	assert.Equal([]string{"string"}, schema.JSONSchema()["type"])
+}
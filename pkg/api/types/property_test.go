@@ -0,0 +1,49 @@
+package types_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/runmachine-io/runmachine/pkg/api/types"
+)
+
+func TestNewProperty(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		doc      string
+		str      string
+		expected interface{}
+	}{
+		{`"x86_64"`, "x86_64", "x86_64"},
+		{`true`, "true", true},
+		{`4`, "4", int64(4)},
+		// Fractional numbers are not truncated
+		{`2.45`, "2.45", 2.45},
+		{
+			`["52:54:00:12:34:56", "52:54:00:12:34:57"]`,
+			`["52:54:00:12:34:56","52:54:00:12:34:57"]`,
+			[]interface{}{"52:54:00:12:34:56", "52:54:00:12:34:57"},
+		},
+		{
+			`{"row": 3, "site": "dc1"}`,
+			`{"row":3,"site":"dc1"}`,
+			map[string]interface{}{"row": int64(3), "site": "dc1"},
+		},
+	}
+	for _, test := range tests {
+		var value interface{}
+		require.Nil(t, json.Unmarshal([]byte(test.doc), &value))
+		prop, err := types.NewProperty("key", value)
+		require.Nil(t, err)
+		assert.Equal("key", prop.Key)
+		assert.Equal(test.str, prop.Value, test.doc)
+		assert.Equal(test.expected, types.PropertyInterface(prop), test.doc)
+	}
+
+	_, err := types.NewProperty("key", struct{}{})
+	assert.NotNil(err)
+}
@@ -0,0 +1,49 @@
+// Package blobstore stores opaque blobs of bytes, such as the bits of images,
+// keyed by a string identifier. Blobs are written once and never changed.
+package blobstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+const (
+	// DriverLocal is the name of the driver storing blobs in a directory on
+	// the local filesystem
+	DriverLocal = "local"
+)
+
+// Info describes a stored blob
+type Info struct {
+	// Number of bytes in the blob
+	Size uint64
+	// Hex-encoded SHA-256 checksum of the blob's bytes
+	Checksum string
+}
+
+// Store is implemented by each blob store driver
+type Store interface {
+	// Put reads the supplied reader until EOF and stores the bytes read as
+	// the blob with the supplied key, returning information about the stored
+	// blob. Returns errors.ErrDuplicate if a blob with the key already exists.
+	// If reading from the reader fails, no blob is stored.
+	Put(ctx context.Context, key string, r io.Reader) (*Info, error)
+	// Get returns a reader over the bytes of the blob with the supplied key.
+	// The caller is responsible for closing the returned reader. Returns
+	// errors.ErrNotFound if no blob with the key exists.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob with the supplied key. Returns
+	// errors.ErrNotFound if no blob with the key exists.
+	Delete(ctx context.Context, key string) error
+}
+
+// New returns a Store using the supplied driver. For the local driver, path
+// is the directory blobs are stored in.
+func New(driver string, path string) (Store, error) {
+	switch driver {
+	case DriverLocal:
+		return NewLocal(path)
+	}
+	return nil, fmt.Errorf("unknown blob store driver %s", driver)
+}
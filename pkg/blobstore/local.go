@@ -0,0 +1,118 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/runmachine-io/runmachine/pkg/errors"
+)
+
+// Local stores each blob as a file in a directory on the local filesystem
+type Local struct {
+	root string
+}
+
+// NewLocal returns a Local blob store keeping blobs in the supplied
+// directory, which is created if it does not exist
+func NewLocal(root string) (*Local, error) {
+	if root == "" {
+		return nil, fmt.Errorf("path to local blob store is required")
+	}
+	if err := os.MkdirAll(root, 0750); err != nil {
+		return nil, err
+	}
+	return &Local{root: root}, nil
+}
+
+// path returns the path of the file holding the blob with the supplied key
+func (l *Local) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, `/\`) || key[0] == '.' {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(l.root, key), nil
+}
+
+func (l *Local) Put(
+	ctx context.Context,
+	key string,
+	r io.Reader,
+) (*Info, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = os.Stat(path); err == nil {
+		return nil, errors.ErrDuplicate
+	}
+	// NOTE(jaypipes): We write to a temporary file in the same directory and
+	// link it into place once every byte has been written, so that a
+	// partially-written blob is never visible to Get
+	tmp, err := ioutil.TempFile(l.root, ".put-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	// NOTE(jaypipes): Unlike a rename, a hard link fails if the blob was
+	// stored by a concurrent Put after we checked for it above
+	if err = os.Link(tmp.Name(), path); err != nil {
+		if os.IsExist(err) {
+			return nil, errors.ErrDuplicate
+		}
+		return nil, err
+	}
+	return &Info{
+		Size:     uint64(size),
+		Checksum: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return errors.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
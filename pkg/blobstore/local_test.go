@@ -0,0 +1,81 @@
+package blobstore_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/runmachine-io/runmachine/pkg/blobstore"
+	"github.com/runmachine-io/runmachine/pkg/errors"
+)
+
+// failingReader returns some bytes and then an error
+type failingReader struct {
+	sent bool
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.sent {
+		return 0, fmt.Errorf("connection reset")
+	}
+	r.sent = true
+	return copy(p, "partial"), nil
+}
+
+func TestLocal(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	root, err := ioutil.TempDir("", "runm-blobstore-")
+	require.Nil(err)
+	defer os.RemoveAll(root)
+
+	store, err := blobstore.New(blobstore.DriverLocal, root)
+	require.Nil(err)
+
+	ctx := context.TODO()
+	contents := "a bootable bunch of bits"
+	sum := sha256.Sum256([]byte(contents))
+
+	info, err := store.Put(ctx, "img0", strings.NewReader(contents))
+	require.Nil(err)
+	assert.Equal(uint64(len(contents)), info.Size)
+	assert.Equal(hex.EncodeToString(sum[:]), info.Checksum)
+
+	// Blobs may not be overwritten
+	_, err = store.Put(ctx, "img0", strings.NewReader("other bits"))
+	assert.Equal(errors.ErrDuplicate, err)
+
+	r, err := store.Get(ctx, "img0")
+	require.Nil(err)
+	got, err := ioutil.ReadAll(r)
+	r.Close()
+	require.Nil(err)
+	assert.Equal(contents, string(got))
+
+	// A failed read leaves no blob behind
+	_, err = store.Put(ctx, "img1", &failingReader{})
+	assert.NotNil(err)
+	_, err = store.Get(ctx, "img1")
+	assert.Equal(errors.ErrNotFound, err)
+	files, err := ioutil.ReadDir(root)
+	require.Nil(err)
+	assert.Len(files, 1)
+
+	// Keys may not escape the store's directory
+	_, err = store.Put(ctx, "../img2", strings.NewReader(contents))
+	assert.NotNil(err)
+
+	require.Nil(store.Delete(ctx, "img0"))
+	assert.Equal(errors.ErrNotFound, store.Delete(ctx, "img0"))
+	_, err = store.Get(ctx, "img0")
+	assert.Equal(errors.ErrNotFound, err)
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"context"
+	"sort"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/runmachine-io/runmachine/pkg/api/types"
+	"github.com/runmachine-io/runmachine/pkg/util"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// machineAction describes when an action may be taken against a machine
+type machineAction struct {
+	// The observed machine states the action may be taken from
+	fromStates []pb.MachineState
+	// True if the machine's consumer must exist in the resource service
+	needsConsumer bool
+}
+
+var (
+	// Map, keyed by action, of the actions that may be taken against
+	// runm.machine objects
+	machineActions = map[string]*machineAction{
+		"machine.build": {
+			fromStates: []pb.MachineState{
+				pb.MachineState_MACHINE_PENDING,
+				pb.MachineState_MACHINE_ERROR,
+			},
+			needsConsumer: true,
+		},
+		"machine.start": {
+			fromStates: []pb.MachineState{
+				pb.MachineState_MACHINE_STOPPED,
+				pb.MachineState_MACHINE_ERROR,
+			},
+		},
+		"machine.stop": {
+			fromStates: []pb.MachineState{
+				pb.MachineState_MACHINE_RUNNING,
+				pb.MachineState_MACHINE_ERROR,
+			},
+		},
+	}
+)
+
+// validActions returns the sorted names of all actions tasks may take
+func validActions() []string {
+	res := make([]string, 0, len(machineActions))
+	for action := range machineActions {
+		res = append(res, action)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// allowedFrom returns true if the action may be taken against a machine in
+// the supplied observed state
+func (a *machineAction) allowedFrom(state pb.MachineState) bool {
+	for _, s := range a.fromStates {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// machineObjectState returns the observed state and provider UUID stored in
+// the properties of the supplied runm.machine object
+func machineObjectState(obj *pb.Object) (pb.MachineState, string) {
+	var state pb.MachineState
+	var provider string
+	for _, prop := range obj.Properties {
+		switch prop.Key {
+		case "state":
+			// NOTE(jaypipes): An invalid state is treated as pending
+			state, _ = types.MachineStateFromString(prop.Value)
+		case "provider":
+			provider = prop.Value
+		}
+	}
+	return state, provider
+}
+
+// targetGet returns the object of the supplied object type in the session's
+// partition and project with the supplied UUID or name. If no such object
+// could be found, returns (nil, ErrNotFound)
+func (s *Server) targetGet(
+	ctx context.Context,
+	sess *pb.Session,
+	objectType string,
+	search string,
+) (*pb.Object, error) {
+	var obj *pb.Object
+	var err error
+	if util.IsUuidLike(search) {
+		req := &pb.ObjectGetByUuidRequest{
+			Session: sess,
+			Uuid:    util.NormalizeUuid(search),
+		}
+		obj, err = s.metaclient.ObjectGetByUuid(ctx, req)
+	} else {
+		req := &pb.ObjectGetByNameRequest{
+			Session:        sess,
+			PartitionUuid:  sess.Partition,
+			ObjectTypeCode: objectType,
+			Project:        sess.Project,
+			Name:           search,
+		}
+		obj, err = s.metaclient.ObjectGetByName(ctx, req)
+	}
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	// TODO(jaypipes): AUTHZ check if user can act on objects owned by other
+	// projects (i.e. an admin)
+	if obj.ObjectType != objectType ||
+		obj.Partition != sess.Partition ||
+		obj.Project != sess.Project {
+		return nil, ErrNotFound
+	}
+	return obj, nil
+}
+
+// validateAction returns the UUID of the object identified by the supplied
+// target if the supplied action may currently be taken against it, or an
+// error describing why the action may not be taken
+func (s *Server) validateAction(
+	ctx context.Context,
+	sess *pb.Session,
+	action string,
+	target string,
+) (string, error) {
+	ma, found := machineActions[action]
+	if !found {
+		return "", errActionUnknown(action, validActions())
+	}
+	obj, err := s.targetGet(ctx, sess, "runm.machine", target)
+	if err != nil {
+		return "", err
+	}
+	state, provider := machineObjectState(obj)
+	if !ma.allowedFrom(state) {
+		return "", errMachineStateInvalid(
+			action, types.MachineStateString(state),
+		)
+	}
+	if ma.needsConsumer {
+		req := &pb.ConsumerGetByUuidRequest{
+			Session: sess,
+			Uuid:    obj.Uuid,
+		}
+		if _, err = s.resclient.ConsumerGetByUuid(ctx, req); err != nil {
+			if status.Code(err) == codes.NotFound {
+				return "", errMachineConsumerNotFound(obj.Name)
+			}
+			return "", err
+		}
+	}
+	if provider != "" {
+		req := &pb.ProviderGetByUuidRequest{
+			Session: sess,
+			Uuid:    provider,
+		}
+		if _, err = s.resclient.ProviderGetByUuid(ctx, req); err != nil {
+			if status.Code(err) == codes.NotFound {
+				return "", errMachineProviderNotFound(obj.Name, provider)
+			}
+			return "", err
+		}
+	}
+	return obj.Uuid, nil
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+func TestMachineObjectState(t *testing.T) {
+	assert := assert.New(t)
+
+	obj := &pb.Object{
+		ObjectType: "runm.machine",
+		Uuid:       "8b4a3ad5c8e44bb8b6cba8d2b1a1ad53",
+		Name:       "db0",
+		Properties: []*pb.Property{
+			{Key: "provider", Value: "0dc1bb6c3f2c4c8e9e2f6bd0d8a1f5c2"},
+			{Key: "role", Value: "database"},
+			{Key: "state", Value: "stopped"},
+		},
+	}
+	state, provider := machineObjectState(obj)
+	assert.Equal(pb.MachineState_MACHINE_STOPPED, state)
+	assert.Equal("0dc1bb6c3f2c4c8e9e2f6bd0d8a1f5c2", provider)
+
+	// A machine without a valid state is pending
+	state, provider = machineObjectState(&pb.Object{
+		Properties: []*pb.Property{{Key: "state", Value: "sleeping"}},
+	})
+	assert.Equal(pb.MachineState_MACHINE_PENDING, state)
+	assert.Equal("", provider)
+}
+
+func TestMachineActionAllowedFrom(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(
+		[]string{"machine.build", "machine.start", "machine.stop"},
+		validActions(),
+	)
+
+	tests := []struct {
+		action string
+		from   pb.MachineState
+		expect bool
+	}{
+		{"machine.build", pb.MachineState_MACHINE_PENDING, true},
+		{"machine.build", pb.MachineState_MACHINE_RUNNING, false},
+		{"machine.build", pb.MachineState_MACHINE_ERROR, true},
+		{"machine.start", pb.MachineState_MACHINE_STOPPED, true},
+		{"machine.start", pb.MachineState_MACHINE_RUNNING, false},
+		{"machine.start", pb.MachineState_MACHINE_BUILDING, false},
+		{"machine.stop", pb.MachineState_MACHINE_RUNNING, true},
+		{"machine.stop", pb.MachineState_MACHINE_PENDING, false},
+	}
+	for _, test := range tests {
+		assert.Equal(
+			test.expect,
+			machineActions[test.action].allowedFrom(test.from),
+			"%s from %s", test.action, test.from,
+		)
+	}
+}
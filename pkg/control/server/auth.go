@@ -0,0 +1,37 @@
+package server
+
+import (
+	"context"
+
+	"github.com/runmachine-io/runmachine/pkg/util"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+func (s *Server) checkSession(ctx context.Context, sess *pb.Session) error {
+	if sess == nil || sess.User == "" {
+		return ErrSessionUserRequired
+	}
+	if sess.Partition == "" {
+		return ErrSessionPartitionRequired
+	} else {
+		// If the Session's partition identifier isn't a UUID, convert it to a
+		// partition UUID from a name. The metadata service returns an error
+		// if no partition with that name exists. Tasks are always stored with
+		// the UUID of their partition.
+		if !util.IsUuidLike(sess.Partition) {
+			req := &pb.PartitionGetByNameRequest{
+				Session: sess,
+				Name:    sess.Partition,
+			}
+			part, err := s.metaclient.PartitionGetByName(ctx, req)
+			if err != nil {
+				return err
+			}
+			sess.Partition = part.Uuid
+		}
+	}
+	if sess.Project == "" {
+		return ErrSessionProjectRequired
+	}
+	return nil
+}
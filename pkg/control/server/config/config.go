@@ -0,0 +1,308 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	flag "github.com/ogier/pflag"
+
+	"github.com/jaypipes/envutil"
+	"github.com/runmachine-io/runmachine/pkg/util"
+)
+
+const (
+	cfgPath                          = "/etc/runmachine/control"
+	defaultUseTLS                    = false
+	defaultBindPort                  = 10003
+	defaultServiceName               = "runmachine-control"
+	defaultMetadataServiceName       = "runmachine-metadata"
+	defaultResourceServiceName       = "runmachine-resource"
+	defaultEtcdEndpoints             = "http://127.0.0.1:2379"
+	defaultEtcdKeyPrefix             = "runm-control/"
+	defaultEtcdConnectTimeoutSeconds = 300
+	defaultEtcdRequestTimeoutSeconds = 1
+	defaultEtcdDialTimeoutSeconds    = 1
+	defaultLBPolicy                  = "round_robin"
+	defaultMetricsAddress            = ""
+	defaultTaskRetentionSeconds      = 86400
+	defaultTaskLeaseSeconds          = 60
+)
+
+var (
+	defaultCertPath = filepath.Join(cfgPath, "server.pem")
+	defaultKeyPath  = filepath.Join(cfgPath, "server.key")
+	defaultBindHost = util.BindHost()
+)
+
+type Config struct {
+	UseTLS                    bool
+	CertPath                  string
+	KeyPath                   string
+	BindHost                  string
+	BindPort                  int
+	ServiceName               string
+	MetadataServiceName       string
+	ResourceServiceName       string
+	EtcdEndpoints             []string
+	EtcdKeyPrefix             string
+	EtcdConnectTimeoutSeconds time.Duration
+	EtcdRequestTimeoutSeconds time.Duration
+	EtcdDialTimeoutSeconds    time.Duration
+	// Policy used to balance RPCs across the endpoints of the metadata and
+	// resource services
+	LBPolicy string
+	// Address (host:port) of the HTTP listener serving Prometheus metrics at
+	// /metrics. Metrics are not served if empty.
+	MetricsAddress string
+	// How long finished tasks are kept before they are removed
+	TaskRetentionSeconds time.Duration
+	// How long an executor's claim on a running task lasts without a
+	// heartbeat from the executor before the task is queued again
+	TaskLeaseSeconds time.Duration
+}
+
+func ConfigFromOpts() *Config {
+	optUseTLS := flag.Bool(
+		"use-tls",
+		envutil.WithDefaultBool(
+			"RUNM_CONTROL_USE_TLS", defaultUseTLS,
+		),
+		"Connection uses TLS if true, else plain TCP",
+	)
+	optCertPath := flag.String(
+		"cert-path",
+		envutil.WithDefault(
+			"RUNM_CONTROL_CERT_PATH", defaultCertPath,
+		),
+		"Path to the TLS cert file",
+	)
+	optKeyPath := flag.String(
+		"key-path",
+		envutil.WithDefault(
+			"RUNM_CONTROL_KEY_PATH", defaultKeyPath,
+		),
+		"Path to the TLS key file",
+	)
+	optHost := flag.String(
+		"bind-address",
+		envutil.WithDefault(
+			"RUNM_CONTROL_BIND_HOST", defaultBindHost,
+		),
+		"The host address the server will listen on",
+	)
+	optPort := flag.Int(
+		"bind-port",
+		envutil.WithDefaultInt(
+			"RUNM_CONTROL_BIND_PORT", defaultBindPort,
+		),
+		"The port the server will listen on",
+	)
+	optServiceName := flag.String(
+		"service-name",
+		envutil.WithDefault(
+			"RUNM_CONTROL_SERVICE_NAME", defaultServiceName,
+		),
+		"Name to use when registering with the service registry",
+	)
+	optMetadataServiceName := flag.String(
+		"metadata-service-name",
+		envutil.WithDefault(
+			"RUNM_METADATA_SERVICE_NAME", defaultMetadataServiceName,
+		),
+		"Name to use when querying the service registry for the metadata service",
+	)
+	optResourceServiceName := flag.String(
+		"resource-service-name",
+		envutil.WithDefault(
+			"RUNM_RESOURCE_SERVICE_NAME", defaultResourceServiceName,
+		),
+		"Name to use when querying the service registry for the resource service",
+	)
+
+	optEtcdEndpointsStr := flag.String(
+		"storage-etcd-endpoints",
+		envutil.WithDefault(
+			"RUNM_CONTROL_STORAGE_ETCD_ENDPOINTS", defaultEtcdEndpoints,
+		),
+		"Comma-delimited list of etcd3 endpoints to use for task queue storage",
+	)
+	endpoints := etcdNormalizeEndpoints(*optEtcdEndpointsStr)
+	optKeyPrefix := flag.String(
+		"storage-etcd-key-prefix",
+		strings.TrimRight(
+			envutil.WithDefault(
+				"RUNM_CONTROL_STORAGE_ETCD_KEY_PREFIX",
+				defaultEtcdKeyPrefix,
+			),
+			"/",
+		)+"/",
+		"Prefix to use to segregate all runm-control tasks inside etcd3",
+	)
+	optConnectTimeout := flag.Int(
+		"storage-etcd-connect-timeout-seconds",
+		envutil.WithDefaultInt(
+			"RUNM_CONTROL_STORAGE_ETCD_CONNECT_TIMEOUT_SECONDS",
+			defaultEtcdConnectTimeoutSeconds,
+		),
+		"Total number of seconds to attempt connection to etcd",
+	)
+	optRequestTimeout := flag.Int(
+		"storage-etcd-request-timeout-seconds",
+		envutil.WithDefaultInt(
+			"RUNM_CONTROL_STORAGE_ETCD_REQUEST_TIMEOUT_SECONDS",
+			defaultEtcdRequestTimeoutSeconds,
+		),
+		"Number of seconds to timeout attempting each individual etcd request",
+	)
+	optDialTimeout := flag.Int(
+		"storage-etcd-dial-timeout-seconds",
+		envutil.WithDefaultInt(
+			"RUNM_CONTROL_STORAGE_ETCD_DIAL_TIMEOUT_SECONDS",
+			defaultEtcdDialTimeoutSeconds,
+		),
+		"Number of seconds to timeout attempting each connect/dial attempt to etcd",
+	)
+	optLBPolicy := flag.String(
+		"lb-policy",
+		envutil.WithDefault(
+			"RUNM_CONTROL_LB_POLICY", defaultLBPolicy,
+		),
+		"Policy (round_robin or least_loaded) used to balance RPCs "+
+			"across metadata and resource service endpoints",
+	)
+	optMetricsAddress := flag.String(
+		"metrics-address",
+		envutil.WithDefault(
+			"RUNM_CONTROL_METRICS_ADDRESS", defaultMetricsAddress,
+		),
+		"Address (host:port) to serve Prometheus metrics on. Metrics are "+
+			"not served if empty",
+	)
+	optTaskRetention := flag.Int(
+		"task-retention-seconds",
+		envutil.WithDefaultInt(
+			"RUNM_CONTROL_TASK_RETENTION_SECONDS",
+			defaultTaskRetentionSeconds,
+		),
+		"Number of seconds finished tasks are kept before they are removed",
+	)
+	optTaskLease := flag.Int(
+		"task-lease-seconds",
+		envutil.WithDefaultInt(
+			"RUNM_CONTROL_TASK_LEASE_SECONDS",
+			defaultTaskLeaseSeconds,
+		),
+		"Number of seconds a running task stays claimed by its executor "+
+			"without a heartbeat from the executor",
+	)
+
+	flag.Parse()
+
+	return &Config{
+		UseTLS:                    *optUseTLS,
+		CertPath:                  *optCertPath,
+		KeyPath:                   *optKeyPath,
+		BindHost:                  *optHost,
+		BindPort:                  *optPort,
+		ServiceName:               *optServiceName,
+		MetadataServiceName:       *optMetadataServiceName,
+		ResourceServiceName:       *optResourceServiceName,
+		EtcdEndpoints:             endpoints,
+		EtcdKeyPrefix:             *optKeyPrefix,
+		EtcdConnectTimeoutSeconds: time.Duration(*optConnectTimeout) * time.Second,
+		EtcdRequestTimeoutSeconds: time.Duration(*optRequestTimeout) * time.Second,
+		EtcdDialTimeoutSeconds:    time.Duration(*optDialTimeout) * time.Second,
+		LBPolicy:                  *optLBPolicy,
+		MetricsAddress:            *optMetricsAddress,
+		TaskRetentionSeconds:      time.Duration(*optTaskRetention) * time.Second,
+		TaskLeaseSeconds:          time.Duration(*optTaskLease) * time.Second,
+	}
+}
+
+// Returns an etcd configuration struct populated with all configured options.
+func (c *Config) EtcdConfig() *etcd.Config {
+	return &etcd.Config{
+		Endpoints:   c.EtcdEndpoints,
+		DialTimeout: c.EtcdDialTimeoutSeconds,
+		TLS:         c.TLSConfig(),
+	}
+}
+
+// Returns the TLS configuration struct to use with etcd client.
+func (c *Config) TLSConfig() *tls.Config {
+	cfg := &tls.Config{}
+
+	if !c.UseTLS {
+		return nil
+	}
+	certPath := c.CertPath
+	keyPath := c.KeyPath
+
+	if certPath == "" || keyPath == "" {
+		fmt.Fprintf(
+			os.Stderr,
+			"error setting up TLS configuration. Either cert or "+
+				"key path not specified.",
+		)
+		return nil
+	}
+
+	certContent, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		fmt.Fprintf(
+			os.Stderr,
+			"error getting cert content: %v",
+			err,
+		)
+		return nil
+	}
+
+	keyContent, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		fmt.Fprintf(
+			os.Stderr,
+			"error getting key content: %v",
+			err,
+		)
+		return nil
+	}
+
+	kp, err := tls.X509KeyPair(certContent, keyContent)
+	if err != nil {
+		fmt.Fprintf(
+			os.Stderr,
+			"error setting up TLS cert: %v.",
+			err,
+		)
+		return nil
+	}
+
+	cfg.MinVersion = tls.VersionTLS10
+	cfg.InsecureSkipVerify = false
+	cfg.Certificates = []tls.Certificate{kp}
+	return cfg
+}
+
+// Returns the set of etcd3 endpoints used by runm-control
+func etcdNormalizeEndpoints(epsStr string) []string {
+	eps := strings.Split(epsStr, ",")
+	res := make([]string, len(eps))
+	// Ensure endpoints begin with http[s]:// and contain a port. If missing,
+	// add default etcd port.
+	for x, ep := range eps {
+		if !strings.HasPrefix(ep, "http") {
+			ep = "http://" + ep
+		}
+		if strings.Count(ep, ":") == 1 {
+			ep = ep + ":2379"
+		}
+		res[x] = ep
+	}
+	return res
+}
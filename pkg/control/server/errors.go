@@ -0,0 +1,100 @@
+package server
+
+import (
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrUnknown = status.Errorf(
+		codes.Unknown,
+		"an unknown error occurred.",
+	)
+	ErrNotFound = status.Errorf(
+		codes.NotFound,
+		"object could not be found.",
+	)
+	ErrSessionUserRequired = status.Errorf(
+		codes.FailedPrecondition,
+		"user is required in session.",
+	)
+	ErrSessionPartitionRequired = status.Errorf(
+		codes.FailedPrecondition,
+		"partition is required in session.",
+	)
+	ErrSessionProjectRequired = status.Errorf(
+		codes.FailedPrecondition,
+		"project is required in session.",
+	)
+	ErrUuidRequired = status.Errorf(
+		codes.FailedPrecondition,
+		"UUID is required.",
+	)
+	ErrActionRequired = status.Errorf(
+		codes.FailedPrecondition,
+		"action is required.",
+	)
+	ErrTargetRequired = status.Errorf(
+		codes.FailedPrecondition,
+		"target is required.",
+	)
+	ErrExecutorRequired = status.Errorf(
+		codes.FailedPrecondition,
+		"executor is required.",
+	)
+	ErrTaskFinishStatusInvalid = status.Errorf(
+		codes.InvalidArgument,
+		"status must be one of SUCCEEDED, FAILED or CANCELLED.",
+	)
+	ErrTaskFinished = status.Errorf(
+		codes.FailedPrecondition,
+		"task has already finished.",
+	)
+	ErrTaskNotClaimed = status.Errorf(
+		codes.FailedPrecondition,
+		"task is not running or was claimed by another executor.",
+	)
+	ErrTaskConflict = status.Errorf(
+		codes.Aborted,
+		"task was changed concurrently. please retry.",
+	)
+)
+
+func errActionUnknown(action string, valid []string) error {
+	return status.Errorf(
+		codes.InvalidArgument,
+		"Unknown action %s. Valid actions: %s",
+		action, strings.Join(valid, ", "),
+	)
+}
+
+func errTargetBusy(target string) error {
+	return status.Errorf(
+		codes.FailedPrecondition,
+		"Another task is already queued or running for %s", target,
+	)
+}
+
+func errMachineStateInvalid(action string, state string) error {
+	return status.Errorf(
+		codes.FailedPrecondition,
+		"Action %s may not be taken against a machine in state %s",
+		action, state,
+	)
+}
+
+func errMachineConsumerNotFound(machine string) error {
+	return status.Errorf(
+		codes.FailedPrecondition,
+		"Resources of machine %s have not been claimed", machine,
+	)
+}
+
+func errMachineProviderNotFound(machine string, provider string) error {
+	return status.Errorf(
+		codes.FailedPrecondition,
+		"Provider %s of machine %s not found", provider, machine,
+	)
+}
@@ -0,0 +1,23 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/runmachine-io/runmachine/pkg/health"
+)
+
+// HealthCheck returns nil if the control service is able to reach etcd and
+// healthy metadata and resource services
+func (s *Server) HealthCheck(ctx context.Context) error {
+	if err := s.store.Ping(ctx); err != nil {
+		return err
+	}
+	if err := health.CheckConn(ctx, s.metasc.Conn()); err != nil {
+		return fmt.Errorf("metadata service unhealthy: %s", err)
+	}
+	if err := health.CheckConn(ctx, s.ressc.Conn()); err != nil {
+		return fmt.Errorf("resource service unhealthy: %s", err)
+	}
+	return nil
+}
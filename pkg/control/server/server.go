@@ -0,0 +1,104 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/jaypipes/gsr"
+
+	"github.com/runmachine-io/runmachine/pkg/control/server/config"
+	"github.com/runmachine-io/runmachine/pkg/control/server/storage"
+	"github.com/runmachine-io/runmachine/pkg/logging"
+	"github.com/runmachine-io/runmachine/pkg/registry"
+	"github.com/runmachine-io/runmachine/pkg/svcclient"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+type Server struct {
+	log        *logging.Logs
+	cfg        *config.Config
+	registry   registry.Registry
+	store      *storage.Store
+	metasc     *svcclient.Client
+	metaclient pb.RunmMetadataClient
+	ressc      *svcclient.Client
+	resclient  pb.RunmResourceClient
+}
+
+func (s *Server) Close() {
+	addr := fmt.Sprintf("%s:%d", s.cfg.BindHost, s.cfg.BindPort)
+	s.log.L1(
+		"unregistering %s:%s endpoint in service registry",
+		s.cfg.ServiceName,
+		addr,
+	)
+	ep := &gsr.Endpoint{
+		Service: &gsr.Service{Name: s.cfg.ServiceName},
+		Address: addr,
+	}
+	err := s.registry.Unregister(ep)
+	if err != nil {
+		s.log.ERR("failed to unregister: %s\n", err)
+	}
+	s.metasc.Close()
+	s.ressc.Close()
+	s.store.Close()
+}
+
+func New(
+	cfg *config.Config,
+	log *logging.Logs,
+	reg registry.Registry,
+) (*Server, error) {
+	store, err := storage.New(log, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to task storage: %v", err)
+	}
+	log.L2("connected to task storage.")
+
+	metasc, err := svcclient.New(
+		log, reg, cfg.MetadataServiceName,
+		svcclient.DefaultOptions(cfg.LBPolicy),
+	)
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to create metadata service client: %v", err)
+	}
+	ressc, err := svcclient.New(
+		log, reg, cfg.ResourceServiceName,
+		svcclient.DefaultOptions(cfg.LBPolicy),
+	)
+	if err != nil {
+		metasc.Close()
+		store.Close()
+		return nil, fmt.Errorf("failed to create resource service client: %v", err)
+	}
+
+	// Register this runm-control service endpoint with the service registry
+	addr := fmt.Sprintf("%s:%d", cfg.BindHost, cfg.BindPort)
+	ep := gsr.Endpoint{
+		Service: &gsr.Service{Name: cfg.ServiceName},
+		Address: addr,
+	}
+	if err = reg.Register(&ep); err != nil {
+		ressc.Close()
+		metasc.Close()
+		store.Close()
+		return nil, fmt.Errorf("failed to register %v with service registry: %v", ep, err)
+	}
+	log.L2(
+		"registered %s service endpoint running at %s with service registry.",
+		cfg.ServiceName,
+		addr,
+	)
+
+	return &Server{
+		log:        log,
+		cfg:        cfg,
+		registry:   reg,
+		store:      store,
+		metasc:     metasc,
+		metaclient: pb.NewRunmMetadataClient(metasc.Conn()),
+		ressc:      ressc,
+		resclient:  pb.NewRunmResourceClient(ressc.Conn()),
+	}, nil
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	etcd "github.com/coreos/etcd/clientv3"
+
+	"github.com/runmachine-io/runmachine/pkg/metrics"
+	"github.com/runmachine-io/runmachine/pkg/tracing"
+)
+
+const (
+	metricsBackend = "etcd"
+)
+
+// startOperation starts a trace span for an etcd operation and returns a
+// function that must be called with the operation's result to end the span
+// and record the operation's duration
+func startOperation(
+	ctx context.Context,
+	operation string,
+) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := tracing.StartSpan(ctx, "etcd."+operation, tracing.SpanKindClient)
+	span.SetAttribute("db.system", metricsBackend)
+	return ctx, func(err error) {
+		metrics.ObserveStorage(metricsBackend, operation, start, err)
+		span.SetError(err)
+		span.End()
+	}
+}
+
+// instrumentedKV wraps an etcd.KV and records a trace span and the duration
+// of each etcd operation
+type instrumentedKV struct {
+	etcd.KV
+}
+
+func newInstrumentedKV(kv etcd.KV) etcd.KV {
+	return &instrumentedKV{kv}
+}
+
+func (kv *instrumentedKV) Get(
+	ctx context.Context,
+	key string,
+	opts ...etcd.OpOption,
+) (*etcd.GetResponse, error) {
+	ctx, done := startOperation(ctx, "get")
+	resp, err := kv.KV.Get(ctx, key, opts...)
+	done(err)
+	return resp, err
+}
+
+func (kv *instrumentedKV) Put(
+	ctx context.Context,
+	key string,
+	val string,
+	opts ...etcd.OpOption,
+) (*etcd.PutResponse, error) {
+	ctx, done := startOperation(ctx, "put")
+	resp, err := kv.KV.Put(ctx, key, val, opts...)
+	done(err)
+	return resp, err
+}
+
+func (kv *instrumentedKV) Delete(
+	ctx context.Context,
+	key string,
+	opts ...etcd.OpOption,
+) (*etcd.DeleteResponse, error) {
+	ctx, done := startOperation(ctx, "delete")
+	resp, err := kv.KV.Delete(ctx, key, opts...)
+	done(err)
+	return resp, err
+}
+
+// Do is called by namespaced KVs for gets, puts and deletes
+func (kv *instrumentedKV) Do(
+	ctx context.Context,
+	op etcd.Op,
+) (etcd.OpResponse, error) {
+	operation := "do"
+	switch {
+	case op.IsGet():
+		operation = "get"
+	case op.IsPut():
+		operation = "put"
+	case op.IsDelete():
+		operation = "delete"
+	case op.IsTxn():
+		operation = "txn"
+	}
+	ctx, done := startOperation(ctx, operation)
+	resp, err := kv.KV.Do(ctx, op)
+	done(err)
+	return resp, err
+}
+
+func (kv *instrumentedKV) Txn(ctx context.Context) etcd.Txn {
+	return &instrumentedTxn{kv.KV.Txn(ctx), ctx}
+}
+
+// instrumentedTxn wraps an etcd.Txn and records a trace span and the duration
+// of the transaction's commit
+type instrumentedTxn struct {
+	etcd.Txn
+	ctx context.Context
+}
+
+func (txn *instrumentedTxn) If(cs ...etcd.Cmp) etcd.Txn {
+	txn.Txn = txn.Txn.If(cs...)
+	return txn
+}
+
+func (txn *instrumentedTxn) Then(ops ...etcd.Op) etcd.Txn {
+	txn.Txn = txn.Txn.Then(ops...)
+	return txn
+}
+
+func (txn *instrumentedTxn) Else(ops ...etcd.Op) etcd.Txn {
+	txn.Txn = txn.Txn.Else(ops...)
+	return txn
+}
+
+func (txn *instrumentedTxn) Commit() (*etcd.TxnResponse, error) {
+	_, done := startOperation(txn.ctx, "txn")
+	resp, err := txn.Txn.Commit()
+	done(err)
+	return resp, err
+}
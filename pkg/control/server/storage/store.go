@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	etcd_namespace "github.com/coreos/etcd/clientv3/namespace"
+
+	"github.com/runmachine-io/runmachine/pkg/control/server/config"
+	"github.com/runmachine-io/runmachine/pkg/logging"
+)
+
+const (
+	// The key that carves out a namespace for the runm-control service to
+	// store stuff in etcd. This namespace comes directly UNDER the
+	// Config.EtcdKeyPrefix namespace. This namespace is referred to as $ROOT
+	_SERVICE_KEY = "runm/control/"
+
+	// Used when creating empty leaf-level keys or key namespaces
+	_NO_VALUE = ""
+)
+
+type Store struct {
+	log    *logging.Logs
+	cfg    *config.Config
+	client *etcd.Client
+	kv     etcd.KV
+}
+
+// New returns a Store connected to etcd
+func New(log *logging.Logs, cfg *config.Config) (*Store, error) {
+	client, err := connect(log, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{
+		log:    log,
+		cfg:    cfg,
+		client: client,
+		kv: newInstrumentedKV(
+			etcd_namespace.NewKV(client.KV, cfg.EtcdKeyPrefix+_SERVICE_KEY),
+		),
+	}, nil
+}
+
+func (s *Store) requestCtx(
+	ctx context.Context,
+) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(
+		ctx,
+		s.cfg.EtcdRequestTimeoutSeconds,
+	)
+}
+
+// Ping returns nil if the etcd cluster is reachable and able to service reads
+func (s *Store) Ping(ctx context.Context) error {
+	_, err := s.kv.Get(ctx, "health", etcd.WithCountOnly())
+	return err
+}
+
+// Close closes the Store's connection to etcd
+func (s *Store) Close() {
+	s.client.Close()
+}
@@ -0,0 +1,596 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/runmachine-io/runmachine/pkg/errors"
+	"github.com/runmachine-io/runmachine/pkg/util"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	// $ROOT/tasks/by-uuid/ is a key namespace that stores valued keys where
+	// the key is the task's UUID and the value is the serialized Task
+	// protobuffer message
+	_TASKS_BY_UUID_KEY = "tasks/by-uuid/"
+	// $ROOT/tasks/by-status/$PARTITION/$STATUS/ is a key namespace containing
+	// a valueless key for each task in the partition having the status, keyed
+	// by the task's UUID
+	_TASKS_BY_STATUS_KEY = "tasks/by-status/"
+	// $ROOT/queues/$PARTITION/ is a key namespace containing a valueless key
+	// for each queued task in the partition, keyed by the task's UUID. Tasks
+	// are claimed in the order their keys were created.
+	_QUEUES_KEY = "queues/"
+	// $ROOT/targets/ is a key namespace that stores valued keys where the key
+	// is the UUID of an object having a queued or running task and the value
+	// is the UUID of that task. Only one task may be active for an object at
+	// a time.
+	_TARGETS_KEY = "targets/"
+	// $ROOT/claims/$PARTITION/ is a key namespace that stores valued keys
+	// where the key is the UUID of a running task and the value is the
+	// executor that claimed the task. Each key is attached to a lease that the
+	// executor keeps alive with heartbeats. A running task without a key here
+	// was abandoned by its executor.
+	_CLAIMS_KEY = "claims/"
+
+	// Number of times to attempt to claim the oldest queued task when other
+	// workers are claiming tasks from the same queue concurrently
+	_CLAIM_ATTEMPTS = 5
+)
+
+// TaskFilter describes the tasks returned by TasksGetMatching
+type TaskFilter struct {
+	Partition string
+	// If not empty, only tasks submitted by this project match
+	Project string
+	// If not empty, only tasks against this object match
+	Target string
+	// If not empty, only tasks having any of these statuses match
+	Statuses []pb.TaskStatus
+}
+
+// matches returns true if the supplied task matches the filter
+func (f *TaskFilter) matches(task *pb.Task) bool {
+	if task.Partition != f.Partition {
+		return false
+	}
+	if f.Project != "" && task.Project != f.Project {
+		return false
+	}
+	if f.Target != "" && task.Target != f.Target {
+		return false
+	}
+	if len(f.Statuses) == 0 {
+		return true
+	}
+	for _, st := range f.Statuses {
+		if task.Status == st {
+			return true
+		}
+	}
+	return false
+}
+
+// taskStatusFinished returns true if the supplied status is a terminal status
+func taskStatusFinished(status pb.TaskStatus) bool {
+	switch status {
+	case pb.TaskStatus_TASK_SUCCEEDED,
+		pb.TaskStatus_TASK_FAILED,
+		pb.TaskStatus_TASK_CANCELLED:
+		return true
+	}
+	return false
+}
+
+// taskStatusPrefix returns the prefix of the keys indexing the tasks in the
+// supplied partition having the supplied status
+func taskStatusPrefix(partition string, status pb.TaskStatus) string {
+	return _TASKS_BY_STATUS_KEY + partition + "/" + status.String() + "/"
+}
+
+func taskStatusKey(task *pb.Task, status pb.TaskStatus) string {
+	return taskStatusPrefix(task.Partition, status) + task.Uuid
+}
+
+func taskQueueKey(task *pb.Task) string {
+	return _QUEUES_KEY + task.Partition + "/" + task.Uuid
+}
+
+func taskTargetKey(task *pb.Task) string {
+	return _TARGETS_KEY + task.Target
+}
+
+func taskClaimKey(task *pb.Task) string {
+	return _CLAIMS_KEY + task.Partition + "/" + task.Uuid
+}
+
+// taskGet returns the task with the supplied UUID along with the revision the
+// task's key was last modified at. If no such task exists, returns
+// errors.ErrNotFound
+func (s *Store) taskGet(
+	ctx context.Context,
+	uuid string,
+) (*pb.Task, int64, error) {
+	resp, err := s.kv.Get(ctx, _TASKS_BY_UUID_KEY+uuid)
+	if err != nil {
+		s.log.ERR("error getting task by UUID(%s): %v", uuid, err)
+		return nil, 0, err
+	}
+	if resp.Count == 0 {
+		return nil, 0, errors.ErrNotFound
+	}
+	task := &pb.Task{}
+	if err = proto.Unmarshal(resp.Kvs[0].Value, task); err != nil {
+		return nil, 0, err
+	}
+	return task, resp.Kvs[0].ModRevision, nil
+}
+
+// TaskGet returns the task with the supplied UUID. If no such task exists,
+// returns errors.ErrNotFound
+func (s *Store) TaskGet(
+	ctx context.Context,
+	uuid string,
+) (*pb.Task, error) {
+	ctx, cancel := s.requestCtx(ctx)
+	defer cancel()
+
+	task, _, err := s.taskGet(ctx, uuid)
+	return task, err
+}
+
+// TasksGetMatching returns the tasks matching the supplied filter, ordered by
+// UUID. Only the tasks in the filter's partition having one of the filter's
+// statuses are read.
+func (s *Store) TasksGetMatching(
+	ctx context.Context,
+	filter *TaskFilter,
+) ([]*pb.Task, error) {
+	ctx, cancel := s.requestCtx(ctx)
+	defer cancel()
+
+	prefixes := []string{_TASKS_BY_STATUS_KEY + filter.Partition + "/"}
+	if len(filter.Statuses) > 0 {
+		prefixes = make([]string, len(filter.Statuses))
+		for x, status := range filter.Statuses {
+			prefixes[x] = taskStatusPrefix(filter.Partition, status)
+		}
+	}
+	res := make([]*pb.Task, 0)
+	for _, prefix := range prefixes {
+		resp, err := s.kv.Get(
+			ctx, prefix, etcd.WithPrefix(), etcd.WithKeysOnly(),
+		)
+		if err != nil {
+			s.log.ERR("error listing tasks at key %s: %v", prefix, err)
+			return nil, err
+		}
+		for _, kv := range resp.Kvs {
+			key := string(kv.Key)
+			uuid := key[strings.LastIndex(key, "/")+1:]
+			task, _, err := s.taskGet(ctx, uuid)
+			if err == errors.ErrNotFound {
+				// The task was removed after its retention period passed
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if filter.matches(task) {
+				res = append(res, task)
+			}
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Uuid < res[j].Uuid
+	})
+	return res, nil
+}
+
+// TaskSubmit queues the supplied task and returns the task with its UUID,
+// status and timestamps set. Returns errors.ErrTaskTargetBusy if another task
+// against the same target is queued or running.
+func (s *Store) TaskSubmit(
+	ctx context.Context,
+	task *pb.Task,
+) (*pb.Task, error) {
+	ctx, cancel := s.requestCtx(ctx)
+	defer cancel()
+
+	now := time.Now().Unix()
+	task.Uuid = util.NewNormalizedUuid()
+	task.Status = pb.TaskStatus_TASK_QUEUED
+	task.CreateTime = now
+	task.UpdateTime = now
+
+	taskValue, err := proto.Marshal(task)
+	if err != nil {
+		s.log.ERR("failed to serialize task: %v", err)
+		return nil, err
+	}
+
+	taskKey := _TASKS_BY_UUID_KEY + task.Uuid
+	targetKey := taskTargetKey(task)
+	then := []etcd.Op{
+		etcd.OpPut(taskKey, string(taskValue)),
+		etcd.OpPut(taskStatusKey(task, task.Status), _NO_VALUE),
+		etcd.OpPut(taskQueueKey(task), _NO_VALUE),
+		etcd.OpPut(targetKey, task.Uuid),
+	}
+	compare := []etcd.Cmp{
+		// Ensure no other task is active for the target
+		etcd.Compare(etcd.Version(targetKey), "=", 0),
+	}
+	resp, err := s.kv.Txn(ctx).If(compare...).Then(then...).Commit()
+	if err != nil {
+		s.log.ERR("failed to create txn in etcd: %v", err)
+		return nil, err
+	} else if !resp.Succeeded {
+		return nil, errors.ErrTaskTargetBusy
+	}
+	return task, nil
+}
+
+// requeueAbandoned queues again the running tasks in the supplied partition
+// whose executors stopped sending heartbeats. An abandoned task whose
+// cancellation was requested is cancelled instead.
+func (s *Store) requeueAbandoned(
+	ctx context.Context,
+	partition string,
+) error {
+	runningKey := taskStatusPrefix(partition, pb.TaskStatus_TASK_RUNNING)
+	running, err := s.kv.Get(
+		ctx, runningKey, etcd.WithPrefix(), etcd.WithKeysOnly(),
+	)
+	if err != nil {
+		s.log.ERR("error getting running tasks: %v", err)
+		return err
+	}
+	if running.Count == 0 {
+		return nil
+	}
+	// NOTE(jaypipes): A task's claim key is created in the same transaction
+	// that adds the task to the running index, so any task in the running
+	// index read above that has no claim key now has an expired claim
+	claimsKey := _CLAIMS_KEY + partition + "/"
+	claims, err := s.kv.Get(
+		ctx, claimsKey, etcd.WithPrefix(), etcd.WithKeysOnly(),
+	)
+	if err != nil {
+		s.log.ERR("error getting task claims: %v", err)
+		return err
+	}
+	claimed := make(map[string]bool, len(claims.Kvs))
+	for _, kv := range claims.Kvs {
+		claimed[string(kv.Key)[len(claimsKey):]] = true
+	}
+
+	for _, kv := range running.Kvs {
+		uuid := string(kv.Key)[len(runningKey):]
+		if claimed[uuid] {
+			continue
+		}
+		task, rev, err := s.taskGet(ctx, uuid)
+		if err == errors.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if task.Status != pb.TaskStatus_TASK_RUNNING {
+			continue
+		}
+		executor := task.Executor
+		// The task may have been requeued and claimed by a live executor
+		// since the claims were read above, so it is only changed if it
+		// still has no claim
+		unclaimed := []etcd.Cmp{
+			etcd.Compare(etcd.Version(taskClaimKey(task)), "=", 0),
+		}
+		if task.CancelRequested {
+			task.Status = pb.TaskStatus_TASK_CANCELLED
+			task.StatusReason = fmt.Sprintf(
+				"executor %s stopped before cancelling the task", executor,
+			)
+			err = s.taskUpdate(
+				ctx, task, rev, pb.TaskStatus_TASK_RUNNING, unclaimed,
+				etcd.OpDelete(taskTargetKey(task)),
+			)
+		} else {
+			task.Status = pb.TaskStatus_TASK_QUEUED
+			task.Executor = ""
+			task.StatusReason = fmt.Sprintf(
+				"executor %s stopped sending heartbeats", executor,
+			)
+			err = s.taskUpdate(
+				ctx, task, rev, pb.TaskStatus_TASK_RUNNING, unclaimed,
+				etcd.OpPut(taskQueueKey(task), _NO_VALUE),
+			)
+		}
+		if err == errors.ErrGenerationConflict {
+			// The executor finished the task, or another worker requeued it
+			// and it may since have been claimed again
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		s.log.L1("%s. task %s is now %s.", task.StatusReason, uuid, task.Status)
+	}
+	return nil
+}
+
+// TaskClaim marks the oldest queued task in the supplied partition as running
+// by the supplied executor and returns the task. Running tasks in the
+// partition that were abandoned by their executors are queued again first.
+// The executor's claim on the task lasts until the task lease time passes
+// without a call to TaskHeartbeat. Returns (nil, nil) if there are no queued
+// tasks in the partition.
+func (s *Store) TaskClaim(
+	ctx context.Context,
+	partition string,
+	executor string,
+) (*pb.Task, error) {
+	ctx, cancel := s.requestCtx(ctx)
+	defer cancel()
+
+	if err := s.requeueAbandoned(ctx, partition); err != nil {
+		return nil, err
+	}
+
+	// NOTE(jaypipes): The lease is granted once for all attempts. If no task
+	// is claimed, the lease has no keys attached and simply expires.
+	var leaseID etcd.LeaseID
+	queueKey := _QUEUES_KEY + partition + "/"
+	for x := 0; x < _CLAIM_ATTEMPTS; x++ {
+		resp, err := s.kv.Get(
+			ctx, queueKey,
+			etcd.WithPrefix(),
+			etcd.WithSort(etcd.SortByCreateRevision, etcd.SortAscend),
+			etcd.WithLimit(1),
+		)
+		if err != nil {
+			s.log.ERR("error getting oldest queued task: %v", err)
+			return nil, err
+		}
+		if resp.Count == 0 {
+			return nil, nil
+		}
+		entry := resp.Kvs[0]
+		uuid := string(entry.Key)[len(queueKey):]
+		task, rev, err := s.taskGet(ctx, uuid)
+		if err != nil {
+			return nil, err
+		}
+		if leaseID == 0 {
+			ttl := int64(s.cfg.TaskLeaseSeconds / time.Second)
+			lease, err := s.client.Grant(ctx, ttl)
+			if err != nil {
+				s.log.ERR("failed to grant task claim lease: %v", err)
+				return nil, err
+			}
+			leaseID = lease.ID
+		}
+
+		task.Status = pb.TaskStatus_TASK_RUNNING
+		task.StatusReason = ""
+		task.Executor = executor
+		task.UpdateTime = time.Now().Unix()
+		taskValue, err := proto.Marshal(task)
+		if err != nil {
+			s.log.ERR("failed to serialize task: %v", err)
+			return nil, err
+		}
+
+		taskKey := _TASKS_BY_UUID_KEY + uuid
+		then := []etcd.Op{
+			etcd.OpDelete(string(entry.Key)),
+			etcd.OpDelete(taskStatusKey(task, pb.TaskStatus_TASK_QUEUED)),
+			etcd.OpPut(taskStatusKey(task, task.Status), _NO_VALUE),
+			etcd.OpPut(taskKey, string(taskValue)),
+			etcd.OpPut(taskClaimKey(task), executor, etcd.WithLease(leaseID)),
+		}
+		// Ensure another worker didn't claim the task and the task wasn't
+		// cancelled underneath us
+		compare := []etcd.Cmp{
+			etcd.Compare(
+				etcd.ModRevision(string(entry.Key)), "=", entry.ModRevision,
+			),
+			etcd.Compare(etcd.ModRevision(taskKey), "=", rev),
+		}
+		txnResp, err := s.kv.Txn(ctx).If(compare...).Then(then...).Commit()
+		if err != nil {
+			s.log.ERR("failed to create txn in etcd: %v", err)
+			return nil, err
+		}
+		if txnResp.Succeeded {
+			return task, nil
+		}
+		s.log.L3("task %s was claimed concurrently. retrying.", uuid)
+	}
+	return nil, errors.ErrGenerationConflict
+}
+
+// taskUpdate saves the supplied task, which was last modified at the supplied
+// revision and had the supplied status, performing any supplied additional
+// operations in the same transaction if the supplied additional comparisons
+// also succeed. A finished task is attached to a lease
+// that removes the task once the retention period for finished tasks has
+// passed. Returns errors.ErrGenerationConflict if the task was modified
+// concurrently.
+func (s *Store) taskUpdate(
+	ctx context.Context,
+	task *pb.Task,
+	rev int64,
+	from pb.TaskStatus,
+	cmps []etcd.Cmp,
+	ops ...etcd.Op,
+) error {
+	task.UpdateTime = time.Now().Unix()
+	taskValue, err := proto.Marshal(task)
+	if err != nil {
+		s.log.ERR("failed to serialize task: %v", err)
+		return err
+	}
+	putOpts := []etcd.OpOption{}
+	if taskStatusFinished(task.Status) {
+		ttl := int64(s.cfg.TaskRetentionSeconds / time.Second)
+		lease, err := s.client.Grant(ctx, ttl)
+		if err != nil {
+			s.log.ERR("failed to grant task retention lease: %v", err)
+			return err
+		}
+		putOpts = append(putOpts, etcd.WithLease(lease.ID))
+	}
+	taskKey := _TASKS_BY_UUID_KEY + task.Uuid
+	then := []etcd.Op{etcd.OpPut(taskKey, string(taskValue), putOpts...)}
+	if task.Status != from {
+		then = append(
+			then,
+			etcd.OpDelete(taskStatusKey(task, from)),
+			etcd.OpPut(
+				taskStatusKey(task, task.Status), _NO_VALUE, putOpts...,
+			),
+		)
+	}
+	then = append(then, ops...)
+	compare := []etcd.Cmp{
+		etcd.Compare(etcd.ModRevision(taskKey), "=", rev),
+	}
+	compare = append(compare, cmps...)
+	resp, err := s.kv.Txn(ctx).If(compare...).Then(then...).Commit()
+	if err != nil {
+		s.log.ERR("failed to create txn in etcd: %v", err)
+		return err
+	} else if !resp.Succeeded {
+		return errors.ErrGenerationConflict
+	}
+	return nil
+}
+
+// TaskCancel cancels the task with the supplied UUID and returns the task. A
+// queued task is removed from its queue and is cancelled immediately. A
+// running task has its cancel_requested field set and is cancelled when its
+// executor stops it. Returns errors.ErrTaskStatusConflict if the task has
+// already finished.
+func (s *Store) TaskCancel(
+	ctx context.Context,
+	uuid string,
+	reason string,
+) (*pb.Task, error) {
+	ctx, cancel := s.requestCtx(ctx)
+	defer cancel()
+
+	task, rev, err := s.taskGet(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	from := task.Status
+	switch task.Status {
+	case pb.TaskStatus_TASK_QUEUED:
+		task.Status = pb.TaskStatus_TASK_CANCELLED
+		task.StatusReason = reason
+		err = s.taskUpdate(
+			ctx, task, rev, from, nil,
+			etcd.OpDelete(taskQueueKey(task)),
+			etcd.OpDelete(taskTargetKey(task)),
+		)
+	case pb.TaskStatus_TASK_RUNNING:
+		if task.CancelRequested {
+			return task, nil
+		}
+		task.CancelRequested = true
+		task.StatusReason = reason
+		err = s.taskUpdate(ctx, task, rev, from, nil)
+	default:
+		return nil, errors.ErrTaskStatusConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// TaskFinish records the outcome of the running task with the supplied UUID
+// and returns the task. Returns errors.ErrTaskStatusConflict if the task is
+// not running or was claimed by a different executor.
+func (s *Store) TaskFinish(
+	ctx context.Context,
+	uuid string,
+	executor string,
+	status pb.TaskStatus,
+	reason string,
+) (*pb.Task, error) {
+	ctx, cancel := s.requestCtx(ctx)
+	defer cancel()
+
+	if !taskStatusFinished(status) {
+		return nil, errors.ErrBadInput
+	}
+
+	task, rev, err := s.taskGet(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status != pb.TaskStatus_TASK_RUNNING || task.Executor != executor {
+		return nil, errors.ErrTaskStatusConflict
+	}
+	task.Status = status
+	task.StatusReason = reason
+	err = s.taskUpdate(
+		ctx, task, rev, pb.TaskStatus_TASK_RUNNING, nil,
+		etcd.OpDelete(taskTargetKey(task)),
+		etcd.OpDelete(taskClaimKey(task)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// TaskHeartbeat renews the supplied executor's claim on the running task with
+// the supplied UUID and returns the task, so that the executor can see if the
+// task's cancellation was requested. Returns errors.ErrTaskStatusConflict if
+// the task is not running, was claimed by a different executor or the
+// executor's claim has expired.
+func (s *Store) TaskHeartbeat(
+	ctx context.Context,
+	uuid string,
+	executor string,
+) (*pb.Task, error) {
+	ctx, cancel := s.requestCtx(ctx)
+	defer cancel()
+
+	task, _, err := s.taskGet(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status != pb.TaskStatus_TASK_RUNNING || task.Executor != executor {
+		return nil, errors.ErrTaskStatusConflict
+	}
+	resp, err := s.kv.Get(ctx, taskClaimKey(task))
+	if err != nil {
+		s.log.ERR("error getting claim on task %s: %v", uuid, err)
+		return nil, err
+	}
+	if resp.Count == 0 || string(resp.Kvs[0].Value) != executor {
+		return nil, errors.ErrTaskStatusConflict
+	}
+	_, err = s.client.KeepAliveOnce(ctx, etcd.LeaseID(resp.Kvs[0].Lease))
+	if err == rpctypes.ErrLeaseNotFound {
+		return nil, errors.ErrTaskStatusConflict
+	}
+	if err != nil {
+		s.log.ERR("failed to renew claim on task %s: %v", uuid, err)
+		return nil, err
+	}
+	return task, nil
+}
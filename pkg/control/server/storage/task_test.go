@@ -0,0 +1,266 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/runmachine-io/runmachine/pkg/control/server/config"
+	"github.com/runmachine-io/runmachine/pkg/errors"
+	"github.com/runmachine-io/runmachine/pkg/logging"
+	"github.com/runmachine-io/runmachine/pkg/testutil"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// newEtcdStore starts an embedded etcd server and returns a Store connected to
+// it along with a function that closes the Store and stops the server
+func newEtcdStore(t *testing.T) (*Store, func()) {
+	endpoint, stop := testutil.StartEtcd(t)
+	cfg := &config.Config{
+		EtcdEndpoints:             []string{endpoint},
+		EtcdKeyPrefix:             "runm-control/",
+		EtcdConnectTimeoutSeconds: 10 * time.Second,
+		EtcdRequestTimeoutSeconds: 5 * time.Second,
+		EtcdDialTimeoutSeconds:    time.Second,
+		TaskRetentionSeconds:      time.Hour,
+		TaskLeaseSeconds:          time.Minute,
+	}
+	s, err := New(logging.New(&logging.Config{}), cfg)
+	if err != nil {
+		stop()
+		t.Fatalf("failed to connect to etcd: %v", err)
+	}
+	return s, func() {
+		s.Close()
+		stop()
+	}
+}
+
+const (
+	partA   = "a4ad9ba1b7f6466c8ea0e5bd7b2d0e6a"
+	partB   = "b09c62c8ec234be1ab9bd4c8b2ab1a4c"
+	targetA = "4f8d6e4bb4d3446a8e9d0c0fb8f84b3a"
+	targetB = "9e0a6c7f1d9b4f4fa0c9b3d5e7f1a2b4"
+)
+
+func TestTaskQueue(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.TODO()
+
+	s, cleanup := newEtcdStore(t)
+	defer cleanup()
+
+	// Nothing to claim in an empty queue
+	task, err := s.TaskClaim(ctx, partA, "exec0")
+	require.Nil(err)
+	assert.Nil(task)
+
+	first, err := s.TaskSubmit(ctx, &pb.Task{
+		Partition: partA,
+		Project:   "proj0",
+		Action:    "machine.start",
+		Target:    targetA,
+	})
+	require.Nil(err)
+	assert.NotEmpty(first.Uuid)
+	assert.Equal(pb.TaskStatus_TASK_QUEUED, first.Status)
+	assert.NotZero(first.CreateTime)
+
+	// Only one task may be active for a target at a time
+	_, err = s.TaskSubmit(ctx, &pb.Task{
+		Partition: partA,
+		Project:   "proj0",
+		Action:    "machine.stop",
+		Target:    targetA,
+	})
+	assert.Equal(errors.ErrTaskTargetBusy, err)
+
+	second, err := s.TaskSubmit(ctx, &pb.Task{
+		Partition: partA,
+		Project:   "proj1",
+		Action:    "machine.build",
+		Target:    targetB,
+	})
+	require.Nil(err)
+
+	// Executors only claim tasks in their own partition
+	task, err = s.TaskClaim(ctx, partB, "exec1")
+	require.Nil(err)
+	assert.Nil(task)
+
+	// Tasks are claimed in the order they were submitted
+	task, err = s.TaskClaim(ctx, partA, "exec0")
+	require.Nil(err)
+	require.NotNil(task)
+	assert.Equal(first.Uuid, task.Uuid)
+	assert.Equal(pb.TaskStatus_TASK_RUNNING, task.Status)
+	assert.Equal("exec0", task.Executor)
+
+	tasks, err := s.TasksGetMatching(ctx, &TaskFilter{
+		Partition: partA,
+		Statuses:  []pb.TaskStatus{pb.TaskStatus_TASK_QUEUED},
+	})
+	require.Nil(err)
+	require.Len(tasks, 1)
+	assert.Equal(second.Uuid, tasks[0].Uuid)
+
+	tasks, err = s.TasksGetMatching(ctx, &TaskFilter{
+		Partition: partA,
+		Project:   "proj0",
+	})
+	require.Nil(err)
+	require.Len(tasks, 1)
+	assert.Equal(first.Uuid, tasks[0].Uuid)
+
+	// An executor keeps its claim on a task by sending heartbeats
+	task, err = s.TaskHeartbeat(ctx, first.Uuid, "exec0")
+	require.Nil(err)
+	assert.Equal(first.Uuid, task.Uuid)
+	_, err = s.TaskHeartbeat(ctx, first.Uuid, "exec1")
+	assert.Equal(errors.ErrTaskStatusConflict, err)
+
+	// Cancelling a queued task removes it from the queue and frees its target
+	task, err = s.TaskCancel(ctx, second.Uuid, "changed my mind")
+	require.Nil(err)
+	assert.Equal(pb.TaskStatus_TASK_CANCELLED, task.Status)
+	assert.Equal("changed my mind", task.StatusReason)
+
+	task, err = s.TaskClaim(ctx, partA, "exec0")
+	require.Nil(err)
+	assert.Nil(task)
+
+	_, err = s.TaskCancel(ctx, second.Uuid, "")
+	assert.Equal(errors.ErrTaskStatusConflict, err)
+
+	// Cancelling a running task only requests its cancellation
+	task, err = s.TaskCancel(ctx, first.Uuid, "")
+	require.Nil(err)
+	assert.Equal(pb.TaskStatus_TASK_RUNNING, task.Status)
+	assert.True(task.CancelRequested)
+
+	// Only the executor that claimed a task may finish it
+	_, err = s.TaskFinish(
+		ctx, first.Uuid, "exec1", pb.TaskStatus_TASK_SUCCEEDED, "",
+	)
+	assert.Equal(errors.ErrTaskStatusConflict, err)
+
+	_, err = s.TaskFinish(
+		ctx, first.Uuid, "exec0", pb.TaskStatus_TASK_RUNNING, "",
+	)
+	assert.Equal(errors.ErrBadInput, err)
+
+	task, err = s.TaskFinish(
+		ctx, first.Uuid, "exec0", pb.TaskStatus_TASK_CANCELLED, "stopped",
+	)
+	require.Nil(err)
+	assert.Equal(pb.TaskStatus_TASK_CANCELLED, task.Status)
+
+	task, err = s.TaskGet(ctx, first.Uuid)
+	require.Nil(err)
+	assert.Equal(pb.TaskStatus_TASK_CANCELLED, task.Status)
+	assert.Equal("stopped", task.StatusReason)
+
+	tasks, err = s.TasksGetMatching(ctx, &TaskFilter{
+		Partition: partA,
+		Statuses:  []pb.TaskStatus{pb.TaskStatus_TASK_CANCELLED},
+	})
+	require.Nil(err)
+	assert.Len(tasks, 2)
+	tasks, err = s.TasksGetMatching(ctx, &TaskFilter{
+		Partition: partA,
+		Statuses:  []pb.TaskStatus{pb.TaskStatus_TASK_RUNNING},
+	})
+	require.Nil(err)
+	assert.Empty(tasks)
+
+	// Finished tasks are removed when their retention lease expires
+	resp, err := s.kv.Get(ctx, _TASKS_BY_UUID_KEY+first.Uuid)
+	require.Nil(err)
+	require.Len(resp.Kvs, 1)
+	assert.NotZero(resp.Kvs[0].Lease)
+
+	// A finished task frees its target for new tasks
+	_, err = s.TaskSubmit(ctx, &pb.Task{
+		Partition: partA,
+		Project:   "proj0",
+		Action:    "machine.stop",
+		Target:    targetA,
+	})
+	assert.Nil(err)
+
+	_, err = s.TaskGet(ctx, "does-not-exist")
+	assert.Equal(errors.ErrNotFound, err)
+}
+
+// expireClaim revokes the lease of the claim on the supplied task, as happens
+// when the executor running the task stops sending heartbeats
+func expireClaim(t *testing.T, s *Store, task *pb.Task) {
+	ctx := context.TODO()
+	resp, err := s.kv.Get(ctx, taskClaimKey(task))
+	require.Nil(t, err)
+	require.Len(t, resp.Kvs, 1)
+	_, err = s.client.Revoke(ctx, etcd.LeaseID(resp.Kvs[0].Lease))
+	require.Nil(t, err)
+}
+
+func TestTaskAbandoned(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.TODO()
+
+	s, cleanup := newEtcdStore(t)
+	defer cleanup()
+
+	submitted, err := s.TaskSubmit(ctx, &pb.Task{
+		Partition: partA,
+		Project:   "proj0",
+		Action:    "machine.start",
+		Target:    targetA,
+	})
+	require.Nil(err)
+	task, err := s.TaskClaim(ctx, partA, "exec0")
+	require.Nil(err)
+	require.NotNil(task)
+
+	// A task abandoned by its executor is queued again and can be claimed by
+	// another executor
+	expireClaim(t, s, task)
+	task, err = s.TaskClaim(ctx, partA, "exec1")
+	require.Nil(err)
+	require.NotNil(task)
+	assert.Equal(submitted.Uuid, task.Uuid)
+	assert.Equal("exec1", task.Executor)
+
+	// The executor that abandoned the task no longer holds a claim on it
+	_, err = s.TaskHeartbeat(ctx, task.Uuid, "exec0")
+	assert.Equal(errors.ErrTaskStatusConflict, err)
+	_, err = s.TaskFinish(
+		ctx, task.Uuid, "exec0", pb.TaskStatus_TASK_SUCCEEDED, "",
+	)
+	assert.Equal(errors.ErrTaskStatusConflict, err)
+
+	// An abandoned task whose cancellation was requested is cancelled
+	_, err = s.TaskCancel(ctx, task.Uuid, "")
+	require.Nil(err)
+	expireClaim(t, s, task)
+	task, err = s.TaskClaim(ctx, partA, "exec1")
+	require.Nil(err)
+	assert.Nil(task)
+
+	task, err = s.TaskGet(ctx, submitted.Uuid)
+	require.Nil(err)
+	assert.Equal(pb.TaskStatus_TASK_CANCELLED, task.Status)
+
+	_, err = s.TaskSubmit(ctx, &pb.Task{
+		Partition: partA,
+		Project:   "proj0",
+		Action:    "machine.start",
+		Target:    targetA,
+	})
+	assert.Nil(err)
+}
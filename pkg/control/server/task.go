@@ -0,0 +1,267 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/runmachine-io/runmachine/pkg/control/server/storage"
+	"github.com/runmachine-io/runmachine/pkg/errors"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// taskGet returns the task with the supplied UUID if it is in the session's
+// partition. If checkProject is true, the task must also have been submitted
+// by the session's project. If no such task could be found, returns (nil,
+// ErrNotFound)
+func (s *Server) taskGet(
+	ctx context.Context,
+	sess *pb.Session,
+	uuid string,
+	checkProject bool,
+) (*pb.Task, error) {
+	task, err := s.store.TaskGet(ctx, uuid)
+	if err != nil {
+		if err == errors.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		s.log.ForContext(ctx).ERR(
+			"failed to get task with UUID %s from storage: %s", uuid, err,
+		)
+		return nil, ErrUnknown
+	}
+	if task.Partition != sess.Partition {
+		return nil, ErrNotFound
+	}
+	// TODO(jaypipes): AUTHZ check if user can see tasks submitted by other
+	// projects (i.e. an admin)
+	if checkProject && task.Project != sess.Project {
+		return nil, ErrNotFound
+	}
+	return task, nil
+}
+
+// TaskSubmit validates that the requested action may be taken against the
+// requested target and queues a task to take the action
+func (s *Server) TaskSubmit(
+	ctx context.Context,
+	req *pb.TaskSubmitRequest,
+) (*pb.TaskSubmitResponse, error) {
+	if err := s.checkSession(ctx, req.Session); err != nil {
+		return nil, err
+	}
+	if req.Action == "" {
+		return nil, ErrActionRequired
+	}
+	if req.Target == "" {
+		return nil, ErrTargetRequired
+	}
+	sess := req.Session
+	target, err := s.validateAction(ctx, sess, req.Action, req.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := s.store.TaskSubmit(ctx, &pb.Task{
+		Partition: sess.Partition,
+		Project:   sess.Project,
+		User:      sess.User,
+		Action:    req.Action,
+		Target:    target,
+	})
+	if err != nil {
+		if err == errors.ErrTaskTargetBusy {
+			return nil, errTargetBusy(req.Target)
+		}
+		s.log.ForContext(ctx).ERR("failed to queue task: %s", err)
+		return nil, ErrUnknown
+	}
+	s.log.ForContext(ctx).L2(
+		"queued task %s to %s %s", task.Uuid, task.Action, task.Target,
+	)
+	return &pb.TaskSubmitResponse{
+		Task: task,
+	}, nil
+}
+
+// TaskGet returns the task with the supplied UUID
+func (s *Server) TaskGet(
+	ctx context.Context,
+	req *pb.TaskGetRequest,
+) (*pb.Task, error) {
+	if err := s.checkSession(ctx, req.Session); err != nil {
+		return nil, err
+	}
+	if req.Uuid == "" {
+		return nil, ErrUuidRequired
+	}
+	return s.taskGet(ctx, req.Session, req.Uuid, true)
+}
+
+// TaskList streams the tasks submitted by the session's project that match
+// the supplied statuses and target
+func (s *Server) TaskList(
+	req *pb.TaskListRequest,
+	stream pb.RunmControl_TaskListServer,
+) error {
+	ctx := stream.Context()
+	if err := s.checkSession(ctx, req.Session); err != nil {
+		return err
+	}
+	filter := &storage.TaskFilter{
+		Partition: req.Session.Partition,
+		Project:   req.Session.Project,
+		Target:    req.Target,
+		Statuses:  req.Statuses,
+	}
+	tasks, err := s.store.TasksGetMatching(ctx, filter)
+	if err != nil {
+		s.log.ForContext(ctx).ERR("failed to list tasks: %s", err)
+		return ErrUnknown
+	}
+	for _, task := range tasks {
+		if err = stream.Send(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TaskCancel cancels a queued task. A running task is cancelled by its
+// executor once the executor sees that cancellation was requested.
+func (s *Server) TaskCancel(
+	ctx context.Context,
+	req *pb.TaskGetRequest,
+) (*pb.TaskCancelResponse, error) {
+	if err := s.checkSession(ctx, req.Session); err != nil {
+		return nil, err
+	}
+	if req.Uuid == "" {
+		return nil, ErrUuidRequired
+	}
+	sess := req.Session
+	if _, err := s.taskGet(ctx, sess, req.Uuid, true); err != nil {
+		return nil, err
+	}
+	reason := fmt.Sprintf("cancelled by %s", sess.User)
+	task, err := s.store.TaskCancel(ctx, req.Uuid, reason)
+	if err != nil {
+		switch err {
+		case errors.ErrTaskStatusConflict:
+			return nil, ErrTaskFinished
+		case errors.ErrGenerationConflict:
+			return nil, ErrTaskConflict
+		}
+		s.log.ForContext(ctx).ERR(
+			"failed to cancel task %s: %s", req.Uuid, err,
+		)
+		return nil, ErrUnknown
+	}
+	s.log.ForContext(ctx).L2("%s task %s", reason, task.Uuid)
+	return &pb.TaskCancelResponse{
+		Task: task,
+	}, nil
+}
+
+// TaskClaim marks the oldest queued task in the session's partition as
+// running by the requesting executor and returns it
+func (s *Server) TaskClaim(
+	ctx context.Context,
+	req *pb.TaskClaimRequest,
+) (*pb.TaskClaimResponse, error) {
+	if err := s.checkSession(ctx, req.Session); err != nil {
+		return nil, err
+	}
+	if req.Executor == "" {
+		return nil, ErrExecutorRequired
+	}
+	task, err := s.store.TaskClaim(ctx, req.Session.Partition, req.Executor)
+	if err != nil {
+		if err == errors.ErrGenerationConflict {
+			return nil, ErrTaskConflict
+		}
+		s.log.ForContext(ctx).ERR("failed to claim task: %s", err)
+		return nil, ErrUnknown
+	}
+	if task != nil {
+		s.log.ForContext(ctx).L2(
+			"executor %s claimed task %s", req.Executor, task.Uuid,
+		)
+	}
+	return &pb.TaskClaimResponse{
+		Task: task,
+	}, nil
+}
+
+// TaskHeartbeat renews the requesting executor's claim on a running task and
+// returns the task
+func (s *Server) TaskHeartbeat(
+	ctx context.Context,
+	req *pb.TaskHeartbeatRequest,
+) (*pb.Task, error) {
+	if err := s.checkSession(ctx, req.Session); err != nil {
+		return nil, err
+	}
+	if req.Uuid == "" {
+		return nil, ErrUuidRequired
+	}
+	if req.Executor == "" {
+		return nil, ErrExecutorRequired
+	}
+	if _, err := s.taskGet(ctx, req.Session, req.Uuid, false); err != nil {
+		return nil, err
+	}
+	task, err := s.store.TaskHeartbeat(ctx, req.Uuid, req.Executor)
+	if err != nil {
+		if err == errors.ErrTaskStatusConflict {
+			return nil, ErrTaskNotClaimed
+		}
+		s.log.ForContext(ctx).ERR(
+			"failed to renew claim on task %s: %s", req.Uuid, err,
+		)
+		return nil, ErrUnknown
+	}
+	return task, nil
+}
+
+// TaskFinish records the outcome of a task reported by the executor that
+// claimed it
+func (s *Server) TaskFinish(
+	ctx context.Context,
+	req *pb.TaskFinishRequest,
+) (*pb.Task, error) {
+	if err := s.checkSession(ctx, req.Session); err != nil {
+		return nil, err
+	}
+	if req.Uuid == "" {
+		return nil, ErrUuidRequired
+	}
+	if req.Executor == "" {
+		return nil, ErrExecutorRequired
+	}
+	// Executors finish tasks submitted by any project in their partition
+	if _, err := s.taskGet(ctx, req.Session, req.Uuid, false); err != nil {
+		return nil, err
+	}
+	task, err := s.store.TaskFinish(
+		ctx, req.Uuid, req.Executor, req.Status, req.Reason,
+	)
+	if err != nil {
+		switch err {
+		case errors.ErrBadInput:
+			return nil, ErrTaskFinishStatusInvalid
+		case errors.ErrTaskStatusConflict:
+			return nil, ErrTaskNotClaimed
+		case errors.ErrGenerationConflict:
+			return nil, ErrTaskConflict
+		}
+		s.log.ForContext(ctx).ERR(
+			"failed to finish task %s: %s", req.Uuid, err,
+		)
+		return nil, ErrUnknown
+	}
+	s.log.ForContext(ctx).L2(
+		"executor %s finished task %s with status %s",
+		req.Executor, task.Uuid, task.Status,
+	)
+	return task, nil
+}
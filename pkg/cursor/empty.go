@@ -24,3 +24,7 @@ func (c *EmptyCursor) Next() bool {
 func (c *EmptyCursor) Close() error {
 	return nil
 }
+
+func (c *EmptyCursor) Err() error {
+	return nil
+}
@@ -43,3 +43,7 @@ func (c *EtcdGetResponseCursor) Next() bool {
 func (c *EtcdGetResponseCursor) Close() error {
 	return nil
 }
+
+func (c *EtcdGetResponseCursor) Err() error {
+	return nil
+}
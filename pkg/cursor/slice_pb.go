@@ -41,3 +41,7 @@ func (c *SlicePBMessageCursor) Next() bool {
 func (c *SlicePBMessageCursor) Close() error {
 	return nil
 }
+
+func (c *SlicePBMessageCursor) Err() error {
+	return nil
+}
@@ -0,0 +1,67 @@
+package cursor
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// implements abstract Cursor interface for the rows of a SQL query result.
+// Each row is read into a protobuffer message by the supplied scan function,
+// so rows are only read from the database as the cursor is iterated.
+// NOTE(jaypipes): It is NOT safe to share these objects between threads.
+type SQLRowsCursor struct {
+	rows *sql.Rows
+	scan func(rows *sql.Rows, msg proto.Message) error
+	// Whether rows.Next() has been called for the record to Scan next
+	advanced bool
+	// The result of the last call to rows.Next()
+	hasRow bool
+	err    error
+}
+
+func NewFromSQLRows(
+	rows *sql.Rows,
+	scan func(rows *sql.Rows, msg proto.Message) error,
+) *SQLRowsCursor {
+	return &SQLRowsCursor{
+		rows: rows,
+		scan: scan,
+	}
+}
+
+func (c *SQLRowsCursor) Scan(msg proto.Message) error {
+	if !c.Next() {
+		return fmt.Errorf("attempted to read past end of SQL rows cursor.")
+	}
+	c.advanced = false
+	if err := c.scan(c.rows, msg); err != nil {
+		c.err = err
+		c.hasRow = false
+		return err
+	}
+	return nil
+}
+
+func (c *SQLRowsCursor) Next() bool {
+	if c.err != nil {
+		return false
+	}
+	if !c.advanced {
+		c.hasRow = c.rows.Next()
+		c.advanced = true
+	}
+	return c.hasRow
+}
+
+func (c *SQLRowsCursor) Close() error {
+	return c.rows.Close()
+}
+
+func (c *SQLRowsCursor) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.rows.Err()
+}
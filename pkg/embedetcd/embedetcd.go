@@ -0,0 +1,45 @@
+// Package embedetcd runs a single-member etcd cluster inside the calling
+// process. It is used by runm-allinone and by the tests of the packages that
+// store data in etcd.
+package embedetcd
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/coreos/etcd/embed"
+)
+
+// Start starts a single-member etcd cluster with the supplied member name,
+// keeping its data in the supplied directory and listening for clients and
+// peers at the supplied URLs. It waits up to the supplied timeout for the
+// server to be ready to serve clients.
+func Start(
+	name string,
+	dir string,
+	clientURL url.URL,
+	peerURL url.URL,
+	timeout time.Duration,
+) (*embed.Etcd, error) {
+	ecfg := embed.NewConfig()
+	ecfg.Name = name
+	ecfg.Dir = dir
+	ecfg.LCUrls = []url.URL{clientURL}
+	ecfg.ACUrls = []url.URL{clientURL}
+	ecfg.LPUrls = []url.URL{peerURL}
+	ecfg.APUrls = []url.URL{peerURL}
+	ecfg.InitialCluster = ecfg.InitialClusterFromName(ecfg.Name)
+
+	e, err := embed.StartEtcd(ecfg)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(timeout):
+		e.Close()
+		return nil, fmt.Errorf("etcd server not ready after %s", timeout)
+	}
+	return e, nil
+}
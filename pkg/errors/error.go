@@ -45,11 +45,41 @@ var (
 		Code:     409003,
 		Message:  "encountered generation conflict.",
 	}
+	ErrCapacityExceeded = &Error{
+		HTTPCode: 409,
+		Code:     409004,
+		Message:  "not enough capacity to satisfy claim.",
+	}
+	ErrTaskTargetBusy = &Error{
+		HTTPCode: 409,
+		Code:     409005,
+		Message:  "another task is already queued or running for the target.",
+	}
+	ErrTaskStatusConflict = &Error{
+		HTTPCode: 409,
+		Code:     409006,
+		Message:  "task status does not allow the requested change.",
+	}
 	ErrUnknown = &Error{
 		HTTPCode: 500,
 		Code:     500,
 		Message:  "unknown error.",
 	}
+	ErrForbidden = &Error{
+		HTTPCode: 403,
+		Code:     403,
+		Message:  "not authorized to perform the requested action.",
+	}
+	ErrMethodNotAllowed = &Error{
+		HTTPCode: 405,
+		Code:     405,
+		Message:  "method not allowed.",
+	}
+	ErrUnavailable = &Error{
+		HTTPCode: 503,
+		Code:     503,
+		Message:  "service unavailable.",
+	}
 )
 
 func ErrObjectTypeNotFound(objType string) *Error {
@@ -0,0 +1,12 @@
+package server
+
+import (
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// apiClient returns an API service client. RPCs made with the client are
+// balanced across the API service endpoints registered in the gsr service
+// registry.
+func (s *Server) apiClient() (pb.RunmAPIClient, error) {
+	return s.apiclient, nil
+}
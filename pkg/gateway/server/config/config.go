@@ -0,0 +1,88 @@
+package config
+
+import (
+	"path/filepath"
+
+	flag "github.com/ogier/pflag"
+
+	"github.com/jaypipes/envutil"
+	"github.com/runmachine-io/runmachine/pkg/util"
+)
+
+const (
+	cfgPath               = "/etc/runmachine/gateway"
+	defaultUseTLS         = false
+	defaultBindPort       = 10080
+	defaultAPIServiceName = "runmachine-api"
+)
+
+var (
+	defaultCertPath = filepath.Join(cfgPath, "server.pem")
+	defaultKeyPath  = filepath.Join(cfgPath, "server.key")
+	defaultBindHost = util.BindHost()
+)
+
+type Config struct {
+	UseTLS         bool
+	CertPath       string
+	KeyPath        string
+	BindHost       string
+	BindPort       int
+	APIServiceName string
+}
+
+func ConfigFromOpts() *Config {
+	optUseTLS := flag.Bool(
+		"use-tls",
+		envutil.WithDefaultBool(
+			"RUNM_GATEWAY_USE_TLS", defaultUseTLS,
+		),
+		"Listen for HTTPS if true, else plain HTTP",
+	)
+	optCertPath := flag.String(
+		"cert-path",
+		envutil.WithDefault(
+			"RUNM_GATEWAY_CERT_PATH", defaultCertPath,
+		),
+		"Path to the TLS cert file",
+	)
+	optKeyPath := flag.String(
+		"key-path",
+		envutil.WithDefault(
+			"RUNM_GATEWAY_KEY_PATH", defaultKeyPath,
+		),
+		"Path to the TLS key file",
+	)
+	optHost := flag.String(
+		"bind-address",
+		envutil.WithDefault(
+			"RUNM_GATEWAY_BIND_HOST", defaultBindHost,
+		),
+		"The host address the server will listen on",
+	)
+	optPort := flag.Int(
+		"bind-port",
+		envutil.WithDefaultInt(
+			"RUNM_GATEWAY_BIND_PORT", defaultBindPort,
+		),
+		"The port the server will listen on",
+	)
+	optAPIServiceName := flag.String(
+		"api-service-name",
+		envutil.WithDefault(
+			"RUNM_API_SERVICE_NAME", defaultAPIServiceName,
+		),
+		"Name to use when querying the service registry for the API service",
+	)
+
+	flag.Parse()
+
+	return &Config{
+		UseTLS:         *optUseTLS,
+		CertPath:       *optCertPath,
+		KeyPath:        *optKeyPath,
+		BindHost:       *optHost,
+		BindPort:       *optPort,
+		APIServiceName: *optAPIServiceName,
+	}
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/runmachine-io/runmachine/pkg/errors"
+	"github.com/runmachine-io/runmachine/pkg/health"
+)
+
+const (
+	healthTimeout = 5 * time.Second
+)
+
+// healthGet handles GET /health. Returns 200 if the API service reports that
+// it is serving, otherwise 503.
+func (s *Server) healthGet(
+	w http.ResponseWriter,
+	r *http.Request,
+	vars map[string]string,
+) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthTimeout)
+	defer cancel()
+	if err := health.CheckConn(ctx, s.apisc.Conn()); err != nil {
+		s.log.ERR("API service unhealthy: %s", err)
+		writeError(w, errors.ErrUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status":"SERVING"}`))
+}
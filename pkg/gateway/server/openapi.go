@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/runmachine-io/runmachine/pkg/errors"
+)
+
+const (
+	openAPIVersion = "3.0.0"
+	openAPITitle   = "runmachine REST gateway"
+	// TODO(jaypipes): Version the gateway API separately from the OpenAPI
+	// document format
+	gatewayAPIVersion = "1.0"
+)
+
+// sessionHeaderParams returns the OpenAPI parameter objects describing the
+// HTTP headers that are translated into the RunmAPI session
+func sessionHeaderParams() []map[string]interface{} {
+	descs := [][]string{
+		{headerUser, "The user taking the action"},
+		{headerProject, "The project the user is acting within"},
+		{headerPartition, "The partition the user is targeting"},
+	}
+	res := make([]map[string]interface{}, len(descs))
+	for x, desc := range descs {
+		res[x] = map[string]interface{}{
+			"name":        desc[0],
+			"in":          "header",
+			"description": desc[1],
+			"schema":      map[string]string{"type": "string"},
+		}
+	}
+	return res
+}
+
+// openAPIDocument returns a map representing the OpenAPI document describing
+// the supplied routes
+func openAPIDocument(routes []*route) map[string]interface{} {
+	paths := make(map[string]interface{}, 0)
+	for _, rt := range routes {
+		params := sessionHeaderParams()
+		for _, v := range rt.pathVars() {
+			params = append(params, map[string]interface{}{
+				"name":     v,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]string{"type": "string"},
+			})
+		}
+		for _, p := range rt.params {
+			params = append(params, map[string]interface{}{
+				"name":        p.name,
+				"in":          "query",
+				"description": p.description,
+				"schema":      map[string]string{"type": "string"},
+			})
+		}
+		op := map[string]interface{}{
+			"summary":    rt.summary,
+			"parameters": params,
+			"responses": map[string]interface{}{
+				strconv.Itoa(rt.successCode): map[string]interface{}{
+					"description": http.StatusText(rt.successCode),
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{},
+					},
+				},
+				"default": map[string]interface{}{
+					"description": "Error",
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{
+							"schema": map[string]string{
+								"$ref": "#/components/schemas/Error",
+							},
+						},
+					},
+				},
+			},
+		}
+		if rt.body != "" {
+			op["requestBody"] = map[string]interface{}{
+				"description": rt.body,
+				"required":    true,
+				"content": map[string]interface{}{
+					"application/json":   map[string]interface{}{},
+					"application/x-yaml": map[string]interface{}{},
+				},
+			}
+		}
+		item, exists := paths[rt.pattern]
+		if !exists {
+			item = make(map[string]interface{}, 0)
+			paths[rt.pattern] = item
+		}
+		item.(map[string]interface{})[strings.ToLower(rt.method)] = op
+	}
+	return map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info": map[string]string{
+			"title":   openAPITitle,
+			"version": gatewayAPIVersion,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"code":    map[string]string{"type": "integer"},
+						"message": map[string]string{"type": "string"},
+					},
+				},
+			},
+		},
+	}
+}
+
+// openAPIGet handles GET /openapi.json
+func (s *Server) openAPIGet(
+	w http.ResponseWriter,
+	r *http.Request,
+	vars map[string]string,
+) {
+	body, err := json.MarshalIndent(openAPIDocument(s.routes), "", "  ")
+	if err != nil {
+		s.log.ERR("failed to marshal OpenAPI document: %s", err)
+		writeError(w, errors.ErrUnknown)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
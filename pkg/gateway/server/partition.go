@@ -0,0 +1,103 @@
+package server
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/runmachine-io/runmachine/pkg/errors"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// partitionList handles GET /partitions
+func (s *Server) partitionList(
+	w http.ResponseWriter,
+	r *http.Request,
+	vars map[string]string,
+) {
+	ac, err := s.apiClient()
+	if err != nil {
+		writeError(w, errors.ErrUnavailable)
+		return
+	}
+	req := &pb.PartitionListRequest{
+		Session: sessionFromRequest(r),
+	}
+	stream, err := ac.PartitionList(r.Context(), req)
+	if err != nil {
+		s.writeGRPCError(w, err)
+		return
+	}
+	msgs := make([]proto.Message, 0)
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			s.writeGRPCError(w, err)
+			return
+		}
+		msgs = append(msgs, msg)
+	}
+	s.writeMessages(w, msgs)
+}
+
+// partitionGet handles GET /partitions/{id}
+func (s *Server) partitionGet(
+	w http.ResponseWriter,
+	r *http.Request,
+	vars map[string]string,
+) {
+	ac, err := s.apiClient()
+	if err != nil {
+		writeError(w, errors.ErrUnavailable)
+		return
+	}
+	req := &pb.PartitionGetRequest{
+		Session: sessionFromRequest(r),
+		Filter: &pb.PartitionFilter{
+			PrimaryFilter: &pb.SearchFilter{
+				Search: vars["id"],
+			},
+		},
+	}
+	part, err := ac.PartitionGet(r.Context(), req)
+	if err != nil {
+		s.writeGRPCError(w, err)
+		return
+	}
+	s.writeMessage(w, http.StatusOK, part)
+}
+
+// partitionCreate handles POST /partitions
+func (s *Server) partitionCreate(
+	w http.ResponseWriter,
+	r *http.Request,
+	vars map[string]string,
+) {
+	payload, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, errors.ErrBadInput)
+		return
+	}
+	ac, err := s.apiClient()
+	if err != nil {
+		writeError(w, errors.ErrUnavailable)
+		return
+	}
+	// JSON is a subset of YAML, so the RunmAPI service can unmarshal either
+	req := &pb.CreateRequest{
+		Session: sessionFromRequest(r),
+		Format:  pb.PayloadFormat_YAML,
+		Payload: payload,
+	}
+	resp, err := ac.PartitionCreate(r.Context(), req)
+	if err != nil {
+		s.writeGRPCError(w, err)
+		return
+	}
+	s.writeMessage(w, http.StatusCreated, resp.Partition)
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/runmachine-io/runmachine/pkg/errors"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// searchFilterFromParam returns a SearchFilter for the supplied query string
+// parameter value, or nil if the value is empty. A trailing '*' in the value
+// indicates a prefix search.
+func searchFilterFromParam(val string) *pb.SearchFilter {
+	if val == "" {
+		return nil
+	}
+	if strings.HasSuffix(val, "*") {
+		return &pb.SearchFilter{
+			Search:    strings.TrimSuffix(val, "*"),
+			UsePrefix: true,
+		}
+	}
+	return &pb.SearchFilter{Search: val}
+}
+
+// providerList handles GET /providers
+func (s *Server) providerList(
+	w http.ResponseWriter,
+	r *http.Request,
+	vars map[string]string,
+) {
+	ac, err := s.apiClient()
+	if err != nil {
+		writeError(w, errors.ErrUnavailable)
+		return
+	}
+	q := r.URL.Query()
+	filter := &pb.ProviderFilter{
+		PrimaryFilter:      searchFilterFromParam(q.Get("name")),
+		PartitionFilter:    searchFilterFromParam(q.Get("partition")),
+		ProviderTypeFilter: searchFilterFromParam(q.Get("type")),
+	}
+	req := &pb.ProviderListRequest{
+		Session:  sessionFromRequest(r),
+		Selector: q.Get("selector"),
+	}
+	if filter.PrimaryFilter != nil ||
+		filter.PartitionFilter != nil ||
+		filter.ProviderTypeFilter != nil {
+		req.Any = []*pb.ProviderFilter{filter}
+	}
+	stream, err := ac.ProviderList(r.Context(), req)
+	if err != nil {
+		s.writeGRPCError(w, err)
+		return
+	}
+	msgs := make([]proto.Message, 0)
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			s.writeGRPCError(w, err)
+			return
+		}
+		msgs = append(msgs, msg)
+	}
+	s.writeMessages(w, msgs)
+}
+
+// providerGet handles GET /providers/{id}
+func (s *Server) providerGet(
+	w http.ResponseWriter,
+	r *http.Request,
+	vars map[string]string,
+) {
+	ac, err := s.apiClient()
+	if err != nil {
+		writeError(w, errors.ErrUnavailable)
+		return
+	}
+	req := &pb.ProviderGetRequest{
+		Session: sessionFromRequest(r),
+		Filter: &pb.ProviderFilter{
+			PrimaryFilter: &pb.SearchFilter{
+				Search: vars["id"],
+			},
+			PartitionFilter: searchFilterFromParam(
+				r.URL.Query().Get("partition"),
+			),
+		},
+	}
+	p, err := ac.ProviderGet(r.Context(), req)
+	if err != nil {
+		s.writeGRPCError(w, err)
+		return
+	}
+	s.writeMessage(w, http.StatusOK, p)
+}
+
+// providerCreate handles POST /providers
+func (s *Server) providerCreate(
+	w http.ResponseWriter,
+	r *http.Request,
+	vars map[string]string,
+) {
+	payload, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, errors.ErrBadInput)
+		return
+	}
+	ac, err := s.apiClient()
+	if err != nil {
+		writeError(w, errors.ErrUnavailable)
+		return
+	}
+	// JSON is a subset of YAML, so the RunmAPI service can unmarshal either
+	req := &pb.CreateRequest{
+		Session: sessionFromRequest(r),
+		Format:  pb.PayloadFormat_YAML,
+		Payload: payload,
+	}
+	resp, err := ac.ProviderCreate(r.Context(), req)
+	if err != nil {
+		s.writeGRPCError(w, err)
+		return
+	}
+	s.writeMessage(w, http.StatusCreated, resp.Provider)
+}
+
+// providerDelete handles DELETE /providers/{id}
+func (s *Server) providerDelete(
+	w http.ResponseWriter,
+	r *http.Request,
+	vars map[string]string,
+) {
+	ac, err := s.apiClient()
+	if err != nil {
+		writeError(w, errors.ErrUnavailable)
+		return
+	}
+	req := &pb.ProviderDeleteRequest{
+		Session: sessionFromRequest(r),
+		Any: []*pb.ProviderFilter{
+			&pb.ProviderFilter{
+				PrimaryFilter: &pb.SearchFilter{
+					Search: vars["id"],
+				},
+			},
+		},
+	}
+	resp, err := ac.ProviderDelete(r.Context(), req)
+	if err != nil {
+		s.writeGRPCError(w, err)
+		return
+	}
+	s.writeMessage(w, http.StatusOK, resp)
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/runmachine-io/runmachine/pkg/errors"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// providerTypeList handles GET /provider-types
+func (s *Server) providerTypeList(
+	w http.ResponseWriter,
+	r *http.Request,
+	vars map[string]string,
+) {
+	ac, err := s.apiClient()
+	if err != nil {
+		writeError(w, errors.ErrUnavailable)
+		return
+	}
+	req := &pb.ProviderTypeListRequest{
+		Session: sessionFromRequest(r),
+	}
+	stream, err := ac.ProviderTypeList(r.Context(), req)
+	if err != nil {
+		s.writeGRPCError(w, err)
+		return
+	}
+	msgs := make([]proto.Message, 0)
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			s.writeGRPCError(w, err)
+			return
+		}
+		msgs = append(msgs, msg)
+	}
+	s.writeMessages(w, msgs)
+}
+
+// providerTypeGet handles GET /provider-types/{code}
+func (s *Server) providerTypeGet(
+	w http.ResponseWriter,
+	r *http.Request,
+	vars map[string]string,
+) {
+	ac, err := s.apiClient()
+	if err != nil {
+		writeError(w, errors.ErrUnavailable)
+		return
+	}
+	req := &pb.ProviderTypeGetRequest{
+		Session: sessionFromRequest(r),
+		Filter: &pb.ProviderTypeFilter{
+			Search: vars["code"],
+		},
+	}
+	pt, err := ac.ProviderTypeGet(r.Context(), req)
+	if err != nil {
+		s.writeGRPCError(w, err)
+		return
+	}
+	s.writeMessage(w, http.StatusOK, pt)
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/runmachine-io/runmachine/pkg/errors"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// HTTP headers that are translated into the Session supplied to the RunmAPI
+// service
+const (
+	headerUser      = "X-Runm-User"
+	headerProject   = "X-Runm-Project"
+	headerPartition = "X-Runm-Partition"
+)
+
+var marshaler = &jsonpb.Marshaler{OrigName: true}
+
+// sessionFromRequest returns a Session message populated from the HTTP
+// request's headers
+func sessionFromRequest(r *http.Request) *pb.Session {
+	return &pb.Session{
+		User:      r.Header.Get(headerUser),
+		Project:   r.Header.Get(headerProject),
+		Partition: r.Header.Get(headerPartition),
+	}
+}
+
+// errorResponse is the JSON body returned to the client when an error occurs
+type errorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// errorFromGRPC translates an error returned by the RunmAPI service into an
+// errors.Error having an HTTP status code appropriate for the gRPC status
+// code. The message of the gRPC status is preserved.
+func errorFromGRPC(err error) *errors.Error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return errors.ErrUnknown
+	}
+	var base *errors.Error
+	switch st.Code() {
+	case codes.NotFound:
+		base = errors.ErrNotFound
+	case codes.AlreadyExists:
+		base = errors.ErrDuplicate
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		base = errors.ErrBadInput
+	case codes.PermissionDenied, codes.Unauthenticated:
+		base = errors.ErrForbidden
+	case codes.Aborted:
+		base = errors.ErrGenerationConflict
+	case codes.Unavailable, codes.DeadlineExceeded:
+		base = errors.ErrUnavailable
+	default:
+		base = errors.ErrUnknown
+	}
+	return &errors.Error{
+		HTTPCode: base.HTTPCode,
+		Code:     base.Code,
+		Message:  st.Message(),
+	}
+}
+
+// writeError writes the supplied error to the HTTP response with the error's
+// HTTP status code
+func writeError(w http.ResponseWriter, err *errors.Error) {
+	body, _ := json.Marshal(&errorResponse{
+		Code:    err.Code,
+		Message: err.Message,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(err.HTTPCode)
+	w.Write(body)
+}
+
+// writeGRPCError writes an error returned by the RunmAPI service to the HTTP
+// response
+func (s *Server) writeGRPCError(w http.ResponseWriter, err error) {
+	herr := errorFromGRPC(err)
+	if herr.HTTPCode >= 500 {
+		s.log.ERR("error calling RunmAPI service: %s", err)
+	}
+	writeError(w, herr)
+}
+
+// writeMessage writes the JSON representation of the supplied protobuf
+// message to the HTTP response
+func (s *Server) writeMessage(
+	w http.ResponseWriter,
+	code int,
+	msg proto.Message,
+) {
+	var buf bytes.Buffer
+	if err := marshaler.Marshal(&buf, msg); err != nil {
+		s.log.ERR("failed to marshal %T to JSON: %s", msg, err)
+		writeError(w, errors.ErrUnknown)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(buf.Bytes())
+}
+
+// writeMessages writes a JSON array containing the JSON representation of
+// each of the supplied protobuf messages to the HTTP response
+func (s *Server) writeMessages(
+	w http.ResponseWriter,
+	msgs []proto.Message,
+) {
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for x, msg := range msgs {
+		if x > 0 {
+			buf.WriteString(",")
+		}
+		if err := marshaler.Marshal(&buf, msg); err != nil {
+			s.log.ERR("failed to marshal %T to JSON: %s", msg, err)
+			writeError(w, errors.ErrUnknown)
+			return
+		}
+	}
+	buf.WriteString("]")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// routeParam describes a query string parameter accepted by a route
+type routeParam struct {
+	name        string
+	description string
+}
+
+// route maps an HTTP method and a URL path pattern to a handler. Path
+// segments enclosed in braces (e.g. "{id}") match any single non-empty
+// segment and are passed to the handler keyed by the name inside the braces.
+type route struct {
+	method  string
+	pattern string
+	// Short description of the route, used in the OpenAPI document
+	summary string
+	// Query string parameters accepted by the route
+	params []*routeParam
+	// Description of the request body, or empty string if the route does not
+	// accept a request body
+	body string
+	// The HTTP status code returned on success
+	successCode int
+	handler     func(*Server, http.ResponseWriter, *http.Request, map[string]string)
+}
+
+// match returns the path variables of the supplied URL path and true if the
+// path matches the route's pattern, otherwise nil and false
+func (rt *route) match(path string) (map[string]string, bool) {
+	patParts := strings.Split(strings.Trim(rt.pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patParts) != len(pathParts) {
+		return nil, false
+	}
+	vars := make(map[string]string, 0)
+	for x, patPart := range patParts {
+		if strings.HasPrefix(patPart, "{") && strings.HasSuffix(patPart, "}") {
+			if pathParts[x] == "" {
+				return nil, false
+			}
+			vars[patPart[1:len(patPart)-1]] = pathParts[x]
+			continue
+		}
+		if patPart != pathParts[x] {
+			return nil, false
+		}
+	}
+	return vars, true
+}
+
+// pathVars returns the names of the variables in the route's path pattern
+func (rt *route) pathVars() []string {
+	res := make([]string, 0)
+	for _, part := range strings.Split(strings.Trim(rt.pattern, "/"), "/") {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			res = append(res, part[1:len(part)-1])
+		}
+	}
+	return res
+}
+
+// routes returns the set of routes served by the gateway. The OpenAPI
+// document served at /openapi.json is generated from this set.
+func routes() []*route {
+	return []*route{
+		&route{
+			method:      "GET",
+			pattern:     "/openapi.json",
+			summary:     "Returns the OpenAPI document describing the gateway",
+			successCode: http.StatusOK,
+			handler:     (*Server).openAPIGet,
+		},
+		&route{
+			method:      "GET",
+			pattern:     "/health",
+			summary:     "Returns whether the gateway can reach a healthy API service",
+			successCode: http.StatusOK,
+			handler:     (*Server).healthGet,
+		},
+		&route{
+			method:      "GET",
+			pattern:     "/partitions",
+			summary:     "Returns information about partitions",
+			successCode: http.StatusOK,
+			handler:     (*Server).partitionList,
+		},
+		&route{
+			method:      "GET",
+			pattern:     "/partitions/{id}",
+			summary:     "Returns information about a specific partition",
+			successCode: http.StatusOK,
+			handler:     (*Server).partitionGet,
+		},
+		&route{
+			method:      "POST",
+			pattern:     "/partitions",
+			summary:     "Creates a new partition",
+			body:        "YAML or JSON document describing the partition",
+			successCode: http.StatusCreated,
+			handler:     (*Server).partitionCreate,
+		},
+		&route{
+			method:      "GET",
+			pattern:     "/provider-types",
+			summary:     "Returns information about provider types",
+			successCode: http.StatusOK,
+			handler:     (*Server).providerTypeList,
+		},
+		&route{
+			method:      "GET",
+			pattern:     "/provider-types/{code}",
+			summary:     "Returns information about a specific provider type",
+			successCode: http.StatusOK,
+			handler:     (*Server).providerTypeGet,
+		},
+		&route{
+			method:  "GET",
+			pattern: "/providers",
+			summary: "Returns information about providers",
+			params: []*routeParam{
+				&routeParam{
+					name:        "partition",
+					description: "UUID or name of the partition",
+				},
+				&routeParam{
+					name:        "type",
+					description: "Code of the provider type",
+				},
+				&routeParam{
+					name: "name",
+					description: "UUID or name of the provider. A trailing " +
+						"'*' indicates a prefix match",
+				},
+				&routeParam{
+					name: "selector",
+					description: "Selector expression to match providers " +
+						"with. May not be used with the other parameters",
+				},
+			},
+			successCode: http.StatusOK,
+			handler:     (*Server).providerList,
+		},
+		&route{
+			method:  "GET",
+			pattern: "/providers/{id}",
+			summary: "Returns information about a specific provider",
+			params: []*routeParam{
+				&routeParam{
+					name:        "partition",
+					description: "UUID or name of the partition",
+				},
+			},
+			successCode: http.StatusOK,
+			handler:     (*Server).providerGet,
+		},
+		&route{
+			method:      "POST",
+			pattern:     "/providers",
+			summary:     "Creates a new provider",
+			body:        "YAML or JSON document describing the provider",
+			successCode: http.StatusCreated,
+			handler:     (*Server).providerCreate,
+		},
+		&route{
+			method:      "DELETE",
+			pattern:     "/providers/{id}",
+			summary:     "Deletes a provider",
+			successCode: http.StatusOK,
+			handler:     (*Server).providerDelete,
+		},
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/runmachine-io/runmachine/pkg/logging"
+)
+
+func TestRouteMatch(t *testing.T) {
+	rt := &route{pattern: "/providers/{id}"}
+
+	tests := []struct {
+		path    string
+		matches bool
+		id      string
+	}{
+		{"/providers/node1", true, "node1"},
+		{"/providers/node1/", true, "node1"},
+		{"/providers", false, ""},
+		{"/providers/", false, ""},
+		{"/providers/node1/extra", false, ""},
+		{"/partitions/part0", false, ""},
+	}
+	for _, test := range tests {
+		vars, ok := rt.match(test.path)
+		assert.Equal(t, test.matches, ok, "path: %s", test.path)
+		if ok {
+			assert.Equal(t, test.id, vars["id"])
+		}
+	}
+}
+
+func TestErrorFromGRPC(t *testing.T) {
+	tests := []struct {
+		code     codes.Code
+		httpCode int
+	}{
+		{codes.NotFound, http.StatusNotFound},
+		{codes.AlreadyExists, http.StatusConflict},
+		{codes.FailedPrecondition, http.StatusBadRequest},
+		{codes.Unavailable, http.StatusServiceUnavailable},
+		{codes.Internal, http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		err := errorFromGRPC(status.Errorf(test.code, "boom"))
+		assert.Equal(t, test.httpCode, err.HTTPCode)
+		assert.Equal(t, "boom", err.Message)
+	}
+}
+
+func TestServeHTTPUnknownRoutes(t *testing.T) {
+	s := &Server{
+		log:    logging.New(&logging.Config{}),
+		routes: routes(),
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{"GET", "/nonexistent", http.StatusNotFound},
+		{"PUT", "/providers", http.StatusMethodNotAllowed},
+		{"GET", "/openapi.json", http.StatusOK},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+		assert.Equal(t, test.code, w.Code, "%s %s", test.method, test.path)
+	}
+}
+
+func TestOpenAPIDocumentCoversRoutes(t *testing.T) {
+	doc := openAPIDocument(routes())
+	paths := doc["paths"].(map[string]interface{})
+	for _, rt := range routes() {
+		item, exists := paths[rt.pattern]
+		assert.True(t, exists, "missing path %s", rt.pattern)
+		if exists {
+			_, exists = item.(map[string]interface{})[map[string]string{
+				"GET":    "get",
+				"POST":   "post",
+				"DELETE": "delete",
+			}[rt.method]]
+			assert.True(t, exists, "missing %s %s", rt.method, rt.pattern)
+		}
+	}
+}
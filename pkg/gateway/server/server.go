@@ -0,0 +1,76 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/runmachine-io/runmachine/pkg/errors"
+	"github.com/runmachine-io/runmachine/pkg/gateway/server/config"
+	"github.com/runmachine-io/runmachine/pkg/logging"
+	"github.com/runmachine-io/runmachine/pkg/registry"
+	"github.com/runmachine-io/runmachine/pkg/svcclient"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// Server is an HTTP server that translates resource-oriented HTTP/JSON
+// requests into calls to the RunmAPI gRPC service
+type Server struct {
+	log       *logging.Logs
+	cfg       *config.Config
+	registry  registry.Registry
+	apisc     *svcclient.Client
+	apiclient pb.RunmAPIClient
+	routes    []*route
+}
+
+// ServeHTTP dispatches the HTTP request to the handler of the first route
+// matching the request's method and path
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.log.L3("%s %s", r.Method, r.URL.Path)
+	pathMatched := false
+	for _, rt := range s.routes {
+		vars, ok := rt.match(r.URL.Path)
+		if !ok {
+			continue
+		}
+		pathMatched = true
+		if rt.method != r.Method {
+			continue
+		}
+		rt.handler(s, w, r, vars)
+		return
+	}
+	if pathMatched {
+		writeError(w, errors.ErrMethodNotAllowed)
+		return
+	}
+	writeError(w, errors.ErrNotFound)
+}
+
+// Close closes all connections to the API service's endpoints
+func (s *Server) Close() {
+	s.apisc.Close()
+}
+
+func New(
+	cfg *config.Config,
+	log *logging.Logs,
+	reg registry.Registry,
+) (*Server, error) {
+	apisc, err := svcclient.New(
+		log, reg, cfg.APIServiceName, svcclient.DefaultOptions(""),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API service client: %v", err)
+	}
+
+	s := &Server{
+		log:       log,
+		cfg:       cfg,
+		registry:  reg,
+		apisc:     apisc,
+		apiclient: pb.NewRunmAPIClient(apisc.Conn()),
+	}
+	s.routes = routes()
+	return s, nil
+}
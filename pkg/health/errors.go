@@ -0,0 +1,15 @@
+package health
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func errNotServing(st healthpb.HealthCheckResponse_ServingStatus) error {
+	return status.Errorf(
+		codes.Unavailable,
+		"service reported %s serving status", st,
+	)
+}
@@ -0,0 +1,145 @@
+// Package health implements the standard grpc.health.v1 Health service for
+// runmachine services. The serving status reported by the Health service
+// reflects the state of the service's dependencies (e.g. etcd for
+// runm-metadata, the SQL database for runm-resource), which is checked periodically.
+package health
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/runmachine-io/runmachine/pkg/logging"
+)
+
+const (
+	defaultInterval = 10 * time.Second
+	defaultTimeout  = 5 * time.Second
+)
+
+// CheckFunc returns nil if all of a service's dependencies are healthy
+type CheckFunc func(ctx context.Context) error
+
+// Checker periodically runs a CheckFunc and updates the serving status
+// reported by the grpc.health.v1 Health service accordingly
+type Checker struct {
+	log      *logging.Logs
+	check    CheckFunc
+	services []string
+	server   *health.Server
+	done     chan struct{}
+	// How often to run the check
+	Interval time.Duration
+	// How long to wait for a single run of the check
+	Timeout time.Duration
+}
+
+// New returns a Checker that reports the overall serving status (empty
+// service name) along with the serving status of each named gRPC service
+// (e.g. "runm.RunmMetadata") based on the result of the supplied check.
+// Services report NOT_SERVING until the first check succeeds.
+func New(
+	log *logging.Logs,
+	check CheckFunc,
+	services ...string,
+) *Checker {
+	c := &Checker{
+		log:      log,
+		check:    check,
+		services: append([]string{""}, services...),
+		server:   health.NewServer(),
+		done:     make(chan struct{}),
+		Interval: defaultInterval,
+		Timeout:  defaultTimeout,
+	}
+	c.setStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	return c
+}
+
+// Register registers the grpc.health.v1 Health service with the supplied gRPC
+// server
+func (c *Checker) Register(s *grpc.Server) {
+	healthpb.RegisterHealthServer(s, c.server)
+}
+
+// Start runs the check immediately and then periodically in the background
+// until Stop is called
+func (c *Checker) Start() {
+	c.run()
+	go func() {
+		ticker := time.NewTicker(c.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-ticker.C:
+				c.run()
+			}
+		}
+	}()
+}
+
+// Stop stops the periodic check and reports NOT_SERVING for all services so
+// that clients stop sending RPCs to a service that is shutting down
+func (c *Checker) Stop() {
+	close(c.done)
+	c.setStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+}
+
+func (c *Checker) run() {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	if err := c.check(ctx); err != nil {
+		c.log.ERR("health check failed: %s", err)
+		c.setStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+		return
+	}
+	c.setStatus(healthpb.HealthCheckResponse_SERVING)
+}
+
+func (c *Checker) setStatus(st healthpb.HealthCheckResponse_ServingStatus) {
+	for _, svc := range c.services {
+		c.server.SetServingStatus(svc, st)
+	}
+}
+
+// CheckConn asks the grpc.health.v1 Health service at the other end of the
+// supplied client connection for the overall serving status and returns nil if
+// the status is SERVING
+func CheckConn(ctx context.Context, conn *grpc.ClientConn) error {
+	resp, err := healthpb.NewHealthClient(conn).Check(
+		ctx, &healthpb.HealthCheckRequest{},
+	)
+	if err != nil {
+		return err
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return errNotServing(resp.Status)
+	}
+	return nil
+}
+
+// CheckAddress connects to the supplied address and returns the overall
+// serving status reported by the grpc.health.v1 Health service there
+func CheckAddress(
+	ctx context.Context,
+	addr string,
+) (healthpb.HealthCheckResponse_ServingStatus, error) {
+	// TODO(jaypipes): Don't hardcode this to WithInsecure
+	conn, err := grpc.DialContext(ctx, addr, grpc.WithInsecure())
+	if err != nil {
+		return healthpb.HealthCheckResponse_UNKNOWN, err
+	}
+	defer conn.Close()
+	resp, err := healthpb.NewHealthClient(conn).Check(
+		ctx, &healthpb.HealthCheckRequest{},
+	)
+	if err != nil {
+		return healthpb.HealthCheckResponse_UNKNOWN, err
+	}
+	return resp.Status, nil
+}
@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"context"
+)
+
+// Fields describes the request being handled when a message is logged
+type Fields struct {
+	RequestID string
+	User      string
+	Project   string
+	Partition string
+	// Full name of the RPC method being handled
+	Method string
+}
+
+type fieldsKey struct{}
+
+// FieldsFromContext returns the logging fields carried by the context
+func FieldsFromContext(ctx context.Context) Fields {
+	f, _ := ctx.Value(fieldsKey{}).(Fields)
+	return f
+}
+
+// ContextWithFields returns a copy of the context carrying the supplied
+// logging fields
+func ContextWithFields(ctx context.Context, f Fields) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, f)
+}
+
+// WithRequestID returns a copy of the context carrying the supplied request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	f := FieldsFromContext(ctx)
+	f.RequestID = id
+	return ContextWithFields(ctx, f)
+}
+
+// RequestID returns the request ID carried by the context, or empty string if
+// the context has no request ID
+func RequestID(ctx context.Context) string {
+	return FieldsFromContext(ctx).RequestID
+}
+
+// WithMethod returns a copy of the context carrying the supplied RPC method
+// name
+func WithMethod(ctx context.Context, method string) context.Context {
+	f := FieldsFromContext(ctx)
+	f.Method = method
+	return ContextWithFields(ctx, f)
+}
+
+// WithSession returns a copy of the context carrying the user, project and
+// partition of the session making the request
+func WithSession(
+	ctx context.Context,
+	user string,
+	project string,
+	partition string,
+) context.Context {
+	f := FieldsFromContext(ctx)
+	f.User = user
+	f.Project = project
+	f.Partition = partition
+	return ContextWithFields(ctx, f)
+}
+
+// ForContext returns a Logs that includes the request information carried by
+// the supplied context in each message. The returned Logs shares its output,
+// configuration and log level with the receiver.
+func (logs *Logs) ForContext(ctx context.Context) *Logs {
+	f := FieldsFromContext(ctx)
+	if f == (Fields{}) {
+		return logs
+	}
+	l := *logs
+	l.fields = f
+	return &l
+}
@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// sessionRequest is implemented by request messages that carry a Session
+type sessionRequest interface {
+	GetSession() *pb.Session
+}
+
+// withRequest returns a copy of the context carrying the RPC method name and,
+// if the request message has a Session, the session's user, project and
+// partition
+func withRequest(
+	ctx context.Context,
+	method string,
+	req interface{},
+) context.Context {
+	ctx = WithMethod(ctx, method)
+	if sr, ok := req.(sessionRequest); ok {
+		if sess := sr.GetSession(); sess != nil {
+			ctx = WithSession(ctx, sess.User, sess.Project, sess.Partition)
+		}
+	}
+	return ctx
+}
+
+// UnaryServerInterceptor returns a gRPC server interceptor that adds the RPC
+// method and request session to the context so that Logs returned from
+// ForContext() include them in each message
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		return handler(withRequest(ctx, info.FullMethod, req), req)
+	}
+}
+
+// loggingServerStream adds the RPC method and the session of the received
+// request message to the stream's context
+type loggingServerStream struct {
+	grpc.ServerStream
+	method string
+	ctx    context.Context
+}
+
+func (s *loggingServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *loggingServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.ctx = withRequest(s.ctx, s.method, m)
+	}
+	return err
+}
+
+// StreamServerInterceptor returns a gRPC server interceptor that adds the RPC
+// method and request session to the stream's context so that Logs returned
+// from ForContext() include them in each message
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		return handler(srv, &loggingServerStream{
+			ServerStream: ss,
+			method:       info.FullMethod,
+			ctx:          WithMethod(ss.Context(), info.FullMethod),
+		})
+	}
+}
@@ -1,34 +1,59 @@
 package logging
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"sync/atomic"
+	"time"
 
 	"github.com/jaypipes/envutil"
 	flag "github.com/ogier/pflag"
 )
 
 const (
-	defaultLogLevel = 0
+	defaultLogLevel  = 0
+	defaultLogFormat = FormatText
+
+	// Messages are written as free-form text lines
+	FormatText = "text"
+	// Messages are written as JSON objects, one per line
+	FormatJSON = "json"
+
+	// The most verbose log level
+	MaxLevel = 3
 )
 
 type Config struct {
 	Level int
+	// One of "text" (default) or "json"
+	Format string
+	// Name of the service doing the logging, included in JSON output
+	Service string
 }
 
 type Logs struct {
-	cfg     *Config
+	cfg *Config
+	// The current log level. Shared by all Logs derived from the same call to
+	// New() so that the level can be changed at runtime.
+	level   *int32
 	log3    *log.Logger
 	log2    *log.Logger
 	log1    *log.Logger
 	elog    *log.Logger
+	jlog    *log.Logger
+	jelog   *log.Logger
 	section string
+	// Information about the request being handled, included in each message
+	fields Fields
 }
 
 func New(cfg *Config) *Logs {
+	level := int32(cfg.Level)
 	logs := &Logs{
-		cfg: cfg,
+		cfg:   cfg,
+		level: &level,
 		log3: log.New(
 			os.Stdout,
 			"",
@@ -49,6 +74,8 @@ func New(cfg *Config) *Logs {
 			"ERROR: ",
 			(log.Ldate | log.Ltime | log.LUTC),
 		),
+		jlog:    log.New(os.Stdout, "", 0),
+		jelog:   log.New(os.Stderr, "", 0),
 		section: "",
 	}
 	return logs
@@ -65,14 +92,41 @@ func ConfigFromOpts() *Config {
 		"The verbosity of logging. 0 (default) = virtually no logging. "+
 			"1 = some logging. 2 = debug-level logging, 3 = trace-level logging",
 	)
+	format := flag.String(
+		"log-format",
+		envutil.WithDefault(
+			"RUNM_LOG_FORMAT", defaultLogFormat,
+		),
+		"The format of log messages: text (default) or json",
+	)
 
 	flag.Parse()
 	cfg := &Config{
-		Level: *level,
+		Level:  *level,
+		Format: *format,
 	}
 	return cfg
 }
 
+// Level returns the current log level
+func (logs *Logs) Level() int {
+	return int(atomic.LoadInt32(logs.level))
+}
+
+// SetLevel changes the log level of the Logs and of all Logs derived from it,
+// clamping the supplied level to between 0 and MaxLevel. Returns the new log
+// level.
+func (logs *Logs) SetLevel(level int) int {
+	if level < 0 {
+		level = 0
+	}
+	if level > MaxLevel {
+		level = MaxLevel
+	}
+	atomic.StoreInt32(logs.level, int32(level))
+	return level
+}
+
 // Sets a scoped log section and returns a functor that should be deferred that
 // resets the log section to its previous scope
 func (logs *Logs) WithSection(section string) func() {
@@ -84,33 +138,84 @@ func (logs *Logs) WithSection(section string) func() {
 	return reset
 }
 
-func (logs *Logs) SQL(sql string, args ...interface{}) {
-	if logs.log3 == nil || logs.cfg.Level <= 2 {
-		return
+// prefix returns the supplied message prefixed with the log section and
+// request ID, if any
+func (logs *Logs) prefix(message string) string {
+	if logs.fields.RequestID != "" {
+		message = fmt.Sprintf("[req-%s] %s", logs.fields.RequestID, message)
 	}
-	message := "== SQL START =="
 	if logs.section != "" {
 		message = fmt.Sprintf("[%s] %s", logs.section, message)
 	}
+	return message
+}
+
+// record is a log message in JSON format
+type record struct {
+	Timestamp string `json:"timestamp"`
+	Level     string `json:"level"`
+	Service   string `json:"service,omitempty"`
+	Section   string `json:"section,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	User      string `json:"user,omitempty"`
+	Project   string `json:"project,omitempty"`
+	Partition string `json:"partition,omitempty"`
+	Method    string `json:"method,omitempty"`
+	Message   string `json:"message"`
+}
+
+// json returns true if messages should be written in JSON format
+func (logs *Logs) json() bool {
+	return logs.cfg.Format == FormatJSON
+}
+
+// writeJSON writes the supplied message as a JSON record at the named level
+func (logs *Logs) writeJSON(out *log.Logger, level string, message string) {
+	b, err := json.Marshal(&record{
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Level:     level,
+		Service:   logs.cfg.Service,
+		Section:   logs.section,
+		RequestID: logs.fields.RequestID,
+		User:      logs.fields.User,
+		Project:   logs.fields.Project,
+		Partition: logs.fields.Partition,
+		Method:    logs.fields.Method,
+		Message:   message,
+	})
+	if err != nil {
+		return
+	}
+	out.Print(string(b))
+}
+
+func (logs *Logs) SQL(sql string, args ...interface{}) {
+	if logs.log3 == nil || logs.Level() <= 2 {
+		return
+	}
+	if logs.json() {
+		logs.writeJSON(logs.jlog, "trace", sql)
+		return
+	}
+	message := logs.prefix("== SQL START ==")
 	message = message + sql
 	// Since we're logging calling file, the 2 below jumps us out of this
 	// function so the file and line numbers will refer to the caller of
 	// Context.Trace(), not this function itself.
 	logs.log3.Output(2, message)
-	footer := "== SQL END =="
-	if logs.section != "" {
-		footer = fmt.Sprintf("[%s] %s", logs.section, footer)
-	}
+	footer := logs.prefix("== SQL END ==")
 	logs.log3.Output(2, footer)
 }
 
 func (logs *Logs) L3(message string, args ...interface{}) {
-	if logs.log3 == nil || logs.cfg.Level <= 2 {
+	if logs.log3 == nil || logs.Level() <= 2 {
 		return
 	}
-	if logs.section != "" {
-		message = fmt.Sprintf("[%s] %s", logs.section, message)
+	if logs.json() {
+		logs.writeJSON(logs.jlog, "trace", fmt.Sprintf(message, args...))
+		return
 	}
+	message = logs.prefix(message)
 	// Since we're logging calling file, the 2 below jumps us out of this
 	// function so the file and line numbers will refer to the caller of
 	// Context.Trace(), not this function itself.
@@ -118,22 +223,36 @@ func (logs *Logs) L3(message string, args ...interface{}) {
 }
 
 func (logs *Logs) L2(message string, args ...interface{}) {
-	if logs.log2 == nil || logs.cfg.Level <= 1 {
+	if logs.log2 == nil || logs.Level() <= 1 {
 		return
 	}
-	if logs.section != "" {
-		message = fmt.Sprintf("[%s] %s", logs.section, message)
+	if logs.json() {
+		logs.writeJSON(logs.jlog, "debug", fmt.Sprintf(message, args...))
+		return
 	}
+	message = logs.prefix(message)
 	logs.log2.Printf(message, args...)
 }
 
 func (logs *Logs) L1(message string, args ...interface{}) {
-	if logs.log1 == nil || logs.cfg.Level <= 0 {
+	if logs.log1 == nil || logs.Level() <= 0 {
 		return
 	}
-	if logs.section != "" {
-		message = fmt.Sprintf("[%s] %s", logs.section, message)
+	if logs.json() {
+		logs.writeJSON(logs.jlog, "info", fmt.Sprintf(message, args...))
+		return
 	}
+	message = logs.prefix(message)
+	logs.log1.Printf(message, args...)
+}
+
+// notice logs an informational message regardless of the current log level
+func (logs *Logs) notice(message string, args ...interface{}) {
+	if logs.json() {
+		logs.writeJSON(logs.jlog, "info", fmt.Sprintf(message, args...))
+		return
+	}
+	message = logs.prefix(message)
 	logs.log1.Printf(message, args...)
 }
 
@@ -141,8 +260,10 @@ func (logs *Logs) ERR(message string, args ...interface{}) {
 	if logs.elog == nil {
 		return
 	}
-	if logs.section != "" {
-		message = fmt.Sprintf("[%s] %s", logs.section, message)
+	if logs.json() {
+		logs.writeJSON(logs.jelog, "error", fmt.Sprintf(message, args...))
+		return
 	}
+	message = logs.prefix(message)
 	logs.elog.Printf(message, args...)
 }
@@ -0,0 +1,117 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// captured returns a Logs writing all output to the returned buffer
+func captured(cfg *Config) (*Logs, *bytes.Buffer) {
+	var buf bytes.Buffer
+	logs := New(cfg)
+	logs.log1 = log.New(&buf, "", 0)
+	logs.log2 = log.New(&buf, "", 0)
+	logs.log3 = log.New(&buf, "", 0)
+	logs.elog = log.New(&buf, "ERROR: ", 0)
+	logs.jlog = log.New(&buf, "", 0)
+	logs.jelog = log.New(&buf, "", 0)
+	return logs, &buf
+}
+
+func TestTextFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	logs, buf := captured(&Config{Level: 1})
+	defer logs.WithSection("test")()
+
+	ctx := WithRequestID(context.TODO(), "abc")
+	logs.ForContext(ctx).L1("hello %s", "world")
+	logs.L2("not logged at level 1")
+	logs.ERR("failed")
+
+	assert.Equal(
+		"[test] [req-abc] hello world\nERROR: [test] failed\n",
+		buf.String(),
+	)
+}
+
+func TestJSONFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	logs, buf := captured(&Config{
+		Level:   2,
+		Format:  FormatJSON,
+		Service: "runm-test",
+	})
+	defer logs.WithSection("test")()
+
+	ctx := WithRequestID(context.TODO(), "abc")
+	ctx = WithSession(ctx, "alice", "proj", "part")
+	ctx = WithMethod(ctx, "/runm.Test/thing_get")
+	logs.ForContext(ctx).L2("hello %s", "world")
+
+	var rec record
+	assert.Nil(json.Unmarshal(buf.Bytes(), &rec))
+	assert.NotEmpty(rec.Timestamp)
+	assert.Equal("debug", rec.Level)
+	assert.Equal("runm-test", rec.Service)
+	assert.Equal("test", rec.Section)
+	assert.Equal("abc", rec.RequestID)
+	assert.Equal("alice", rec.User)
+	assert.Equal("proj", rec.Project)
+	assert.Equal("part", rec.Partition)
+	assert.Equal("/runm.Test/thing_get", rec.Method)
+	assert.Equal("hello world", rec.Message)
+}
+
+func TestSetLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	logs, buf := captured(&Config{Level: 0})
+	derived := logs.ForContext(WithRequestID(context.TODO(), "abc"))
+
+	derived.L1("not logged")
+	assert.Equal(1, logs.SetLevel(1))
+	derived.L1("logged")
+	assert.Equal("[req-abc] logged\n", buf.String())
+
+	assert.Equal(MaxLevel, logs.SetLevel(10))
+	assert.Equal(0, logs.SetLevel(-1))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	assert := assert.New(t)
+
+	var got Fields
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got = FieldsFromContext(ctx)
+		return nil, nil
+	}
+	req := &pb.PartitionGetRequest{
+		Session: &pb.Session{
+			User:      "alice",
+			Project:   "proj",
+			Partition: "part",
+		},
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/runm.RunmAPI/partition_get"}
+	UnaryServerInterceptor()(context.TODO(), req, info, handler)
+
+	assert.Equal(
+		Fields{
+			User:      "alice",
+			Project:   "proj",
+			Partition: "part",
+			Method:    "/runm.RunmAPI/partition_get",
+		},
+		got,
+	)
+}
@@ -0,0 +1,27 @@
+package logging
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// HandleLevelSignals changes the log level at runtime when the process
+// receives a signal: SIGUSR1 increases the verbosity by one level and SIGUSR2
+// decreases it by one level.
+func (logs *Logs) HandleLevelSignals() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for sig := range sigs {
+			level := logs.Level()
+			switch sig {
+			case syscall.SIGUSR1:
+				level = logs.SetLevel(level + 1)
+			case syscall.SIGUSR2:
+				level = logs.SetLevel(level - 1)
+			}
+			logs.notice("received %s. log level is now %d", sig, level)
+		}
+	}()
+}
@@ -11,4 +11,6 @@ const (
 	OP_LESSER_THAN_EQUAL     = 5
 	OP_IN                    = 6
 	OP_NOT_IN                = 7
+	OP_REGEX                 = 8
+	OP_PREFIX                = 9
 )
@@ -2,6 +2,7 @@ package conditions
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 
 	pb "github.com/runmachine-io/runmachine/proto"
@@ -18,7 +19,7 @@ type ObjectCondition struct {
 	NameCondition       *NameCondition
 	ProjectCondition    string
 	PropertyCondition   *PropertyCondition
-	// TODO(jaypipes): Add support for tag filters
+	TagCondition        *TagCondition
 }
 
 func (f *ObjectCondition) Matches(obj *pb.Object) bool {
@@ -45,6 +46,9 @@ func (f *ObjectCondition) Matches(obj *pb.Object) bool {
 	if !f.PropertyCondition.Matches(obj) {
 		return false
 	}
+	if !f.TagCondition.Matches(obj) {
+		return false
+	}
 	return true
 }
 
@@ -55,6 +59,7 @@ func (f *ObjectCondition) IsEmpty() bool {
 		f.UuidsCondition == nil &&
 		f.ProjectCondition == "" &&
 		f.PropertyCondition == nil &&
+		f.TagCondition == nil &&
 		f.NameCondition == nil
 }
 
@@ -83,22 +88,35 @@ func (f *ObjectCondition) String() string {
 	}
 	if f.PropertyCondition != nil {
 		attrMap["properties"] = fmt.Sprintf(
-			"reqkeys=%s,reqitems=%s,anykeys=%s,anyitems=%s,forbidkeys=%s,forbiditems=%s",
+			"reqkeys=%s,reqitems=%s,anykeys=%s,anyitems=%s,forbidkeys=%s,forbiditems=%s,exprs=%s",
 			f.PropertyCondition.RequireKeys,
 			f.PropertyCondition.RequireItems,
 			f.PropertyCondition.AnyKeys,
 			f.PropertyCondition.AnyItems,
 			f.PropertyCondition.ForbidKeys,
 			f.PropertyCondition.ForbidItems,
+			f.PropertyCondition.Expressions,
 		)
 	}
+	if f.TagCondition != nil {
+		attrMap["tags"] = fmt.Sprintf(
+			"reqtags=%s,anytags=%s,forbidtags=%s",
+			f.TagCondition.RequireTags,
+			f.TagCondition.AnyTags,
+			f.TagCondition.ForbidTags,
+		)
+	}
+	keys := make([]string, 0, len(attrMap))
+	for k := range attrMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 	attrs := ""
-	x := 0
-	for k, v := range attrMap {
+	for x, k := range keys {
 		if x > 0 {
 			attrs += ","
 		}
-		attrs += k + "=" + v
+		attrs += k + "=" + attrMap[k]
 	}
 	return fmt.Sprintf("ObjectCondition(%s)", attrs)
 }
@@ -1,6 +1,11 @@
 package conditions
 
 import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
 	pb "github.com/runmachine-io/runmachine/proto"
 )
 
@@ -15,6 +20,7 @@ type PropertyCondition struct {
 	AnyItems     []*pb.Property
 	ForbidKeys   []string
 	ForbidItems  []*pb.Property
+	Expressions  []*PropertyExpression
 }
 
 func (c *PropertyCondition) Matches(obj HasProperties) bool {
@@ -26,7 +32,8 @@ func (c *PropertyCondition) Matches(obj HasProperties) bool {
 		(c.AnyKeys == nil || len(c.AnyKeys) == 0) &&
 		(c.AnyItems == nil || len(c.AnyItems) == 0) &&
 		(c.ForbidKeys == nil || len(c.ForbidKeys) == 0) &&
-		(c.ForbidItems == nil || len(c.ForbidItems) == 0) {
+		(c.ForbidItems == nil || len(c.ForbidItems) == 0) &&
+		len(c.Expressions) == 0 {
 		return true
 	}
 	props := obj.GetProperties()
@@ -61,7 +68,7 @@ func (c *PropertyCondition) Matches(obj HasProperties) bool {
 	}
 	if len(c.AnyKeys) > 0 {
 		found := false
-		for _, anyKey := range c.ForbidKeys {
+		for _, anyKey := range c.AnyKeys {
 			for _, prop := range props {
 				if anyKey == prop.Key {
 					found = true
@@ -78,7 +85,7 @@ func (c *PropertyCondition) Matches(obj HasProperties) bool {
 	}
 	if len(c.AnyItems) > 0 {
 		found := false
-		for _, anyItem := range c.ForbidItems {
+		for _, anyItem := range c.AnyItems {
 			for _, prop := range props {
 				if anyItem.Key == prop.Key && anyItem.Value == prop.Value {
 					found = true
@@ -111,12 +118,42 @@ func (c *PropertyCondition) Matches(obj HasProperties) bool {
 			}
 		}
 	}
+	for _, expr := range c.Expressions {
+		found := false
+		for _, prop := range props {
+			if expr.Key == prop.Key && expr.Matches(prop) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
 	return true
 }
 
-// PropertyEqual is a helper function that returns a PropertyCondition
-// filtering on an exact property item match
-func PropertyConditionFromFilter(filter *pb.PropertyFilter) *PropertyCondition {
+// PropertyConditionFromFilter is a helper function that returns a
+// PropertyCondition for the supplied PropertyFilter. Returns an error if any
+// of the filter's property expressions is invalid.
+func PropertyConditionFromFilter(
+	filter *pb.PropertyFilter,
+) (*PropertyCondition, error) {
+	var exprs []*PropertyExpression
+	for _, pe := range filter.RequireExpressions {
+		op, found := propertyOperatorOps[pe.Operator]
+		if !found {
+			return nil, fmt.Errorf(
+				"unknown operator %s in expression on property %s",
+				pe.Operator, pe.Key,
+			)
+		}
+		expr, err := NewPropertyExpression(pe.Key, op, pe.Values)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
 	return &PropertyCondition{
 		RequireKeys:  filter.RequireKeys,
 		RequireItems: filter.RequireItems,
@@ -124,5 +161,163 @@ func PropertyConditionFromFilter(filter *pb.PropertyFilter) *PropertyCondition {
 		AnyItems:     filter.AnyItems,
 		ForbidKeys:   filter.ForbidKeys,
 		ForbidItems:  filter.ForbidItems,
+		Expressions:  exprs,
+	}, nil
+}
+
+// propertyOperatorOps maps the operators in PropertyExpression protobuffer
+// messages to the Op a PropertyExpression condition uses
+var propertyOperatorOps = map[pb.PropertyOperator]Op{
+	pb.PropertyOperator_PROPERTY_EQUAL:              OP_EQUAL,
+	pb.PropertyOperator_PROPERTY_GREATER_THAN:       OP_GREATER_THAN,
+	pb.PropertyOperator_PROPERTY_GREATER_THAN_EQUAL: OP_GREATER_THAN_EQUAL,
+	pb.PropertyOperator_PROPERTY_LESS_THAN:          OP_LESSER_THAN,
+	pb.PropertyOperator_PROPERTY_LESS_THAN_EQUAL:    OP_LESSER_THAN_EQUAL,
+	pb.PropertyOperator_PROPERTY_REGEX:              OP_REGEX,
+	pb.PropertyOperator_PROPERTY_IN:                 OP_IN,
+	pb.PropertyOperator_PROPERTY_PREFIX:             OP_PREFIX,
+}
+
+// opStrings contains the string representation of the Ops a
+// PropertyExpression may use
+var opStrings = map[Op]string{
+	OP_EQUAL:              "=",
+	OP_GREATER_THAN:       ">",
+	OP_GREATER_THAN_EQUAL: ">=",
+	OP_LESSER_THAN:        "<",
+	OP_LESSER_THAN_EQUAL:  "<=",
+	OP_REGEX:              "~",
+	OP_IN:                 " in ",
+	OP_PREFIX:             "^=",
+}
+
+// PropertyExpression compares the value of an object's property having a key
+// with one or more values
+type PropertyExpression struct {
+	Key    string
+	Op     Op
+	Values []string
+	// Numeric is true when the property is defined as an integer or number
+	// property, in which case values are compared numerically even for
+	// properties that do not have a typed value
+	Numeric bool
+	regex   *regexp.Regexp
+}
+
+// NewPropertyExpression returns a PropertyExpression for the supplied
+// property key, Op and values. Returns an error if no values are supplied or
+// the values are not valid for the Op.
+func NewPropertyExpression(
+	key string,
+	op Op,
+	values []string,
+) (*PropertyExpression, error) {
+	if key == "" {
+		return nil, fmt.Errorf("property expression requires a property key")
+	}
+	if _, found := opStrings[op]; !found {
+		return nil, fmt.Errorf(
+			"unsupported operator in expression on property %s", key,
+		)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf(
+			"expression on property %s requires a value", key,
+		)
+	}
+	expr := &PropertyExpression{
+		Key:    key,
+		Op:     op,
+		Values: values,
+	}
+	if op == OP_REGEX {
+		re, err := regexp.Compile(values[0])
+		if err != nil {
+			return nil, fmt.Errorf(
+				"invalid regular expression in expression on property %s: %s",
+				key, err,
+			)
+		}
+		expr.regex = re
+	}
+	return expr, nil
+}
+
+func (e *PropertyExpression) String() string {
+	return e.Key + opStrings[e.Op] + strings.Join(e.Values, ",")
+}
+
+// isNumeric returns true if the supplied property's value should be compared
+// numerically
+func (e *PropertyExpression) isNumeric(prop *pb.Property) bool {
+	if e.Numeric {
+		return true
+	}
+	switch prop.TypedValue.GetKind().(type) {
+	case *pb.PropertyValue_IntegerValue, *pb.PropertyValue_NumberValue:
+		return true
+	}
+	return false
+}
+
+// compare returns -1, 0 or 1 if the supplied property's value is less than,
+// equal to or greater than the supplied value. The second return value is
+// false if a numeric comparison was needed and either value is not a number.
+func (e *PropertyExpression) compare(
+	prop *pb.Property,
+	value string,
+) (int, bool) {
+	if !e.isNumeric(prop) {
+		return strings.Compare(prop.Value, value), true
+	}
+	a, err := strconv.ParseFloat(prop.Value, 64)
+	if err != nil {
+		return 0, false
+	}
+	b, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	switch {
+	case a < b:
+		return -1, true
+	case a > b:
+		return 1, true
+	}
+	return 0, true
+}
+
+// Matches returns true if the supplied property's value satisfies the
+// expression. The property's key is not checked.
+func (e *PropertyExpression) Matches(prop *pb.Property) bool {
+	switch e.Op {
+	case OP_REGEX:
+		return e.regex != nil && e.regex.MatchString(prop.Value)
+	case OP_PREFIX:
+		return strings.HasPrefix(prop.Value, e.Values[0])
+	case OP_IN:
+		for _, value := range e.Values {
+			if cmp, ok := e.compare(prop, value); ok && cmp == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	cmp, ok := e.compare(prop, e.Values[0])
+	if !ok {
+		return false
+	}
+	switch e.Op {
+	case OP_EQUAL:
+		return cmp == 0
+	case OP_GREATER_THAN:
+		return cmp > 0
+	case OP_GREATER_THAN_EQUAL:
+		return cmp >= 0
+	case OP_LESSER_THAN:
+		return cmp < 0
+	case OP_LESSER_THAN_EQUAL:
+		return cmp <= 0
 	}
+	return false
 }
@@ -0,0 +1,58 @@
+package conditions
+
+import (
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+type HasTags interface {
+	GetTags() []string
+}
+
+type TagCondition struct {
+	RequireTags []string
+	AnyTags     []string
+	ForbidTags  []string
+}
+
+func (c *TagCondition) Matches(obj HasTags) bool {
+	if c == nil {
+		return true
+	}
+	tags := make(map[string]bool, len(obj.GetTags()))
+	for _, tag := range obj.GetTags() {
+		tags[tag] = true
+	}
+	for _, reqTag := range c.RequireTags {
+		if !tags[reqTag] {
+			return false
+		}
+	}
+	if len(c.AnyTags) > 0 {
+		found := false
+		for _, anyTag := range c.AnyTags {
+			if tags[anyTag] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, forbidTag := range c.ForbidTags {
+		if tags[forbidTag] {
+			return false
+		}
+	}
+	return true
+}
+
+// TagConditionFromFilter is a helper function that returns a TagCondition
+// from the supplied TagFilter
+func TagConditionFromFilter(filter *pb.TagFilter) *TagCondition {
+	return &TagCondition{
+		RequireTags: filter.RequireTags,
+		AnyTags:     filter.AnyTags,
+		ForbidTags:  filter.ForbidTags,
+	}
+}
@@ -1,12 +1,14 @@
 package server
 
 import (
+	"context"
+
 	"github.com/runmachine-io/runmachine/pkg/errors"
 	"github.com/runmachine-io/runmachine/pkg/util"
 	pb "github.com/runmachine-io/runmachine/proto"
 )
 
-func (s *Server) checkSession(sess *pb.Session) error {
+func (s *Server) checkSession(ctx context.Context, sess *pb.Session) error {
 	if sess.User == "" {
 		return ErrSessionUserRequired
 	}
@@ -17,7 +19,7 @@ func (s *Server) checkSession(sess *pb.Session) error {
 		// partition UUID from a name and return an error if no partition with
 		// that name exists
 		if !util.IsUuidLike(sess.Partition) {
-			part, err := s.store.PartitionGetByName(sess.Partition)
+			part, err := s.store.PartitionGetByName(ctx, sess.Partition)
 			if err != nil {
 				if err == errors.ErrNotFound {
 					return errSessionUnknownPartition(sess.Partition)
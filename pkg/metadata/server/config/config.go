@@ -26,6 +26,7 @@ const (
 	defaultEtcdConnectTimeoutSeconds = 300
 	defaultEtcdRequestTimeoutSeconds = 1
 	defaultEtcdDialTimeoutSeconds    = 1
+	defaultMetricsAddress            = ""
 )
 
 var (
@@ -49,6 +50,9 @@ type Config struct {
 	// The value of a one-time-use token that can be used to bootstrap a
 	// runmachine deployment with a new partition by an unauthenticated user
 	BootstrapToken string
+	// Address (host:port) of the HTTP listener serving Prometheus metrics at
+	// /metrics. Metrics are not served if empty.
+	MetricsAddress string
 }
 
 func ConfigFromOpts() *Config {
@@ -146,6 +150,14 @@ func ConfigFromOpts() *Config {
 		"Value of the one-time-use bootstrap token to create on startup. "+
 			"The default is empty string, which means that no bootstrap token will be created.",
 	)
+	optMetricsAddress := flag.String(
+		"metrics-address",
+		envutil.WithDefault(
+			"RUNM_METADATA_METRICS_ADDRESS", defaultMetricsAddress,
+		),
+		"Address (host:port) to serve Prometheus metrics on. Metrics are "+
+			"not served if empty",
+	)
 
 	flag.Parse()
 
@@ -162,6 +174,7 @@ func ConfigFromOpts() *Config {
 		EtcdRequestTimeoutSeconds: time.Duration(*optRequestTimeout) * time.Second,
 		EtcdDialTimeoutSeconds:    time.Duration(*optDialTimeout) * time.Second,
 		BootstrapToken:            *optBootstrapToken,
+		MetricsAddress:            *optMetricsAddress,
 	}
 }
 
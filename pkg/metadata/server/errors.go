@@ -1,6 +1,8 @@
 package server
 
 import (
+	"strings"
+
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -134,6 +136,24 @@ var (
 		codes.FailedPrecondition,
 		"failed to delete object definition (check response errors collection).",
 	)
+	ErrObjectConflict = status.Errorf(
+		codes.Aborted,
+		"object was changed concurrently. please retry.",
+	)
+	ErrObjectDefinitionConflict = status.Errorf(
+		codes.Aborted,
+		"object definition was changed concurrently. please retry.",
+	)
+	ErrRevisionRequired = status.Errorf(
+		codes.FailedPrecondition,
+		"revision is required.",
+	)
+	ErrObjectTooManyTagsAndProperties = status.Errorf(
+		codes.InvalidArgument,
+		"object has too many tags and properties. an object may have at "+
+			"most 126 tags and properties combined, and at most 126 may be "+
+			"added or removed in a single update.",
+	)
 )
 
 func errPartitionNotFound(partition string) error {
@@ -150,6 +170,28 @@ func errObjectTypeNotFound(objectType string) error {
 	)
 }
 
+func errObjectPropertiesInvalid(objectType string, errs []string) error {
+	return status.Errorf(
+		codes.FailedPrecondition,
+		"Properties not valid for object type %s:\n- %s",
+		objectType, strings.Join(errs, "\n- "),
+	)
+}
+
+func errObjectDefinitionRevisionNotFound(revision uint64) error {
+	return status.Errorf(
+		codes.FailedPrecondition,
+		"Object definition revision %d not found", revision,
+	)
+}
+
+func errPropertyFilterInvalid(err error) error {
+	return status.Errorf(
+		codes.InvalidArgument,
+		"Invalid property filter: %s", err,
+	)
+}
+
 func errProviderTypeNotFound(providerType string) error {
 	return status.Errorf(
 		codes.FailedPrecondition,
@@ -0,0 +1,10 @@
+package server
+
+import (
+	"context"
+)
+
+// HealthCheck returns nil if the metadata service is able to reach etcd
+func (s *Server) HealthCheck(ctx context.Context) error {
+	return s.store.Ping(ctx)
+}
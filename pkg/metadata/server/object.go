@@ -3,19 +3,28 @@ package server
 import (
 	"context"
 
+	"google.golang.org/grpc/metadata"
+
 	"github.com/runmachine-io/runmachine/pkg/errors"
 	"github.com/runmachine-io/runmachine/pkg/metadata/conditions"
 	"github.com/runmachine-io/runmachine/pkg/metadata/types"
 	pb "github.com/runmachine-io/runmachine/proto"
 )
 
+const (
+	// The response header containing the query plans chosen for an
+	// ObjectFindRequest with Explain set. Headers with a "-bin" suffix are
+	// base64-encoded on the wire by gRPC, so plans may contain any characters.
+	queryPlanHeader = "runm-query-plan-bin"
+)
+
 // ObjectDeleteByUuids accepts a payload with one or more UUIDs and deletes the
 // objects with those UUIDs, returning the number of objects that were deleted
 func (s *Server) ObjectDeleteByUuids(
 	ctx context.Context,
 	req *pb.ObjectDeleteByUuidsRequest,
 ) (*pb.DeleteResponse, error) {
-	if err := s.checkSession(req.Session); err != nil {
+	if err := s.checkSession(ctx, req.Session); err != nil {
 		return nil, err
 	}
 	if len(req.Uuids) == 0 {
@@ -26,7 +35,7 @@ func (s *Server) ObjectDeleteByUuids(
 		UuidsCondition: conditions.UuidIn(req.Uuids),
 	}
 	owrs, err := s.store.ObjectFindWithReferences(
-		[]*conditions.ObjectCondition{cond},
+		ctx, []*conditions.ObjectCondition{cond},
 	)
 	if err != nil {
 		return nil, err
@@ -34,11 +43,14 @@ func (s *Server) ObjectDeleteByUuids(
 
 	numDeleted := uint64(0)
 	for _, owr := range owrs {
-		if err = s.store.ObjectDelete(owr); err != nil {
+		if err = s.store.ObjectDelete(ctx, owr); err != nil {
+			if err == errors.ErrGenerationConflict {
+				return nil, ErrObjectConflict
+			}
 			return nil, err
 		}
 		// TODO(jaypipes): Send an event notification
-		s.log.L1(
+		s.log.ForContext(ctx).L1(
 			"user %s deleted object with UUID %s",
 			req.Session.User,
 			owr.Object.Uuid,
@@ -54,7 +66,7 @@ func (s *Server) ObjectGetByUuid(
 	ctx context.Context,
 	req *pb.ObjectGetByUuidRequest,
 ) (*pb.Object, error) {
-	if err := s.checkSession(req.Session); err != nil {
+	if err := s.checkSession(ctx, req.Session); err != nil {
 		return nil, err
 	}
 	uuid := req.Uuid
@@ -62,12 +74,12 @@ func (s *Server) ObjectGetByUuid(
 		return nil, ErrUuidRequired
 	}
 
-	obj, err := s.store.ObjectGetByUuid(uuid)
+	obj, err := s.store.ObjectGetByUuid(ctx, uuid)
 	if err != nil {
 		return nil, err
 	}
 
-	if err = s.checkObjectOwnership(obj, req.Session); err != nil {
+	if err = s.checkObjectOwnership(ctx, obj, req.Session); err != nil {
 		return nil, err
 	}
 
@@ -75,6 +87,7 @@ func (s *Server) ObjectGetByUuid(
 }
 
 func (s *Server) checkObjectOwnership(
+	ctx context.Context,
 	obj *pb.Object,
 	sess *pb.Session,
 ) error {
@@ -83,17 +96,17 @@ func (s *Server) checkObjectOwnership(
 	// TODO(jaypipes): Allow not checking this if the user is in a specific
 	// role -- i.e. an admin?
 	if obj.Partition != sess.Partition {
-		s.log.L3(
+		s.log.ForContext(ctx).L3(
 			"found object with UUID '%s' but its partition '%s' did not "+
 				"match user's Session partition '%s'",
 			obj.Uuid, obj.Partition, sess.Partition,
 		)
 		return ErrNotFound
 	}
-	objTypeScope := s.objectTypes.ScopeOf(obj.ObjectType)
+	objTypeScope := s.objectTypes.ScopeOf(ctx, obj.ObjectType)
 	if objTypeScope == pb.ObjectTypeScope_PROJECT &&
 		obj.Project != sess.Project {
-		s.log.L3(
+		s.log.ForContext(ctx).L3(
 			"found object with UUID '%s' but its project '%s' did not "+
 				"match user's Session project '%s'",
 			obj.Uuid, obj.Project, sess.Project,
@@ -108,7 +121,7 @@ func (s *Server) ObjectGetByName(
 	req *pb.ObjectGetByNameRequest,
 ) (*pb.Object, error) {
 	var err error
-	if err = s.checkSession(req.Session); err != nil {
+	if err = s.checkSession(ctx, req.Session); err != nil {
 		return nil, err
 	}
 
@@ -122,7 +135,7 @@ func (s *Server) ObjectGetByName(
 	if objTypeCode == "" {
 		return nil, ErrObjectTypeCodeRequired
 	} else {
-		objType, err := s.store.ObjectTypeGetByCode(objTypeCode)
+		objType, err := s.store.ObjectTypeGetByCode(ctx, objTypeCode)
 		if err != nil {
 			if err == errors.ErrNotFound {
 				return nil, errObjectTypeNotFound(objTypeCode)
@@ -139,7 +152,7 @@ func (s *Server) ObjectGetByName(
 		// request Session.Partition is a valid partition UUID.
 		partUuid = req.Session.Partition
 	} else {
-		_, err = s.store.PartitionGetByUuid(partUuid)
+		_, err = s.store.PartitionGetByUuid(ctx, partUuid)
 		if err != nil {
 			if err == errors.ErrNotFound {
 				return nil, errPartitionNotFound(partUuid)
@@ -158,6 +171,7 @@ func (s *Server) ObjectGetByName(
 	var objs []*pb.Object
 	if objScope == pb.ObjectTypeScope_PROJECT {
 		objs, err = s.store.ObjectsGetByProjectNameIndex(
+			ctx,
 			partUuid,
 			objTypeCode,
 			project,
@@ -166,6 +180,7 @@ func (s *Server) ObjectGetByName(
 		)
 	} else {
 		objs, err = s.store.ObjectsGetByNameIndex(
+			ctx,
 			partUuid,
 			objTypeCode,
 			name,
@@ -184,7 +199,7 @@ func (s *Server) ObjectGetByName(
 
 	obj := objs[0]
 
-	if err = s.checkObjectOwnership(obj, req.Session); err != nil {
+	if err = s.checkObjectOwnership(ctx, obj, req.Session); err != nil {
 		return nil, err
 	}
 
@@ -195,30 +210,53 @@ func (s *Server) ObjectFind(
 	req *pb.ObjectFindRequest,
 	stream pb.RunmMetadata_ObjectFindServer,
 ) error {
-	if err := s.checkSession(req.Session); err != nil {
+	ctx := stream.Context()
+	if err := s.checkSession(ctx, req.Session); err != nil {
+		return err
+	}
+
+	filters, err := s.normalizeObjectFilters(ctx, req.Session, req.Any)
+	if err != nil {
 		return err
 	}
 
-	filters, err := s.normalizeObjectFilters(req.Session, req.Any)
+	plans, err := s.store.PlanObjectFind(ctx, filters)
 	if err != nil {
 		return err
 	}
+	if req.Explain {
+		explain := make([]string, len(plans))
+		for x, plan := range plans {
+			explain[x] = plan.String()
+			s.log.ForContext(ctx).L2("object find plan: %s", explain[x])
+		}
+		md := metadata.MD{queryPlanHeader: explain}
+		if err = stream.SetHeader(md); err != nil {
+			return err
+		}
+	}
 
-	objects, err := s.store.ObjectFind(filters)
+	cur, err := s.store.ExecuteObjectFind(ctx, plans)
 	if err != nil {
 		return err
 	}
-	for _, obj := range objects {
+	defer cur.Close()
+	for cur.Next() {
+		obj := &pb.Object{}
+		if err = cur.Scan(obj); err != nil {
+			return err
+		}
 		if err = stream.Send(obj); err != nil {
 			return err
 		}
 	}
-	return nil
+	return cur.Err()
 }
 
 // validateObjectCreateRequest ensures that the data the user sent is valid and
 // all referenced projects, partitions, and object types are correct.
 func (s *Server) validateObjectCreateRequest(
+	ctx context.Context,
 	req *pb.ObjectCreateRequest,
 ) (*types.ObjectWithReferences, error) {
 	obj := req.Object
@@ -232,23 +270,23 @@ func (s *Server) validateObjectCreateRequest(
 	}
 
 	// Validate the referred to type, partition and project actually exist
-	part, err := s.store.PartitionGetByUuid(obj.Partition)
+	part, err := s.store.PartitionGetByUuid(ctx, obj.Partition)
 	if err != nil {
 		if err == errors.ErrNotFound {
 			return nil, errPartitionNotFound(obj.Partition)
 		}
 		// We don't want to leak internal implementation errors...
-		s.log.ERR("failed when validating partition in object set: %s", err)
+		s.log.ForContext(ctx).ERR("failed when validating partition in object set: %s", err)
 		return nil, errors.ErrUnknown
 	}
 
-	objType, err := s.store.ObjectTypeGetByCode(obj.ObjectType)
+	objType, err := s.store.ObjectTypeGetByCode(ctx, obj.ObjectType)
 	if err != nil {
 		if err == errors.ErrNotFound {
 			return nil, errObjectTypeNotFound(obj.ObjectType)
 		}
 		// We don't want to leak internal implementation errors...
-		s.log.ERR("failed when validating object type in object set: %s", err)
+		s.log.ForContext(ctx).ERR("failed when validating object type in object set: %s", err)
 		return nil, errors.ErrUnknown
 	}
 
@@ -271,26 +309,33 @@ func (s *Server) ObjectCreate(
 	ctx context.Context,
 	req *pb.ObjectCreateRequest,
 ) (*pb.ObjectCreateResponse, error) {
-	if err := s.checkSession(req.Session); err != nil {
+	if err := s.checkSession(ctx, req.Session); err != nil {
 		return nil, err
 	}
 	// TODO(jaypipes): AUTHZ check if user can write objects
 
-	input, err := s.validateObjectCreateRequest(req)
+	input, err := s.validateObjectCreateRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	err = s.validateObjectProperties(ctx, input, req.Subtype, true)
 	if err != nil {
 		return nil, err
 	}
-	s.log.L3(
+	s.log.ForContext(ctx).L3(
 		"creating new object of type %s in partition %s with name %s...",
 		input.ObjectType.Code,
 		input.Partition.Uuid,
 		input.Object.Name,
 	)
-	changed, err := s.store.ObjectCreate(input)
+	changed, err := s.store.ObjectCreate(ctx, input)
 	if err != nil {
+		if err == errors.ErrBadInput {
+			return nil, ErrObjectTooManyTagsAndProperties
+		}
 		return nil, err
 	}
-	s.log.L1(
+	s.log.ForContext(ctx).L1(
 		"created new object with UUID %s of type %s in partition %s with name %s",
 		changed.Object.Uuid,
 		input.ObjectType.Code,
@@ -302,3 +347,87 @@ func (s *Server) ObjectCreate(
 		Object: changed.Object,
 	}, nil
 }
+
+// ObjectUpdate replaces the properties and tags of an existing object with
+// those of the supplied object, after validating the new properties against
+// the object definition that governs the object
+func (s *Server) ObjectUpdate(
+	ctx context.Context,
+	req *pb.ObjectUpdateRequest,
+) (*pb.ObjectUpdateResponse, error) {
+	if err := s.checkSession(ctx, req.Session); err != nil {
+		return nil, err
+	}
+	// TODO(jaypipes): AUTHZ check if user can write objects
+
+	if req.Object == nil || req.Object.Uuid == "" {
+		return nil, ErrUuidRequired
+	}
+	obj, err := s.store.ObjectGetByUuid(ctx, req.Object.Uuid)
+	if err != nil {
+		if err == errors.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if err = s.checkObjectOwnership(ctx, obj, req.Session); err != nil {
+		return nil, err
+	}
+
+	part, err := s.store.PartitionGetByUuid(ctx, obj.Partition)
+	if err != nil {
+		// We don't want to leak internal implementation errors...
+		s.log.ForContext(ctx).ERR(
+			"failed getting partition %s of object %s: %s",
+			obj.Partition, obj.Uuid, err,
+		)
+		return nil, errors.ErrUnknown
+	}
+	objType, err := s.store.ObjectTypeGetByCode(ctx, obj.ObjectType)
+	if err != nil {
+		// We don't want to leak internal implementation errors...
+		s.log.ForContext(ctx).ERR(
+			"failed getting object type %s of object %s: %s",
+			obj.ObjectType, obj.Uuid, err,
+		)
+		return nil, errors.ErrUnknown
+	}
+
+	// The caller may supply the generation of the object it read so that its
+	// update fails rather than overwriting a concurrent change
+	if req.Object.Generation != 0 {
+		obj.Generation = req.Object.Generation
+	}
+	obj.Properties = req.Object.Properties
+	obj.Tags = req.Object.Tags
+	input := &types.ObjectWithReferences{
+		Partition:  part,
+		ObjectType: objType,
+		Object:     obj,
+	}
+	err = s.validateObjectProperties(ctx, input, req.Subtype, false)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := s.store.ObjectUpdate(ctx, input)
+	if err != nil {
+		switch err {
+		case errors.ErrGenerationConflict:
+			return nil, ErrObjectConflict
+		case errors.ErrBadInput:
+			return nil, ErrObjectTooManyTagsAndProperties
+		}
+		return nil, err
+	}
+	s.log.ForContext(ctx).L1(
+		"user %s updated object with UUID %s of type %s in partition %s",
+		req.Session.User,
+		obj.Uuid,
+		objType.Code,
+		part.Uuid,
+	)
+
+	return &pb.ObjectUpdateResponse{
+		Object: changed.Object,
+	}, nil
+}
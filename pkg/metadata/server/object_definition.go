@@ -0,0 +1,369 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	apitypes "github.com/runmachine-io/runmachine/pkg/api/types"
+	"github.com/runmachine-io/runmachine/pkg/errors"
+	"github.com/runmachine-io/runmachine/pkg/metadata/types"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// validateObjectDefinitionKey ensures that the object type, partition and
+// subtype an object definition is being looked up or set for all exist
+func (s *Server) validateObjectDefinitionKey(
+	ctx context.Context,
+	objTypeCode string,
+	partUuid string,
+	subtype string,
+) error {
+	if objTypeCode == "" {
+		return ErrObjectTypeCodeRequired
+	}
+	// Validate the referred to object type actually exists
+	_, err := s.store.ObjectTypeGetByCode(ctx, objTypeCode)
+	if err != nil {
+		if err == errors.ErrNotFound {
+			return errObjectTypeNotFound(objTypeCode)
+		}
+		// We don't want to leak internal implementation errors...
+		s.log.ForContext(ctx).ERR(
+			"failed validating object type in object definition: %s",
+			err,
+		)
+		return errors.ErrUnknown
+	}
+	if partUuid != "" {
+		// Validate the referred to partition actually exists
+		// TODO(jaypipes): AUTHZ check user can specify partition
+		_, err := s.store.PartitionGetByUuid(ctx, partUuid)
+		if err != nil {
+			if err == errors.ErrNotFound {
+				return errPartitionNotFound(partUuid)
+			}
+			// We don't want to leak internal implementation errors...
+			s.log.ForContext(ctx).ERR(
+				"failed validating partition in object definition: %s",
+				err,
+			)
+			return errors.ErrUnknown
+		}
+	}
+	if subtype != "" && objTypeCode == "runm.provider" {
+		// The subtypes of providers are provider types, so validate the
+		// referred to provider type actually exists
+		_, err := s.store.ProviderTypeGetByCode(ctx, subtype)
+		if err != nil {
+			if err == errors.ErrNotFound {
+				return errProviderTypeNotFound(subtype)
+			}
+			// We don't want to leak internal implementation errors...
+			s.log.ForContext(ctx).ERR(
+				"failed validating provider type in object definition: %s",
+				err,
+			)
+			return errors.ErrUnknown
+		}
+	}
+	return nil
+}
+
+// ObjectDefinitionGet looks up the object definition for an object type and
+// optional partition and object subtype
+func (s *Server) ObjectDefinitionGet(
+	ctx context.Context,
+	req *pb.ObjectDefinitionGetRequest,
+) (*pb.ObjectDefinition, error) {
+	if err := s.checkSession(ctx, req.Session); err != nil {
+		return nil, err
+	}
+
+	err := s.validateObjectDefinitionKey(
+		ctx, req.ObjectType, req.PartitionUuid, req.Subtype,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var def *pb.ObjectDefinition
+	if req.Resolve {
+		def, err = s.store.ObjectDefinitionGetMostExplicit(
+			ctx, req.ObjectType, req.PartitionUuid, req.Subtype,
+		)
+	} else {
+		def, err = s.store.ObjectDefinitionGet(
+			ctx, req.ObjectType, req.PartitionUuid, req.Subtype,
+		)
+	}
+	if err != nil {
+		if err == errors.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return def, nil
+}
+
+// ObjectDefinitionSet receives an object definition to create or update and
+// saves the object definition in backend storage
+func (s *Server) ObjectDefinitionSet(
+	ctx context.Context,
+	req *pb.ObjectDefinitionSetRequest,
+) (*pb.ObjectDefinitionSetResponse, error) {
+	if err := s.checkSession(ctx, req.Session); err != nil {
+		return nil, err
+	}
+
+	// TODO(jaypipes): AUTHZ check for writing object definitions
+
+	objType := req.ObjectType
+	partUuid := req.PartitionUuid
+	subtype := req.Subtype
+	err := s.validateObjectDefinitionKey(ctx, objType, partUuid, subtype)
+	if err != nil {
+		return nil, err
+	}
+
+	def := req.ObjectDefinition
+	def.RollbackOf = 0
+	return s.objectDefinitionSave(
+		ctx, req.Session, objType, partUuid, subtype, def,
+	)
+}
+
+// objectDefinitionKeyString returns a string describing the object type,
+// partition and subtype an object definition applies to, for use in log
+// messages
+func objectDefinitionKeyString(
+	objType string,
+	partUuid string,
+	subtype string,
+) string {
+	pk := objType + ":" + partUuid
+	if partUuid == "" {
+		pk += "default"
+	}
+	if subtype != "" {
+		pk += ":" + subtype
+	}
+	return pk
+}
+
+// objectDefinitionSave saves the supplied object definition as a new revision
+// of the object definition for the object type, partition and subtype
+func (s *Server) objectDefinitionSave(
+	ctx context.Context,
+	sess *pb.Session,
+	objType string,
+	partUuid string,
+	subtype string,
+	def *pb.ObjectDefinition,
+) (*pb.ObjectDefinitionSetResponse, error) {
+	pk := objectDefinitionKeyString(objType, partUuid, subtype)
+
+	def.Author = sess.User
+	def.CreatedAt = time.Now().Unix()
+	err := s.store.ObjectDefinitionSet(ctx, objType, partUuid, subtype, def)
+	if err != nil {
+		if err == errors.ErrGenerationConflict {
+			return nil, ErrObjectDefinitionConflict
+		}
+		s.log.ForContext(ctx).ERR(
+			"Failed saving object definition '%s': %s", pk, err,
+		)
+		// NOTE(jaypipes): don't return internal errors
+		return nil, ErrUnknown
+	}
+	if def.Revision == 1 {
+		s.log.ForContext(ctx).L1("created new object definition '%s'", pk)
+	} else if def.RollbackOf != 0 {
+		s.log.ForContext(ctx).L1(
+			"rolled back object definition '%s' to revision %d "+
+				"(new revision %d)",
+			pk, def.RollbackOf, def.Revision,
+		)
+	} else {
+		s.log.ForContext(ctx).L1(
+			"updated object definition '%s' (revision %d)", pk, def.Revision,
+		)
+	}
+
+	resp := &pb.ObjectDefinitionSetResponse{
+		ObjectDefinition: def,
+	}
+	return resp, nil
+}
+
+// ObjectDefinitionHistory streams every revision of the object definition for
+// an object type and optional partition and object subtype, oldest first
+func (s *Server) ObjectDefinitionHistory(
+	req *pb.ObjectDefinitionHistoryRequest,
+	stream pb.RunmMetadata_ObjectDefinitionHistoryServer,
+) error {
+	ctx := stream.Context()
+	if err := s.checkSession(ctx, req.Session); err != nil {
+		return err
+	}
+
+	err := s.validateObjectDefinitionKey(
+		ctx, req.ObjectType, req.PartitionUuid, req.Subtype,
+	)
+	if err != nil {
+		return err
+	}
+
+	defs, err := s.store.ObjectDefinitionHistory(
+		ctx, req.ObjectType, req.PartitionUuid, req.Subtype,
+	)
+	if err != nil {
+		s.log.ForContext(ctx).ERR(
+			"Failed listing history of object definition '%s': %s",
+			objectDefinitionKeyString(
+				req.ObjectType, req.PartitionUuid, req.Subtype,
+			),
+			err,
+		)
+		return ErrUnknown
+	}
+	if len(defs) == 0 {
+		return ErrNotFound
+	}
+	for _, def := range defs {
+		if err = stream.Send(def); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ObjectDefinitionRollback sets the object definition for an object type and
+// optional partition and object subtype back to an earlier revision. The
+// earlier revision's schema and property permissions are saved as a new
+// revision of the object definition.
+func (s *Server) ObjectDefinitionRollback(
+	ctx context.Context,
+	req *pb.ObjectDefinitionRollbackRequest,
+) (*pb.ObjectDefinitionSetResponse, error) {
+	if err := s.checkSession(ctx, req.Session); err != nil {
+		return nil, err
+	}
+
+	// TODO(jaypipes): AUTHZ check for writing object definitions
+
+	objType := req.ObjectType
+	partUuid := req.PartitionUuid
+	subtype := req.Subtype
+	err := s.validateObjectDefinitionKey(ctx, objType, partUuid, subtype)
+	if err != nil {
+		return nil, err
+	}
+	if req.Revision == 0 {
+		return nil, ErrRevisionRequired
+	}
+
+	target, err := s.store.ObjectDefinitionGetRevision(
+		ctx, objType, partUuid, subtype, req.Revision,
+	)
+	if err != nil {
+		if err == errors.ErrNotFound {
+			return nil, errObjectDefinitionRevisionNotFound(req.Revision)
+		}
+		s.log.ForContext(ctx).ERR(
+			"Failed getting revision %d of object definition '%s': %s",
+			req.Revision,
+			objectDefinitionKeyString(objType, partUuid, subtype),
+			err,
+		)
+		return nil, ErrUnknown
+	}
+
+	def := &pb.ObjectDefinition{
+		Schema:              target.Schema,
+		PropertyPermissions: target.PropertyPermissions,
+		RollbackOf:          req.Revision,
+	}
+	return s.objectDefinitionSave(
+		ctx, req.Session, objType, partUuid, subtype, def,
+	)
+}
+
+// validateObjectProperties validates the supplied object's properties against
+// the property schema in the object definition that governs the object. If no
+// object definition governs the object, the object's properties are not
+// constrained. If applyDefaults is true, any property with a default value in
+// the object definition that isn't set on the object is first set to its
+// default value.
+func (s *Server) validateObjectProperties(
+	ctx context.Context,
+	owr *types.ObjectWithReferences,
+	subtype string,
+	applyDefaults bool,
+) error {
+	obj := owr.Object
+	def, err := s.store.ObjectDefinitionGetMostExplicit(
+		ctx, obj.ObjectType, obj.Partition, subtype,
+	)
+	if err != nil {
+		if err == errors.ErrNotFound {
+			return nil
+		}
+		s.log.ForContext(ctx).ERR(
+			"failed looking up object definition for object type %s: %s",
+			obj.ObjectType, err,
+		)
+		return ErrUnknown
+	}
+
+	if applyDefaults {
+		if err = applyPropertyDefaults(def.Schema, obj); err != nil {
+			s.log.ForContext(ctx).ERR(
+				"failed applying default property values from object "+
+					"definition %s: %s",
+				def.Uuid, err,
+			)
+			return ErrUnknown
+		}
+	}
+
+	msgs, err := apitypes.PropertiesErrors(def.Schema, obj.Properties)
+	if err != nil {
+		s.log.ForContext(ctx).ERR(
+			"failed validating object against object definition %s: %s",
+			def.Uuid, err,
+		)
+		return ErrUnknown
+	}
+	if len(msgs) > 0 {
+		return errObjectPropertiesInvalid(obj.ObjectType, msgs)
+	}
+	return nil
+}
+
+// applyPropertyDefaults sets any property that has a default value in the
+// supplied object definition schema document and is not set on the supplied
+// object to its default value
+func applyPropertyDefaults(schemaDoc string, obj *pb.Object) error {
+	defaults, err := apitypes.PropertyDefaults(schemaDoc)
+	if err != nil {
+		return err
+	}
+	if len(defaults) == 0 {
+		return nil
+	}
+	for _, prop := range obj.Properties {
+		delete(defaults, prop.Key)
+	}
+	added := make([]*pb.Property, 0, len(defaults))
+	for key, value := range defaults {
+		prop, err := apitypes.NewProperty(key, value)
+		if err != nil {
+			return err
+		}
+		added = append(added, prop)
+	}
+	apitypes.SortProperties(added)
+	obj.Properties = append(obj.Properties, added...)
+	return nil
+}
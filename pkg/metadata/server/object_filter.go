@@ -1,6 +1,12 @@
 package server
 
 import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	apitypes "github.com/runmachine-io/runmachine/pkg/api/types"
 	"github.com/runmachine-io/runmachine/pkg/errors"
 	"github.com/runmachine-io/runmachine/pkg/metadata/conditions"
 	pb "github.com/runmachine-io/runmachine/proto"
@@ -10,14 +16,15 @@ import (
 // didn't specify any filters themselves. This filter will be across the
 // partition that the user's session is on.
 func (s *Server) defaultObjectFilter(
+	ctx context.Context,
 	session *pb.Session,
 ) (*conditions.ObjectCondition, error) {
-	p, err := s.store.PartitionGetByUuid(session.Partition)
+	p, err := s.store.PartitionGetByUuid(ctx, session.Partition)
 	if err != nil {
 		if err == errors.ErrNotFound {
 			// Just return nil since clearly we can have no
 			// property schemas matching an unknown partition
-			s.log.L3(
+			s.log.ForContext(ctx).L3(
 				"'%s' listed objects with no filters "+
 					"and supplied unknown partition '%s' in the session",
 				session.User,
@@ -38,6 +45,7 @@ func (s *Server) defaultObjectFilter(
 // objects. A types.ObjectCondition is used to describe a filter on objects in
 // a *specific* partition and having a *specific* object type.
 func (s *Server) expandObjectFilter(
+	ctx context.Context,
 	session *pb.Session,
 	filter *pb.ObjectFilter,
 ) ([]*conditions.ObjectCondition, error) {
@@ -63,7 +71,7 @@ func (s *Server) expandObjectFilter(
 				},
 			}
 		}
-		partitions, err = s.store.PartitionFind(pfils)
+		partitions, err = s.store.PartitionFind(ctx, pfils)
 		if err != nil {
 			return nil, err
 		}
@@ -73,12 +81,12 @@ func (s *Server) expandObjectFilter(
 	} else {
 		// By default, filter by the session's partition if the user didn't
 		// specify any filtering.
-		part, err := s.store.PartitionGetByUuid(session.Partition)
+		part, err := s.store.PartitionGetByUuid(ctx, session.Partition)
 		if err != nil {
 			if err == errors.ErrNotFound {
 				// Just return nil since clearly we can have no
 				// property schemas matching an unknown partition
-				s.log.L3(
+				s.log.ForContext(ctx).L3(
 					"'%s' listed objects with no partition filters "+
 						"and supplied unknown partition '%s' in the session",
 					session.User,
@@ -93,7 +101,7 @@ func (s *Server) expandObjectFilter(
 	if filter.ObjectTypeFilter != nil {
 		// Verify that the object type even exists
 		objTypes, err = s.store.ObjectTypeFind(
-			[]*pb.ObjectTypeFilter{filter.ObjectTypeFilter},
+			ctx, []*pb.ObjectTypeFilter{filter.ObjectTypeFilter},
 		)
 		if err != nil {
 			return nil, err
@@ -105,7 +113,11 @@ func (s *Server) expandObjectFilter(
 
 	// Default the object list to filtering by the session's project if the
 	// user didn't specify a specific project to filter on
-	if filter.Project == "" {
+	if filter.AnyProject {
+		// TODO(jaypipes): Determine if the user has the ability to list
+		// objects in other projects...
+		filter.Project = ""
+	} else if filter.Project == "" {
 		filter.Project = session.Project
 	} else {
 		// TODO(jaypipes): Determine if the user has the ability to list
@@ -147,7 +159,7 @@ func (s *Server) expandObjectFilter(
 	// partition filters, then go ahead and just return a single
 	// types.ObjectCondition with the search term and prefix indicator for the
 	// object.
-	if filter.NameFilter != nil || filter.UuidFilter != nil || filter.Project != "" || filter.PropertyFilter != nil {
+	if filter.NameFilter != nil || filter.UuidFilter != nil || filter.Project != "" || filter.PropertyFilter != nil || filter.TagFilter != nil {
 		if len(res) == 0 {
 			res = append(res, &conditions.ObjectCondition{})
 		}
@@ -170,9 +182,21 @@ func (s *Server) expandObjectFilter(
 				}
 			}
 			if filter.PropertyFilter != nil {
-				pf.PropertyCondition = conditions.PropertyConditionFromFilter(
+				pc, err := conditions.PropertyConditionFromFilter(
 					filter.PropertyFilter,
 				)
+				if err != nil {
+					return nil, errPropertyFilterInvalid(err)
+				}
+				if err = s.resolveNumericProperties(ctx, pf, pc); err != nil {
+					return nil, err
+				}
+				pf.PropertyCondition = pc
+			}
+			if filter.TagFilter != nil {
+				pf.TagCondition = conditions.TagConditionFromFilter(
+					filter.TagFilter,
+				)
 			}
 			pf.ProjectCondition = filter.Project
 		}
@@ -180,6 +204,44 @@ func (s *Server) expandObjectFilter(
 	return res, nil
 }
 
+// resolveNumericProperties marks the property expressions in the supplied
+// property condition that compare properties the object definition governing
+// the condition's partition and object type defines as integers or numbers,
+// so that those properties are compared numerically
+func (s *Server) resolveNumericProperties(
+	ctx context.Context,
+	cond *conditions.ObjectCondition,
+	pc *conditions.PropertyCondition,
+) error {
+	if len(pc.Expressions) == 0 || cond.PartitionCondition == nil ||
+		cond.ObjectTypeCondition == nil {
+		return nil
+	}
+	// TODO(jaypipes): Object subtypes are not known to the metadata service,
+	// so properties defined as numeric only by a subtype's object definition
+	// are compared numerically only when they have a typed value
+	def, err := s.store.ObjectDefinitionGetMostExplicit(
+		ctx,
+		cond.ObjectTypeCondition.ObjectType.Code,
+		cond.PartitionCondition.Partition.Uuid,
+		"",
+	)
+	if err != nil {
+		if err == errors.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	numeric, err := apitypes.NumericProperties(def.Schema)
+	if err != nil {
+		return err
+	}
+	for _, expr := range pc.Expressions {
+		expr.Numeric = numeric[expr.Key]
+	}
+	return nil
+}
+
 // normalizeObjectFilters is passed a Session object and a slice of
 // ObjectFilter messages. It then expands those supplied ObjectFilter messages
 // if they contain partition or object type filters that have a prefix. If no
@@ -187,19 +249,24 @@ func (s *Server) expandObjectFilter(
 // types.ObjectCondition which will return all objects for the Session's partition
 // and project.
 func (s *Server) normalizeObjectFilters(
+	ctx context.Context,
 	session *pb.Session,
 	any []*pb.ObjectFilter,
 ) ([]*conditions.ObjectCondition, error) {
 	res := make([]*conditions.ObjectCondition, 0)
 	for _, filter := range any {
-		if pfs, err := s.expandObjectFilter(session, filter); err != nil {
+		if pfs, err := s.expandObjectFilter(ctx, session, filter); err != nil {
 			if err == errors.ErrNotFound {
 				// Just continue since clearly we can have no objects matching
 				// an unknown partition but we need to OR together all filters,
 				// which is why we don't just return nil here
 				continue
 			}
-			s.log.ERR(
+			if st, ok := status.FromError(err); ok &&
+				st.Code() == codes.InvalidArgument {
+				return nil, err
+			}
+			s.log.ForContext(ctx).ERR(
 				"normalizeObjectFilters: failed to expand object filter %s: %s",
 				filter, err,
 			)
@@ -214,7 +281,7 @@ func (s *Server) normalizeObjectFilters(
 	if len(res) == 0 {
 		if len(any) == 0 {
 			// At least one filter should have been expanded
-			defFilter, err := s.defaultObjectFilter(session)
+			defFilter, err := s.defaultObjectFilter(ctx, session)
 			if err != nil {
 				return nil, ErrFailedExpandObjectFilters
 			}
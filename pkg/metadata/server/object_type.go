@@ -13,21 +13,21 @@ func (s *Server) ObjectTypeGetByCode(
 	ctx context.Context,
 	req *pb.ObjectTypeGetByCodeRequest,
 ) (*pb.ObjectType, error) {
-	if err := s.checkSession(req.Session); err != nil {
+	if err := s.checkSession(ctx, req.Session); err != nil {
 		return nil, err
 	}
 	code := req.Code
 	if code == "" {
 		return nil, ErrCodeRequired
 	}
-	obj, err := s.store.ObjectTypeGetByCode(code)
+	obj, err := s.store.ObjectTypeGetByCode(ctx, code)
 	if err != nil {
 		if err == errors.ErrNotFound {
 			return nil, ErrNotFound
 		}
 		// We don't want to expose internal errors to the user, so just return
 		// an unknown error after logging it.
-		s.log.ERR(
+		s.log.ForContext(ctx).ERR(
 			"failed to retrieve object type of %s: %s",
 			code, err,
 		)
@@ -42,10 +42,11 @@ func (s *Server) ObjectTypeFind(
 	req *pb.ObjectTypeFindRequest,
 	stream pb.RunmMetadata_ObjectTypeFindServer,
 ) error {
-	if err := s.checkSession(req.Session); err != nil {
+	ctx := stream.Context()
+	if err := s.checkSession(ctx, req.Session); err != nil {
 		return err
 	}
-	objs, err := s.store.ObjectTypeFind(req.Any)
+	objs, err := s.store.ObjectTypeFind(ctx, req.Any)
 	if err != nil {
 		return err
 	}
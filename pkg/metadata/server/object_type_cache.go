@@ -1,6 +1,9 @@
 package server
 
 import (
+	"context"
+	"sync/atomic"
+
 	"github.com/runmachine-io/runmachine/pkg/logging"
 	"github.com/runmachine-io/runmachine/pkg/metadata/server/storage"
 	pb "github.com/runmachine-io/runmachine/proto"
@@ -38,17 +41,17 @@ func NewObjectTypeCache(
 // Get returns the ObjectType protobuffer message for the supplied code,
 // retrieving the message from backend storage if not in the cache. Returns nil
 // if no such object type could be found.
-func (c *ObjectTypeCache) Get(code string) *pb.ObjectType {
+func (c *ObjectTypeCache) Get(ctx context.Context, code string) *pb.ObjectType {
 	obj, ok := c.cache[code]
 	if ok {
-		c.CountHit += 1
+		atomic.AddUint64(&c.CountHit, 1)
 		return obj
 	}
-	c.CountMiss += 1
+	atomic.AddUint64(&c.CountMiss, 1)
 	// Try looking up in backend storage and setting our cache entry if found
-	obj, err := c.store.ObjectTypeGetByCode(code)
+	obj, err := c.store.ObjectTypeGetByCode(ctx, code)
 	if err != nil {
-		c.log.ERR(
+		c.log.ForContext(ctx).ERR(
 			"failed to retrieve object type of %s: %s",
 			code, err,
 		)
@@ -58,14 +61,28 @@ func (c *ObjectTypeCache) Get(code string) *pb.ObjectType {
 	return obj
 }
 
+// Hits returns the number of lookups that found an entry in the cache
+func (c *ObjectTypeCache) Hits() uint64 {
+	return atomic.LoadUint64(&c.CountHit)
+}
+
+// Misses returns the number of lookups that did not find an entry in the
+// cache
+func (c *ObjectTypeCache) Misses() uint64 {
+	return atomic.LoadUint64(&c.CountMiss)
+}
+
 // ScopeOf returns the object type's scope given an object type code. This
 // method has no mechanism for returning any error state and is intended to be
 // used when the caller absolutely knows that the object type with the supplied
 // code actually exists. If the object type with that code does not exist or
 // there was some failure in retrieving the object type from backend storage,
 // the function returns ObjectTypeScope_PARTITION.
-func (c *ObjectTypeCache) ScopeOf(code string) pb.ObjectTypeScope {
-	ot := c.Get(code)
+func (c *ObjectTypeCache) ScopeOf(
+	ctx context.Context,
+	code string,
+) pb.ObjectTypeScope {
+	ot := c.Get(ctx, code)
 	if ot == nil {
 		return pb.ObjectTypeScope_PARTITION
 	}
@@ -14,21 +14,21 @@ func (s *Server) PartitionGetByUuid(
 	ctx context.Context,
 	req *pb.PartitionGetByUuidRequest,
 ) (*pb.Partition, error) {
-	if err := s.checkSession(req.Session); err != nil {
+	if err := s.checkSession(ctx, req.Session); err != nil {
 		return nil, err
 	}
 	uuid := req.Uuid
 	if uuid == "" || !util.IsUuidLike(uuid) {
 		return nil, ErrUuidRequired
 	}
-	obj, err := s.store.PartitionGetByUuid(util.NormalizeUuid(uuid))
+	obj, err := s.store.PartitionGetByUuid(ctx, util.NormalizeUuid(uuid))
 	if err != nil {
 		if err == errors.ErrNotFound {
 			return nil, ErrNotFound
 		}
 		// We don't want to expose internal errors to the user, so just return
 		// an unknown error after logging it.
-		s.log.ERR(
+		s.log.ForContext(ctx).ERR(
 			"failed to retrieve partition with UUID '%s': %s",
 			uuid, err,
 		)
@@ -43,21 +43,21 @@ func (s *Server) PartitionGetByName(
 	ctx context.Context,
 	req *pb.PartitionGetByNameRequest,
 ) (*pb.Partition, error) {
-	if err := s.checkSession(req.Session); err != nil {
+	if err := s.checkSession(ctx, req.Session); err != nil {
 		return nil, err
 	}
 	name := req.Name
 	if name == "" {
 		return nil, ErrNameRequired
 	}
-	obj, err := s.store.PartitionGetByName(name)
+	obj, err := s.store.PartitionGetByName(ctx, name)
 	if err != nil {
 		if err == errors.ErrNotFound {
 			return nil, ErrNotFound
 		}
 		// We don't want to expose internal errors to the user, so just return
 		// an unknown error after logging it.
-		s.log.ERR(
+		s.log.ForContext(ctx).ERR(
 			"failed to retrieve partition with name '%s': %s",
 			name, err,
 		)
@@ -72,10 +72,11 @@ func (s *Server) PartitionFind(
 	req *pb.PartitionFindRequest,
 	stream pb.RunmMetadata_PartitionFindServer,
 ) error {
-	if err := s.checkSession(req.Session); err != nil {
+	ctx := stream.Context()
+	if err := s.checkSession(ctx, req.Session); err != nil {
 		return err
 	}
-	objs, err := s.store.PartitionFind(req.Any)
+	objs, err := s.store.PartitionFind(ctx, req.Any)
 	if err != nil {
 		return err
 	}
@@ -112,14 +113,14 @@ func (s *Server) PartitionCreate(
 	if err != nil {
 		return nil, err
 	}
-	changed, err := s.store.PartitionCreate(p)
+	changed, err := s.store.PartitionCreate(ctx, p)
 	if err != nil {
 		if err == errors.ErrDuplicate {
 			return nil, ErrDuplicate
 		}
 		return nil, err
 	}
-	s.log.L1(
+	s.log.ForContext(ctx).L1(
 		"created new partition with UUID %s and name %s",
 		changed.Uuid,
 		changed.Name,
@@ -13,21 +13,21 @@ func (s *Server) ProviderTypeGetByCode(
 	ctx context.Context,
 	req *pb.ProviderTypeGetByCodeRequest,
 ) (*pb.ProviderType, error) {
-	if err := s.checkSession(req.Session); err != nil {
+	if err := s.checkSession(ctx, req.Session); err != nil {
 		return nil, err
 	}
 	code := req.Code
 	if code == "" {
 		return nil, ErrCodeRequired
 	}
-	obj, err := s.store.ProviderTypeGetByCode(code)
+	obj, err := s.store.ProviderTypeGetByCode(ctx, code)
 	if err != nil {
 		if err == errors.ErrNotFound {
 			return nil, ErrNotFound
 		}
 		// We don't want to expose internal errors to the user, so just return
 		// an unknown error after logging it.
-		s.log.ERR(
+		s.log.ForContext(ctx).ERR(
 			"failed to retrieve provider type of %s: %s",
 			code, err,
 		)
@@ -42,10 +42,11 @@ func (s *Server) ProviderTypeFind(
 	req *pb.ProviderTypeFindRequest,
 	stream pb.RunmMetadata_ProviderTypeFindServer,
 ) error {
-	if err := s.checkSession(req.Session); err != nil {
+	ctx := stream.Context()
+	if err := s.checkSession(ctx, req.Session); err != nil {
 		return err
 	}
-	objs, err := s.store.ProviderTypeFind(req.Any)
+	objs, err := s.store.ProviderTypeFind(ctx, req.Any)
 	if err != nil {
 		return err
 	}
@@ -8,6 +8,8 @@ import (
 	"github.com/runmachine-io/runmachine/pkg/logging"
 	"github.com/runmachine-io/runmachine/pkg/metadata/server/config"
 	"github.com/runmachine-io/runmachine/pkg/metadata/server/storage"
+	"github.com/runmachine-io/runmachine/pkg/metrics"
+	"github.com/runmachine-io/runmachine/pkg/registry"
 )
 
 var (
@@ -17,7 +19,7 @@ var (
 type Server struct {
 	log         *logging.Logs
 	cfg         *config.Config
-	registry    *gsr.Registry
+	registry    registry.Registry
 	store       *storage.Store
 	objectTypes *ObjectTypeCache
 }
@@ -25,7 +27,7 @@ type Server struct {
 func (s *Server) Close() {
 	addr := fmt.Sprintf("%s:%d", s.cfg.BindHost, s.cfg.BindPort)
 	s.log.L1(
-		"unregistering %s:%s endpoint in gsr",
+		"unregistering %s:%s endpoint in service registry",
 		s.cfg.ServiceName,
 		addr,
 	)
@@ -42,14 +44,8 @@ func (s *Server) Close() {
 func New(
 	cfg *config.Config,
 	log *logging.Logs,
+	reg registry.Registry,
 ) (*Server, error) {
-	log.L3("connecting to gsr service registry.")
-	registry, err := gsr.New()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create gsr.Registry object: %v", err)
-	}
-	log.L2("connected to gsr service registry.")
-
 	store, err := storage.New(log, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to metadata storage: %v", err)
@@ -62,21 +58,24 @@ func New(
 		Service: &gsr.Service{Name: cfg.ServiceName},
 		Address: addr,
 	}
-	err = registry.Register(&ep)
+	err = reg.Register(&ep)
 	if err != nil {
-		return nil, fmt.Errorf("failed to register %v with gsr: %v", ep, err)
+		return nil, fmt.Errorf("failed to register %v with service registry: %v", ep, err)
 	}
 	log.L2(
-		"registered %s service endpoint running at %s with gsr.",
+		"registered %s service endpoint running at %s with service registry.",
 		cfg.ServiceName,
 		addr,
 	)
 
+	objectTypes := NewObjectTypeCache(log, store)
+	metrics.RegisterCache("object_type", objectTypes.Hits, objectTypes.Misses)
+
 	return &Server{
 		log:         log,
 		cfg:         cfg,
-		registry:    registry,
+		registry:    reg,
 		store:       store,
-		objectTypes: NewObjectTypeCache(log, store),
+		objectTypes: objectTypes,
 	}, nil
 }
@@ -0,0 +1,359 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/clientv3/concurrency"
+)
+
+const (
+	// $ROOT/version is a valued key containing the version of the layout of
+	// the keys and values under $ROOT
+	_VERSION_KEY = "version"
+	// $ROOT/migrate-lock/ is the key namespace of the lock held while
+	// migrating the keyspace
+	_MIGRATE_LOCK_KEY = "migrate-lock/"
+
+	// Maximum number of keys read from etcd at a time while migrating
+	_MIGRATE_PAGE_SIZE = 500
+	// Maximum number of operations in each transaction committed while
+	// migrating. Must be below etcd's --max-txn-ops (default 128).
+	_MIGRATE_BATCH_SIZE = 100
+	// Number of changed keys listed in a KeyspaceMigrationReport
+	_MIGRATE_REPORT_KEYS = 10
+	// TTL, in seconds, of the session holding the migration lock
+	_MIGRATE_LOCK_TTL = 30
+)
+
+// keyChange describes a change to a key under $ROOT
+type keyChange struct {
+	Key string
+	// The new value of the key. A nil Value deletes the key.
+	Value []byte
+}
+
+// keyspaceMigration transforms the keys and values under $ROOT from one
+// version of the keyspace layout to the next
+type keyspaceMigration struct {
+	description string
+	// Key namespace, relative to $ROOT, containing the keys the migration
+	// transforms
+	prefix string
+	// transform returns the changes to make for an existing key under prefix
+	// and its value. The key is relative to $ROOT.
+	//
+	// NOTE(jaypipes): A migration interrupted partway through is run again
+	// from the start, so transform must return changes that are safe to
+	// make more than once.
+	transform func(key string, value []byte) ([]keyChange, error)
+}
+
+var (
+	// The migrations between versions of the layout of keys and values under
+	// $ROOT. The migration at index N transforms the keyspace from version N
+	// to version N+1. Append a migration here whenever the layout changes in
+	// a way that leaves existing keys unreadable or unindexed.
+	_KEYSPACE_MIGRATIONS = []keyspaceMigration{
+		indexObjectsMigration,
+		historyObjectDefinitionsMigration,
+		historyPartitionObjectDefinitionsMigration,
+	}
+)
+
+// KeyspaceMigrationReport describes the changes made, or that would be made
+// in a dry run, by a keyspace migration
+type KeyspaceMigrationReport struct {
+	Version     int
+	Description string
+	// Number of keys passed to the migration's transform
+	Scanned int
+	// Number of keys created or updated
+	Puts int
+	// Number of keys deleted
+	Deletes int
+	// The first few changed keys
+	Keys []string
+}
+
+// LatestKeyspaceVersion returns the version of the keyspace layout this
+// runm-metadata uses
+func (s *Store) LatestKeyspaceVersion() int {
+	return len(s.migrations)
+}
+
+// KeyspaceVersion returns the version of the layout of the keys and values in
+// etcd. Keyspaces without a version record are version 0.
+func (s *Store) KeyspaceVersion(ctx context.Context) (int, error) {
+	version, _, err := s.keyspaceVersion(ctx)
+	return version, err
+}
+
+func (s *Store) keyspaceVersion(ctx context.Context) (int, bool, error) {
+	ctx, cancel := s.requestCtx(ctx)
+	defer cancel()
+	resp, err := s.kv.Get(ctx, _VERSION_KEY)
+	if err != nil {
+		return 0, false, err
+	}
+	if resp.Count == 0 {
+		return 0, false, nil
+	}
+	version, err := strconv.Atoi(string(resp.Kvs[0].Value))
+	if err != nil {
+		return 0, false, fmt.Errorf(
+			"invalid keyspace version %q: %s", resp.Kvs[0].Value, err,
+		)
+	}
+	return version, true, nil
+}
+
+// keyspaceEmpty returns true if there are no keys under $ROOT other than the
+// migration lock
+func (s *Store) keyspaceEmpty(ctx context.Context) (bool, error) {
+	ctx, cancel := s.requestCtx(ctx)
+	defer cancel()
+	// NOTE(jaypipes): etcd rejects an empty key, even with a prefix option,
+	// so $ROOT itself is counted using the non-namespaced client
+	root := s.cfg.EtcdKeyPrefix + _SERVICE_KEY
+	all, err := s.client.Get(ctx, root, etcd.WithPrefix(), etcd.WithCountOnly())
+	if err != nil {
+		return false, err
+	}
+	locks, err := s.kv.Get(
+		ctx, _MIGRATE_LOCK_KEY, etcd.WithPrefix(), etcd.WithCountOnly(),
+	)
+	if err != nil {
+		return false, err
+	}
+	return all.Count == locks.Count, nil
+}
+
+// setKeyspaceVersion changes the keyspace version from one version to
+// another. A from of -1 means there is no version record yet.
+func (s *Store) setKeyspaceVersion(
+	ctx context.Context,
+	from int,
+	to int,
+) error {
+	ctx, cancel := s.requestCtx(ctx)
+	defer cancel()
+	resp, err := s.kv.Txn(ctx).If(
+		s.keyspaceVersionIs(from),
+	).Then(
+		etcd.OpPut(_VERSION_KEY, strconv.Itoa(to)),
+	).Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return fmt.Errorf(
+			"keyspace version changed while setting it to %d", to,
+		)
+	}
+	return nil
+}
+
+// keyspaceVersionIs returns a comparison that succeeds if the keyspace is at
+// the supplied version. A version of -1 means there is no version record.
+func (s *Store) keyspaceVersionIs(version int) etcd.Cmp {
+	if version < 0 {
+		return etcd.Compare(etcd.CreateRevision(_VERSION_KEY), "=", 0)
+	}
+	return etcd.Compare(etcd.Value(_VERSION_KEY), "=", strconv.Itoa(version))
+}
+
+// lockKeyspace acquires the lock held while migrating the keyspace and
+// returns a function that releases it
+func (s *Store) lockKeyspace(ctx context.Context) (func(), error) {
+	session, err := concurrency.NewSession(
+		s.client,
+		concurrency.WithTTL(_MIGRATE_LOCK_TTL),
+		concurrency.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	mutex := concurrency.NewMutex(
+		session, s.cfg.EtcdKeyPrefix+_SERVICE_KEY+_MIGRATE_LOCK_KEY,
+	)
+	s.log.L3("acquiring keyspace migration lock...")
+	if err := mutex.Lock(ctx); err != nil {
+		session.Close()
+		return nil, err
+	}
+	s.log.L3("acquired keyspace migration lock")
+	return func() {
+		mutex.Unlock(context.Background())
+		session.Close()
+	}, nil
+}
+
+// MigrateKeyspace brings the layout of the keys and values in etcd up to the
+// latest version and returns a report for each migration run. If dryRun is
+// true, no changes are made and the reports describe the changes that would
+// be made. An empty keyspace is simply marked as being at the latest version.
+func (s *Store) MigrateKeyspace(
+	ctx context.Context,
+	dryRun bool,
+) ([]*KeyspaceMigrationReport, error) {
+	if !dryRun {
+		unlock, err := s.lockKeyspace(ctx)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to acquire keyspace migration lock: %s", err,
+			)
+		}
+		defer unlock()
+	}
+
+	latest := s.LatestKeyspaceVersion()
+	version, exists, err := s.keyspaceVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		empty, err := s.keyspaceEmpty(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if empty {
+			// A new deployment. There is nothing to migrate.
+			if dryRun {
+				return nil, nil
+			}
+			s.log.L2("initializing keyspace version to %d", latest)
+			return nil, s.setKeyspaceVersion(ctx, -1, latest)
+		}
+		if !dryRun {
+			s.log.L2("keyspace has no version record. assuming version 0")
+			if err := s.setKeyspaceVersion(ctx, -1, 0); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if version > latest {
+		return nil, fmt.Errorf(
+			"keyspace version %d is newer than latest known version %d. "+
+				"Is this runm-metadata older than the keyspace?",
+			version, latest,
+		)
+	}
+
+	reports := make([]*KeyspaceMigrationReport, 0, latest-version)
+	for v := version + 1; v <= latest; v++ {
+		rep, err := s.runKeyspaceMigration(ctx, v, dryRun)
+		if err != nil {
+			return reports, fmt.Errorf(
+				"failed migrating keyspace to version %d: %s", v, err,
+			)
+		}
+		reports = append(reports, rep)
+		if !dryRun {
+			if err := s.setKeyspaceVersion(ctx, v-1, v); err != nil {
+				return reports, err
+			}
+			s.log.L1(
+				"migrated keyspace to version %d (%s): %d puts, %d deletes",
+				v, rep.Description, rep.Puts, rep.Deletes,
+			)
+		}
+	}
+	return reports, nil
+}
+
+// runKeyspaceMigration transforms all keys under the prefix of the migration
+// to the supplied version, committing the changes in batched transactions
+// that only succeed if the keyspace is still at the previous version
+func (s *Store) runKeyspaceMigration(
+	ctx context.Context,
+	version int,
+	dryRun bool,
+) (*KeyspaceMigrationReport, error) {
+	mig := s.migrations[version-1]
+	rep := &KeyspaceMigrationReport{
+		Version:     version,
+		Description: mig.description,
+		Keys:        []string{},
+	}
+
+	ops := make([]etcd.Op, 0, _MIGRATE_BATCH_SIZE)
+	flush := func() error {
+		if dryRun || len(ops) == 0 {
+			ops = ops[:0]
+			return nil
+		}
+		ctx, cancel := s.requestCtx(ctx)
+		defer cancel()
+		resp, err := s.kv.Txn(ctx).If(
+			s.keyspaceVersionIs(version - 1),
+		).Then(ops...).Commit()
+		if err != nil {
+			return err
+		}
+		if !resp.Succeeded {
+			return fmt.Errorf("keyspace version changed during migration")
+		}
+		ops = ops[:0]
+		return nil
+	}
+
+	// Read all pages of keys at the same revision so that keys written by the
+	// migration itself are not transformed again
+	key := mig.prefix
+	end := etcd.GetPrefixRangeEnd(mig.prefix)
+	rev := int64(0)
+	for {
+		opts := []etcd.OpOption{
+			etcd.WithRange(end),
+			etcd.WithLimit(_MIGRATE_PAGE_SIZE),
+		}
+		if rev > 0 {
+			opts = append(opts, etcd.WithRev(rev))
+		}
+		rctx, cancel := s.requestCtx(ctx)
+		resp, err := s.kv.Get(rctx, key, opts...)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		if rev == 0 {
+			rev = resp.Header.Revision
+		}
+		for _, kv := range resp.Kvs {
+			rep.Scanned++
+			changes, err := mig.transform(string(kv.Key), kv.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed transforming %s: %s", kv.Key, err)
+			}
+			for _, c := range changes {
+				if c.Value == nil {
+					ops = append(ops, etcd.OpDelete(c.Key))
+					rep.Deletes++
+				} else {
+					ops = append(ops, etcd.OpPut(c.Key, string(c.Value)))
+					rep.Puts++
+				}
+				if len(rep.Keys) < _MIGRATE_REPORT_KEYS {
+					rep.Keys = append(rep.Keys, c.Key)
+				}
+				if len(ops) >= _MIGRATE_BATCH_SIZE {
+					if err := flush(); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+		if !resp.More || len(resp.Kvs) == 0 {
+			break
+		}
+		// Continue from just after the last key read
+		key = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return rep, nil
+}
@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/runmachine-io/runmachine/pkg/logging"
+	"github.com/runmachine-io/runmachine/pkg/metadata/server/config"
+	"github.com/runmachine-io/runmachine/pkg/testutil"
+)
+
+// startEtcd starts an embedded etcd server and returns a Config pointing at
+// it along with a function that stops the server
+func startEtcd(t *testing.T) (*config.Config, func()) {
+	endpoint, stop := testutil.StartEtcd(t)
+	cfg := &config.Config{
+		EtcdEndpoints:             []string{endpoint},
+		EtcdKeyPrefix:             "runm-metadata/",
+		EtcdConnectTimeoutSeconds: 10 * time.Second,
+		EtcdRequestTimeoutSeconds: 5 * time.Second,
+		EtcdDialTimeoutSeconds:    time.Second,
+	}
+	return cfg, stop
+}
+
+// newEtcdStore returns a Store connected to an embedded etcd server, without
+// migrating the keyspace, along with a function that closes the Store and
+// stops the etcd server
+func newEtcdStore(t *testing.T) (*Store, func()) {
+	cfg, stop := startEtcd(t)
+	s, err := Connect(logging.New(&logging.Config{}), cfg)
+	if err != nil {
+		stop()
+		t.Fatalf("failed to connect to etcd: %v", err)
+	}
+	return s, func() {
+		s.Close()
+		stop()
+	}
+}
+
+// renameMigration moves every key under "old/" to the same name under "new/"
+var renameMigration = keyspaceMigration{
+	description: "move old/ to new/",
+	prefix:      "old/",
+	transform: func(key string, value []byte) ([]keyChange, error) {
+		return []keyChange{
+			{Key: "new/" + strings.TrimPrefix(key, "old/"), Value: value},
+			{Key: key},
+		}, nil
+	},
+}
+
+// indexMigration adds a by-value/ index key for every key under "new/"
+var indexMigration = keyspaceMigration{
+	description: "index new/ by value",
+	prefix:      "new/",
+	transform: func(key string, value []byte) ([]keyChange, error) {
+		return []keyChange{
+			{
+				Key:   "by-value/" + string(value),
+				Value: []byte(strings.TrimPrefix(key, "new/")),
+			},
+		}, nil
+	},
+}
+
+func TestMigrateKeyspaceEmpty(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.TODO()
+
+	s, cleanup := newEtcdStore(t)
+	defer cleanup()
+	s.migrations = []keyspaceMigration{renameMigration, indexMigration}
+
+	reports, err := s.MigrateKeyspace(ctx, true)
+	assert.Nil(err)
+	assert.Empty(reports)
+
+	// A new keyspace starts at the latest version without running migrations
+	reports, err = s.MigrateKeyspace(ctx, false)
+	assert.Nil(err)
+	assert.Empty(reports)
+
+	version, err := s.KeyspaceVersion(ctx)
+	assert.Nil(err)
+	assert.Equal(2, version)
+}
+
+func TestMigrateKeyspace(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.TODO()
+
+	s, cleanup := newEtcdStore(t)
+	defer cleanup()
+	s.migrations = []keyspaceMigration{renameMigration, indexMigration}
+
+	// Write enough unversioned keys that the changes span several
+	// transactions
+	numKeys := _MIGRATE_BATCH_SIZE + 50
+	for x := 0; x < numKeys; x++ {
+		key := fmt.Sprintf("old/%03d", x)
+		_, err := s.kv.Put(ctx, key, fmt.Sprintf("v%03d", x))
+		require.Nil(err)
+	}
+
+	reports, err := s.MigrateKeyspace(ctx, true)
+	require.Nil(err)
+	require.Len(reports, 2)
+	assert.Equal(1, reports[0].Version)
+	assert.Equal(numKeys, reports[0].Scanned)
+	assert.Equal(numKeys, reports[0].Puts)
+	assert.Equal(numKeys, reports[0].Deletes)
+	assert.Len(reports[0].Keys, _MIGRATE_REPORT_KEYS)
+	assert.Equal("new/000", reports[0].Keys[0])
+	// The dry run of the second migration sees the keyspace as it is, before
+	// the first migration
+	assert.Equal(0, reports[1].Scanned)
+
+	// A dry run changes nothing
+	resp, err := s.kv.Get(ctx, "old/", etcd.WithPrefix(), etcd.WithCountOnly())
+	require.Nil(err)
+	assert.Equal(int64(numKeys), resp.Count)
+	resp, err = s.kv.Get(ctx, _VERSION_KEY)
+	require.Nil(err)
+	assert.Zero(resp.Count)
+
+	reports, err = s.MigrateKeyspace(ctx, false)
+	require.Nil(err)
+	require.Len(reports, 2)
+	assert.Equal(numKeys, reports[1].Scanned)
+	assert.Equal(numKeys, reports[1].Puts)
+
+	version, err := s.KeyspaceVersion(ctx)
+	assert.Nil(err)
+	assert.Equal(2, version)
+
+	resp, err = s.kv.Get(ctx, "old/", etcd.WithPrefix(), etcd.WithCountOnly())
+	require.Nil(err)
+	assert.Zero(resp.Count)
+	resp, err = s.kv.Get(ctx, "new/042")
+	require.Nil(err)
+	require.Equal(int64(1), resp.Count)
+	assert.Equal("v042", string(resp.Kvs[0].Value))
+	resp, err = s.kv.Get(ctx, "by-value/v042")
+	require.Nil(err)
+	require.Equal(int64(1), resp.Count)
+	assert.Equal("042", string(resp.Kvs[0].Value))
+
+	// Nothing left to migrate
+	reports, err = s.MigrateKeyspace(ctx, false)
+	assert.Nil(err)
+	assert.Empty(reports)
+}
+
+func TestMigrateKeyspaceNewer(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.TODO()
+
+	s, cleanup := newEtcdStore(t)
+	defer cleanup()
+	s.migrations = []keyspaceMigration{renameMigration}
+
+	_, err := s.kv.Put(ctx, _VERSION_KEY, "2")
+	assert.Nil(err)
+
+	_, err = s.MigrateKeyspace(ctx, false)
+	assert.NotNil(err)
+	_, err = s.MigrateKeyspace(ctx, true)
+	assert.NotNil(err)
+}
+
+func TestNewMigratesKeyspace(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.TODO()
+
+	cfg, stop := startEtcd(t)
+	defer stop()
+
+	s, err := New(logging.New(&logging.Config{}), cfg)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	version, err := s.KeyspaceVersion(ctx)
+	assert.Nil(err)
+	assert.Equal(s.LatestKeyspaceVersion(), version)
+}
@@ -1,11 +1,13 @@
 package storage
 
 import (
+	"context"
 	"fmt"
 
 	etcd "github.com/coreos/etcd/clientv3"
 	"github.com/golang/protobuf/proto"
 
+	"github.com/runmachine-io/runmachine/pkg/abstract"
 	"github.com/runmachine-io/runmachine/pkg/errors"
 	"github.com/runmachine-io/runmachine/pkg/metadata/conditions"
 	"github.com/runmachine-io/runmachine/pkg/metadata/types"
@@ -46,8 +48,11 @@ func (s *Store) objectByNameIndexKey(owr *types.ObjectWithReferences) (string, e
 	return "", fmt.Errorf("Unknown object type scope: %s", owr.ObjectType.Scope)
 }
 
-// ObjectDelete removes an object from backend storage
+// ObjectDelete removes an object from backend storage. If the object was
+// changed by another thread while being deleted, returns
+// ErrGenerationConflict
 func (s *Store) ObjectDelete(
+	ctx context.Context,
 	owr *types.ObjectWithReferences,
 ) error {
 	objByNameKey, err := s.objectByNameIndexKey(owr)
@@ -57,89 +62,91 @@ func (s *Store) ObjectDelete(
 	objUuid := owr.Object.Uuid
 	objByUuidKey := _OBJECTS_BY_UUID_KEY + objUuid
 
-	ctx, cancel := s.requestCtx()
+	ctx, cancel := s.requestCtx(ctx)
 	defer cancel()
 
-	// creates all the indexes and the objects/by-uuid/ entry using a
-	// transaction that ensures if another thread modified anything underneath
-	// us, we return an error
+	// The tag and property index entries to delete are those of the object
+	// as currently stored, which may differ from the supplied object
+	getResp, err := s.kv.Get(ctx, objByUuidKey)
+	if err != nil {
+		s.log.ERR("storage.ObjectDelete: failed to get object %s: %v", objUuid, err)
+		return errors.ErrUnknown
+	}
+	if getResp.Count == 0 {
+		return errors.ErrNotFound
+	}
+	obj := &pb.Object{}
+	if err = proto.Unmarshal(getResp.Kvs[0].Value, obj); err != nil {
+		s.log.ERR("storage.ObjectDelete: failed to deserialize object: %v", err)
+		return errors.ErrUnknown
+	}
+
+	// deletes all the indexes and the objects/by-uuid/ entry using a
+	// transaction that ensures if another thread modified the object
+	// underneath us, we return an error instead of leaving index entries
+	// behind
 	then := []etcd.Op{
 		// Delete the entry for the index by object name
 		etcd.OpDelete(objByNameKey),
 		// Delete the entry for the primary index by object UUID
 		etcd.OpDelete(objByUuidKey),
 	}
-	// TODO(jaypipes): Should we put some If(...) clause in here that verifies
-	// the object primary key and index entry existed? Not sure it's worth it,
-	// really...
-	resp, err := s.kv.Txn(ctx).Then(then...).Commit()
+	// Delete the entries for the tag and property indexes
+	for key := range objectIndexKeys(obj) {
+		then = append(then, etcd.OpDelete(key))
+	}
+	compare := []etcd.Cmp{
+		etcd.Compare(
+			etcd.ModRevision(objByUuidKey), "=", getResp.Kvs[0].ModRevision,
+		),
+	}
+	resp, err := s.kv.Txn(ctx).If(compare...).Then(then...).Commit()
 
 	if err != nil {
 		s.log.ERR("storage.ObjectDelete: failed to create txn in etcd: %v", err)
 		return errors.ErrUnknown
 	} else if resp.Succeeded == false {
-		s.log.ERR("storage.ObjectDelete: txn commit failed in etcd")
-		return errors.ErrUnknown
+		s.log.L3("object %s was changed concurrently.", objUuid)
+		return errors.ErrGenerationConflict
 	}
 	return nil
 }
 
-// ObjectFind returns a slice of pointers to objects matching any of the
-// supplied filters
+// ObjectFind returns a cursor over the objects matching any of the supplied
+// filters, ordered by UUID. The caller must Close the cursor.
 func (s *Store) ObjectFind(
+	ctx context.Context,
 	any []*conditions.ObjectCondition,
-) ([]*pb.Object, error) {
-	if len(any) == 0 {
-		return s.objectsGetAll()
-	}
-	// We iterate over our conditions, evaluating each and OR'ing them together
-	// into this map of object UUID to pb.Object message. This map is used to
-	// group objects with the same UUID that match multiple conditions.
-	objs := make(map[string]*pb.Object, 0)
-
-	for _, cond := range any {
-		if cond.IsEmpty() {
-			s.log.ERR("received empty types.ObjectCondition in ObjectFind()")
-			continue
-		}
-		// If the types.ObjectCondition contains a value for the Search field,
-		// that means we need to look up objects by UUID or name (with an
-		// optional prefix for the name). If no Search field is present, that
-		// means that in order to evaluate this types.ObjectCondition we'll be
-		// searching on ranges of objects by type, partition or project.
-		filtered, err := s.objectsGetMatching(cond)
-		if err != nil {
-			if err == errors.ErrNotFound {
-				continue // Remember, we need to OR together the filters
-			}
-			return nil, err
-		}
-		for _, obj := range filtered {
-			objs[obj.Uuid] = obj
-		}
-	}
-	if len(objs) == 0 {
-		return nil, nil
-	}
-
-	res := make([]*pb.Object, len(objs))
-	x := 0
-	for _, obj := range objs {
-		res[x] = obj
-		x += 1
+) (abstract.Cursor, error) {
+	plans, err := s.PlanObjectFind(ctx, any)
+	if err != nil {
+		return nil, err
 	}
-	return res, nil
+	return s.ExecuteObjectFind(ctx, plans)
 }
 
 // ObjectFindWithReferences returns a slice of pointers to ObjectWithReference
 // structs that have had Partition and ObjectType relations expanded inline.
 func (s *Store) ObjectFindWithReferences(
+	ctx context.Context,
 	any []*conditions.ObjectCondition,
 ) ([]*types.ObjectWithReferences, error) {
-	objects, err := s.ObjectFind(any)
+	cur, err := s.ObjectFind(ctx, any)
 	if err != nil {
 		return nil, err
 	}
+	defer cur.Close()
+	objects := make([]*pb.Object, 0)
+	for cur.Next() {
+		obj := &pb.Object{}
+		if err = cur.Scan(obj); err != nil {
+			return nil, err
+		}
+		objects = append(objects, obj)
+	}
+	if err = cur.Err(); err != nil {
+		return nil, err
+	}
 
 	// We have two maps for Partition and ObjectType messages that we fetch by
 	// partition UUID or object type code while iterating over the objects to
@@ -151,7 +158,7 @@ func (s *Store) ObjectFindWithReferences(
 	for x, obj := range objects {
 		part, ok := partitions[obj.Partition]
 		if !ok {
-			part, err = s.PartitionGetByUuid(obj.Partition)
+			part, err = s.PartitionGetByUuid(ctx, obj.Partition)
 			if err != nil {
 				msg := fmt.Sprintf(
 					"failed to find partition %s while attempting to delete "+
@@ -165,7 +172,7 @@ func (s *Store) ObjectFindWithReferences(
 		}
 		ot, ok := objTypes[obj.ObjectType]
 		if !ok {
-			ot, err = s.ObjectTypeGetByCode(obj.ObjectType)
+			ot, err = s.ObjectTypeGetByCode(ctx, obj.ObjectType)
 			if err != nil {
 				msg := fmt.Sprintf(
 					"failed to find object type %s while attempting to delete "+
@@ -187,94 +194,13 @@ func (s *Store) ObjectFindWithReferences(
 	return res, nil
 }
 
-func (s *Store) objectsGetMatching(
-	cond *conditions.ObjectCondition,
-) ([]*pb.Object, error) {
-	if cond.UuidCondition != nil {
-		// If the filter specifies a Search and it looks like a UUID, then
-		// all we need to do is grab the object from the primary
-		// objects/by-uuid/ index and check that any other fields match the
-		// object's fields. If so, just return the UUID
-		obj, err := s.ObjectGetByUuid(cond.UuidCondition.Uuid)
-		if err != nil {
-			return nil, err
-		}
-		// The filter may have contained more matchers than UUID, so apply
-		// those here too...
-		if !cond.Matches(obj) {
-			return nil, errors.ErrNotFound
-		}
-		return []*pb.Object{obj}, nil
-	}
-	if cond.NameCondition != nil {
-		// OK, we were asked to search for one or more objects having a
-		// supplied name (optionally have the name as a prefix).
-		//
-		// If the object type has been specified, things can be searched
-		// more efficiently because the object type's scope will tell us
-		// whether the name index for the object is going to be be object
-		// type and name or object type, project and name.
-		//
-		// If no object type was specified, we will need to do a full range
-		// scan on all objects by the primary objects/by-uuid/ index and
-		// manually check to see if the deserialized Object's name has the
-		// requested name...
-		if cond.ObjectTypeCondition != nil && cond.PartitionCondition != nil {
-			objScope := cond.ObjectTypeCondition.ObjectType.Scope
-			if objScope == pb.ObjectTypeScope_PROJECT {
-				if cond.ProjectCondition != "" {
-					// Just drop through if we don't have a project because
-					// we won't be able to look up a project-scoped object
-					// type when no project was specified, so we'll do the
-					// less efficient range-scan sieve pattern to solve
-					// this cond
-					return s.ObjectsGetByProjectNameIndex(
-						cond.PartitionCondition.Partition.Uuid,
-						cond.ObjectTypeCondition.ObjectType.Code,
-						cond.ProjectCondition,
-						cond.NameCondition.Name,
-						cond.NameCondition.Op != conditions.OP_EQUAL,
-					)
-				}
-			} else {
-				return s.ObjectsGetByNameIndex(
-					cond.PartitionCondition.Partition.Uuid,
-					cond.ObjectTypeCondition.ObjectType.Code,
-					cond.NameCondition.Name,
-					cond.NameCondition.Op != conditions.OP_EQUAL,
-				)
-			}
-		}
-	}
-
-	// This is called when we have no filter on object UUID/name or we have a
-	// filter on name but not object type. We will get all objects and cond
-	// out any objects that don't meet the supplied partition UUID, project and
-	// object type code filters.
-	objects, err := s.objectsGetAll()
-	if err != nil {
-		return nil, err
-	}
-
-	res := make([]*pb.Object, 0)
-	for _, obj := range objects {
-		// Use a sieve pattern, only adding the object to our results if it
-		// passes all match expressions
-		if !cond.Matches(obj) {
-			continue
-		}
-		res = append(res, obj)
-	}
-
-	return res, nil
-}
-
 // ObjectGetByUuid returns an Object protobuffer message with the supplied
 // object UUID
 func (s *Store) ObjectGetByUuid(
+	ctx context.Context,
 	uuid string,
 ) (*pb.Object, error) {
-	ctx, cancel := s.requestCtx()
+	ctx, cancel := s.requestCtx(ctx)
 	defer cancel()
 
 	key := _OBJECTS_BY_UUID_KEY + uuid
@@ -299,13 +225,14 @@ func (s *Store) ObjectGetByUuid(
 // ObjectsGetByProjectNameIndex returns Object messages that have a specified
 // project and name (with optional prefix) in the supplied partition.
 func (s *Store) ObjectsGetByProjectNameIndex(
+	ctx context.Context,
 	partUuid string,
 	objTypeCode string,
 	project string,
 	objName string,
 	usePrefix bool,
 ) ([]*pb.Object, error) {
-	ctx, cancel := s.requestCtx()
+	ctx, cancel := s.requestCtx(ctx)
 	defer cancel()
 
 	kv := s.kvPartition(partUuid)
@@ -336,7 +263,7 @@ func (s *Store) ObjectsGetByProjectNameIndex(
 	res := make([]*pb.Object, resp.Count)
 
 	for x, entry := range resp.Kvs {
-		obj, err := s.ObjectGetByUuid(string(entry.Value))
+		obj, err := s.ObjectGetByUuid(ctx, string(entry.Value))
 		if err != nil {
 			return nil, err
 		}
@@ -349,12 +276,13 @@ func (s *Store) ObjectsGetByProjectNameIndex(
 // ObjectsGetByNameIndex returns Object messages that have a specified name
 // (with optional prefix) in the supplied partition.
 func (s *Store) ObjectsGetByNameIndex(
+	ctx context.Context,
 	partUuid string,
 	objTypeCode string,
 	objName string,
 	usePrefix bool,
 ) ([]*pb.Object, error) {
-	ctx, cancel := s.requestCtx()
+	ctx, cancel := s.requestCtx(ctx)
 	defer cancel()
 
 	kv := s.kvPartition(partUuid)
@@ -382,7 +310,7 @@ func (s *Store) ObjectsGetByNameIndex(
 	res := make([]*pb.Object, resp.Count)
 
 	for x, entry := range resp.Kvs {
-		obj, err := s.ObjectGetByUuid(string(entry.Value))
+		obj, err := s.ObjectGetByUuid(ctx, string(entry.Value))
 		if err != nil {
 			return nil, err
 		}
@@ -392,43 +320,10 @@ func (s *Store) ObjectsGetByNameIndex(
 	return res, nil
 }
 
-func (s *Store) objectsGetAll() ([]*pb.Object, error) {
-	ctx, cancel := s.requestCtx()
-	defer cancel()
-
-	resp, err := s.kv.Get(
-		ctx,
-		_OBJECTS_BY_UUID_KEY,
-		etcd.WithPrefix(),
-		// TODO(jaypipes): Factor the sorting/limiting/pagination out into a
-		// separate utility
-		etcd.WithSort(etcd.SortByKey, etcd.SortAscend),
-	)
-
-	if err != nil {
-		s.log.ERR("error listing all objects: %v", err)
-		return nil, err
-	}
-
-	if resp.Count == 0 {
-		return []*pb.Object{}, nil
-	}
-
-	res := make([]*pb.Object, resp.Count)
-	for x, kv := range resp.Kvs {
-		msg := &pb.Object{}
-		if err := proto.Unmarshal(kv.Value, msg); err != nil {
-			return nil, err
-		}
-		res[x] = msg
-	}
-
-	return res, nil
-}
-
 // ObjectCreate puts the supplied object into backend storage, adding all the
 // appropriate indexes. It returns the newly-created object.
 func (s *Store) ObjectCreate(
+	ctx context.Context,
 	owr *types.ObjectWithReferences,
 ) (*types.ObjectWithReferences, error) {
 	if owr.Object.Uuid == "" {
@@ -437,6 +332,7 @@ func (s *Store) ObjectCreate(
 		owr.Object.Uuid = util.NormalizeUuid(owr.Object.Uuid)
 	}
 	objUuid := owr.Object.Uuid
+	owr.Object.Generation = 1
 
 	objValue, err := proto.Marshal(owr.Object)
 	if err != nil {
@@ -450,7 +346,7 @@ func (s *Store) ObjectCreate(
 	}
 	objByUuidKey := _OBJECTS_BY_UUID_KEY + objUuid
 
-	ctx, cancel := s.requestCtx()
+	ctx, cancel := s.requestCtx(ctx)
 	defer cancel()
 
 	// creates all the indexes and the objects/by-uuid/ entry using a
@@ -462,6 +358,17 @@ func (s *Store) ObjectCreate(
 		// Add the entry for the primary index by object UUID
 		etcd.OpPut(objByUuidKey, string(objValue)),
 	}
+	// Add the entries for the tag and property indexes
+	for key, objType := range objectIndexKeys(owr.Object) {
+		then = append(then, etcd.OpPut(key, objType))
+	}
+	if len(then) > _OBJECT_WRITE_MAX_OPS {
+		s.log.L3(
+			"object_create: object %s has too many tags and properties.",
+			objUuid,
+		)
+		return nil, errors.ErrBadInput
+	}
 	compare := []etcd.Cmp{
 		// Ensure the object value and index by name don't yet exist
 		etcd.Compare(etcd.Version(objByNameKey), "=", 0),
@@ -479,26 +386,53 @@ func (s *Store) ObjectCreate(
 }
 
 // ObjectUpdate puts the supplied object into backend storage, updating any
-// appropriate indexes. It returns the newly-changed object.
+// appropriate indexes. It returns the newly-changed object with its
+// generation incremented.
+//
+// The supplied object's generation must be the generation of the object as
+// currently stored. If the object was changed by another thread after it was
+// read, returns ErrGenerationConflict
 func (s *Store) ObjectUpdate(
+	ctx context.Context,
 	owr *types.ObjectWithReferences,
 ) (*types.ObjectWithReferences, error) {
 	objUuid := owr.Object.Uuid
 
-	objValue, err := proto.Marshal(owr.Object)
+	objByNameKey, err := s.objectByNameIndexKey(owr)
 	if err != nil {
-		s.log.ERR("failed to serialize object: %v", err)
 		return nil, errors.ErrUnknown
 	}
+	objByUuidKey := _OBJECTS_BY_UUID_KEY + objUuid
 
-	objByNameKey, err := s.objectByNameIndexKey(owr)
+	ctx, cancel := s.requestCtx(ctx)
+	defer cancel()
+
+	// We need the object as currently stored in order to remove the tag and
+	// property index entries for tags and properties the object no longer has
+	getResp, err := s.kv.Get(ctx, objByUuidKey)
 	if err != nil {
+		s.log.ERR("object_update: failed to get object %s: %v", objUuid, err)
 		return nil, errors.ErrUnknown
 	}
-	objByUuidKey := _OBJECTS_BY_UUID_KEY + objUuid
+	if getResp.Count == 0 {
+		return nil, errors.ErrNotFound
+	}
+	before := &pb.Object{}
+	if err = proto.Unmarshal(getResp.Kvs[0].Value, before); err != nil {
+		s.log.ERR("object_update: failed to deserialize object: %v", err)
+		return nil, errors.ErrUnknown
+	}
+	if owr.Object.Generation != before.Generation {
+		s.log.L3("object %s was changed concurrently.", objUuid)
+		return nil, errors.ErrGenerationConflict
+	}
+	owr.Object.Generation = before.Generation + 1
 
-	ctx, cancel := s.requestCtx()
-	defer cancel()
+	objValue, err := proto.Marshal(owr.Object)
+	if err != nil {
+		s.log.ERR("failed to serialize object: %v", err)
+		return nil, errors.ErrUnknown
+	}
 
 	// creates all the indexes and the objects/by-uuid/ entry using a
 	// transaction that ensures if another thread modified anything underneath
@@ -509,10 +443,37 @@ func (s *Store) ObjectUpdate(
 		// Add the entry for the primary index by object UUID
 		etcd.OpPut(objByUuidKey, string(objValue)),
 	}
+	// Only the index entries for added or removed tags and properties are
+	// changed. The entries for unchanged ones already exist.
+	existing := objectIndexKeys(before)
+	after := objectIndexKeys(owr.Object)
+	for key := range existing {
+		if _, ok := after[key]; !ok {
+			then = append(then, etcd.OpDelete(key))
+		}
+	}
+	for key, objType := range after {
+		if _, ok := existing[key]; !ok {
+			then = append(then, etcd.OpPut(key, objType))
+		}
+	}
+	// The object must stay small enough to be deleted in a single
+	// transaction, and the changes must fit in this one
+	if 2+len(after) > _OBJECT_WRITE_MAX_OPS || len(then) > _OBJECT_WRITE_MAX_OPS {
+		s.log.L3(
+			"object_update: object %s would have too many tags and "+
+				"properties or too many of them changed.",
+			objUuid,
+		)
+		return nil, errors.ErrBadInput
+	}
 	compare := []etcd.Cmp{
-		// Ensure the object value and index by name exists
+		// Ensure the object value and index by name exists and the object
+		// hasn't changed since we read it
 		etcd.Compare(etcd.Version(objByNameKey), ">", 0),
-		etcd.Compare(etcd.Version(objByUuidKey), ">", 0),
+		etcd.Compare(
+			etcd.ModRevision(objByUuidKey), "=", getResp.Kvs[0].ModRevision,
+		),
 	}
 	resp, err := s.kv.Txn(ctx).If(compare...).Then(then...).Commit()
 
@@ -520,7 +481,8 @@ func (s *Store) ObjectUpdate(
 		s.log.ERR("object_update: failed to create txn in etcd: %v", err)
 		return nil, errors.ErrUnknown
 	} else if resp.Succeeded == false {
-		return nil, errors.ErrDuplicate
+		s.log.L3("object %s was changed concurrently.", objUuid)
+		return nil, errors.ErrGenerationConflict
 	}
 	return owr, nil
 }
@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/runmachine-io/runmachine/pkg/metadata/conditions"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	// Maximum number of objects read from etcd at a time when scanning the
+	// objects/by-uuid/ index
+	_OBJECTS_PAGE_SIZE = 500
+)
+
+// objectFetcher returns the next object, or nil when there are no more
+// objects
+type objectFetcher func() (*pb.Object, error)
+
+// objectCursor implements the abstract.Cursor interface for Object messages
+// read one at a time from a fetcher, so that only the objects being read are
+// held in memory.
+// NOTE(jaypipes): It is NOT safe to share these objects between threads.
+type objectCursor struct {
+	fetch objectFetcher
+	// The next object to Scan, if already fetched
+	cur  *pb.Object
+	done bool
+	err  error
+}
+
+func newObjectCursor(fetch objectFetcher) *objectCursor {
+	return &objectCursor{fetch: fetch}
+}
+
+// peek returns the next object to Scan without moving past it, or nil if
+// there are no more objects
+func (c *objectCursor) peek() *pb.Object {
+	if c.cur == nil && !c.done {
+		c.cur, c.err = c.fetch()
+		if c.cur == nil || c.err != nil {
+			c.cur = nil
+			c.done = true
+		}
+	}
+	return c.cur
+}
+
+func (c *objectCursor) Next() bool {
+	return c.peek() != nil
+}
+
+func (c *objectCursor) Scan(msg proto.Message) error {
+	obj := c.peek()
+	if obj == nil {
+		return fmt.Errorf("attempted to read past end of object cursor.")
+	}
+	proto.Merge(msg, obj)
+	c.cur = nil
+	return nil
+}
+
+func (c *objectCursor) Close() error {
+	c.cur = nil
+	c.done = true
+	return nil
+}
+
+func (c *objectCursor) Err() error {
+	return c.err
+}
+
+// objectScanFetcher returns a fetcher of all objects in the objects/by-uuid/
+// index, in order of UUID. Objects are read a page at a time, with all pages
+// read at the revision of the first so that the fetcher sees a consistent
+// snapshot of the objects.
+func (s *Store) objectScanFetcher(ctx context.Context) objectFetcher {
+	key := _OBJECTS_BY_UUID_KEY
+	end := etcd.GetPrefixRangeEnd(_OBJECTS_BY_UUID_KEY)
+	rev := int64(0)
+	more := true
+	var page []*mvccpb.KeyValue
+	return func() (*pb.Object, error) {
+		for len(page) == 0 {
+			if !more {
+				return nil, nil
+			}
+			opts := []etcd.OpOption{
+				etcd.WithRange(end),
+				etcd.WithLimit(_OBJECTS_PAGE_SIZE),
+			}
+			if rev > 0 {
+				opts = append(opts, etcd.WithRev(rev))
+			}
+			rctx, cancel := s.requestCtx(ctx)
+			resp, err := s.kv.Get(rctx, key, opts...)
+			cancel()
+			if err != nil {
+				s.log.ERR("error listing objects: %v", err)
+				return nil, err
+			}
+			if rev == 0 {
+				rev = resp.Header.Revision
+			}
+			page = resp.Kvs
+			more = resp.More
+			if len(page) > 0 {
+				// Continue from just after the last key read
+				key = string(page[len(page)-1].Key) + "\x00"
+			}
+		}
+		obj := &pb.Object{}
+		if err := proto.Unmarshal(page[0].Value, obj); err != nil {
+			return nil, err
+		}
+		page = page[1:]
+		return obj, nil
+	}
+}
+
+// objectUuidsFetcher returns a fetcher of the objects with the supplied UUIDs,
+// in the order supplied. Objects are read in batches and UUIDs of objects that
+// do not exist are skipped.
+func (s *Store) objectUuidsFetcher(
+	ctx context.Context,
+	uuids []string,
+) objectFetcher {
+	var batch []*pb.Object
+	return func() (*pb.Object, error) {
+		for len(batch) == 0 {
+			if len(uuids) == 0 {
+				return nil, nil
+			}
+			n := _OBJECTS_GET_BATCH_SIZE
+			if n > len(uuids) {
+				n = len(uuids)
+			}
+			var err error
+			batch, err = s.objectsGetByUuids(ctx, uuids[:n])
+			if err != nil {
+				return nil, err
+			}
+			uuids = uuids[n:]
+		}
+		obj := batch[0]
+		batch = batch[1:]
+		return obj, nil
+	}
+}
+
+// filterObjects returns a fetcher of the objects from the supplied fetcher that
+// match the supplied condition
+func filterObjects(
+	fetch objectFetcher,
+	cond *conditions.ObjectCondition,
+) objectFetcher {
+	return func() (*pb.Object, error) {
+		for {
+			obj, err := fetch()
+			if obj == nil || err != nil {
+				return nil, err
+			}
+			if cond.Matches(obj) {
+				return obj, nil
+			}
+		}
+	}
+}
+
+// mergeObjects returns a fetcher that merges the objects of the supplied
+// cursors, each ordered by UUID, in order of UUID without duplicates
+func mergeObjects(cursors []*objectCursor) objectFetcher {
+	last := ""
+	return func() (*pb.Object, error) {
+		for {
+			var next *objectCursor
+			for _, c := range cursors {
+				obj := c.peek()
+				if c.err != nil {
+					return nil, c.err
+				}
+				if obj == nil {
+					continue
+				}
+				if next == nil || obj.Uuid < next.peek().Uuid {
+					next = c
+				}
+			}
+			if next == nil {
+				return nil, nil
+			}
+			obj := next.peek()
+			next.cur = nil
+			if obj.Uuid == last {
+				continue
+			}
+			last = obj.Uuid
+			return obj, nil
+		}
+	}
+}
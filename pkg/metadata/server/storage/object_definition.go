@@ -1,6 +1,11 @@
 package storage
 
 import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
 	etcd "github.com/coreos/etcd/clientv3"
 	"github.com/gogo/protobuf/proto"
 
@@ -15,72 +20,144 @@ const (
 	_OBJECT_DEFINITIONS_BY_UUID_KEY = "definitions/by-uuid/"
 	// A key namespace by object type
 	_OBJECT_DEFINITIONS_BY_TYPE_KEY = "definitions/by-type/"
+	// A key namespace by object type containing, for each object definition,
+	// valued keys where the key is the zero-padded revision number and the
+	// value is the UUID of that revision of the object definition
+	_OBJECT_DEFINITIONS_HISTORY_KEY = "definitions/history/"
+)
+
+var (
+	// historyObjectDefinitionsMigration records the object definitions that
+	// were set before object definitions were versioned as the first revision
+	// of each object definition
+	historyObjectDefinitionsMigration = keyspaceMigration{
+		description: "record global object definitions as revision 1",
+		prefix:      _OBJECT_DEFINITIONS_BY_TYPE_KEY,
+		transform:   objectDefinitionHistoryTransform,
+	}
+	// historyPartitionObjectDefinitionsMigration does the same as
+	// historyObjectDefinitionsMigration for the object definition overrides
+	// that were set for partitions
+	historyPartitionObjectDefinitionsMigration = keyspaceMigration{
+		description: "record partition object definitions as revision 1",
+		prefix:      _PARTITIONS_KEY,
+		transform:   objectDefinitionHistoryTransform,
+	}
 )
 
-func providerDefinitionKey(
+// objectDefinitionHistoryTransform returns the history key for the first
+// revision of the object definition at the supplied key
+func objectDefinitionHistoryTransform(
+	key string,
+	value []byte,
+) ([]keyChange, error) {
+	// Skip any key in a partition that isn't an object definition key, which
+	// must be either $ROOT/definitions/by-type/... or
+	// $ROOT/partitions/{uuid}/definitions/by-type/...
+	idx := strings.Index(key, _OBJECT_DEFINITIONS_BY_TYPE_KEY)
+	if idx < 0 || (idx > 0 && strings.Count(key[:idx], "/") != 2) {
+		return nil, nil
+	}
+	return []keyChange{
+		{
+			Key:   objectDefinitionRevisionKey(key, 1),
+			Value: value,
+		},
+	}, nil
+}
+
+// objectDefinitionHistoryKey returns the key namespace containing the
+// revisions of the object definition with the supplied key
+func objectDefinitionHistoryKey(defKey string) string {
+	return strings.Replace(
+		defKey,
+		_OBJECT_DEFINITIONS_BY_TYPE_KEY,
+		_OBJECT_DEFINITIONS_HISTORY_KEY,
+		1,
+	) + "/"
+}
+
+// objectDefinitionRevisionKey returns the key of a revision of the object
+// definition with the supplied key.
+// NOTE(jaypipes): Revision numbers are zero-padded so that the history keys
+// sort in revision order.
+func objectDefinitionRevisionKey(defKey string, revision uint64) string {
+	return objectDefinitionHistoryKey(defKey) + fmt.Sprintf("%010d", revision)
+}
+
+// objectDefinitionKey returns the key of the object definition for the supplied
+// object type, optional partition UUID and optional object subtype.
+// NOTE(jaypipes): The subtype overrides are stored under a "by-type/" key
+// namespace since, before object definitions applied to all object types, the
+// only subtype was the provider type of runm.provider objects.
+func objectDefinitionKey(
+	objType string,
 	partUuid string,
-	provType string,
+	subtype string,
 ) string {
-	if partUuid == "" {
-		if provType == "" {
-			// The global default provider definition
-			return _OBJECT_DEFINITIONS_BY_TYPE_KEY + "runm.provider/default"
-		}
-		// The global default provider definition for a particular provider
-		// type
-		return _OBJECT_DEFINITIONS_BY_TYPE_KEY + "runm.provider/by-type/" +
-			provType
+	key := _OBJECT_DEFINITIONS_BY_TYPE_KEY + objType + "/"
+	if subtype == "" {
+		// The default object definition for the object type
+		key += "default"
 	} else {
-		if provType == "" {
-			// The provider definition default override for the partition
-			return _PARTITIONS_KEY + partUuid + "/" +
-				_OBJECT_DEFINITIONS_BY_TYPE_KEY + "runm.provider/default"
-		} else {
-			// The provider definition override for the partition and specific
-			// provider type
-			return _PARTITIONS_KEY + partUuid + "/" +
-				_OBJECT_DEFINITIONS_BY_TYPE_KEY + "runm.provider/by-type/" +
-				provType
-		}
+		// The object definition override for a particular subtype
+		key += "by-type/" + subtype
+	}
+	if partUuid != "" {
+		// The object definition override for the partition
+		key = _PARTITIONS_KEY + partUuid + "/" + key
 	}
+	return key
 }
 
-// ensureDefaultProviderDefinition looks up the global default provider
-// definition and if not found, creates it
-func (s *Store) ensureDefaultProviderDefinition() error {
-
-	s.log.L3("ensuring default provider definition...")
-
-	if _, err := s.ProviderDefinitionGet("", ""); err != nil {
-		if err == errors.ErrNotFound {
-			s.log.L3("default provider definition does not exist. creating...")
-			pdef := apitypes.DefaultProviderDefinition()
-			odef := &pb.ObjectDefinition{
-				Schema:              pdef.JSONSchemaString(),
-				PropertyPermissions: []*pb.PropertyPermissions{},
-			}
-
-			err := s.ProviderDefinitionSet("", "", odef)
-			if err != nil {
-				s.log.ERR("failed ensuring default provider definition: %s", err)
-				return err
-			}
-			s.log.L1("default provider definition created")
-			return nil
+// ensureDefaultObjectDefinitions looks up the global default object definition
+// for each of the well-known runm object types and if not found, creates it
+func (s *Store) ensureDefaultObjectDefinitions() error {
+	ctx := context.Background()
+
+	s.log.L3("ensuring default object definitions...")
+
+	for _, ot := range runmObjectTypes {
+		_, err := s.ObjectDefinitionGet(ctx, ot.Code, "", "")
+		if err == nil {
+			s.log.L3("default object definition for %s exists", ot.Code)
+			continue
 		}
-		s.log.ERR("failed ensuring default provider definition: %s", err)
-		return err
+		if err != errors.ErrNotFound {
+			s.log.ERR(
+				"failed ensuring default object definition for %s: %s",
+				ot.Code, err,
+			)
+			return err
+		}
+		s.log.L3(
+			"default object definition for %s does not exist. creating...",
+			ot.Code,
+		)
+		def := apitypes.DefaultObjectDefinition()
+		odef := &pb.ObjectDefinition{
+			Schema:              def.JSONSchemaString(ot.Code),
+			PropertyPermissions: []*pb.PropertyPermissions{},
+		}
+		if err = s.ObjectDefinitionSet(ctx, ot.Code, "", "", odef); err != nil {
+			s.log.ERR(
+				"failed ensuring default object definition for %s: %s",
+				ot.Code, err,
+			)
+			return err
+		}
+		s.log.L1("default object definition for %s created", ot.Code)
 	}
-	s.log.L3("default provider definition exists")
 	return nil
 }
 
 // objectDefinitionGetUuidFromKey returns an object definition UUID given a
 // a string key where a UUID is expected
 func (s *Store) objectDefinitionGetUuidFromKey(
+	ctx context.Context,
 	key string,
 ) (string, error) {
-	ctx, cancel := s.requestCtx()
+	ctx, cancel := s.requestCtx(ctx)
 	defer cancel()
 
 	resp, err := s.kv.Get(ctx, key)
@@ -95,27 +172,76 @@ func (s *Store) objectDefinitionGetUuidFromKey(
 	return string(resp.Kvs[0].Value), nil
 }
 
-// ProviderDefinitionGet returns an object definition given an partition UUID
-// and provider type. If the partition UUID is empty, returns the global
-// default object definition for that provider type. If the provider type is
-// empty, returns the global default or partition default for providers.
-func (s *Store) ProviderDefinitionGet(
+// ObjectDefinitionGet returns an object definition given an object type,
+// partition UUID and object subtype. If the partition UUID is empty, returns
+// the global object definition for the object type and subtype. If the subtype
+// is empty, returns the global default or partition default for objects of the
+// object type.
+func (s *Store) ObjectDefinitionGet(
+	ctx context.Context,
+	objType string,
 	partUuid string,
-	provType string,
+	subtype string,
 ) (*pb.ObjectDefinition, error) {
-	key := providerDefinitionKey(partUuid, provType)
-	uuid, err := s.objectDefinitionGetUuidFromKey(key)
+	key := objectDefinitionKey(objType, partUuid, subtype)
+	uuid, err := s.objectDefinitionGetUuidFromKey(ctx, key)
 	if err != nil {
 		return nil, err
 	}
-	return s.objectDefinitionGetByUuid(uuid)
+	return s.objectDefinitionGetByUuid(ctx, uuid)
+}
+
+// ObjectDefinitionGetMostExplicit returns the object definition that is used
+// to validate objects of the supplied object type in the supplied partition and
+// having the supplied subtype.
+//
+// If an object definition override has been set for the partition and
+// subtype, that object definition will be returned, otherwise...
+//
+// If an object definition override has been set for the partition but not the
+// subtype, that object definition will be returned, otherwise...
+//
+// If an object definition override has been set for the subtype but not the
+// partition, that object definition will be returned, otherwise...
+//
+// The global default object definition for the object type is returned.
+//
+// If no object definition could be found, returns (nil, ErrNotFound)
+func (s *Store) ObjectDefinitionGetMostExplicit(
+	ctx context.Context,
+	objType string,
+	partUuid string,
+	subtype string,
+) (*pb.ObjectDefinition, error) {
+	tries := [][]string{
+		{partUuid, subtype},
+		{partUuid, ""},
+		{"", subtype},
+		{"", ""},
+	}
+	for x, try := range tries {
+		if x > 0 && try[0] == tries[x-1][0] && try[1] == tries[x-1][1] {
+			// Empty partition or subtype arguments make some of the above
+			// lookups the same as the previous one
+			continue
+		}
+		def, err := s.ObjectDefinitionGet(ctx, objType, try[0], try[1])
+		if err == nil {
+			return def, nil
+		}
+		if err != errors.ErrNotFound {
+			return nil, err
+		}
+	}
+	return nil, errors.ErrNotFound
 }
 
 // ObjectDefinitionGetByUuid returns an object definition given a UUID.
 func (s *Store) objectDefinitionGetByUuid(
+	ctx context.Context,
 	uuid string,
 ) (*pb.ObjectDefinition, error) {
-	ctx, cancel := s.requestCtx()
+	ctx, cancel := s.requestCtx(ctx)
 	defer cancel()
 
 	key := _OBJECT_DEFINITIONS_BY_UUID_KEY + uuid
@@ -136,51 +262,188 @@ func (s *Store) objectDefinitionGetByUuid(
 	return &obj, nil
 }
 
-// ProviderDefinitionSet replaces an object definition in backend storage for
-// an optional partition UUID and optional provider type. If the supplied
-// partition UUID is empty, this method replaces the default object definition
-// for the specified provider type. If the provider type is empty, this method
-// replaces the global default provider definition or the partition override
-// provider definition.
-func (s *Store) ProviderDefinitionSet(
+// ObjectDefinitionGetRevision returns a revision of the object definition for
+// an object type, partition UUID and object subtype.
+//
+// If no such revision could be found, returns (nil, ErrNotFound)
+func (s *Store) ObjectDefinitionGetRevision(
+	ctx context.Context,
+	objType string,
+	partUuid string,
+	subtype string,
+	revision uint64,
+) (*pb.ObjectDefinition, error) {
+	defKey := objectDefinitionKey(objType, partUuid, subtype)
+	key := objectDefinitionRevisionKey(defKey, revision)
+	uuid, err := s.objectDefinitionGetUuidFromKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	def, err := s.objectDefinitionGetByUuid(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	// Object definitions set before object definitions were versioned do not
+	// have their revision number in the stored object definition
+	def.Revision = revision
+	return def, nil
+}
+
+// ObjectDefinitionHistory returns all revisions of the object definition for
+// an object type, partition UUID and object subtype, ordered by revision.
+// Returns an empty slice if the object definition has never been set.
+func (s *Store) ObjectDefinitionHistory(
+	ctx context.Context,
+	objType string,
 	partUuid string,
-	provType string,
+	subtype string,
+) ([]*pb.ObjectDefinition, error) {
+	defKey := objectDefinitionKey(objType, partUuid, subtype)
+	histKey := objectDefinitionHistoryKey(defKey)
+
+	gctx, cancel := s.requestCtx(ctx)
+	resp, err := s.kv.Get(
+		gctx,
+		histKey,
+		etcd.WithPrefix(),
+		etcd.WithSort(etcd.SortByKey, etcd.SortAscend),
+	)
+	cancel()
+	if err != nil {
+		s.log.ERR("error listing object definition history at key %s: %v",
+			histKey, err,
+		)
+		return nil, err
+	}
+
+	res := make([]*pb.ObjectDefinition, 0, resp.Count)
+	for _, kv := range resp.Kvs {
+		revision, err := objectDefinitionRevisionFromKey(string(kv.Key))
+		if err != nil {
+			return nil, err
+		}
+		def, err := s.objectDefinitionGetByUuid(ctx, string(kv.Value))
+		if err != nil {
+			return nil, err
+		}
+		def.Revision = revision
+		res = append(res, def)
+	}
+	return res, nil
+}
+
+// objectDefinitionRevisionFromKey returns the revision number at the end of
+// an object definition history key
+func objectDefinitionRevisionFromKey(key string) (uint64, error) {
+	revStr := key[strings.LastIndex(key, "/")+1:]
+	revision, err := strconv.ParseUint(revStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf(
+			"invalid object definition history key %q: %s", key, err,
+		)
+	}
+	return revision, nil
+}
+
+// ObjectDefinitionSet saves a new revision of the object definition for an
+// object type, optional partition UUID and optional object subtype. If the
+// supplied partition UUID is empty, this method replaces the global object
+// definition for the object type and subtype. If the subtype is empty, this
+// method replaces the global default object definition or the partition
+// override object definition for the object type.
+//
+// Each revision of an object definition gets a new UUID, and the supplied
+// object definition's UUID, Revision and Diff fields are set to describe the
+// new revision. Earlier revisions are kept and can be listed with
+// ObjectDefinitionHistory.
+//
+// If the object definition was changed by another thread after it was read,
+// returns ErrGenerationConflict
+func (s *Store) ObjectDefinitionSet(
+	ctx context.Context,
+	objType string,
+	partUuid string,
+	subtype string,
 	def *pb.ObjectDefinition,
 ) error {
-	ctx, cancel := s.requestCtx()
-	defer cancel()
+	defKey := objectDefinitionKey(objType, partUuid, subtype)
+	histKey := objectDefinitionHistoryKey(defKey)
 
-	if def.Uuid == "" {
-		def.Uuid = util.NewNormalizedUuid()
-	} else {
-		def.Uuid = util.NormalizeUuid(def.Uuid)
+	gctx, cancel := s.requestCtx(ctx)
+	cur, err := s.kv.Get(gctx, defKey)
+	cancel()
+	if err != nil {
+		s.log.ERR("error getting object definition at key %s: %v", defKey, err)
+		return err
+	}
+	// The object definition must not have changed between here and the
+	// transaction below
+	compare := etcd.Compare(etcd.Version(defKey), "=", 0)
+	var prev *pb.ObjectDefinition
+	if cur.Count > 0 {
+		compare = etcd.Compare(
+			etcd.ModRevision(defKey), "=", cur.Kvs[0].ModRevision,
+		)
+		prev, err = s.objectDefinitionGetByUuid(ctx, string(cur.Kvs[0].Value))
+		if err != nil && err != errors.ErrNotFound {
+			return err
+		}
 	}
 
+	gctx, cancel = s.requestCtx(ctx)
+	last, err := s.kv.Get(gctx, histKey, etcd.WithLastKey()...)
+	cancel()
+	if err != nil {
+		s.log.ERR("error getting object definition history at key %s: %v",
+			histKey, err,
+		)
+		return err
+	}
+	prevRevision := uint64(0)
+	if last.Count > 0 {
+		prevRevision, err = objectDefinitionRevisionFromKey(
+			string(last.Kvs[0].Key),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	def.Uuid = util.NewNormalizedUuid()
+	def.Revision = prevRevision + 1
+	def.Diff = apitypes.ObjectDefinitionDiff(
+		prev,
+		def,
+		fmt.Sprintf("revision %d", prevRevision),
+		fmt.Sprintf("revision %d", def.Revision),
+	)
+
 	value, err := proto.Marshal(def)
 	if err != nil {
 		return err
 	}
 
-	provDefKey := providerDefinitionKey(partUuid, provType)
 	uuidKey := _OBJECT_DEFINITIONS_BY_UUID_KEY + def.Uuid
+	revKey := objectDefinitionRevisionKey(defKey, def.Revision)
 
-	// create the object definition using a transaction that ensures another
-	// thread hasn't created a object definition with the same key underneath
-	// us
 	onSuccess := []etcd.Op{
-		etcd.OpPut(provDefKey, def.Uuid),
+		etcd.OpPut(defKey, def.Uuid),
 		etcd.OpPut(uuidKey, string(value)),
+		etcd.OpPut(revKey, def.Uuid),
 	}
-	// TODO(jaypipes): Add in versioning check here
-	// compare := etcd.Compare(etcd.Version(uuidKey), "=", cmpVersion)
-	resp, err := s.kv.Txn(ctx).Then(onSuccess...).Commit()
+	ctx, cancel = s.requestCtx(ctx)
+	defer cancel()
+	resp, err := s.kv.Txn(ctx).If(
+		compare,
+		etcd.Compare(etcd.Version(revKey), "=", 0),
+	).Then(onSuccess...).Commit()
 
 	if err != nil {
 		s.log.ERR("failed to create txn in etcd: %v", err)
 		return err
 	} else if resp.Succeeded == false {
-		s.log.L3("another thread already created key %s.", provDefKey)
-		return errors.ErrDuplicate
+		s.log.L3("another thread already changed key %s.", defKey)
+		return errors.ErrGenerationConflict
 	}
 	return nil
 }
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/runmachine-io/runmachine/pkg/errors"
+	"github.com/runmachine-io/runmachine/pkg/util"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+func TestObjectDefinitionGetMostExplicit(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.TODO()
+
+	s, cleanup := newEtcdStore(t)
+	defer cleanup()
+
+	// Every well-known object type gets a global default object definition
+	require.Nil(t, s.ensureDefaultObjectDefinitions())
+	for _, ot := range runmObjectTypes {
+		def, err := s.ObjectDefinitionGet(ctx, ot.Code, "", "")
+		require.Nil(t, err, ot.Code)
+		assert.Contains(def.Schema, ot.Code+".schema.json")
+	}
+	_, err := s.ObjectDefinitionGet(ctx, "example.widget", "", "")
+	assert.Equal(errors.ErrNotFound, err)
+
+	part := testPartition.Uuid
+	set := func(partUuid string, subtype string, schema string) {
+		def := &pb.ObjectDefinition{Schema: schema}
+		require.Nil(t, s.ObjectDefinitionSet(
+			ctx, "runm.image", partUuid, subtype, def,
+		))
+	}
+	get := func(partUuid string, subtype string) string {
+		def, err := s.ObjectDefinitionGetMostExplicit(
+			ctx, "runm.image", partUuid, subtype,
+		)
+		require.Nil(t, err)
+		return def.Schema
+	}
+
+	set("", "", "global")
+	assert.Equal("global", get(part, "iso"))
+
+	set("", "iso", "subtype")
+	assert.Equal("subtype", get(part, "iso"))
+	assert.Equal("global", get(part, "qcow2"))
+
+	set(part, "", "partition")
+	assert.Equal("partition", get(part, "iso"))
+	assert.Equal("subtype", get("", "iso"))
+
+	set(part, "iso", "partition+subtype")
+	assert.Equal("partition+subtype", get(part, "iso"))
+	assert.Equal("partition", get(part, "qcow2"))
+	assert.Equal("global", get("", ""))
+}
+
+func TestObjectDefinitionHistory(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.TODO()
+
+	s, cleanup := newEtcdStore(t)
+	defer cleanup()
+
+	part := testPartition.Uuid
+	history, err := s.ObjectDefinitionHistory(ctx, "runm.image", part, "iso")
+	require.Nil(err)
+	assert.Len(history, 0)
+
+	uuids := make([]string, 0)
+	for _, schema := range []string{"one\n", "two\n", "three\n"} {
+		def := &pb.ObjectDefinition{Schema: schema}
+		require.Nil(s.ObjectDefinitionSet(ctx, "runm.image", part, "iso", def))
+		uuids = append(uuids, def.Uuid)
+	}
+	// Every revision gets its own UUID
+	assert.NotEqual(uuids[0], uuids[1])
+
+	history, err = s.ObjectDefinitionHistory(ctx, "runm.image", part, "iso")
+	require.Nil(err)
+	require.Len(history, 3)
+	for x, def := range history {
+		assert.Equal(uint64(x+1), def.Revision)
+		assert.Equal(uuids[x], def.Uuid)
+	}
+	assert.Contains(history[0].Diff, "+one\n")
+	assert.Contains(history[2].Diff, "--- revision 2\n+++ revision 3\n")
+	assert.Contains(history[2].Diff, "-two\n+three\n")
+
+	// The latest revision is the current object definition
+	cur, err := s.ObjectDefinitionGet(ctx, "runm.image", part, "iso")
+	require.Nil(err)
+	assert.Equal(uint64(3), cur.Revision)
+	assert.Equal("three\n", cur.Schema)
+
+	def, err := s.ObjectDefinitionGetRevision(ctx, "runm.image", part, "iso", 2)
+	require.Nil(err)
+	assert.Equal("two\n", def.Schema)
+	_, err = s.ObjectDefinitionGetRevision(ctx, "runm.image", part, "iso", 4)
+	assert.Equal(errors.ErrNotFound, err)
+
+	// Other object definitions for the object type have their own history
+	history, err = s.ObjectDefinitionHistory(ctx, "runm.image", part, "")
+	require.Nil(err)
+	assert.Len(history, 0)
+}
+
+func TestObjectDefinitionHistoryMigration(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.TODO()
+
+	s, cleanup := newEtcdStore(t)
+	defer cleanup()
+	s.migrations = []keyspaceMigration{
+		historyObjectDefinitionsMigration,
+		historyPartitionObjectDefinitionsMigration,
+	}
+
+	// Store object definitions the way they were stored before object
+	// definitions were versioned
+	part := testPartition.Uuid
+	legacy := map[string]string{
+		objectDefinitionKey("runm.provider", "", ""):               "global",
+		objectDefinitionKey("runm.provider", part, "runm.compute"): "override",
+	}
+	for defKey, schema := range legacy {
+		def := &pb.ObjectDefinition{
+			Uuid:   util.NewNormalizedUuid(),
+			Schema: schema,
+		}
+		value, err := proto.Marshal(def)
+		require.Nil(err)
+		_, err = s.kv.Put(ctx, defKey, def.Uuid)
+		require.Nil(err)
+		uuidKey := _OBJECT_DEFINITIONS_BY_UUID_KEY + def.Uuid
+		_, err = s.kv.Put(ctx, uuidKey, string(value))
+		require.Nil(err)
+	}
+
+	reports, err := s.MigrateKeyspace(ctx, false)
+	require.Nil(err)
+	require.Len(reports, 2)
+	assert.Equal(1, reports[0].Puts)
+	assert.Equal(1, reports[1].Puts)
+
+	history, err := s.ObjectDefinitionHistory(ctx, "runm.provider", "", "")
+	require.Nil(err)
+	require.Len(history, 1)
+	assert.Equal(uint64(1), history[0].Revision)
+	assert.Equal("global", history[0].Schema)
+
+	history, err = s.ObjectDefinitionHistory(
+		ctx, "runm.provider", part, "runm.compute",
+	)
+	require.Nil(err)
+	require.Len(history, 1)
+	assert.Equal("override", history[0].Schema)
+
+	// Setting the object definition again continues from the migrated
+	// revision
+	def := &pb.ObjectDefinition{Schema: "changed"}
+	require.Nil(s.ObjectDefinitionSet(ctx, "runm.provider", "", "", def))
+	assert.Equal(uint64(2), def.Revision)
+	assert.Contains(def.Diff, "-global\n+changed\n")
+}
@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strconv"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/golang/protobuf/proto"
+
+	apitypes "github.com/runmachine-io/runmachine/pkg/api/types"
+	"github.com/runmachine-io/runmachine/pkg/metadata/conditions"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+const (
+	// $PARTITION/objects/by-tag/ is a key namespace that has a key namespace
+	// for each tag, containing valued keys where the key is the UUID of an
+	// object with that tag and the value is the object's type code
+	_OBJECTS_BY_TAG_KEY = "objects/by-tag/"
+	// $PARTITION/objects/by-property/ is a key namespace that has a key
+	// namespace for each property key and, under that, one for each property
+	// value, containing valued keys where the key is the UUID of an object
+	// with that property item and the value is the object's type code
+	_OBJECTS_BY_PROPERTY_KEY = "objects/by-property/"
+
+	// Maximum number of objects fetched from the objects/by-uuid/ index in a
+	// single transaction
+	_OBJECTS_GET_BATCH_SIZE = 100
+	// Maximum number of operations in the transaction that creates or updates
+	// an object and its index entries. This is etcd's default --max-txn-ops,
+	// and limits an object to 126 tags and properties combined.
+	_OBJECT_WRITE_MAX_OPS = 128
+)
+
+// indexObjectsMigration adds the by-tag and by-property index keys for
+// objects created before those indexes existed
+var indexObjectsMigration = keyspaceMigration{
+	description: "index objects by tag and property",
+	prefix:      _OBJECTS_BY_UUID_KEY,
+	transform: func(key string, value []byte) ([]keyChange, error) {
+		obj := &pb.Object{}
+		if err := proto.Unmarshal(value, obj); err != nil {
+			return nil, err
+		}
+		idx := objectIndexKeys(obj)
+		changes := make([]keyChange, 0, len(idx))
+		for k, v := range idx {
+			changes = append(changes, keyChange{Key: k, Value: []byte(v)})
+		}
+		// Changes are reported in key order so that dry runs are repeatable
+		sort.Slice(changes, func(i, j int) bool {
+			return changes[i].Key < changes[j].Key
+		})
+		return changes, nil
+	},
+}
+
+// indexKeyPart escapes a tag, property key or property value so that it can
+// be used as a single segment of an index key
+func indexKeyPart(s string) string {
+	return url.PathEscape(s)
+}
+
+// objectTagIndexPrefix returns the key namespace, relative to $PARTITION, of
+// the tag index entries for objects with the supplied tag
+func objectTagIndexPrefix(tag string) string {
+	return _OBJECTS_BY_TAG_KEY + indexKeyPart(tag) + "/"
+}
+
+// objectPropertyKeyIndexPrefix returns the key namespace, relative to
+// $PARTITION, of the property index entries for objects with a property with
+// the supplied key, regardless of its value
+func objectPropertyKeyIndexPrefix(key string) string {
+	return _OBJECTS_BY_PROPERTY_KEY + indexKeyPart(key) + "/"
+}
+
+// objectPropertyItemIndexPrefix returns the key namespace, relative to
+// $PARTITION, of the property index entries for objects with a property with
+// the supplied key and value
+func objectPropertyItemIndexPrefix(key string, value string) string {
+	return objectPropertyKeyIndexPrefix(key) + indexKeyPart(value) + "/"
+}
+
+// objectIndexKeys returns a map, keyed by index key relative to $ROOT, of the
+// tag and property index entries for the supplied object. The map values are
+// the object's type code.
+func objectIndexKeys(obj *pb.Object) map[string]string {
+	partKey := _PARTITIONS_KEY + obj.Partition + "/"
+	res := make(map[string]string, len(obj.Tags)+len(obj.Properties))
+	for _, tag := range obj.Tags {
+		res[partKey+objectTagIndexPrefix(tag)+obj.Uuid] = obj.ObjectType
+	}
+	for _, prop := range obj.Properties {
+		key := partKey + objectPropertyItemIndexPrefix(prop.Key, prop.Value) +
+			obj.Uuid
+		res[key] = obj.ObjectType
+	}
+	return res
+}
+
+// objectIndexLookups returns the sets of index key namespaces that, when each
+// set's UUIDs are unioned and the sets intersected, give the UUIDs of all
+// objects that may match the supplied condition's property and tag
+// conditions. Returns nil if the condition cannot be answered by the indexes.
+func objectIndexLookups(cond *conditions.ObjectCondition) [][]string {
+	res := make([][]string, 0)
+	if pc := cond.PropertyCondition; pc != nil {
+		for _, item := range pc.RequireItems {
+			res = append(res, []string{
+				objectPropertyItemIndexPrefix(item.Key, item.Value),
+			})
+		}
+		for _, key := range pc.RequireKeys {
+			res = append(res, []string{objectPropertyKeyIndexPrefix(key)})
+		}
+		if len(pc.AnyItems) > 0 {
+			any := make([]string, len(pc.AnyItems))
+			for x, item := range pc.AnyItems {
+				any[x] = objectPropertyItemIndexPrefix(item.Key, item.Value)
+			}
+			res = append(res, any)
+		}
+		if len(pc.AnyKeys) > 0 {
+			any := make([]string, len(pc.AnyKeys))
+			for x, key := range pc.AnyKeys {
+				any[x] = objectPropertyKeyIndexPrefix(key)
+			}
+			res = append(res, any)
+		}
+		for _, expr := range pc.Expressions {
+			res = append(res, propertyExpressionIndexLookups(expr))
+		}
+	}
+	if tc := cond.TagCondition; tc != nil {
+		for _, tag := range tc.RequireTags {
+			res = append(res, []string{objectTagIndexPrefix(tag)})
+		}
+		if len(tc.AnyTags) > 0 {
+			any := make([]string, len(tc.AnyTags))
+			for x, tag := range tc.AnyTags {
+				any[x] = objectTagIndexPrefix(tag)
+			}
+			res = append(res, any)
+		}
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
+
+// propertyExpressionIndexLookups returns the index key namespaces containing
+// the UUIDs of all objects that may match the supplied property expression.
+//
+// Property values are indexed by their string value, so only expressions that
+// compare string values for equality or by prefix can be narrowed to the
+// entries for particular property values. All other expressions look up every
+// object with the property key and rely on the expression being evaluated
+// against each object.
+//
+// Properties with integer or number typed values are compared numerically
+// even when the expression is not, so a numeric value in an equality
+// expression is also looked up by the string value a typed property with that
+// value is indexed by. For example, prop:x=2.0 looks up the entries for both
+// "2.0" and "2".
+func propertyExpressionIndexLookups(
+	expr *conditions.PropertyExpression,
+) []string {
+	if !expr.Numeric {
+		switch expr.Op {
+		case conditions.OP_EQUAL, conditions.OP_IN:
+			res := make([]string, 0, len(expr.Values))
+			seen := make(map[string]bool, len(expr.Values))
+			for _, value := range expr.Values {
+				values := []string{value}
+				if f, err := strconv.ParseFloat(value, 64); err == nil {
+					values = append(values, apitypes.PropertyValueString(f))
+				}
+				for _, v := range values {
+					if seen[v] {
+						continue
+					}
+					seen[v] = true
+					res = append(res, objectPropertyItemIndexPrefix(expr.Key, v))
+				}
+			}
+			return res
+		case conditions.OP_PREFIX:
+			// NOTE(jaypipes): indexKeyPart escapes each character
+			// independently, so the escaped prefix is a prefix of the escaped
+			// values beginning with it
+			return []string{
+				objectPropertyKeyIndexPrefix(expr.Key) +
+					indexKeyPart(expr.Values[0]),
+			}
+		}
+	}
+	return []string{objectPropertyKeyIndexPrefix(expr.Key)}
+}
+
+// objectsGetByUuids returns the Object messages with the supplied UUIDs,
+// skipping any UUIDs that are not found, in batched transactions
+func (s *Store) objectsGetByUuids(
+	ctx context.Context,
+	uuids []string,
+) ([]*pb.Object, error) {
+	res := make([]*pb.Object, 0, len(uuids))
+	for start := 0; start < len(uuids); start += _OBJECTS_GET_BATCH_SIZE {
+		end := start + _OBJECTS_GET_BATCH_SIZE
+		if end > len(uuids) {
+			end = len(uuids)
+		}
+		ops := make([]etcd.Op, 0, end-start)
+		for _, uuid := range uuids[start:end] {
+			ops = append(ops, etcd.OpGet(_OBJECTS_BY_UUID_KEY+uuid))
+		}
+		rctx, cancel := s.requestCtx(ctx)
+		resp, err := s.kv.Txn(rctx).Then(ops...).Commit()
+		cancel()
+		if err != nil {
+			s.log.ERR("error getting objects by UUID: %v", err)
+			return nil, err
+		}
+		for _, r := range resp.Responses {
+			for _, kv := range r.GetResponseRange().Kvs {
+				obj := &pb.Object{}
+				if err := proto.Unmarshal(kv.Value, obj); err != nil {
+					return nil, err
+				}
+				res = append(res, obj)
+			}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,477 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/runmachine-io/runmachine/pkg/errors"
+	"github.com/runmachine-io/runmachine/pkg/metadata/conditions"
+	"github.com/runmachine-io/runmachine/pkg/metadata/types"
+	"github.com/runmachine-io/runmachine/pkg/util"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+var (
+	testPartition = &pb.Partition{
+		Uuid: util.NewNormalizedUuid(),
+		Name: "part0",
+	}
+	testProviderType = &pb.ObjectType{
+		Code:  "runm.provider",
+		Scope: pb.ObjectTypeScope_PARTITION,
+	}
+	testImageType = &pb.ObjectType{
+		Code:  "runm.image",
+		Scope: pb.ObjectTypeScope_PARTITION,
+	}
+)
+
+func createObject(
+	t *testing.T,
+	s *Store,
+	ot *pb.ObjectType,
+	name string,
+	tags []string,
+	props map[string]string,
+) *pb.Object {
+	obj := &pb.Object{
+		Partition:  testPartition.Uuid,
+		ObjectType: ot.Code,
+		Name:       name,
+		Tags:       tags,
+	}
+	for k, v := range props {
+		obj.Properties = append(obj.Properties, &pb.Property{Key: k, Value: v})
+	}
+	_, err := s.ObjectCreate(context.TODO(), &types.ObjectWithReferences{
+		Partition:  testPartition,
+		ObjectType: ot,
+		Object:     obj,
+	})
+	require.Nil(t, err)
+	return obj
+}
+
+// findNames returns the sorted names of the objects matching the supplied
+// condition
+func findNames(
+	t *testing.T,
+	s *Store,
+	cond *conditions.ObjectCondition,
+) []string {
+	cur, err := s.ObjectFind(context.TODO(), []*conditions.ObjectCondition{cond})
+	require.Nil(t, err)
+	defer cur.Close()
+	names := make([]string, 0)
+	for cur.Next() {
+		obj := &pb.Object{}
+		require.Nil(t, cur.Scan(obj))
+		names = append(names, obj.Name)
+	}
+	require.Nil(t, cur.Err())
+	sort.Strings(names)
+	return names
+}
+
+func TestObjectIndexes(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.TODO()
+
+	s, cleanup := newEtcdStore(t)
+	defer cleanup()
+
+	createObject(t, s, testProviderType, "p0", []string{"gpu", "rack/1"},
+		map[string]string{"arch": "x86_64", "row": "1"})
+	p1 := createObject(t, s, testProviderType, "p1", []string{"gpu"},
+		map[string]string{"arch": "aarch64", "row": "1"})
+	createObject(t, s, testProviderType, "p2", nil,
+		map[string]string{"arch": "x86_64"})
+	createObject(t, s, testImageType, "i0", []string{"gpu"},
+		map[string]string{"arch": "x86_64"})
+
+	part := conditions.PartitionEqual(testPartition)
+	provs := conditions.ObjectTypeEqual(testProviderType)
+
+	tests := []struct {
+		cond   *conditions.ObjectCondition
+		expect []string
+	}{
+		{
+			cond: &conditions.ObjectCondition{
+				PartitionCondition: part,
+				PropertyCondition: &conditions.PropertyCondition{
+					RequireItems: []*pb.Property{{Key: "arch", Value: "x86_64"}},
+				},
+			},
+			expect: []string{"i0", "p0", "p2"},
+		},
+		{
+			cond: &conditions.ObjectCondition{
+				PartitionCondition:  part,
+				ObjectTypeCondition: provs,
+				PropertyCondition: &conditions.PropertyCondition{
+					RequireItems: []*pb.Property{{Key: "arch", Value: "x86_64"}},
+					RequireKeys:  []string{"row"},
+				},
+			},
+			expect: []string{"p0"},
+		},
+		{
+			cond: &conditions.ObjectCondition{
+				PartitionCondition: part,
+				PropertyCondition: &conditions.PropertyCondition{
+					AnyItems: []*pb.Property{
+						{Key: "arch", Value: "aarch64"},
+						{Key: "arch", Value: "ppc64le"},
+					},
+				},
+			},
+			expect: []string{"p1"},
+		},
+		{
+			cond: &conditions.ObjectCondition{
+				PartitionCondition:  part,
+				ObjectTypeCondition: provs,
+				TagCondition: &conditions.TagCondition{
+					RequireTags: []string{"gpu"},
+					ForbidTags:  []string{"rack/1"},
+				},
+			},
+			expect: []string{"p1"},
+		},
+		{
+			cond: &conditions.ObjectCondition{
+				PartitionCondition: part,
+				TagCondition: &conditions.TagCondition{
+					AnyTags: []string{"rack/1", "rack/2"},
+				},
+				PropertyCondition: &conditions.PropertyCondition{
+					RequireKeys: []string{"arch"},
+				},
+			},
+			expect: []string{"p0"},
+		},
+		{
+			cond: &conditions.ObjectCondition{
+				PartitionCondition: part,
+				TagCondition: &conditions.TagCondition{
+					RequireTags: []string{"unknown"},
+				},
+			},
+			expect: []string{},
+		},
+	}
+	for _, test := range tests {
+		assert.Equal(test.expect, findNames(t, s, test.cond), test.cond.String())
+	}
+
+	// Removing a tag from an object removes its tag index entry
+	gpu := &conditions.ObjectCondition{
+		PartitionCondition:  part,
+		ObjectTypeCondition: provs,
+		TagCondition: &conditions.TagCondition{
+			RequireTags: []string{"gpu"},
+		},
+	}
+	stale := *p1
+	p1.Tags = []string{"fpga"}
+	_, err := s.ObjectUpdate(ctx, &types.ObjectWithReferences{
+		Partition:  testPartition,
+		ObjectType: testProviderType,
+		Object:     p1,
+	})
+	assert.Nil(err)
+	assert.Equal(uint32(2), p1.Generation)
+	assert.Equal([]string{"p0"}, findNames(t, s, gpu))
+
+	// An update of an object read before the object was last changed fails
+	stale.Tags = []string{"gpu"}
+	_, err = s.ObjectUpdate(ctx, &types.ObjectWithReferences{
+		Partition:  testPartition,
+		ObjectType: testProviderType,
+		Object:     &stale,
+	})
+	assert.Equal(errors.ErrGenerationConflict, err)
+	assert.Equal([]string{"p0"}, findNames(t, s, gpu))
+
+	// Deleting an object removes all of its index entries
+	assert.Nil(s.ObjectDelete(ctx, &types.ObjectWithReferences{
+		Partition:  testPartition,
+		ObjectType: testProviderType,
+		Object:     p1,
+	}))
+	resp, err := s.kv.Get(
+		ctx, _PARTITIONS_KEY, etcd.WithPrefix(), etcd.WithKeysOnly(),
+	)
+	require.Nil(t, err)
+	for _, kv := range resp.Kvs {
+		assert.NotContains(string(kv.Key), p1.Uuid)
+	}
+}
+
+func TestObjectTooManyIndexEntries(t *testing.T) {
+	assert := assert.New(t)
+
+	s, cleanup := newEtcdStore(t)
+	defer cleanup()
+
+	// Each tag and property has an index entry, and the object and its
+	// by-name index entry are written in the same transaction
+	tags := make([]string, _OBJECT_WRITE_MAX_OPS-1)
+	for x := range tags {
+		tags[x] = fmt.Sprintf("tag%d", x)
+	}
+	_, err := s.ObjectCreate(context.TODO(), &types.ObjectWithReferences{
+		Partition:  testPartition,
+		ObjectType: testProviderType,
+		Object: &pb.Object{
+			Partition:  testPartition.Uuid,
+			ObjectType: testProviderType.Code,
+			Name:       "p0",
+			Tags:       tags,
+		},
+	})
+	assert.Equal(errors.ErrBadInput, err)
+
+	p1 := createObject(t, s, testProviderType, "p1", tags[:len(tags)-1], nil)
+	tag0 := &conditions.ObjectCondition{
+		PartitionCondition: conditions.PartitionEqual(testPartition),
+		TagCondition: &conditions.TagCondition{
+			RequireTags: []string{"tag0"},
+		},
+	}
+	assert.Equal([]string{"p1"}, findNames(t, s, tag0))
+
+	// An object cannot grow past the limit by being updated, since it
+	// could then not be deleted
+	grown := *p1
+	grown.Tags = tags
+	_, err = s.ObjectUpdate(context.TODO(), &types.ObjectWithReferences{
+		Partition:  testPartition,
+		ObjectType: testProviderType,
+		Object:     &grown,
+	})
+	assert.Equal(errors.ErrBadInput, err)
+
+	assert.Nil(s.ObjectDelete(context.TODO(), &types.ObjectWithReferences{
+		Partition:  testPartition,
+		ObjectType: testProviderType,
+		Object:     p1,
+	}))
+	assert.Equal([]string{}, findNames(t, s, tag0))
+}
+
+func TestIndexObjectsMigration(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.TODO()
+
+	s, cleanup := newEtcdStore(t)
+	defer cleanup()
+	s.migrations = []keyspaceMigration{indexObjectsMigration}
+
+	// Store an object the way it was stored before the tag and property
+	// indexes existed
+	obj := &pb.Object{
+		Partition:  testPartition.Uuid,
+		ObjectType: testProviderType.Code,
+		Uuid:       util.NewNormalizedUuid(),
+		Name:       "p0",
+		Tags:       []string{"gpu"},
+		Properties: []*pb.Property{{Key: "arch", Value: "x86_64"}},
+	}
+	value, err := proto.Marshal(obj)
+	require.Nil(err)
+	_, err = s.kv.Put(ctx, _OBJECTS_BY_UUID_KEY+obj.Uuid, string(value))
+	require.Nil(err)
+
+	reports, err := s.MigrateKeyspace(ctx, false)
+	require.Nil(err)
+	require.Len(reports, 1)
+	assert.Equal(2, reports[0].Puts)
+
+	cond := &conditions.ObjectCondition{
+		PartitionCondition: conditions.PartitionEqual(testPartition),
+		TagCondition: &conditions.TagCondition{
+			RequireTags: []string{"gpu"},
+		},
+		PropertyCondition: &conditions.PropertyCondition{
+			RequireItems: []*pb.Property{{Key: "arch", Value: "x86_64"}},
+		},
+	}
+	assert.Equal([]string{"p0"}, findNames(t, s, cond))
+}
+
+func TestObjectPropertyExpressions(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, cleanup := newEtcdStore(t)
+	defer cleanup()
+
+	createObject(t, s, testProviderType, "p0", nil,
+		map[string]string{"row": "2", "os": "linux-5.4"})
+	createObject(t, s, testProviderType, "p1", nil,
+		map[string]string{"row": "3", "os": "linux-4.19"})
+	createObject(t, s, testProviderType, "p2", nil,
+		map[string]string{"row": "10", "os": "windows"})
+
+	expr := func(
+		key string,
+		op conditions.Op,
+		numeric bool,
+		values ...string,
+	) *conditions.PropertyExpression {
+		e, err := conditions.NewPropertyExpression(key, op, values)
+		require.Nil(err)
+		e.Numeric = numeric
+		return e
+	}
+
+	tests := []struct {
+		expr   *conditions.PropertyExpression
+		expect []string
+	}{
+		{
+			// Properties not defined as numeric are compared lexically
+			expr:   expr("row", conditions.OP_GREATER_THAN_EQUAL, false, "3"),
+			expect: []string{"p1"},
+		},
+		{
+			expr:   expr("row", conditions.OP_GREATER_THAN_EQUAL, true, "3"),
+			expect: []string{"p1", "p2"},
+		},
+		{
+			expr:   expr("row", conditions.OP_LESSER_THAN, true, "3"),
+			expect: []string{"p0"},
+		},
+		{
+			expr:   expr("row", conditions.OP_IN, false, "2", "10", "11"),
+			expect: []string{"p0", "p2"},
+		},
+		{
+			expr:   expr("os", conditions.OP_REGEX, false, "^linux-[0-9]\\.[0-9]$"),
+			expect: []string{"p0"},
+		},
+		{
+			expr:   expr("os", conditions.OP_PREFIX, false, "linux"),
+			expect: []string{"p0", "p1"},
+		},
+		{
+			expr:   expr("os", conditions.OP_EQUAL, false, "windows"),
+			expect: []string{"p2"},
+		},
+	}
+	for _, test := range tests {
+		cond := &conditions.ObjectCondition{
+			PartitionCondition:  conditions.PartitionEqual(testPartition),
+			ObjectTypeCondition: conditions.ObjectTypeEqual(testProviderType),
+			PropertyCondition: &conditions.PropertyCondition{
+				Expressions: []*conditions.PropertyExpression{test.expr},
+			},
+		}
+		assert.Equal(test.expect, findNames(t, s, cond), test.expr.String())
+	}
+
+	// Properties with integer or number typed values are always compared
+	// numerically
+	typed := &conditions.PropertyExpression{
+		Key:    "row",
+		Op:     conditions.OP_GREATER_THAN,
+		Values: []string{"9"},
+	}
+	assert.True(typed.Matches(&pb.Property{
+		Key:   "row",
+		Value: "10",
+		TypedValue: &pb.PropertyValue{
+			Kind: &pb.PropertyValue_IntegerValue{IntegerValue: 10},
+		},
+	}))
+	assert.False(typed.Matches(&pb.Property{Key: "row", Value: "10"}))
+
+	_, err := conditions.NewPropertyExpression(
+		"os", conditions.OP_REGEX, []string{"linux("},
+	)
+	assert.NotNil(err)
+}
+
+// TestObjectPropertyExpressionAccessPaths checks that a property expression
+// finds the same objects whether the by-property index is used or all objects
+// are scanned
+func TestObjectPropertyExpressionAccessPaths(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.TODO()
+
+	s, cleanup := newEtcdStore(t)
+	defer cleanup()
+
+	createObject(t, s, testProviderType, "p0", nil,
+		map[string]string{"row": "2"})
+	createObject(t, s, testProviderType, "p1", nil,
+		map[string]string{"row": "2.0"})
+	_, err := s.ObjectCreate(ctx, &types.ObjectWithReferences{
+		Partition:  testPartition,
+		ObjectType: testProviderType,
+		Object: &pb.Object{
+			Partition:  testPartition.Uuid,
+			ObjectType: testProviderType.Code,
+			Name:       "p2",
+			Properties: []*pb.Property{
+				&pb.Property{
+					Key:   "row",
+					Value: "2",
+					TypedValue: &pb.PropertyValue{
+						Kind: &pb.PropertyValue_IntegerValue{IntegerValue: 2},
+					},
+				},
+			},
+		},
+	})
+	require.Nil(err)
+
+	tests := []struct {
+		op     conditions.Op
+		values []string
+		expect []string
+	}{
+		{conditions.OP_EQUAL, []string{"2.0"}, []string{"p1", "p2"}},
+		{conditions.OP_EQUAL, []string{"2"}, []string{"p0", "p2"}},
+		{conditions.OP_IN, []string{"2.00", "3"}, []string{"p2"}},
+		{conditions.OP_EQUAL, []string{"3"}, []string{}},
+	}
+	for _, test := range tests {
+		expr, err := conditions.NewPropertyExpression("row", test.op, test.values)
+		require.Nil(err)
+		cond := &conditions.ObjectCondition{
+			PartitionCondition: conditions.PartitionEqual(testPartition),
+			PropertyCondition: &conditions.PropertyCondition{
+				Expressions: []*conditions.PropertyExpression{expr},
+			},
+		}
+		for _, p := range objectAccessPaths(cond) {
+			if p.Access != ACCESS_TAG_PROPERTY && p.Access != ACCESS_SCAN {
+				continue
+			}
+			cur, err := s.ExecuteObjectFind(ctx, []*QueryPlan{p})
+			require.Nil(err)
+			names := make([]string, 0)
+			for cur.Next() {
+				obj := &pb.Object{}
+				require.Nil(cur.Scan(obj))
+				names = append(names, obj.Name)
+			}
+			require.Nil(cur.Err())
+			cur.Close()
+			sort.Strings(names)
+			assert.Equal(test.expect, names, "%s via %s", expr, p.Access)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"strings"
 
 	etcd "github.com/coreos/etcd/clientv3"
@@ -46,7 +47,7 @@ var (
 // ensureObjectTypes is responsible for making sure etcd has the well-known
 // runm object types in storage.
 func (s *Store) ensureObjectTypes() error {
-	ctx, cancel := s.requestCtx()
+	ctx, cancel := s.requestCtx(context.Background())
 	defer cancel()
 
 	s.log.L3("ensuring object types...")
@@ -70,7 +71,7 @@ func (s *Store) ensureObjectTypes() error {
 	for _, ot := range runmObjectTypes {
 		if _, ok := all[ot.Code]; !ok {
 			s.log.L3("object type %s not in storage. adding...", ot.Code)
-			if err = s.objectTypeCreate(ot); err != nil {
+			if err = s.objectTypeCreate(ctx, ot); err != nil {
 				if err == errors.ErrDuplicate {
 					// some other thread created the object type... just ignore
 					continue
@@ -86,9 +87,10 @@ func (s *Store) ensureObjectTypes() error {
 // ObjectTypeGet returns an ObjectType protobuffer object having the supplied
 // code
 func (s *Store) ObjectTypeGetByCode(
+	ctx context.Context,
 	code string,
 ) (*pb.ObjectType, error) {
-	ctx, cancel := s.requestCtx()
+	ctx, cancel := s.requestCtx(ctx)
 	defer cancel()
 
 	key := _OBJECT_TYPES_KEY + code
@@ -113,11 +115,12 @@ func (s *Store) ObjectTypeGetByCode(
 // ObjectTypeFind returns a slice of pointers to ObjectType protobuffer
 // messages matching a set of supplied filters.
 func (s *Store) ObjectTypeFind(
+	ctx context.Context,
 	any []*pb.ObjectTypeFilter,
 ) ([]*pb.ObjectType, error) {
 	if len(any) == 0 {
 		// Just return all object types
-		return s.objectTypesGetByCode("", true)
+		return s.objectTypesGetByCode(ctx, "", true)
 	}
 
 	// Each filter is evaluated in an OR fashion, so we keep a hashmap of
@@ -126,6 +129,7 @@ func (s *Store) ObjectTypeFind(
 	for _, filter := range any {
 		if filter.CodeFilter != nil {
 			filterObjs, err := s.objectTypesGetByCode(
+				ctx,
 				filter.CodeFilter.Code,
 				filter.CodeFilter.UsePrefix,
 			)
@@ -147,10 +151,11 @@ func (s *Store) ObjectTypeFind(
 }
 
 func (s *Store) objectTypesGetByCode(
+	ctx context.Context,
 	code string,
 	usePrefix bool,
 ) ([]*pb.ObjectType, error) {
-	ctx, cancel := s.requestCtx()
+	ctx, cancel := s.requestCtx(ctx)
 	defer cancel()
 
 	key := _OBJECT_TYPES_KEY + code
@@ -190,9 +195,10 @@ func (s *Store) objectTypesGetByCode(
 // objectTypeCreate writes the supplied ObjectType object to the key at
 // $ROOT/object-types/{object_type_code}
 func (s *Store) objectTypeCreate(
+	ctx context.Context,
 	obj *pb.ObjectType,
 ) error {
-	ctx, cancel := s.requestCtx()
+	ctx, cancel := s.requestCtx(ctx)
 	defer cancel()
 
 	key := _OBJECT_TYPES_KEY + obj.Code
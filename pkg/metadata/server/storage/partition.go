@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	etcd "github.com/coreos/etcd/clientv3"
 	etcd_namespace "github.com/coreos/etcd/clientv3/namespace"
 	"github.com/golang/protobuf/proto"
@@ -35,9 +36,10 @@ func (s *Store) kvPartition(partUuid string) etcd.KV {
 // PartitionGetByUuid returns a Partition protobuffer message with the supplied
 // UUID
 func (s *Store) PartitionGetByUuid(
+	ctx context.Context,
 	uuid string,
 ) (*pb.Partition, error) {
-	ctx, cancel := s.requestCtx()
+	ctx, cancel := s.requestCtx(ctx)
 	defer cancel()
 	key := _PARTITIONS_BY_UUID_KEY + util.NormalizeUuid(uuid)
 	resp, err := s.kv.Get(ctx, key)
@@ -58,9 +60,10 @@ func (s *Store) PartitionGetByUuid(
 // PartitionGetByName returns a Partition protobuffer message with the supplied
 // name
 func (s *Store) PartitionGetByName(
+	ctx context.Context,
 	name string,
 ) (*pb.Partition, error) {
-	ctx, cancel := s.requestCtx()
+	ctx, cancel := s.requestCtx(ctx)
 	defer cancel()
 	byNameKey := _PARTITIONS_BY_NAME_KEY + name
 	resp, err := s.kv.Get(ctx, byNameKey)
@@ -72,7 +75,7 @@ func (s *Store) PartitionGetByName(
 		return nil, errors.ErrNotFound
 	}
 	partUuid := string(resp.Kvs[0].Value)
-	p, err := s.PartitionGetByUuid(partUuid)
+	p, err := s.PartitionGetByUuid(ctx, partUuid)
 	if p != nil {
 		return p, nil
 	}
@@ -96,10 +99,11 @@ func (s *Store) PartitionGetByName(
 // PartitionFind returns a cursor that may be used to iterate over Partition
 // protobuffer objects stored in etcd
 func (s *Store) PartitionFind(
+	ctx context.Context,
 	any []*pb.PartitionFindFilter,
 ) ([]*pb.Partition, error) {
 	if len(any) == 0 {
-		return s.partitionsGetAll()
+		return s.partitionsGetAll(ctx)
 	}
 
 	// OK, we've got some filters so we need to process each filter, OR'ing
@@ -120,6 +124,7 @@ func (s *Store) PartitionFind(
 		}
 
 		uuidsByName, err := s.partitionUuidsGetByName(
+			ctx,
 			filter.NameFilter.Name,
 			filter.NameFilter.UsePrefix,
 		)
@@ -146,7 +151,7 @@ func (s *Store) PartitionFind(
 	res := make([]*pb.Partition, len(uuids))
 	x := 0
 	for uuid := range uuids {
-		obj, err := s.PartitionGetByUuid(uuid)
+		obj, err := s.PartitionGetByUuid(ctx, uuid)
 		if err != nil {
 			if err == errors.ErrNotFound {
 				continue
@@ -162,10 +167,11 @@ func (s *Store) PartitionFind(
 // partitionUuidsGetByName returns a slice of strings with all partition UUIDs
 // have a supplied name
 func (s *Store) partitionUuidsGetByName(
+	ctx context.Context,
 	search string,
 	usePrefix bool,
 ) ([]string, error) {
-	ctx, cancel := s.requestCtx()
+	ctx, cancel := s.requestCtx(ctx)
 	defer cancel()
 
 	key := _PARTITIONS_BY_NAME_KEY + search
@@ -196,8 +202,10 @@ func (s *Store) partitionUuidsGetByName(
 	return res, nil
 }
 
-func (s *Store) partitionsGetAll() ([]*pb.Partition, error) {
-	ctx, cancel := s.requestCtx()
+func (s *Store) partitionsGetAll(
+	ctx context.Context,
+) ([]*pb.Partition, error) {
+	ctx, cancel := s.requestCtx(ctx)
 	defer cancel()
 
 	resp, err := s.kv.Get(
@@ -233,9 +241,10 @@ func (s *Store) partitionsGetAll() ([]*pb.Partition, error) {
 // Returns the Partition that was written to storage, which may have had a UUID
 // created for it.
 func (s *Store) PartitionCreate(
+	ctx context.Context,
 	part *pb.Partition,
 ) (*pb.Partition, error) {
-	ctx, cancel := s.requestCtx()
+	ctx, cancel := s.requestCtx(ctx)
 	defer cancel()
 
 	if part.Uuid == "" {
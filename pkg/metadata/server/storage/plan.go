@@ -0,0 +1,401 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	etcd "github.com/coreos/etcd/clientv3"
+
+	"github.com/runmachine-io/runmachine/pkg/abstract"
+	"github.com/runmachine-io/runmachine/pkg/metadata/conditions"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// The access paths a QueryPlan may use to find the objects that may match an
+// ObjectCondition, in order of preference when their costs are equal
+const (
+	// Objects are read from the primary objects/by-uuid/ index
+	ACCESS_UUID = "uuid"
+	// Object UUIDs are read from the by-name index of an object type, and
+	// optionally project, in a partition
+	ACCESS_NAME = "by-name"
+	// Object UUIDs are read from the by-tag and by-property indexes in a
+	// partition
+	ACCESS_TAG_PROPERTY = "by-tag-property"
+	// Object UUIDs are read from all by-name index entries of an object type,
+	// and optionally project, in a partition
+	ACCESS_TYPE = "by-type"
+	// All objects are read from the primary objects/by-uuid/ index
+	ACCESS_SCAN = "scan"
+)
+
+// indexRead describes the read of a key, or a key namespace, of an index into
+// objects
+type indexRead struct {
+	// The key or key namespace, relative to $ROOT
+	key    string
+	prefix bool
+	// If true, the values of the index keys are object UUIDs. Otherwise, the
+	// last segment of each index key is an object UUID and the value is the
+	// object's type code.
+	uuidInValue bool
+}
+
+// QueryPlan describes how the objects matching an ObjectCondition are found.
+// The access path finds the objects that may match the condition and the
+// entire condition is then evaluated against each of those objects.
+type QueryPlan struct {
+	Condition *conditions.ObjectCondition
+	// The chosen access path
+	Access string
+	// The estimated number of keys read from etcd to find the objects that
+	// may match the condition
+	Cost int64
+	// The estimated cost of each access path considered, in the order
+	// considered
+	Considered []string
+	// The UUIDs of the objects that may match, for the ACCESS_UUID path
+	uuids []string
+	// Sets of index reads. The UUIDs found by the reads in a set are unioned
+	// and the results of all sets are intersected.
+	reads [][]indexRead
+}
+
+func (p *QueryPlan) String() string {
+	keys := make([]string, 0)
+	for _, set := range p.reads {
+		setKeys := make([]string, len(set))
+		for x, r := range set {
+			setKeys[x] = r.key
+			if r.prefix {
+				setKeys[x] += "*"
+			}
+		}
+		keys = append(keys, strings.Join(setKeys, "|"))
+	}
+	for _, uuid := range p.uuids {
+		keys = append(keys, _OBJECTS_BY_UUID_KEY+uuid)
+	}
+	if p.Access == ACCESS_SCAN {
+		keys = append(keys, _OBJECTS_BY_UUID_KEY+"*")
+	}
+	return fmt.Sprintf(
+		"%s access=%s cost=%d keys=[%s] considered=[%s]",
+		p.Condition,
+		p.Access,
+		p.Cost,
+		strings.Join(keys, " & "),
+		strings.Join(p.Considered, ","),
+	)
+}
+
+// PlanObjectFind returns a QueryPlan for each of the supplied conditions. If
+// no conditions are supplied, the returned plan finds all objects.
+func (s *Store) PlanObjectFind(
+	ctx context.Context,
+	any []*conditions.ObjectCondition,
+) ([]*QueryPlan, error) {
+	if len(any) == 0 {
+		any = []*conditions.ObjectCondition{&conditions.ObjectCondition{}}
+	}
+	plans := make([]*QueryPlan, 0, len(any))
+	for _, cond := range any {
+		if cond == nil {
+			s.log.ERR("received nil conditions.ObjectCondition in PlanObjectFind()")
+			continue
+		}
+		plan, err := s.planObjectCondition(ctx, cond)
+		if err != nil {
+			return nil, err
+		}
+		s.log.L3("object find plan: %s", plan)
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+// planObjectCondition estimates the cost of each access path that can be used
+// to evaluate the supplied condition and returns a QueryPlan using the
+// cheapest one
+func (s *Store) planObjectCondition(
+	ctx context.Context,
+	cond *conditions.ObjectCondition,
+) (*QueryPlan, error) {
+	var best *QueryPlan
+	considered := make([]string, 0)
+	for _, p := range objectAccessPaths(cond) {
+		cost, err := s.estimateCost(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		p.Cost = cost
+		considered = append(considered, fmt.Sprintf("%s=%d", p.Access, cost))
+		if best == nil || cost < best.Cost {
+			best = p
+		}
+		if best.Cost <= 1 {
+			// Nothing is going to be cheaper than reading a single key
+			break
+		}
+	}
+	best.Considered = considered
+	return best, nil
+}
+
+// objectAccessPaths returns uncosted QueryPlans for each access path that can
+// be used to evaluate the supplied condition, in order of preference
+func objectAccessPaths(cond *conditions.ObjectCondition) []*QueryPlan {
+	res := make([]*QueryPlan, 0)
+	if uc := cond.UuidCondition; uc != nil && uc.Uuid != "" &&
+		uc.Op == conditions.OP_EQUAL {
+		res = append(res, &QueryPlan{
+			Condition: cond,
+			Access:    ACCESS_UUID,
+			uuids:     []string{uc.Uuid},
+		})
+	} else if uc := cond.UuidsCondition; uc != nil && len(uc.Uuids) > 0 &&
+		uc.Op == conditions.OP_IN {
+		uuids := make([]string, len(uc.Uuids))
+		copy(uuids, uc.Uuids)
+		sort.Strings(uuids)
+		res = append(res, &QueryPlan{
+			Condition: cond,
+			Access:    ACCESS_UUID,
+			uuids:     uuids,
+		})
+	}
+
+	// The secondary indexes are all under a partition's key namespace
+	pc := cond.PartitionCondition
+	if pc != nil && pc.Partition != nil && pc.Op == conditions.OP_EQUAL {
+		partKey := _PARTITIONS_KEY + pc.Partition.Uuid + "/"
+		if typeKey := objectTypeIndexKey(cond); typeKey != "" {
+			if nc := cond.NameCondition; nc != nil && nc.Name != "" &&
+				(nc.Op == conditions.OP_EQUAL ||
+					nc.Op == conditions.OP_GREATER_THAN_EQUAL) {
+				res = append(res, &QueryPlan{
+					Condition: cond,
+					Access:    ACCESS_NAME,
+					reads: [][]indexRead{{{
+						key:         partKey + typeKey + _BY_NAME_KEY + nc.Name,
+						prefix:      nc.Op != conditions.OP_EQUAL,
+						uuidInValue: true,
+					}}},
+				})
+			}
+		}
+		if lookups := objectIndexLookups(cond); lookups != nil {
+			reads := make([][]indexRead, len(lookups))
+			for x, prefixes := range lookups {
+				reads[x] = make([]indexRead, len(prefixes))
+				for y, prefix := range prefixes {
+					reads[x][y] = indexRead{key: partKey + prefix, prefix: true}
+				}
+			}
+			res = append(res, &QueryPlan{
+				Condition: cond,
+				Access:    ACCESS_TAG_PROPERTY,
+				reads:     reads,
+			})
+		}
+		if typeKey := objectTypeIndexKey(cond); typeKey != "" {
+			res = append(res, &QueryPlan{
+				Condition: cond,
+				Access:    ACCESS_TYPE,
+				reads: [][]indexRead{{{
+					key:         partKey + typeKey,
+					prefix:      true,
+					uuidInValue: true,
+				}}},
+			})
+		}
+	}
+
+	res = append(res, &QueryPlan{
+		Condition: cond,
+		Access:    ACCESS_SCAN,
+	})
+	return res
+}
+
+// objectTypeIndexKey returns the key namespace, relative to $PARTITION, of the
+// by-name index entries of the supplied condition's object type and, for
+// project-scoped object types, project. Returns an empty string if the
+// condition does not specify a single object type or if the object type is
+// project-scoped and the condition has no project.
+func objectTypeIndexKey(cond *conditions.ObjectCondition) string {
+	tc := cond.ObjectTypeCondition
+	if tc == nil || tc.ObjectType == nil || tc.Op != conditions.OP_EQUAL {
+		return ""
+	}
+	key := _OBJECTS_BY_TYPE_KEY + tc.ObjectType.Code + "/"
+	switch tc.ObjectType.Scope {
+	case pb.ObjectTypeScope_PARTITION:
+		return key
+	case pb.ObjectTypeScope_PROJECT:
+		if cond.ProjectCondition == "" {
+			return ""
+		}
+		return key + _BY_PROJECT_KEY + cond.ProjectCondition + "/"
+	}
+	return ""
+}
+
+// estimateCost returns the number of keys the supplied plan would read from
+// etcd to find the objects that may match its condition
+func (s *Store) estimateCost(ctx context.Context, p *QueryPlan) (int64, error) {
+	switch p.Access {
+	case ACCESS_UUID:
+		return int64(len(p.uuids)), nil
+	case ACCESS_SCAN:
+		return s.countKeys(ctx, _OBJECTS_BY_UUID_KEY, true)
+	}
+	cost := int64(0)
+	for _, set := range p.reads {
+		for _, r := range set {
+			count, err := s.countKeys(ctx, r.key, r.prefix)
+			if err != nil {
+				return 0, err
+			}
+			cost += count
+		}
+	}
+	return cost, nil
+}
+
+// countKeys returns the number of keys with the supplied key, relative to
+// $ROOT, or under the supplied key namespace if prefix is true
+func (s *Store) countKeys(
+	ctx context.Context,
+	key string,
+	prefix bool,
+) (int64, error) {
+	if !prefix {
+		// A single key is always assumed to exist
+		return 1, nil
+	}
+	ctx, cancel := s.requestCtx(ctx)
+	defer cancel()
+	resp, err := s.kv.Get(ctx, key, etcd.WithPrefix(), etcd.WithCountOnly())
+	if err != nil {
+		s.log.ERR("error counting keys in %s: %v", key, err)
+		return 0, err
+	}
+	return resp.Count, nil
+}
+
+// ExecuteObjectFind returns a cursor over the objects matching the condition
+// of any of the supplied plans, ordered by UUID. Objects are read from etcd as
+// the cursor is iterated.
+func (s *Store) ExecuteObjectFind(
+	ctx context.Context,
+	plans []*QueryPlan,
+) (abstract.Cursor, error) {
+	cursors := make([]*objectCursor, 0, len(plans))
+	for _, p := range plans {
+		fetch, err := s.planFetcher(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		cursors = append(cursors, newObjectCursor(fetch))
+	}
+	return newObjectCursor(mergeObjects(cursors)), nil
+}
+
+// planFetcher returns a fetcher of the objects matching the condition of the
+// supplied plan, ordered by UUID
+func (s *Store) planFetcher(
+	ctx context.Context,
+	p *QueryPlan,
+) (objectFetcher, error) {
+	var fetch objectFetcher
+	switch p.Access {
+	case ACCESS_SCAN:
+		fetch = s.objectScanFetcher(ctx)
+	case ACCESS_UUID:
+		fetch = s.objectUuidsFetcher(ctx, p.uuids)
+	default:
+		// NOTE(jaypipes): The UUIDs found in the indexes are held in memory
+		// in order to intersect them, but the objects themselves are read in
+		// batches as the cursor is iterated.
+		uuids, err := s.indexCandidates(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		fetch = s.objectUuidsFetcher(ctx, uuids)
+	}
+	// Use a sieve pattern, only returning the object if it passes all match
+	// expressions, including those the access path didn't use
+	return filterObjects(fetch, p.Condition), nil
+}
+
+// indexCandidates performs the index reads of the supplied plan and returns
+// the sorted UUIDs of the objects that may match the plan's condition
+func (s *Store) indexCandidates(
+	ctx context.Context,
+	p *QueryPlan,
+) ([]string, error) {
+	var candidates map[string]bool
+	for _, set := range p.reads {
+		found := make(map[string]bool, 0)
+		for _, r := range set {
+			uuids, err := s.indexRead(ctx, r, p.Condition)
+			if err != nil {
+				return nil, err
+			}
+			for _, uuid := range uuids {
+				if candidates == nil || candidates[uuid] {
+					found[uuid] = true
+				}
+			}
+		}
+		candidates = found
+		if len(candidates) == 0 {
+			break
+		}
+	}
+	res := make([]string, 0, len(candidates))
+	for uuid := range candidates {
+		res = append(res, uuid)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// indexRead returns the UUIDs of the objects found by the supplied index
+// read. For indexes that record object type codes, only objects having the
+// object type of the supplied condition, if any, are returned.
+func (s *Store) indexRead(
+	ctx context.Context,
+	r indexRead,
+	cond *conditions.ObjectCondition,
+) ([]string, error) {
+	ctx, cancel := s.requestCtx(ctx)
+	defer cancel()
+
+	opts := []etcd.OpOption{}
+	if r.prefix {
+		opts = append(opts, etcd.WithPrefix())
+	}
+	resp, err := s.kv.Get(ctx, r.key, opts...)
+	if err != nil {
+		s.log.ERR("error reading object index %s: %v", r.key, err)
+		return nil, err
+	}
+	res := make([]string, 0, len(resp.Kvs))
+	for _, entry := range resp.Kvs {
+		if r.uuidInValue {
+			res = append(res, string(entry.Value))
+			continue
+		}
+		key := string(entry.Key)
+		objType := &pb.Object{ObjectType: string(entry.Value)}
+		if !cond.ObjectTypeCondition.Matches(objType) {
+			continue
+		}
+		res = append(res, key[strings.LastIndex(key, "/")+1:])
+	}
+	return res, nil
+}
@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/runmachine-io/runmachine/pkg/abstract"
+	"github.com/runmachine-io/runmachine/pkg/metadata/conditions"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+func TestPlanObjectFind(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.TODO()
+
+	s, cleanup := newEtcdStore(t)
+	defer cleanup()
+
+	provs := make([]*pb.Object, 5)
+	for x := range provs {
+		tags := []string{"common"}
+		if x == 0 {
+			tags = append(tags, "rare")
+		}
+		provs[x] = createObject(
+			t, s, testProviderType, fmt.Sprintf("p%d", x), tags, nil,
+		)
+	}
+	img := createObject(t, s, testImageType, "i0", []string{"common"}, nil)
+
+	part := conditions.PartitionEqual(testPartition)
+	provType := conditions.ObjectTypeEqual(testProviderType)
+	projectType := conditions.ObjectTypeEqual(&pb.ObjectType{
+		Code:  "runm.machine",
+		Scope: pb.ObjectTypeScope_PROJECT,
+	})
+
+	tests := []struct {
+		name   string
+		cond   *conditions.ObjectCondition
+		access string
+		cost   int64
+	}{
+		{
+			name:   "no condition scans",
+			cond:   &conditions.ObjectCondition{},
+			access: ACCESS_SCAN,
+			cost:   6,
+		},
+		{
+			name: "uuid",
+			cond: &conditions.ObjectCondition{
+				PartitionCondition: part,
+				UuidCondition:      conditions.UuidEqual(img.Uuid),
+			},
+			access: ACCESS_UUID,
+			cost:   1,
+		},
+		{
+			name: "uuids",
+			cond: &conditions.ObjectCondition{
+				UuidsCondition: conditions.UuidIn(
+					[]string{provs[1].Uuid, provs[0].Uuid},
+				),
+			},
+			access: ACCESS_UUID,
+			cost:   2,
+		},
+		{
+			name: "exact name",
+			cond: &conditions.ObjectCondition{
+				PartitionCondition:  part,
+				ObjectTypeCondition: provType,
+				NameCondition:       conditions.NameEqual("p1"),
+			},
+			access: ACCESS_NAME,
+			cost:   1,
+		},
+		{
+			name: "name prefix",
+			cond: &conditions.ObjectCondition{
+				PartitionCondition:  part,
+				ObjectTypeCondition: provType,
+				NameCondition:       conditions.NameLike("p"),
+			},
+			access: ACCESS_NAME,
+			cost:   5,
+		},
+		{
+			name: "type range cheaper than common tag",
+			cond: &conditions.ObjectCondition{
+				PartitionCondition:  part,
+				ObjectTypeCondition: provType,
+				TagCondition: &conditions.TagCondition{
+					RequireTags: []string{"common"},
+				},
+			},
+			access: ACCESS_TYPE,
+			cost:   5,
+		},
+		{
+			name: "rare tag cheaper than type range",
+			cond: &conditions.ObjectCondition{
+				PartitionCondition:  part,
+				ObjectTypeCondition: provType,
+				TagCondition: &conditions.TagCondition{
+					RequireTags: []string{"rare"},
+				},
+			},
+			access: ACCESS_TAG_PROPERTY,
+			cost:   1,
+		},
+		{
+			name: "project-scoped type without project scans",
+			cond: &conditions.ObjectCondition{
+				PartitionCondition:  part,
+				ObjectTypeCondition: projectType,
+			},
+			access: ACCESS_SCAN,
+			cost:   6,
+		},
+		{
+			name: "project-scoped type with project",
+			cond: &conditions.ObjectCondition{
+				PartitionCondition:  part,
+				ObjectTypeCondition: projectType,
+				ProjectCondition:    "proj0",
+			},
+			access: ACCESS_TYPE,
+			cost:   0,
+		},
+	}
+	for _, test := range tests {
+		plans, err := s.PlanObjectFind(
+			ctx, []*conditions.ObjectCondition{test.cond},
+		)
+		require.Nil(err, test.name)
+		require.Len(plans, 1, test.name)
+		assert.Equal(test.access, plans[0].Access, test.name)
+		assert.Equal(test.cost, plans[0].Cost, test.name)
+		assert.NotEmpty(plans[0].Considered, test.name)
+		assert.Contains(plans[0].String(), "access="+test.access, test.name)
+	}
+
+	// OR'd conditions are merged in UUID order without duplicates
+	plans, err := s.PlanObjectFind(ctx, []*conditions.ObjectCondition{
+		{
+			PartitionCondition: part,
+			TagCondition: &conditions.TagCondition{
+				RequireTags: []string{"rare"},
+			},
+		},
+		{
+			PartitionCondition:  part,
+			ObjectTypeCondition: provType,
+		},
+		{
+			PartitionCondition: part,
+			UuidCondition:      conditions.UuidEqual(img.Uuid),
+		},
+	})
+	require.Nil(err)
+	cur, err := s.ExecuteObjectFind(ctx, plans)
+	require.Nil(err)
+	defer cur.Close()
+
+	expect := []string{img.Uuid}
+	for _, prov := range provs {
+		expect = append(expect, prov.Uuid)
+	}
+	sort.Strings(expect)
+	assert.Equal(expect, scanUuids(t, cur))
+}
+
+// scanUuids returns the UUIDs of the objects read from the supplied cursor
+func scanUuids(t *testing.T, cur abstract.Cursor) []string {
+	res := make([]string, 0)
+	for cur.Next() {
+		obj := &pb.Object{}
+		require.Nil(t, cur.Scan(obj))
+		res = append(res, obj.Uuid)
+	}
+	require.Nil(t, cur.Err())
+	return res
+}
+
+func TestMergeObjects(t *testing.T) {
+	assert := assert.New(t)
+
+	objs := func(uuids ...string) *objectCursor {
+		fetch := func() (*pb.Object, error) {
+			if len(uuids) == 0 {
+				return nil, nil
+			}
+			obj := &pb.Object{Uuid: uuids[0]}
+			uuids = uuids[1:]
+			return obj, nil
+		}
+		return newObjectCursor(fetch)
+	}
+	merged := newObjectCursor(mergeObjects([]*objectCursor{
+		objs("a", "c", "e"),
+		objs(),
+		objs("b", "c", "f"),
+		objs("a", "d"),
+	}))
+	assert.Equal(
+		[]string{"a", "b", "c", "d", "e", "f"},
+		scanUuids(t, merged),
+	)
+
+	// Errors from any of the merged cursors end the merge
+	failing := newObjectCursor(func() (*pb.Object, error) {
+		return nil, fmt.Errorf("boom")
+	})
+	merged = newObjectCursor(mergeObjects([]*objectCursor{
+		objs("a"),
+		failing,
+	}))
+	assert.False(merged.Next())
+	assert.NotNil(merged.Err())
+}
+
+func TestObjectScanPages(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.TODO()
+
+	s, cleanup := newEtcdStore(t)
+	defer cleanup()
+
+	// Scans read objects a page at a time
+	expect := make([]string, 0)
+	for x := 0; x < _OBJECTS_PAGE_SIZE+10; x++ {
+		obj := createObject(
+			t, s, testProviderType, fmt.Sprintf("p%04d", x), nil, nil,
+		)
+		expect = append(expect, obj.Uuid)
+	}
+	sort.Strings(expect)
+
+	cur, err := s.ObjectFind(ctx, nil)
+	assert.Nil(err)
+	defer cur.Close()
+	assert.Equal(expect, scanUuids(t, cur))
+}
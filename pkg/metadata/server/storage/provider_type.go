@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"strings"
 
 	etcd "github.com/coreos/etcd/clientv3"
@@ -37,7 +38,7 @@ var (
 // ensureProviderTypes is responsible for making sure etcd has the well-known
 // runm provider types in storage.
 func (s *Store) ensureProviderTypes() error {
-	ctx, cancel := s.requestCtx()
+	ctx, cancel := s.requestCtx(context.Background())
 	defer cancel()
 
 	s.log.L3("ensuring provider types...")
@@ -61,7 +62,7 @@ func (s *Store) ensureProviderTypes() error {
 	for _, ot := range runmProviderTypes {
 		if _, ok := all[ot.Code]; !ok {
 			s.log.L3("provider type %s not in storage. adding...", ot.Code)
-			if err = s.providerTypeCreate(ot); err != nil {
+			if err = s.providerTypeCreate(ctx, ot); err != nil {
 				if err == errors.ErrDuplicate {
 					// some other thread created the type... just ignore
 					continue
@@ -77,9 +78,10 @@ func (s *Store) ensureProviderTypes() error {
 // ProviderTypeGetByCode returns an ProviderType protobuffer message having the
 // supplied code
 func (s *Store) ProviderTypeGetByCode(
+	ctx context.Context,
 	code string,
 ) (*pb.ProviderType, error) {
-	ctx, cancel := s.requestCtx()
+	ctx, cancel := s.requestCtx(ctx)
 	defer cancel()
 
 	key := _PROVIDER_TYPES_KEY + code
@@ -104,11 +106,12 @@ func (s *Store) ProviderTypeGetByCode(
 // ProviderTypeFind returns a slice of pointers to ProviderType protobuffer
 // messages matching a set of supplied filters.
 func (s *Store) ProviderTypeFind(
+	ctx context.Context,
 	any []*pb.ProviderTypeFindFilter,
 ) ([]*pb.ProviderType, error) {
 	if len(any) == 0 {
 		// Just return all object types
-		return s.providerTypesGetByCode("", true)
+		return s.providerTypesGetByCode(ctx, "", true)
 	}
 
 	// Each filter is evaluated in an OR fashion, so we keep a hashmap of
@@ -117,6 +120,7 @@ func (s *Store) ProviderTypeFind(
 	for _, filter := range any {
 		if filter.CodeFilter != nil {
 			filterObjs, err := s.providerTypesGetByCode(
+				ctx,
 				filter.CodeFilter.Code,
 				filter.CodeFilter.UsePrefix,
 			)
@@ -138,10 +142,11 @@ func (s *Store) ProviderTypeFind(
 }
 
 func (s *Store) providerTypesGetByCode(
+	ctx context.Context,
 	code string,
 	usePrefix bool,
 ) ([]*pb.ProviderType, error) {
-	ctx, cancel := s.requestCtx()
+	ctx, cancel := s.requestCtx(ctx)
 	defer cancel()
 
 	key := _PROVIDER_TYPES_KEY + code
@@ -181,9 +186,10 @@ func (s *Store) providerTypesGetByCode(
 // providerTypeCreate writes the supplied ObjectType provider to the key at
 // $ROOT/provider-types/{provider_type_code}
 func (s *Store) providerTypeCreate(
+	ctx context.Context,
 	obj *pb.ProviderType,
 ) error {
-	ctx, cancel := s.requestCtx()
+	ctx, cancel := s.requestCtx(ctx)
 	defer cancel()
 
 	key := _PROVIDER_TYPES_KEY + obj.Code
@@ -28,18 +28,39 @@ type Store struct {
 	cfg    *config.Config
 	client *etcd.Client
 	kv     etcd.KV
+	// Migrations between versions of the keyspace layout
+	migrations []keyspaceMigration
 }
 
-func New(log *logging.Logs, cfg *config.Config) (*Store, error) {
+// Connect returns a Store connected to etcd without migrating the keyspace or
+// ensuring that the well-known object types, provider types and definitions
+// exist
+func Connect(log *logging.Logs, cfg *config.Config) (*Store, error) {
 	client, err := connect(log, cfg)
 	if err != nil {
 		return nil, err
 	}
-	s := &Store{
+	return &Store{
 		log:    log,
 		cfg:    cfg,
 		client: client,
-		kv:     etcd_namespace.NewKV(client.KV, cfg.EtcdKeyPrefix+_SERVICE_KEY),
+		kv: newInstrumentedKV(
+			etcd_namespace.NewKV(client.KV, cfg.EtcdKeyPrefix+_SERVICE_KEY),
+		),
+		migrations: _KEYSPACE_MIGRATIONS,
+	}, nil
+}
+
+// New returns a Store connected to etcd, migrating the keyspace to the latest
+// layout if necessary
+func New(log *logging.Logs, cfg *config.Config) (*Store, error) {
+	s, err := Connect(log, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = s.MigrateKeyspace(context.Background(), false); err != nil {
+		s.Close()
+		return nil, err
 	}
 	if err = s.ensureObjectTypes(); err != nil {
 		return nil, err
@@ -47,15 +68,28 @@ func New(log *logging.Logs, cfg *config.Config) (*Store, error) {
 	if err = s.ensureProviderTypes(); err != nil {
 		return nil, err
 	}
-	if err = s.ensureDefaultProviderDefinition(); err != nil {
+	if err = s.ensureDefaultObjectDefinitions(); err != nil {
 		return nil, err
 	}
 	return s, nil
 }
 
-func (s *Store) requestCtx() (context.Context, context.CancelFunc) {
+func (s *Store) requestCtx(
+	ctx context.Context,
+) (context.Context, context.CancelFunc) {
 	return context.WithTimeout(
-		context.Background(),
+		ctx,
 		s.cfg.EtcdRequestTimeoutSeconds,
 	)
 }
+
+// Ping returns nil if the etcd cluster is reachable and able to service reads
+func (s *Store) Ping(ctx context.Context) error {
+	_, err := s.kv.Get(ctx, "health", etcd.WithCountOnly())
+	return err
+}
+
+// Close closes the Store's connection to etcd
+func (s *Store) Close() {
+	s.client.Close()
+}
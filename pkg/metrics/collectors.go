@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// register registers the supplied collectors with the default registry,
+// replacing any collector exporting the same metrics. Servers register their
+// collectors when they are created, and more than one server may be created
+// in a process, for instance by runm-allinone or by tests.
+func register(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		prometheus.Unregister(c)
+		prometheus.MustRegister(c)
+	}
+}
+
+// RegisterCache exports the hit and miss counts of the named cache along with
+// its hit ratio. The supplied functions are called whenever metrics are
+// scraped and must be safe to call concurrently with cache access. Calling
+// RegisterCache again for the same cache replaces the functions.
+func RegisterCache(
+	name string,
+	hits func() uint64,
+	misses func() uint64,
+) {
+	labels := prometheus.Labels{"cache": name}
+	register(
+		prometheus.NewCounterFunc(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				Name:        "cache_hits_total",
+				Help:        "Number of cache lookups that found an entry.",
+				ConstLabels: labels,
+			},
+			func() float64 { return float64(hits()) },
+		),
+		prometheus.NewCounterFunc(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				Name:        "cache_misses_total",
+				Help:        "Number of cache lookups that did not find an entry.",
+				ConstLabels: labels,
+			},
+			func() float64 { return float64(misses()) },
+		),
+		prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Name:        "cache_hit_ratio",
+				Help:        "Ratio of cache lookups that found an entry.",
+				ConstLabels: labels,
+			},
+			func() float64 {
+				h := float64(hits())
+				total := h + float64(misses())
+				if total == 0 {
+					return 0
+				}
+				return h / total
+			},
+		),
+	)
+}
+
+// CountsFunc returns a count of something keyed by a label value
+type CountsFunc func() (map[string]int, error)
+
+// countsCollector is a Prometheus collector that emits a gauge for each entry
+// returned by a CountsFunc at scrape time
+type countsCollector struct {
+	desc   *prometheus.Desc
+	counts CountsFunc
+}
+
+func (c *countsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *countsCollector) Collect(ch chan<- prometheus.Metric) {
+	counts, err := c.counts()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	for label, count := range counts {
+		ch <- prometheus.MustNewConstMetric(
+			c.desc, prometheus.GaugeValue, float64(count), label,
+		)
+	}
+}
+
+// RegisterProvidersPerPartition exports a gauge of the number of providers in
+// each partition. The supplied function is called whenever metrics are
+// scraped and returns provider counts keyed by partition UUID. Calling
+// RegisterProvidersPerPartition again replaces the function.
+func RegisterProvidersPerPartition(counts CountsFunc) {
+	register(&countsCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "providers"),
+			"Number of providers, by partition.",
+			[]string{"partition"},
+			nil,
+		),
+		counts: counts,
+	})
+}
@@ -0,0 +1,143 @@
+// Package metrics exposes Prometheus metrics for runmachine services: per-RPC
+// request counters and latency histograms, etcd request and SQL store
+// operation timings, cache hit ratios and gauges describing the state of the
+// system.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/runmachine-io/runmachine/pkg/logging"
+)
+
+const (
+	namespace = "runm"
+)
+
+var (
+	rpcRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rpc_requests_total",
+			Help:      "Number of RPCs handled, by service, method and gRPC status code.",
+		},
+		[]string{"service", "method", "code"},
+	)
+	rpcDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rpc_duration_seconds",
+			Help:      "Time taken to handle RPCs, by service and method.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"service", "method"},
+	)
+	storageDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_operation_duration_seconds",
+			Help:      "Time taken by backend storage operations, by backend and operation.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"backend", "operation"},
+	)
+	storageErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "storage_operation_errors_total",
+			Help:      "Number of failed backend storage operations, by backend and operation.",
+		},
+		[]string{"backend", "operation"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		rpcRequests,
+		rpcDuration,
+		storageDuration,
+		storageErrors,
+	)
+}
+
+// UnaryServerInterceptor returns a gRPC server interceptor that counts and
+// times unary RPCs handled by the named service
+func UnaryServerInterceptor(service string) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observeRPC(service, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a gRPC server interceptor that counts and
+// times streaming RPCs handled by the named service
+func StreamServerInterceptor(service string) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observeRPC(service, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func observeRPC(
+	service string,
+	method string,
+	start time.Time,
+	err error,
+) {
+	code := status.Code(err).String()
+	rpcRequests.WithLabelValues(service, method, code).Inc()
+	rpcDuration.WithLabelValues(service, method).Observe(
+		time.Since(start).Seconds(),
+	)
+}
+
+// ObserveStorage records the duration of a backend storage operation that
+// started at the supplied time and, if err is not nil, counts the operation as
+// failed
+func ObserveStorage(
+	backend string,
+	operation string,
+	start time.Time,
+	err error,
+) {
+	storageDuration.WithLabelValues(backend, operation).Observe(
+		time.Since(start).Seconds(),
+	)
+	if err != nil {
+		storageErrors.WithLabelValues(backend, operation).Inc()
+	}
+}
+
+// Serve starts an HTTP listener in the background that serves all registered
+// metrics in the Prometheus text format at /metrics on the supplied address
+func Serve(log *logging.Logs, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		log.L2("serving metrics on HTTP %s/metrics", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.ERR("failed to serve metrics: %v", err)
+		}
+	}()
+}
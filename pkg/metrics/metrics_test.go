@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	assert := assert.New(t)
+
+	intercept := UnaryServerInterceptor("test")
+	info := &grpc.UnaryServerInfo{FullMethod: "/runm.Test/thing_get"}
+
+	okHandler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	failHandler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "no such thing")
+	}
+
+	resp, err := intercept(context.TODO(), nil, info, okHandler)
+	assert.Nil(err)
+	assert.Equal("ok", resp)
+	_, err = intercept(context.TODO(), nil, info, failHandler)
+	assert.NotNil(err)
+	_, err = intercept(context.TODO(), nil, info, failHandler)
+	assert.NotNil(err)
+
+	assert.Equal(
+		float64(1),
+		testutil.ToFloat64(
+			rpcRequests.WithLabelValues("test", info.FullMethod, "OK"),
+		),
+	)
+	assert.Equal(
+		float64(2),
+		testutil.ToFloat64(
+			rpcRequests.WithLabelValues("test", info.FullMethod, "NotFound"),
+		),
+	)
+}
+
+func TestObserveStorage(t *testing.T) {
+	assert := assert.New(t)
+
+	ObserveStorage("test", "get", time.Now(), nil)
+	ObserveStorage("test", "get", time.Now(), errors.New("boom"))
+
+	assert.Equal(
+		float64(1),
+		testutil.ToFloat64(storageErrors.WithLabelValues("test", "get")),
+	)
+}
+
+func TestRegisterTwice(t *testing.T) {
+	assert := assert.New(t)
+
+	// Each server registers its collectors when created, so registering
+	// the same metrics again must replace the earlier collectors rather
+	// than panic
+	RegisterProvidersPerPartition(func() (map[string]int, error) {
+		return map[string]int{"a": 1}, nil
+	})
+	RegisterProvidersPerPartition(func() (map[string]int, error) {
+		return map[string]int{"a": 2}, nil
+	})
+	RegisterCache("test", func() uint64 { return 1 }, func() uint64 { return 1 })
+	RegisterCache("test", func() uint64 { return 3 }, func() uint64 { return 1 })
+
+	mfs, err := prometheus.DefaultGatherer.Gather()
+	assert.Nil(err)
+	got := map[string]float64{}
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			if m.GetGauge() != nil {
+				got[mf.GetName()] = m.GetGauge().GetValue()
+			}
+		}
+	}
+	assert.Equal(float64(2), got["runm_providers"])
+	assert.Equal(float64(0.75), got["runm_cache_hit_ratio"])
+}
+
+func TestCountsCollector(t *testing.T) {
+	assert := assert.New(t)
+
+	c := &countsCollector{
+		desc: prometheus.NewDesc("test_things", "", []string{"kind"}, nil),
+		counts: func() (map[string]int, error) {
+			return map[string]int{"a": 3, "b": 4}, nil
+		},
+	}
+	ch := make(chan prometheus.Metric, 10)
+	c.Collect(ch)
+	close(ch)
+	got := 0
+	for range ch {
+		got++
+	}
+	assert.Equal(2, got)
+}
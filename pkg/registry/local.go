@@ -0,0 +1,71 @@
+package registry
+
+import (
+	"sync"
+
+	"github.com/jaypipes/gsr"
+)
+
+// Local is a Registry that keeps service endpoints in memory. It is only
+// useful when the services registering and looking up endpoints run in the
+// same process.
+type Local struct {
+	sync.RWMutex
+	// Map, keyed by service name, of endpoint addresses
+	endpoints map[string][]string
+}
+
+// NewLocal returns an empty in-process Registry
+func NewLocal() *Local {
+	return &Local{
+		endpoints: map[string][]string{},
+	}
+}
+
+// Register advertises the supplied service endpoint. Registering an endpoint
+// that is already registered is a no-op.
+func (r *Local) Register(ep *gsr.Endpoint) error {
+	r.Lock()
+	defer r.Unlock()
+	svc := ep.Service.Name
+	for _, addr := range r.endpoints[svc] {
+		if addr == ep.Address {
+			return nil
+		}
+	}
+	r.endpoints[svc] = append(r.endpoints[svc], ep.Address)
+	return nil
+}
+
+// Unregister removes the supplied service endpoint
+func (r *Local) Unregister(ep *gsr.Endpoint) error {
+	r.Lock()
+	defer r.Unlock()
+	svc := ep.Service.Name
+	addrs := r.endpoints[svc]
+	for x, addr := range addrs {
+		if addr == ep.Address {
+			r.endpoints[svc] = append(addrs[:x:x], addrs[x+1:]...)
+			break
+		}
+	}
+	if len(r.endpoints[svc]) == 0 {
+		delete(r.endpoints, svc)
+	}
+	return nil
+}
+
+// Endpoints returns the endpoints currently registered for the named service
+func (r *Local) Endpoints(service string) []*gsr.Endpoint {
+	r.RLock()
+	defer r.RUnlock()
+	addrs := r.endpoints[service]
+	eps := make([]*gsr.Endpoint, len(addrs))
+	for x, addr := range addrs {
+		eps[x] = &gsr.Endpoint{
+			Service: &gsr.Service{Name: service},
+			Address: addr,
+		}
+	}
+	return eps
+}
@@ -0,0 +1,60 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/jaypipes/gsr"
+	"github.com/stretchr/testify/assert"
+)
+
+func endpoint(service string, addr string) *gsr.Endpoint {
+	return &gsr.Endpoint{
+		Service: &gsr.Service{Name: service},
+		Address: addr,
+	}
+}
+
+func addresses(eps []*gsr.Endpoint) []string {
+	addrs := make([]string, len(eps))
+	for x, ep := range eps {
+		addrs[x] = ep.Address
+	}
+	return addrs
+}
+
+func TestLocal(t *testing.T) {
+	assert := assert.New(t)
+
+	var reg Registry = NewLocal()
+
+	assert.Empty(reg.Endpoints("runmachine-metadata"))
+
+	assert.Nil(reg.Register(endpoint("runmachine-metadata", "127.0.0.1:10002")))
+	assert.Nil(reg.Register(endpoint("runmachine-metadata", "127.0.0.1:10012")))
+	// Registering the same endpoint twice should not duplicate it
+	assert.Nil(reg.Register(endpoint("runmachine-metadata", "127.0.0.1:10002")))
+	assert.Nil(reg.Register(endpoint("runmachine-resource", "127.0.0.1:10001")))
+
+	assert.Equal(
+		[]string{"127.0.0.1:10002", "127.0.0.1:10012"},
+		addresses(reg.Endpoints("runmachine-metadata")),
+	)
+	assert.Equal(
+		[]string{"127.0.0.1:10001"},
+		addresses(reg.Endpoints("runmachine-resource")),
+	)
+	eps := reg.Endpoints("runmachine-resource")
+	assert.Equal("runmachine-resource", eps[0].Service.Name)
+
+	assert.Nil(reg.Unregister(endpoint("runmachine-metadata", "127.0.0.1:10002")))
+	assert.Equal(
+		[]string{"127.0.0.1:10012"},
+		addresses(reg.Endpoints("runmachine-metadata")),
+	)
+
+	// Unregistering an unknown endpoint is not an error
+	assert.Nil(reg.Unregister(endpoint("runmachine-api", "127.0.0.1:10000")))
+
+	assert.Nil(reg.Unregister(endpoint("runmachine-metadata", "127.0.0.1:10012")))
+	assert.Empty(reg.Endpoints("runmachine-metadata"))
+}
@@ -0,0 +1,37 @@
+// Package registry describes the service registry that runmachine services use
+// to advertise their own endpoints and to discover the endpoints of the other
+// runmachine services. Services normally use the gsr service registry, which
+// is backed by etcd. A Local registry keeps endpoints in memory and is used
+// when all runmachine services run in a single process.
+package registry
+
+import (
+	"fmt"
+
+	"github.com/jaypipes/gsr"
+
+	"github.com/runmachine-io/runmachine/pkg/logging"
+)
+
+// Registry is implemented by service registries. A *gsr.Registry satisfies
+// this interface.
+type Registry interface {
+	// Register advertises the supplied service endpoint
+	Register(ep *gsr.Endpoint) error
+	// Unregister removes the supplied service endpoint
+	Unregister(ep *gsr.Endpoint) error
+	// Endpoints returns the endpoints currently registered for the named
+	// service
+	Endpoints(service string) []*gsr.Endpoint
+}
+
+// Connect returns a Registry connected to the gsr service registry
+func Connect(log *logging.Logs) (Registry, error) {
+	log.L3("connecting to gsr service registry.")
+	reg, err := gsr.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gsr.Registry object: %v", err)
+	}
+	log.L2("connected to gsr service registry.")
+	return reg, nil
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+
+	"github.com/runmachine-io/runmachine/pkg/errors"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// ClaimCreate transactionally allocates the resources requested in the
+// supplied claim to the claim's consumer
+func (s *Server) ClaimCreate(
+	ctx context.Context,
+	req *pb.ClaimCreateRequest,
+) (*pb.ClaimCreateResponse, error) {
+	claim := req.Claim
+	if claim == nil || claim.Allocation == nil ||
+		claim.Allocation.Consumer == nil {
+		return nil, ErrClaimAllocationRequired
+	}
+	consUuid := claim.Allocation.Consumer.Uuid
+	for _, item := range claim.Allocation.Items {
+		if item.Provider == nil || item.ResourceType == nil {
+			return nil, ErrClaimItemInvalid
+		}
+		// NOTE(jaypipes): ClaimCreate() also checks that each provider
+		// exists, but looking them up first allows us to tell the caller
+		// which provider is missing
+		_, err := s.store.ProviderGetByUuid(ctx, item.Provider.Uuid)
+		if err == errors.ErrNotFound {
+			return nil, errProviderNotFound(item.Provider.Uuid)
+		}
+	}
+
+	claim, err := s.store.ClaimCreate(ctx, claim)
+	if err != nil {
+		switch err {
+		case errors.ErrNotFound:
+			return nil, errConsumerNotFound(consUuid)
+		case errors.ErrCapacityExceeded:
+			return nil, ErrCapacityExceeded
+		case errors.ErrGenerationConflict:
+			return nil, ErrGenerationConflict
+		}
+		s.log.ForContext(ctx).ERR(
+			"failed creating claim for consumer %s: %s", consUuid, err,
+		)
+		return nil, ErrUnknown
+	}
+	s.log.ForContext(ctx).L2(
+		"allocated %d resources to consumer %s",
+		len(claim.Allocation.Items), consUuid,
+	)
+	return &pb.ClaimCreateResponse{
+		Claim: claim,
+	}, nil
+}
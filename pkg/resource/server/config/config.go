@@ -22,6 +22,8 @@ const (
 	defaultMetadataServiceName          = "runmachine-metadata"
 	defaultStorageConnectTimeoutSeconds = 300
 	defaultStorageDSN                   = "user:password@tcp(localhost:3306)/dbname"
+	defaultLBPolicy                     = "round_robin"
+	defaultMetricsAddress               = ""
 )
 
 var (
@@ -40,7 +42,16 @@ type Config struct {
 	MetadataServiceName          string
 	StorageConnectTimeoutSeconds time.Duration
 	// TODO(jaypipes): move this to gsr
+	// DSNs beginning with sqlite:// name a SQLite database file and DSNs
+	// beginning with postgres:// name a PostgreSQL database. All other DSNs
+	// are MySQL DSNs.
 	StorageDSN string
+	// Policy used to balance RPCs across the endpoints of the metadata
+	// service
+	LBPolicy string
+	// Address (host:port) of the HTTP listener serving Prometheus metrics at
+	// /metrics. Metrics are not served if empty.
+	MetricsAddress string
 }
 
 func ConfigFromOpts() *Config {
@@ -106,7 +117,26 @@ func ConfigFromOpts() *Config {
 		envutil.WithDefault(
 			"RUNM_RESOURCE_STORAGE_DSN", defaultStorageDSN,
 		),
-		"DSN for connecting to backend database storage",
+		"DSN for connecting to backend database storage. Use "+
+			"sqlite://$PATH for a SQLite database file or a "+
+			"postgres:// URL for a PostgreSQL database. Any other "+
+			"value is a MySQL DSN.",
+	)
+	optLBPolicy := flag.String(
+		"lb-policy",
+		envutil.WithDefault(
+			"RUNM_RESOURCE_LB_POLICY", defaultLBPolicy,
+		),
+		"Policy (round_robin or least_loaded) used to balance RPCs "+
+			"across metadata service endpoints",
+	)
+	optMetricsAddress := flag.String(
+		"metrics-address",
+		envutil.WithDefault(
+			"RUNM_RESOURCE_METRICS_ADDRESS", defaultMetricsAddress,
+		),
+		"Address (host:port) to serve Prometheus metrics on. Metrics are "+
+			"not served if empty",
 	)
 
 	flag.Parse()
@@ -122,7 +152,9 @@ func ConfigFromOpts() *Config {
 		StorageConnectTimeoutSeconds: time.Duration(
 			*optStorageConnectTimeout,
 		) * time.Second,
-		StorageDSN: *optStorageDSN,
+		StorageDSN:     *optStorageDSN,
+		LBPolicy:       *optLBPolicy,
+		MetricsAddress: *optMetricsAddress,
 	}
 }
 
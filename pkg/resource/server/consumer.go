@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+
+	"github.com/runmachine-io/runmachine/pkg/errors"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// ConsumerGetByUuid returns the consumer with the supplied UUID
+func (s *Server) ConsumerGetByUuid(
+	ctx context.Context,
+	req *pb.ConsumerGetByUuidRequest,
+) (*pb.Consumer, error) {
+	if req.Uuid == "" {
+		return nil, ErrUuidRequired
+	}
+	rec, err := s.store.ConsumerGetByUuid(ctx, req.Uuid)
+	if err != nil {
+		if err == errors.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		s.log.ForContext(ctx).ERR(
+			"failed to get consumer with UUID %s from storage: %s",
+			req.Uuid, err,
+		)
+		return nil, ErrUnknown
+	}
+	return rec.Consumer, nil
+}
+
+// ConsumerCreate creates a new consumer record in backend storage
+func (s *Server) ConsumerCreate(
+	ctx context.Context,
+	req *pb.ConsumerCreateRequest,
+) (*pb.ConsumerCreateResponse, error) {
+	cons := req.Consumer
+	if cons == nil || cons.Uuid == "" || cons.Type == nil ||
+		cons.Type.Code == "" {
+		return nil, ErrConsumerRequired
+	}
+	rec, err := s.store.ConsumerCreate(ctx, cons)
+	if err != nil {
+		if err == errors.ErrDuplicate {
+			return nil, ErrDuplicate
+		}
+		return nil, err
+	}
+	s.log.ForContext(ctx).L2(
+		"created consumer with UUID %s of type %s",
+		cons.Uuid, cons.Type.Code,
+	)
+	return &pb.ConsumerCreateResponse{
+		Consumer: rec.Consumer,
+	}, nil
+}
+
+// ConsumerDeleteByUuids deletes any consumer from backend storage that
+// matches any supplied UUID along with the consumers' allocations, returning
+// a response that indicates the number of consumers that were deleted
+func (s *Server) ConsumerDeleteByUuids(
+	ctx context.Context,
+	req *pb.ConsumerDeleteByUuidsRequest,
+) (*pb.DeleteResponse, error) {
+	if len(req.Uuids) == 0 {
+		return nil, ErrAtLeastOneUuidRequired
+	}
+
+	numDeleted, err := s.store.ConsumerDeleteByUuids(ctx, req.Uuids)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.DeleteResponse{
+		NumDeleted: numDeleted,
+	}, nil
+}
@@ -110,6 +110,26 @@ var (
 		codes.FailedPrecondition,
 		"invalid property definition filter.",
 	)
+	ErrConsumerRequired = status.Errorf(
+		codes.FailedPrecondition,
+		"a consumer with a UUID and consumer type is required.",
+	)
+	ErrClaimAllocationRequired = status.Errorf(
+		codes.FailedPrecondition,
+		"a claim with an allocation for a consumer is required.",
+	)
+	ErrClaimItemInvalid = status.Errorf(
+		codes.FailedPrecondition,
+		"each allocation item requires a provider and a resource type.",
+	)
+	ErrCapacityExceeded = status.Errorf(
+		codes.ResourceExhausted,
+		"not enough capacity to satisfy claim.",
+	)
+	ErrGenerationConflict = status.Errorf(
+		codes.Aborted,
+		"a provider was changed by a concurrent claim. retry the claim.",
+	)
 	ErrPropertyDefinitionDeleteFailed = status.Errorf(
 		codes.FailedPrecondition,
 		"failed to delete property definition (check response errors collection).",
@@ -136,3 +156,17 @@ func errObjectTypeNotFound(objectType string) error {
 		"Object type %s not found", objectType,
 	)
 }
+
+func errConsumerNotFound(consumer string) error {
+	return status.Errorf(
+		codes.FailedPrecondition,
+		"Consumer %s not found", consumer,
+	)
+}
+
+func errProviderNotFound(provider string) error {
+	return status.Errorf(
+		codes.FailedPrecondition,
+		"Provider %s not found", provider,
+	)
+}
@@ -0,0 +1,11 @@
+package server
+
+import (
+	"context"
+)
+
+// HealthCheck returns nil if the resource service is able to reach its
+// database
+func (s *Server) HealthCheck(ctx context.Context) error {
+	return s.store.Ping(ctx)
+}
@@ -16,12 +16,12 @@ func (s *Server) ProviderGetByUuid(
 	if req.Uuid == "" {
 		return nil, ErrUuidRequired
 	}
-	rec, err := s.store.ProviderGetByUuid(req.Uuid)
+	rec, err := s.store.ProviderGetByUuid(ctx, req.Uuid)
 	if err != nil {
 		if err == errors.ErrNotFound {
 			return nil, ErrNotFound
 		}
-		s.log.ERR(
+		s.log.ForContext(ctx).ERR(
 			"failed to get provider with UUID %s from storage: %s",
 			req.Uuid, err,
 		)
@@ -36,16 +36,21 @@ func (s *Server) ProviderFind(
 	req *pb.ProviderFindRequest,
 	stream pb.RunmResource_ProviderFindServer,
 ) error {
-	objs, err := s.store.ProvidersGetMatching(req.Any)
+	cur, err := s.store.ProvidersGetMatching(stream.Context(), req.Any)
 	if err != nil {
 		return err
 	}
-	for _, obj := range objs {
-		if err = stream.Send(obj.Provider); err != nil {
+	defer cur.Close()
+	for cur.Next() {
+		p := &pb.Provider{}
+		if err = cur.Scan(p); err != nil {
+			return err
+		}
+		if err = stream.Send(p); err != nil {
 			return err
 		}
 	}
-	return nil
+	return cur.Err()
 }
 
 // ProviderCreate creates a new provider record in backend storage
@@ -53,7 +58,7 @@ func (s *Server) ProviderCreate(
 	ctx context.Context,
 	req *pb.ProviderCreateRequest,
 ) (*pb.ProviderCreateResponse, error) {
-	rec, err := s.store.ProviderCreate(req.Provider)
+	rec, err := s.store.ProviderCreate(ctx, req.Provider)
 	if err != nil {
 		if err == errors.ErrDuplicate {
 			return nil, ErrDuplicate
@@ -76,7 +81,7 @@ func (s *Server) ProviderDeleteByUuids(
 		return nil, ErrAtLeastOneUuidRequired
 	}
 
-	numDeleted, err := s.store.ProviderDeleteByUuid(req.Uuids)
+	numDeleted, err := s.store.ProviderDeleteByUuid(ctx, req.Uuids)
 	if err != nil {
 		return nil, err
 	}
@@ -6,8 +6,11 @@ import (
 	"github.com/jaypipes/gsr"
 
 	"github.com/runmachine-io/runmachine/pkg/logging"
+	"github.com/runmachine-io/runmachine/pkg/metrics"
+	"github.com/runmachine-io/runmachine/pkg/registry"
 	"github.com/runmachine-io/runmachine/pkg/resource/server/config"
 	"github.com/runmachine-io/runmachine/pkg/resource/server/storage"
+	"github.com/runmachine-io/runmachine/pkg/svcclient"
 	metapb "github.com/runmachine-io/runmachine/proto"
 )
 
@@ -18,15 +21,16 @@ var (
 type Server struct {
 	log        *logging.Logs
 	cfg        *config.Config
-	registry   *gsr.Registry
+	registry   registry.Registry
 	store      *storage.Store
+	metasc     *svcclient.Client
 	metaclient metapb.RunmMetadataClient
 }
 
 func (s *Server) Close() {
 	addr := fmt.Sprintf("%s:%d", s.cfg.BindHost, s.cfg.BindPort)
 	s.log.L3(
-		"unregistering %s:%s endpoint in gsr...",
+		"unregistering %s:%s endpoint in service registry...",
 		s.cfg.ServiceName,
 		addr,
 	)
@@ -38,24 +42,20 @@ func (s *Server) Close() {
 	if err != nil {
 		s.log.ERR("failed to unregister: %s\n", err)
 	}
+	s.metasc.Close()
 }
 
 func New(
 	cfg *config.Config,
 	log *logging.Logs,
+	reg registry.Registry,
 ) (*Server, error) {
-	log.L3("connecting to gsr service registry.")
-	registry, err := gsr.New()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create gsr.Registry object: %v", err)
-	}
-	log.L2("connected to gsr service registry.")
-
 	store, err := storage.New(log, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize resource storage: %v", err)
 	}
 	log.L2("initialized resource storage.")
+	metrics.RegisterProvidersPerPartition(store.ProviderCountsByPartition)
 
 	// Register this runm-api service endpoint with the service registry
 	addr := fmt.Sprintf("%s:%d", cfg.BindHost, cfg.BindPort)
@@ -63,20 +63,30 @@ func New(
 		Service: &gsr.Service{Name: cfg.ServiceName},
 		Address: addr,
 	}
-	err = registry.Register(&ep)
+	err = reg.Register(&ep)
 	if err != nil {
-		return nil, fmt.Errorf("failed to register %v with gsr: %v", ep, err)
+		return nil, fmt.Errorf("failed to register %v with service registry: %v", ep, err)
 	}
 	log.L2(
-		"registered %s service endpoint running at %s with gsr.",
+		"registered %s service endpoint running at %s with service registry.",
 		cfg.ServiceName,
 		addr,
 	)
 
+	metasc, err := svcclient.New(
+		log, reg, cfg.MetadataServiceName,
+		svcclient.DefaultOptions(cfg.LBPolicy),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metadata service client: %v", err)
+	}
+
 	return &Server{
-		log:      log,
-		cfg:      cfg,
-		registry: registry,
-		store:    store,
+		log:        log,
+		cfg:        cfg,
+		registry:   reg,
+		store:      store,
+		metasc:     metasc,
+		metaclient: metapb.NewRunmMetadataClient(metasc.Conn()),
 	}, nil
 }
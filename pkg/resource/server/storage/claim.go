@@ -0,0 +1,265 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/runmachine-io/runmachine/pkg/errors"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// ensureResourceType creates a record in the resource_types table for the
+// supplied resource type code if no such record exists and returns the
+// resource type record's internal identifier
+func (s *Store) ensureResourceType(
+	ctx context.Context,
+	code string,
+) (int64, error) {
+	var id int64
+	db := s.DB()
+	qs := "SELECT id FROM resource_types WHERE code = ?"
+	err := db.QueryRowContext(ctx, s.driver.Rebind(qs), code).Scan(&id)
+	switch {
+	case err == sql.ErrNoRows:
+		// New record. Create it and return the newly-created internal ID
+		qs = "INSERT INTO resource_types (code) VALUES (?)"
+		id, err := s.driver.Insert(ctx, db, s.driver.Rebind(qs), code)
+		if err != nil {
+			if s.driver.IsDuplicate(err) {
+				// Another thread already inserted this resource type, so
+				// just grab the resource type's internal ID
+				qs := "SELECT id FROM resource_types WHERE code = ?"
+				err := db.QueryRowContext(ctx, s.driver.Rebind(qs), code).Scan(&id)
+				if err != nil {
+					s.log.ERR(
+						"failed getting resource_type internal ID: %s",
+						err,
+					)
+					return 0, err
+				}
+				return id, nil
+			}
+			s.log.ERR("failed creating resource_type record: %s", err)
+			return 0, err
+		}
+		s.log.L2("created new resource_types record for code %s", code)
+		return id, nil
+	case err != nil:
+		return 0, err
+	}
+	return id, nil
+}
+
+// claimProvider is a provider having resources allocated to it by a claim
+type claimProvider struct {
+	id         int64
+	generation uint32
+}
+
+// checkCapacity returns errors.ErrCapacityExceeded if the provider does not
+// have enough unused inventory of the resource type to allocate the supplied
+// amount at the supplied time, or if the amount does not satisfy the
+// inventory's min_unit, max_unit and step_size
+// TODO(jaypipes): Providers without an inventory of a resource type are not
+// limited until inventories can be managed through the resource service
+func (s *Store) checkCapacity(
+	ctx context.Context,
+	tx *sql.Tx,
+	providerId int64,
+	resourceTypeId int64,
+	amount uint64,
+	at int64,
+) error {
+	var total, reserved, minUnit, maxUnit, stepSize, used uint64
+	var ratio float64
+	qs := `SELECT
+  total
+, reserved
+, min_unit
+, max_unit
+, step_size
+, allocation_ratio
+FROM inventories
+WHERE provider_id = ?
+AND resource_type_id = ?`
+	err := tx.QueryRowContext(
+		ctx, s.driver.Rebind(qs), providerId, resourceTypeId,
+	).Scan(&total, &reserved, &minUnit, &maxUnit, &stepSize, &ratio)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil
+	case err != nil:
+		return err
+	}
+	if amount < minUnit || (maxUnit > 0 && amount > maxUnit) {
+		return errors.ErrCapacityExceeded
+	}
+	if stepSize > 0 && amount%stepSize != 0 {
+		return errors.ErrCapacityExceeded
+	}
+
+	// An allocation with a release_time of 0 is held until its consumer is
+	// deleted
+	qs = `SELECT COALESCE(SUM(ai.used), 0)
+FROM allocation_items AS ai
+JOIN allocations AS a
+ ON ai.allocation_id = a.id
+WHERE ai.provider_id = ?
+AND ai.resource_type_id = ?
+AND a.acquire_time <= ?
+AND (a.release_time = 0 OR a.release_time > ?)`
+	err = tx.QueryRowContext(
+		ctx, s.driver.Rebind(qs), providerId, resourceTypeId, at, at,
+	).Scan(&used)
+	if err != nil {
+		return err
+	}
+	if reserved > total {
+		return errors.ErrCapacityExceeded
+	}
+	capacity := uint64(float64(total-reserved) * ratio)
+	if used+amount > capacity {
+		return errors.ErrCapacityExceeded
+	}
+	return nil
+}
+
+// ClaimCreate allocates the resources in the supplied claim's allocation to
+// the allocation's consumer in a single transaction and returns the claim.
+// Returns errors.ErrNotFound if the consumer or any provider does not exist
+// and errors.ErrCapacityExceeded if any provider does not have enough unused
+// inventory to satisfy the claim. Each provider's generation is incremented,
+// and errors.ErrGenerationConflict is returned if another claim allocated
+// resources on one of the providers concurrently.
+func (s *Store) ClaimCreate(
+	ctx context.Context,
+	claim *pb.Claim,
+) (res *pb.Claim, err error) {
+	ctx, op := s.startOperation(ctx, "claim_create")
+	defer op.end(&err)
+	alloc := claim.Allocation
+	if alloc == nil || alloc.Consumer == nil {
+		return nil, errors.ErrBadInput
+	}
+	if alloc.AcquireTime == 0 {
+		alloc.AcquireTime = time.Now().Unix()
+	}
+	if claim.RequestTime == 0 {
+		claim.RequestTime = time.Now().Unix()
+	}
+
+	// Grab the internal IDs of the claimed resource types, ensuring that
+	// records exist for them
+	rtIds := make([]int64, len(alloc.Items))
+	for x, item := range alloc.Items {
+		if item.Provider == nil || item.ResourceType == nil {
+			return nil, errors.ErrBadInput
+		}
+		rtIds[x], err = s.ensureResourceType(ctx, item.ResourceType.Code)
+		if err != nil {
+			return nil, errors.ErrUnknown
+		}
+	}
+
+	tx, err := s.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var consumerId int64
+	qs := "SELECT id FROM consumers WHERE uuid = ?"
+	err = tx.QueryRowContext(
+		ctx, s.driver.Rebind(qs), alloc.Consumer.Uuid,
+	).Scan(&consumerId)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, errors.ErrNotFound
+	case err != nil:
+		return nil, err
+	}
+
+	qs = `
+INSERT INTO allocations (
+  consumer_id
+, acquire_time
+, release_time
+) VALUES (?, ?, ?)
+`
+	allocId, err := s.driver.Insert(
+		ctx, tx, s.driver.Rebind(qs),
+		consumerId, alloc.AcquireTime, alloc.ReleaseTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provs := make(map[string]*claimProvider, 0)
+	for x, item := range alloc.Items {
+		provUuid := item.Provider.Uuid
+		prov, found := provs[provUuid]
+		if !found {
+			prov = &claimProvider{}
+			qs = "SELECT id, generation FROM providers WHERE uuid = ?"
+			err = tx.QueryRowContext(
+				ctx, s.driver.Rebind(qs), provUuid,
+			).Scan(&prov.id, &prov.generation)
+			switch {
+			case err == sql.ErrNoRows:
+				return nil, errors.ErrNotFound
+			case err != nil:
+				return nil, err
+			}
+			provs[provUuid] = prov
+		}
+		err = s.checkCapacity(
+			ctx, tx, prov.id, rtIds[x], item.Used, alloc.AcquireTime,
+		)
+		if err != nil {
+			return nil, err
+		}
+		qs = `
+INSERT INTO allocation_items (
+  allocation_id
+, provider_id
+, resource_type_id
+, used
+) VALUES (?, ?, ?, ?)
+`
+		_, err = s.driver.Insert(
+			ctx, tx, s.driver.Rebind(qs),
+			allocId, prov.id, rtIds[x], item.Used,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// NOTE(jaypipes): Incrementing the generation of each provider we
+	// allocated resources on ensures that two claims that each checked the
+	// provider's capacity before the other committed can't both succeed
+	qs = `UPDATE providers SET generation = ?
+WHERE id = ?
+AND generation = ?`
+	for _, prov := range provs {
+		upd, err := tx.ExecContext(
+			ctx, s.driver.Rebind(qs), prov.generation+1, prov.id,
+			prov.generation,
+		)
+		if err != nil {
+			return nil, err
+		}
+		affected, err := upd.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if affected != 1 {
+			return nil, errors.ErrGenerationConflict
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return claim, nil
+}
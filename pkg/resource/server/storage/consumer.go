@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/runmachine-io/runmachine/pkg/errors"
+	"github.com/runmachine-io/runmachine/pkg/util"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+type ConsumerRecord struct {
+	Consumer *pb.Consumer
+	ID       int64
+}
+
+// ensureConsumerType creates a record in the consumer_types table for the
+// supplied consumer type code if no such record exists and returns the
+// consumer type record's internal identifier
+func (s *Store) ensureConsumerType(
+	ctx context.Context,
+	code string,
+) (int64, error) {
+	var id int64
+	db := s.DB()
+	qs := "SELECT id FROM consumer_types WHERE code = ?"
+	err := db.QueryRowContext(ctx, s.driver.Rebind(qs), code).Scan(&id)
+	switch {
+	case err == sql.ErrNoRows:
+		// New record. Create it and return the newly-created internal ID
+		qs = "INSERT INTO consumer_types (code) VALUES (?)"
+		id, err := s.driver.Insert(ctx, db, s.driver.Rebind(qs), code)
+		if err != nil {
+			if s.driver.IsDuplicate(err) {
+				// Another thread already inserted this consumer type, so
+				// just grab the consumer type's internal ID
+				qs := "SELECT id FROM consumer_types WHERE code = ?"
+				err := db.QueryRowContext(ctx, s.driver.Rebind(qs), code).Scan(&id)
+				if err != nil {
+					s.log.ERR(
+						"failed getting consumer_type internal ID: %s",
+						err,
+					)
+					return 0, err
+				}
+				return id, nil
+			}
+			s.log.ERR("failed creating consumer_type record: %s", err)
+			return 0, err
+		}
+		s.log.L2("created new consumer_types record for code %s", code)
+		return id, nil
+	case err != nil:
+		return 0, err
+	}
+	return id, nil
+}
+
+// ConsumerGetByUuid returns a consumer record matching the supplied UUID. If
+// no such record exists, returns ErrNotFound
+func (s *Store) ConsumerGetByUuid(
+	ctx context.Context,
+	uuid string,
+) (rec *ConsumerRecord, err error) {
+	ctx, op := s.startOperation(ctx, "consumer_get")
+	defer op.end(&err)
+	qs := `SELECT
+  c.id
+, ct.code AS consumer_type
+, c.owner_project_uuid
+, c.owner_user_uuid
+, c.generation
+FROM consumers AS c
+JOIN consumer_types AS ct
+ ON c.consumer_type_id = ct.id
+WHERE c.uuid = ?`
+	rec = &ConsumerRecord{
+		Consumer: &pb.Consumer{
+			Uuid: uuid,
+			Type: &pb.ConsumerType{},
+		},
+	}
+	err = s.DB().QueryRowContext(ctx, s.driver.Rebind(qs), uuid).Scan(
+		&rec.ID,
+		&rec.Consumer.Type.Code,
+		&rec.Consumer.Project,
+		&rec.Consumer.User,
+		&rec.Consumer.Generation,
+	)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, errors.ErrNotFound
+	case err != nil:
+		return nil, err
+	}
+	return rec, nil
+}
+
+// ConsumerCreate creates the consumer record in backend storage and returns a
+// ConsumerRecord describing the new consumer
+func (s *Store) ConsumerCreate(
+	ctx context.Context,
+	cons *pb.Consumer,
+) (rec *ConsumerRecord, err error) {
+	ctx, op := s.startOperation(ctx, "consumer_create")
+	defer op.end(&err)
+	if !util.IsUuidLike(cons.Uuid) {
+		return nil, errors.ErrBadInput
+	}
+
+	ctId, err := s.ensureConsumerType(ctx, cons.Type.Code)
+	if err != nil {
+		return nil, errors.ErrUnknown
+	}
+
+	qs := `
+INSERT INTO consumers (
+  consumer_type_id
+, uuid
+, generation
+, owner_project_uuid
+, owner_user_uuid
+) VALUES (?, ?, ?, ?, ?)
+`
+	newId, err := s.driver.Insert(
+		ctx,
+		s.DB(),
+		s.driver.Rebind(qs),
+		ctId,
+		cons.Uuid,
+		1, // generation
+		cons.Project,
+		cons.User,
+	)
+	if err != nil {
+		if s.driver.IsDuplicate(err) {
+			return nil, errors.ErrDuplicate
+		}
+		return nil, err
+	}
+	cons.Generation = 1
+	return &ConsumerRecord{
+		Consumer: cons,
+		ID:       newId,
+	}, nil
+}
+
+// ConsumerDeleteByUuids deletes consumer records for any consumer with a
+// matching UUID along with the consumers' allocations. It returns the number
+// of consumer records deleted.
+func (s *Store) ConsumerDeleteByUuids(
+	ctx context.Context,
+	uuids []string,
+) (numDeleted uint64, err error) {
+	ctx, op := s.startOperation(ctx, "consumer_delete")
+	defer op.end(&err)
+	qargs := make([]interface{}, len(uuids))
+	for x, uuid := range uuids {
+		qargs[x] = uuid
+	}
+	tx, err := s.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	consumerIds := `
+SELECT id FROM consumers WHERE uuid ` + InParamString(len(uuids))
+	allocIds := `
+SELECT id FROM allocations WHERE consumer_id IN (` + consumerIds + `)`
+	stmts := []string{
+		`DELETE FROM allocation_items WHERE allocation_id IN (` + allocIds + `)`,
+		`DELETE FROM allocations WHERE consumer_id IN (` + consumerIds + `)`,
+		`DELETE FROM consumers WHERE uuid ` + InParamString(len(uuids)),
+	}
+	var res sql.Result
+	for _, qs := range stmts {
+		res, err = tx.ExecContext(ctx, s.driver.Rebind(qs), qargs...)
+		if err != nil {
+			return 0, err
+		}
+	}
+	// The last statement deleted the consumers themselves
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(affected), nil
+}
@@ -0,0 +1,333 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+const (
+	driverMySQL    = "mysql"
+	driverSQLite   = "sqlite"
+	driverPostgres = "postgres"
+
+	schemeMySQL      = "mysql://"
+	schemeSQLite     = "sqlite://"
+	schemePostgres   = "postgres://"
+	schemePostgreSQL = "postgresql://"
+
+	// SQLSTATE code for a unique constraint violation
+	pgUniqueViolation = "23505"
+)
+
+// execQueryer is implemented by both *sql.DB and *sql.Tx
+type execQueryer interface {
+	ExecContext(
+		ctx context.Context,
+		query string,
+		args ...interface{},
+	) (sql.Result, error)
+	QueryRowContext(
+		ctx context.Context,
+		query string,
+		args ...interface{},
+	) *sql.Row
+}
+
+// driver hides the differences between the SQL databases that may be used to
+// store resource information
+type driver interface {
+	// Name returns the name of the driver, which is used as the key into
+	// _MIGRATIONS and as the backend label of storage metrics
+	Name() string
+	// Open returns a new handle to the database. If multiStatements is true,
+	// the handle's connections accept multiple statements in a single
+	// execution.
+	Open(multiStatements bool) (*sql.DB, error)
+	// TableExists returns true if the named table exists in the database
+	TableExists(db *sql.DB, name string) (bool, error)
+	// IsDuplicate returns true if the supplied error was returned because a
+	// record violated a unique constraint
+	IsDuplicate(err error) bool
+	// Rebind returns the supplied query with each "?" parameter placeholder
+	// replaced with the driver's placeholder syntax
+	Rebind(query string) string
+	// Insert executes the supplied INSERT statement, which must not contain
+	// a RETURNING clause, and returns the value of the new record's
+	// auto-incrementing "id" column
+	Insert(
+		ctx context.Context,
+		q execQueryer,
+		query string,
+		args ...interface{},
+	) (int64, error)
+	// Lock acquires the named database-wide advisory lock, waiting until
+	// the supplied context is done, and returns a function that releases the
+	// lock. The lock is held by the supplied connection, which must stay open
+	// until the lock is released.
+	Lock(ctx context.Context, conn *sql.Conn, name string) (func(), error)
+}
+
+// newDriver returns the driver for the database named by the supplied DSN.
+// DSNs beginning with "sqlite://" name a SQLite database file and DSNs
+// beginning with "postgres://" or "postgresql://" are PostgreSQL connection
+// URLs. All other DSNs are MySQL DSNs, optionally beginning with "mysql://".
+func newDriver(dsn string) driver {
+	switch {
+	case strings.HasPrefix(dsn, schemeSQLite):
+		return &sqliteDriver{path: strings.TrimPrefix(dsn, schemeSQLite)}
+	case strings.HasPrefix(dsn, schemePostgres),
+		strings.HasPrefix(dsn, schemePostgreSQL):
+		return &postgresDriver{dsn: dsn}
+	}
+	return &mysqlDriver{dsn: strings.TrimPrefix(dsn, schemeMySQL)}
+}
+
+// insertLastID executes the supplied INSERT statement and returns the last
+// inserted ID reported by the database
+func insertLastID(
+	ctx context.Context,
+	q execQueryer,
+	query string,
+	args ...interface{},
+) (int64, error) {
+	res, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+type mysqlDriver struct {
+	dsn string
+}
+
+func (d *mysqlDriver) Name() string {
+	return driverMySQL
+}
+
+func (d *mysqlDriver) Open(multiStatements bool) (*sql.DB, error) {
+	cfg, err := mysql.ParseDSN(d.dsn)
+	if err != nil {
+		return nil, err
+	}
+	if multiStatements {
+		cfg.MultiStatements = true
+	}
+	return sql.Open("mysql", cfg.FormatDSN())
+}
+
+func (d *mysqlDriver) TableExists(db *sql.DB, name string) (bool, error) {
+	rows, err := db.Query("SHOW TABLES LIKE ?", name)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	return rows.Next(), rows.Err()
+}
+
+func (d *mysqlDriver) IsDuplicate(err error) bool {
+	me, ok := err.(*mysql.MySQLError)
+	return ok && me.Number == 1062
+}
+
+func (d *mysqlDriver) Rebind(query string) string {
+	return query
+}
+
+func (d *mysqlDriver) Insert(
+	ctx context.Context,
+	q execQueryer,
+	query string,
+	args ...interface{},
+) (int64, error) {
+	return insertLastID(ctx, q, query, args...)
+}
+
+// Lock uses GET_LOCK() to acquire a named lock
+func (d *mysqlDriver) Lock(
+	ctx context.Context,
+	conn *sql.Conn,
+	name string,
+) (func(), error) {
+	// GET_LOCK() takes a timeout in seconds. A negative timeout means wait
+	// forever, in which case the query is cancelled when ctx is done.
+	timeout := -1
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = int(time.Until(deadline).Seconds())
+	}
+	var got sql.NullInt64
+	qs := "SELECT GET_LOCK(?, ?)"
+	if err := conn.QueryRowContext(ctx, qs, name, timeout).Scan(&got); err != nil {
+		return nil, err
+	}
+	if !got.Valid || got.Int64 != 1 {
+		return nil, fmt.Errorf("timed out waiting for lock %s", name)
+	}
+	return func() {
+		var released sql.NullInt64
+		qs := "SELECT RELEASE_LOCK(?)"
+		conn.QueryRowContext(context.Background(), qs, name).Scan(&released)
+	}, nil
+}
+
+type sqliteDriver struct {
+	path string
+}
+
+func (d *sqliteDriver) Name() string {
+	return driverSQLite
+}
+
+// Open returns a handle to the SQLite database file. The SQLite driver always
+// accepts multiple statements in a single execution.
+//
+// NOTE(jaypipes): SQLite allows a single writer at a time. The database is
+// opened in WAL mode and with a busy timeout so that readers do not block the
+// writer and concurrent writers wait for each other instead of failing.
+func (d *sqliteDriver) Open(multiStatements bool) (*sql.DB, error) {
+	dsn := "file:" + d.path + "?_busy_timeout=5000&_journal_mode=WAL"
+	return sql.Open("sqlite3", dsn)
+}
+
+func (d *sqliteDriver) TableExists(db *sql.DB, name string) (bool, error) {
+	qs := "SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?"
+	rows, err := db.Query(qs, name)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	return rows.Next(), rows.Err()
+}
+
+func (d *sqliteDriver) IsDuplicate(err error) bool {
+	se, ok := err.(sqlite3.Error)
+	if !ok {
+		return false
+	}
+	return se.ExtendedCode == sqlite3.ErrConstraintUnique ||
+		se.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+}
+
+func (d *sqliteDriver) Rebind(query string) string {
+	return query
+}
+
+func (d *sqliteDriver) Insert(
+	ctx context.Context,
+	q execQueryer,
+	query string,
+	args ...interface{},
+) (int64, error) {
+	return insertLastID(ctx, q, query, args...)
+}
+
+// Lock is a no-op for SQLite.
+//
+// NOTE(jaypipes): SQLite has no advisory locks. SQLite databases must not be
+// shared by more than one runm-resource, and each migration runs in a
+// transaction, which SQLite serializes with other writers.
+func (d *sqliteDriver) Lock(
+	ctx context.Context,
+	conn *sql.Conn,
+	name string,
+) (func(), error) {
+	return func() {}, nil
+}
+
+type postgresDriver struct {
+	dsn string
+}
+
+func (d *postgresDriver) Name() string {
+	return driverPostgres
+}
+
+// Open returns a handle to the PostgreSQL database. PostgreSQL accepts
+// multiple statements in a single execution that has no parameters, so
+// multiStatements is ignored.
+func (d *postgresDriver) Open(multiStatements bool) (*sql.DB, error) {
+	return sql.Open("postgres", d.dsn)
+}
+
+func (d *postgresDriver) TableExists(db *sql.DB, name string) (bool, error) {
+	qs := `SELECT table_name FROM information_schema.tables
+WHERE table_schema = current_schema() AND table_name = $1`
+	rows, err := db.Query(qs, name)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	return rows.Next(), rows.Err()
+}
+
+func (d *postgresDriver) IsDuplicate(err error) bool {
+	pe, ok := err.(*pq.Error)
+	return ok && pe.Code == pgUniqueViolation
+}
+
+// Rebind replaces each "?" in the supplied query with a numbered "$n"
+// placeholder. Question marks in quoted string literals and identifiers are
+// left alone.
+func (d *postgresDriver) Rebind(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	var quote rune
+	for _, c := range query {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Insert executes the supplied INSERT statement with a RETURNING clause since
+// the PostgreSQL driver does not support sql.Result.LastInsertId()
+func (d *postgresDriver) Insert(
+	ctx context.Context,
+	q execQueryer,
+	query string,
+	args ...interface{},
+) (int64, error) {
+	var id int64
+	err := q.QueryRowContext(ctx, query+" RETURNING id", args...).Scan(&id)
+	return id, err
+}
+
+// Lock uses pg_advisory_lock() with a key derived from the lock name
+func (d *postgresDriver) Lock(
+	ctx context.Context,
+	conn *sql.Conn,
+	name string,
+) (func(), error) {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	key := int64(h.Sum64())
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+		return nil, err
+	}
+	return func() {
+		qs := "SELECT pg_advisory_unlock($1)"
+		conn.ExecContext(context.Background(), qs, key)
+	}, nil
+}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/runmachine-io/runmachine/pkg/metrics"
+	"github.com/runmachine-io/runmachine/pkg/tracing"
+)
+
+// operation is a traced and timed storage operation
+type operation struct {
+	backend string
+	name    string
+	start   time.Time
+	span    *tracing.Span
+}
+
+// startOperation starts a trace span for the named storage operation. It is
+// intended to be used by Store methods having a named error result, like so:
+//
+//	ctx, op := s.startOperation(ctx, "provider_get")
+//	defer op.end(&err)
+func (s *Store) startOperation(
+	ctx context.Context,
+	name string,
+) (context.Context, *operation) {
+	ctx, span := tracing.StartSpan(ctx, "sql."+name, tracing.SpanKindClient)
+	span.SetAttribute("db.system", s.driver.Name())
+	return ctx, &operation{
+		backend: s.driver.Name(),
+		name:    name,
+		start:   time.Now(),
+		span:    span,
+	}
+}
+
+// end records the duration and outcome of the operation and ends its span
+func (op *operation) end(err *error) {
+	metrics.ObserveStorage(op.backend, op.name, op.start, *err)
+	op.span.SetError(*err)
+	op.span.End()
+}
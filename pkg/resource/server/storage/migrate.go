@@ -1,279 +1,458 @@
 package storage
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
-	"log"
-)
+	"time"
 
-var (
-	// A hash of DB driver -> list of forward-only schema and data migrations
-	_MIGRATIONS = map[string][]string{
-		"mysql": []string{
-			`
-CREATE TABLE partitions (
-  id INT NOT NULL AUTO_INCREMENT PRIMARY KEY
-, uuid CHAR(32) NOT NULL
-, UNIQUE INDEX uix_uuid (uuid)
-) CHARACTER SET latin1 COLLATE latin1_bin;
+	"github.com/cenkalti/backoff"
 
-CREATE TABLE resource_types (
-  id INT NOT NULL AUTO_INCREMENT PRIMARY KEY
-, code VARCHAR(200) NOT NULL
-, UNIQUE INDEX uix_code (code)
-) CHARACTER SET latin1 COLLATE latin1_bin;
+	"github.com/runmachine-io/runmachine/pkg/logging"
+	"github.com/runmachine-io/runmachine/pkg/resource/server/config"
+)
 
-CREATE TABLE capabilities (
-  id INT NOT NULL AUTO_INCREMENT PRIMARY KEY
-, code VARCHAR(200) NOT NULL
-, description TEXT CHARACTER SET utf8 COLLATE utf8_bin NULL
-, UNIQUE INDEX uix_code (code)
-) CHARACTER SET latin1 COLLATE latin1_bin;
+const (
+	// Table recording the version, checksum and time of each applied
+	// migration
+	migrationsTable = "schema_migrations"
+	// Table used by earlier releases to record the schema version
+	legacyVersionTable = "db_version"
+	// Name of the advisory lock held while migrating
+	migrateLockName = "runm-resource-migrate"
+	// How long to wait for another runm-resource to finish migrating
+	migrateLockTimeout = 5 * time.Minute
+)
 
-CREATE TABLE distance_types (
-  id INT NOT NULL AUTO_INCREMENT PRIMARY KEY
-, code VARCHAR(200) NOT NULL
-, generation INT NOT NULL
-, UNIQUE INDEX uix_code (code)
-) CHARACTER SET latin1 COLLATE latin1_bin;
+// migration is a numbered, reversible change to the resource database schema
+type migration struct {
+	// Short description of the change
+	description string
+	// SQL script applying the change
+	up string
+	// SQL script reverting the change
+	down string
+}
 
-CREATE TABLE distances (
-  id INT NOT NULL AUTO_INCREMENT PRIMARY KEY
-, distance_type_id INT NOT NULL
-, code VARCHAR(200) NOT NULL
-, description TEXT CHARACTER SET utf8 COLLATE utf8_bin NULL
-, position INT NOT NULL
-, UNIQUE INDEX uix_distance_type_id (distance_type_id, code)
-) CHARACTER SET latin1 COLLATE latin1_bin;
+// checksum returns the hex-encoded SHA-256 hash of the migration's up script
+func (m migration) checksum() string {
+	sum := sha256.Sum256([]byte(m.up))
+	return hex.EncodeToString(sum[:])
+}
 
-CREATE TABLE provider_types (
-  id SMALLINT NOT NULL AUTO_INCREMENT PRIMARY KEY
-, code VARCHAR(200) NOT NULL
-, UNIQUE INDEX uix_code (code)
-) CHARACTER SET latin1 COLLATE latin1_bin;
+// MigrationStatus describes a migration and whether it has been applied to the
+// resource database
+type MigrationStatus struct {
+	Version     int
+	Description string
+	Applied     bool
+	// Time the migration was applied. Zero if the migration has not been
+	// applied or was applied before migrations were recorded individually.
+	AppliedAt time.Time
+	// True if the migration's up script has changed since it was applied
+	Modified bool
+}
 
-CREATE TABLE providers (
-  id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY
-, uuid CHAR(32) NOT NULL
-, provider_type_id INT NOT NULL
-, generation INT UNSIGNED NOT NULL
-, partition_id INT NOT NULL
-, parent_provider_id BIGINT NULL
-, UNIQUE INDEX uix_uuid (uuid)
-, INDEX ix_partition_id_provider_type_id (partition_id, provider_type_id)
-, INDEX ix_parent_provider_id (parent_provider_id)
-) CHARACTER SET latin1 COLLATE latin1_bin;
+// appliedMigration is a record of an applied migration
+type appliedMigration struct {
+	checksum  string
+	appliedAt int64
+}
 
-CREATE TABLE provider_trees (
-  id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY
-, root_provider_id BIGINT NOT NULL
-, nested_left INT NOT NULL
-, nested_right INT NOT NULL
-, generation INT NOT NULL
-, UNIQUE INDEX uix_nested_sets (root_provider_id, nested_left, nested_right)
-) CHARACTER SET latin1 COLLATE latin1_bin;
+// Migrator applies and reverts resource database schema migrations. Each
+// migration is run in its own transaction and recorded in the
+// schema_migrations table along with the checksum of its up script.
+//
+// NOTE(jaypipes): MySQL implicitly commits the transaction when executing
+// DDL statements, so a MySQL migration that fails partway may leave some of
+// its changes applied. SQLite and PostgreSQL roll back the whole migration.
+type Migrator struct {
+	log        *logging.Logs
+	driver     driver
+	db         *sql.DB
+	migrations []migration
+}
 
-CREATE TABLE provider_capabilities (
-  id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY
-, provider_id INT NOT NULL
-, capability_id INT NOT NULL
-, UNIQUE INDEX uix_provider_capability (provider_id, capability_id)
-, INDEX ix_capability (capability_id)
-) CHARACTER SET latin1 COLLATE latin1_bin;
+// NewMigrator returns a Migrator for the database named by the configured
+// storage DSN. It waits, using an exponential backoff, for up to the
+// configured storage connect timeout for the database to become reachable.
+// This is to be tolerant of the database starting *after* the service that
+// requires it.
+func NewMigrator(
+	log *logging.Logs,
+	cfg *config.Config,
+) (*Migrator, error) {
+	d := newDriver(cfg.StorageDSN)
+	migrations, ok := _MIGRATIONS[d.Name()]
+	if !ok {
+		return nil, fmt.Errorf("no migrations for DB driver %s", d.Name())
+	}
+	// Grab a DB handle that can support executing multiple statements in an
+	// execution context
+	db, err := d.Open(true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a DB handle: %s", err)
+	}
+	m := &Migrator{
+		log:        log,
+		driver:     d,
+		db:         db,
+		migrations: migrations,
+	}
+	if err := m.ping(cfg.StorageConnectTimeoutSeconds); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping DB: %s", err)
+	}
+	return m, nil
+}
 
-CREATE TABLE inventories (
-  id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY
-, provider_id BIGINT NOT NULL
-, resource_type_id INT NOT NULL
-, total BIGINT UNSIGNED NOT NULL
-, reserved BIGINT UNSIGNED NOT NULL
-, min_unit BIGINT UNSIGNED NOT NULL
-, max_unit BIGINT UNSIGNED NOT NULL
-, step_size BIGINT UNSIGNED NOT NULL
-, allocation_ratio FLOAT NOT NULL
-, UNIQUE INDEX uix_provider_id_resource_type_id (provider_id, resource_type_id)
-, INDEX ix_resource_type_id_total (resource_type_id, total)
-) CHARACTER SET latin1 COLLATE latin1_bin;
+// Close closes the Migrator's DB handle
+func (m *Migrator) Close() {
+	m.db.Close()
+}
 
-CREATE TABLE provider_groups (
-  id INT NOT NULL AUTO_INCREMENT PRIMARY KEY
-, uuid CHAR(32) NOT NULL
-, UNIQUE INDEX uix_uuid (uuid)
-) CHARACTER SET latin1 COLLATE latin1_bin;
+// Latest returns the version of the most recent migration
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
 
-CREATE TABLE provider_group_members (
-  provider_group_id INT NOT NULL
-, provider_id BIGINT NOT NULL
-, PRIMARY KEY (provider_group_id, provider_id)
-, INDEX (provider_id)
-);
+func (m *Migrator) ping(timeout time.Duration) error {
+	if timeout <= 0 {
+		return m.db.Ping()
+	}
+	bo := backoff.NewExponentialBackOff()
+	bo.MaxElapsedTime = timeout
+	notify := func(err error, wait time.Duration) {
+		m.log.L1("failed to ping DB, retrying in %s: %s", wait, err)
+	}
+	return backoff.RetryNotify(m.db.Ping, bo, notify)
+}
 
-CREATE TABLE provider_distances (
-  id INT NOT NULL AUTO_INCREMENT PRIMARY KEY
-, provider_id INT NOT NULL
-, provider_group_id INT NOT NULL
-, distance_id BIGINT NOT NULL
-, UNIQUE INDEX uix_provider_provider_group_distance (
-    provider_id
-  , provider_group_id
-  , distance_id)
-);
+// Status returns the status of all migrations, in version order
+func (m *Migrator) Status(ctx context.Context) ([]*MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*MigrationStatus, len(m.migrations))
+	for x, mig := range m.migrations {
+		version := x + 1
+		st := &MigrationStatus{
+			Version:     version,
+			Description: mig.description,
+		}
+		if am, ok := applied[version]; ok {
+			st.Applied = true
+			if am.appliedAt > 0 {
+				st.AppliedAt = time.Unix(am.appliedAt, 0).UTC()
+			}
+			st.Modified = am.checksum != "" && am.checksum != mig.checksum()
+		}
+		res[x] = st
+	}
+	return res, nil
+}
 
-CREATE TABLE consumer_types (
-  id SMALLINT NOT NULL AUTO_INCREMENT PRIMARY KEY
-, code VARCHAR(200) NOT NULL
-, UNIQUE INDEX uix_code (code)
-) CHARACTER SET latin1 COLLATE latin1_bin;
+// Up applies all unapplied migrations up to and including the target version
+// and returns the number of migrations applied. A target of 0 means the latest
+// version.
+func (m *Migrator) Up(ctx context.Context, target int) (int, error) {
+	if target == 0 {
+		target = m.Latest()
+	}
+	if target < 0 || target > m.Latest() {
+		return 0, fmt.Errorf(
+			"invalid target version %d. latest version is %d",
+			target, m.Latest(),
+		)
+	}
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
 
-CREATE TABLE consumers (
-  id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY
-, consumer_type_id SMALLINT NOT NULL
-, uuid CHAR(32) NOT NULL
-, generation INT UNSIGNED NOT NULL
-, owner_project_uuid CHAR(32) NOT NULL
-, owner_user_uuid CHAR(32) NOT NULL
-, UNIQUE INDEX uix_uuid (uuid)
-, INDEX ix_consumer_type_id (consumer_type_id)
-, INDEX ix_owner (owner_project_uuid, owner_user_uuid)
-) CHARACTER SET latin1 COLLATE latin1_bin;
+	applied, err := m.prepare(ctx)
+	if err != nil {
+		return 0, err
+	}
+	numApplied := 0
+	for version := 1; version <= target; version++ {
+		if _, ok := applied[version]; ok {
+			continue
+		}
+		if err := m.apply(ctx, version); err != nil {
+			return numApplied, err
+		}
+		numApplied++
+	}
+	return numApplied, nil
+}
 
-CREATE TABLE allocations (
-  id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY
-, consumer_id BIGINT NOT NULL
-, acquire_time BIGINT NOT NULL
-, release_time BIGINT NOT NULL
-, INDEX ix_consumer_window (consumer_id, acquire_time, release_time)
-, INDEX ix_window (acquire_time, release_time)
-) CHARACTER SET latin1 COLLATE latin1_bin;
+// Down reverts all applied migrations with a version greater than the target
+// version, most recent first, and returns the number of migrations reverted. A
+// target of 0 reverts all migrations.
+func (m *Migrator) Down(ctx context.Context, target int) (int, error) {
+	if target < 0 || target > m.Latest() {
+		return 0, fmt.Errorf(
+			"invalid target version %d. latest version is %d",
+			target, m.Latest(),
+		)
+	}
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
 
-CREATE TABLE allocation_items (
-  id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY
-, allocation_id BIGINT NOT NULL
-, provider_id BIGINT NOT NULL
-, resource_type_id INT NOT NULL
-, used BIGINT UNSIGNED NOT NULL
-, INDEX ix_allocation_id_provider_id_resource_type_id (
-    allocation_id
-  , provider_id
-  , resource_type_id)
-, INDEX ix_resource_type_id_provider_id_allocation_id (
-    resource_type_id
-  , provider_id
-  , allocation_id)
-) CHARACTER SET latin1 COLLATE latin1_bin;
-`,
-		},
+	applied, err := m.prepare(ctx)
+	if err != nil {
+		return 0, err
 	}
-)
+	numReverted := 0
+	for version := m.Latest(); version > target; version-- {
+		if _, ok := applied[version]; !ok {
+			continue
+		}
+		if err := m.revert(ctx, version); err != nil {
+			return numReverted, err
+		}
+		numReverted++
+	}
+	return numReverted, nil
+}
 
-// migrate checks to see if the resource database schema is up to date, and if
-// not, runs schema and data migrations to get the database up to the latest
-// schema version
-func (s *Store) migrate() error {
-	// Grab a single-use DB handle that can support executing multiple
-	// statements in an execution context
-	db, err := s.getDB(true, true)
+// lock acquires the migration advisory lock and returns a function that
+// releases it
+func (m *Migrator) lock(ctx context.Context) (func(), error) {
+	lockCtx, cancel := context.WithTimeout(ctx, migrateLockTimeout)
+	defer cancel()
+	conn, err := m.db.Conn(lockCtx)
+	if err != nil {
+		return nil, err
+	}
+	m.log.L3("acquiring migration lock...")
+	unlock, err := m.driver.Lock(lockCtx, conn, migrateLockName)
 	if err != nil {
-		// The DSN is bad or we got an OOM, so it's appropriate to stop
-		// execution here
-		log.Fatalf("failed to get a DB handle during migration: %s\n", err)
+		conn.Close()
+		return nil, fmt.Errorf("failed to acquire migration lock: %s", err)
 	}
-	defer db.Close()
+	m.log.L3("acquired migration lock")
+	return func() {
+		unlock()
+		conn.Close()
+	}, nil
+}
 
-	// TODO(jaypipes): Support retry with exponential backoff to allow for
-	// out-of-order service startup
-	if err := db.Ping(); err != nil {
-		// The credentials for connecting to the DB are bad, so it's
-		// appropriate to stop execution here
-		log.Fatalf("failed to ping DB during migration: %s\n", err)
+// applied returns a map, keyed by version, of applied migrations. Databases
+// versioned by earlier releases of runm-resource are supported.
+func (m *Migrator) applied(
+	ctx context.Context,
+) (map[int]*appliedMigration, error) {
+	res := map[int]*appliedMigration{}
+	exists, err := m.driver.TableExists(m.db, migrationsTable)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		// Databases migrated by earlier releases of runm-resource only
+		// recorded the version of the most recently applied migration
+		legacy, err := m.driver.TableExists(m.db, legacyVersionTable)
+		if err != nil || !legacy {
+			return res, err
+		}
+		var dbVersion int
+		qs := "SELECT version FROM " + legacyVersionTable
+		if err := m.db.QueryRowContext(ctx, qs).Scan(&dbVersion); err != nil {
+			return nil, err
+		}
+		for version := 1; version <= dbVersion; version++ {
+			res[version] = &appliedMigration{}
+		}
+		return res, nil
 	}
 
-	s.log.L3("determining current DB version...")
+	qs := "SELECT version, checksum, applied_at FROM " + migrationsTable
+	rows, err := m.db.QueryContext(ctx, qs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		am := &appliedMigration{}
+		if err := rows.Scan(&version, &am.checksum, &am.appliedAt); err != nil {
+			return nil, err
+		}
+		res[version] = am
+	}
+	return res, rows.Err()
+}
 
-	dbVersion := int64(0)
-	if !tableExists(db, "db_version") {
-		s.log.L3("resource DB is not versioned. initializing versioned DB...")
-		if err := versionDB(db); err != nil {
-			// If we couldn't create a simple DB table, it's appropriate to
-			// stop execution here
-			log.Fatalf(
-				"failed to create db_version table during migration: %s\n",
-				err,
+// prepare creates the schema_migrations table if it does not exist, recording
+// the migrations applied by earlier releases of runm-resource, and returns the
+// applied migrations. An error is returned if any applied migration has been
+// modified since it was applied.
+func (m *Migrator) prepare(
+	ctx context.Context,
+) (map[int]*appliedMigration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := m.driver.TableExists(m.db, migrationsTable)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err := m.createMigrationsTable(ctx, applied); err != nil {
+			return nil, err
+		}
+		return m.applied(ctx)
+	}
+	for version, am := range applied {
+		if version > m.Latest() {
+			return nil, fmt.Errorf(
+				"DB has migration %d applied but latest known migration "+
+					"is %d. Is this runm-resource older than the DB?",
+				version, m.Latest(),
+			)
+		}
+		if am.checksum != m.migrations[version-1].checksum() {
+			return nil, fmt.Errorf(
+				"migration %d was modified after it was applied", version,
 			)
 		}
-		s.log.L1("initialized DB version")
-	} else {
-		dbVersion = getDBVersion(db)
 	}
+	return applied, nil
+}
 
-	migrations := _MIGRATIONS["mysql"]
-	if int64(len(migrations)) > dbVersion {
-		// Execute each migration SQL script, setting the DB version for each
-		// successful migration.
-		for x, migration := range migrations {
-			// TODO(jaypipes): Wrap this in a transaction
-			res, err := db.Exec(migration)
-			if err != nil {
-				s.log.ERR("failed executing migration %d: %s", x, err)
-				return err
-			}
-			qs := "UPDATE db_version SET version = ? WHERE version = ?"
-			res, err = db.Exec(qs, x+1, x)
-			if err != nil {
-				s.log.ERR("failed updating DB version to %d: %s", x+1, err)
-				return err
+// createMigrationsTable creates the schema_migrations table, records the
+// supplied applied migrations in it and drops the legacy db_version table
+func (m *Migrator) createMigrationsTable(
+	ctx context.Context,
+	applied map[int]*appliedMigration,
+) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qs := `CREATE TABLE ` + migrationsTable + ` (
+  version INT NOT NULL PRIMARY KEY
+, checksum CHAR(64) NOT NULL
+, applied_at BIGINT NOT NULL
+)`
+	if _, err := tx.ExecContext(ctx, qs); err != nil {
+		return err
+	}
+	if len(applied) > 0 {
+		m.log.L1(
+			"recording %d migrations applied by earlier runm-resource",
+			len(applied),
+		)
+		for version := range applied {
+			if version > m.Latest() {
+				return fmt.Errorf(
+					"DB version %d is newer than latest known migration %d",
+					version, m.Latest(),
+				)
 			}
-			if affected, err := res.RowsAffected(); err != nil {
-				s.log.ERR("failed updating DB version to %d: %s", x+1, err)
+			if err := m.record(ctx, tx, version, 0); err != nil {
 				return err
-			} else if affected != 1 {
-				s.log.L2("another service migrated to DB version", x+1, err)
-				continue
 			}
-			s.log.L1("migrated resource DB version to %d", x+1)
+		}
+		qs = "DROP TABLE " + legacyVersionTable
+		if _, err := tx.ExecContext(ctx, qs); err != nil {
+			return err
 		}
 	}
+	return tx.Commit()
+}
 
-	return nil
+// record inserts a record of the applied migration with the supplied version
+func (m *Migrator) record(
+	ctx context.Context,
+	tx *sql.Tx,
+	version int,
+	appliedAt int64,
+) error {
+	qs := "INSERT INTO " + migrationsTable +
+		" (version, checksum, applied_at) VALUES (?, ?, ?)"
+	_, err := tx.ExecContext(
+		ctx,
+		m.driver.Rebind(qs),
+		version,
+		m.migrations[version-1].checksum(),
+		appliedAt,
+	)
+	return err
 }
 
-// versionDB attempts to create the db_version table and sets the initial DB
-// version to 0
-func versionDB(db *sql.DB) error {
-	qs := `
-CREATE TABLE db_version (
-	version INT UNSIGNED NOT NULL DEFAULT 0 PRIMARY KEY
-);
-INSERT INTO db_version (version) VALUES (0);
-`
-	if _, err := db.Query(qs); err != nil {
+// apply runs the up script of the migration with the supplied version and
+// records it as applied in the same transaction
+func (m *Migrator) apply(ctx context.Context, version int) error {
+	mig := m.migrations[version-1]
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, mig.up); err != nil {
+		return fmt.Errorf("failed executing migration %d: %s", version, err)
+	}
+	if err := m.record(ctx, tx, version, time.Now().Unix()); err != nil {
+		return fmt.Errorf("failed recording migration %d: %s", version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed committing migration %d: %s", version, err)
+	}
+	m.log.L1("applied migration %d (%s)", version, mig.description)
 	return nil
 }
 
-// getDBVersion returns the current version of the database, or 0 if there was
-// an error attempting to determine the database version
-func getDBVersion(db *sql.DB) int64 {
-	var version int64
-	err := db.QueryRow("SELECT version FROM db_version").Scan(&version)
+// revert runs the down script of the migration with the supplied version and
+// removes its record in the same transaction
+func (m *Migrator) revert(ctx context.Context, version int) error {
+	mig := m.migrations[version-1]
+	tx, err := m.db.BeginTx(ctx, nil)
 	if err != nil {
-		return 0
+		return err
 	}
-	return version
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, mig.down); err != nil {
+		return fmt.Errorf("failed reverting migration %d: %s", version, err)
+	}
+	qs := "DELETE FROM " + migrationsTable + " WHERE version = ?"
+	if _, err := tx.ExecContext(ctx, m.driver.Rebind(qs), version); err != nil {
+		return fmt.Errorf("failed unrecording migration %d: %s", version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed committing revert of migration %d: %s", version, err)
+	}
+	m.log.L1("reverted migration %d (%s)", version, mig.description)
+	return nil
 }
 
-// tableExists returns true if the searched-for table exists in the DB, false
-// otherwise. The supplied DB handle should have already been pinged to verify
-// connectivity. This function swallows connectivity errors.
-func tableExists(db *sql.DB, name string) bool {
-	rows, err := db.Query("SHOW TABLES LIKE '" + name + "'")
+// migrate brings the resource database schema up to date
+func (s *Store) migrate(ctx context.Context) error {
+	m, err := NewMigrator(s.log, s.cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	numApplied, err := m.Up(ctx, 0)
 	if err != nil {
-		fmt.Printf("failed trying to check if table exists: %s\n", err)
-		return false
+		return err
 	}
-	for rows.Next() {
-		return true
+	if numApplied > 0 {
+		s.log.L1("migrated resource DB to version %d", m.Latest())
 	}
-	return false
+	return nil
 }
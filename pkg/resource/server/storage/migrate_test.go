@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/runmachine-io/runmachine/pkg/logging"
+	"github.com/runmachine-io/runmachine/pkg/resource/server/config"
+)
+
+// newSQLiteMigrator returns a Migrator for an empty SQLite database in a
+// temporary directory along with a function that closes the Migrator and
+// removes the directory
+func newSQLiteMigrator(t *testing.T) (*Migrator, func()) {
+	dir, err := ioutil.TempDir("", "runm-resource-migrate")
+	require.Nil(t, err)
+	cfg := &config.Config{
+		StorageDSN: "sqlite://" + filepath.Join(dir, "resource.db"),
+	}
+	m, err := NewMigrator(logging.New(&logging.Config{}), cfg)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+	return m, func() {
+		m.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func tableExists(t *testing.T, m *Migrator, name string) bool {
+	exists, err := m.driver.TableExists(m.db, name)
+	require.Nil(t, err)
+	return exists
+}
+
+func TestMigrateUpDown(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.TODO()
+
+	m, cleanup := newSQLiteMigrator(t)
+	defer cleanup()
+
+	statuses, err := m.Status(ctx)
+	assert.Nil(err)
+	assert.Len(statuses, m.Latest())
+	for _, st := range statuses {
+		assert.False(st.Applied)
+	}
+
+	n, err := m.Up(ctx, 0)
+	assert.Nil(err)
+	assert.Equal(m.Latest(), n)
+	assert.True(tableExists(t, m, "providers"))
+
+	statuses, err = m.Status(ctx)
+	assert.Nil(err)
+	for _, st := range statuses {
+		assert.True(st.Applied)
+		assert.False(st.Modified)
+		assert.False(st.AppliedAt.IsZero())
+	}
+
+	// Migrating an up-to-date database should be a no-op
+	n, err = m.Up(ctx, 0)
+	assert.Nil(err)
+	assert.Equal(0, n)
+
+	n, err = m.Down(ctx, 0)
+	assert.Nil(err)
+	assert.Equal(m.Latest(), n)
+	assert.False(tableExists(t, m, "providers"))
+	assert.True(tableExists(t, m, migrationsTable))
+
+	statuses, err = m.Status(ctx)
+	assert.Nil(err)
+	for _, st := range statuses {
+		assert.False(st.Applied)
+	}
+
+	// Reverted migrations can be applied again
+	n, err = m.Up(ctx, 0)
+	assert.Nil(err)
+	assert.Equal(m.Latest(), n)
+	assert.True(tableExists(t, m, "providers"))
+}
+
+func TestMigrateInvalidTarget(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.TODO()
+
+	m, cleanup := newSQLiteMigrator(t)
+	defer cleanup()
+
+	_, err := m.Up(ctx, m.Latest()+1)
+	assert.NotNil(err)
+	_, err = m.Down(ctx, -1)
+	assert.NotNil(err)
+}
+
+func TestMigrateModified(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.TODO()
+
+	m, cleanup := newSQLiteMigrator(t)
+	defer cleanup()
+
+	_, err := m.Up(ctx, 0)
+	require.Nil(t, err)
+
+	// Simulate the up script of an applied migration having been edited
+	_, err = m.db.Exec(
+		"UPDATE "+migrationsTable+" SET checksum = ? WHERE version = 1",
+		"deadbeef",
+	)
+	require.Nil(t, err)
+
+	statuses, err := m.Status(ctx)
+	assert.Nil(err)
+	assert.True(statuses[0].Modified)
+
+	_, err = m.Up(ctx, 0)
+	assert.NotNil(err)
+	_, err = m.Down(ctx, 0)
+	assert.NotNil(err)
+}
+
+func TestMigrateLegacyVersion(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.TODO()
+
+	m, cleanup := newSQLiteMigrator(t)
+	defer cleanup()
+
+	// Set up the database the way earlier releases of runm-resource did
+	_, err := m.db.Exec(m.migrations[0].up)
+	require.Nil(t, err)
+	_, err = m.db.Exec(`
+CREATE TABLE db_version (
+	version INT UNSIGNED NOT NULL DEFAULT 0 PRIMARY KEY
+);
+INSERT INTO db_version (version) VALUES (1);
+`)
+	require.Nil(t, err)
+
+	statuses, err := m.Status(ctx)
+	assert.Nil(err)
+	assert.True(statuses[0].Applied)
+	assert.True(statuses[0].AppliedAt.IsZero())
+
+	n, err := m.Up(ctx, 0)
+	assert.Nil(err)
+	assert.Equal(m.Latest()-1, n)
+	assert.False(tableExists(t, m, legacyVersionTable))
+
+	statuses, err = m.Status(ctx)
+	assert.Nil(err)
+	assert.True(statuses[0].Applied)
+	assert.False(statuses[0].Modified)
+}
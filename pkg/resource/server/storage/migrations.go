@@ -0,0 +1,518 @@
+package storage
+
+// initialSchemaDown reverts the initial schema migration for all drivers
+const initialSchemaDown = `
+DROP TABLE allocation_items;
+DROP TABLE allocations;
+DROP TABLE consumers;
+DROP TABLE consumer_types;
+DROP TABLE provider_distances;
+DROP TABLE provider_group_members;
+DROP TABLE provider_groups;
+DROP TABLE inventories;
+DROP TABLE provider_capabilities;
+DROP TABLE provider_trees;
+DROP TABLE providers;
+DROP TABLE provider_types;
+DROP TABLE distances;
+DROP TABLE distance_types;
+DROP TABLE capabilities;
+DROP TABLE resource_types;
+DROP TABLE partitions;
+`
+
+var (
+	// A hash of DB driver -> list of schema and data migrations. Migrations
+	// are numbered from 1 in the order they appear in the list. The
+	// migrations for each driver must describe the same schema changes in the
+	// same order.
+	//
+	// NOTE(jaypipes): Never edit the up script of a released migration. The
+	// checksum of each up script is recorded when the migration is applied and
+	// runm-resource refuses to migrate a database whose applied migrations no
+	// longer match. Add a new migration instead.
+	_MIGRATIONS = map[string][]migration{
+		"mysql": []migration{
+			{
+				description: "initial schema",
+				up: `
+CREATE TABLE partitions (
+  id INT NOT NULL AUTO_INCREMENT PRIMARY KEY
+, uuid CHAR(32) NOT NULL
+, UNIQUE INDEX uix_uuid (uuid)
+) CHARACTER SET latin1 COLLATE latin1_bin;
+
+CREATE TABLE resource_types (
+  id INT NOT NULL AUTO_INCREMENT PRIMARY KEY
+, code VARCHAR(200) NOT NULL
+, UNIQUE INDEX uix_code (code)
+) CHARACTER SET latin1 COLLATE latin1_bin;
+
+CREATE TABLE capabilities (
+  id INT NOT NULL AUTO_INCREMENT PRIMARY KEY
+, code VARCHAR(200) NOT NULL
+, description TEXT CHARACTER SET utf8 COLLATE utf8_bin NULL
+, UNIQUE INDEX uix_code (code)
+) CHARACTER SET latin1 COLLATE latin1_bin;
+
+CREATE TABLE distance_types (
+  id INT NOT NULL AUTO_INCREMENT PRIMARY KEY
+, code VARCHAR(200) NOT NULL
+, generation INT NOT NULL
+, UNIQUE INDEX uix_code (code)
+) CHARACTER SET latin1 COLLATE latin1_bin;
+
+CREATE TABLE distances (
+  id INT NOT NULL AUTO_INCREMENT PRIMARY KEY
+, distance_type_id INT NOT NULL
+, code VARCHAR(200) NOT NULL
+, description TEXT CHARACTER SET utf8 COLLATE utf8_bin NULL
+, position INT NOT NULL
+, UNIQUE INDEX uix_distance_type_id (distance_type_id, code)
+) CHARACTER SET latin1 COLLATE latin1_bin;
+
+CREATE TABLE provider_types (
+  id SMALLINT NOT NULL AUTO_INCREMENT PRIMARY KEY
+, code VARCHAR(200) NOT NULL
+, UNIQUE INDEX uix_code (code)
+) CHARACTER SET latin1 COLLATE latin1_bin;
+
+CREATE TABLE providers (
+  id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY
+, uuid CHAR(32) NOT NULL
+, provider_type_id INT NOT NULL
+, generation INT UNSIGNED NOT NULL
+, partition_id INT NOT NULL
+, parent_provider_id BIGINT NULL
+, UNIQUE INDEX uix_uuid (uuid)
+, INDEX ix_partition_id_provider_type_id (partition_id, provider_type_id)
+, INDEX ix_parent_provider_id (parent_provider_id)
+) CHARACTER SET latin1 COLLATE latin1_bin;
+
+CREATE TABLE provider_trees (
+  id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY
+, root_provider_id BIGINT NOT NULL
+, nested_left INT NOT NULL
+, nested_right INT NOT NULL
+, generation INT NOT NULL
+, UNIQUE INDEX uix_nested_sets (root_provider_id, nested_left, nested_right)
+) CHARACTER SET latin1 COLLATE latin1_bin;
+
+CREATE TABLE provider_capabilities (
+  id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY
+, provider_id INT NOT NULL
+, capability_id INT NOT NULL
+, UNIQUE INDEX uix_provider_capability (provider_id, capability_id)
+, INDEX ix_capability (capability_id)
+) CHARACTER SET latin1 COLLATE latin1_bin;
+
+CREATE TABLE inventories (
+  id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY
+, provider_id BIGINT NOT NULL
+, resource_type_id INT NOT NULL
+, total BIGINT UNSIGNED NOT NULL
+, reserved BIGINT UNSIGNED NOT NULL
+, min_unit BIGINT UNSIGNED NOT NULL
+, max_unit BIGINT UNSIGNED NOT NULL
+, step_size BIGINT UNSIGNED NOT NULL
+, allocation_ratio FLOAT NOT NULL
+, UNIQUE INDEX uix_provider_id_resource_type_id (provider_id, resource_type_id)
+, INDEX ix_resource_type_id_total (resource_type_id, total)
+) CHARACTER SET latin1 COLLATE latin1_bin;
+
+CREATE TABLE provider_groups (
+  id INT NOT NULL AUTO_INCREMENT PRIMARY KEY
+, uuid CHAR(32) NOT NULL
+, UNIQUE INDEX uix_uuid (uuid)
+) CHARACTER SET latin1 COLLATE latin1_bin;
+
+CREATE TABLE provider_group_members (
+  provider_group_id INT NOT NULL
+, provider_id BIGINT NOT NULL
+, PRIMARY KEY (provider_group_id, provider_id)
+, INDEX (provider_id)
+);
+
+CREATE TABLE provider_distances (
+  id INT NOT NULL AUTO_INCREMENT PRIMARY KEY
+, provider_id INT NOT NULL
+, provider_group_id INT NOT NULL
+, distance_id BIGINT NOT NULL
+, UNIQUE INDEX uix_provider_provider_group_distance (
+    provider_id
+  , provider_group_id
+  , distance_id)
+);
+
+CREATE TABLE consumer_types (
+  id SMALLINT NOT NULL AUTO_INCREMENT PRIMARY KEY
+, code VARCHAR(200) NOT NULL
+, UNIQUE INDEX uix_code (code)
+) CHARACTER SET latin1 COLLATE latin1_bin;
+
+CREATE TABLE consumers (
+  id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY
+, consumer_type_id SMALLINT NOT NULL
+, uuid CHAR(32) NOT NULL
+, generation INT UNSIGNED NOT NULL
+, owner_project_uuid CHAR(32) NOT NULL
+, owner_user_uuid CHAR(32) NOT NULL
+, UNIQUE INDEX uix_uuid (uuid)
+, INDEX ix_consumer_type_id (consumer_type_id)
+, INDEX ix_owner (owner_project_uuid, owner_user_uuid)
+) CHARACTER SET latin1 COLLATE latin1_bin;
+
+CREATE TABLE allocations (
+  id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY
+, consumer_id BIGINT NOT NULL
+, acquire_time BIGINT NOT NULL
+, release_time BIGINT NOT NULL
+, INDEX ix_consumer_window (consumer_id, acquire_time, release_time)
+, INDEX ix_window (acquire_time, release_time)
+) CHARACTER SET latin1 COLLATE latin1_bin;
+
+CREATE TABLE allocation_items (
+  id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY
+, allocation_id BIGINT NOT NULL
+, provider_id BIGINT NOT NULL
+, resource_type_id INT NOT NULL
+, used BIGINT UNSIGNED NOT NULL
+, INDEX ix_allocation_id_provider_id_resource_type_id (
+    allocation_id
+  , provider_id
+  , resource_type_id)
+, INDEX ix_resource_type_id_provider_id_allocation_id (
+    resource_type_id
+  , provider_id
+  , allocation_id)
+) CHARACTER SET latin1 COLLATE latin1_bin;
+`,
+				down: initialSchemaDown,
+			},
+		},
+		"sqlite": []migration{
+			{
+				description: "initial schema",
+				up: `
+CREATE TABLE partitions (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT
+, uuid CHAR(32) NOT NULL
+);
+CREATE UNIQUE INDEX partitions_uix_uuid ON partitions (uuid);
+
+CREATE TABLE resource_types (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT
+, code VARCHAR(200) NOT NULL
+);
+CREATE UNIQUE INDEX resource_types_uix_code ON resource_types (code);
+
+CREATE TABLE capabilities (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT
+, code VARCHAR(200) NOT NULL
+, description TEXT NULL
+);
+CREATE UNIQUE INDEX capabilities_uix_code ON capabilities (code);
+
+CREATE TABLE distance_types (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT
+, code VARCHAR(200) NOT NULL
+, generation INT NOT NULL
+);
+CREATE UNIQUE INDEX distance_types_uix_code ON distance_types (code);
+
+CREATE TABLE distances (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT
+, distance_type_id INT NOT NULL
+, code VARCHAR(200) NOT NULL
+, description TEXT NULL
+, position INT NOT NULL
+);
+CREATE UNIQUE INDEX distances_uix_distance_type_id
+  ON distances (distance_type_id, code);
+
+CREATE TABLE provider_types (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT
+, code VARCHAR(200) NOT NULL
+);
+CREATE UNIQUE INDEX provider_types_uix_code ON provider_types (code);
+
+CREATE TABLE providers (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT
+, uuid CHAR(32) NOT NULL
+, provider_type_id INT NOT NULL
+, generation INT NOT NULL
+, partition_id INT NOT NULL
+, parent_provider_id BIGINT NULL
+);
+CREATE UNIQUE INDEX providers_uix_uuid ON providers (uuid);
+CREATE INDEX providers_ix_partition_id_provider_type_id
+  ON providers (partition_id, provider_type_id);
+CREATE INDEX providers_ix_parent_provider_id ON providers (parent_provider_id);
+
+CREATE TABLE provider_trees (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT
+, root_provider_id BIGINT NOT NULL
+, nested_left INT NOT NULL
+, nested_right INT NOT NULL
+, generation INT NOT NULL
+);
+CREATE UNIQUE INDEX provider_trees_uix_nested_sets
+  ON provider_trees (root_provider_id, nested_left, nested_right);
+
+CREATE TABLE provider_capabilities (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT
+, provider_id INT NOT NULL
+, capability_id INT NOT NULL
+);
+CREATE UNIQUE INDEX provider_capabilities_uix_provider_capability
+  ON provider_capabilities (provider_id, capability_id);
+CREATE INDEX provider_capabilities_ix_capability
+  ON provider_capabilities (capability_id);
+
+CREATE TABLE inventories (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT
+, provider_id BIGINT NOT NULL
+, resource_type_id INT NOT NULL
+, total BIGINT NOT NULL
+, reserved BIGINT NOT NULL
+, min_unit BIGINT NOT NULL
+, max_unit BIGINT NOT NULL
+, step_size BIGINT NOT NULL
+, allocation_ratio FLOAT NOT NULL
+);
+CREATE UNIQUE INDEX inventories_uix_provider_id_resource_type_id
+  ON inventories (provider_id, resource_type_id);
+CREATE INDEX inventories_ix_resource_type_id_total
+  ON inventories (resource_type_id, total);
+
+CREATE TABLE provider_groups (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT
+, uuid CHAR(32) NOT NULL
+);
+CREATE UNIQUE INDEX provider_groups_uix_uuid ON provider_groups (uuid);
+
+CREATE TABLE provider_group_members (
+  provider_group_id INT NOT NULL
+, provider_id BIGINT NOT NULL
+, PRIMARY KEY (provider_group_id, provider_id)
+);
+CREATE INDEX provider_group_members_ix_provider_id
+  ON provider_group_members (provider_id);
+
+CREATE TABLE provider_distances (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT
+, provider_id INT NOT NULL
+, provider_group_id INT NOT NULL
+, distance_id BIGINT NOT NULL
+);
+CREATE UNIQUE INDEX provider_distances_uix_provider_provider_group_distance
+  ON provider_distances (provider_id, provider_group_id, distance_id);
+
+CREATE TABLE consumer_types (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT
+, code VARCHAR(200) NOT NULL
+);
+CREATE UNIQUE INDEX consumer_types_uix_code ON consumer_types (code);
+
+CREATE TABLE consumers (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT
+, consumer_type_id SMALLINT NOT NULL
+, uuid CHAR(32) NOT NULL
+, generation INT NOT NULL
+, owner_project_uuid CHAR(32) NOT NULL
+, owner_user_uuid CHAR(32) NOT NULL
+);
+CREATE UNIQUE INDEX consumers_uix_uuid ON consumers (uuid);
+CREATE INDEX consumers_ix_consumer_type_id ON consumers (consumer_type_id);
+CREATE INDEX consumers_ix_owner
+  ON consumers (owner_project_uuid, owner_user_uuid);
+
+CREATE TABLE allocations (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT
+, consumer_id BIGINT NOT NULL
+, acquire_time BIGINT NOT NULL
+, release_time BIGINT NOT NULL
+);
+CREATE INDEX allocations_ix_consumer_window
+  ON allocations (consumer_id, acquire_time, release_time);
+CREATE INDEX allocations_ix_window ON allocations (acquire_time, release_time);
+
+CREATE TABLE allocation_items (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT
+, allocation_id BIGINT NOT NULL
+, provider_id BIGINT NOT NULL
+, resource_type_id INT NOT NULL
+, used BIGINT NOT NULL
+);
+CREATE INDEX allocation_items_ix_allocation_id_provider_id_resource_type_id
+  ON allocation_items (allocation_id, provider_id, resource_type_id);
+CREATE INDEX allocation_items_ix_resource_type_id_provider_id_allocation_id
+  ON allocation_items (resource_type_id, provider_id, allocation_id);
+`,
+				down: initialSchemaDown,
+			},
+		},
+		"postgres": []migration{
+			{
+				description: "initial schema",
+				up: `
+CREATE TABLE partitions (
+  id SERIAL PRIMARY KEY
+, uuid CHAR(32) NOT NULL
+);
+CREATE UNIQUE INDEX partitions_uix_uuid ON partitions (uuid);
+
+CREATE TABLE resource_types (
+  id SERIAL PRIMARY KEY
+, code VARCHAR(200) NOT NULL
+);
+CREATE UNIQUE INDEX resource_types_uix_code ON resource_types (code);
+
+CREATE TABLE capabilities (
+  id SERIAL PRIMARY KEY
+, code VARCHAR(200) NOT NULL
+, description TEXT NULL
+);
+CREATE UNIQUE INDEX capabilities_uix_code ON capabilities (code);
+
+CREATE TABLE distance_types (
+  id SERIAL PRIMARY KEY
+, code VARCHAR(200) NOT NULL
+, generation INT NOT NULL
+);
+CREATE UNIQUE INDEX distance_types_uix_code ON distance_types (code);
+
+CREATE TABLE distances (
+  id SERIAL PRIMARY KEY
+, distance_type_id INT NOT NULL
+, code VARCHAR(200) NOT NULL
+, description TEXT NULL
+, position INT NOT NULL
+);
+CREATE UNIQUE INDEX distances_uix_distance_type_id
+  ON distances (distance_type_id, code);
+
+CREATE TABLE provider_types (
+  id SMALLSERIAL PRIMARY KEY
+, code VARCHAR(200) NOT NULL
+);
+CREATE UNIQUE INDEX provider_types_uix_code ON provider_types (code);
+
+CREATE TABLE providers (
+  id BIGSERIAL PRIMARY KEY
+, uuid CHAR(32) NOT NULL
+, provider_type_id INT NOT NULL
+, generation INT NOT NULL
+, partition_id INT NOT NULL
+, parent_provider_id BIGINT NULL
+);
+CREATE UNIQUE INDEX providers_uix_uuid ON providers (uuid);
+CREATE INDEX providers_ix_partition_id_provider_type_id
+  ON providers (partition_id, provider_type_id);
+CREATE INDEX providers_ix_parent_provider_id ON providers (parent_provider_id);
+
+CREATE TABLE provider_trees (
+  id BIGSERIAL PRIMARY KEY
+, root_provider_id BIGINT NOT NULL
+, nested_left INT NOT NULL
+, nested_right INT NOT NULL
+, generation INT NOT NULL
+);
+CREATE UNIQUE INDEX provider_trees_uix_nested_sets
+  ON provider_trees (root_provider_id, nested_left, nested_right);
+
+CREATE TABLE provider_capabilities (
+  id BIGSERIAL PRIMARY KEY
+, provider_id INT NOT NULL
+, capability_id INT NOT NULL
+);
+CREATE UNIQUE INDEX provider_capabilities_uix_provider_capability
+  ON provider_capabilities (provider_id, capability_id);
+CREATE INDEX provider_capabilities_ix_capability
+  ON provider_capabilities (capability_id);
+
+CREATE TABLE inventories (
+  id BIGSERIAL PRIMARY KEY
+, provider_id BIGINT NOT NULL
+, resource_type_id INT NOT NULL
+, total BIGINT NOT NULL
+, reserved BIGINT NOT NULL
+, min_unit BIGINT NOT NULL
+, max_unit BIGINT NOT NULL
+, step_size BIGINT NOT NULL
+, allocation_ratio DOUBLE PRECISION NOT NULL
+);
+CREATE UNIQUE INDEX inventories_uix_provider_id_resource_type_id
+  ON inventories (provider_id, resource_type_id);
+CREATE INDEX inventories_ix_resource_type_id_total
+  ON inventories (resource_type_id, total);
+
+CREATE TABLE provider_groups (
+  id SERIAL PRIMARY KEY
+, uuid CHAR(32) NOT NULL
+);
+CREATE UNIQUE INDEX provider_groups_uix_uuid ON provider_groups (uuid);
+
+CREATE TABLE provider_group_members (
+  provider_group_id INT NOT NULL
+, provider_id BIGINT NOT NULL
+, PRIMARY KEY (provider_group_id, provider_id)
+);
+CREATE INDEX provider_group_members_ix_provider_id
+  ON provider_group_members (provider_id);
+
+CREATE TABLE provider_distances (
+  id SERIAL PRIMARY KEY
+, provider_id INT NOT NULL
+, provider_group_id INT NOT NULL
+, distance_id BIGINT NOT NULL
+);
+CREATE UNIQUE INDEX provider_distances_uix_provider_provider_group_distance
+  ON provider_distances (provider_id, provider_group_id, distance_id);
+
+CREATE TABLE consumer_types (
+  id SMALLSERIAL PRIMARY KEY
+, code VARCHAR(200) NOT NULL
+);
+CREATE UNIQUE INDEX consumer_types_uix_code ON consumer_types (code);
+
+CREATE TABLE consumers (
+  id BIGSERIAL PRIMARY KEY
+, consumer_type_id SMALLINT NOT NULL
+, uuid CHAR(32) NOT NULL
+, generation INT NOT NULL
+, owner_project_uuid CHAR(32) NOT NULL
+, owner_user_uuid CHAR(32) NOT NULL
+);
+CREATE UNIQUE INDEX consumers_uix_uuid ON consumers (uuid);
+CREATE INDEX consumers_ix_consumer_type_id ON consumers (consumer_type_id);
+CREATE INDEX consumers_ix_owner
+  ON consumers (owner_project_uuid, owner_user_uuid);
+
+CREATE TABLE allocations (
+  id BIGSERIAL PRIMARY KEY
+, consumer_id BIGINT NOT NULL
+, acquire_time BIGINT NOT NULL
+, release_time BIGINT NOT NULL
+);
+CREATE INDEX allocations_ix_consumer_window
+  ON allocations (consumer_id, acquire_time, release_time);
+CREATE INDEX allocations_ix_window ON allocations (acquire_time, release_time);
+
+CREATE TABLE allocation_items (
+  id BIGSERIAL PRIMARY KEY
+, allocation_id BIGINT NOT NULL
+, provider_id BIGINT NOT NULL
+, resource_type_id INT NOT NULL
+, used BIGINT NOT NULL
+);
+CREATE INDEX allocation_items_ix_allocation_id_provider_id_resource_type_id
+  ON allocation_items (allocation_id, provider_id, resource_type_id);
+CREATE INDEX allocation_items_ix_resource_type_id_provider_id_allocation_id
+  ON allocation_items (resource_type_id, provider_id, allocation_id);
+`,
+				down: initialSchemaDown,
+			},
+		},
+	}
+)
@@ -1,12 +1,18 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
+	"time"
 
-	"github.com/go-sql-driver/mysql"
+	"github.com/golang/protobuf/proto"
 
+	"github.com/runmachine-io/runmachine/pkg/abstract"
+	"github.com/runmachine-io/runmachine/pkg/cursor"
 	"github.com/runmachine-io/runmachine/pkg/errors"
+	"github.com/runmachine-io/runmachine/pkg/metrics"
 	"github.com/runmachine-io/runmachine/pkg/util"
 	pb "github.com/runmachine-io/runmachine/proto"
 )
@@ -43,17 +49,21 @@ func InParamString(numArgs int) string {
 // providerExists returns true if a provider with the UUID exists, false
 // otherwise
 func (s *Store) providerExists(
+	ctx context.Context,
 	uuid string,
 ) (bool, error) {
-	_, err := s.ProviderGetByUuid(uuid)
+	_, err := s.ProviderGetByUuid(ctx, uuid)
 	return err == nil, nil
 }
 
 // ProviderGetByUuid returns a provider record matching the supplied UUID. If
 // no such record exists, returns ErrNotFound
 func (s *Store) ProviderGetByUuid(
+	ctx context.Context,
 	uuid string,
-) (*ProviderRecord, error) {
+) (rec *ProviderRecord, err error) {
+	ctx, op := s.startOperation(ctx, "provider_get")
+	defer op.end(&err)
 	qs := `SELECT
   p.id
 , part.uuid AS partition_uuid
@@ -65,14 +75,14 @@ JOIN provider_types AS pt
 JOIN partitions AS part
  ON p.partition_id = part.id
 WHERE p.uuid = ?`
-	rec := &ProviderRecord{
+	rec = &ProviderRecord{
 		Provider: &pb.Provider{
 			Uuid:         uuid,
 			Partition:    &pb.Partition{},
 			ProviderType: &pb.ProviderType{},
 		},
 	}
-	err := s.DB().QueryRow(qs, uuid).Scan(
+	err = s.DB().QueryRowContext(ctx, s.driver.Rebind(qs), uuid).Scan(
 		&rec.ID,
 		&rec.Provider.Partition.Uuid,
 		&rec.Provider.ProviderType.Code,
@@ -87,14 +97,26 @@ WHERE p.uuid = ?`
 	return rec, nil
 }
 
-// ProviderGetMatching returns provider records matching any of the supplied
-// filters.
+// ProvidersGetMatching returns a cursor over Provider messages, ordered by
+// UUID, matching any of the supplied filters. Rows are read from the database
+// as the cursor is iterated, and the caller must Close the cursor.
 func (s *Store) ProvidersGetMatching(
+	ctx context.Context,
 	any []*pb.ProviderFindFilter,
-) ([]*ProviderRecord, error) {
+) (cur abstract.Cursor, err error) {
+	ctx, op := s.startOperation(ctx, "provider_get_matching")
+	defer op.end(&err)
 	// TODO(jaypipes): Validate that the slice of supplied ProviderFilters is
 	// valid (for example, that the filter contains at least one UUID,
 	// partition, or provider type filter...
+	for _, filter := range any {
+		if filter.UuidFilter == nil && filter.PartitionFilter == nil &&
+			filter.ProviderTypeFilter == nil {
+			// A filter without any conditions matches every provider
+			any = nil
+			break
+		}
+	}
 	qargs := make([]interface{}, 0)
 	qs := `SELECT
   p.id
@@ -148,31 +170,35 @@ OR
 		}
 		qs += ")"
 	}
-	rows, err := s.DB().Query(qs, qargs...)
+	// Callers merge the returned providers with other streams ordered by
+	// UUID, like the objects from runm-metadata
+	qs += `
+ORDER BY p.uuid`
+	rows, err := s.DB().QueryContext(ctx, s.driver.Rebind(qs), qargs...)
 	if err != nil {
 		s.log.ERR("failed to get providers: %s.\nSQL: %s", err, qs)
 		return nil, err
 	}
-	recs := make([]*ProviderRecord, 0)
-	for rows.Next() {
-		rec := &ProviderRecord{
-			Provider: &pb.Provider{
-				Partition:    &pb.Partition{},
-				ProviderType: &pb.ProviderType{},
-			},
-		}
-		if err := rows.Scan(
-			&rec.ID,
-			&rec.Provider.Uuid,
-			&rec.Provider.Partition.Uuid,
-			&rec.Provider.ProviderType.Code,
-			&rec.Provider.Generation,
-		); err != nil {
-			panic(err.Error())
-		}
-		recs = append(recs, rec)
+	return cursor.NewFromSQLRows(rows, scanProvider), nil
+}
+
+// scanProvider reads a row of the ProvidersGetMatching query into the
+// supplied Provider message
+func scanProvider(rows *sql.Rows, msg proto.Message) error {
+	p, ok := msg.(*pb.Provider)
+	if !ok {
+		return fmt.Errorf("expected *pb.Provider but got %T", msg)
 	}
-	return recs, nil
+	var id int64
+	p.Partition = &pb.Partition{}
+	p.ProviderType = &pb.ProviderType{}
+	return rows.Scan(
+		&id,
+		&p.Uuid,
+		&p.Partition.Uuid,
+		&p.ProviderType.Code,
+		&p.Generation,
+	)
 }
 
 // ensurePartition creates a record in the partitions table for the supplied
@@ -180,28 +206,24 @@ OR
 // partition record's internal identifier. If a partition record already exists
 // for the UUID, the function just returns the internal identifier.
 func (s *Store) ensurePartition(
+	ctx context.Context,
 	uuid string,
 ) (int64, error) {
 	var id int64
 	db := s.DB()
 	qs := "SELECT id FROM partitions WHERE uuid = ?"
-	err := db.QueryRow(qs, uuid).Scan(&id)
+	err := db.QueryRowContext(ctx, s.driver.Rebind(qs), uuid).Scan(&id)
 	switch {
 	case err == sql.ErrNoRows:
 		// New record. Create it and return the newly-created internal ID
 		qs = "INSERT INTO partitions (uuid) VALUES (?)"
-		res, err := db.Exec(qs, uuid)
+		id, err := s.driver.Insert(ctx, db, s.driver.Rebind(qs), uuid)
 		if err != nil {
-			me, ok := err.(*mysql.MySQLError)
-			if !ok {
-				s.log.ERR("failed converting err to mysql.MYSQLError: %s", err)
-				return 0, err
-			}
-			if me.Number == 1062 {
+			if s.driver.IsDuplicate(err) {
 				// Another thread already inserted this partition, so just grab
 				// the partition's internal ID
 				qs := "SELECT id FROM partitions WHERE uuid = ?"
-				err := db.QueryRow(qs, uuid).Scan(&id)
+				err := db.QueryRowContext(ctx, s.driver.Rebind(qs), uuid).Scan(&id)
 				if err != nil {
 					s.log.ERR(
 						"failed getting partition internal ID: %s",
@@ -211,11 +233,11 @@ func (s *Store) ensurePartition(
 				}
 				return id, nil
 			}
-			s.log.ERR("failed getting partition internal ID: %s", me)
+			s.log.ERR("failed creating partition record: %s", err)
 			return 0, err
 		}
 		s.log.L2("created new partitions record for UUID %s", uuid)
-		return res.LastInsertId()
+		return id, nil
 	case err != nil:
 		return 0, err
 	}
@@ -228,28 +250,24 @@ func (s *Store) ensurePartition(
 // provider_type record already exists for the code, the function just returns
 // the internal identifier.
 func (s *Store) ensureProviderType(
+	ctx context.Context,
 	code string,
 ) (int64, error) {
 	var id int64
 	db := s.DB()
 	qs := "SELECT id FROM provider_types WHERE code = ?"
-	err := db.QueryRow(qs, code).Scan(&id)
+	err := db.QueryRowContext(ctx, s.driver.Rebind(qs), code).Scan(&id)
 	switch {
 	case err == sql.ErrNoRows:
 		// New record. Create it and return the newly-created internal ID
 		qs = "INSERT INTO provider_types (code) VALUES (?)"
-		res, err := db.Exec(qs, code)
+		id, err := s.driver.Insert(ctx, db, s.driver.Rebind(qs), code)
 		if err != nil {
-			me, ok := err.(*mysql.MySQLError)
-			if !ok {
-				s.log.ERR("failed converting err to mysql.MYSQLError: %s", err)
-				return 0, err
-			}
-			if me.Number == 1062 {
+			if s.driver.IsDuplicate(err) {
 				// Another thread already inserted this provider_type, so just grab
 				// the provider_type's internal ID
 				qs := "SELECT id FROM provider_types WHERE code = ?"
-				err := db.QueryRow(qs, code).Scan(&id)
+				err := db.QueryRowContext(ctx, s.driver.Rebind(qs), code).Scan(&id)
 				if err != nil {
 					s.log.ERR(
 						"failed getting provider_type internal ID: %s",
@@ -259,11 +277,11 @@ func (s *Store) ensureProviderType(
 				}
 				return id, nil
 			}
-			s.log.ERR("failed getting provider_type internal ID: %s", me)
+			s.log.ERR("failed creating provider_type record: %s", err)
 			return 0, err
 		}
 		s.log.L2("created new provider_types record for code %s", code)
-		return res.LastInsertId()
+		return id, nil
 	case err != nil:
 		return 0, err
 	}
@@ -273,9 +291,12 @@ func (s *Store) ensureProviderType(
 // ProviderCreate creates the provider record in backend storage and returns a
 // ProviderRecord describing the new provider
 func (s *Store) ProviderCreate(
+	ctx context.Context,
 	prov *pb.Provider,
-) (*ProviderRecord, error) {
-	exists, err := s.providerExists(prov.Uuid)
+) (rec *ProviderRecord, err error) {
+	ctx, op := s.startOperation(ctx, "provider_create")
+	defer op.end(&err)
+	exists, err := s.providerExists(ctx, prov.Uuid)
 	if err != nil {
 		s.log.ERR("failed looking up provider by UUID: %s", err)
 		return nil, errors.ErrUnknown
@@ -290,16 +311,16 @@ func (s *Store) ProviderCreate(
 
 	// Grab the internal IDs of the new provider's partition and provider type,
 	// ensuring that records exist for the partition and provider type.
-	partId, err := s.ensurePartition(prov.Partition.Uuid)
+	partId, err := s.ensurePartition(ctx, prov.Partition.Uuid)
 	if err != nil {
 		return nil, errors.ErrUnknown
 	}
-	ptId, err := s.ensureProviderType(prov.ProviderType.Code)
+	ptId, err := s.ensureProviderType(ctx, prov.ProviderType.Code)
 	if err != nil {
 		return nil, errors.ErrUnknown
 	}
 
-	tx, err := s.DB().Begin()
+	tx, err := s.DB().BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -313,13 +334,10 @@ INSERT INTO providers (
 , generation
 ) VALUES (?, ?, ?, ?)
 `
-	stmt, err := tx.Prepare(qs)
-	if err != nil {
-		return nil, err
-	}
-	defer stmt.Close()
-
-	res, err := stmt.Exec(
+	newId, err := s.driver.Insert(
+		ctx,
+		tx,
+		s.driver.Rebind(qs),
 		prov.Uuid,
 		ptId,
 		partId,
@@ -328,10 +346,6 @@ INSERT INTO providers (
 	if err != nil {
 		return nil, err
 	}
-	newId, err := res.LastInsertId()
-	if err != nil {
-		return nil, err
-	}
 	err = tx.Commit()
 	if err != nil {
 		return nil, err
@@ -347,30 +361,33 @@ INSERT INTO providers (
 // TODO(jaypipes): Pass a hashmap of UUID -> generation and limit deletions by
 // generation?
 func (s *Store) ProviderDeleteByUuid(
+	ctx context.Context,
 	uuids []string,
-) (uint64, error) {
+) (numDeleted uint64, err error) {
+	ctx, op := s.startOperation(ctx, "provider_delete")
+	defer op.end(&err)
 	qargs := make([]interface{}, len(uuids))
 	qs := `DELETE FROM providers WHERE uuid ` + InParamString(len(uuids))
 	for x, uuid := range uuids {
 		qargs[x] = uuid
 	}
-	tx, err := s.DB().Begin()
+	tx, err := s.DB().BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.Prepare(qs)
+	stmt, err := tx.PrepareContext(ctx, s.driver.Rebind(qs))
 	if err != nil {
 		return 0, err
 	}
 	defer stmt.Close()
 
-	res, err := stmt.Exec(qargs...)
+	res, err := stmt.ExecContext(ctx, qargs...)
 	if err != nil {
 		return 0, err
 	}
-	numDeleted, err := res.RowsAffected()
+	affected, err := res.RowsAffected()
 	if err != nil {
 		return 0, err
 	}
@@ -378,5 +395,36 @@ func (s *Store) ProviderDeleteByUuid(
 	if err != nil {
 		return 0, err
 	}
-	return uint64(numDeleted), nil
+	return uint64(affected), nil
+}
+
+// ProviderCountsByPartition returns the number of providers in each partition,
+// keyed by partition UUID
+func (s *Store) ProviderCountsByPartition() (counts map[string]int, err error) {
+	defer func(start time.Time) {
+		metrics.ObserveStorage(s.driver.Name(), "provider_count", start, err)
+	}(time.Now())
+	qs := `SELECT
+  part.uuid AS partition_uuid
+, COUNT(*) AS num_providers
+FROM providers AS p
+JOIN partitions AS part
+ ON p.partition_id = part.id
+GROUP BY part.uuid`
+	rows, err := s.DB().Query(qs)
+	if err != nil {
+		s.log.ERR("failed to count providers: %s.\nSQL: %s", err, qs)
+		return nil, err
+	}
+	defer rows.Close()
+	counts = make(map[string]int, 0)
+	for rows.Next() {
+		var partUuid string
+		var count int
+		if err := rows.Scan(&partUuid, &count); err != nil {
+			return nil, err
+		}
+		counts[partUuid] = count
+	}
+	return counts, rows.Err()
 }
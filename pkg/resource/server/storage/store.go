@@ -1,19 +1,18 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
-	"strings"
-
-	"github.com/go-sql-driver/mysql"
 
 	"github.com/runmachine-io/runmachine/pkg/logging"
 	"github.com/runmachine-io/runmachine/pkg/resource/server/config"
 )
 
 type Store struct {
-	log *logging.Logs
-	cfg *config.Config
-	db  *sql.DB
+	log    *logging.Logs
+	cfg    *config.Config
+	driver driver
+	db     *sql.DB
 }
 
 func (s *Store) Close() {
@@ -31,17 +30,7 @@ func (s *Store) getDB(forceNew bool, unsafe bool) (*sql.DB, error) {
 	if !unsafe && !forceNew && s.db != nil {
 		return s.db, nil
 	}
-	cfg, err := mysql.ParseDSN(
-		strings.TrimPrefix(s.cfg.StorageDSN, "mysql://"),
-	)
-	if err != nil {
-		return nil, err
-	}
-	if unsafe {
-		cfg.MultiStatements = true
-	}
-	dsn := cfg.FormatDSN()
-	db, err := sql.Open("mysql", dsn)
+	db, err := s.driver.Open(unsafe)
 	if err != nil {
 		// NOTE(jaypipes): sql.Open() doesn't actually connect to the DB or
 		// anything, so any error here is likely an OOM error and so fatal...
@@ -50,6 +39,11 @@ func (s *Store) getDB(forceNew bool, unsafe bool) (*sql.DB, error) {
 	return db, nil
 }
 
+// Ping returns nil if the database is reachable
+func (s *Store) Ping(ctx context.Context) error {
+	return s.DB().PingContext(ctx)
+}
+
 func (s *Store) DB() *sql.DB {
 	db, err := s.getDB(false, false)
 	if err != nil {
@@ -61,11 +55,17 @@ func (s *Store) DB() *sql.DB {
 
 func New(log *logging.Logs, cfg *config.Config) (*Store, error) {
 	s := &Store{
-		log: log,
-		cfg: cfg,
+		log:    log,
+		cfg:    cfg,
+		driver: newDriver(cfg.StorageDSN),
 	}
-	if err := s.migrate(); err != nil {
+	if err := s.migrate(context.Background()); err != nil {
+		return nil, err
+	}
+	db, err := s.getDB(true, false)
+	if err != nil {
 		return nil, err
 	}
+	s.db = db
 	return s, nil
 }
@@ -0,0 +1,118 @@
+package selector
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokAnd
+	tokOr
+	tokNot
+	tokIn
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	// The text of the token. For quoted strings, this is the unquoted string
+	text string
+	// Offset of the token in the selector string, used in error messages
+	pos int
+}
+
+// keywords are bare words that are operators. A value that is the same as a
+// keyword must be quoted.
+var keywords = map[string]tokenKind{
+	"and": tokAnd,
+	"or":  tokOr,
+	"not": tokNot,
+	"in":  tokIn,
+}
+
+// wordBreaks are the characters, other than whitespace, that end a bare word
+const wordBreaks = "()=!<>~,'\"&|"
+
+// lex splits the supplied selector string into tokens
+func lex(s string) ([]token, error) {
+	res := make([]token, 0)
+	x := 0
+	for x < len(s) {
+		c := s[x]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			x++
+		case c == '(':
+			res = append(res, token{kind: tokLParen, text: "(", pos: x})
+			x++
+		case c == ')':
+			res = append(res, token{kind: tokRParen, text: ")", pos: x})
+			x++
+		case c == ',':
+			res = append(res, token{kind: tokComma, text: ",", pos: x})
+			x++
+		case strings.HasPrefix(s[x:], "&&"):
+			res = append(res, token{kind: tokAnd, text: "&&", pos: x})
+			x += 2
+		case strings.HasPrefix(s[x:], "||"):
+			res = append(res, token{kind: tokOr, text: "||", pos: x})
+			x += 2
+		case c == '&' || c == '|':
+			return nil, fmt.Errorf(
+				"unexpected %q at position %d (did you mean %q?)",
+				c, x, strings.Repeat(string(c), 2),
+			)
+		case strings.HasPrefix(s[x:], "!="),
+			strings.HasPrefix(s[x:], "=="),
+			strings.HasPrefix(s[x:], ">="),
+			strings.HasPrefix(s[x:], "<="):
+			op := s[x : x+2]
+			if op == "==" {
+				op = "="
+			}
+			res = append(res, token{kind: tokOp, text: op, pos: x})
+			x += 2
+		case c == '=' || c == '>' || c == '<' || c == '~':
+			res = append(res, token{kind: tokOp, text: string(c), pos: x})
+			x++
+		case c == '!':
+			res = append(res, token{kind: tokNot, text: "!", pos: x})
+			x++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(s[x+1:], c)
+			if end == -1 {
+				return nil, fmt.Errorf(
+					"unterminated string starting at position %d", x,
+				)
+			}
+			res = append(res, token{
+				kind: tokString,
+				text: s[x+1 : x+1+end],
+				pos:  x,
+			})
+			x += end + 2
+		default:
+			start := x
+			for x < len(s) && !strings.ContainsRune(" \t\n\r", rune(s[x])) &&
+				!strings.ContainsRune(wordBreaks, rune(s[x])) {
+				x++
+			}
+			word := s[start:x]
+			if kind, found := keywords[strings.ToLower(word)]; found {
+				res = append(res, token{kind: kind, text: word, pos: start})
+				continue
+			}
+			res = append(res, token{kind: tokWord, text: word, pos: start})
+		}
+	}
+	res = append(res, token{kind: tokEOF, pos: len(s)})
+	return res, nil
+}
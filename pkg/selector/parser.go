@@ -0,0 +1,285 @@
+package selector
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The fields of a provider or object that may be used in a predicate. Any
+// other field is a property key.
+const (
+	fieldPartition = "partition"
+	fieldType      = "type"
+	fieldUuid      = "uuid"
+	fieldName      = "name"
+	fieldTag       = "tag"
+)
+
+// propertyFieldPrefix may be prepended to a field to indicate the field is a
+// property key, even if it is the same as one of the fields above
+const propertyFieldPrefix = "prop:"
+
+// The operators of a predicate, in addition to the comparison operators
+const (
+	opExists = "exists"
+	opIn     = "in"
+	opNotIn  = "not in"
+)
+
+// expr is a node in the syntax tree of a selector. It is one of andExpr,
+// orExpr, notExpr or *predicate.
+type expr interface{}
+
+// andExpr matches when all of its children match
+type andExpr []expr
+
+// orExpr matches when any of its children match
+type orExpr []expr
+
+// notExpr matches when its child does not match
+type notExpr struct {
+	child expr
+}
+
+// predicate compares a single field of a provider or object
+type predicate struct {
+	field string
+	// true when field is a property key
+	property bool
+	op       string
+	values   []string
+	// true when the value ended in an unquoted asterisk, which was removed
+	prefix bool
+}
+
+func (p *predicate) String() string {
+	field := p.field
+	if p.property {
+		field = propertyFieldPrefix + field
+	}
+	switch p.op {
+	case opExists:
+		return field
+	case opIn, opNotIn:
+		return field + " " + p.op + " (" + strings.Join(p.values, ",") + ")"
+	}
+	value := p.values[0]
+	if p.prefix {
+		value += "*"
+	}
+	return field + p.op + value
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parse returns the syntax tree of the supplied selector string
+func parse(s string) (expr, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	if tokens[0].kind == tokEOF {
+		return nil, fmt.Errorf("selector is empty")
+	}
+	p := &parser{tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.unexpected(tok)
+	}
+	return e, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) unexpected(tok token) error {
+	if tok.kind == tokEOF {
+		return fmt.Errorf("unexpected end of selector")
+	}
+	return fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
+
+// parseOr parses one or more AND expressions separated by OR
+func (p *parser) parseOr() (expr, error) {
+	e, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	res := orExpr{e}
+	for p.peek().kind == tokOr {
+		p.next()
+		e, err = p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+	if len(res) == 1 {
+		return res[0], nil
+	}
+	return res, nil
+}
+
+// parseAnd parses one or more unary expressions separated by AND. Unary
+// expressions separated only by whitespace are also AND'd together, so that
+// selectors may be written the same way as the --filter CLI option.
+func (p *parser) parseAnd() (expr, error) {
+	e, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	res := andExpr{e}
+	for {
+		switch p.peek().kind {
+		case tokAnd:
+			p.next()
+		case tokWord, tokNot, tokLParen:
+		default:
+			if len(res) == 1 {
+				return res[0], nil
+			}
+			return res, nil
+		}
+		e, err = p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+}
+
+// parseUnary parses a NOT expression, a parenthesized expression or a
+// predicate
+func (p *parser) parseUnary() (expr, error) {
+	tok := p.peek()
+	switch tok.kind {
+	case tokNot:
+		p.next()
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{child: e}, nil
+	case tokLParen:
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok = p.next(); tok.kind != tokRParen {
+			return nil, p.unexpected(tok)
+		}
+		return e, nil
+	case tokWord:
+		return p.parsePredicate()
+	}
+	return nil, p.unexpected(tok)
+}
+
+// parsePredicate parses a field followed by an optional operator and value or
+// list of values
+func (p *parser) parsePredicate() (expr, error) {
+	tok := p.next()
+	pred := &predicate{field: tok.text}
+	switch tok.text {
+	case fieldPartition, fieldType, fieldUuid, fieldName, fieldTag:
+	default:
+		pred.property = true
+		pred.field = strings.TrimPrefix(tok.text, propertyFieldPrefix)
+		if pred.field == "" {
+			return nil, fmt.Errorf(
+				"missing property key at position %d", tok.pos,
+			)
+		}
+	}
+
+	switch p.peek().kind {
+	case tokOp:
+		pred.op = p.next().text
+		tok = p.next()
+		if tok.kind != tokWord && tok.kind != tokString {
+			return nil, p.unexpected(tok)
+		}
+		value := tok.text
+		if tok.kind == tokWord && (pred.op == "=" || pred.op == "!=") &&
+			strings.HasSuffix(value, "*") {
+			pred.prefix = true
+			value = strings.TrimRight(value, "*")
+		}
+		pred.values = []string{value}
+	case tokIn:
+		p.next()
+		pred.op = opIn
+	case tokNot:
+		if p.tokens[p.pos+1].kind != tokIn {
+			// A NOT following a property key begins the next expression
+			// AND'd with a property key existence predicate
+			return p.existsPredicate(pred, tok)
+		}
+		p.next()
+		p.next()
+		pred.op = opNotIn
+	default:
+		return p.existsPredicate(pred, tok)
+	}
+
+	if pred.op == opIn || pred.op == opNotIn {
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		pred.values = values
+	}
+	return pred, nil
+}
+
+// existsPredicate returns the supplied predicate, which was parsed from the
+// supplied field token, as a predicate on a property key existing
+func (p *parser) existsPredicate(pred *predicate, tok token) (expr, error) {
+	if !pred.property {
+		return nil, fmt.Errorf(
+			"%s at position %d requires an operator and value",
+			pred.field, tok.pos,
+		)
+	}
+	pred.op = opExists
+	return pred, nil
+}
+
+// parseList parses a parenthesized, comma-separated list of values
+func (p *parser) parseList() ([]string, error) {
+	if tok := p.next(); tok.kind != tokLParen {
+		return nil, p.unexpected(tok)
+	}
+	res := make([]string, 0)
+	for {
+		tok := p.next()
+		if tok.kind != tokWord && tok.kind != tokString {
+			return nil, p.unexpected(tok)
+		}
+		res = append(res, tok.text)
+		tok = p.next()
+		switch tok.kind {
+		case tokComma:
+			continue
+		case tokRParen:
+			return res, nil
+		}
+		return nil, p.unexpected(tok)
+	}
+}
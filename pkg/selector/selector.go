@@ -0,0 +1,441 @@
+// Package selector parses the selector language used to find providers and
+// objects and compiles selectors into the ProviderFilter and ObjectFilter
+// protobuffer messages understood by runm-api and runm-metadata.
+//
+// A selector is made up of predicates combined with AND (or &&), OR (or ||)
+// and NOT (or !), with parentheses for grouping. Predicates separated only by
+// whitespace are AND'd together. For example:
+//
+//	type=runm.compute and (partition=part0 or partition=part1)
+//	tag in (gpu,fpga) and not tag=maintenance
+//	prop:location.row>=3 prop:os~^linux
+//
+// The field of a predicate is one of partition, type, uuid, name or tag, or
+// a property key. Property keys that are the same as one of those fields must
+// be prefixed with "prop:". The operators are =, != and in (...) for all
+// fields, "not in (...)" for tags and properties, and >, >=, <, <=, ~ (regular
+// expression match) for properties. A property key on its own matches objects
+// having the property. An unquoted value ending in an asterisk matches by
+// prefix. Values containing whitespace or any of the characters ()=!<>~,&|
+// must be quoted with single or double quotes.
+package selector
+
+import (
+	"fmt"
+	"regexp"
+
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+// Maximum number of filters a selector may be compiled into. Selectors are
+// compiled into a set of filters that are OR'd together, and each OR nested
+// in an AND multiplies the number of filters.
+const _MAX_CLAUSES = 64
+
+// Match is a predicate on the value of a partition, type, UUID or name
+type Match struct {
+	Value     string
+	UsePrefix bool
+}
+
+// Clause is a set of predicates that must all be true for a provider or
+// object to match. A compiled selector is a set of clauses, any of which may
+// match.
+type Clause struct {
+	Partition  *Match
+	Type       *Match
+	Uuid       *Match
+	Name       *Match
+	Properties *pb.PropertyFilter
+	Tags       *pb.TagFilter
+}
+
+// Compile parses the supplied selector string and returns the set of clauses
+// it is equivalent to
+func Compile(s string) ([]*Clause, error) {
+	e, err := parse(s)
+	if err != nil {
+		return nil, err
+	}
+	conjs, err := disjunctiveNormalForm(e, false)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*Clause, len(conjs))
+	for x, conj := range conjs {
+		c := &Clause{}
+		for _, lit := range conj {
+			if err = c.add(lit); err != nil {
+				return nil, err
+			}
+		}
+		res[x] = c
+	}
+	return res, nil
+}
+
+// ProviderFilters compiles the supplied selector string into a set of
+// ProviderFilter messages, any of which may match
+func ProviderFilters(s string) ([]*pb.ProviderFilter, error) {
+	clauses, err := Compile(s)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*pb.ProviderFilter, len(clauses))
+	for x, c := range clauses {
+		if res[x], err = c.ProviderFilter(); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// ObjectFilters compiles the supplied selector string into a set of
+// ObjectFilter messages, any of which may match. The partition in a selector
+// must be a partition UUID.
+func ObjectFilters(s string) ([]*pb.ObjectFilter, error) {
+	clauses, err := Compile(s)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*pb.ObjectFilter, len(clauses))
+	for x, c := range clauses {
+		if res[x], err = c.ObjectFilter(); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// ProviderFilter returns the ProviderFilter message equivalent to the clause
+func (c *Clause) ProviderFilter() (*pb.ProviderFilter, error) {
+	if c.Uuid != nil && c.Name != nil {
+		return nil, fmt.Errorf(
+			"providers may be selected by uuid or name but not both",
+		)
+	}
+	f := &pb.ProviderFilter{
+		PartitionFilter:    c.Partition.searchFilter(),
+		ProviderTypeFilter: c.Type.searchFilter(),
+		PrimaryFilter:      c.Uuid.searchFilter(),
+		PropertyFilter:     c.Properties,
+		TagFilter:          c.Tags,
+	}
+	if c.Name != nil {
+		f.PrimaryFilter = c.Name.searchFilter()
+	}
+	return f, nil
+}
+
+// ObjectFilter returns the ObjectFilter message equivalent to the clause
+func (c *Clause) ObjectFilter() (*pb.ObjectFilter, error) {
+	f := &pb.ObjectFilter{
+		PropertyFilter: c.Properties,
+		TagFilter:      c.Tags,
+	}
+	if c.Partition != nil {
+		if c.Partition.UsePrefix {
+			return nil, fmt.Errorf(
+				"objects may not be selected by partition prefix",
+			)
+		}
+		f.PartitionFilter = &pb.UuidsFilter{
+			Uuids: []string{c.Partition.Value},
+		}
+	}
+	if c.Type != nil {
+		f.ObjectTypeFilter = &pb.ObjectTypeFilter{
+			CodeFilter: &pb.CodeFilter{
+				Code:      c.Type.Value,
+				UsePrefix: c.Type.UsePrefix,
+			},
+		}
+	}
+	if c.Uuid != nil {
+		f.UuidFilter = &pb.UuidFilter{
+			Uuid:      c.Uuid.Value,
+			UsePrefix: c.Uuid.UsePrefix,
+		}
+	}
+	if c.Name != nil {
+		f.NameFilter = &pb.NameFilter{
+			Name:      c.Name.Value,
+			UsePrefix: c.Name.UsePrefix,
+		}
+	}
+	return f, nil
+}
+
+func (m *Match) searchFilter() *pb.SearchFilter {
+	if m == nil {
+		return nil
+	}
+	return &pb.SearchFilter{Search: m.Value, UsePrefix: m.UsePrefix}
+}
+
+// literal is a predicate that may be negated
+type literal struct {
+	pred    *predicate
+	negated bool
+}
+
+// disjunctiveNormalForm returns the supplied expression, or its negation if
+// negate is true, as a set of AND'd literals that are OR'd together.
+// Predicates on partitions, types, UUIDs, names and tags with an "in"
+// operator are expanded into an OR of equality predicates.
+func disjunctiveNormalForm(e expr, negate bool) ([][]literal, error) {
+	switch e := e.(type) {
+	case notExpr:
+		return disjunctiveNormalForm(e.child, !negate)
+	case andExpr:
+		if negate {
+			// NOT (a AND b) == (NOT a) OR (NOT b)
+			return disjunctiveNormalFormOr([]expr(e), true)
+		}
+		return disjunctiveNormalFormAnd([]expr(e), false)
+	case orExpr:
+		if negate {
+			// NOT (a OR b) == (NOT a) AND (NOT b)
+			return disjunctiveNormalFormAnd([]expr(e), true)
+		}
+		return disjunctiveNormalFormOr([]expr(e), false)
+	case *predicate:
+		op, neg := e.op, negate
+		if op == opNotIn {
+			op, neg = opIn, !neg
+		}
+		if !e.property && op == opIn && !neg {
+			res := make([][]literal, len(e.values))
+			for x, value := range e.values {
+				res[x] = []literal{{pred: &predicate{
+					field:  e.field,
+					op:     "=",
+					values: []string{value},
+				}}}
+			}
+			return res, nil
+		}
+		return [][]literal{{{pred: e, negated: negate}}}, nil
+	}
+	return nil, fmt.Errorf("unknown selector expression %v", e)
+}
+
+// disjunctiveNormalFormOr returns the OR of the supplied expressions, each
+// negated if negate is true, in disjunctive normal form
+func disjunctiveNormalFormOr(es []expr, negate bool) ([][]literal, error) {
+	res := make([][]literal, 0)
+	for _, e := range es {
+		conjs, err := disjunctiveNormalForm(e, negate)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, conjs...)
+		if len(res) > _MAX_CLAUSES {
+			return nil, errTooManyClauses
+		}
+	}
+	return res, nil
+}
+
+// disjunctiveNormalFormAnd returns the AND of the supplied expressions, each
+// negated if negate is true, in disjunctive normal form
+func disjunctiveNormalFormAnd(es []expr, negate bool) ([][]literal, error) {
+	res := [][]literal{{}}
+	for _, e := range es {
+		conjs, err := disjunctiveNormalForm(e, negate)
+		if err != nil {
+			return nil, err
+		}
+		if len(res)*len(conjs) > _MAX_CLAUSES {
+			return nil, errTooManyClauses
+		}
+		product := make([][]literal, 0, len(res)*len(conjs))
+		for _, left := range res {
+			for _, right := range conjs {
+				conj := make([]literal, 0, len(left)+len(right))
+				conj = append(conj, left...)
+				conj = append(conj, right...)
+				product = append(product, conj)
+			}
+		}
+		res = product
+	}
+	return res, nil
+}
+
+var errTooManyClauses = fmt.Errorf(
+	"selector is too complex: it expands into more than %d filters",
+	_MAX_CLAUSES,
+)
+
+// negatedComparisons maps each comparison operator to the operator that
+// matches when it does not
+var negatedComparisons = map[string]string{
+	">":  "<=",
+	">=": "<",
+	"<":  ">=",
+	"<=": ">",
+}
+
+// propertyOperators maps the comparison operators of property predicates to
+// the operators of PropertyExpression messages
+var propertyOperators = map[string]pb.PropertyOperator{
+	">":  pb.PropertyOperator_PROPERTY_GREATER_THAN,
+	">=": pb.PropertyOperator_PROPERTY_GREATER_THAN_EQUAL,
+	"<":  pb.PropertyOperator_PROPERTY_LESS_THAN,
+	"<=": pb.PropertyOperator_PROPERTY_LESS_THAN_EQUAL,
+	"~":  pb.PropertyOperator_PROPERTY_REGEX,
+}
+
+// add adds the supplied literal to the clause's predicates
+func (c *Clause) add(lit literal) error {
+	pred := lit.pred
+	negated := lit.negated
+	op := pred.op
+	// Normalize != and "not in" into negated = and in predicates
+	switch op {
+	case "!=":
+		op = "="
+		negated = !negated
+	case opNotIn:
+		op = opIn
+		negated = !negated
+	}
+	if pred.property {
+		return c.addProperty(pred, op, negated)
+	}
+	if pred.field == fieldTag {
+		return c.addTag(pred, op, negated)
+	}
+
+	if negated || op != "=" {
+		return fmt.Errorf(
+			"%s only supports = and in (...) predicates: %s",
+			pred.field, pred,
+		)
+	}
+	m := &Match{Value: pred.values[0], UsePrefix: pred.prefix}
+	var dest **Match
+	switch pred.field {
+	case fieldPartition:
+		dest = &c.Partition
+	case fieldType:
+		dest = &c.Type
+	case fieldUuid:
+		dest = &c.Uuid
+	case fieldName:
+		dest = &c.Name
+	}
+	if *dest != nil && **dest != *m {
+		return fmt.Errorf(
+			"%s may only be compared to one value in each AND'd set of "+
+				"predicates: %s",
+			pred.field, pred,
+		)
+	}
+	*dest = m
+	return nil
+}
+
+// addTag adds a predicate on tags to the clause's predicates
+func (c *Clause) addTag(pred *predicate, op string, negated bool) error {
+	if pred.prefix {
+		return fmt.Errorf("tags may not be matched by prefix: %s", pred)
+	}
+	if c.Tags == nil {
+		c.Tags = &pb.TagFilter{}
+	}
+	switch {
+	case op == "=" && !negated:
+		c.Tags.RequireTags = append(c.Tags.RequireTags, pred.values[0])
+	case op == "=" || (op == opIn && negated):
+		c.Tags.ForbidTags = append(c.Tags.ForbidTags, pred.values...)
+	default:
+		return fmt.Errorf(
+			"tag only supports =, != and in (...) predicates: %s", pred,
+		)
+	}
+	return nil
+}
+
+// addProperty adds a predicate on properties to the clause's predicates
+func (c *Clause) addProperty(pred *predicate, op string, negated bool) error {
+	if c.Properties == nil {
+		c.Properties = &pb.PropertyFilter{}
+	}
+	pf := c.Properties
+	key := pred.field
+	switch op {
+	case opExists:
+		if negated {
+			pf.ForbidKeys = append(pf.ForbidKeys, key)
+		} else {
+			pf.RequireKeys = append(pf.RequireKeys, key)
+		}
+		return nil
+	case "=":
+		if pred.prefix {
+			if negated {
+				return fmt.Errorf(
+					"prefix matches on properties may not be negated: %s",
+					pred,
+				)
+			}
+			c.addPropertyExpression(
+				key, pb.PropertyOperator_PROPERTY_PREFIX, pred.values,
+			)
+			return nil
+		}
+		prop := &pb.Property{Key: key, Value: pred.values[0]}
+		if negated {
+			pf.ForbidItems = append(pf.ForbidItems, prop)
+		} else {
+			pf.RequireItems = append(pf.RequireItems, prop)
+		}
+		return nil
+	case opIn:
+		if negated {
+			for _, value := range pred.values {
+				pf.ForbidItems = append(
+					pf.ForbidItems, &pb.Property{Key: key, Value: value},
+				)
+			}
+			return nil
+		}
+		c.addPropertyExpression(
+			key, pb.PropertyOperator_PROPERTY_IN, pred.values,
+		)
+		return nil
+	case "~":
+		if negated {
+			return fmt.Errorf(
+				"regular expression matches on properties may not be "+
+					"negated: %s",
+				pred,
+			)
+		}
+		if _, err := regexp.Compile(pred.values[0]); err != nil {
+			return fmt.Errorf(
+				"invalid regular expression in %s: %s", pred, err,
+			)
+		}
+	}
+	if negated {
+		// NOTE(jaypipes): NOT (row > 3) is compiled to row <= 3, so objects
+		// without the property do not match either
+		op = negatedComparisons[op]
+	}
+	c.addPropertyExpression(key, propertyOperators[op], pred.values)
+	return nil
+}
+
+func (c *Clause) addPropertyExpression(
+	key string,
+	op pb.PropertyOperator,
+	values []string,
+) {
+	c.Properties.RequireExpressions = append(
+		c.Properties.RequireExpressions,
+		&pb.PropertyExpression{Key: key, Operator: op, Values: values},
+	)
+}
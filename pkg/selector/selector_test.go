@@ -0,0 +1,182 @@
+package selector_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/runmachine-io/runmachine/pkg/selector"
+	pb "github.com/runmachine-io/runmachine/proto"
+)
+
+func TestProviderFilters(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		selector string
+		expect   []*pb.ProviderFilter
+	}{
+		{
+			selector: "type=runm.compute name=db*",
+			expect: []*pb.ProviderFilter{
+				{
+					ProviderTypeFilter: &pb.SearchFilter{Search: "runm.compute"},
+					PrimaryFilter: &pb.SearchFilter{
+						Search:    "db",
+						UsePrefix: true,
+					},
+				},
+			},
+		},
+		{
+			selector: "type=runm.compute and (partition=part0 || partition=part1)",
+			expect: []*pb.ProviderFilter{
+				{
+					PartitionFilter:    &pb.SearchFilter{Search: "part0"},
+					ProviderTypeFilter: &pb.SearchFilter{Search: "runm.compute"},
+				},
+				{
+					PartitionFilter:    &pb.SearchFilter{Search: "part1"},
+					ProviderTypeFilter: &pb.SearchFilter{Search: "runm.compute"},
+				},
+			},
+		},
+		{
+			selector: "partition in (part0, 'part 1')",
+			expect: []*pb.ProviderFilter{
+				{PartitionFilter: &pb.SearchFilter{Search: "part0"}},
+				{PartitionFilter: &pb.SearchFilter{Search: "part 1"}},
+			},
+		},
+		{
+			selector: "tag in (gpu,fpga) AND NOT tag=maintenance",
+			expect: []*pb.ProviderFilter{
+				{
+					TagFilter: &pb.TagFilter{
+						RequireTags: []string{"gpu"},
+						ForbidTags:  []string{"maintenance"},
+					},
+				},
+				{
+					TagFilter: &pb.TagFilter{
+						RequireTags: []string{"fpga"},
+						ForbidTags:  []string{"maintenance"},
+					},
+				},
+			},
+		},
+		{
+			// De Morgan: NOT (a OR b) == NOT a AND NOT b
+			selector: "!(tag=gpu || prop:cpu.model) tag not in (a,b)",
+			expect: []*pb.ProviderFilter{
+				{
+					TagFilter: &pb.TagFilter{
+						ForbidTags: []string{"gpu", "a", "b"},
+					},
+					PropertyFilter: &pb.PropertyFilter{
+						ForbidKeys: []string{"cpu.model"},
+					},
+				},
+			},
+		},
+		{
+			selector: `prop:location.row>=3 location.site in (dc1,dc2) ` +
+				`prop:os~^linux arch!=ppc64le not (prop:cores>8) ` +
+				`prop:type="a b" hw.vendor=Int*`,
+			expect: []*pb.ProviderFilter{
+				{
+					PropertyFilter: &pb.PropertyFilter{
+						RequireItems: []*pb.Property{
+							{Key: "type", Value: "a b"},
+						},
+						ForbidItems: []*pb.Property{
+							{Key: "arch", Value: "ppc64le"},
+						},
+						RequireExpressions: []*pb.PropertyExpression{
+							{
+								Key:      "location.row",
+								Operator: pb.PropertyOperator_PROPERTY_GREATER_THAN_EQUAL,
+								Values:   []string{"3"},
+							},
+							{
+								Key:      "location.site",
+								Operator: pb.PropertyOperator_PROPERTY_IN,
+								Values:   []string{"dc1", "dc2"},
+							},
+							{
+								Key:      "os",
+								Operator: pb.PropertyOperator_PROPERTY_REGEX,
+								Values:   []string{"^linux"},
+							},
+							{
+								Key:      "cores",
+								Operator: pb.PropertyOperator_PROPERTY_LESS_THAN_EQUAL,
+								Values:   []string{"8"},
+							},
+							{
+								Key:      "hw.vendor",
+								Operator: pb.PropertyOperator_PROPERTY_PREFIX,
+								Values:   []string{"Int"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		filters, err := selector.ProviderFilters(test.selector)
+		assert.Nil(err, test.selector)
+		assert.Equal(test.expect, filters, test.selector)
+	}
+}
+
+func TestObjectFilters(t *testing.T) {
+	assert := assert.New(t)
+
+	filters, err := selector.ObjectFilters(
+		"type=runm.image partition=1f0d2ab9 name=ubuntu* tag=lts",
+	)
+	assert.Nil(err)
+	assert.Equal([]*pb.ObjectFilter{
+		{
+			PartitionFilter: &pb.UuidsFilter{Uuids: []string{"1f0d2ab9"}},
+			ObjectTypeFilter: &pb.ObjectTypeFilter{
+				CodeFilter: &pb.CodeFilter{Code: "runm.image"},
+			},
+			NameFilter: &pb.NameFilter{Name: "ubuntu", UsePrefix: true},
+			TagFilter:  &pb.TagFilter{RequireTags: []string{"lts"}},
+		},
+	}, filters)
+
+	_, err = selector.ObjectFilters("partition=part*")
+	assert.NotNil(err)
+}
+
+func TestCompileErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []string{
+		"",
+		"type",
+		"type=",
+		"partition!=part0",
+		"type=a type=b",
+		"tag>3",
+		"not prop:os~^linux",
+		"prop:os~(",
+		"prop:row>=3 &",
+		"(tag=gpu",
+		"tag in gpu",
+		"tag in (gpu,)",
+		"name='unterminated",
+		"uuid=f287 name=db",
+		"(a=1 or a=2) and (b=1 or b=2) and (c=1 or c=2) and " +
+			"(d=1 or d=2) and (e=1 or e=2) and (f=1 or f=2) and " +
+			"(g=1 or g=2)",
+	}
+	for _, test := range tests {
+		_, err := selector.ProviderFilters(test)
+		assert.NotNil(err, test)
+	}
+}
@@ -0,0 +1,94 @@
+package svcclient
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/resolver"
+)
+
+const leastLoadedName = "runm_least_loaded"
+
+func init() {
+	balancer.Register(
+		base.NewBalancerBuilder(leastLoadedName, newLeastLoadedPickerBuilder()),
+	)
+}
+
+// leastLoadedPickerBuilder builds pickers that send each RPC to the ready
+// endpoint with the fewest in-flight RPCs. The in-flight counters outlive any
+// single picker since gRPC rebuilds the picker whenever the set of ready
+// endpoints changes.
+type leastLoadedPickerBuilder struct {
+	sync.Mutex
+	inflight map[balancer.SubConn]*int64
+}
+
+func newLeastLoadedPickerBuilder() *leastLoadedPickerBuilder {
+	return &leastLoadedPickerBuilder{
+		inflight: make(map[balancer.SubConn]*int64, 0),
+	}
+}
+
+func (b *leastLoadedPickerBuilder) Build(
+	readySCs map[resolver.Address]balancer.SubConn,
+) balancer.Picker {
+	b.Lock()
+	defer b.Unlock()
+	p := &leastLoadedPicker{
+		subConns: make([]balancer.SubConn, 0, len(readySCs)),
+		counts:   make([]*int64, 0, len(readySCs)),
+	}
+	// NOTE(jaypipes): SubConns that are no longer ready are dropped from the
+	// counter map. Any RPCs still in flight on them hold a reference to their
+	// counter, so decrementing it on completion is harmless.
+	inflight := make(map[balancer.SubConn]*int64, len(readySCs))
+	for _, sc := range readySCs {
+		count, exists := b.inflight[sc]
+		if !exists {
+			count = new(int64)
+		}
+		inflight[sc] = count
+		p.subConns = append(p.subConns, sc)
+		p.counts = append(p.counts, count)
+	}
+	b.inflight = inflight
+	return p
+}
+
+type leastLoadedPicker struct {
+	subConns []balancer.SubConn
+	counts   []*int64
+	// Where to start scanning for the least-loaded SubConn, so that ties are
+	// broken round-robin
+	next uint32
+}
+
+func (p *leastLoadedPicker) Pick(
+	ctx context.Context,
+	opts balancer.PickOptions,
+) (balancer.SubConn, func(balancer.DoneInfo), error) {
+	n := len(p.subConns)
+	if n == 0 {
+		return nil, nil, balancer.ErrNoSubConnAvailable
+	}
+	start := int(atomic.AddUint32(&p.next, 1) % uint32(n))
+	best := start
+	bestCount := atomic.LoadInt64(p.counts[start])
+	for x := 1; x < n; x++ {
+		idx := (start + x) % n
+		if c := atomic.LoadInt64(p.counts[idx]); c < bestCount {
+			best = idx
+			bestCount = c
+		}
+	}
+	count := p.counts[best]
+	atomic.AddInt64(count, 1)
+	done := func(balancer.DoneInfo) {
+		atomic.AddInt64(count, -1)
+	}
+	return p.subConns[best], done, nil
+}
@@ -0,0 +1,68 @@
+package svcclient
+
+import (
+	"sync"
+	"time"
+)
+
+// ejector tracks consecutive failures of RPCs sent to each endpoint address
+// and ejects an endpoint for a period of time after too many failures
+type ejector struct {
+	sync.Mutex
+	maxFailures int
+	duration    time.Duration
+	failures    map[string]int
+	// Map, keyed by address, of the time the endpoint's ejection ends
+	ejected map[string]time.Time
+	// Allows tests to control the clock
+	now func() time.Time
+}
+
+func newEjector(maxFailures int, duration time.Duration) *ejector {
+	return &ejector{
+		maxFailures: maxFailures,
+		duration:    duration,
+		failures:    make(map[string]int, 0),
+		ejected:     make(map[string]time.Time, 0),
+		now:         time.Now,
+	}
+}
+
+// success resets the consecutive failure count for the address
+func (e *ejector) success(addr string) {
+	e.Lock()
+	defer e.Unlock()
+	delete(e.failures, addr)
+}
+
+// failure increments the consecutive failure count for the address and
+// returns true if the address was newly ejected
+func (e *ejector) failure(addr string) bool {
+	if e.maxFailures <= 0 {
+		return false
+	}
+	e.Lock()
+	defer e.Unlock()
+	e.failures[addr] += 1
+	if e.failures[addr] < e.maxFailures {
+		return false
+	}
+	delete(e.failures, addr)
+	e.ejected[addr] = e.now().Add(e.duration)
+	return true
+}
+
+// isEjected returns true if the address is currently ejected
+func (e *ejector) isEjected(addr string) bool {
+	e.Lock()
+	defer e.Unlock()
+	until, exists := e.ejected[addr]
+	if !exists {
+		return false
+	}
+	if e.now().After(until) {
+		delete(e.ejected, addr)
+		return false
+	}
+	return true
+}
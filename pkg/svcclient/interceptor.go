@@ -0,0 +1,266 @@
+package svcclient
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/status"
+)
+
+// readVerbs are the words in an RPC method name that indicate the RPC only
+// reads data and is therefore safe to retry
+var readVerbs = []string{"get", "find", "list"}
+
+// IsReadMethod returns true if the supplied full gRPC method name (e.g.
+// "/runm.RunmMetadata/object_get_by_uuid") names an RPC that only reads
+// data. runmachine RPC names are snake_case with the verb following the noun.
+func IsReadMethod(method string) bool {
+	name := method[strings.LastIndex(method, "/")+1:]
+	for _, word := range strings.Split(name, "_") {
+		for _, verb := range readVerbs {
+			if word == verb {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isUnavailable returns true if the error indicates the endpoint could not
+// service the RPC, as opposed to the endpoint returning an application error
+func isUnavailable(err error) bool {
+	if st, ok := status.FromError(err); ok {
+		return st.Code() == codes.Unavailable
+	}
+	return false
+}
+
+// errNoEndpoints is returned for RPCs made while gsr has no endpoints
+// registered for the service. Without this, gRPC would block the RPC until an
+// endpoint appears.
+func (c *Client) errNoEndpoints() error {
+	return status.Errorf(
+		codes.Unavailable,
+		"no endpoints registered for %s service", c.service,
+	)
+}
+
+// record updates the ejection state of the endpoint that serviced an RPC
+func (c *Client) record(p *peer.Peer, err error) {
+	if p == nil || p.Addr == nil {
+		return
+	}
+	addr := p.Addr.String()
+	if err == nil || !isUnavailable(err) {
+		c.ejector.success(addr)
+		return
+	}
+	if c.ejector.failure(addr) {
+		c.log.ERR(
+			"ejecting %s service endpoint at %s for %s after %d "+
+				"consecutive failures",
+			c.service, addr, c.opts.EjectDuration, c.opts.MaxFailures,
+		)
+		c.resolver.ResolveNow(resolver.ResolveNowOption{})
+	}
+}
+
+// withPeer returns a copy of the supplied call options with an option that
+// records the address of the endpoint servicing the RPC into p
+func withPeer(opts []grpc.CallOption, p *peer.Peer) []grpc.CallOption {
+	res := make([]grpc.CallOption, len(opts), len(opts)+1)
+	copy(res, opts)
+	return append(res, grpc.Peer(p))
+}
+
+// backoff sleeps before the supplied retry attempt, returning false if the
+// context was cancelled while waiting
+func (c *Client) backoff(ctx context.Context, attempt int) bool {
+	wait := c.opts.RetryBackoff << uint(attempt)
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(wait):
+		return true
+	}
+}
+
+func (c *Client) unaryInterceptor(
+	ctx context.Context,
+	method string,
+	req interface{},
+	reply interface{},
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	if c.resolver.numAddrs() == 0 {
+		return c.errNoEndpoints()
+	}
+	retries := 0
+	if IsReadMethod(method) {
+		retries = c.opts.MaxRetries
+	}
+	var err error
+	for attempt := 0; ; attempt++ {
+		p := &peer.Peer{}
+		err = invoker(ctx, method, req, reply, cc, withPeer(opts, p)...)
+		c.record(p, err)
+		if err == nil || !isUnavailable(err) || attempt >= retries {
+			return err
+		}
+		c.log.L3(
+			"retrying %s on %s service after error: %s",
+			method, c.service, err,
+		)
+		if !c.backoff(ctx, attempt) {
+			return err
+		}
+	}
+}
+
+func (c *Client) streamInterceptor(
+	ctx context.Context,
+	desc *grpc.StreamDesc,
+	cc *grpc.ClientConn,
+	method string,
+	streamer grpc.Streamer,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	if c.resolver.numAddrs() == 0 {
+		return nil, c.errNoEndpoints()
+	}
+	rs := &retryStream{
+		client:   c,
+		ctx:      ctx,
+		desc:     desc,
+		cc:       cc,
+		method:   method,
+		streamer: streamer,
+		opts:     opts,
+	}
+	// Only server-streaming reads can be transparently retried, since the
+	// single request message can be replayed to a new stream
+	if IsReadMethod(method) && desc.ServerStreams && !desc.ClientStreams {
+		rs.retries = c.opts.MaxRetries
+	}
+	if err := rs.open(); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// retryStream wraps a client stream for a server-streaming read RPC. If the
+// stream fails with an unavailable error before any response message has been
+// received, the stream is re-opened (possibly on another endpoint) and the
+// request message is replayed.
+type retryStream struct {
+	grpc.ClientStream
+	client   *Client
+	ctx      context.Context
+	desc     *grpc.StreamDesc
+	cc       *grpc.ClientConn
+	method   string
+	streamer grpc.Streamer
+	opts     []grpc.CallOption
+	retries  int
+	attempt  int
+	peer     *peer.Peer
+	// The request message(s) sent on the stream, replayed on retry
+	sent []interface{}
+	// Whether CloseSend was called on the stream, replayed on retry
+	closed bool
+	// Once a response message has been received, the stream can no longer be
+	// transparently retried
+	received bool
+}
+
+// open creates the underlying client stream, retrying on unavailable errors
+func (rs *retryStream) open() error {
+	for {
+		rs.peer = &peer.Peer{}
+		opts := withPeer(rs.opts, rs.peer)
+		cs, err := rs.streamer(rs.ctx, rs.desc, rs.cc, rs.method, opts...)
+		if err == nil {
+			rs.ClientStream = cs
+			return nil
+		}
+		rs.client.record(rs.peer, err)
+		if !rs.canRetry(err) {
+			return err
+		}
+	}
+}
+
+// canRetry returns true after waiting for backoff if the stream may be
+// re-opened after the supplied error
+func (rs *retryStream) canRetry(err error) bool {
+	if rs.received || !isUnavailable(err) || rs.attempt >= rs.retries {
+		return false
+	}
+	rs.client.log.L3(
+		"retrying %s on %s service after error: %s",
+		rs.method, rs.client.service, err,
+	)
+	if !rs.client.backoff(rs.ctx, rs.attempt) {
+		return false
+	}
+	rs.attempt++
+	return true
+}
+
+// replay re-opens the stream and re-sends the messages sent on the failed
+// stream
+func (rs *retryStream) replay() error {
+	if err := rs.open(); err != nil {
+		return err
+	}
+	for _, m := range rs.sent {
+		if err := rs.ClientStream.SendMsg(m); err != nil {
+			return err
+		}
+	}
+	if rs.closed {
+		return rs.ClientStream.CloseSend()
+	}
+	return nil
+}
+
+func (rs *retryStream) SendMsg(m interface{}) error {
+	if rs.retries > 0 {
+		rs.sent = append(rs.sent, m)
+	}
+	return rs.ClientStream.SendMsg(m)
+}
+
+func (rs *retryStream) CloseSend() error {
+	rs.closed = true
+	return rs.ClientStream.CloseSend()
+}
+
+func (rs *retryStream) RecvMsg(m interface{}) error {
+	for {
+		err := rs.ClientStream.RecvMsg(m)
+		if err == nil {
+			rs.received = true
+			return nil
+		}
+		if err == io.EOF {
+			rs.client.record(rs.peer, nil)
+			return err
+		}
+		rs.client.record(rs.peer, err)
+		if !rs.canRetry(err) {
+			return err
+		}
+		if err = rs.replay(); err != nil {
+			return err
+		}
+	}
+}
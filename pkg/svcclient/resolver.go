@@ -0,0 +1,145 @@
+package svcclient
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/resolver"
+)
+
+var resolverMu sync.Mutex
+
+// registerResolver registers the supplied resolver builder with gRPC. The gRPC
+// resolver registry is not safe for concurrent use, so we serialize
+// registrations.
+func registerResolver(b *gsrResolverBuilder) {
+	resolverMu.Lock()
+	defer resolverMu.Unlock()
+	resolver.Register(b)
+}
+
+// gsrResolverBuilder builds a resolver that looks up the endpoints of a
+// service in gsr
+type gsrResolverBuilder struct {
+	scheme string
+	client *Client
+	// Returns the addresses of all endpoints of the service registered in gsr
+	lookup func() []string
+}
+
+func (b *gsrResolverBuilder) Scheme() string {
+	return b.scheme
+}
+
+func (b *gsrResolverBuilder) Build(
+	target resolver.Target,
+	cc resolver.ClientConn,
+	opts resolver.BuildOption,
+) (resolver.Resolver, error) {
+	r := &gsrResolver{
+		client:  b.client,
+		lookup:  b.lookup,
+		cc:      cc,
+		refresh: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	b.client.resolver = r
+	r.update()
+	go r.watch(b.client.opts.RefreshInterval)
+	return r, nil
+}
+
+// gsrResolver periodically looks up the endpoints of a service in gsr and
+// notifies gRPC of the set of endpoint addresses RPCs should be balanced
+// across. Endpoints that have been ejected by the Client are excluded from
+// that set unless every endpoint has been ejected.
+type gsrResolver struct {
+	sync.Mutex
+	client  *Client
+	lookup  func() []string
+	cc      resolver.ClientConn
+	refresh chan struct{}
+	done    chan struct{}
+	// The set of addresses last sent to gRPC
+	addrs []string
+}
+
+// numAddrs returns the number of endpoint addresses last sent to gRPC
+func (r *gsrResolver) numAddrs() int {
+	r.Lock()
+	defer r.Unlock()
+	return len(r.addrs)
+}
+
+func (r *gsrResolver) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+		case <-r.refresh:
+		}
+		r.update()
+	}
+}
+
+// update looks up the service's endpoints in gsr and notifies gRPC if the set
+// of healthy endpoint addresses has changed
+func (r *gsrResolver) update() {
+	all := r.lookup()
+	addrs := make([]string, 0, len(all))
+	for _, addr := range all {
+		if !r.client.ejector.isEjected(addr) {
+			addrs = append(addrs, addr)
+		}
+	}
+	if len(addrs) == 0 {
+		// Better to try an endpoint that was recently failing than to fail
+		// every RPC outright
+		addrs = all
+	}
+	sort.Strings(addrs)
+
+	r.Lock()
+	changed := !stringsEqual(addrs, r.addrs)
+	r.addrs = addrs
+	r.Unlock()
+	if !changed {
+		return
+	}
+	r.client.log.L3(
+		"endpoints for %s service changed: %v", r.client.service, addrs,
+	)
+	raddrs := make([]resolver.Address, len(addrs))
+	for x, addr := range addrs {
+		raddrs[x] = resolver.Address{Addr: addr}
+	}
+	r.cc.NewAddress(raddrs)
+}
+
+// ResolveNow triggers an immediate lookup of the service's endpoints
+func (r *gsrResolver) ResolveNow(opts resolver.ResolveNowOption) {
+	select {
+	case r.refresh <- struct{}{}:
+	default:
+	}
+}
+
+func (r *gsrResolver) Close() {
+	close(r.done)
+}
+
+func stringsEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for x := range a {
+		if a[x] != b[x] {
+			return false
+		}
+	}
+	return true
+}
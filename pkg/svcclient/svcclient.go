@@ -0,0 +1,183 @@
+// Package svcclient provides load-balanced gRPC client connections to the
+// endpoints of a runmachine service registered in the gsr service registry.
+//
+// A Client watches gsr for changes to the set of endpoints of a service,
+// balances RPCs across the healthy endpoints using either a round-robin or a
+// least-loaded policy, temporarily ejects endpoints that repeatedly fail with
+// an unavailable error and retries idempotent (read) RPCs on another endpoint.
+package svcclient
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer/roundrobin"
+
+	"github.com/runmachine-io/runmachine/pkg/logging"
+	"github.com/runmachine-io/runmachine/pkg/registry"
+	"github.com/runmachine-io/runmachine/pkg/tracing"
+	"github.com/runmachine-io/runmachine/pkg/util"
+)
+
+const (
+	// Balance RPCs across endpoints in turn
+	PolicyRoundRobin = "round_robin"
+	// Send each RPC to the endpoint with the fewest in-flight RPCs
+	PolicyLeastLoaded = "least_loaded"
+)
+
+const (
+	defaultPolicy          = PolicyRoundRobin
+	defaultRefreshInterval = 5 * time.Second
+	defaultMaxFailures     = 3
+	defaultEjectDuration   = 30 * time.Second
+	defaultMaxRetries      = 2
+	defaultRetryBackoff    = 100 * time.Millisecond
+)
+
+// Options controls the behaviour of a Client
+type Options struct {
+	// One of PolicyRoundRobin or PolicyLeastLoaded
+	Policy string
+	// How often to look up the set of endpoints for the service in gsr
+	RefreshInterval time.Duration
+	// Number of consecutive unavailable errors from an endpoint after which
+	// the endpoint is ejected from the set of endpoints RPCs are balanced
+	// across
+	MaxFailures int
+	// How long an ejected endpoint is excluded from balancing
+	EjectDuration time.Duration
+	// Maximum number of times an idempotent RPC is retried after an
+	// unavailable error
+	MaxRetries int
+	// Time to wait before the first retry. Doubled for each further retry.
+	RetryBackoff time.Duration
+}
+
+// DefaultOptions returns an Options struct populated with default values,
+// using the supplied balancing policy. If policy is empty, the round-robin
+// policy is used.
+func DefaultOptions(policy string) *Options {
+	if policy == "" {
+		policy = defaultPolicy
+	}
+	return &Options{
+		Policy:          policy,
+		RefreshInterval: defaultRefreshInterval,
+		MaxFailures:     defaultMaxFailures,
+		EjectDuration:   defaultEjectDuration,
+		MaxRetries:      defaultMaxRetries,
+		RetryBackoff:    defaultRetryBackoff,
+	}
+}
+
+// Client is a load-balanced gRPC connection to all endpoints of a single
+// service registered in gsr. Pass the result of Conn() to a generated gRPC
+// client constructor (e.g. pb.NewRunmMetadataClient).
+type Client struct {
+	log      *logging.Logs
+	service  string
+	opts     *Options
+	resolver *gsrResolver
+	ejector  *ejector
+	conn     *grpc.ClientConn
+}
+
+// Conn returns the underlying load-balanced gRPC client connection
+func (c *Client) Conn() *grpc.ClientConn {
+	return c.conn
+}
+
+// Close stops watching gsr and closes all connections to the service's
+// endpoints
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Each Client registers its own resolver scheme since the gRPC resolver
+// registry is global and each resolver needs its own gsr lookup state
+var schemeCounter uint64
+
+// New returns a Client balancing RPCs across the endpoints of the named
+// service in the supplied service registry. Connections are established lazily,
+// so New succeeds even when no endpoints of the service are registered yet.
+func New(
+	log *logging.Logs,
+	reg registry.Registry,
+	service string,
+	opts *Options,
+) (*Client, error) {
+	lookup := func() []string {
+		eps := reg.Endpoints(service)
+		addrs := make([]string, len(eps))
+		for x, ep := range eps {
+			addrs[x] = ep.Address
+		}
+		return addrs
+	}
+	return newClient(log, service, lookup, opts)
+}
+
+// newClient returns a Client balancing RPCs across the endpoint addresses
+// returned by the supplied lookup function
+func newClient(
+	log *logging.Logs,
+	service string,
+	lookup func() []string,
+	opts *Options,
+) (*Client, error) {
+	if opts == nil {
+		opts = DefaultOptions("")
+	}
+	balancerName := ""
+	switch opts.Policy {
+	case PolicyRoundRobin:
+		balancerName = roundrobin.Name
+	case PolicyLeastLoaded:
+		balancerName = leastLoadedName
+	default:
+		return nil, fmt.Errorf(
+			"unknown load balancing policy %s. expected %s or %s",
+			opts.Policy, PolicyRoundRobin, PolicyLeastLoaded,
+		)
+	}
+	c := &Client{
+		log:     log,
+		service: service,
+		opts:    opts,
+		ejector: newEjector(opts.MaxFailures, opts.EjectDuration),
+	}
+	scheme := fmt.Sprintf(
+		"runm-gsr-%d", atomic.AddUint64(&schemeCounter, 1),
+	)
+	builder := &gsrResolverBuilder{
+		scheme: scheme,
+		client: c,
+		lookup: lookup,
+	}
+	registerResolver(builder)
+
+	var dopts []grpc.DialOption
+	// TODO(jaypipes): Don't hardcode this to WithInsecure
+	dopts = append(dopts, grpc.WithInsecure())
+	dopts = append(dopts, grpc.WithBalancerName(balancerName))
+	// Each logical RPC is traced once, outside of any retries
+	dopts = append(dopts, grpc.WithUnaryInterceptor(util.ChainUnaryClient(
+		tracing.UnaryClientInterceptor(), c.unaryInterceptor,
+	)))
+	dopts = append(dopts, grpc.WithStreamInterceptor(util.ChainStreamClient(
+		tracing.StreamClientInterceptor(), c.streamInterceptor,
+	)))
+	conn, err := grpc.Dial(scheme+":///"+service, dopts...)
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
+	log.L2(
+		"created %s service client balancing RPCs using %s policy",
+		service, opts.Policy,
+	)
+	return c, nil
+}
@@ -0,0 +1,118 @@
+package svcclient
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/runmachine-io/runmachine/pkg/logging"
+)
+
+func TestIsReadMethod(t *testing.T) {
+	tests := []struct {
+		method string
+		read   bool
+	}{
+		{"/runm.RunmMetadata/object_get_by_uuid", true},
+		{"/runm.RunmMetadata/partition_find", true},
+		{"/runm.RunmAPI/provider_list", true},
+		{"/runm.RunmMetadata/object_create", false},
+		{"/runm.RunmResource/provider_delete_by_uuids", false},
+		{"/runm.RunmMetadata/object_definition_set", false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.read, IsReadMethod(test.method), test.method)
+	}
+}
+
+func TestEjector(t *testing.T) {
+	now := time.Now()
+	e := newEjector(2, time.Minute)
+	e.now = func() time.Time { return now }
+
+	assert.False(t, e.failure("a:1"))
+	// A success resets the consecutive failure count
+	e.success("a:1")
+	assert.False(t, e.failure("a:1"))
+	assert.True(t, e.failure("a:1"))
+	assert.True(t, e.isEjected("a:1"))
+	assert.False(t, e.isEjected("b:1"))
+
+	now = now.Add(2 * time.Minute)
+	assert.False(t, e.isEjected("a:1"))
+}
+
+// countingHealth is a health service that counts the Check RPCs it receives
+type countingHealth struct {
+	healthpb.HealthServer
+	count int64
+}
+
+func (h *countingHealth) Check(
+	ctx context.Context,
+	req *healthpb.HealthCheckRequest,
+) (*healthpb.HealthCheckResponse, error) {
+	atomic.AddInt64(&h.count, 1)
+	return &healthpb.HealthCheckResponse{
+		Status: healthpb.HealthCheckResponse_SERVING,
+	}, nil
+}
+
+func startHealth(t *testing.T) (string, *countingHealth, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	h := &countingHealth{}
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, h)
+	go srv.Serve(lis)
+	return lis.Addr().String(), h, srv.Stop
+}
+
+func TestClientBalancesAcrossEndpoints(t *testing.T) {
+	for _, policy := range []string{PolicyRoundRobin, PolicyLeastLoaded} {
+		addr1, h1, stop1 := startHealth(t)
+		defer stop1()
+		addr2, h2, stop2 := startHealth(t)
+		defer stop2()
+
+		lookup := func() []string { return []string{addr1, addr2} }
+		c, err := newClient(
+			logging.New(&logging.Config{}), "test", lookup,
+			DefaultOptions(policy),
+		)
+		assert.Nil(t, err)
+		hc := healthpb.NewHealthClient(c.Conn())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		// Wait for both endpoints to become ready before counting
+		for atomic.LoadInt64(&h1.count) == 0 || atomic.LoadInt64(&h2.count) == 0 {
+			_, err = hc.Check(ctx, &healthpb.HealthCheckRequest{})
+			if !assert.Nil(t, err, policy) {
+				break
+			}
+		}
+		cancel()
+		c.Close()
+	}
+}
+
+func TestClientNoEndpoints(t *testing.T) {
+	lookup := func() []string { return nil }
+	c, err := newClient(
+		logging.New(&logging.Config{}), "test", lookup, nil,
+	)
+	assert.Nil(t, err)
+	defer c.Close()
+
+	hc := healthpb.NewHealthClient(c.Conn())
+	_, err = hc.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.True(t, isUnavailable(err))
+}
@@ -0,0 +1,52 @@
+// Package testutil contains helpers shared by the tests of runmachine
+// packages
+package testutil
+
+import (
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/runmachine-io/runmachine/pkg/embedetcd"
+)
+
+const (
+	etcdName         = "runm-test"
+	etcdStartTimeout = 30 * time.Second
+)
+
+// freeURL returns an http URL on the loopback interface with a port that is
+// not in use
+func freeURL(t *testing.T) url.URL {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	defer lis.Close()
+	return url.URL{Scheme: "http", Host: lis.Addr().String()}
+}
+
+// StartEtcd starts an embedded etcd server in a temporary directory and
+// returns the URL clients should use to connect to it along with a function
+// that stops the server and removes the directory
+func StartEtcd(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "runm-etcd")
+	if err != nil {
+		t.Fatalf("failed to create etcd data directory: %v", err)
+	}
+	clientURL := freeURL(t)
+	e, err := embedetcd.Start(
+		etcdName, dir, clientURL, freeURL(t), etcdStartTimeout,
+	)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("failed to start etcd: %v", err)
+	}
+	return clientURL.String(), func() {
+		e.Close()
+		os.RemoveAll(dir)
+	}
+}
@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jaypipes/envutil"
+	flag "github.com/ogier/pflag"
+
+	"github.com/runmachine-io/runmachine/pkg/logging"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	ExporterOTLP   = "otlp"
+
+	defaultExporter     = ExporterNone
+	defaultFilePath     = "/var/log/runmachine/traces.json"
+	defaultOTLPEndpoint = "http://localhost:4318/v1/traces"
+)
+
+type Config struct {
+	// One of "none", "stdout", "file" or "otlp"
+	Exporter string
+	// Path of the file spans are appended to when Exporter is "file"
+	FilePath string
+	// URL of the OTLP/HTTP traces endpoint when Exporter is "otlp"
+	OTLPEndpoint string
+}
+
+// Returns a new Config struct populated with the configuration values read
+// from the command line or environment variables
+func ConfigFromOpts() *Config {
+	optExporter := flag.String(
+		"trace-exporter",
+		envutil.WithDefault(
+			"RUNM_TRACE_EXPORTER", defaultExporter,
+		),
+		"Where to send trace spans: none (default), stdout, file or otlp",
+	)
+	optFilePath := flag.String(
+		"trace-file",
+		envutil.WithDefault(
+			"RUNM_TRACE_FILE", defaultFilePath,
+		),
+		"Path of the file to append trace spans to when --trace-exporter=file",
+	)
+	optOTLPEndpoint := flag.String(
+		"trace-otlp-endpoint",
+		envutil.WithDefault(
+			"RUNM_TRACE_OTLP_ENDPOINT", defaultOTLPEndpoint,
+		),
+		"URL of the OTLP/HTTP traces endpoint when --trace-exporter=otlp",
+	)
+
+	flag.Parse()
+
+	return &Config{
+		Exporter:     *optExporter,
+		FilePath:     *optFilePath,
+		OTLPEndpoint: *optOTLPEndpoint,
+	}
+}
+
+// Init creates a Tracer for the named service using the configured exporter
+// and makes it the tracer used by StartSpan and the gRPC interceptors. Callers
+// should Close() the returned Tracer on shutdown to flush buffered spans.
+func Init(
+	log *logging.Logs,
+	service string,
+	cfg *Config,
+) (*Tracer, error) {
+	t := &Tracer{service: service}
+	switch cfg.Exporter {
+	case "", ExporterNone:
+	case ExporterStdout:
+		t.exporter = NewWriterExporter(os.Stdout)
+	case ExporterFile:
+		exp, err := NewFileExporter(cfg.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to open trace file %s: %s", cfg.FilePath, err,
+			)
+		}
+		t.exporter = exp
+	case ExporterOTLP:
+		t.exporter = NewOTLPExporter(log, cfg.OTLPEndpoint)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+	if t.exporter != nil {
+		log.L2("exporting trace spans to %s exporter", cfg.Exporter)
+	}
+	SetTracer(t)
+	return t, nil
+}
@@ -0,0 +1,10 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+)
+
+func errHTTPStatus(code int) error {
+	return fmt.Errorf("HTTP %d %s", code, http.StatusText(code))
+}
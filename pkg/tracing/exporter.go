@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// Exporter sends completed spans somewhere they can be inspected
+type Exporter interface {
+	// Export hands a completed span to the exporter. Implementations must not
+	// block the caller on slow I/O for long.
+	Export(*SpanData)
+	// Close flushes any buffered spans and releases the exporter's resources
+	Close()
+}
+
+// jsonSpan is the representation of a span written by the writer exporter,
+// one JSON object per line
+type jsonSpan struct {
+	Service      string            `json:"service"`
+	Name         string            `json:"name"`
+	Kind         string            `json:"kind"`
+	TraceID      string            `json:"trace_id"`
+	SpanID       string            `json:"span_id"`
+	ParentSpanID string            `json:"parent_span_id,omitempty"`
+	Start        string            `json:"start"`
+	DurationMs   float64           `json:"duration_ms"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+	Error        string            `json:"error,omitempty"`
+}
+
+// writerExporter writes each span as a line of JSON to an io.Writer
+type writerExporter struct {
+	sync.Mutex
+	w     io.Writer
+	enc   *json.Encoder
+	close func() error
+}
+
+// NewWriterExporter returns an Exporter that writes each span as a line of
+// JSON to the supplied writer
+func NewWriterExporter(w io.Writer) Exporter {
+	return &writerExporter{w: w, enc: json.NewEncoder(w)}
+}
+
+// NewFileExporter returns an Exporter that appends each span as a line of JSON
+// to the file at the supplied path, creating the file if necessary
+func NewFileExporter(path string) (Exporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &writerExporter{w: f, enc: json.NewEncoder(f), close: f.Close}, nil
+}
+
+func (e *writerExporter) Export(sd *SpanData) {
+	js := &jsonSpan{
+		Service:    sd.Service,
+		Name:       sd.Name,
+		Kind:       sd.Kind.String(),
+		TraceID:    sd.TraceID.String(),
+		SpanID:     sd.SpanID.String(),
+		Start:      sd.Start.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		DurationMs: float64(sd.End.Sub(sd.Start).Nanoseconds()) / 1e6,
+		Attributes: sd.Attributes,
+		Error:      sd.Error,
+	}
+	if sd.ParentSpanID.IsValid() {
+		js.ParentSpanID = sd.ParentSpanID.String()
+	}
+	e.Lock()
+	defer e.Unlock()
+	e.enc.Encode(js)
+}
+
+func (e *writerExporter) Close() {
+	e.Lock()
+	defer e.Unlock()
+	if e.close != nil {
+		e.close()
+	}
+}
@@ -0,0 +1,158 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/runmachine-io/runmachine/pkg/logging"
+)
+
+// serverSpan starts a server span for the supplied gRPC method that is a child
+// of any span context propagated by the client in the incoming metadata. The
+// returned context carries the span and the trace ID as the request ID used
+// in log messages.
+func serverSpan(ctx context.Context, method string) (context.Context, *Span) {
+	var parent SpanContext
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if vals := md.Get(TraceparentHeader); len(vals) > 0 {
+			parent, _ = ParseTraceparent(vals[0])
+		}
+	}
+	ctx, span := startSpan(ctx, method, SpanKindServer, parent)
+	span.SetAttribute("rpc.system", "grpc")
+	span.SetAttribute("rpc.method", method)
+	ctx = logging.WithRequestID(ctx, span.sc.TraceID.String())
+	return ctx, span
+}
+
+// clientSpan starts a client span for the supplied gRPC method and adds the
+// span's context to the outgoing metadata so the server can continue the trace
+func clientSpan(ctx context.Context, method string) (context.Context, *Span) {
+	ctx, span := StartSpan(ctx, method, SpanKindClient)
+	span.SetAttribute("rpc.system", "grpc")
+	span.SetAttribute("rpc.method", method)
+	ctx = metadata.AppendToOutgoingContext(
+		ctx, TraceparentHeader, span.sc.Traceparent(),
+	)
+	return ctx, span
+}
+
+// endSpan records the gRPC status of the RPC and ends the span
+func endSpan(span *Span, err error) {
+	span.SetAttribute("rpc.grpc.status_code", status.Code(err).String())
+	span.SetError(err)
+	span.End()
+}
+
+// UnaryServerInterceptor returns a gRPC server interceptor that records a span
+// for each unary RPC, continuing any trace propagated by the client
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		ctx, span := serverSpan(ctx, info.FullMethod)
+		resp, err := handler(ctx, req)
+		endSpan(span, err)
+		return resp, err
+	}
+}
+
+// tracedServerStream overrides the context of a grpc.ServerStream with one
+// carrying the RPC's server span
+type tracedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tracedServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// StreamServerInterceptor returns a gRPC server interceptor that records a
+// span for each streaming RPC, continuing any trace propagated by the client
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, span := serverSpan(ss.Context(), info.FullMethod)
+		err := handler(srv, &tracedServerStream{ss, ctx})
+		endSpan(span, err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor returns a gRPC client interceptor that records a
+// span for each unary RPC and propagates the trace context to the server
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req interface{},
+		reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		ctx, span := clientSpan(ctx, method)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		endSpan(span, err)
+		return err
+	}
+}
+
+// tracedClientStream ends the RPC's client span when the stream is exhausted
+// or fails
+type tracedClientStream struct {
+	grpc.ClientStream
+	span *Span
+	once sync.Once
+}
+
+func (s *tracedClientStream) finish(err error) {
+	s.once.Do(func() {
+		if err == io.EOF {
+			err = nil
+		}
+		endSpan(s.span, err)
+	})
+}
+
+func (s *tracedClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.finish(err)
+	}
+	return err
+}
+
+// StreamClientInterceptor returns a gRPC client interceptor that records a
+// span for each streaming RPC and propagates the trace context to the server
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		ctx, span := clientSpan(ctx, method)
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			endSpan(span, err)
+			return nil, err
+		}
+		return &tracedClientStream{ClientStream: cs, span: span}, nil
+	}
+}
@@ -0,0 +1,48 @@
+package tracing
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/runmachine-io/runmachine/pkg/logging"
+)
+
+// RequestIDHeader is the HTTP response header carrying the request ID
+const RequestIDHeader = "X-Request-Id"
+
+// statusRecorder records the status code written to an http.ResponseWriter
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// HTTPHandler wraps an http.Handler, recording a span for each HTTP request
+// that continues any trace propagated in the request's traceparent header.
+// The request ID is returned to the caller in the X-Request-Id header.
+func HTTPHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parent, _ := ParseTraceparent(r.Header.Get(TraceparentHeader))
+		ctx, span := startSpan(
+			r.Context(), "HTTP "+r.Method, SpanKindServer, parent,
+		)
+		span.SetAttribute("http.method", r.Method)
+		span.SetAttribute("http.target", r.URL.Path)
+		reqID := span.sc.TraceID.String()
+		ctx = logging.WithRequestID(ctx, reqID)
+		w.Header().Set(RequestIDHeader, reqID)
+
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		h.ServeHTTP(rec, r.WithContext(ctx))
+
+		span.SetAttribute("http.status_code", strconv.Itoa(rec.code))
+		if rec.code >= 500 {
+			span.SetError(errHTTPStatus(rec.code))
+		}
+		span.End()
+	})
+}
@@ -0,0 +1,209 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/runmachine-io/runmachine/pkg/logging"
+)
+
+const (
+	otlpBatchSize     = 512
+	otlpFlushInterval = 5 * time.Second
+	otlpQueueSize     = 4096
+	otlpTimeout       = 10 * time.Second
+)
+
+// otlpExporter buffers spans and sends them in batches to an OpenTelemetry
+// collector using the OTLP/HTTP protocol with JSON encoding
+type otlpExporter struct {
+	log      *logging.Logs
+	endpoint string
+	client   *http.Client
+	queue    chan *SpanData
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewOTLPExporter returns an Exporter that sends spans to the OTLP/HTTP traces
+// endpoint at the supplied URL, e.g. "http://localhost:4318/v1/traces". Spans
+// are dropped if the exporter's queue is full.
+func NewOTLPExporter(log *logging.Logs, endpoint string) Exporter {
+	e := &otlpExporter{
+		log:      log,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: otlpTimeout},
+		queue:    make(chan *SpanData, otlpQueueSize),
+		done:     make(chan struct{}),
+	}
+	e.wg.Add(1)
+	go e.run()
+	return e
+}
+
+func (e *otlpExporter) Export(sd *SpanData) {
+	select {
+	case e.queue <- sd:
+	default:
+		e.log.L3("tracing: OTLP export queue full. dropping span %s", sd.Name)
+	}
+}
+
+func (e *otlpExporter) Close() {
+	close(e.done)
+	e.wg.Wait()
+}
+
+// run sends batches of queued spans whenever a batch fills up or the flush
+// interval elapses, and sends any remaining spans when the exporter is closed
+func (e *otlpExporter) run() {
+	defer e.wg.Done()
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+	batch := make([]*SpanData, 0, otlpBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.send(batch); err != nil {
+			e.log.ERR("tracing: failed to export %d spans: %s", len(batch), err)
+		}
+		batch = make([]*SpanData, 0, otlpBatchSize)
+	}
+	for {
+		select {
+		case sd := <-e.queue:
+			batch = append(batch, sd)
+			if len(batch) >= otlpBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-e.done:
+			for {
+				select {
+				case sd := <-e.queue:
+					batch = append(batch, sd)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (e *otlpExporter) send(batch []*SpanData) error {
+	body, err := json.Marshal(otlpRequest(batch))
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// The types below mirror the JSON encoding of the OTLP
+// ExportTraceServiceRequest message
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []*otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpExportRequest struct {
+	ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+}
+
+const (
+	otlpStatusOK    = 1
+	otlpStatusError = 2
+)
+
+// otlpRequest groups the supplied spans by service into an OTLP export request
+func otlpRequest(batch []*SpanData) *otlpExportRequest {
+	req := &otlpExportRequest{}
+	byService := make(map[string]*otlpScopeSpans, 0)
+	for _, sd := range batch {
+		ss, exists := byService[sd.Service]
+		if !exists {
+			rs := &otlpResourceSpans{}
+			rs.Resource.Attributes = []otlpKeyValue{
+				{Key: "service.name", Value: otlpValue{sd.Service}},
+			}
+			ss = &otlpScopeSpans{}
+			ss.Scope.Name = "github.com/runmachine-io/runmachine/pkg/tracing"
+			rs.ScopeSpans = []*otlpScopeSpans{ss}
+			req.ResourceSpans = append(req.ResourceSpans, rs)
+			byService[sd.Service] = ss
+		}
+		span := &otlpSpan{
+			TraceID:           sd.TraceID.String(),
+			SpanID:            sd.SpanID.String(),
+			Name:              sd.Name,
+			Kind:              int(sd.Kind),
+			StartTimeUnixNano: strconv.FormatInt(sd.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(sd.End.UnixNano(), 10),
+			Status:            otlpStatus{Code: otlpStatusOK},
+		}
+		if sd.ParentSpanID.IsValid() {
+			span.ParentSpanID = sd.ParentSpanID.String()
+		}
+		for k, v := range sd.Attributes {
+			span.Attributes = append(
+				span.Attributes, otlpKeyValue{Key: k, Value: otlpValue{v}},
+			)
+		}
+		if sd.Error != "" {
+			span.Status = otlpStatus{Code: otlpStatusError, Message: sd.Error}
+		}
+		ss.Spans = append(ss.Spans, span)
+	}
+	return req
+}
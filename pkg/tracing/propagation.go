@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Name of the gRPC metadata key and HTTP header carrying trace context
+	TraceparentHeader  = "traceparent"
+	traceparentVersion = "00"
+)
+
+// Traceparent returns the W3C traceparent representation of the span context
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf(
+		"%s-%s-%s-%s",
+		traceparentVersion, sc.TraceID, sc.SpanID, flags,
+	)
+}
+
+// ParseTraceparent returns the span context described by a W3C traceparent
+// value. The returned bool is false if the value is not a valid traceparent.
+func ParseTraceparent(value string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || parts[0] != traceparentVersion {
+		return sc, false
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, false
+	}
+	sc.Sampled = flags[0]&0x01 == 0x01
+	return sc, sc.IsValid()
+}
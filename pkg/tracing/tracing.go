@@ -0,0 +1,274 @@
+// Package tracing records spans describing the work done by runmachine
+// services while handling a request and propagates trace context between
+// services so that the spans recorded by runm-api, runm-metadata and
+// runm-resource for a single request can be stitched together into one trace.
+//
+// The data model follows OpenTelemetry: a trace is identified by a 16-byte
+// trace ID, each span by an 8-byte span ID, and trace context is carried
+// between processes in a W3C "traceparent" value.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// TraceID uniquely identifies a trace
+type TraceID [16]byte
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid returns true if the trace ID is not all zeroes
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+// SpanID uniquely identifies a span within a trace
+type SpanID [8]byte
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid returns true if the span ID is not all zeroes
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// SpanContext is the portion of a span that is propagated to child spans,
+// including child spans in other processes
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	// Whether the trace is being recorded
+	Sampled bool
+}
+
+// IsValid returns true if the span context has both a trace and span ID
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// SpanKind describes the relationship between a span and its parent
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = iota + 1
+	SpanKindServer
+	SpanKindClient
+)
+
+func (k SpanKind) String() string {
+	switch k {
+	case SpanKindServer:
+		return "server"
+	case SpanKindClient:
+		return "client"
+	default:
+		return "internal"
+	}
+}
+
+// Span describes a single timed operation within a trace
+type Span struct {
+	sync.Mutex
+	tracer *Tracer
+	name   string
+	kind   SpanKind
+	sc     SpanContext
+	parent SpanID
+	start  time.Time
+	end    time.Time
+	attrs  map[string]string
+	err    error
+	ended  bool
+}
+
+// Context returns the span's SpanContext
+func (s *Span) Context() SpanContext {
+	return s.sc
+}
+
+// SetAttribute records a key/value attribute describing the span
+func (s *Span) SetAttribute(key string, value string) {
+	s.Lock()
+	defer s.Unlock()
+	s.attrs[key] = value
+}
+
+// SetError marks the span as failed with the supplied error. A nil error is
+// ignored.
+func (s *Span) SetError(err error) {
+	if err == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.err = err
+}
+
+// End marks the span as complete and hands it to the tracer's exporter if the
+// trace is sampled. Calls after the first are ignored.
+func (s *Span) End() {
+	s.Lock()
+	if s.ended {
+		s.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.Unlock()
+	if s.sc.Sampled && s.tracer.exporter != nil {
+		s.tracer.exporter.Export(s.data())
+	}
+}
+
+// data returns a snapshot of the span for exporting
+func (s *Span) data() *SpanData {
+	s.Lock()
+	defer s.Unlock()
+	attrs := make(map[string]string, len(s.attrs))
+	for k, v := range s.attrs {
+		attrs[k] = v
+	}
+	errMsg := ""
+	if s.err != nil {
+		errMsg = s.err.Error()
+	}
+	return &SpanData{
+		Service:      s.tracer.service,
+		Name:         s.name,
+		Kind:         s.kind,
+		TraceID:      s.sc.TraceID,
+		SpanID:       s.sc.SpanID,
+		ParentSpanID: s.parent,
+		Start:        s.start,
+		End:          s.end,
+		Attributes:   attrs,
+		Error:        errMsg,
+	}
+}
+
+// SpanData is a completed span handed to an Exporter
+type SpanData struct {
+	Service      string
+	Name         string
+	Kind         SpanKind
+	TraceID      TraceID
+	SpanID       SpanID
+	ParentSpanID SpanID
+	Start        time.Time
+	End          time.Time
+	Attributes   map[string]string
+	// Empty string if the operation succeeded
+	Error string
+}
+
+// Tracer creates spans for a named service and hands completed, sampled spans
+// to an Exporter
+type Tracer struct {
+	service   string
+	exporter  Exporter
+	closeOnce sync.Once
+}
+
+// Close flushes any spans buffered by the tracer's exporter. Calls after the
+// first are ignored.
+func (t *Tracer) Close() {
+	t.closeOnce.Do(func() {
+		if t.exporter != nil {
+			t.exporter.Close()
+		}
+	})
+}
+
+var (
+	globalMu sync.RWMutex
+	// The default tracer creates spans so that request IDs are available to
+	// callers but does not record them anywhere
+	global = &Tracer{}
+)
+
+// SetTracer replaces the tracer used by StartSpan and the gRPC interceptors
+func SetTracer(t *Tracer) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	global = t
+}
+
+func getTracer() *Tracer {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	return global
+}
+
+type spanKey struct{}
+
+// FromContext returns the span stored in the context, or nil if there is none
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// ContextWithSpan returns a copy of the context holding the supplied span
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// RequestID returns the ID of the trace that the context's span belongs to,
+// or empty string if the context has no span
+func RequestID(ctx context.Context) string {
+	if s := FromContext(ctx); s != nil {
+		return s.sc.TraceID.String()
+	}
+	return ""
+}
+
+// StartSpan starts a new span named after the supplied operation. If the
+// context holds a span, the new span is a child of it, otherwise the new span
+// starts a new trace. The returned context holds the new span, and callers
+// must call End() on the span when the operation completes.
+func StartSpan(
+	ctx context.Context,
+	name string,
+	kind SpanKind,
+) (context.Context, *Span) {
+	var parent SpanContext
+	if s := FromContext(ctx); s != nil {
+		parent = s.sc
+	}
+	return startSpan(ctx, name, kind, parent)
+}
+
+// startSpan starts a new span that is a child of the supplied parent span
+// context, which may belong to a span in another process
+func startSpan(
+	ctx context.Context,
+	name string,
+	kind SpanKind,
+	parent SpanContext,
+) (context.Context, *Span) {
+	t := getTracer()
+	s := &Span{
+		tracer: t,
+		name:   name,
+		kind:   kind,
+		start:  time.Now(),
+		attrs:  make(map[string]string, 0),
+	}
+	if parent.IsValid() {
+		s.sc.TraceID = parent.TraceID
+		s.sc.Sampled = parent.Sampled
+		s.parent = parent.SpanID
+	} else {
+		rand.Read(s.sc.TraceID[:])
+		s.sc.Sampled = t.exporter != nil
+	}
+	rand.Read(s.sc.SpanID[:])
+	return ContextWithSpan(ctx, s), s
+}
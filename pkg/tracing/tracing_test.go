@@ -0,0 +1,167 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/runmachine-io/runmachine/pkg/logging"
+)
+
+// recordingExporter keeps exported spans in memory
+type recordingExporter struct {
+	spans []*SpanData
+}
+
+func (e *recordingExporter) Export(sd *SpanData) {
+	e.spans = append(e.spans, sd)
+}
+
+func (e *recordingExporter) Close() {}
+
+func TestTraceparent(t *testing.T) {
+	assert := assert.New(t)
+
+	_, span := StartSpan(context.TODO(), "test", SpanKindInternal)
+	sc := span.Context()
+	sc.Sampled = true
+
+	got, ok := ParseTraceparent(sc.Traceparent())
+	assert.True(ok)
+	assert.Equal(sc, got)
+
+	tests := []string{
+		"",
+		"garbage",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+	}
+	for _, tp := range tests {
+		_, ok := ParseTraceparent(tp)
+		assert.False(ok, tp)
+	}
+}
+
+func TestInterceptorsPropagate(t *testing.T) {
+	assert := assert.New(t)
+
+	exp := &recordingExporter{}
+	SetTracer(&Tracer{service: "test", exporter: exp})
+	defer SetTracer(&Tracer{})
+
+	ctx, root := StartSpan(context.TODO(), "root", SpanKindInternal)
+
+	// The client interceptor's invoker plays the part of the network,
+	// handing the outgoing metadata to the server interceptor
+	var serverReqID string
+	var serverSpan *Span
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		serverReqID = logging.RequestID(ctx)
+		serverSpan = FromContext(ctx)
+		return nil, errors.New("boom")
+	}
+	invoker := func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		opts ...grpc.CallOption,
+	) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		ctx = metadata.NewIncomingContext(context.TODO(), md)
+		_, err := UnaryServerInterceptor()(
+			ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, handler,
+		)
+		return err
+	}
+	err := UnaryClientInterceptor()(
+		ctx, "/runm.Test/thing_get", nil, nil, nil, invoker,
+	)
+	assert.NotNil(err)
+	root.End()
+
+	traceID := root.Context().TraceID
+	assert.Equal(traceID.String(), serverReqID)
+	assert.Equal(traceID, serverSpan.Context().TraceID)
+
+	// Spans are exported as they end: server, then client, then root
+	assert.Len(exp.spans, 3)
+	server, client := exp.spans[0], exp.spans[1]
+	assert.Equal(SpanKindServer, server.Kind)
+	assert.Equal(SpanKindClient, client.Kind)
+	assert.Equal(client.SpanID, server.ParentSpanID)
+	assert.Equal(root.Context().SpanID, client.ParentSpanID)
+	assert.Equal("boom", server.Error)
+	for _, sd := range exp.spans {
+		assert.Equal(traceID, sd.TraceID)
+		assert.Equal("test", sd.Service)
+	}
+}
+
+func TestUnsampledSpansNotExported(t *testing.T) {
+	assert := assert.New(t)
+
+	exp := &recordingExporter{}
+	SetTracer(&Tracer{service: "test", exporter: exp})
+	defer SetTracer(&Tracer{})
+
+	parent := SpanContext{Sampled: false}
+	parent.TraceID[0] = 1
+	parent.SpanID[0] = 1
+	_, span := startSpan(context.TODO(), "test", SpanKindServer, parent)
+	span.End()
+	assert.Len(exp.spans, 0)
+}
+
+func TestWriterExporter(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	SetTracer(&Tracer{service: "test", exporter: NewWriterExporter(&buf)})
+	defer SetTracer(&Tracer{})
+
+	_, span := StartSpan(context.TODO(), "etcd.get", SpanKindClient)
+	span.SetAttribute("db.system", "etcd")
+	span.End()
+	span.End()
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	assert.Len(lines, 1)
+
+	var js jsonSpan
+	assert.Nil(json.Unmarshal(lines[0], &js))
+	assert.Equal("test", js.Service)
+	assert.Equal("etcd.get", js.Name)
+	assert.Equal("client", js.Kind)
+	assert.Equal(span.Context().TraceID.String(), js.TraceID)
+	assert.Equal("", js.ParentSpanID)
+	assert.Equal("etcd", js.Attributes["db.system"])
+}
+
+func TestOTLPRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	var tid TraceID
+	tid[0] = 1
+	batch := []*SpanData{
+		&SpanData{Service: "a", Name: "one", Kind: SpanKindServer, TraceID: tid},
+		&SpanData{Service: "b", Name: "two", Kind: SpanKindClient, TraceID: tid, Error: "boom"},
+		&SpanData{Service: "a", Name: "three", Kind: SpanKindInternal, TraceID: tid},
+	}
+	req := otlpRequest(batch)
+	assert.Len(req.ResourceSpans, 2)
+	assert.Equal("a", req.ResourceSpans[0].Resource.Attributes[0].Value.StringValue)
+	assert.Len(req.ResourceSpans[0].ScopeSpans[0].Spans, 2)
+	two := req.ResourceSpans[1].ScopeSpans[0].Spans[0]
+	assert.Equal(3, two.Kind)
+	assert.Equal(otlpStatusError, two.Status.Code)
+	assert.Equal(tid.String(), two.TraceID)
+}
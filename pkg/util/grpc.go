@@ -0,0 +1,118 @@
+package util
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// NOTE(jaypipes): gRPC only allows a single unary and a single stream
+// interceptor per server or client connection, so the functions below combine
+// multiple interceptors into one. Interceptors are called in the order
+// supplied, with the first interceptor being the outermost.
+
+// ChainUnaryServer returns a unary server interceptor that calls each of the
+// supplied interceptors in order
+func ChainUnaryServer(
+	interceptors ...grpc.UnaryServerInterceptor,
+) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		next := handler
+		for x := len(interceptors) - 1; x >= 0; x-- {
+			ic, h := interceptors[x], next
+			next = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return ic(ctx, req, info, h)
+			}
+		}
+		return next(ctx, req)
+	}
+}
+
+// ChainStreamServer returns a stream server interceptor that calls each of
+// the supplied interceptors in order
+func ChainStreamServer(
+	interceptors ...grpc.StreamServerInterceptor,
+) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		next := handler
+		for x := len(interceptors) - 1; x >= 0; x-- {
+			ic, h := interceptors[x], next
+			next = func(srv interface{}, ss grpc.ServerStream) error {
+				return ic(srv, ss, info, h)
+			}
+		}
+		return next(srv, ss)
+	}
+}
+
+// ChainUnaryClient returns a unary client interceptor that calls each of the
+// supplied interceptors in order
+func ChainUnaryClient(
+	interceptors ...grpc.UnaryClientInterceptor,
+) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req interface{},
+		reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		next := invoker
+		for x := len(interceptors) - 1; x >= 0; x-- {
+			ic, inv := interceptors[x], next
+			next = func(
+				ctx context.Context,
+				method string,
+				req interface{},
+				reply interface{},
+				cc *grpc.ClientConn,
+				opts ...grpc.CallOption,
+			) error {
+				return ic(ctx, method, req, reply, cc, inv, opts...)
+			}
+		}
+		return next(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// ChainStreamClient returns a stream client interceptor that calls each of
+// the supplied interceptors in order
+func ChainStreamClient(
+	interceptors ...grpc.StreamClientInterceptor,
+) grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		next := streamer
+		for x := len(interceptors) - 1; x >= 0; x-- {
+			ic, st := interceptors[x], next
+			next = func(
+				ctx context.Context,
+				desc *grpc.StreamDesc,
+				cc *grpc.ClientConn,
+				method string,
+				opts ...grpc.CallOption,
+			) (grpc.ClientStream, error) {
+				return ic(ctx, desc, cc, method, st, opts...)
+			}
+		}
+		return next(ctx, desc, cc, method, opts...)
+	}
+}
@@ -0,0 +1,80 @@
+package util
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func TestChainUnaryServer(t *testing.T) {
+	calls := make([]string, 0)
+	record := func(name string) grpc.UnaryServerInterceptor {
+		return func(
+			ctx context.Context,
+			req interface{},
+			info *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler,
+		) (interface{}, error) {
+			calls = append(calls, name+" before")
+			resp, err := handler(ctx, req)
+			calls = append(calls, name+" after")
+			return resp, err
+		}
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls = append(calls, "handler")
+		return req, nil
+	}
+
+	chain := ChainUnaryServer(record("first"), record("second"))
+	resp, err := chain(context.TODO(), "req", &grpc.UnaryServerInfo{}, handler)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "req", resp)
+	assert.Equal(
+		t,
+		[]string{
+			"first before",
+			"second before",
+			"handler",
+			"second after",
+			"first after",
+		},
+		calls,
+	)
+}
+
+func TestChainUnaryClient(t *testing.T) {
+	calls := make([]string, 0)
+	record := func(name string) grpc.UnaryClientInterceptor {
+		return func(
+			ctx context.Context,
+			method string,
+			req, reply interface{},
+			cc *grpc.ClientConn,
+			invoker grpc.UnaryInvoker,
+			opts ...grpc.CallOption,
+		) error {
+			calls = append(calls, name)
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+	}
+	invoker := func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		opts ...grpc.CallOption,
+	) error {
+		calls = append(calls, "invoker "+method)
+		return nil
+	}
+
+	chain := ChainUnaryClient(record("first"), record("second"))
+	err := chain(context.TODO(), "/test", nil, nil, nil, invoker)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"first", "second", "invoker /test"}, calls)
+}
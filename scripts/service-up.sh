@@ -24,6 +24,7 @@ MYSQL_CONTAINER_NAME=${MYSQL_CONTAINER_NAME:-"runm-test-mysql"}
 METADATA_CONTAINER_NAME=${METADATA_CONTAINER_NAME:-"runm-test-metadata"}
 RESOURCE_CONTAINER_NAME=${RESOURCE_CONTAINER_NAME:-"runm-test-resource"}
 API_CONTAINER_NAME=${API_CONTAINER_NAME:-"runm-test-api"}
+GATEWAY_CONTAINER_NAME=${GATEWAY_CONTAINER_NAME:-"runm-test-gateway"}
 
 if ! container_is_running "$ETCD_CONTAINER_NAME"; then
     $SCRIPTS_DIR/start-etcd-container.sh "$ETCD_CONTAINER_NAME"
@@ -116,3 +117,46 @@ else
     echo "ERROR: could not get IP for runm-api container"
     exit 1
 fi
+
+if ! container_is_running "$GATEWAY_CONTAINER_NAME"; then
+    inline_if_verbose "Starting runm-gateway container named $GATEWAY_CONTAINER_NAME... "
+    docker run -d \
+        --rm \
+        -p 10080:10080 \
+        --name $GATEWAY_CONTAINER_NAME \
+        -e GSR_LOG_LEVEL=3 \
+        -e GSR_ETCD_ENDPOINTS="http://$etcd_container_ip:2379" \
+        -e RUNM_LOG_LEVEL=3 \
+        runm/gateway:$VERSION >/dev/null 2>&1
+    print_if_verbose "ok."
+fi
+
+inline_if_verbose "Grabbing IP for $GATEWAY_CONTAINER_NAME ... "
+if container_get_ip "$GATEWAY_CONTAINER_NAME" gateway_container_ip; then
+    print_if_verbose "ok."
+    print_if_verbose "runm-gateway running in container at ${gateway_container_ip}:10080."
+else
+    echo "ERROR: could not get IP for runm-gateway container"
+    exit 1
+fi
+
+# Rather than assuming the services are ready once their containers are
+# running, poll the runm-api service for the health of every registered
+# service endpoint until all endpoints report SERVING.
+if is_installed runm; then
+    inline_if_verbose "Waiting for runmachine services to report healthy ... "
+    sleep_time=0
+    healthy=0
+    until [ $sleep_time -eq 8 ]; do
+        sleep $(( sleep_time++ ))
+        if runm status --host $api_container_ip >/dev/null 2>&1; then
+            healthy=1
+            break
+        fi
+    done
+    if [ $healthy -eq 0 ]; then
+        echo "ERROR: runmachine services did not report healthy. Check runm status."
+        exit 1
+    fi
+    print_if_verbose "ok."
+fi